@@ -7,30 +7,56 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
 	"github.com/rs/cors"
 	"github.com/sirupsen/logrus"
 
 	"github.com/gridmate/backend/internal/auth"
+	"github.com/gridmate/backend/internal/auth/oidc"
 	"github.com/gridmate/backend/internal/config"
 	"github.com/gridmate/backend/internal/database"
 	"github.com/gridmate/backend/internal/handlers"
+	"github.com/gridmate/backend/internal/mcp"
+	"github.com/gridmate/backend/internal/memory"
 	"github.com/gridmate/backend/internal/middleware"
 	"github.com/gridmate/backend/internal/repository"
 	"github.com/gridmate/backend/internal/routes"
 	"github.com/gridmate/backend/internal/services"
 	"github.com/gridmate/backend/internal/services/ai"
+	"github.com/gridmate/backend/internal/services/audit"
+	"github.com/gridmate/backend/internal/services/ratelimit"
+	"github.com/gridmate/backend/internal/services/sso"
 	"github.com/gridmate/backend/internal/services/documents"
 	"github.com/gridmate/backend/internal/services/indexing"
 	"github.com/gridmate/backend/pkg/logger"
 )
 
 func main() {
+	// Offline maintenance of memory stores: api memory <command>
+	if len(os.Args) > 1 && os.Args[1] == "memory" {
+		if err := runMemoryCommand(os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// MCP over stdio for agent clients: api mcp [flags]
+	if len(os.Args) > 1 && os.Args[1] == "mcp" {
+		if err := runMCPCommand(os.Args[2:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Load environment variables from project root
 	if err := godotenv.Load("../../.env"); err != nil {
 		// Try loading from current directory as fallback
@@ -63,6 +89,13 @@ func main() {
 	// Initialize repositories
 	repos := repository.NewRepositories(db)
 
+	// Hash-chain the audit log and prune it by retention
+	if cfg.Audit.HashKey == "" {
+		logger.Warn("AUDIT_HASH_KEY not set, audit log hashes can be recomputed by anyone with database access")
+	}
+	auditService := audit.NewService(logger, repos.AuditLogs, repos.Workspaces, []byte(cfg.Audit.HashKey), cfg.Audit.RetentionDays)
+	aiAuditLog := services.NewAIAuditLog(repos.AuditLogs)
+
 	// Initialize JWT manager
 	jwtManager := auth.NewJWTManager(&cfg.JWT)
 
@@ -90,6 +123,8 @@ func main() {
 	// Initialize Excel bridge service, injecting the AI service
 	excelBridge := services.NewExcelBridge(logger, aiService)
 	excelBridge.SetSessionManager(sessionManager)
+	// Hold every AI change for four-eyes review on regulated deployments
+	excelBridge.GetQueuedOperationRegistry().SetReviewRequired(cfg.AI.RequireReview)
 	
 	// Initialize embedding provider and indexing service for vector memory
 	var indexingService *indexing.IndexingService
@@ -97,12 +132,70 @@ func main() {
 	openAIKey := os.Getenv("OPENAI_API_KEY")
 	if openAIKey != "" {
 		embeddingProvider = ai.NewOpenAIEmbeddingProvider(openAIKey)
-		indexingService = indexing.NewIndexingService(embeddingProvider, logger)
-		excelBridge.SetIndexingService(indexingService)
-		
 		logger.Info("Vector memory indexing service initialized with OpenAI embeddings")
 	} else {
-		logger.Warn("OPENAI_API_KEY not set, vector memory search will use keyword fallback")
+		embeddingProvider = ai.NewLocalEmbeddingProvider()
+		logger.Warn("OPENAI_API_KEY not set, vector memory will use local lexical embeddings")
+	}
+	cachedProvider, err := ai.NewCachedEmbeddingProvider(embeddingProvider, getEnv("EMBEDDING_CACHE_PATH", "./data/embeddings.db"))
+	if err != nil {
+		logger.WithError(err).Warn("Embedding cache unavailable, embeddings will not be cached")
+	} else {
+		defer cachedProvider.Close()
+		embeddingProvider = cachedProvider
+	}
+	indexingService = indexing.NewIndexingService(embeddingProvider, logger)
+	excelBridge.SetIndexingService(indexingService)
+
+	// Enable or disable tools for this deployment
+	if toolExecutor := excelBridge.GetToolExecutor(); toolExecutor != nil {
+		// Offer tools from external MCP servers; workspace policies apply
+		// to the workspace of the user that owns each session
+		if path := os.Getenv("MCP_SERVERS_CONFIG"); path != "" {
+			if mcpConfig, err := mcp.LoadClientConfig(path); err != nil {
+				logger.WithError(err).Warn("Invalid MCP server configuration, external tools disabled")
+			} else {
+				externalTools := mcp.ConnectExternalTools(context.Background(), mcpConfig, func(sessionID string) string {
+					workspace, err := toolExecutor.SessionWorkspace(context.Background(), sessionID)
+					if err != nil {
+						return ""
+					}
+					return workspace
+				})
+				defer externalTools.Close()
+				externalTools.Register(context.Background(), toolExecutor.Registry())
+			}
+		}
+
+		if toolConfig, err := ai.ToolConfigFromEnv(); err != nil {
+			logger.WithError(err).Warn("Invalid tool configuration, all tools remain enabled")
+		} else if err := toolExecutor.Registry().Configure(toolConfig); err != nil {
+			logger.WithError(err).Warn("Invalid tool configuration, all tools remain enabled")
+		}
+
+		// Audit every tool execution and queued operation, and record where
+		// every AI-written cell came from
+		lineageStore := services.NewCellLineageStore(repos.CellLineage)
+		toolExecutor.SetToolAuditor(aiAuditLog)
+		toolExecutor.SetLineageStore(lineageStore)
+		excelBridge.GetQueuedOperationRegistry().SetAuditor(aiAuditLog, lineageStore)
+
+		// Apply the settings of the workspace each session's user works in
+		toolExecutor.SetWorkspaceSource(services.NewUserWorkspaces(repos.Workspaces))
+
+		// Decide how AI writes apply per workspace, auditing every decision
+		var policyConfig ai.PolicyConfig
+		if path := os.Getenv("AI_AUTONOMY_POLICY"); path != "" {
+			if loaded, err := ai.LoadPolicyConfig(path); err != nil {
+				logger.WithError(err).Warn("Invalid autonomy policy, using the default policy")
+			} else {
+				policyConfig = loaded
+			}
+		}
+		toolExecutor.SetPolicyEngine(ai.NewPolicyEngine(policyConfig, aiAuditLog))
+
+		// Refuse writes to the cells users protected in their workbooks
+		toolExecutor.SetProtectedRangeSource(services.NewProtectedRangeStore(repos.ProtectedRanges, repos.Workspaces))
 	}
 	
 	if aiService != nil {
@@ -167,7 +260,39 @@ func main() {
 	excelBridge.SetSignalRBridge(signalRBridge)
 
 	// Initialize document service
-	docService := documents.NewDocumentService(logger, aiService, repos.Documents, repos.Embeddings)
+	docService := documents.NewDocumentService(logger, aiService, repos.Documents, repos.Embeddings, repos.Facts)
+
+	// Expire old session memory and compact its files in the background
+	if ttl := getEnv("MEMORY_TTL", ""); ttl != "" {
+		policy, err := memory.ParseTTLPolicy(ttl)
+		if err != nil {
+			logger.Fatalf("Invalid MEMORY_TTL: %v", err)
+		}
+		excelBridge.SetMemoryOptions(
+			memory.WithTTLPolicy(policy),
+			memory.WithMaintenance(time.Hour),
+		)
+	}
+
+	// Optionally share one pgvector index between filings and session memory
+	if getEnv("MEMORY_STORE", "memory") == "postgres" {
+		sharedMemory := memory.NewPostgresStore(db.DB, memory.WithNamespaceQuota(getEnvAsInt("MEMORY_NAMESPACE_QUOTA", 0)))
+		excelBridge.SetSharedMemoryStore(sharedMemory)
+		docService.SetChunkStore(sharedMemory)
+		logger.Info("Using shared Postgres memory store")
+	}
+
+	// Let AI tools read filing facts for the session's user
+	if toolExecutor := excelBridge.GetToolExecutor(); toolExecutor != nil {
+		toolExecutor.SetFactSource(documents.NewFactSource(docService, func(sessionID string) (uuid.UUID, bool) {
+			session := excelBridge.GetSession(sessionID)
+			if session == nil {
+				return uuid.Nil, false
+			}
+			userID, err := uuid.Parse(session.UserID)
+			return userID, err == nil
+		}))
+	}
 
 	// Create SignalR handler
 	signalRHandler := handlers.NewSignalRHandler(excelBridge, signalRBridge, logger)
@@ -189,6 +314,18 @@ func main() {
 	ackMiddleware := middleware.AcknowledgmentMiddleware(logger)
 	router.Use(ackMiddleware)
 
+	// Rate limit chat, tool responses, uploads and auth per caller
+	if cfg.RateLimit.Enabled {
+		limiter, err := newRateLimiter(cfg.RateLimit, repos, logger)
+		if err != nil {
+			logger.Fatalf("Failed to configure rate limiting: %v", err)
+		}
+		go limiter.Run(context.Background(), cfg.RateLimit.SyncInterval)
+		rateLimits := middleware.NewRateLimitMiddleware(limiter, jwtManager, repos.APIKeys, logger)
+		rateLimits.SetSessionOwners(excelBridge)
+		router.Use(rateLimits.Middleware)
+	}
+
 	// Add compression middleware for better performance
 	router.Use(middleware.GzipMiddleware)
 
@@ -219,15 +356,25 @@ func main() {
 	router.HandleFunc("/api/metrics/sessions/by-type", metricsHandler.GetSessionsByType).Methods("GET")
 	router.HandleFunc("/api/metrics/sessions/by-user", metricsHandler.GetSessionsByUser).Methods("GET")
 
+	// Single sign-on with an OpenID Connect provider
+	var ssoHandler *handlers.SSOHandler
+	if cfg.SSO.Issuer != "" {
+		ssoService, err := newSSOService(cfg.SSO, repos, logger)
+		if err != nil {
+			logger.Fatalf("Failed to configure single sign-on: %v", err)
+		}
+		ssoHandler = handlers.NewSSOHandler(ssoService, repos, jwtManager, cfg.SSO.ReturnURL, logger)
+	}
+
 	// Register API routes
-	routes.RegisterAPIRoutes(router, repos, jwtManager, excelBridge, docService, signalRBridge, logger)
+	routes.RegisterAPIRoutes(router, repos, jwtManager, excelBridge, docService, signalRBridge, auditService, ssoHandler, logger)
 
 	// Configure CORS
 	corsOptions := cors.New(cors.Options{
 		AllowedOrigins:   getEnvAsSlice("CORS_ALLOWED_ORIGINS", []string{"http://localhost:3000", "https://localhost:3000", "http://localhost:8080", "https://localhost:7171"}),
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
 		AllowedHeaders:   []string{"Authorization", "Content-Type", "X-Requested-With", "Upgrade", "Connection", "Cache-Control"},
-		ExposedHeaders:   []string{"Content-Length", "Content-Type"},
+		ExposedHeaders:   []string{"Content-Length", "Content-Type", "Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	})
@@ -243,6 +390,11 @@ func main() {
 		MaxHeaderBytes: 1 << 20, // 1 MB - increased to handle larger context payloads
 	}
 
+	// Apply audit log retention
+	if cfg.Audit.RetentionInterval > 0 {
+		go auditService.RunRetention(context.Background(), cfg.Audit.RetentionInterval)
+	}
+
 	// Start periodic session cleanup
 	go func() {
 		ticker := time.NewTicker(15 * time.Minute)
@@ -284,6 +436,58 @@ func main() {
 	logger.Info("Server exited")
 }
 
+// newRateLimiter builds the limiter described by cfg. Workspaces can
+// choose a plan and override budgets in their settings.
+func newRateLimiter(cfg config.RateLimitConfig, repos *repository.Repositories, logger *logrus.Logger) (*ratelimit.Limiter, error) {
+	defaults, err := ratelimit.ParseBudgets(map[ratelimit.Category]string{
+		ratelimit.CategoryChat:         cfg.Chat,
+		ratelimit.CategoryToolResponse: cfg.ToolResponse,
+		ratelimit.CategoryUpload:       cfg.Upload,
+		ratelimit.CategoryAuth:         cfg.Auth,
+	})
+	if err != nil {
+		return nil, err
+	}
+	plans, err := ratelimit.ParsePlans(cfg.Plans)
+	if err != nil {
+		return nil, err
+	}
+
+	var store ratelimit.Store = ratelimit.NewMemoryStore()
+	if cfg.Persist {
+		store = ratelimit.NewPersistentStore(repos.RateLimits)
+	}
+	limiter := ratelimit.NewLimiter(logger, store, defaults)
+	limiter.SetPlans(plans)
+	limiter.SetPolicySource(ratelimit.NewWorkspacePolicies(logger, repos.Workspaces))
+	return limiter, nil
+}
+
+// newSSOService builds sign-in with the provider described by cfg
+func newSSOService(cfg config.SSOConfig, repos *repository.Repositories, logger *logrus.Logger) (*sso.Service, error) {
+	if cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, fmt.Errorf("OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required with OIDC_ISSUER")
+	}
+	groupWorkspaces, err := sso.ParseGroupWorkspaces(cfg.GroupWorkspaces)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := oidc.NewProvider(oidc.Config{
+		Issuer:       cfg.Issuer,
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		RedirectURL:  cfg.RedirectURL,
+		Scopes:       cfg.Scopes,
+	}, nil)
+	return sso.NewService(logger, provider, sso.Config{
+		Provider:        cfg.Provider,
+		GroupsClaim:     cfg.GroupsClaim,
+		GroupWorkspaces: groupWorkspaces,
+		AutoProvision:   cfg.AutoProvision,
+	}, repos.Users, repos.OAuthProviders, repos.Workspaces), nil
+}
+
 func loggingMiddleware(logger *logrus.Logger) mux.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -310,6 +514,13 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getEnvAsInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
 func getEnvAsSlice(key string, defaultValue []string) []string {
 	value := os.Getenv(key)
 	if value == "" {
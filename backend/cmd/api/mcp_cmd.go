@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/gridmate/backend/internal/mcp"
+)
+
+const mcpUsage = `Usage: api mcp [flags]
+
+Serves the Model Context Protocol over stdio for agent clients that launch
+their servers as subprocesses. Messages are forwarded to the MCP endpoint of
+a running Gridmate server, where the Excel add-in is connected.
+
+Flags:
+  -url       MCP endpoint (default $GRIDMATE_MCP_URL or http://localhost:8080/api/v1/mcp)
+  -token     access token (default $GRIDMATE_TOKEN)
+  -session   Gridmate session to act on
+  -autonomy  agent-default (writes queued for approval) or read-only
+`
+
+// runMCPCommand serves MCP over stdin and stdout
+func runMCPCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("mcp", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() { fmt.Fprint(stderr, mcpUsage) }
+	url := flags.String("url", getEnv("GRIDMATE_MCP_URL", "http://localhost:8080/api/v1/mcp"), "MCP endpoint")
+	token := flags.String("token", os.Getenv("GRIDMATE_TOKEN"), "access token")
+	session := flags.String("session", "", "Gridmate session to act on")
+	autonomy := flags.String("autonomy", mcp.AutonomyAgentDefault, "autonomy mode")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	mode, err := mcp.NormalizeAutonomyMode(*autonomy)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	handler := mcp.NewRemoteHandler(*url, *token, mcp.Binding{SessionID: *session, AutonomyMode: mode})
+	return mcp.ServeStdio(ctx, stdin, stdout, handler)
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gridmate/backend/internal/memory"
+)
+
+const memoryUsage = `Usage: api memory <command> [flags]
+
+Commands:
+  stats     Show chunk counts for the store or one namespace
+  export    Write a tenant's or session's chunks as JSON lines
+  import    Add chunks from an export
+  snapshot  Write a consistent copy of the store
+  restore   Replace the store with a snapshot
+  compact   Rewrite the store file to reclaim free pages
+  expire    Delete chunks past a TTL policy
+  rebuild   Rebuild the expiry index and statistics
+
+Every command takes -db, the path of a BoltDB memory store. The API server
+holds a lock on its stores, so stop it or work on a copy first.
+`
+
+// runMemoryCommand implements the memory maintenance CLI
+func runMemoryCommand(args []string, stdout io.Writer) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "help" {
+		fmt.Fprint(stdout, memoryUsage)
+		return nil
+	}
+
+	command := args[0]
+	flags := flag.NewFlagSet("memory "+command, flag.ContinueOnError)
+	dbPath := flags.String("db", "", "path of the BoltDB memory store")
+	nsFlag := flags.String("ns", "", "namespace as tenant or tenant/session")
+	input := flags.String("i", "", "input file (default stdin)")
+	output := flags.String("o", "", "output file (default stdout)")
+	ttl := flags.String("ttl", "", "TTL policy, e.g. chat=720h,document=8760h")
+	hnswPath := flags.String("hnsw", "", "also write an HNSW snapshot of all chunks to this file")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *dbPath == "" {
+		return fmt.Errorf("-db is required")
+	}
+
+	var ns memory.Namespace
+	if *nsFlag != "" {
+		var err error
+		if ns, err = parseNamespaceFlag(*nsFlag); err != nil {
+			return err
+		}
+	}
+
+	store, err := memory.OpenBoltDBStore(*dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", *dbPath, err)
+	}
+	defer store.Close()
+
+	switch command {
+	case "stats":
+		if ns.Tenant != "" {
+			return printJSON(stdout, store.NamespaceStats(ns))
+		}
+		namespaces := make(map[string]memory.Stats)
+		for _, space := range store.Namespaces() {
+			namespaces[space.String()] = store.NamespaceStats(space)
+		}
+		return printJSON(stdout, map[string]interface{}{
+			"store":      store.GetStats(),
+			"namespaces": namespaces,
+		})
+
+	case "export":
+		if ns.Tenant == "" {
+			return fmt.Errorf("-ns is required")
+		}
+		w, closeOutput, err := openOutput(*output, stdout)
+		if err != nil {
+			return err
+		}
+		n, err := store.Export(ns, w)
+		if closeErr := closeOutput(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "exported %d chunks from %s\n", n, ns)
+
+	case "import":
+		r, err := openInput(*input)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		n, err := store.Import(r, ns)
+		if err != nil {
+			return fmt.Errorf("imported %d chunks before failing: %w", n, err)
+		}
+		fmt.Fprintf(stdout, "imported %d chunks\n", n)
+
+	case "snapshot":
+		if *output == "" {
+			return fmt.Errorf("-o is required")
+		}
+		n, err := store.SnapshotFile(*output)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "wrote %d bytes to %s\n", n, *output)
+
+	case "restore":
+		r, err := openInput(*input)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		if err := store.Restore(r); err != nil {
+			return err
+		}
+		return printJSON(stdout, store.GetStats())
+
+	case "compact":
+		result, err := store.Compact()
+		if err != nil {
+			return err
+		}
+		return printJSON(stdout, result)
+
+	case "expire":
+		policy, err := memory.ParseTTLPolicy(*ttl)
+		if err != nil {
+			return err
+		}
+		if len(policy) == 0 {
+			return fmt.Errorf("-ttl is required")
+		}
+		n, err := store.ExpireWith(policy, time.Now())
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "expired %d chunks\n", n)
+
+	case "rebuild":
+		stats, err := store.RebuildIndexes()
+		if err != nil {
+			return err
+		}
+		if *hnswPath != "" {
+			index := memory.NewHNSWStore()
+			for _, space := range store.Namespaces() {
+				if err := index.Add(space, store.Chunks(space)); err != nil {
+					return fmt.Errorf("failed to index %s: %w", space, err)
+				}
+			}
+			if err := index.SaveSnapshot(*hnswPath); err != nil {
+				return err
+			}
+		}
+		return printJSON(stdout, stats)
+
+	default:
+		fmt.Fprint(stdout, memoryUsage)
+		return fmt.Errorf("unknown memory command %q", command)
+	}
+
+	return nil
+}
+
+// parseNamespaceFlag accepts "tenant" or "tenant/session"
+func parseNamespaceFlag(value string) (memory.Namespace, error) {
+	if !strings.Contains(value, "/") {
+		ns := memory.TenantNamespace(value)
+		return ns, ns.Validate()
+	}
+	return memory.ParseNamespace(value)
+}
+
+func openInput(path string) (io.ReadCloser, error) {
+	if path == "" || path == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
+
+func openOutput(path string, stdout io.Writer) (io.Writer, func() error, error) {
+	if path == "" || path == "-" {
+		return stdout, func() error { return nil }, nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, f.Close, nil
+}
+
+func printJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
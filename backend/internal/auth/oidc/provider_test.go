@@ -0,0 +1,125 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gridmate/backend/internal/auth/oidc"
+	"github.com/gridmate/backend/internal/auth/oidc/oidctest"
+)
+
+// signIn follows the provider's redirect like a browser and returns the
+// code and state sent back to the redirect URL
+func signIn(t *testing.T, authURL string) (code, state string) {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize = %d %q, want a redirect", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	ctx := context.Background()
+	idp := oidctest.NewServer("gridmate", "secret")
+	defer idp.Close()
+	idp.SetUser(map[string]interface{}{
+		"sub":            "user-1",
+		"email":          "ana@example.com",
+		"email_verified": true,
+		"groups":         []string{"analysts"},
+	})
+
+	provider := oidc.NewProvider(oidc.Config{
+		Issuer:       idp.URL,
+		ClientID:     "gridmate",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost/callback",
+		Scopes:       []string{"email"},
+	}, nil)
+
+	verifier, _ := oidc.RandomToken()
+	authURL, err := provider.AuthCodeURL(ctx, "state-1", "nonce-1", oidc.Challenge(verifier))
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state := signIn(t, authURL)
+	if state != "state-1" {
+		t.Errorf("state = %q, want state-1", state)
+	}
+
+	if _, err := provider.Exchange(ctx, code, "wrong-verifier"); !errors.Is(err, oidc.ErrExchangeFailed) {
+		t.Fatalf("exchange with wrong verifier error = %v, want ErrExchangeFailed", err)
+	}
+	// The rejected attempt used up the code
+	code, _ = signIn(t, authURL)
+	tokens, err := provider.Exchange(ctx, code, verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := provider.Verify(ctx, tokens.IDToken, "other-nonce"); !errors.Is(err, oidc.ErrInvalidToken) {
+		t.Errorf("verify with wrong nonce error = %v, want ErrInvalidToken", err)
+	}
+	token, err := provider.Verify(ctx, tokens.IDToken, "nonce-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Subject != "user-1" || token.Email != "ana@example.com" || !token.EmailVerified {
+		t.Errorf("token = %+v, want user-1 with a verified email", token)
+	}
+	if groups := token.Strings("groups"); len(groups) != 1 || groups[0] != "analysts" {
+		t.Errorf("groups = %v, want [analysts]", groups)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+	idp := oidctest.NewServer("gridmate", "secret")
+	defer idp.Close()
+	provider := oidc.NewProvider(oidc.Config{Issuer: idp.URL, ClientID: "gridmate"}, nil)
+
+	valid := map[string]interface{}{"sub": "user-1", "nonce": "n"}
+	for i := 0; i < 2; i++ {
+		if _, err := provider.Verify(ctx, idp.SignIDToken(valid), "n"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := idp.JWKSRequests.Load(); got != 1 {
+		t.Errorf("JWKS fetched %d times, want the keys cached after 1", got)
+	}
+
+	invalid := map[string]map[string]interface{}{
+		"other audience": {"sub": "user-1", "nonce": "n", "aud": "someone-else"},
+		"other issuer":   {"sub": "user-1", "nonce": "n", "iss": "https://evil.example.com"},
+		"expired":        {"sub": "user-1", "nonce": "n", "exp": time.Now().Add(-time.Hour).Unix()},
+		"no subject":     {"nonce": "n"},
+		"unauthorized party": {
+			"sub": "user-1", "nonce": "n", "aud": []string{"gridmate", "other"}, "azp": "other",
+		},
+	}
+	for name, claims := range invalid {
+		if _, err := provider.Verify(ctx, idp.SignIDToken(claims), "n"); !errors.Is(err, oidc.ErrInvalidToken) {
+			t.Errorf("%s: error = %v, want ErrInvalidToken", name, err)
+		}
+	}
+
+	// A token from a rotated key within a minute of the last fetch is
+	// rejected until the keys may be refetched
+	idp.RotateKey()
+	if _, err := provider.Verify(ctx, idp.SignIDToken(valid), "n"); !errors.Is(err, oidc.ErrInvalidToken) {
+		t.Errorf("rotated key error = %v, want ErrInvalidToken before the refresh interval", err)
+	}
+}
@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// API key scopes are "<resource>:read" or "<resource>:write". A write
+// scope includes the read scope of its resource.
+const (
+	ScopeChatRead       = "chat:read"
+	ScopeChatWrite      = "chat:write"
+	ScopeDocumentsRead  = "documents:read"
+	ScopeDocumentsWrite = "documents:write"
+	ScopeExcelRead      = "excel:read"
+	ScopeExcelWrite     = "excel:write"
+	ScopeModelsRead     = "models:read"
+	ScopeModelsWrite    = "models:write"
+	ScopeWorkbooksRead  = "workbooks:read"
+	ScopeWorkbooksWrite = "workbooks:write"
+	ScopeReviewsRead    = "reviews:read"
+	ScopeReviewsWrite   = "reviews:write"
+	ScopeAuditRead      = "audit:read"
+	ScopeAuditWrite     = "audit:write"
+	ScopeMCPRead        = "mcp:read"
+	ScopeMCPWrite       = "mcp:write"
+)
+
+// scopeResources maps the first path segment under /api/v1 to the resource
+// its scopes are named after. Paths not listed here, such as /auth, /users
+// and /admin, cannot be reached with an API key.
+var scopeResources = map[string]string{
+	"ai":        "chat",
+	"documents": "documents",
+	"excel":     "excel",
+	"models":    "models",
+	"workbooks": "workbooks",
+	"audit":     "audit",
+	"mcp":       "mcp",
+}
+
+// readOnlyPosts are POST endpoints that only read. Chat is not one of them:
+// the assistant runs tools and queues changes to the user's workbooks.
+var readOnlyPosts = map[string]bool{
+	"/ai/suggest":       true,
+	"/documents/search": true,
+	"/excel/diff":       true,
+}
+
+// RequiredScope returns the scope an API key needs for a request to path,
+// given relative to /api/v1. ok is false for endpoints API keys may not use.
+func RequiredScope(method, path string) (scope string, ok bool) {
+	path = "/" + strings.Trim(path, "/")
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	resource, ok := scopeResources[segments[0]]
+	if !ok {
+		return "", false
+	}
+	if resource == "chat" && len(segments) > 1 && segments[1] == "reviews" {
+		resource = "reviews"
+	}
+
+	switch {
+	case method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions:
+		return resource + ":read", true
+	case method == http.MethodPost && readOnlyPosts[path]:
+		return resource + ":read", true
+	default:
+		return resource + ":write", true
+	}
+}
+
+// HasScope reports whether granted includes required, directly or through
+// the write scope of the same resource
+func HasScope(granted []string, required string) bool {
+	write := strings.TrimSuffix(required, ":read") + ":write"
+	for _, scope := range granted {
+		if scope == required || scope == write {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateScopes checks that every scope names a known resource and access
+func ValidateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return fmt.Errorf("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		resource, access, found := strings.Cut(scope, ":")
+		if !found || (access != "read" && access != "write") || !knownResource(resource) {
+			return fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+	return nil
+}
+
+func knownResource(name string) bool {
+	if name == "reviews" {
+		return true
+	}
+	for _, resource := range scopeResources {
+		if resource == name {
+			return true
+		}
+	}
+	return false
+}
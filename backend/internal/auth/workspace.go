@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// RoleViewer is the workspace role that may read but not change what the
+// workspace shares
+const RoleViewer = "viewer"
+
+// ErrNotWorkspaceMember is returned when the caller may not act in a workspace
+var ErrNotWorkspaceMember = errors.New("not a member of this workspace")
+
+// MemberRoles looks up the roles of workspace members
+type MemberRoles interface {
+	GetMemberRole(ctx context.Context, workspaceID, userID uuid.UUID) (string, error)
+}
+
+type boundWorkspaceKey struct{}
+
+// WithBoundWorkspace restricts ctx to one workspace, as for requests made
+// with a workspace-bound API key
+func WithBoundWorkspace(ctx context.Context, workspaceID uuid.UUID) context.Context {
+	return context.WithValue(ctx, boundWorkspaceKey{}, workspaceID)
+}
+
+// BoundWorkspace returns the workspace ctx is restricted to. ok is false
+// when ctx may act on any workspace its user belongs to.
+func BoundWorkspace(ctx context.Context) (workspaceID uuid.UUID, ok bool) {
+	workspaceID, ok = ctx.Value(boundWorkspaceKey{}).(uuid.UUID)
+	return workspaceID, ok
+}
+
+// WorkspaceAllowed reports whether ctx may act on workspaceID. Services
+// check it alongside workspace membership.
+func WorkspaceAllowed(ctx context.Context, workspaceID uuid.UUID) bool {
+	bound, ok := BoundWorkspace(ctx)
+	return !ok || bound == workspaceID
+}
+
+// WorkspaceRole returns the caller's role in a workspace. Callers restricted
+// to another workspace are refused as non-members.
+func WorkspaceRole(ctx context.Context, roles MemberRoles, workspaceID, userID uuid.UUID) (string, error) {
+	if !WorkspaceAllowed(ctx, workspaceID) {
+		return "", ErrNotWorkspaceMember
+	}
+	role, err := roles.GetMemberRole(ctx, workspaceID, userID)
+	if err != nil {
+		return "", ErrNotWorkspaceMember
+	}
+	return role, nil
+}
+
+// CanWrite reports whether a workspace role may change what the workspace
+// shares
+func CanWrite(role string) bool {
+	return role != RoleViewer
+}
@@ -10,13 +10,16 @@ import (
 
 // Config holds all configuration for the application
 type Config struct {
-	App      AppConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
-	Redis    RedisConfig
-	CORS     CORSConfig
-	API      APIConfig
-	AI       AIConfig
+	App       AppConfig
+	Database  DatabaseConfig
+	JWT       JWTConfig
+	Redis     RedisConfig
+	CORS      CORSConfig
+	API       APIConfig
+	AI        AIConfig
+	Audit     AuditConfig
+	RateLimit RateLimitConfig
+	SSO       SSOConfig
 }
 
 // AppConfig holds application-specific configuration
@@ -82,6 +85,7 @@ type AIConfig struct {
 	RetryDelay      time.Duration // New field
 	EnableActions   bool
 	EnableEmbedding bool
+	RequireReview   bool // Only apply AI changes approved in four-eyes review
 	// Provider-specific configs
 	AnthropicAPIKey     string
 	AzureOpenAIKey      string
@@ -91,6 +95,41 @@ type AIConfig struct {
 	ChatRequestTimeout time.Duration
 }
 
+// AuditConfig holds audit log configuration
+type AuditConfig struct {
+	HashKey           string // HMAC key of the audit hash chain
+	RetentionDays     int    // Zero keeps entries forever
+	RetentionInterval time.Duration
+}
+
+// RateLimitConfig holds rate limiting configuration. Budgets are
+// requests per period such as "30/m"; Plans is JSON of budgets per plan.
+type RateLimitConfig struct {
+	Enabled      bool
+	Persist      bool // Save buckets to the rate_limits table
+	SyncInterval time.Duration
+	Chat         string
+	ToolResponse string
+	Upload       string
+	Auth         string
+	Plans        string
+}
+
+// SSOConfig holds OpenID Connect single sign-on configuration. Sign-on is
+// enabled when Issuer is set.
+type SSOConfig struct {
+	Provider        string // Name of the provider in linked accounts
+	Issuer          string
+	ClientID        string
+	ClientSecret    string
+	RedirectURL     string // This API's callback URL registered with the provider
+	Scopes          []string
+	GroupsClaim     string
+	GroupWorkspaces string // JSON mapping groups to workspace memberships
+	AutoProvision   bool
+	ReturnURL       string // Where the browser is sent with tokens after sign-in
+}
+
 // Load loads configuration from environment variables
 func Load() (*Config, error) {
 	cfg := &Config{
@@ -144,12 +183,40 @@ func Load() (*Config, error) {
 			RetryDelay:          getEnvAsDuration("AI_RETRY_DELAY", 2*time.Second), // New line
 			EnableActions:       getEnvAsBool("AI_ENABLE_ACTIONS", true),
 			EnableEmbedding:     getEnvAsBool("AI_ENABLE_EMBEDDING", true),
+			RequireReview:       getEnvAsBool("AI_REQUIRE_REVIEW", false),
 			AnthropicAPIKey:     getEnv("ANTHROPIC_API_KEY", ""),
 			AzureOpenAIKey:      getEnv("AZURE_OPENAI_KEY", ""),
 			AzureOpenAIEndpoint: getEnv("AZURE_OPENAI_ENDPOINT", ""),
 			ToolRequestTimeout:  getEnvAsDuration("TOOL_REQUEST_TIMEOUT", 300*time.Second),
 			ChatRequestTimeout:  getEnvAsDuration("CHAT_REQUEST_TIMEOUT", 5*time.Minute),
 		},
+		Audit: AuditConfig{
+			HashKey:           getEnv("AUDIT_HASH_KEY", ""),
+			RetentionDays:     getEnvAsInt("AUDIT_RETENTION_DAYS", 0),
+			RetentionInterval: getEnvAsDuration("AUDIT_RETENTION_INTERVAL", 24*time.Hour),
+		},
+		SSO: SSOConfig{
+			Provider:        getEnv("OIDC_PROVIDER", "oidc"),
+			Issuer:          getEnv("OIDC_ISSUER", ""),
+			ClientID:        getEnv("OIDC_CLIENT_ID", ""),
+			ClientSecret:    getEnv("OIDC_CLIENT_SECRET", ""),
+			RedirectURL:     getEnv("OIDC_REDIRECT_URL", ""),
+			Scopes:          getEnvAsSlice("OIDC_SCOPES", []string{"profile", "email"}),
+			GroupsClaim:     getEnv("OIDC_GROUPS_CLAIM", "groups"),
+			GroupWorkspaces: getEnv("OIDC_GROUP_WORKSPACES", ""),
+			AutoProvision:   getEnvAsBool("OIDC_AUTO_PROVISION", true),
+			ReturnURL:       getEnv("OIDC_RETURN_URL", ""),
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:      getEnvAsBool("RATE_LIMIT_ENABLED", true),
+			Persist:      getEnvAsBool("RATE_LIMIT_PERSIST", false),
+			SyncInterval: getEnvAsDuration("RATE_LIMIT_SYNC_INTERVAL", 10*time.Second),
+			Chat:         getEnv("RATE_LIMIT_CHAT", "30/m"),
+			ToolResponse: getEnv("RATE_LIMIT_TOOL_RESPONSE", "600/m"),
+			Upload:       getEnv("RATE_LIMIT_UPLOAD", "20/h"),
+			Auth:         getEnv("RATE_LIMIT_AUTH", "10/m"),
+			Plans:        getEnv("RATE_LIMIT_PLANS", ""),
+		},
 	}
 
 	// Validate required configuration
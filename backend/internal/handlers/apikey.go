@@ -2,32 +2,59 @@ package handlers
 
 import (
 	"encoding/json"
+	"net"
 	"net/http"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/lib/pq"
 	"github.com/sirupsen/logrus"
-	
+
+	"github.com/gridmate/backend/internal/auth"
+	"github.com/gridmate/backend/internal/middleware"
 	"github.com/gridmate/backend/internal/models"
 	"github.com/gridmate/backend/internal/repository"
 )
 
+const (
+	// defaultRotationGrace is how long a rotated key keeps working by default
+	defaultRotationGrace = 24 * time.Hour
+	// maxRotationGrace caps the grace period a rotation may ask for
+	maxRotationGrace = 7 * 24 * time.Hour
+)
+
 type APIKeyHandler struct {
-	apiKeyRepo repository.APIKeyRepository
-	logger     *logrus.Logger
+	apiKeyRepo    repository.APIKeyRepository
+	workspaceRepo repository.WorkspaceRepository
+	logger        *logrus.Logger
 }
 
 func NewAPIKeyHandler(repos *repository.Repositories, logger *logrus.Logger) *APIKeyHandler {
 	return &APIKeyHandler{
-		apiKeyRepo: repos.APIKeys,
-		logger:     logger,
+		apiKeyRepo:    repos.APIKeys,
+		workspaceRepo: repos.Workspaces,
+		logger:        logger,
 	}
 }
 
 type CreateAPIKeyRequest struct {
-	Name        string    `json:"name" validate:"required"`
-	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	Name      string     `json:"name" validate:"required"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// Scopes the key is limited to, e.g. ["chat:read", "audit:read"]
+	Scopes []string `json:"scopes" validate:"required"`
+	// WorkspaceID binds the key to a workspace the user is a member of
+	WorkspaceID *uuid.UUID `json:"workspace_id,omitempty"`
+	// AllowedIPs limits the key to these addresses and CIDR ranges
+	AllowedIPs []string `json:"allowed_ips,omitempty"`
+}
+
+type RotateAPIKeyRequest struct {
+	// GracePeriod is how long the old key keeps working, e.g. "1h"
+	GracePeriod string `json:"grace_period,omitempty"`
+	// ExpiresAt overrides the expiry of the new key, which otherwise gets
+	// the lifetime of the old one
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
 
 type CreateAPIKeyResponse struct {
@@ -36,23 +63,34 @@ type CreateAPIKeyResponse struct {
 	Key         string     `json:"key"` // Only returned on creation
 	CreatedAt   time.Time  `json:"created_at"`
 	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	Scopes      []string   `json:"scopes"`
+	WorkspaceID *uuid.UUID `json:"workspace_id,omitempty"`
+	AllowedIPs  []string   `json:"allowed_ips"`
+}
+
+type RotateAPIKeyResponse struct {
+	CreateAPIKeyResponse
+	// PreviousKeyExpiresAt is when the rotated key stops working
+	PreviousKeyExpiresAt time.Time `json:"previous_key_expires_at"`
 }
 
 type APIKeyListResponse struct {
 	ID          string     `json:"id"`
 	Name        string     `json:"name"`
+	KeyPrefix   string     `json:"key_prefix"`
 	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	UsageCount  int64      `json:"usage_count"`
 	CreatedAt   time.Time  `json:"created_at"`
 	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	Scopes      []string   `json:"scopes"`
+	WorkspaceID *uuid.UUID `json:"workspace_id,omitempty"`
+	AllowedIPs  []string   `json:"allowed_ips"`
+	ReplacedBy  *uuid.UUID `json:"replaced_by,omitempty"`
 }
 
 func (h *APIKeyHandler) Create(w http.ResponseWriter, r *http.Request) {
-	userIDStr := r.Context().Value("user_id").(string)
-	
-	// Parse user ID
-	userID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		h.sendError(w, http.StatusUnauthorized, "Invalid user ID")
+	userID, ok := h.userID(w, r)
+	if !ok {
 		return
 	}
 
@@ -61,14 +99,42 @@ func (h *APIKeyHandler) Create(w http.ResponseWriter, r *http.Request) {
 		h.sendError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
+	if req.Name == "" {
+		h.sendError(w, http.StatusBadRequest, "Name is required")
+		return
+	}
+	if err := auth.ValidateScopes(req.Scopes); err != nil {
+		h.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		h.sendError(w, http.StatusBadRequest, "Expiry must be in the future")
+		return
+	}
+	for _, entry := range req.AllowedIPs {
+		if _, _, err := net.ParseCIDR(entry); err != nil && net.ParseIP(entry) == nil {
+			h.sendError(w, http.StatusBadRequest, "Invalid allowed IP: "+entry)
+			return
+		}
+	}
+	if req.WorkspaceID != nil {
+		member, err := h.workspaceRepo.IsMember(r.Context(), *req.WorkspaceID, userID)
+		if err != nil || !member {
+			h.sendError(w, http.StatusForbidden, "Not a member of the workspace")
+			return
+		}
+	}
 
 	// Generate API key
 	plainKey := models.GenerateAPIKey()
-	
+
 	apiKey := &models.APIKey{
 		UserID:      userID,
 		Name:        req.Name,
 		ExpiresAt:   req.ExpiresAt,
+		Scopes:      pq.StringArray(req.Scopes),
+		WorkspaceID: req.WorkspaceID,
+		AllowedIPs:  pq.StringArray(req.AllowedIPs),
 	}
 
 	// Create the API key (this will hash the plain key)
@@ -78,24 +144,12 @@ func (h *APIKeyHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp := CreateAPIKeyResponse{
-		ID:          apiKey.ID.String(),
-		Name:        apiKey.Name,
-		Key:         plainKey, // Return the plain key only on creation
-		CreatedAt:   apiKey.CreatedAt,
-		ExpiresAt:   apiKey.ExpiresAt,
-	}
-
-	h.sendJSON(w, http.StatusCreated, resp)
+	h.sendJSON(w, http.StatusCreated, newCreateAPIKeyResponse(apiKey, plainKey))
 }
 
 func (h *APIKeyHandler) List(w http.ResponseWriter, r *http.Request) {
-	userIDStr := r.Context().Value("user_id").(string)
-	
-	// Parse user ID
-	userID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		h.sendError(w, http.StatusBadRequest, "Invalid user ID")
+	userID, ok := h.userID(w, r)
+	if !ok {
 		return
 	}
 
@@ -112,47 +166,110 @@ func (h *APIKeyHandler) List(w http.ResponseWriter, r *http.Request) {
 		resp[i] = APIKeyListResponse{
 			ID:          key.ID.String(),
 			Name:        key.Name,
+			KeyPrefix:   key.KeyPrefix,
 			LastUsedAt:  key.LastUsedAt,
+			UsageCount:  key.UsageCount,
 			CreatedAt:   key.CreatedAt,
 			ExpiresAt:   key.ExpiresAt,
+			Scopes:      key.Scopes,
+			WorkspaceID: key.WorkspaceID,
+			AllowedIPs:  key.AllowedIPs,
+			ReplacedBy:  key.ReplacedBy,
 		}
 	}
 
 	h.sendJSON(w, http.StatusOK, resp)
 }
 
-func (h *APIKeyHandler) Delete(w http.ResponseWriter, r *http.Request) {
-	userIDStr := r.Context().Value("user_id").(string)
-	keyIDStr := mux.Vars(r)["id"]
-	
-	// Parse user ID
-	userID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		h.sendError(w, http.StatusBadRequest, "Invalid user ID")
+// Rotate issues a new key with the settings of an existing one. The old key
+// keeps working for a grace period so clients can switch over.
+func (h *APIKeyHandler) Rotate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.userID(w, r)
+	if !ok {
 		return
 	}
-	
-	// Parse key ID
-	keyID, err := uuid.Parse(keyIDStr)
-	if err != nil {
-		h.sendError(w, http.StatusBadRequest, "Invalid API key ID")
+	old, ok := h.ownedKey(w, r, userID)
+	if !ok {
 		return
 	}
 
-	// Check if the key belongs to the user
-	apiKey, err := h.apiKeyRepo.GetByID(r.Context(), keyID)
-	if err != nil {
-		h.sendError(w, http.StatusNotFound, "API key not found")
+	var req RotateAPIKeyRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.sendError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+	grace := defaultRotationGrace
+	if req.GracePeriod != "" {
+		parsed, err := time.ParseDuration(req.GracePeriod)
+		if err != nil || parsed < 0 || parsed > maxRotationGrace {
+			h.sendError(w, http.StatusBadRequest, "Grace period must be a duration of at most "+maxRotationGrace.String())
+			return
+		}
+		grace = parsed
+	}
+
+	now := time.Now()
+	if old.ReplacedBy != nil {
+		h.sendError(w, http.StatusConflict, "API key has already been rotated")
+		return
+	}
+	if !old.IsActive || (old.ExpiresAt != nil && !old.ExpiresAt.After(now)) {
+		h.sendError(w, http.StatusConflict, "API key is no longer active")
 		return
 	}
 
-	if apiKey.UserID != userID {
-		h.sendError(w, http.StatusForbidden, "Access denied")
+	expiresAt := req.ExpiresAt
+	if expiresAt == nil && old.ExpiresAt != nil {
+		renewed := now.Add(old.ExpiresAt.Sub(old.CreatedAt))
+		expiresAt = &renewed
+	}
+	if expiresAt != nil && !expiresAt.After(now) {
+		h.sendError(w, http.StatusBadRequest, "Expiry must be in the future")
+		return
+	}
+
+	plainKey := models.GenerateAPIKey()
+	newKey := &models.APIKey{
+		UserID:      userID,
+		Name:        old.Name,
+		Permissions: old.Permissions,
+		ExpiresAt:   expiresAt,
+		Scopes:      old.Scopes,
+		WorkspaceID: old.WorkspaceID,
+		AllowedIPs:  old.AllowedIPs,
+	}
+	graceUntil := now.Add(grace)
+	if err := h.apiKeyRepo.Rotate(r.Context(), old.ID, newKey, plainKey, graceUntil); err != nil {
+		h.logger.WithError(err).WithField("api_key_id", old.ID).Error("Failed to rotate API key")
+		h.sendError(w, http.StatusInternalServerError, "Failed to rotate API key")
+		return
+	}
+	if old.ExpiresAt != nil && old.ExpiresAt.Before(graceUntil) {
+		graceUntil = *old.ExpiresAt
+	}
+
+	h.sendJSON(w, http.StatusCreated, RotateAPIKeyResponse{
+		CreateAPIKeyResponse: newCreateAPIKeyResponse(newKey, plainKey),
+		PreviousKeyExpiresAt: graceUntil,
+	})
+}
+
+func (h *APIKeyHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	// Check if the key belongs to the user
+	apiKey, ok := h.ownedKey(w, r, userID)
+	if !ok {
 		return
 	}
 
 	// Delete the key
-	if err := h.apiKeyRepo.Delete(r.Context(), keyID); err != nil {
+	if err := h.apiKeyRepo.Delete(r.Context(), apiKey.ID); err != nil {
 		h.logger.WithError(err).Error("Failed to delete API key")
 		h.sendError(w, http.StatusInternalServerError, "Failed to delete API key")
 		return
@@ -161,6 +278,49 @@ func (h *APIKeyHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	h.sendJSON(w, http.StatusOK, map[string]string{"message": "API key deleted successfully"})
 }
 
+// ownedKey loads the key named in the path and checks it belongs to userID
+func (h *APIKeyHandler) ownedKey(w http.ResponseWriter, r *http.Request, userID uuid.UUID) (*models.APIKey, bool) {
+	keyID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid API key ID")
+		return nil, false
+	}
+
+	apiKey, err := h.apiKeyRepo.GetByID(r.Context(), keyID)
+	if err != nil {
+		h.sendError(w, http.StatusNotFound, "API key not found")
+		return nil, false
+	}
+	if apiKey.UserID != userID {
+		h.sendError(w, http.StatusForbidden, "Access denied")
+		return nil, false
+	}
+	return apiKey, true
+}
+
+func (h *APIKeyHandler) userID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userIDStr, _ := r.Context().Value(middleware.UserIDKey).(string)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		h.sendError(w, http.StatusUnauthorized, "Invalid user ID")
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+func newCreateAPIKeyResponse(apiKey *models.APIKey, plainKey string) CreateAPIKeyResponse {
+	return CreateAPIKeyResponse{
+		ID:          apiKey.ID.String(),
+		Name:        apiKey.Name,
+		Key:         plainKey, // Return the plain key only on creation
+		CreatedAt:   apiKey.CreatedAt,
+		ExpiresAt:   apiKey.ExpiresAt,
+		Scopes:      apiKey.Scopes,
+		WorkspaceID: apiKey.WorkspaceID,
+		AllowedIPs:  apiKey.AllowedIPs,
+	}
+}
+
 func (h *APIKeyHandler) sendJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -171,4 +331,4 @@ func (h *APIKeyHandler) sendJSON(w http.ResponseWriter, status int, data interfa
 
 func (h *APIKeyHandler) sendError(w http.ResponseWriter, status int, message string) {
 	h.sendJSON(w, status, map[string]string{"error": message})
-}
\ No newline at end of file
+}
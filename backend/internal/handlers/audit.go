@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net"
 	"net/http"
 	"strconv"
@@ -10,19 +12,23 @@ import (
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	
+	"github.com/gridmate/backend/internal/middleware"
 	"github.com/gridmate/backend/internal/models"
 	"github.com/gridmate/backend/internal/repository"
+	"github.com/gridmate/backend/internal/services/audit"
 )
 
 type AuditHandler struct {
-	repos  *repository.Repositories
-	logger *logrus.Logger
+	repos        *repository.Repositories
+	auditService *audit.Service
+	logger       *logrus.Logger
 }
 
-func NewAuditHandler(repos *repository.Repositories, logger *logrus.Logger) *AuditHandler {
+func NewAuditHandler(repos *repository.Repositories, auditService *audit.Service, logger *logrus.Logger) *AuditHandler {
 	return &AuditHandler{
-		repos:  repos,
-		logger: logger,
+		repos:        repos,
+		auditService: auditService,
+		logger:       logger,
 	}
 }
 
@@ -51,6 +57,8 @@ type AuditLog struct {
 	ID          string                 `json:"id"`
 	UserID      string                 `json:"user_id"`
 	WorkspaceID string                 `json:"workspace_id,omitempty"`
+	Sequence    int64                  `json:"sequence,omitempty"`
+	Hash        string                 `json:"hash,omitempty"`
 	Action      string                 `json:"action"`
 	EntityType  string                 `json:"entity_type"`
 	EntityID    *string                `json:"entity_id,omitempty"`
@@ -63,10 +71,10 @@ type AuditLog struct {
 
 // LogAction records an audit log entry
 func (h *AuditHandler) LogAction(w http.ResponseWriter, r *http.Request) {
-	userIDStr := r.Context().Value("user_id").(string)
+	userIDStr, _ := r.Context().Value(middleware.UserIDKey).(string)
 	userID, err := uuid.Parse(userIDStr)
 	if err != nil {
-		h.sendError(w, http.StatusBadRequest, "Invalid user ID")
+		h.sendError(w, http.StatusUnauthorized, "Invalid user ID")
 		return
 	}
 	
@@ -86,9 +94,6 @@ func (h *AuditHandler) LogAction(w http.ResponseWriter, r *http.Request) {
 	}
 	userAgent := r.Header.Get("User-Agent")
 	
-	// Note: WorkspaceID is in the request but not in the AuditLog model
-	// This might be a future feature
-	
 	// Parse IP address
 	var ipAddr *net.IP
 	if ipAddress != "" {
@@ -105,7 +110,29 @@ func (h *AuditHandler) LogAction(w http.ResponseWriter, r *http.Request) {
 		Changes:    nil, // Will be set below after conversion
 		IPAddress:  ipAddr,
 		UserAgent:  &userAgent,
-		CreatedAt:  time.Now(),
+	}
+	
+	// A workspace-bound API key only adds entries to its workspace
+	if boundWorkspace, ok := middleware.APIKeyWorkspace(r.Context()); ok {
+		if req.WorkspaceID != "" && req.WorkspaceID != boundWorkspace.String() {
+			h.sendError(w, http.StatusForbidden, "API key is bound to another workspace")
+			return
+		}
+		req.WorkspaceID = boundWorkspace.String()
+	}
+
+	// Entries of a workspace join its chain, so only members may add them
+	if req.WorkspaceID != "" {
+		workspaceID, err := uuid.Parse(req.WorkspaceID)
+		if err != nil {
+			h.sendError(w, http.StatusBadRequest, "Invalid workspace ID")
+			return
+		}
+		if member, err := h.repos.Workspaces.IsMember(r.Context(), workspaceID, userID); err != nil || !member {
+			h.sendError(w, http.StatusForbidden, "Not a member of the workspace")
+			return
+		}
+		auditLog.WorkspaceID = &workspaceID
 	}
 	
 	// Parse EntityID if provided
@@ -148,10 +175,10 @@ func (h *AuditHandler) LogAction(w http.ResponseWriter, r *http.Request) {
 
 // GetLogs retrieves audit logs with pagination
 func (h *AuditHandler) GetLogs(w http.ResponseWriter, r *http.Request) {
-	userIDStr := r.Context().Value("user_id").(string)
+	userIDStr, _ := r.Context().Value(middleware.UserIDKey).(string)
 	userID, err := uuid.Parse(userIDStr)
 	if err != nil {
-		h.sendError(w, http.StatusBadRequest, "Invalid user ID")
+		h.sendError(w, http.StatusUnauthorized, "Invalid user ID")
 		return
 	}
 	
@@ -166,23 +193,18 @@ func (h *AuditHandler) GetLogs(w http.ResponseWriter, r *http.Request) {
 		pageSize = 20
 	}
 	
-	// workspaceIDStr := r.URL.Query().Get("workspace_id") // Not used in current model
-	entityType := r.URL.Query().Get("entity_type")
-	action := r.URL.Query().Get("action")
-	
 	// Build filter
-	filter := &models.AuditLogFilter{
-		UserID:     &userID,
-		Limit:      pageSize,
-		Offset:     (page - 1) * pageSize,
-	}
-	
-	if entityType != "" {
-		filter.EntityType = &entityType
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, err.Error())
+		return
 	}
-	if action != "" {
-		filter.Action = &action
+	filter.UserID = &userID
+	if boundWorkspace, ok := middleware.APIKeyWorkspace(r.Context()); ok {
+		filter.WorkspaceID = &boundWorkspace
 	}
+	filter.Limit = pageSize
+	filter.Offset = (page - 1) * pageSize
 	
 	// Get logs and count separately
 	logs, err := h.repos.AuditLogs.List(r.Context(), filter)
@@ -204,6 +226,8 @@ func (h *AuditHandler) GetLogs(w http.ResponseWriter, r *http.Request) {
 	for i, log := range logs {
 		auditLog := AuditLog{
 			ID:        log.ID.String(),
+			Sequence:  log.Sequence,
+			Hash:      log.Hash,
 			Action:    log.Action,
 			Metadata:  nil, // Will be set below after parsing
 			CreatedAt: log.CreatedAt,
@@ -221,6 +245,9 @@ func (h *AuditHandler) GetLogs(w http.ResponseWriter, r *http.Request) {
 		if log.UserID != nil {
 			auditLog.UserID = log.UserID.String()
 		}
+		if log.WorkspaceID != nil {
+			auditLog.WorkspaceID = log.WorkspaceID.String()
+		}
 		if log.EntityType != nil {
 			auditLog.EntityType = *log.EntityType
 		}
@@ -247,6 +274,140 @@ func (h *AuditHandler) GetLogs(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// Verify checks the hash chain of one workspace, or of every chain when no
+// workspace_id is given
+func (h *AuditHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("workspace_id") == "" {
+		results, err := h.auditService.VerifyAll(r.Context())
+		if err != nil {
+			h.logger.WithError(err).Error("Failed to verify audit log")
+			h.sendError(w, http.StatusInternalServerError, "Failed to verify audit log")
+			return
+		}
+		h.sendJSON(w, http.StatusOK, results)
+		return
+	}
+
+	workspaceID, err := uuid.Parse(r.URL.Query().Get("workspace_id"))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid workspace ID")
+		return
+	}
+	result, err := h.auditService.Verify(r.Context(), &workspaceID)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to verify audit log")
+		h.sendError(w, http.StatusInternalServerError, "Failed to verify audit log")
+		return
+	}
+	h.sendJSON(w, http.StatusOK, result)
+}
+
+// Export streams the entries between start_date and end_date as CSV or
+// JSON lines
+func (h *AuditHandler) Export(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.StartDate == nil || filter.EndDate == nil {
+		h.sendError(w, http.StatusBadRequest, "start_date and end_date are required")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = audit.FormatCSV
+	}
+	contentType := map[string]string{
+		audit.FormatCSV:   "text/csv",
+		audit.FormatJSONL: "application/x-ndjson",
+	}[format]
+	if contentType == "" {
+		h.sendError(w, http.StatusBadRequest, "format must be csv or jsonl")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="audit-%s-%s.%s"`,
+		filter.StartDate.Format("20060102"), filter.EndDate.Format("20060102"), format))
+	if err := h.auditService.Export(r.Context(), w, format, filter); err != nil {
+		// Headers are already sent, so the export just ends early
+		h.logger.WithError(err).Error("Failed to export audit log")
+	}
+}
+
+// ApplyRetention prunes entries past their workspace's retention now
+// instead of waiting for the scheduled run
+func (h *AuditHandler) ApplyRetention(w http.ResponseWriter, r *http.Request) {
+	pruned, err := h.auditService.ApplyRetention(r.Context())
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to apply audit log retention")
+		h.sendError(w, http.StatusInternalServerError, "Failed to apply retention")
+		return
+	}
+	if pruned == nil {
+		pruned = []audit.Pruned{}
+	}
+	h.sendJSON(w, http.StatusOK, pruned)
+}
+
+// parseAuditFilter reads the workspace_id, entity_type, entity_id, action,
+// start_date and end_date query parameters. Dates are RFC 3339 timestamps or
+// days, an end day including the whole day.
+func parseAuditFilter(r *http.Request) (*models.AuditLogFilter, error) {
+	query := r.URL.Query()
+	filter := &models.AuditLogFilter{}
+
+	if v := query.Get("workspace_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return nil, errors.New("Invalid workspace ID")
+		}
+		filter.WorkspaceID = &id
+	}
+	if v := query.Get("entity_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return nil, errors.New("Invalid entity ID")
+		}
+		filter.EntityID = &id
+	}
+	if v := query.Get("entity_type"); v != "" {
+		filter.EntityType = &v
+	}
+	if v := query.Get("action"); v != "" {
+		filter.Action = &v
+	}
+
+	for _, p := range []struct {
+		name  string
+		dest  **time.Time
+		isEnd bool
+	}{
+		{"start_date", &filter.StartDate, false},
+		{"end_date", &filter.EndDate, true},
+	} {
+		v := query.Get(p.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			day, dayErr := time.Parse("2006-01-02", v)
+			if dayErr != nil {
+				return nil, fmt.Errorf("Invalid %s", p.name)
+			}
+			t = day
+			if p.isEnd {
+				t = day.AddDate(0, 0, 1).Add(-time.Microsecond)
+			}
+		}
+		*p.dest = &t
+	}
+	return filter, nil
+}
+
 func (h *AuditHandler) sendJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/gridmate/backend/internal/middleware"
+	"github.com/gridmate/backend/internal/services/ai"
+)
+
+// CellLineageHandler explains where the AI-written cells of a workbook came
+// from
+type CellLineageHandler struct {
+	store  ai.LineageStore
+	logger *logrus.Logger
+}
+
+func NewCellLineageHandler(store ai.LineageStore, logger *logrus.Logger) *CellLineageHandler {
+	return &CellLineageHandler{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// CellHistory returns the recorded AI changes to the cell given by the
+// cell query parameter, such as Valuation!D15, newest first
+func (h *CellLineageHandler) CellHistory(w http.ResponseWriter, r *http.Request) {
+	userIDStr, _ := r.Context().Value(middleware.UserIDKey).(string)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		h.sendError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	cell := r.URL.Query().Get("cell")
+	if cell == "" {
+		h.sendError(w, http.StatusBadRequest, "cell is required")
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	workbook := mux.Vars(r)["workbook"]
+	history, err := ai.ExplainCell(r.Context(), h.store, userID.String(), workbook, cell, "", limit)
+	if errors.Is(err, ai.ErrInvalidCell) {
+		h.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get cell history")
+		h.sendError(w, http.StatusInternalServerError, "Failed to get cell history")
+		return
+	}
+	if history == nil {
+		history = []*ai.LineageRecord{}
+	}
+
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"workbook": workbook,
+		"cell":     cell,
+		"history":  history,
+	})
+}
+
+func (h *CellLineageHandler) sendJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *CellLineageHandler) sendError(w http.ResponseWriter, status int, message string) {
+	h.sendJSON(w, status, map[string]string{"error": message})
+}
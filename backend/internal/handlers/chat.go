@@ -8,6 +8,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/gridmate/backend/internal/services"
+	"github.com/gridmate/backend/internal/services/ai"
 	"github.com/gridmate/backend/internal/services/documents"
 )
 
@@ -56,6 +57,7 @@ type ChatResponse struct {
 	Suggestions  []string                  `json:"suggestions,omitempty"`
 	Actions      []services.ProposedAction `json:"actions,omitempty"`
 	DocumentRefs []DocumentReference       `json:"document_refs,omitempty"`
+	Citations    []ai.Citation             `json:"citations,omitempty"`
 	SessionID    string                    `json:"session_id"`
 }
 
@@ -162,6 +164,7 @@ func (h *ChatHandler) Chat(w http.ResponseWriter, r *http.Request) {
 		Suggestions:  response.Suggestions,
 		Actions:      response.Actions,
 		DocumentRefs: documentRefs,
+		Citations:    response.Citations,
 		SessionID:    response.SessionID,
 	}
 
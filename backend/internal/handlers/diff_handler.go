@@ -15,9 +15,15 @@ import (
 type DiffHandler struct {
 	diffService   diff.Service
 	signalRBridge *SignalRBridge
+	reindexer     cellReindexer
 	logger        *logrus.Logger
 }
 
+// cellReindexer refreshes memory for edited cells
+type cellReindexer interface {
+	ReindexChangedCells(sessionID string, cells []string)
+}
+
 // NewDiffHandler creates a new diff handler
 func NewDiffHandler(diffService diff.Service, signalRBridge *SignalRBridge, logger *logrus.Logger) *DiffHandler {
 	return &DiffHandler{
@@ -27,6 +33,11 @@ func NewDiffHandler(diffService diff.Service, signalRBridge *SignalRBridge, logg
 	}
 }
 
+// SetReindexer enables memory re-indexing of cells reported by diffs
+func (h *DiffHandler) SetReindexer(reindexer cellReindexer) {
+	h.reindexer = reindexer
+}
+
 // ComputeDiff handles diff computation requests
 func (h *DiffHandler) ComputeDiff(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -90,6 +101,17 @@ func (h *DiffHandler) ComputeDiff(w http.ResponseWriter, r *http.Request) {
 		// Continue anyway - the diff was computed successfully
 	}
 
+	// Keep session memory current with the edited cells
+	if h.reindexer != nil && payload.SessionID != "" && len(hunks) > 0 {
+		cells := make([]string, 0, len(hunks))
+		for _, hunk := range hunks {
+			if hunk.Kind != models.StyleChanged {
+				cells = append(cells, hunk.Key.Address())
+			}
+		}
+		go h.reindexer.ReindexChangedCells(payload.SessionID, cells)
+	}
+
 	// Respond to the original request
 	h.sendJSON(w, http.StatusOK, map[string]interface{}{
 		"success":     true,
@@ -4,11 +4,14 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 	
+	"github.com/gridmate/backend/internal/middleware"
+	"github.com/gridmate/backend/internal/models"
 	"github.com/gridmate/backend/internal/services/documents"
 )
 
@@ -138,6 +141,74 @@ func (h *DocumentHandler) GetDocumentContext(w http.ResponseWriter, r *http.Requ
 	h.sendJSON(w, http.StatusOK, context)
 }
 
+// SearchFacts searches XBRL facts by concept and period.
+// Query params: concept, document_id, period_type, period_end_from, period_end_to
+// (YYYY-MM-DD), include_dimensional, limit, offset.
+func (h *DocumentHandler) SearchFacts(w http.ResponseWriter, r *http.Request) {
+	userIDStr, _ := r.Context().Value(middleware.UserIDKey).(string)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		h.sendError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	q := r.URL.Query()
+	filter := &models.FinancialFactFilter{
+		UserID:             userID,
+		IncludeDimensional: q.Get("include_dimensional") == "true",
+	}
+
+	if concept := q.Get("concept"); concept != "" {
+		filter.Concept = &concept
+	}
+	if periodType := q.Get("period_type"); periodType != "" {
+		filter.PeriodType = &periodType
+	}
+	if docIDStr := q.Get("document_id"); docIDStr != "" {
+		docID, err := uuid.Parse(docIDStr)
+		if err != nil {
+			h.sendError(w, http.StatusBadRequest, "Invalid document ID")
+			return
+		}
+		filter.DocumentID = &docID
+	}
+	for param, target := range map[string]**time.Time{
+		"period_end_from": &filter.PeriodEndFrom,
+		"period_end_to":   &filter.PeriodEndTo,
+	} {
+		if value := q.Get(param); value != "" {
+			date, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				h.sendError(w, http.StatusBadRequest, "Invalid "+param+", expected YYYY-MM-DD")
+				return
+			}
+			*target = &date
+		}
+	}
+	if limitStr := q.Get("limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 {
+			filter.Limit = parsed
+		}
+	}
+	if offsetStr := q.Get("offset"); offsetStr != "" {
+		if parsed, err := strconv.Atoi(offsetStr); err == nil && parsed > 0 {
+			filter.Offset = parsed
+		}
+	}
+
+	facts, err := h.docService.SearchFacts(r.Context(), filter)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to search financial facts")
+		h.sendError(w, http.StatusInternalServerError, "Failed to search financial facts")
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"facts": facts,
+		"count": len(facts),
+	})
+}
+
 // ListDocuments lists recent documents for a user
 func (h *DocumentHandler) ListDocuments(w http.ResponseWriter, r *http.Request) {
 	userIDStr := r.Context().Value("user_id").(string)
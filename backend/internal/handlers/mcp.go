@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gridmate/backend/internal/mcp"
+	"github.com/gridmate/backend/internal/middleware"
+	"github.com/gridmate/backend/internal/services"
+	"github.com/sirupsen/logrus"
+)
+
+// NewMCPHandler serves the Model Context Protocol over streamable HTTP so
+// other agent clients can drive Excel sessions connected to this server
+func NewMCPHandler(excelBridge *services.ExcelBridge, logger *logrus.Logger) http.Handler {
+	opts := []mcp.Option{
+		// Clients may only drive the Excel sessions of the user they
+		// authenticated as
+		mcp.WithSessionAuthorizer(func(ctx context.Context, sessionID string) error {
+			session := excelBridge.GetSession(sessionID)
+			if session == nil {
+				return fmt.Errorf("session not found: %s", sessionID)
+			}
+			userID, _ := ctx.Value(middleware.UserIDKey).(string)
+			if userID == "" || session.UserID != userID {
+				return fmt.Errorf("%w: %s", mcp.ErrForbidden, sessionID)
+			}
+			return nil
+		}),
+	}
+	if contextBuilder := excelBridge.GetContextBuilder(); contextBuilder != nil {
+		opts = append(opts, mcp.WithWorkbookSource(contextBuilder))
+	}
+
+	server := mcp.NewServer(excelBridge.GetToolExecutor(), opts...)
+	logger.Info("MCP server enabled")
+
+	return mcp.NewHTTPHandler(server, func(r *http.Request) string {
+		userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+		return userID
+	})
+}
@@ -2,11 +2,13 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/gridmate/backend/internal/memory"
 	"github.com/gridmate/backend/internal/services"
 	"github.com/gridmate/backend/internal/services/indexing"
 	"github.com/sirupsen/logrus"
@@ -38,16 +40,22 @@ func (h *MemoryHandler) GetMemoryStats(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	stats := (*session.MemoryStore).GetStats()
+	stats := (*session.MemoryStore).NamespaceStats(session.MemoryNamespace)
 
 	response := map[string]interface{}{
 		"totalChunks":       stats.TotalChunks,
 		"spreadsheetChunks": stats.SpreadsheetChunks,
 		"documentChunks":    stats.DocumentChunks,
 		"chatChunks":        stats.ChatChunks,
+		"quota":             stats.Quota,
 		"lastIndexed":       session.MemoryStats.LastIndexed,
 		"indexVersion":      session.MemoryStats.IndexVersion,
 	}
+	if h.indexingService != nil {
+		if cacheStats, ok := h.indexingService.EmbeddingCacheStats(); ok {
+			response["embeddingCache"] = cacheStats
+		}
+	}
 
 	h.sendJSON(w, response)
 }
@@ -73,7 +81,7 @@ func (h *MemoryHandler) UploadDocument(w http.ResponseWriter, r *http.Request) {
 	// Start indexing in background
 	go h.indexingService.IndexDocument(
 		r.Context(),
-		sessionID,
+		session.MemoryNamespace,
 		file,
 		header.Filename,
 		*session.MemoryStore,
@@ -99,6 +107,26 @@ func (h *MemoryHandler) ReindexWorkbook(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Edited ranges only refresh the chunks that cover them
+	var request struct {
+		Ranges []string `json:"ranges"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			h.sendError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+	if len(request.Ranges) > 0 {
+		go h.excelBridge.ReindexChangedCells(sessionID, request.Ranges)
+		h.sendJSON(w, map[string]interface{}{
+			"status": "started",
+			"mode":   "incremental",
+			"ranges": request.Ranges,
+		})
+		return
+	}
+
 	// Get current workbook data
 	// TODO: Implement GetWorkbookData method on ExcelBridge
 	// For now, we'll just update the stats
@@ -148,6 +176,10 @@ func (h *MemoryHandler) SearchMemory(w http.ResponseWriter, r *http.Request) {
 		h.sendError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
+	if request.Query == "" {
+		h.sendError(w, http.StatusBadRequest, "Query is required")
+		return
+	}
 
 	session := h.excelBridge.GetSession(request.SessionID)
 	if session == nil || session.MemoryStore == nil {
@@ -158,21 +190,63 @@ func (h *MemoryHandler) SearchMemory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// For now, return sample results
-	// Full implementation would use the embedding service to search
-	results := []map[string]interface{}{
-		{
-			"source":     "spreadsheet",
-			"content":    "Revenue projections for Q1-Q4 2024",
-			"similarity": 0.92,
-			"reference":  "Sheet1!A1:E20",
-		},
-		{
-			"source":     "document",
-			"content":    "Market analysis indicates 15% growth potential",
-			"similarity": 0.87,
-			"reference":  "MarketAnalysis.pdf, page 5",
-		},
+	limit := request.Limit
+	if limit <= 0 {
+		limit = 5
+	} else if limit > 20 {
+		limit = 20
+	}
+
+	var filter memory.FilterFunc
+	if request.SourceFilter != "" && request.SourceFilter != "all" {
+		filter = func(chunk memory.Chunk) bool {
+			return chunk.Metadata.Source == request.SourceFilter
+		}
+	}
+
+	// Without an embedding provider the retriever ranks by BM25 alone
+	var queryVector []float32
+	if h.indexingService != nil {
+		vector, err := h.indexingService.EmbedQuery(r.Context(), request.Query)
+		if err != nil {
+			h.logger.WithError(err).Warn("Failed to embed memory query, using keyword retrieval only")
+		} else {
+			queryVector = vector
+		}
+	}
+
+	searchResults, err := memory.NewRetriever().Search(*session.MemoryStore, session.MemoryNamespace, request.Query, queryVector, limit, filter)
+	if err != nil {
+		h.logger.WithError(err).Error("Memory search failed")
+		h.sendError(w, http.StatusInternalServerError, "Memory search failed")
+		return
+	}
+
+	results := make([]map[string]interface{}, 0, len(searchResults))
+	for _, result := range searchResults {
+		meta := result.Chunk.Metadata
+		entry := map[string]interface{}{
+			"id":         result.Chunk.ID,
+			"source":     meta.Source,
+			"content":    result.Chunk.Content,
+			"similarity": result.Similarity,
+			"score":      result.Score,
+		}
+		switch meta.Source {
+		case "spreadsheet":
+			entry["reference"] = meta.SheetName
+			if meta.CellRange != "" {
+				entry["reference"] = meta.SheetName + "!" + meta.CellRange
+			}
+		case "document":
+			entry["reference"] = meta.DocumentName
+			if meta.PageNumber > 0 {
+				entry["reference"] = fmt.Sprintf("%s, page %d", meta.DocumentName, meta.PageNumber)
+			}
+		case "chat":
+			entry["reference"] = fmt.Sprintf("Chat turn %d", meta.Turn)
+		}
+		results = append(results, entry)
 	}
 
 	h.sendJSON(w, map[string]interface{}{
@@ -182,6 +256,28 @@ func (h *MemoryHandler) SearchMemory(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// PurgeNamespace deletes a tenant's memory, or one of its sessions when the
+// session query parameter is set. Admin only.
+func (h *MemoryHandler) PurgeNamespace(w http.ResponseWriter, r *http.Request) {
+	ns := memory.SessionNamespace(mux.Vars(r)["tenant"], r.URL.Query().Get("session"))
+	if err := ns.Validate(); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid namespace")
+		return
+	}
+
+	removed, err := h.excelBridge.PurgeMemory(ns)
+	if err != nil {
+		h.logger.WithError(err).WithField("namespace", ns.String()).Error("Failed to purge memory namespace")
+		h.sendError(w, http.StatusInternalServerError, "Failed to purge namespace")
+		return
+	}
+
+	h.sendJSON(w, map[string]interface{}{
+		"namespace":     ns.String(),
+		"chunksRemoved": removed,
+	})
+}
+
 // Helper methods
 
 func (h *MemoryHandler) sendJSON(w http.ResponseWriter, data interface{}) {
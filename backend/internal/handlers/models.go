@@ -2,132 +2,255 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
-	
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
+
+	"github.com/gridmate/backend/internal/middleware"
+	"github.com/gridmate/backend/internal/models"
+	"github.com/gridmate/backend/internal/services/templates"
 )
 
 type ModelsHandler struct {
-	logger *logrus.Logger
+	templateService *templates.Service
+	logger          *logrus.Logger
 }
 
-func NewModelsHandler(logger *logrus.Logger) *ModelsHandler {
+func NewModelsHandler(templateService *templates.Service, logger *logrus.Logger) *ModelsHandler {
 	return &ModelsHandler{
-		logger: logger,
-	}
-}
-
-type ModelTemplate struct {
-	ID          string                 `json:"id"`
-	Name        string                 `json:"name"`
-	Category    string                 `json:"category"`
-	Description string                 `json:"description"`
-	Structure   map[string]interface{} `json:"structure"`
-	Formulas    map[string]string      `json:"formulas"`
-	Assumptions map[string]interface{} `json:"assumptions"`
-}
-
-var defaultTemplates = []ModelTemplate{
-	{
-		ID:          "dcf-basic",
-		Name:        "DCF Model - Basic",
-		Category:    "Valuation",
-		Description: "Basic Discounted Cash Flow model with revenue projections and WACC calculation",
-		Structure: map[string]interface{}{
-			"sheets": []string{"Assumptions", "Revenue", "Costs", "FCF", "Valuation"},
-		},
-		Formulas: map[string]string{
-			"fcf":           "=EBIT*(1-TaxRate)+Depreciation-CapEx-ChangeInNWC",
-			"terminalValue": "=FCF_LastYear*(1+TerminalGrowth)/(WACC-TerminalGrowth)",
-			"npv":           "=NPV(WACC,FCF_Range)+TerminalValue/(1+WACC)^Years",
-		},
-		Assumptions: map[string]interface{}{
-			"wacc":           0.10,
-			"terminalGrowth": 0.025,
-			"taxRate":        0.21,
-		},
-	},
-	{
-		ID:          "lbo-basic",
-		Name:        "LBO Model - Basic",
-		Category:    "Private Equity",
-		Description: "Basic Leveraged Buyout model with debt schedule and returns analysis",
-		Structure: map[string]interface{}{
-			"sheets": []string{"Assumptions", "Sources & Uses", "OpModel", "DebtSchedule", "Returns"},
-		},
-		Formulas: map[string]string{
-			"debtPaydown":    "=MIN(CashAvailable,BeginningDebt)",
-			"exitEquity":     "=ExitEV-NetDebt",
-			"moic":           "=ExitEquity/InitialEquity",
-			"irr":            "=IRR(CashFlows)",
-		},
-		Assumptions: map[string]interface{}{
-			"entryMultiple": 10.0,
-			"exitMultiple":  12.0,
-			"debtMultiple":  5.0,
-			"holdPeriod":    5,
-		},
-	},
-	{
-		ID:          "comps-analysis",
-		Name:        "Trading Comps Analysis",
-		Category:    "Valuation",
-		Description: "Trading comparables analysis with peer benchmarking",
-		Structure: map[string]interface{}{
-			"sheets": []string{"CompanyData", "Multiples", "Benchmarking", "Summary"},
-		},
-		Formulas: map[string]string{
-			"evToEbitda":     "=EnterpriseValue/EBITDA",
-			"peRatio":        "=Price/EPS",
-			"evToRevenue":    "=EnterpriseValue/Revenue",
-			"medianMultiple": "=MEDIAN(MultiplesRange)",
-		},
-		Assumptions: map[string]interface{}{
-			"outlierThreshold": 2.0,
-			"sectorFilter":     true,
-		},
-	},
-}
-
-// GetTemplates returns available financial model templates
+		templateService: templateService,
+		logger:          logger,
+	}
+}
+
+// captureTemplateRequest allows the workbook to be posted directly instead of
+// being read from a live session
+type captureTemplateRequest struct {
+	models.CaptureTemplateRequest
+	Workbook *models.Workbook `json:"workbook,omitempty"`
+}
+
+// GetTemplates returns available financial model templates. Built-in templates
+// are always listed; workspace templates are added when workspace_id is set.
 func (h *ModelsHandler) GetTemplates(w http.ResponseWriter, r *http.Request) {
-	category := r.URL.Query().Get("category")
-	
-	var templates []ModelTemplate
-	if category != "" {
-		for _, tmpl := range defaultTemplates {
-			if tmpl.Category == category {
-				templates = append(templates, tmpl)
-			}
+	userID, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	workspaceID := uuid.Nil
+	if ws := r.URL.Query().Get("workspace_id"); ws != "" {
+		parsed, err := uuid.Parse(ws)
+		if err != nil {
+			h.sendError(w, http.StatusBadRequest, "Invalid workspace ID")
+			return
 		}
-	} else {
-		templates = defaultTemplates
+		workspaceID = parsed
 	}
-	
-	h.logger.WithField("count", len(templates)).Info("Returning model templates")
-	
+
+	category := r.URL.Query().Get("category")
+	includeDeprecated := r.URL.Query().Get("include_deprecated") == "true"
+
+	list, err := h.templateService.List(r.Context(), userID, workspaceID, category, includeDeprecated)
+	if err != nil {
+		h.handleError(w, err, "Failed to list templates")
+		return
+	}
+
+	h.logger.WithField("count", len(list)).Info("Returning model templates")
+
 	h.sendJSON(w, http.StatusOK, map[string]interface{}{
-		"templates": templates,
-		"count":     len(templates),
+		"templates": list,
+		"count":     len(list),
 	})
 }
 
-// GetTemplate returns a specific template by ID
+// GetTemplate returns a specific template by ID, from either the id query
+// parameter or the path
 func (h *ModelsHandler) GetTemplate(w http.ResponseWriter, r *http.Request) {
-	templateID := r.URL.Query().Get("id")
+	userID, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	templateID := mux.Vars(r)["id"]
+	if templateID == "" {
+		templateID = r.URL.Query().Get("id")
+	}
 	if templateID == "" {
 		h.sendError(w, http.StatusBadRequest, "Template ID required")
 		return
 	}
-	
-	for _, tmpl := range defaultTemplates {
-		if tmpl.ID == templateID {
-			h.sendJSON(w, http.StatusOK, tmpl)
-			return
-		}
+
+	tmpl, err := h.templateService.Get(r.Context(), userID, templateID)
+	if err != nil {
+		h.handleError(w, err, "Failed to get template")
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, tmpl)
+}
+
+// GetTemplateSchema returns the JSON schema for template definitions
+func (h *ModelsHandler) GetTemplateSchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(templates.DefinitionSchema))
+}
+
+// CreateTemplate adds a new template to a workspace
+func (h *ModelsHandler) CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CreateModelTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	tmpl, err := h.templateService.Create(r.Context(), userID, &req)
+	if err != nil {
+		h.handleError(w, err, "Failed to create template")
+		return
+	}
+
+	h.sendJSON(w, http.StatusCreated, tmpl)
+}
+
+// UpdateTemplate edits a template version in place
+func (h *ModelsHandler) UpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateModelTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	tmpl, err := h.templateService.Update(r.Context(), userID, mux.Vars(r)["id"], &req)
+	if err != nil {
+		h.handleError(w, err, "Failed to update template")
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, tmpl)
+}
+
+// CreateTemplateVersion publishes a new version of a template
+func (h *ModelsHandler) CreateTemplateVersion(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CreateTemplateVersionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	tmpl, err := h.templateService.CreateVersion(r.Context(), userID, mux.Vars(r)["id"], &req)
+	if err != nil {
+		h.handleError(w, err, "Failed to create template version")
+		return
+	}
+
+	h.sendJSON(w, http.StatusCreated, tmpl)
+}
+
+// ListTemplateVersions returns the version history of a template
+func (h *ModelsHandler) ListTemplateVersions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	versions, err := h.templateService.ListVersions(r.Context(), userID, mux.Vars(r)["id"])
+	if err != nil {
+		h.handleError(w, err, "Failed to list template versions")
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"versions": versions,
+		"count":    len(versions),
+	})
+}
+
+// DeprecateTemplate marks a template version as deprecated
+func (h *ModelsHandler) DeprecateTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.templateService.Deprecate(r.Context(), userID, mux.Vars(r)["id"]); err != nil {
+		h.handleError(w, err, "Failed to deprecate template")
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, map[string]string{"message": "Template deprecated"})
+}
+
+// CaptureTemplate creates a template from an open workbook session or a
+// posted workbook snapshot
+func (h *ModelsHandler) CaptureTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	var req captureTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	tmpl, err := h.templateService.Capture(r.Context(), userID, &req.CaptureTemplateRequest, req.Workbook)
+	if err != nil {
+		h.handleError(w, err, "Failed to capture template")
+		return
+	}
+
+	h.sendJSON(w, http.StatusCreated, tmpl)
+}
+
+func (h *ModelsHandler) userID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userIDStr, _ := r.Context().Value(middleware.UserIDKey).(string)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		h.sendError(w, http.StatusUnauthorized, "Invalid user ID")
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// handleError maps template service errors to HTTP responses
+func (h *ModelsHandler) handleError(w http.ResponseWriter, err error, message string) {
+	var verr *templates.ValidationError
+	switch {
+	case errors.As(err, &verr):
+		h.sendJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":  "Invalid template definition",
+			"fields": verr.Errors,
+		})
+	case errors.Is(err, templates.ErrTemplateNotFound):
+		h.sendError(w, http.StatusNotFound, "Template not found")
+	case errors.Is(err, templates.ErrForbidden), errors.Is(err, templates.ErrBuiltinReadOnly):
+		h.sendError(w, http.StatusForbidden, err.Error())
+	default:
+		h.logger.WithError(err).Error(message)
+		h.sendError(w, http.StatusInternalServerError, message)
 	}
-	
-	h.sendError(w, http.StatusNotFound, "Template not found")
 }
 
 func (h *ModelsHandler) sendJSON(w http.ResponseWriter, status int, data interface{}) {
@@ -138,4 +261,4 @@ func (h *ModelsHandler) sendJSON(w http.ResponseWriter, status int, data interfa
 
 func (h *ModelsHandler) sendError(w http.ResponseWriter, status int, message string) {
 	h.sendJSON(w, status, map[string]string{"error": message})
-}
\ No newline at end of file
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/gridmate/backend/internal/middleware"
+	"github.com/gridmate/backend/internal/models"
+	"github.com/gridmate/backend/internal/repository"
+	"github.com/gridmate/backend/internal/services"
+	"github.com/gridmate/backend/internal/services/ai"
+)
+
+// ProtectedRangeHandler manages the cells of a workspace's workbooks the
+// AI must never modify
+type ProtectedRangeHandler struct {
+	store  *services.ProtectedRangeStore
+	logger *logrus.Logger
+}
+
+func NewProtectedRangeHandler(store *services.ProtectedRangeStore, logger *logrus.Logger) *ProtectedRangeHandler {
+	return &ProtectedRangeHandler{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// List returns the protected ranges a workspace has on a workbook
+func (h *ProtectedRangeHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	workspaceID, err := uuid.Parse(r.URL.Query().Get("workspace_id"))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "workspace_id is required")
+		return
+	}
+
+	ranges, err := h.store.List(r.Context(), userID, workspaceID, mux.Vars(r)["workbook"])
+	if err != nil {
+		h.handleError(w, err, "Failed to list protected ranges")
+		return
+	}
+	if ranges == nil {
+		ranges = []*models.ProtectedRange{}
+	}
+
+	h.sendJSON(w, http.StatusOK, ranges)
+}
+
+// Create protects an address, named range or sheet of a workspace's workbook
+func (h *ProtectedRangeHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CreateProtectedRangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.WorkspaceID == uuid.Nil {
+		h.sendError(w, http.StatusBadRequest, "workspace_id is required")
+		return
+	}
+	req.Target = strings.TrimSpace(req.Target)
+	if err := ai.ValidateProtectedRange(req.Kind, req.Target); err != nil {
+		h.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	pr, err := h.store.Create(r.Context(), userID, mux.Vars(r)["workbook"], &req)
+	if err != nil {
+		h.handleError(w, err, "Failed to create protected range")
+		return
+	}
+
+	h.sendJSON(w, http.StatusCreated, pr)
+}
+
+// Delete removes a protection
+func (h *ProtectedRangeHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid protected range ID")
+		return
+	}
+
+	if err := h.store.Delete(r.Context(), userID, id); err != nil {
+		h.handleError(w, err, "Failed to delete protected range")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ProtectedRangeHandler) handleError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrProtectedRangeForbidden):
+		h.sendError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, repository.ErrProtectedRangeNotFound):
+		h.sendError(w, http.StatusNotFound, "Protected range not found")
+	default:
+		h.logger.WithError(err).Error(message)
+		h.sendError(w, http.StatusInternalServerError, message)
+	}
+}
+
+func (h *ProtectedRangeHandler) userID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userIDStr, _ := r.Context().Value(middleware.UserIDKey).(string)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		h.sendError(w, http.StatusUnauthorized, "Invalid user ID")
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+func (h *ProtectedRangeHandler) sendJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *ProtectedRangeHandler) sendError(w http.ResponseWriter, status int, message string) {
+	h.sendJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/gridmate/backend/internal/middleware"
+	"github.com/gridmate/backend/internal/services/review"
+)
+
+// ReviewHandler serves four-eyes review of queued AI changes
+type ReviewHandler struct {
+	reviewService *review.Service
+	logger        *logrus.Logger
+}
+
+func NewReviewHandler(reviewService *review.Service, logger *logrus.Logger) *ReviewHandler {
+	return &ReviewHandler{
+		reviewService: reviewService,
+		logger:        logger,
+	}
+}
+
+// Submit puts a batch of queued operations under review
+func (h *ReviewHandler) Submit(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	var req review.SubmitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	detail, err := h.reviewService.Submit(r.Context(), userID, &req)
+	if err != nil {
+		h.handleError(w, err, "Failed to submit changes for review")
+		return
+	}
+
+	h.sendJSON(w, http.StatusCreated, detail)
+}
+
+// ListAssigned returns the pending reviews assigned to the caller
+func (h *ReviewHandler) ListAssigned(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	details, err := h.reviewService.ListAssigned(r.Context(), userID)
+	if err != nil {
+		h.handleError(w, err, "Failed to list reviews")
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, details)
+}
+
+// Get returns a review with the state of its operations
+func (h *ReviewHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	detail, err := h.reviewService.Get(r.Context(), userID, mux.Vars(r)["id"])
+	if err != nil {
+		h.handleError(w, err, "Failed to get review")
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, detail)
+}
+
+// Decide approves or rejects one operation of a review
+func (h *ReviewHandler) Decide(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	var req review.DecisionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	vars := mux.Vars(r)
+	detail, err := h.reviewService.Decide(r.Context(), userID, vars["id"], vars["operationId"], &req)
+	if err != nil {
+		h.handleError(w, err, "Failed to record review decision")
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, detail)
+}
+
+func (h *ReviewHandler) userID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userIDStr, _ := r.Context().Value(middleware.UserIDKey).(string)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		h.sendError(w, http.StatusUnauthorized, "Invalid user ID")
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// handleError maps review service errors to HTTP responses
+func (h *ReviewHandler) handleError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, review.ErrReviewNotFound):
+		h.sendError(w, http.StatusNotFound, "Review not found")
+	case errors.Is(err, review.ErrForbidden):
+		h.sendError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, review.ErrInvalidReview):
+		h.sendError(w, http.StatusUnprocessableEntity, err.Error())
+	default:
+		h.logger.WithError(err).Error(message)
+		h.sendError(w, http.StatusInternalServerError, message)
+	}
+}
+
+func (h *ReviewHandler) sendJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *ReviewHandler) sendError(w http.ResponseWriter, status int, message string) {
+	h.sendJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/gridmate/backend/internal/auth"
+	"github.com/gridmate/backend/internal/auth/oidc"
+	"github.com/gridmate/backend/internal/models"
+	"github.com/gridmate/backend/internal/repository"
+	"github.com/gridmate/backend/internal/services/sso"
+)
+
+type SSOHandler struct {
+	service     *sso.Service
+	sessionRepo repository.SessionRepository
+	jwtManager  *auth.JWTManager
+	returnURL   string
+	logger      *logrus.Logger
+}
+
+// NewSSOHandler creates the single sign-on endpoints. After sign-in the
+// browser is sent to returnURL with the tokens in the fragment, or the
+// tokens are returned as JSON when returnURL is empty.
+func NewSSOHandler(service *sso.Service, repos *repository.Repositories, jwtManager *auth.JWTManager, returnURL string, logger *logrus.Logger) *SSOHandler {
+	return &SSOHandler{
+		service:     service,
+		sessionRepo: repos.Sessions,
+		jwtManager:  jwtManager,
+		returnURL:   returnURL,
+		logger:      logger,
+	}
+}
+
+// ssoStateCookie binds a sign-in to the browser that started it
+const ssoStateCookie = "gridmate_sso_state"
+
+// Login redirects the browser to the identity provider
+func (h *SSOHandler) Login(w http.ResponseWriter, r *http.Request) {
+	authURL, state, err := h.service.Begin(r.Context())
+	if errors.Is(err, sso.ErrTooManyLogins) {
+		h.sendError(w, http.StatusServiceUnavailable, "Too many sign-ins in progress, try again shortly")
+		return
+	}
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to start single sign-on")
+		h.sendError(w, http.StatusBadGateway, "Identity provider unavailable")
+		return
+	}
+
+	h.setStateCookie(w, r, state, 0)
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// Callback completes the sign-in the identity provider redirected back from
+func (h *SSOHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if providerErr := query.Get("error"); providerErr != "" {
+		h.logger.WithFields(logrus.Fields{
+			"error":       providerErr,
+			"description": query.Get("error_description"),
+		}).Warn("Identity provider rejected sign-in")
+		h.sendError(w, http.StatusUnauthorized, "Sign-in was not completed: "+providerErr)
+		return
+	}
+
+	var browserState string
+	if cookie, err := r.Cookie(ssoStateCookie); err == nil {
+		browserState = cookie.Value
+	}
+	h.setStateCookie(w, r, "", -1)
+
+	login, err := h.service.Complete(r.Context(), query.Get("state"), browserState, query.Get("code"))
+	if err != nil {
+		h.handleError(w, err)
+		return
+	}
+
+	resp, err := h.startSession(r, login.User)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to create session")
+		h.sendError(w, http.StatusInternalServerError, "Failed to create session")
+		return
+	}
+
+	if h.returnURL == "" {
+		h.sendJSON(w, http.StatusOK, resp)
+		return
+	}
+	fragment := url.Values{
+		"access_token":  {resp.AccessToken},
+		"refresh_token": {resp.RefreshToken},
+		"expires_in":    {strconv.Itoa(resp.ExpiresIn)},
+	}
+	http.Redirect(w, r, h.returnURL+"#"+fragment.Encode(), http.StatusFound)
+}
+
+// setStateCookie sets or, with maxAge -1, clears the sign-in state cookie.
+// SameSite=Lax still sends it on the provider's top-level redirect back.
+func (h *SSOHandler) setStateCookie(w http.ResponseWriter, r *http.Request, state string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     ssoStateCookie,
+		Value:    state,
+		Path:     "/api/v1/auth/sso",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// startSession issues tokens for user and records the session
+func (h *SSOHandler) startSession(r *http.Request, user *models.User) (*LoginResponse, error) {
+	accessToken, refreshToken, err := h.jwtManager.GenerateTokenPair(user.ID.String(), user.Email, user.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenHash := auth.HashAPIKey(accessToken)
+	refreshTokenHash := auth.HashAPIKey(refreshToken)
+	expiresAt := time.Now().Add(30 * 24 * time.Hour).Unix() // 30 days
+	if err := h.sessionRepo.Create(r.Context(), user.ID, tokenHash, refreshTokenHash, expiresAt); err != nil {
+		return nil, err
+	}
+
+	resp := &LoginResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(h.jwtManager.GetAccessTokenDuration().Seconds()),
+		User: &UserResponse{
+			ID:        user.ID.String(),
+			Email:     user.Email,
+			CreatedAt: user.CreatedAt,
+		},
+	}
+	if user.FirstName != nil {
+		resp.User.FirstName = *user.FirstName
+	}
+	if user.LastName != nil {
+		resp.User.LastName = *user.LastName
+	}
+	return resp, nil
+}
+
+func (h *SSOHandler) handleError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, sso.ErrInvalidState):
+		h.sendError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, sso.ErrEmailNotVerified), errors.Is(err, sso.ErrNotProvisioned):
+		h.sendError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, oidc.ErrInvalidToken), errors.Is(err, oidc.ErrExchangeFailed):
+		h.logger.WithError(err).Warn("Single sign-on failed")
+		h.sendError(w, http.StatusUnauthorized, "Sign-in failed")
+	default:
+		h.logger.WithError(err).Error("Single sign-on failed")
+		h.sendError(w, http.StatusInternalServerError, "Sign-in failed")
+	}
+}
+
+func (h *SSOHandler) sendJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.WithError(err).Error("Failed to encode response")
+	}
+}
+
+func (h *SSOHandler) sendError(w http.ResponseWriter, status int, message string) {
+	h.sendJSON(w, status, map[string]string{"error": message})
+}
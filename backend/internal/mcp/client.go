@@ -0,0 +1,390 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// defaultCallTimeout applies to servers that configure no timeout
+const defaultCallTimeout = 30 * time.Second
+
+// Client talks to an external MCP server over a subprocess's stdio or the
+// streamable HTTP transport
+type Client struct {
+	name      string
+	transport clientTransport
+	nextID    atomic.Int64
+
+	// Instructions are the server's usage notes from initialize
+	Instructions string
+}
+
+// clientTransport delivers requests to a server and returns its replies
+type clientTransport interface {
+	roundTrip(ctx context.Context, id string, data []byte) (*message, error)
+	notify(ctx context.Context, data []byte) error
+	close() error
+}
+
+// CallToolResponse is a server's answer to a tool call
+type CallToolResponse struct {
+	Content           []ContentItem `json:"content"`
+	StructuredContent interface{}   `json:"structuredContent,omitempty"`
+	IsError           bool          `json:"isError,omitempty"`
+}
+
+// ContentItem is one piece of a tool result. Only text is read; other
+// kinds are summarised by type.
+type ContentItem struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+}
+
+// Connect starts or dials the server described by cfg and initializes an
+// MCP session with it
+func Connect(ctx context.Context, cfg ServerConfig) (*Client, error) {
+	var transport clientTransport
+	var err error
+	switch {
+	case cfg.Command != "":
+		transport, err = startProcess(cfg)
+	case cfg.URL != "":
+		headers := make(http.Header)
+		for key, value := range cfg.Headers {
+			headers.Set(key, os.ExpandEnv(value))
+		}
+		transport = &httpTransport{handler: newHTTPHandler(cfg.URL, headers, cfg.timeout()+5*time.Second)}
+	default:
+		return nil, fmt.Errorf("MCP server %s needs a command or a url", cfg.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{name: cfg.Name, transport: transport}
+	if err := c.initialize(ctx, cfg.timeout()); err != nil {
+		transport.close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Name returns the server's configured name
+func (c *Client) Name() string {
+	return c.name
+}
+
+func (c *Client) initialize(ctx context.Context, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	params := map[string]interface{}{
+		"protocolVersion": supportedProtocolVersions[0],
+		"capabilities":    map[string]interface{}{},
+		"clientInfo":      implementation{Name: "gridmate", Version: "1.0.0"},
+	}
+	var result initializeResult
+	if err := c.request(ctx, "initialize", params, &result); err != nil {
+		return err
+	}
+	c.Instructions = result.Instructions
+
+	data, _ := json.Marshal(map[string]string{"jsonrpc": jsonRPCVersion, "method": "notifications/initialized"})
+	if err := c.transport.notify(ctx, data); err != nil {
+		return fmt.Errorf("failed to confirm initialization with %s: %w", c.name, err)
+	}
+	return nil
+}
+
+// ListTools returns every tool the server offers
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	var tools []Tool
+	cursor := ""
+	for {
+		params := map[string]interface{}{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		var page struct {
+			Tools      []Tool `json:"tools"`
+			NextCursor string `json:"nextCursor"`
+		}
+		if err := c.request(ctx, "tools/list", params, &page); err != nil {
+			return nil, err
+		}
+		tools = append(tools, page.Tools...)
+		if page.NextCursor == "" || page.NextCursor == cursor {
+			return tools, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// CallTool calls a tool on the server
+func (c *Client) CallTool(ctx context.Context, name string, arguments map[string]interface{}) (*CallToolResponse, error) {
+	if arguments == nil {
+		arguments = make(map[string]interface{})
+	}
+	var result CallToolResponse
+	if err := c.request(ctx, "tools/call", callToolParams{Name: name, Arguments: arguments}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Close ends the session and stops the server if it is a subprocess
+func (c *Client) Close() error {
+	return c.transport.close()
+}
+
+func (c *Client) request(ctx context.Context, method string, params, result interface{}) error {
+	id := strconv.FormatInt(c.nextID.Add(1), 10)
+	data, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": jsonRPCVersion,
+		"id":      json.RawMessage(id),
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %w", method, err)
+	}
+
+	reply, err := c.transport.roundTrip(ctx, id, data)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", c.name, method, err)
+	}
+	if reply.Error != nil {
+		return fmt.Errorf("%s %s: %s", c.name, method, reply.Error.Message)
+	}
+	if err := json.Unmarshal(reply.Result, result); err != nil {
+		return fmt.Errorf("failed to decode %s result from %s: %w", method, c.name, err)
+	}
+	return nil
+}
+
+// httpTransport sends each request as a POST to the server's endpoint
+type httpTransport struct {
+	handler *RemoteHandler
+}
+
+func (t *httpTransport) roundTrip(ctx context.Context, id string, data []byte) (*message, error) {
+	body, err := t.handler.post(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	messages, _, rpcErr := parseMessages(body)
+	if rpcErr != nil {
+		return nil, fmt.Errorf("invalid reply: %s", rpcErr.Message)
+	}
+	for i := range messages {
+		if string(messages[i].ID) == id && messages[i].Method == "" {
+			return &messages[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no reply to request %s", id)
+}
+
+func (t *httpTransport) notify(ctx context.Context, data []byte) error {
+	_, err := t.handler.post(ctx, data)
+	return err
+}
+
+func (t *httpTransport) close() error {
+	t.handler.mu.Lock()
+	sessionID := t.handler.sessionID
+	t.handler.mu.Unlock()
+	if sessionID == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, t.handler.url, nil)
+	if err != nil {
+		return err
+	}
+	for key, values := range t.handler.headers {
+		req.Header[key] = values
+	}
+	req.Header.Set(SessionHeader, sessionID)
+	resp, err := t.handler.client.Do(req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// processTransport exchanges newline-delimited messages with a subprocess
+type processTransport struct {
+	name  string
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	pending map[string]chan *message
+	done    chan struct{}
+	err     error
+}
+
+func startProcess(cfg ServerConfig) (*processTransport, error) {
+	cmd := exec.Command(cfg.Command, cfg.Args...)
+	cmd.Env = os.Environ()
+	for key, value := range cfg.Env {
+		cmd.Env = append(cmd.Env, key+"="+os.ExpandEnv(value))
+	}
+	cmd.Dir = cfg.Dir
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stdin for %s: %w", cfg.Name, err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stdout for %s: %w", cfg.Name, err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stderr for %s: %w", cfg.Name, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start MCP server %s: %w", cfg.Name, err)
+	}
+
+	t := &processTransport{
+		name:    cfg.Name,
+		cmd:     cmd,
+		stdin:   stdin,
+		pending: make(map[string]chan *message),
+		done:    make(chan struct{}),
+	}
+	go t.logStderr(stderr)
+	go t.readLoop(stdout)
+	return t, nil
+}
+
+func (t *processTransport) roundTrip(ctx context.Context, id string, data []byte) (*message, error) {
+	ch := make(chan *message, 1)
+	t.mu.Lock()
+	if t.err != nil {
+		t.mu.Unlock()
+		return nil, t.err
+	}
+	t.pending[id] = ch
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		delete(t.pending, id)
+		t.mu.Unlock()
+	}()
+
+	if err := t.write(data); err != nil {
+		return nil, err
+	}
+	select {
+	case reply := <-ch:
+		return reply, nil
+	case <-t.done:
+		return nil, t.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("no reply: %w", ctx.Err())
+	}
+}
+
+func (t *processTransport) notify(ctx context.Context, data []byte) error {
+	return t.write(data)
+}
+
+func (t *processTransport) write(data []byte) error {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	if _, err := t.stdin.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write to %s: %w", t.name, err)
+	}
+	return nil
+}
+
+// readLoop delivers replies to waiting requests and answers the server's
+// own requests until the process closes its stdout
+func (t *processTransport) readLoop(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxStdioMessageSize)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		messages, _, rpcErr := parseMessages(line)
+		if rpcErr != nil {
+			log.Warn().Str("mcp_server", t.name).Str("line", string(line)).Msg("Ignoring invalid message from MCP server")
+			continue
+		}
+		for i := range messages {
+			t.dispatch(&messages[i])
+		}
+	}
+
+	err := scanner.Err()
+	if err == nil {
+		err = io.EOF
+	}
+	t.mu.Lock()
+	t.err = fmt.Errorf("MCP server %s exited: %w", t.name, err)
+	t.mu.Unlock()
+	close(t.done)
+}
+
+func (t *processTransport) dispatch(msg *message) {
+	switch {
+	case msg.Method == "":
+		t.mu.Lock()
+		ch, ok := t.pending[string(msg.ID)]
+		t.mu.Unlock()
+		if ok {
+			select {
+			case ch <- msg:
+			default: // Duplicate reply
+			}
+		}
+	case msg.isNotification():
+		// Progress and log notifications are not surfaced
+	case msg.Method == "ping":
+		data, _ := json.Marshal(response{JSONRPC: jsonRPCVersion, ID: msg.ID, Result: map[string]interface{}{}})
+		t.write(data)
+	default:
+		// Sampling, roots and elicitation are not supported
+		data, _ := json.Marshal(response{JSONRPC: jsonRPCVersion, ID: msg.ID, Error: newRPCError(codeMethodNotFound, "method not supported by client: "+msg.Method)})
+		t.write(data)
+	}
+}
+
+func (t *processTransport) logStderr(stderr io.Reader) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		log.Debug().Str("mcp_server", t.name).Msg(scanner.Text())
+	}
+}
+
+func (t *processTransport) close() error {
+	t.stdin.Close()
+	select {
+	case <-t.done:
+	case <-time.After(2 * time.Second):
+		t.cmd.Process.Kill()
+	}
+	return t.cmd.Wait()
+}
@@ -0,0 +1,360 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gridmate/backend/internal/services/ai"
+	"github.com/rs/zerolog/log"
+)
+
+// ClientConfig lists the external MCP servers whose tools the assistant
+// may use, and which workspaces may use them
+type ClientConfig struct {
+	Servers []ServerConfig `json:"servers"`
+
+	// Workspaces limits tools per workspace ID. "*" applies to workspaces
+	// not listed. Without any entries every session may use every tool;
+	// with entries, sessions whose workspace is unknown may use none.
+	Workspaces map[string]WorkspacePolicy `json:"workspaces,omitempty"`
+}
+
+// ServerConfig describes one external server. Command starts a subprocess
+// that speaks MCP over stdio; URL dials a streamable HTTP endpoint.
+// Values in Env and Headers may reference environment variables.
+type ServerConfig struct {
+	Name    string            `json:"name"`
+	Command string            `json:"command,omitempty"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	Dir     string            `json:"dir,omitempty"`
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Timeout Duration          `json:"timeout,omitempty"`
+
+	// Tools offers only these tools from the server; empty offers all
+	Tools []string `json:"tools,omitempty"`
+
+	// WriteTools are tools that change data. Every other tool is treated
+	// as read-only.
+	WriteTools []string `json:"write_tools,omitempty"`
+}
+
+// WorkspacePolicy is what a workspace may use. Tools entries are
+// "server.tool", "server.*" or "*".
+type WorkspacePolicy struct {
+	Tools   []string `json:"tools"`
+	Timeout Duration `json:"timeout,omitempty"`
+}
+
+// Duration is a time.Duration written as "30s" or as a number of seconds
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var seconds float64
+	if err := json.Unmarshal(data, &seconds); err == nil {
+		*d = Duration(seconds * float64(time.Second))
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("duration must be a string or a number of seconds")
+	}
+	parsed, err := time.ParseDuration(text)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (c ServerConfig) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return time.Duration(c.Timeout)
+	}
+	return defaultCallTimeout
+}
+
+// LoadClientConfig reads a JSON client configuration from path
+func LoadClientConfig(path string) (*ClientConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read MCP server config: %w", err)
+	}
+	var cfg ClientConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse MCP server config: %w", err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+var serverNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+func (c *ClientConfig) validate() error {
+	seen := make(map[string]bool)
+	for _, server := range c.Servers {
+		if !serverNamePattern.MatchString(server.Name) {
+			return fmt.Errorf("MCP server name %q must use only letters, digits, _ and -", server.Name)
+		}
+		if seen[server.Name] {
+			return fmt.Errorf("MCP server %s is configured twice", server.Name)
+		}
+		seen[server.Name] = true
+		if (server.Command == "") == (server.URL == "") {
+			return fmt.Errorf("MCP server %s needs exactly one of command and url", server.Name)
+		}
+	}
+	return nil
+}
+
+// policy returns the policy for a workspace, or false if it may use no
+// external tools. An empty workspace is one that could not be resolved.
+func (c *ClientConfig) policy(workspace string) (WorkspacePolicy, bool) {
+	if len(c.Workspaces) == 0 {
+		return WorkspacePolicy{Tools: []string{"*"}}, true
+	}
+	if workspace == "" {
+		return WorkspacePolicy{}, false
+	}
+	if policy, ok := c.Workspaces[workspace]; ok {
+		return policy, true
+	}
+	policy, ok := c.Workspaces["*"]
+	return policy, ok
+}
+
+// allows reports whether a workspace may use a server's tool
+func (c *ClientConfig) allows(workspace, server, tool string) bool {
+	policy, ok := c.policy(workspace)
+	if !ok {
+		return false
+	}
+	for _, pattern := range policy.Tools {
+		if pattern == "*" || pattern == server+".*" || pattern == server+"."+tool {
+			return true
+		}
+	}
+	return false
+}
+
+// timeout returns how long a workspace's calls to server may take
+func (c *ClientConfig) timeout(workspace string, server ServerConfig) time.Duration {
+	if policy, ok := c.policy(workspace); ok && policy.Timeout > 0 {
+		return min(time.Duration(policy.Timeout), server.timeout())
+	}
+	return server.timeout()
+}
+
+// ExternalTools connects to the configured servers and registers their
+// tools with the assistant's tool registry
+type ExternalTools struct {
+	config      *ClientConfig
+	workspaceOf func(sessionID string) string
+	clients     []*Client
+}
+
+// ConnectExternalTools connects to every configured server. workspaceOf
+// maps a Gridmate session to the workspace whose policy applies to it, or
+// "" when the session's workspace is unknown.
+// Servers that fail to start are logged and left out.
+func ConnectExternalTools(ctx context.Context, cfg *ClientConfig, workspaceOf func(sessionID string) string) *ExternalTools {
+	if workspaceOf == nil {
+		workspaceOf = func(string) string { return "" }
+	}
+	e := &ExternalTools{config: cfg, workspaceOf: workspaceOf}
+	for _, server := range cfg.Servers {
+		client, err := Connect(ctx, server)
+		if err != nil {
+			log.Error().Err(err).Str("mcp_server", server.Name).Msg("Failed to connect to MCP server")
+			continue
+		}
+		e.clients = append(e.clients, client)
+	}
+	return e
+}
+
+// Register adds the tools of every connected server to registry as
+// "<server>__<tool>" in the external tool group
+func (e *ExternalTools) Register(ctx context.Context, registry *ai.ToolRegistry) {
+	for _, client := range e.clients {
+		server := e.server(client.Name())
+		listCtx, cancel := context.WithTimeout(ctx, server.timeout())
+		tools, err := client.ListTools(listCtx)
+		cancel()
+		if err != nil {
+			log.Error().Err(err).Str("mcp_server", server.Name).Msg("Failed to list MCP server tools")
+			continue
+		}
+
+		registered := 0
+		for _, tool := range tools {
+			if len(server.Tools) > 0 && !containsString(server.Tools, tool.Name) {
+				continue
+			}
+			if err := registry.Register(e.definition(client, server, tool)); err != nil {
+				log.Warn().Err(err).Str("mcp_server", server.Name).Str("tool", tool.Name).Msg("Skipping MCP server tool")
+				continue
+			}
+			registered++
+		}
+		log.Info().
+			Str("mcp_server", server.Name).
+			Int("tools", registered).
+			Msg("Registered MCP server tools")
+	}
+}
+
+// Close disconnects from every server
+func (e *ExternalTools) Close() {
+	for _, client := range e.clients {
+		if err := client.Close(); err != nil {
+			log.Debug().Err(err).Str("mcp_server", client.Name()).Msg("MCP server closed with error")
+		}
+	}
+}
+
+func (e *ExternalTools) server(name string) ServerConfig {
+	for _, server := range e.config.Servers {
+		if server.Name == name {
+			return server
+		}
+	}
+	return ServerConfig{Name: name}
+}
+
+func (e *ExternalTools) definition(client *Client, server ServerConfig, tool Tool) ai.ToolDefinition {
+	write := containsString(server.WriteTools, tool.Name)
+	permission := "read"
+	if write {
+		permission = "write"
+	}
+
+	schema := tool.InputSchema
+	if schema == nil {
+		schema = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+	}
+	description := tool.Description
+	if description == "" {
+		description = tool.Name
+	}
+
+	return ai.ToolDefinition{
+		ExcelTool: ai.ExcelTool{
+			Name:        ExternalToolName(server.Name, tool.Name),
+			Description: fmt.Sprintf("[%s] %s", server.Name, description),
+			InputSchema: schema,
+			Category:    "external",
+			Permission:  permission,
+			PreviewType: "none",
+		},
+		Groups: []string{ai.ToolGroupExternal},
+		Available: func(sessionID string) bool {
+			return e.config.allows(e.workspaceOf(sessionID), server.Name, tool.Name)
+		},
+		Execute: e.handler(client, server, tool.Name, write),
+	}
+}
+
+func (e *ExternalTools) handler(client *Client, server ServerConfig, tool string, write bool) ai.ToolHandler {
+	return func(ctx context.Context, te *ai.ToolExecutor, sessionID string, call ai.ToolCall, autonomyMode string, result *ai.ToolResult) error {
+		// Queued operations are carried out by the Excel add-in, which
+		// cannot reach external servers, so writes need full autonomy
+		if write && autonomyMode != "full-autonomy" && autonomyMode != "yolo" {
+			result.IsError = true
+			result.Status = "error"
+			result.Content = map[string]interface{}{
+				"error": fmt.Sprintf("%s changes data in %s and can only run in full autonomy mode. Ask the user to run it or switch modes.", call.Name, server.Name),
+			}
+			return nil
+		}
+
+		arguments := make(map[string]interface{}, len(call.Input))
+		for key, value := range call.Input {
+			if !strings.HasPrefix(key, "_") {
+				arguments[key] = value
+			}
+		}
+
+		timeout := e.config.timeout(e.workspaceOf(sessionID), server)
+		callCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		start := time.Now()
+		response, err := client.CallTool(callCtx, tool, arguments)
+		log.Info().
+			Str("mcp_server", server.Name).
+			Str("tool", tool).
+			Str("session", sessionID).
+			Dur("duration", time.Since(start)).
+			Err(err).
+			Msg("Called MCP server tool")
+		if err != nil {
+			result.IsError = true
+			result.Status = "error"
+			result.Content = map[string]interface{}{"error": err.Error(), "server": server.Name}
+			return nil
+		}
+
+		content := map[string]interface{}{
+			"server": server.Name,
+			"tool":   tool,
+			"text":   responseText(response),
+		}
+		if response.StructuredContent != nil {
+			content["structured"] = response.StructuredContent
+		}
+		result.Content = content
+		result.IsError = response.IsError
+		result.Status = "success"
+		if response.IsError {
+			result.Status = "error"
+		}
+		return nil
+	}
+}
+
+// ExternalToolName is the registry name of a server's tool. Tool names
+// sent to the model allow only letters, digits, _ and - up to 64 long.
+func ExternalToolName(server, tool string) string {
+	name := server + "__" + invalidToolNameChars.ReplaceAllString(tool, "_")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+var invalidToolNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// responseText joins the text parts of a tool result
+func responseText(response *CallToolResponse) string {
+	var parts []string
+	for _, item := range response.Content {
+		if item.Type == "text" {
+			parts = append(parts, item.Text)
+		} else {
+			parts = append(parts, fmt.Sprintf("[%s content omitted]", item.Type))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package mcp
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gridmate/backend/internal/services/ai"
+)
+
+func TestExternalTools(t *testing.T) {
+	runner := &fakeRunner{registry: ai.NewBuiltinToolRegistry()}
+	upstream := httptest.NewServer(NewHTTPHandler(NewServer(runner), nil))
+	defer upstream.Close()
+
+	cfg := &ClientConfig{
+		Servers: []ServerConfig{{
+			Name:       "sheets",
+			URL:        upstream.URL,
+			Headers:    map[string]string{GridmateSession: "s1"},
+			Tools:      []string{"read_range", "write_range"},
+			WriteTools: []string{"write_range"},
+		}},
+		Workspaces: map[string]WorkspacePolicy{
+			"acme": {Tools: []string{"sheets.*"}},
+			"*":    {Tools: []string{"sheets.read_range"}},
+		},
+	}
+	workspaces := map[string]string{"session-acme": "acme", "session-other": "other"}
+	external := ConnectExternalTools(context.Background(), cfg, func(sessionID string) string { return workspaces[sessionID] })
+	defer external.Close()
+
+	registry := ai.NewToolRegistry()
+	external.Register(context.Background(), registry)
+
+	if names := registry.Names(); len(names) != 2 {
+		t.Fatalf("registered tools = %v, want the two allowed tools", names)
+	}
+	if registry.IsWrite("sheets__read_range") || !registry.IsWrite("sheets__write_range") {
+		t.Error("only tools listed in write_tools should be write tools")
+	}
+	if got := len(registry.SessionGroup(ai.ToolGroupExternal, "session-acme")); got != 2 {
+		t.Errorf("tools for acme = %d, want 2", got)
+	}
+	if got := len(registry.SessionGroup(ai.ToolGroupExternal, "session-other")); got != 1 {
+		t.Errorf("tools for other workspaces = %d, want 1", got)
+	}
+	if _, ok := registry.LookupForSession("sheets__write_range", "session-other"); ok {
+		t.Error("write_range should not be available outside acme")
+	}
+	if got := len(registry.SessionGroup(ai.ToolGroupExternal, "session-unlinked")); got != 0 {
+		t.Errorf("tools for a session without a workspace = %d, want 0", got)
+	}
+
+	call := func(name, mode string) *ai.ToolResult {
+		t.Helper()
+		def, ok := registry.LookupForSession(name, "session-acme")
+		if !ok {
+			t.Fatalf("%s is not available", name)
+		}
+		result := &ai.ToolResult{}
+		input := map[string]interface{}{"range": "A1", "_tool_id": "t1"}
+		if err := def.Execute(context.Background(), nil, "session-acme", ai.ToolCall{Name: name, Input: input}, mode, result); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	result := call("sheets__read_range", "agent-default")
+	content, _ := result.Content.(map[string]interface{})
+	if result.IsError || content["server"] != "sheets" {
+		t.Errorf("read_range result = %+v", result)
+	}
+	if len(runner.calls) != 1 || runner.calls[0] != "s1 read_range agent-default" {
+		t.Errorf("upstream calls = %v", runner.calls)
+	}
+
+	if result := call("sheets__write_range", "agent-default"); !result.IsError {
+		t.Error("write tools should not run without full autonomy")
+	}
+	if len(runner.calls) != 1 {
+		t.Errorf("write tool reached the server: %v", runner.calls)
+	}
+	if result := call("sheets__write_range", "full-autonomy"); result.IsError {
+		t.Errorf("write_range in full autonomy = %+v", result)
+	}
+}
@@ -0,0 +1,196 @@
+package mcp
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// Headers and query parameters clients use to pick the Gridmate session and
+// autonomy mode when they initialize
+const (
+	SessionHeader      = "Mcp-Session-Id"
+	GridmateSession    = "X-Gridmate-Session"
+	GridmateAutonomy   = "X-Gridmate-Autonomy-Mode"
+	maxRequestBodySize = 4 << 20
+	sessionIdleTimeout = time.Hour
+)
+
+// HTTPHandler serves the streamable HTTP transport. Every response is a
+// single JSON body; the server sends no requests of its own, so it offers
+// no SSE stream.
+type HTTPHandler struct {
+	server *Server
+	caller func(*http.Request) string
+
+	mu       sync.Mutex
+	sessions map[string]*httpSession
+}
+
+type httpSession struct {
+	binding  Binding
+	caller   string
+	lastUsed time.Time
+}
+
+// NewHTTPHandler creates the HTTP transport for server. caller identifies
+// the authenticated user so MCP sessions cannot be used by anyone else.
+func NewHTTPHandler(server *Server, caller func(*http.Request) string) *HTTPHandler {
+	if caller == nil {
+		caller = func(*http.Request) string { return "" }
+	}
+	return &HTTPHandler{
+		server:   server,
+		caller:   caller,
+		sessions: make(map[string]*httpSession),
+	}
+}
+
+func (h *HTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		h.handlePost(w, r)
+	case http.MethodDelete:
+		h.mu.Lock()
+		if sess, ok := h.sessions[r.Header.Get(SessionHeader)]; ok && sess.caller == h.caller(r) {
+			delete(h.sessions, r.Header.Get(SessionHeader))
+		}
+		h.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *HTTPHandler) handlePost(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBodySize+1))
+	if err != nil || len(body) > maxRequestBodySize {
+		writeRPCError(w, http.StatusRequestEntityTooLarge, newRPCError(codeInvalidRequest, "request body too large"))
+		return
+	}
+	messages, _, rpcErr := parseMessages(body)
+	if rpcErr != nil {
+		writeRPCError(w, http.StatusBadRequest, rpcErr)
+		return
+	}
+
+	var sessionID string
+	var binding Binding
+	if isInitialize(messages) {
+		if binding, err = h.bindingFromRequest(r); err != nil {
+			writeRPCError(w, http.StatusForbidden, newRPCError(codeInvalidParams, err.Error()))
+			return
+		}
+		sessionID = h.startSession(binding, h.caller(r))
+		w.Header().Set(SessionHeader, sessionID)
+	} else {
+		sessionID = r.Header.Get(SessionHeader)
+		if sessionID == "" {
+			writeRPCError(w, http.StatusBadRequest, newRPCError(codeInvalidRequest, "missing "+SessionHeader+" header"))
+			return
+		}
+		sess, ok := h.session(sessionID, h.caller(r))
+		if !ok {
+			writeRPCError(w, http.StatusNotFound, newRPCError(codeInvalidRequest, "unknown MCP session"))
+			return
+		}
+		binding = sess.binding
+	}
+
+	reply := h.server.Handle(WithBinding(r.Context(), binding), body)
+	if reply == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if forbidden(reply) {
+		w.WriteHeader(http.StatusForbidden)
+	}
+	w.Write(reply)
+}
+
+// forbidden reports whether reply is a single error refusing access to a
+// session, which is sent with a 403 status
+func forbidden(reply []byte) bool {
+	var resp response
+	if err := json.Unmarshal(reply, &resp); err != nil {
+		return false // A batch
+	}
+	return resp.Error != nil && resp.Error.Code == codeForbidden
+}
+
+// bindingFromRequest reads the session and autonomy mode a client asked for
+func (h *HTTPHandler) bindingFromRequest(r *http.Request) (Binding, error) {
+	sessionID := r.Header.Get(GridmateSession)
+	if sessionID == "" {
+		sessionID = r.URL.Query().Get("session")
+	}
+	mode := r.Header.Get(GridmateAutonomy)
+	if mode == "" {
+		mode = r.URL.Query().Get("autonomy")
+	}
+
+	mode, err := NormalizeAutonomyMode(mode)
+	if err != nil {
+		return Binding{}, err
+	}
+	if sessionID != "" {
+		if err := h.server.Authorize(r.Context(), sessionID); err != nil {
+			return Binding{}, err
+		}
+	}
+	return Binding{SessionID: sessionID, AutonomyMode: mode}, nil
+}
+
+func (h *HTTPHandler) startSession(binding Binding, caller string) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	for id, sess := range h.sessions {
+		if now.Sub(sess.lastUsed) > sessionIdleTimeout {
+			delete(h.sessions, id)
+		}
+	}
+
+	id := uuid.New().String()
+	h.sessions[id] = &httpSession{binding: binding, caller: caller, lastUsed: now}
+	log.Debug().
+		Str("mcp_session", id).
+		Str("session", binding.SessionID).
+		Int("active_mcp_sessions", len(h.sessions)).
+		Msg("Started MCP session")
+	return id
+}
+
+func (h *HTTPHandler) session(id, caller string) (*httpSession, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	sess, ok := h.sessions[id]
+	if !ok || sess.caller != caller {
+		return nil, false
+	}
+	sess.lastUsed = time.Now()
+	return sess, true
+}
+
+func isInitialize(messages []message) bool {
+	for _, msg := range messages {
+		if msg.Method == "initialize" {
+			return true
+		}
+	}
+	return false
+}
+
+func writeRPCError(w http.ResponseWriter, status int, rpcErr *rpcError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response{JSONRPC: jsonRPCVersion, ID: json.RawMessage("null"), Error: rpcErr})
+}
@@ -0,0 +1,504 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gridmate/backend/internal/services/ai"
+	"github.com/rs/zerolog/log"
+)
+
+// Autonomy modes available to MCP clients. Writes are queued for approval
+// in the Excel add-in exactly as they are for chat; full autonomy is not
+// offered over MCP.
+const (
+	AutonomyReadOnly     = "read-only"
+	AutonomyAgentDefault = "agent-default"
+)
+
+// NormalizeAutonomyMode validates an autonomy mode requested by a client
+func NormalizeAutonomyMode(mode string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "", "default", AutonomyAgentDefault:
+		return AutonomyAgentDefault, nil
+	case AutonomyReadOnly:
+		return AutonomyReadOnly, nil
+	}
+	return "", fmt.Errorf("autonomy mode %q is not available over MCP, use %s or %s", mode, AutonomyAgentDefault, AutonomyReadOnly)
+}
+
+// ToolRunner executes tool calls against a Gridmate session
+type ToolRunner interface {
+	ExecuteTool(ctx context.Context, sessionID string, toolCall ai.ToolCall, autonomyMode string) (*ai.ToolResult, error)
+	Registry() *ai.ToolRegistry
+}
+
+// WorkbookSource builds a snapshot of a session's workbook
+type WorkbookSource interface {
+	BuildContext(ctx context.Context, sessionID string) (*ai.FinancialContext, error)
+}
+
+// Binding is the Gridmate session and autonomy mode an MCP client acts on
+type Binding struct {
+	SessionID    string
+	AutonomyMode string
+}
+
+type bindingKey struct{}
+
+// WithBinding returns a context whose MCP calls act on binding
+func WithBinding(ctx context.Context, binding Binding) context.Context {
+	return context.WithValue(ctx, bindingKey{}, binding)
+}
+
+func bindingFromContext(ctx context.Context) Binding {
+	binding, _ := ctx.Value(bindingKey{}).(Binding)
+	if binding.AutonomyMode == "" {
+		binding.AutonomyMode = AutonomyAgentDefault
+	}
+	return binding
+}
+
+// MessageHandler handles a JSON-RPC message or batch and returns the
+// reply, or nil when there is nothing to send back
+type MessageHandler interface {
+	Handle(ctx context.Context, data []byte) []byte
+}
+
+// Server answers MCP requests with Gridmate's tools and resources
+type Server struct {
+	tools     ToolRunner
+	workbooks WorkbookSource
+	authorize func(ctx context.Context, sessionID string) error
+	info      implementation
+}
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithWorkbookSource exposes workbook structure as a resource
+func WithWorkbookSource(source WorkbookSource) Option {
+	return func(s *Server) {
+		s.workbooks = source
+	}
+}
+
+// ErrForbidden is wrapped by session authorizers when the session exists
+// but belongs to someone other than the caller
+var ErrForbidden = errors.New("session belongs to another user")
+
+// WithSessionAuthorizer checks that the caller may act on a session
+func WithSessionAuthorizer(authorize func(ctx context.Context, sessionID string) error) Option {
+	return func(s *Server) {
+		s.authorize = authorize
+	}
+}
+
+// NewServer creates an MCP server that routes tool calls through tools
+func NewServer(tools ToolRunner, opts ...Option) *Server {
+	s := &Server{
+		tools: tools,
+		info:  implementation{Name: "gridmate", Version: "1.0.0"},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Authorize checks that the caller may act on a session
+func (s *Server) Authorize(ctx context.Context, sessionID string) error {
+	if s.authorize == nil {
+		return nil
+	}
+	return s.authorize(ctx, sessionID)
+}
+
+// Handle processes a JSON-RPC message or batch
+func (s *Server) Handle(ctx context.Context, data []byte) []byte {
+	messages, batch, err := parseMessages(data)
+	if err != nil {
+		return marshalReply([]response{{JSONRPC: jsonRPCVersion, ID: json.RawMessage("null"), Error: err}}, false)
+	}
+
+	var replies []response
+	for _, msg := range messages {
+		if reply := s.handleMessage(ctx, msg); reply != nil {
+			replies = append(replies, *reply)
+		}
+	}
+	return marshalReply(replies, batch)
+}
+
+func (s *Server) handleMessage(ctx context.Context, msg message) *response {
+	if msg.Method == "" {
+		return nil // Responses to server requests; the server sends none
+	}
+	if msg.JSONRPC != jsonRPCVersion {
+		if msg.isNotification() {
+			return nil
+		}
+		return &response{JSONRPC: jsonRPCVersion, ID: msg.ID, Error: newRPCError(codeInvalidRequest, "jsonrpc must be 2.0")}
+	}
+
+	result, rpcErr := s.dispatch(ctx, msg)
+	if msg.isNotification() {
+		return nil
+	}
+	if rpcErr != nil {
+		return &response{JSONRPC: jsonRPCVersion, ID: msg.ID, Error: rpcErr}
+	}
+	return &response{JSONRPC: jsonRPCVersion, ID: msg.ID, Result: result}
+}
+
+func (s *Server) dispatch(ctx context.Context, msg message) (interface{}, *rpcError) {
+	switch msg.Method {
+	case "initialize":
+		var params initializeParams
+		if err := decodeParams(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.initialize(ctx, params), nil
+	case "ping":
+		return struct{}{}, nil
+	case "tools/list":
+		return map[string]interface{}{"tools": s.listTools(ctx)}, nil
+	case "tools/call":
+		var params callToolParams
+		if err := decodeParams(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.callTool(ctx, params)
+	case "resources/list":
+		return map[string]interface{}{"resources": s.listResources(ctx)}, nil
+	case "resources/templates/list":
+		return map[string]interface{}{"resourceTemplates": s.resourceTemplates()}, nil
+	case "resources/read":
+		var params readResourceParams
+		if err := decodeParams(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.readResource(ctx, params.URI)
+	}
+	if strings.HasPrefix(msg.Method, "notifications/") {
+		return nil, nil
+	}
+	return nil, newRPCError(codeMethodNotFound, fmt.Sprintf("method not found: %s", msg.Method))
+}
+
+func (s *Server) initialize(ctx context.Context, params initializeParams) initializeResult {
+	version := supportedProtocolVersions[0]
+	for _, v := range supportedProtocolVersions {
+		if v == params.ProtocolVersion {
+			version = v
+		}
+	}
+
+	binding := bindingFromContext(ctx)
+	log.Info().
+		Str("client", params.ClientInfo.Name).
+		Str("client_version", params.ClientInfo.Version).
+		Str("protocol_version", version).
+		Str("session", binding.SessionID).
+		Str("autonomy_mode", binding.AutonomyMode).
+		Msg("MCP client initialized")
+
+	instructions := "Tools act on the Gridmate session " + binding.SessionID + "."
+	if binding.SessionID == "" {
+		instructions = "No Gridmate session is selected, so tool calls will fail until the client reconnects with one."
+	}
+	if binding.AutonomyMode == AutonomyReadOnly {
+		instructions += " The session is read-only, so only read tools are available."
+	} else {
+		instructions += " Changes to the workbook are queued until the user approves them in Excel."
+	}
+
+	return initializeResult{
+		ProtocolVersion: version,
+		Capabilities: map[string]interface{}{
+			"tools":     map[string]interface{}{"listChanged": false},
+			"resources": map[string]interface{}{"listChanged": false},
+		},
+		ServerInfo:   s.info,
+		Instructions: instructions,
+	}
+}
+
+func (s *Server) listTools(ctx context.Context) []Tool {
+	binding := bindingFromContext(ctx)
+	registry := s.tools.Registry()
+
+	var tools []Tool
+	for _, tool := range registry.SessionTools(binding.SessionID) {
+		write := registry.IsWrite(tool.Name)
+		if write && binding.AutonomyMode == AutonomyReadOnly {
+			continue
+		}
+		tools = append(tools, Tool{
+			Name:        tool.Name,
+			Description: tool.Description,
+			InputSchema: tool.InputSchema,
+			Annotations: &ToolAnnotations{ReadOnlyHint: !write, DestructiveHint: write},
+		})
+	}
+	return tools
+}
+
+func (s *Server) callTool(ctx context.Context, params callToolParams) (interface{}, *rpcError) {
+	binding := bindingFromContext(ctx)
+	registry := s.tools.Registry()
+
+	if _, ok := registry.LookupForSession(params.Name, binding.SessionID); !ok {
+		return nil, newRPCError(codeInvalidParams, fmt.Sprintf("unknown tool: %s", params.Name))
+	}
+	if binding.SessionID == "" {
+		return toolError("No Gridmate session is selected. Reconnect with a session ID."), nil
+	}
+	if err := s.Authorize(ctx, binding.SessionID); err != nil {
+		return toolError(err.Error()), nil
+	}
+	if binding.AutonomyMode == AutonomyReadOnly && registry.IsWrite(params.Name) {
+		return toolError(fmt.Sprintf("%s modifies the workbook and this MCP session is read-only.", params.Name)), nil
+	}
+
+	input := params.Arguments
+	if input == nil {
+		input = make(map[string]interface{})
+	}
+	call := ai.ToolCall{
+		ID:    "mcp_" + uuid.New().String(),
+		Name:  params.Name,
+		Input: input,
+	}
+
+	log.Info().
+		Str("tool", call.Name).
+		Str("tool_id", call.ID).
+		Str("session", binding.SessionID).
+		Str("autonomy_mode", binding.AutonomyMode).
+		Msg("Executing tool for MCP client")
+
+	result, err := s.tools.ExecuteTool(ctx, binding.SessionID, call, binding.AutonomyMode)
+	if err != nil {
+		return toolError(err.Error()), nil
+	}
+	text, err := json.Marshal(result.Content)
+	if err != nil {
+		return nil, newRPCError(codeInternalError, fmt.Sprintf("failed to encode tool result: %v", err))
+	}
+	return callToolResult{
+		Content: []content{{Type: "text", Text: string(text)}},
+		IsError: result.IsError,
+	}, nil
+}
+
+func toolError(text string) callToolResult {
+	return callToolResult{Content: []content{{Type: "text", Text: text}}, IsError: true}
+}
+
+const (
+	resourceScheme   = "gridmate"
+	workbookResource = "workbook"
+	memoryResource   = "memory"
+)
+
+func resourceURI(sessionID, kind string) string {
+	return fmt.Sprintf("%s://sessions/%s/%s", resourceScheme, url.PathEscape(sessionID), kind)
+}
+
+func (s *Server) listResources(ctx context.Context) []Resource {
+	binding := bindingFromContext(ctx)
+	if binding.SessionID == "" || s.workbooks == nil {
+		return []Resource{}
+	}
+	return []Resource{{
+		URI:         resourceURI(binding.SessionID, workbookResource),
+		Name:        "Workbook structure",
+		Description: "Sheets, selection, model structure, named ranges and pending operations of the session's workbook",
+		MimeType:    "application/json",
+	}}
+}
+
+func (s *Server) resourceTemplates() []ResourceTemplate {
+	templates := []ResourceTemplate{{
+		URITemplate: resourceScheme + "://sessions/{session_id}/" + memoryResource + "{?query,source,limit}",
+		Name:        "Memory search",
+		Description: "Chunks of the session's workbook, documents and chat history that match a query. source is all, spreadsheet, document or chat.",
+		MimeType:    "application/json",
+	}}
+	if s.workbooks != nil {
+		templates = append(templates, ResourceTemplate{
+			URITemplate: resourceScheme + "://sessions/{session_id}/" + workbookResource,
+			Name:        "Workbook structure",
+			MimeType:    "application/json",
+		})
+	}
+	return templates
+}
+
+func (s *Server) readResource(ctx context.Context, uri string) (interface{}, *rpcError) {
+	sessionID, kind, query, err := parseResourceURI(uri)
+	if err != nil {
+		return nil, newRPCError(codeResourceNotFound, err.Error())
+	}
+
+	// Clients bound to a session may only read that session's resources
+	if bound := bindingFromContext(ctx).SessionID; bound != "" && bound != sessionID {
+		return nil, newRPCError(codeResourceNotFound, fmt.Sprintf("resource not found: %s", uri))
+	}
+	if err := s.Authorize(ctx, sessionID); err != nil {
+		if errors.Is(err, ErrForbidden) {
+			return nil, newRPCError(codeForbidden, err.Error())
+		}
+		return nil, newRPCError(codeResourceNotFound, err.Error())
+	}
+
+	var data interface{}
+	switch kind {
+	case workbookResource:
+		if s.workbooks == nil {
+			return nil, newRPCError(codeResourceNotFound, fmt.Sprintf("resource not found: %s", uri))
+		}
+		fc, err := s.workbooks.BuildContext(ctx, sessionID)
+		if err != nil {
+			return nil, newRPCError(codeInternalError, fmt.Sprintf("failed to read workbook: %v", err))
+		}
+		data = workbookStructure(fc)
+	case memoryResource:
+		if data, err = s.searchMemory(ctx, sessionID, query); err != nil {
+			return nil, newRPCError(codeInvalidParams, err.Error())
+		}
+	default:
+		return nil, newRPCError(codeResourceNotFound, fmt.Sprintf("resource not found: %s", uri))
+	}
+
+	text, err := json.Marshal(data)
+	if err != nil {
+		return nil, newRPCError(codeInternalError, fmt.Sprintf("failed to encode resource: %v", err))
+	}
+	return map[string]interface{}{
+		"contents": []resourceContents{{URI: uri, MimeType: "application/json", Text: string(text)}},
+	}, nil
+}
+
+// searchMemory runs search_memory for a resource read. The tool only
+// reads, so it runs read-only whatever the client's autonomy mode.
+func (s *Server) searchMemory(ctx context.Context, sessionID string, query url.Values) (interface{}, error) {
+	if query.Get("query") == "" {
+		return nil, fmt.Errorf("memory resources need a query parameter")
+	}
+	input := map[string]interface{}{"query": query.Get("query")}
+	if source := query.Get("source"); source != "" {
+		input["source_filter"] = source
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return nil, fmt.Errorf("invalid limit %q", limit)
+		}
+		input["limit"] = float64(n)
+	}
+
+	result, err := s.tools.ExecuteTool(ctx, sessionID, ai.ToolCall{
+		ID:    "mcp_" + uuid.New().String(),
+		Name:  "search_memory",
+		Input: input,
+	}, AutonomyReadOnly)
+	if err != nil {
+		return nil, err
+	}
+	if result.IsError {
+		text, _ := json.Marshal(result.Content)
+		return nil, fmt.Errorf("memory search failed: %s", text)
+	}
+	return result.Content, nil
+}
+
+func parseResourceURI(uri string) (sessionID, kind string, query url.Values, err error) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != resourceScheme || u.Host != "sessions" {
+		return "", "", nil, fmt.Errorf("resource not found: %s", uri)
+	}
+	parts := strings.Split(strings.Trim(u.EscapedPath(), "/"), "/")
+	if len(parts) != 2 {
+		return "", "", nil, fmt.Errorf("resource not found: %s", uri)
+	}
+	if sessionID, err = url.PathUnescape(parts[0]); err != nil || sessionID == "" {
+		return "", "", nil, fmt.Errorf("resource not found: %s", uri)
+	}
+	return sessionID, parts[1], u.Query(), nil
+}
+
+// workbookStructure keeps the structural parts of a workbook snapshot and
+// leaves out cell values
+func workbookStructure(fc *ai.FinancialContext) map[string]interface{} {
+	if fc == nil {
+		return map[string]interface{}{}
+	}
+	return map[string]interface{}{
+		"workbook_name":      fc.WorkbookName,
+		"worksheet_name":     fc.WorksheetName,
+		"selected_range":     fc.SelectedRange,
+		"model_type":         fc.ModelType,
+		"model_structure":    fc.ModelStructure,
+		"named_ranges":       fc.NamedRanges,
+		"pending_operations": fc.PendingOperations,
+		"cell_count":         len(fc.CellValues),
+		"formula_count":      len(fc.Formulas),
+	}
+}
+
+func decodeParams(raw json.RawMessage, v interface{}) *rpcError {
+	if len(raw) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return newRPCError(codeInvalidParams, fmt.Sprintf("invalid params: %v", err))
+	}
+	return nil
+}
+
+// parseMessages decodes a single message or a batch
+func parseMessages(data []byte) ([]message, bool, *rpcError) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var batch []message
+		if err := json.Unmarshal(data, &batch); err != nil {
+			return nil, true, newRPCError(codeParseError, fmt.Sprintf("parse error: %v", err))
+		}
+		if len(batch) == 0 {
+			return nil, true, newRPCError(codeInvalidRequest, "empty batch")
+		}
+		return batch, true, nil
+	}
+	var msg message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, false, newRPCError(codeParseError, fmt.Sprintf("parse error: %v", err))
+	}
+	return []message{msg}, false, nil
+}
+
+func marshalReply(replies []response, batch bool) []byte {
+	if len(replies) == 0 {
+		return nil
+	}
+	var data []byte
+	var err error
+	if batch {
+		data, err = json.Marshal(replies)
+	} else {
+		data, err = json.Marshal(replies[0])
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to encode MCP response")
+		return nil
+	}
+	return data
+}
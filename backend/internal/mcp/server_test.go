@@ -0,0 +1,172 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gridmate/backend/internal/services/ai"
+)
+
+type fakeRunner struct {
+	registry *ai.ToolRegistry
+	calls    []string
+}
+
+func (f *fakeRunner) Registry() *ai.ToolRegistry { return f.registry }
+
+func (f *fakeRunner) ExecuteTool(ctx context.Context, sessionID string, call ai.ToolCall, autonomyMode string) (*ai.ToolResult, error) {
+	f.calls = append(f.calls, sessionID+" "+call.Name+" "+autonomyMode)
+	if autonomyMode == AutonomyAgentDefault && f.registry.IsWrite(call.Name) {
+		return &ai.ToolResult{Status: "queued", Content: map[string]interface{}{"status": "queued"}}, nil
+	}
+	return &ai.ToolResult{Status: "success", Content: map[string]interface{}{"query": call.Input["query"]}}, nil
+}
+
+func post(t *testing.T, h http.Handler, mcpSession string, headers map[string]string, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body))
+	if mcpSession != "" {
+		req.Header.Set(SessionHeader, mcpSession)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeResult(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	var resp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *rpcError       `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response %q: %v", rec.Body.String(), err)
+	}
+	if resp.Error != nil {
+		t.Fatalf("unexpected error: %+v", resp.Error)
+	}
+	if err := json.Unmarshal(resp.Result, v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHTTPHandler_ToolCalls(t *testing.T) {
+	runner := &fakeRunner{registry: ai.NewBuiltinToolRegistry()}
+	server := NewServer(runner, WithSessionAuthorizer(func(ctx context.Context, sessionID string) error {
+		if sessionID != "s1" {
+			return context.Canceled
+		}
+		return nil
+	}))
+	h := NewHTTPHandler(server, nil)
+
+	if rec := post(t, h, "", map[string]string{GridmateSession: "other"}, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`); rec.Code != http.StatusForbidden {
+		t.Errorf("initialize for an unauthorized session = %d", rec.Code)
+	}
+	if rec := post(t, h, "", map[string]string{GridmateAutonomy: "full-autonomy"}, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`); rec.Code != http.StatusForbidden {
+		t.Errorf("initialize with full autonomy = %d", rec.Code)
+	}
+
+	rec := post(t, h, "", map[string]string{GridmateSession: "s1"}, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}`)
+	var init initializeResult
+	decodeResult(t, rec, &init)
+	sid := rec.Header().Get(SessionHeader)
+	if sid == "" || init.ProtocolVersion != "2024-11-05" {
+		t.Fatalf("initialize: session %q, result %+v", sid, init)
+	}
+
+	if rec := post(t, h, sid, nil, `{"jsonrpc":"2.0","method":"notifications/initialized"}`); rec.Code != http.StatusAccepted {
+		t.Errorf("notification = %d, want 202", rec.Code)
+	}
+	if rec := post(t, h, "unknown", nil, `{"jsonrpc":"2.0","id":2,"method":"ping"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown MCP session = %d, want 404", rec.Code)
+	}
+
+	var call callToolResult
+	decodeResult(t, post(t, h, sid, nil, `{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"write_range","arguments":{"range":"A1","values":[[1]]}}}`), &call)
+	if call.IsError || !strings.Contains(call.Content[0].Text, "queued") {
+		t.Errorf("write_range result = %+v", call)
+	}
+	if len(runner.calls) != 1 || runner.calls[0] != "s1 write_range agent-default" {
+		t.Errorf("calls = %v", runner.calls)
+	}
+}
+
+func TestHTTPHandler_ForeignSession(t *testing.T) {
+	runner := &fakeRunner{registry: ai.NewBuiltinToolRegistry()}
+	server := NewServer(runner, WithSessionAuthorizer(func(ctx context.Context, sessionID string) error {
+		if sessionID != "mine" {
+			return fmt.Errorf("%w: %s", ErrForbidden, sessionID)
+		}
+		return nil
+	}))
+	h := NewHTTPHandler(server, nil)
+
+	// A client bound to no session still cannot read another user's session
+	rec := post(t, h, "", nil, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`)
+	sid := rec.Header().Get(SessionHeader)
+	read := `{"jsonrpc":"2.0","id":2,"method":"resources/read","params":{"uri":"gridmate://sessions/theirs/memory?query=revenue"}}`
+	if rec := post(t, h, sid, nil, read); rec.Code != http.StatusForbidden {
+		t.Errorf("reading another user's session = %d, want 403", rec.Code)
+	}
+	if len(runner.calls) != 0 {
+		t.Errorf("calls = %v, want none", runner.calls)
+	}
+}
+
+func TestServer_ReadOnly(t *testing.T) {
+	runner := &fakeRunner{registry: ai.NewBuiltinToolRegistry()}
+	server := NewServer(runner)
+	ctx := WithBinding(context.Background(), Binding{SessionID: "s1", AutonomyMode: AutonomyReadOnly})
+
+	for _, tool := range server.listTools(ctx) {
+		if !tool.Annotations.ReadOnlyHint {
+			t.Errorf("write tool %s listed in read-only mode", tool.Name)
+		}
+	}
+
+	result, rpcErr := server.callTool(ctx, callToolParams{Name: "apply_formula", Arguments: map[string]interface{}{"range": "A1", "formula": "=1"}})
+	if rpcErr != nil || !result.(callToolResult).IsError || len(runner.calls) != 0 {
+		t.Errorf("write in read-only mode: %+v %v %v", result, rpcErr, runner.calls)
+	}
+
+	read, rpcErr := server.readResource(ctx, "gridmate://sessions/s1/memory?query=revenue&limit=3")
+	if rpcErr != nil {
+		t.Fatalf("readResource() error = %v", rpcErr)
+	}
+	contents := read.(map[string]interface{})["contents"].([]resourceContents)
+	if !strings.Contains(contents[0].Text, "revenue") || runner.calls[0] != "s1 search_memory read-only" {
+		t.Errorf("memory resource = %+v, calls %v", contents, runner.calls)
+	}
+	if _, rpcErr := server.readResource(ctx, "gridmate://sessions/s2/memory?query=x"); rpcErr == nil {
+		t.Error("read another session's resource")
+	}
+}
+
+func TestServeStdio(t *testing.T) {
+	server := NewServer(&fakeRunner{registry: ai.NewBuiltinToolRegistry()})
+	in := strings.NewReader(`[{"jsonrpc":"2.0","id":1,"method":"ping"},{"jsonrpc":"2.0","method":"notifications/initialized"}]` + "\n" +
+		`{"jsonrpc":"2.0","id":"x","method":"nope"}` + "\n")
+	var out bytes.Buffer
+	if err := ServeStdio(context.Background(), in, &out, server); err != nil {
+		t.Fatalf("ServeStdio() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d replies: %q", len(lines), out.String())
+	}
+	joined := strings.Join(lines, "\n")
+	if !strings.Contains(joined, `[{"jsonrpc":"2.0","id":1,"result":{}}]`) || !strings.Contains(joined, `"code":-32601`) {
+		t.Errorf("unexpected replies: %s", joined)
+	}
+}
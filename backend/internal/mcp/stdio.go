@@ -0,0 +1,183 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxStdioMessageSize caps a single newline-delimited message
+const maxStdioMessageSize = 16 << 20
+
+// ServeStdio serves newline-delimited JSON-RPC messages from in and writes
+// replies to out until in is closed or ctx is cancelled. Messages are
+// handled concurrently so a slow tool call does not hold up pings.
+func ServeStdio(ctx context.Context, in io.Reader, out io.Writer, handler MessageHandler) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), maxStdioMessageSize)
+
+	var wg sync.WaitGroup
+	var writeMu sync.Mutex
+	var writeErr error
+	defer wg.Wait()
+
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		data := append([]byte(nil), line...)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reply := handler.Handle(ctx, data)
+			if reply == nil {
+				return
+			}
+			writeMu.Lock()
+			defer writeMu.Unlock()
+			if _, err := out.Write(append(reply, '\n')); err != nil && writeErr == nil {
+				writeErr = err
+			}
+		}()
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read MCP message: %w", err)
+	}
+	wg.Wait()
+	return writeErr
+}
+
+// RemoteHandler forwards messages to the streamable HTTP endpoint of a
+// running Gridmate server. It lets stdio clients drive sessions that live
+// in that server, where the Excel add-in is connected.
+type RemoteHandler struct {
+	url     string
+	headers http.Header
+	client  *http.Client
+
+	mu        sync.Mutex
+	sessionID string
+}
+
+// NewRemoteHandler creates a handler for the MCP endpoint at url
+func NewRemoteHandler(url, token string, binding Binding) *RemoteHandler {
+	headers := make(http.Header)
+	if token != "" {
+		headers.Set("Authorization", "Bearer "+token)
+	}
+	if binding.SessionID != "" {
+		headers.Set(GridmateSession, binding.SessionID)
+	}
+	if binding.AutonomyMode != "" {
+		headers.Set(GridmateAutonomy, binding.AutonomyMode)
+	}
+	// Tool calls time out after 30 seconds on the server
+	return newHTTPHandler(url, headers, 60*time.Second)
+}
+
+func newHTTPHandler(url string, headers http.Header, timeout time.Duration) *RemoteHandler {
+	return &RemoteHandler{
+		url:     url,
+		headers: headers,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// Handle posts a message to the server and returns its reply
+func (h *RemoteHandler) Handle(ctx context.Context, data []byte) []byte {
+	reply, err := h.post(ctx, data)
+	if err != nil {
+		return errorReplies(data, newRPCError(codeInternalError, err.Error()))
+	}
+	return reply
+}
+
+func (h *RemoteHandler) post(ctx context.Context, data []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, values := range h.headers {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	h.mu.Lock()
+	if h.sessionID != "" {
+		req.Header.Set(SessionHeader, h.sessionID)
+	}
+	h.mu.Unlock()
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach MCP server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if id := resp.Header.Get(SessionHeader); id != "" {
+		h.mu.Lock()
+		h.sessionID = id
+		h.mu.Unlock()
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxStdioMessageSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusAccepted:
+		return nil, nil
+	case resp.StatusCode >= 300:
+		// Error bodies are JSON-RPC errors; pass on their message
+		if msgs, _, rpcErr := parseMessages(body); rpcErr == nil && len(msgs) == 1 && msgs[0].Error != nil {
+			return nil, fmt.Errorf("MCP server returned %d: %s", resp.StatusCode, msgs[0].Error.Message)
+		}
+		return nil, fmt.Errorf("MCP server returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	case strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream"):
+		return eventStreamData(body), nil
+	}
+	return bytes.TrimSpace(body), nil
+}
+
+// eventStreamData joins the data of the events in an SSE response
+func eventStreamData(body []byte) []byte {
+	var events [][]byte
+	for _, line := range bytes.Split(body, []byte("\n")) {
+		if data, ok := bytes.CutPrefix(bytes.TrimRight(line, "\r"), []byte("data:")); ok {
+			events = append(events, bytes.TrimSpace(data))
+		}
+	}
+	if len(events) == 1 {
+		return events[0]
+	}
+	if len(events) == 0 {
+		return nil
+	}
+	return append(append([]byte("["), bytes.Join(events, []byte(","))...), ']')
+}
+
+// errorReplies answers every request in data with rpcErr
+func errorReplies(data []byte, rpcErr *rpcError) []byte {
+	messages, batch, parseErr := parseMessages(data)
+	if parseErr != nil {
+		return marshalReply([]response{{JSONRPC: jsonRPCVersion, ID: []byte("null"), Error: parseErr}}, false)
+	}
+	var replies []response
+	for _, msg := range messages {
+		if msg.Method != "" && !msg.isNotification() {
+			replies = append(replies, response{JSONRPC: jsonRPCVersion, ID: msg.ID, Error: rpcErr})
+		}
+	}
+	return marshalReply(replies, batch)
+}
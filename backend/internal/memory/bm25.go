@@ -0,0 +1,141 @@
+package memory
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// BM25 parameters as commonly tuned for short passages
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// stopWords are dropped by Tokenize; they carry no signal for BM25
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "for": true, "from": true, "in": true, "is": true,
+	"it": true, "of": true, "on": true, "or": true, "the": true, "this": true,
+	"to": true, "was": true, "what": true, "where": true, "which": true, "with": true,
+}
+
+// Tokenize lowercases text and splits it into terms. Cell references keep
+// their shape ("Sheet1!$B$5" yields "sheet1" and "b5") so exact address
+// lookups score through BM25 as well as through boosts.
+func Tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '$'
+	})
+
+	tokens := make([]string, 0, len(fields))
+	for _, field := range fields {
+		field = strings.ReplaceAll(field, "$", "")
+		if field == "" || stopWords[field] {
+			continue
+		}
+		tokens = append(tokens, field)
+	}
+	return tokens
+}
+
+// BM25Index scores documents against keyword queries with Okapi BM25. It is
+// keyed by chunk ID and is not safe for concurrent use; stores guard it with
+// their own locks.
+type BM25Index struct {
+	docs        map[string]bm25Doc
+	postings    map[string]map[string]int // term -> id -> term frequency
+	totalLength int
+}
+
+type bm25Doc struct {
+	length int
+	terms  []string // Distinct terms, for removal
+}
+
+// NewBM25Index creates an empty index
+func NewBM25Index() *BM25Index {
+	return &BM25Index{
+		docs:     make(map[string]bm25Doc),
+		postings: make(map[string]map[string]int),
+	}
+}
+
+// Add indexes content under id, replacing any previous version
+func (b *BM25Index) Add(id, content string) {
+	b.Remove(id)
+
+	tokens := Tokenize(content)
+	doc := bm25Doc{length: len(tokens)}
+	for _, token := range tokens {
+		posting, ok := b.postings[token]
+		if !ok {
+			posting = make(map[string]int)
+			b.postings[token] = posting
+		}
+		if posting[id] == 0 {
+			doc.terms = append(doc.terms, token)
+		}
+		posting[id]++
+	}
+
+	b.docs[id] = doc
+	b.totalLength += doc.length
+}
+
+// Remove drops id from the index
+func (b *BM25Index) Remove(id string) {
+	doc, ok := b.docs[id]
+	if !ok {
+		return
+	}
+	for _, term := range doc.terms {
+		posting := b.postings[term]
+		delete(posting, id)
+		if len(posting) == 0 {
+			delete(b.postings, term)
+		}
+	}
+	b.totalLength -= doc.length
+	delete(b.docs, id)
+}
+
+// Len returns the number of indexed documents
+func (b *BM25Index) Len() int {
+	return len(b.docs)
+}
+
+// Score returns the BM25 score of every document matching at least one term
+func (b *BM25Index) Score(terms []string) map[string]float64 {
+	scores := make(map[string]float64)
+	if len(b.docs) == 0 {
+		return scores
+	}
+
+	n := float64(len(b.docs))
+	avgLength := float64(b.totalLength) / n
+	if avgLength == 0 {
+		avgLength = 1
+	}
+
+	seen := make(map[string]bool, len(terms))
+	for _, term := range terms {
+		posting := b.postings[term]
+		if seen[term] || len(posting) == 0 {
+			continue
+		}
+		seen[term] = true
+
+		// The +1 keeps IDF positive for terms present in most documents
+		df := float64(len(posting))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+
+		for id, freq := range posting {
+			tf := float64(freq)
+			norm := tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*float64(b.docs[id].length)/avgLength))
+			scores[id] += idf * norm
+		}
+	}
+
+	return scores
+}
@@ -2,9 +2,12 @@ package memory
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/gob"
 	"fmt"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/boltdb/bolt"
@@ -13,46 +16,138 @@ import (
 const (
 	chunksBucket = "chunks"
 	metaBucket   = "metadata"
+	expiryBucket = "expiry"
 )
 
-// BoltDBStore provides persistent vector storage using BoltDB
+// BoltConfig tunes the BoltDB store
+type BoltConfig struct {
+	NamespaceQuota      int           // Max chunks per namespace, 0 for no cap
+	TTL                 TTLPolicy     // Chunk lifetime per source, empty to keep forever
+	MaintenanceInterval time.Duration // How often to expire chunks and compact, 0 to disable
+}
+
+// BoltDBStore provides persistent vector storage using BoltDB. Each
+// namespace is a nested bucket under the chunks bucket, keyed by chunk ID.
+// An expiry bucket indexes chunks by source and timestamp so TTL sweeps
+// never scan the whole store.
 type BoltDBStore struct {
-	db   *bolt.DB
-	path string
+	db     *bolt.DB
+	path   string
+	config BoltConfig
+
+	// mu guards db, which Compact and Restore swap for a new file
+	mu   sync.RWMutex
+	stop chan struct{}
 }
 
 // NewBoltDBStore creates a new BoltDB-backed store
-func NewBoltDBStore(path string) *BoltDBStore {
-	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 1 * time.Second})
+func NewBoltDBStore(path string, opts ...Option) *BoltDBStore {
+	store, err := OpenBoltDBStore(path, opts...)
 	if err != nil {
 		panic(fmt.Sprintf("Failed to open bolt database: %v", err))
 	}
+	return store
+}
+
+// OpenBoltDBStore opens a BoltDB-backed store, returning an error instead
+// of panicking when the file is locked or invalid
+func OpenBoltDBStore(path string, opts ...Option) (*BoltDBStore, error) {
+	config := BoltConfig{}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	db, err := openBolt(path)
+	if err != nil {
+		return nil, err
+	}
+
+	store := &BoltDBStore{
+		db:     db,
+		path:   path,
+		config: config,
+		stop:   make(chan struct{}),
+	}
+
+	if config.MaintenanceInterval > 0 {
+		go store.maintenanceRoutine()
+	}
+
+	return store, nil
+}
+
+// openBolt opens the database and creates its buckets. Files written
+// before the expiry index existed get it built on first open.
+func openBolt(path string) (*bolt.DB, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return nil, err
+	}
 
-	// Initialize buckets
 	err = db.Update(func(tx *bolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists([]byte(chunksBucket))
-		if err != nil {
+		if _, err := tx.CreateBucketIfNotExists([]byte(chunksBucket)); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists([]byte(metaBucket)); err != nil {
 			return err
 		}
-		_, err = tx.CreateBucketIfNotExists([]byte(metaBucket))
+		if tx.Bucket([]byte(expiryBucket)) != nil {
+			return nil
+		}
+		_, err := rebuildIndexes(tx)
 		return err
 	})
 	if err != nil {
-		panic(fmt.Sprintf("Failed to create buckets: %v", err))
+		db.Close()
+		return nil, fmt.Errorf("failed to create buckets: %w", err)
 	}
 
-	return &BoltDBStore{
-		db:   db,
-		path: path,
-	}
+	return db, nil
 }
 
-// Add chunks to the store
-func (b *BoltDBStore) Add(chunks []Chunk) error {
-	return b.db.Update(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(chunksBucket))
+// Add chunks to a namespace
+func (b *BoltDBStore) Add(ns Namespace, chunks []Chunk) error {
+	if err := ns.Validate(); err != nil {
+		return err
+	}
 
+	now := time.Now()
+	return b.update(func(tx *bolt.Tx) error {
+		bucket, err := tx.Bucket([]byte(chunksBucket)).CreateBucketIfNotExists([]byte(ns.Key()))
+		if err != nil {
+			return fmt.Errorf("failed to create namespace bucket: %w", err)
+		}
+		expiry := tx.Bucket([]byte(expiryBucket))
+
+		added := make(map[string]bool)
+		for _, chunk := range chunks {
+			if bucket.Get([]byte(chunk.ID)) == nil {
+				added[chunk.ID] = true
+			}
+		}
+		if err := checkQuota(ns, b.config.NamespaceQuota, bucket.Stats().KeyN, len(added)); err != nil {
+			return err
+		}
+
+		delta := make(statsDelta)
 		for _, chunk := range chunks {
+			chunk.Namespace = ns
+			if chunk.Timestamp.IsZero() {
+				chunk.Timestamp = now
+			}
+
+			// Replacing a chunk drops its old expiry entry
+			if v := bucket.Get([]byte(chunk.ID)); v != nil {
+				old, err := decodeChunk(v)
+				if err != nil {
+					return err
+				}
+				if err := expiry.Delete(expiryKey(ns, old)); err != nil {
+					return err
+				}
+				delta[old.Metadata.Source]--
+			}
+
 			// Serialize chunk
 			var buf bytes.Buffer
 			enc := gob.NewEncoder(&buf)
@@ -64,17 +159,22 @@ func (b *BoltDBStore) Add(chunks []Chunk) error {
 			if err := bucket.Put([]byte(chunk.ID), buf.Bytes()); err != nil {
 				return fmt.Errorf("failed to store chunk: %w", err)
 			}
+			if err := expiry.Put(expiryKey(ns, chunk), nil); err != nil {
+				return fmt.Errorf("failed to index chunk: %w", err)
+			}
+			delta[chunk.Metadata.Source]++
 		}
 
-		// Update stats
-		b.updateStats(tx)
-
-		return nil
+		return applyStats(tx, delta)
 	})
 }
 
-// Search performs vector similarity search
-func (b *BoltDBStore) Search(query []float32, topK int, filter FilterFunc) ([]SearchResult, error) {
+// Search performs vector similarity search over the chunks visible to ns
+func (b *BoltDBStore) Search(ns Namespace, query []float32, topK int, filter FilterFunc) ([]SearchResult, error) {
+	if err := ns.Validate(); err != nil {
+		return nil, err
+	}
+
 	var results []SearchResult
 
 	// Normalize query
@@ -85,35 +185,31 @@ func (b *BoltDBStore) Search(query []float32, topK int, filter FilterFunc) ([]Se
 		}
 	}
 
-	err := b.db.View(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(chunksBucket))
-
-		// Iterate through all chunks
-		return bucket.ForEach(func(k, v []byte) error {
-			var chunk Chunk
-			dec := gob.NewDecoder(bytes.NewReader(v))
-			if err := dec.Decode(&chunk); err != nil {
-				return err
-			}
-
-			// Apply filter
-			if filter != nil && !filter(chunk) {
+	err := b.view(func(tx *bolt.Tx) error {
+		return forEachNamespace(tx, func(space Namespace) error {
+			if !ns.Sees(space) {
 				return nil
 			}
+			return forEachChunk(tx, space, func(k []byte, chunk Chunk) error {
+				// Apply filter
+				if filter != nil && !filter(chunk) {
+					return nil
+				}
+
+				// Calculate similarity
+				similarity := dotProduct(query, chunk.Vector)
+
+				// Only include results above threshold
+				if similarity > 0.7 {
+					results = append(results, SearchResult{
+						Chunk:      chunk,
+						Similarity: similarity,
+						Score:      similarity,
+					})
+				}
 
-			// Calculate similarity
-			similarity := dotProduct(query, chunk.Vector)
-
-			// Only include results above threshold
-			if similarity > 0.7 {
-				results = append(results, SearchResult{
-					Chunk:      chunk,
-					Similarity: similarity,
-					Score:      similarity,
-				})
-			}
-
-			return nil
+				return nil
+			})
 		})
 	})
 
@@ -134,25 +230,23 @@ func (b *BoltDBStore) Search(query []float32, topK int, filter FilterFunc) ([]Se
 	return results, nil
 }
 
-// Delete removes chunks matching the filter
-func (b *BoltDBStore) Delete(filter FilterFunc) error {
-	return b.db.Update(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(chunksBucket))
+// Delete removes chunks in ns matching the filter
+func (b *BoltDBStore) Delete(ns Namespace, filter FilterFunc) error {
+	if err := ns.Validate(); err != nil {
+		return err
+	}
+	if filter == nil {
+		return nil
+	}
 
-		// Collect keys to delete
-		var keysToDelete [][]byte
+	return b.update(func(tx *bolt.Tx) error {
+		// Collect chunks to delete
+		var matched []Chunk
 
-		err := bucket.ForEach(func(k, v []byte) error {
-			var chunk Chunk
-			dec := gob.NewDecoder(bytes.NewReader(v))
-			if err := dec.Decode(&chunk); err != nil {
-				return err
+		err := forEachChunk(tx, ns, func(k []byte, chunk Chunk) error {
+			if filter(chunk) {
+				matched = append(matched, chunk)
 			}
-
-			if filter != nil && filter(chunk) {
-				keysToDelete = append(keysToDelete, k)
-			}
-
 			return nil
 		})
 
@@ -160,33 +254,74 @@ func (b *BoltDBStore) Delete(filter FilterFunc) error {
 			return err
 		}
 
-		// Delete collected keys
-		for _, key := range keysToDelete {
-			if err := bucket.Delete(key); err != nil {
+		delta, err := deleteChunks(tx, matched)
+		if err != nil {
+			return err
+		}
+		return applyStats(tx, delta)
+	})
+}
+
+// Purge drops the namespace's bucket, or every bucket of a tenant when ns
+// has no session
+func (b *BoltDBStore) Purge(ns Namespace) (int, error) {
+	if err := ns.Validate(); err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	err := b.update(func(tx *bolt.Tx) error {
+		root := tx.Bucket([]byte(chunksBucket))
+		expiry := tx.Bucket([]byte(expiryBucket))
+
+		var spaces []Namespace
+		forEachNamespace(tx, func(space Namespace) error {
+			if ns.Covers(space) {
+				spaces = append(spaces, space)
+			}
+			return nil
+		})
+
+		delta := make(statsDelta)
+		for _, space := range spaces {
+			err := forEachChunk(tx, space, func(k []byte, chunk Chunk) error {
+				delta[chunk.Metadata.Source]--
+				removed++
+				return expiry.Delete(expiryKey(space, chunk))
+			})
+			if err != nil {
 				return err
 			}
+			if err := root.DeleteBucket([]byte(space.Key())); err != nil {
+				return fmt.Errorf("failed to purge namespace %s: %w", space, err)
+			}
 		}
 
-		// Update stats
-		b.updateStats(tx)
+		return applyStats(tx, delta)
+	})
+	return removed, err
+}
 
-		return nil
+// NamespaceStats returns statistics for the chunks stored in ns
+func (b *BoltDBStore) NamespaceStats(ns Namespace) Stats {
+	stats := Stats{LastUpdated: time.Now(), Quota: b.config.NamespaceQuota}
+
+	b.view(func(tx *bolt.Tx) error {
+		return forEachChunk(tx, ns, func(k []byte, chunk Chunk) error {
+			stats.countSource(chunk.Metadata.Source)
+			return nil
+		})
 	})
+
+	return stats
 }
 
 // GetStats returns statistics about the store
 func (b *BoltDBStore) GetStats() Stats {
 	var stats Stats
 
-	b.db.View(func(tx *bolt.Tx) error {
-		metaBucket := tx.Bucket([]byte(metaBucket))
-		if metaBucket != nil {
-			v := metaBucket.Get([]byte("stats"))
-			if v != nil {
-				dec := gob.NewDecoder(bytes.NewReader(v))
-				dec.Decode(&stats)
-			}
-		}
+	b.view(func(tx *bolt.Tx) error {
+		stats = readStats(tx)
 
 		// Get database size
 		stats.StorageSize = tx.Size()
@@ -194,32 +329,34 @@ func (b *BoltDBStore) GetStats() Stats {
 		return nil
 	})
 
+	stats.Quota = b.config.NamespaceQuota
 	return stats
 }
 
-// Close closes the database
+// Close stops background maintenance and closes the database
 func (b *BoltDBStore) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	select {
+	case <-b.stop:
+	default:
+		close(b.stop)
+	}
 	return b.db.Close()
 }
 
-// GetRecentChunks returns the most recent n chunks
+// GetRecentChunks returns the most recent n chunks across all namespaces
 func (b *BoltDBStore) GetRecentChunks(n int) []Chunk {
 	var chunks []Chunk
 
-	b.db.View(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(chunksBucket))
-
-		// Collect all chunks
-		bucket.ForEach(func(k, v []byte) error {
-			var chunk Chunk
-			dec := gob.NewDecoder(bytes.NewReader(v))
-			if err := dec.Decode(&chunk); err == nil {
+	b.view(func(tx *bolt.Tx) error {
+		return forEachNamespace(tx, func(ns Namespace) error {
+			return forEachChunk(tx, ns, func(k []byte, chunk Chunk) error {
 				chunks = append(chunks, chunk)
-			}
-			return nil
+				return nil
+			})
 		})
-
-		return nil
 	})
 
 	// Sort by timestamp descending
@@ -234,33 +371,84 @@ func (b *BoltDBStore) GetRecentChunks(n int) []Chunk {
 	return chunks
 }
 
-// updateStats updates the statistics in the metadata bucket
-func (b *BoltDBStore) updateStats(tx *bolt.Tx) error {
-	chunksBucket := tx.Bucket([]byte(chunksBucket))
-	metaBucket := tx.Bucket([]byte(metaBucket))
+// Namespaces lists the namespaces holding chunks
+func (b *BoltDBStore) Namespaces() []Namespace {
+	var spaces []Namespace
 
-	stats := Stats{
-		LastUpdated: time.Now(),
-	}
+	b.view(func(tx *bolt.Tx) error {
+		return forEachNamespace(tx, func(ns Namespace) error {
+			spaces = append(spaces, ns)
+			return nil
+		})
+	})
+
+	return spaces
+}
 
-	// Count chunks by type
-	chunksBucket.ForEach(func(k, v []byte) error {
-		var chunk Chunk
-		dec := gob.NewDecoder(bytes.NewReader(v))
-		if err := dec.Decode(&chunk); err == nil {
-			stats.TotalChunks++
-			switch chunk.Metadata.Source {
-			case "spreadsheet":
-				stats.SpreadsheetChunks++
-			case "document":
-				stats.DocumentChunks++
-			case "chat":
-				stats.ChatChunks++
+// keys returns the store keys of chunks in namespaces accepted by match
+func (b *BoltDBStore) keys(match func(Namespace) bool) map[string]bool {
+	keys := make(map[string]bool)
+
+	b.view(func(tx *bolt.Tx) error {
+		root := tx.Bucket([]byte(chunksBucket))
+		return forEachNamespace(tx, func(ns Namespace) error {
+			if !match(ns) {
+				return nil
 			}
-		}
-		return nil
+			return root.Bucket([]byte(ns.Key())).ForEach(func(k, v []byte) error {
+				keys[storeKey(ns, string(k))] = true
+				return nil
+			})
+		})
+	})
+
+	return keys
+}
+
+// Chunks returns every chunk stored in ns
+func (b *BoltDBStore) Chunks(ns Namespace) []Chunk {
+	var chunks []Chunk
+
+	b.view(func(tx *bolt.Tx) error {
+		return forEachChunk(tx, ns, func(k []byte, chunk Chunk) error {
+			chunks = append(chunks, chunk)
+			return nil
+		})
 	})
 
+	return chunks
+}
+
+// view runs fn in a read transaction
+func (b *BoltDBStore) view(fn func(tx *bolt.Tx) error) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.db.View(fn)
+}
+
+// update runs fn in a write transaction
+func (b *BoltDBStore) update(fn func(tx *bolt.Tx) error) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.db.Update(fn)
+}
+
+// statsDelta accumulates chunk count changes by source within a transaction
+type statsDelta map[string]int
+
+// readStats decodes the statistics in the metadata bucket
+func readStats(tx *bolt.Tx) Stats {
+	var stats Stats
+	if v := tx.Bucket([]byte(metaBucket)).Get([]byte("stats")); v != nil {
+		gob.NewDecoder(bytes.NewReader(v)).Decode(&stats)
+	}
+	return stats
+}
+
+// writeStats stores statistics in the metadata bucket
+func writeStats(tx *bolt.Tx, stats Stats) error {
+	stats.LastUpdated = time.Now()
+
 	// Serialize and store stats
 	var buf bytes.Buffer
 	enc := gob.NewEncoder(&buf)
@@ -268,5 +456,92 @@ func (b *BoltDBStore) updateStats(tx *bolt.Tx) error {
 		return err
 	}
 
-	return metaBucket.Put([]byte("stats"), buf.Bytes())
-}
\ No newline at end of file
+	return tx.Bucket([]byte(metaBucket)).Put([]byte("stats"), buf.Bytes())
+}
+
+// applyStats adjusts the stored statistics by delta
+func applyStats(tx *bolt.Tx, delta statsDelta) error {
+	stats := readStats(tx)
+	for source, n := range delta {
+		stats.addSource(source, n)
+	}
+	return writeStats(tx, stats)
+}
+
+// deleteChunks removes chunks, which carry their namespace, along with
+// their expiry entries
+func deleteChunks(tx *bolt.Tx, chunks []Chunk) (statsDelta, error) {
+	root := tx.Bucket([]byte(chunksBucket))
+	expiry := tx.Bucket([]byte(expiryBucket))
+
+	delta := make(statsDelta)
+	for _, chunk := range chunks {
+		bucket := root.Bucket([]byte(chunk.Namespace.Key()))
+		if bucket == nil || bucket.Get([]byte(chunk.ID)) == nil {
+			continue
+		}
+		if err := bucket.Delete([]byte(chunk.ID)); err != nil {
+			return nil, err
+		}
+		if err := expiry.Delete(expiryKey(chunk.Namespace, chunk)); err != nil {
+			return nil, err
+		}
+		delta[chunk.Metadata.Source]--
+	}
+	return delta, nil
+}
+
+// expiryKey orders a chunk by source, then timestamp:
+// source 0x00 unix-nanos(8 bytes) tenant/session 0x00 id
+func expiryKey(ns Namespace, chunk Chunk) []byte {
+	key := make([]byte, 0, len(chunk.Metadata.Source)+9+len(ns.Key())+1+len(chunk.ID))
+	key = append(key, chunk.Metadata.Source...)
+	key = append(key, 0)
+	var nanos uint64
+	if !chunk.Timestamp.IsZero() {
+		nanos = uint64(chunk.Timestamp.UnixNano())
+	}
+	key = binary.BigEndian.AppendUint64(key, nanos)
+	return append(key, storeKey(ns, chunk.ID)...)
+}
+
+// forEachNamespace visits every namespace bucket. Chunks written before
+// namespaces existed sit directly in the chunks bucket and are skipped.
+func forEachNamespace(tx *bolt.Tx, fn func(ns Namespace) error) error {
+	return tx.Bucket([]byte(chunksBucket)).ForEach(func(k, v []byte) error {
+		if v != nil || !strings.Contains(string(k), "/") {
+			return nil
+		}
+		ns, err := ParseNamespace(string(k))
+		if err != nil {
+			return nil
+		}
+		return fn(ns)
+	})
+}
+
+// forEachChunk decodes every chunk in a namespace bucket
+func forEachChunk(tx *bolt.Tx, ns Namespace, fn func(k []byte, chunk Chunk) error) error {
+	bucket := tx.Bucket([]byte(chunksBucket)).Bucket([]byte(ns.Key()))
+	if bucket == nil {
+		return nil
+	}
+
+	return bucket.ForEach(func(k, v []byte) error {
+		chunk, err := decodeChunk(v)
+		if err != nil {
+			return err
+		}
+		chunk.Namespace = ns
+		return fn(k, chunk)
+	})
+}
+
+func decodeChunk(v []byte) (Chunk, error) {
+	var chunk Chunk
+	dec := gob.NewDecoder(bytes.NewReader(v))
+	if err := dec.Decode(&chunk); err != nil {
+		return chunk, fmt.Errorf("failed to decode chunk: %w", err)
+	}
+	return chunk, nil
+}
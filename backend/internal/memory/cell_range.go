@@ -0,0 +1,105 @@
+package memory
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CellBounds is a rectangular block of cells with 1-based, inclusive
+// columns and rows
+type CellBounds struct {
+	Sheet    string // Empty when the range had no sheet prefix
+	StartCol int
+	StartRow int
+	EndCol   int
+	EndRow   int
+}
+
+// ParseCellRange parses "B5", "A1:D10" or "Sheet1!$A$1:$D$10"
+func ParseCellRange(cellRange string) (CellBounds, bool) {
+	var bounds CellBounds
+	if idx := strings.LastIndex(cellRange, "!"); idx >= 0 {
+		bounds.Sheet = strings.Trim(cellRange[:idx], "'")
+		cellRange = cellRange[idx+1:]
+	}
+	if cellRange == "" {
+		return bounds, false
+	}
+
+	parts := strings.SplitN(strings.ReplaceAll(cellRange, "$", ""), ":", 2)
+	startCol, startRow, ok := splitCell(parts[0])
+	if !ok {
+		return bounds, false
+	}
+	endCol, endRow := startCol, startRow
+	if len(parts) == 2 {
+		if endCol, endRow, ok = splitCell(parts[1]); !ok {
+			return bounds, false
+		}
+	}
+
+	bounds.StartCol, bounds.EndCol = min(startCol, endCol), max(startCol, endCol)
+	bounds.StartRow, bounds.EndRow = min(startRow, endRow), max(startRow, endRow)
+	return bounds, true
+}
+
+// Contains reports whether the 1-based cell lies inside the bounds
+func (b CellBounds) Contains(col, row int) bool {
+	return col >= b.StartCol && col <= b.EndCol && row >= b.StartRow && row <= b.EndRow
+}
+
+// Overlaps reports whether two blocks share a cell. Sheets are only
+// compared when both are known.
+func (b CellBounds) Overlaps(other CellBounds) bool {
+	if b.Sheet != "" && other.Sheet != "" && b.Sheet != other.Sheet {
+		return false
+	}
+	return b.StartCol <= other.EndCol && other.StartCol <= b.EndCol &&
+		b.StartRow <= other.EndRow && other.StartRow <= b.EndRow
+}
+
+// String formats the bounds in A1 notation without the sheet
+func (b CellBounds) String() string {
+	start := columnLetters(b.StartCol) + strconv.Itoa(b.StartRow)
+	if b.StartCol == b.EndCol && b.StartRow == b.EndRow {
+		return start
+	}
+	return fmt.Sprintf("%s:%s%d", start, columnLetters(b.EndCol), b.EndRow)
+}
+
+// splitCell parses "B12" into 1-based column and row
+func splitCell(cell string) (col, row int, ok bool) {
+	i := 0
+	for i < len(cell) && (cell[i] >= 'A' && cell[i] <= 'Z' || cell[i] >= 'a' && cell[i] <= 'z') {
+		i++
+	}
+	if i == 0 || i == len(cell) {
+		return 0, 0, false
+	}
+	row, err := strconv.Atoi(cell[i:])
+	if err != nil || row < 1 {
+		return 0, 0, false
+	}
+	return columnNumber(cell[:i]), row, true
+}
+
+// columnNumber converts column letters to a 1-based index (A=1, AA=27)
+func columnNumber(letters string) int {
+	col := 0
+	for _, ch := range strings.ToUpper(letters) {
+		col = col*26 + int(ch-'A') + 1
+	}
+	return col
+}
+
+// columnLetters converts a 1-based column index to letters (27=AA)
+func columnLetters(col int) string {
+	letters := ""
+	for col > 0 {
+		col--
+		letters = string(rune('A'+col%26)) + letters
+		col /= 26
+	}
+	return letters
+}
@@ -67,6 +67,57 @@ func (c *SpreadsheetChunker) ChunkSpreadsheet(sheet *models.Sheet) []memory.Chun
 	return chunks
 }
 
+// ChunkRanges re-chunks only the tables, sections, formulas and named
+// ranges that overlap changed, plus a fresh overview of the sheet. Regions
+// are detected on the current data, so a table that grew past an edit is
+// returned with its new bounds.
+func (c *SpreadsheetChunker) ChunkRanges(sheet *models.Sheet, changed []memory.CellBounds) []memory.Chunk {
+	if sheet == nil || sheet.Data == nil || len(changed) == 0 {
+		return []memory.Chunk{}
+	}
+
+	data := sheet.Data
+	touches := func(startRow, startCol, endRow, endCol int) bool {
+		region := memory.CellBounds{
+			StartCol: startCol + 1, StartRow: startRow + 1,
+			EndCol: endCol + 1, EndRow: endRow + 1,
+		}
+		for _, bounds := range changed {
+			if bounds.Overlaps(region) {
+				return true
+			}
+		}
+		return false
+	}
+
+	chunks := []memory.Chunk{}
+	for _, table := range c.detectTables(data) {
+		if touches(table.StartRow, table.StartCol, table.EndRow, table.EndCol) {
+			chunks = append(chunks, c.chunkTable(table, data))
+		}
+	}
+	for _, section := range c.detectFinancialSections(data) {
+		if touches(section.StartRow, section.StartCol, section.EndRow, section.EndCol) {
+			chunks = append(chunks, c.chunkSection(section, data))
+		}
+	}
+	for _, formula := range c.extractComplexFormulas(data) {
+		if touches(formula.Row, formula.Col, formula.Row, formula.Col) {
+			chunks = append(chunks, c.chunkFormula(formula, data))
+		}
+	}
+	for _, nr := range c.extractNamedRanges(data) {
+		if bounds, ok := memory.ParseCellRange(nr.Range); ok && touches(bounds.StartRow-1, bounds.StartCol-1, bounds.EndRow-1, bounds.EndCol-1) {
+			chunks = append(chunks, c.chunkNamedRange(nr, data))
+		}
+	}
+
+	// Cell and formula counts change with any edit
+	chunks = append(chunks, c.createSheetOverview(sheet))
+
+	return chunks
+}
+
 // detectTables finds table structures in the data
 func (c *SpreadsheetChunker) detectTables(data *models.RangeData) []TableRegion {
 	tables := []TableRegion{}
@@ -0,0 +1,764 @@
+package memory
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const hnswSnapshotVersion = 2 // 2: chunks carry their namespace
+
+// HNSWConfig tunes the approximate nearest neighbour graph
+type HNSWConfig struct {
+	M              int     // Neighbours per node on upper layers (layer 0 keeps 2*M)
+	EfConstruction int     // Candidate list size while inserting
+	EfSearch       int     // Candidate list size while searching
+	MinSimilarity  float32 // Results below this cosine similarity are dropped
+	RebuildRatio   float64 // Rebuild the graph once this share of nodes is deleted
+	Seed           int64
+	NamespaceQuota int // Max chunks per namespace, 0 for no cap
+}
+
+// HNSWStore is a VectorStore backed by a Hierarchical Navigable Small World
+// graph. Search is approximate but sub-linear, unlike the brute-force
+// InMemoryStore and BoltDBStore. Deletes are tombstoned and the graph is
+// rebuilt once enough nodes are dead.
+type HNSWStore struct {
+	config HNSWConfig
+	mu     sync.RWMutex
+
+	nodes    []*hnswNode
+	ids      map[string]int // Store key -> live node index
+	counts   map[Namespace]int
+	entry    int
+	maxLevel int
+	deleted  int
+	dim      int
+
+	levelMult float64
+	rng       *rand.Rand
+
+	// BM25 index over live chunk content for keyword and hybrid search,
+	// keyed by store key
+	keywords *BM25Index
+}
+
+type hnswNode struct {
+	Chunk     Chunk
+	Level     int
+	Neighbors [][]int32 // Per layer
+	Deleted   bool
+}
+
+// NewHNSWStore creates an empty HNSW store
+func NewHNSWStore(opts ...Option) *HNSWStore {
+	config := HNSWConfig{
+		M:              16,
+		EfConstruction: 200,
+		EfSearch:       128,
+		MinSimilarity:  0.7, // Same cut-off as the brute-force stores
+		RebuildRatio:   0.3,
+		Seed:           time.Now().UnixNano(),
+	}
+
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return newHNSWStore(config)
+}
+
+func newHNSWStore(config HNSWConfig) *HNSWStore {
+	if config.M < 2 {
+		config.M = 2
+	}
+	if config.EfConstruction < config.M {
+		config.EfConstruction = config.M
+	}
+	return &HNSWStore{
+		config:    config,
+		ids:       make(map[string]int),
+		counts:    make(map[Namespace]int),
+		entry:     -1,
+		levelMult: 1 / math.Log(float64(config.M)),
+		rng:       rand.New(rand.NewSource(config.Seed)),
+		keywords:  NewBM25Index(),
+	}
+}
+
+// Add inserts chunks into a namespace. A chunk with an existing ID in the
+// namespace replaces the previous version.
+func (h *HNSWStore) Add(ns Namespace, chunks []Chunk) error {
+	if err := ns.Validate(); err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	added := make(map[string]bool)
+	for _, chunk := range chunks {
+		if _, ok := h.ids[storeKey(ns, chunk.ID)]; !ok {
+			added[chunk.ID] = true
+		}
+	}
+	if err := checkQuota(ns, h.config.NamespaceQuota, h.counts[ns], len(added)); err != nil {
+		return err
+	}
+
+	for _, chunk := range chunks {
+		chunk.Namespace = ns
+		if len(chunk.Vector) == 0 {
+			return fmt.Errorf("chunk %s has no vector", chunk.ID)
+		}
+		if h.dim == 0 {
+			h.dim = len(chunk.Vector)
+		} else if len(chunk.Vector) != h.dim {
+			return fmt.Errorf("chunk %s has dimension %d, index uses %d", chunk.ID, len(chunk.Vector), h.dim)
+		}
+
+		if idx, ok := h.ids[storeKey(ns, chunk.ID)]; ok {
+			h.tombstone(idx)
+		}
+
+		chunk.Vector = NormalizeVector(chunk.Vector)
+		h.insert(chunk)
+	}
+
+	h.maybeRebuild()
+	return nil
+}
+
+// Search returns the topK most similar chunks visible to ns that pass the
+// filter
+func (h *HNSWStore) Search(ns Namespace, query []float32, topK int, filter FilterFunc) ([]SearchResult, error) {
+	if err := ns.Validate(); err != nil {
+		return nil, err
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	visible := 0
+	for space, count := range h.counts {
+		if ns.Sees(space) {
+			visible += count
+		}
+	}
+	if visible == 0 || topK <= 0 {
+		return nil, nil
+	}
+	if len(query) != h.dim {
+		return nil, fmt.Errorf("query has dimension %d, index uses %d", len(query), h.dim)
+	}
+
+	query = NormalizeVector(query)
+	entry := h.descend(query, 1)
+
+	// Filters, other namespaces and tombstones can starve the candidate
+	// list, so widen the beam until enough results survive, every visible
+	// chunk was found or the whole graph was visited
+	ef := h.config.EfSearch
+	if ef < topK {
+		ef = topK
+	}
+	for {
+		candidates := h.searchLayer(query, []int{entry}, ef, 0)
+
+		results := make([]SearchResult, 0, topK)
+		for _, c := range candidates {
+			node := h.nodes[c.id]
+			if node.Deleted || c.sim < h.config.MinSimilarity || !ns.Sees(node.Chunk.Namespace) {
+				continue
+			}
+			if filter != nil && !filter(node.Chunk) {
+				continue
+			}
+			results = append(results, SearchResult{
+				Chunk:      node.Chunk,
+				Similarity: c.sim,
+				Score:      c.sim,
+			})
+			if len(results) == topK {
+				break
+			}
+		}
+
+		unfiltered := filter == nil && h.deleted == 0 && visible == len(h.ids)
+		if len(results) >= topK || len(results) >= visible || ef >= len(h.nodes) || unfiltered {
+			return results, nil
+		}
+		ef *= 4
+	}
+}
+
+// Delete tombstones every chunk in ns matching the filter
+func (h *HNSWStore) Delete(ns Namespace, filter FilterFunc) error {
+	if err := ns.Validate(); err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, idx := range h.ids {
+		chunk := h.nodes[idx].Chunk
+		if filter != nil && chunk.Namespace == ns && filter(chunk) {
+			h.tombstone(idx)
+		}
+	}
+
+	h.maybeRebuild()
+	return nil
+}
+
+// DeleteByID removes a specific chunk
+func (h *HNSWStore) DeleteByID(ns Namespace, id string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if idx, ok := h.ids[storeKey(ns, id)]; ok {
+		h.tombstone(idx)
+		h.maybeRebuild()
+	}
+}
+
+// Purge tombstones every chunk in ns, or in all of a tenant's namespaces
+// when ns has no session
+func (h *HNSWStore) Purge(ns Namespace) (int, error) {
+	if err := ns.Validate(); err != nil {
+		return 0, err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	removed := 0
+	for _, idx := range h.ids {
+		if ns.Covers(h.nodes[idx].Chunk.Namespace) {
+			h.tombstone(idx)
+			removed++
+		}
+	}
+
+	h.maybeRebuild()
+	return removed, nil
+}
+
+// NamespaceStats returns statistics for the live chunks stored in ns
+func (h *HNSWStore) NamespaceStats(ns Namespace) Stats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	stats := Stats{LastUpdated: time.Now(), Quota: h.config.NamespaceQuota}
+	for _, idx := range h.ids {
+		if chunk := h.nodes[idx].Chunk; chunk.Namespace == ns {
+			stats.countSource(chunk.Metadata.Source)
+		}
+	}
+	stats.StorageSize = int64(stats.TotalChunks) * int64(h.dim*4+1024)
+	return stats
+}
+
+// GetStats returns statistics about the store
+func (h *HNSWStore) GetStats() Stats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	stats := Stats{
+		TotalChunks: len(h.ids),
+		LastUpdated: time.Now(),
+		Quota:       h.config.NamespaceQuota,
+	}
+
+	edges := 0
+	for _, node := range h.nodes {
+		for _, layer := range node.Neighbors {
+			edges += len(layer)
+		}
+		if node.Deleted {
+			continue
+		}
+		switch node.Chunk.Metadata.Source {
+		case "spreadsheet":
+			stats.SpreadsheetChunks++
+		case "document":
+			stats.DocumentChunks++
+		case "chat":
+			stats.ChatChunks++
+		}
+	}
+
+	// Vectors plus adjacency lists plus ~1KB of content and metadata per node
+	stats.StorageSize = int64(len(h.nodes))*int64(h.dim*4+1024) + int64(edges)*4
+
+	return stats
+}
+
+// KeywordSearch ranks live chunks visible to ns by BM25 over their content
+func (h *HNSWStore) KeywordSearch(ns Namespace, keywords []string, topK int, filter FilterFunc) []SearchResult {
+	if ns.Validate() != nil {
+		return nil
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	scores := h.keywords.Score(Tokenize(strings.Join(keywords, " ")))
+	results := make([]SearchResult, 0, len(scores))
+	for key, score := range scores {
+		chunk := h.nodes[h.ids[key]].Chunk
+		if !ns.Sees(chunk.Namespace) {
+			continue
+		}
+		if filter != nil && !filter(chunk) {
+			continue
+		}
+		results = append(results, SearchResult{Chunk: chunk, Score: float32(score)})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if len(results) > topK {
+		return results[:topK]
+	}
+	return results
+}
+
+// SetEfSearch trades search latency for recall
+func (h *HNSWStore) SetEfSearch(ef int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.config.EfSearch = ef
+}
+
+// Close is a no-op; use SaveSnapshot to persist the graph
+func (h *HNSWStore) Close() error {
+	return nil
+}
+
+// Size returns the number of live chunks
+func (h *HNSWStore) Size() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.ids)
+}
+
+// insert adds a normalized chunk to the graph. Callers hold the write lock.
+func (h *HNSWStore) insert(chunk Chunk) {
+	level := int(math.Floor(-math.Log(1-h.rng.Float64()) * h.levelMult))
+	idx := len(h.nodes)
+	node := &hnswNode{
+		Chunk:     chunk,
+		Level:     level,
+		Neighbors: make([][]int32, level+1),
+	}
+	h.nodes = append(h.nodes, node)
+	key := storeKey(chunk.Namespace, chunk.ID)
+	h.ids[key] = idx
+	h.counts[chunk.Namespace]++
+	h.keywords.Add(key, chunk.Content)
+
+	if h.entry < 0 {
+		h.entry = idx
+		h.maxLevel = level
+		return
+	}
+
+	// Greedy descent through layers above the new node's level
+	current := h.entry
+	currentSim := dot(chunk.Vector, h.nodes[current].Chunk.Vector)
+	for l := h.maxLevel; l > level; l-- {
+		current, currentSim = h.greedyStep(chunk.Vector, current, currentSim, l)
+	}
+
+	entryPoints := []int{current}
+	for l := minInt(level, h.maxLevel); l >= 0; l-- {
+		candidates := h.searchLayer(chunk.Vector, entryPoints, h.config.EfConstruction, l)
+		neighbors := h.selectNeighbors(candidates, h.config.M)
+
+		node.Neighbors[l] = make([]int32, len(neighbors))
+		for i, n := range neighbors {
+			node.Neighbors[l][i] = int32(n.id)
+			h.link(n.id, idx, l)
+		}
+
+		entryPoints = entryPoints[:0]
+		for _, c := range candidates {
+			entryPoints = append(entryPoints, c.id)
+		}
+	}
+
+	if level > h.maxLevel {
+		h.entry = idx
+		h.maxLevel = level
+	}
+}
+
+// link adds a reverse edge from -> to on a layer, pruning when full
+func (h *HNSWStore) link(from, to, level int) {
+	node := h.nodes[from]
+	node.Neighbors[level] = append(node.Neighbors[level], int32(to))
+
+	maxConn := h.config.M
+	if level == 0 {
+		maxConn = 2 * h.config.M
+	}
+	if len(node.Neighbors[level]) <= maxConn {
+		return
+	}
+
+	candidates := make([]hnswCandidate, len(node.Neighbors[level]))
+	for i, n := range node.Neighbors[level] {
+		candidates[i] = hnswCandidate{id: int(n), sim: dot(node.Chunk.Vector, h.nodes[n].Chunk.Vector)}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].sim > candidates[j].sim })
+
+	selected := h.selectNeighbors(candidates, maxConn)
+	node.Neighbors[level] = node.Neighbors[level][:0]
+	for _, s := range selected {
+		node.Neighbors[level] = append(node.Neighbors[level], int32(s.id))
+	}
+}
+
+// selectNeighbors applies the HNSW diversity heuristic: a candidate is kept
+// only if it is closer to the base than to any already selected neighbour.
+// Remaining slots are back-filled with the nearest discarded candidates.
+// Candidates must be sorted by similarity, highest first.
+func (h *HNSWStore) selectNeighbors(candidates []hnswCandidate, m int) []hnswCandidate {
+	if len(candidates) <= m {
+		return candidates
+	}
+
+	selected := make([]hnswCandidate, 0, m)
+	var discarded []hnswCandidate
+	for _, c := range candidates {
+		if len(selected) == m {
+			break
+		}
+		keep := true
+		for _, s := range selected {
+			if dot(h.nodes[c.id].Chunk.Vector, h.nodes[s.id].Chunk.Vector) > c.sim {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			selected = append(selected, c)
+		} else {
+			discarded = append(discarded, c)
+		}
+	}
+	for _, c := range discarded {
+		if len(selected) == m {
+			break
+		}
+		selected = append(selected, c)
+	}
+	return selected
+}
+
+// descend walks the upper layers greedily and returns the layer 0 entry
+func (h *HNSWStore) descend(query []float32, stopLevel int) int {
+	current := h.entry
+	currentSim := dot(query, h.nodes[current].Chunk.Vector)
+	for l := h.maxLevel; l >= stopLevel; l-- {
+		current, currentSim = h.greedyStep(query, current, currentSim, l)
+	}
+	return current
+}
+
+func (h *HNSWStore) greedyStep(query []float32, current int, currentSim float32, level int) (int, float32) {
+	for changed := true; changed; {
+		changed = false
+		for _, n := range h.nodes[current].Neighbors[level] {
+			if sim := dot(query, h.nodes[n].Chunk.Vector); sim > currentSim {
+				current, currentSim, changed = int(n), sim, true
+			}
+		}
+	}
+	return current, currentSim
+}
+
+// searchLayer runs a beam search on one layer and returns up to ef
+// candidates sorted by similarity, highest first. Tombstoned nodes are
+// still traversed so the graph stays connected.
+func (h *HNSWStore) searchLayer(query []float32, entryPoints []int, ef, level int) []hnswCandidate {
+	visited := make(map[int]bool, ef*4)
+	candidates := &candidateHeap{max: true}
+	results := &candidateHeap{}
+
+	for _, ep := range entryPoints {
+		if visited[ep] {
+			continue
+		}
+		visited[ep] = true
+		c := hnswCandidate{id: ep, sim: dot(query, h.nodes[ep].Chunk.Vector)}
+		heap.Push(candidates, c)
+		heap.Push(results, c)
+	}
+	for results.Len() > ef {
+		heap.Pop(results)
+	}
+
+	for candidates.Len() > 0 {
+		c := heap.Pop(candidates).(hnswCandidate)
+		if results.Len() >= ef && c.sim < results.items[0].sim {
+			break
+		}
+
+		node := h.nodes[c.id]
+		if level >= len(node.Neighbors) {
+			continue
+		}
+		for _, n := range node.Neighbors[level] {
+			id := int(n)
+			if visited[id] {
+				continue
+			}
+			visited[id] = true
+
+			sim := dot(query, h.nodes[id].Chunk.Vector)
+			if results.Len() < ef || sim > results.items[0].sim {
+				heap.Push(candidates, hnswCandidate{id: id, sim: sim})
+				heap.Push(results, hnswCandidate{id: id, sim: sim})
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+
+	out := make([]hnswCandidate, results.Len())
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = heap.Pop(results).(hnswCandidate)
+	}
+	return out
+}
+
+// tombstone marks a node deleted. Callers hold the write lock.
+func (h *HNSWStore) tombstone(idx int) {
+	node := h.nodes[idx]
+	if node.Deleted {
+		return
+	}
+	node.Deleted = true
+	key := storeKey(node.Chunk.Namespace, node.Chunk.ID)
+	delete(h.ids, key)
+	h.keywords.Remove(key)
+	h.counts[node.Chunk.Namespace]--
+	if h.counts[node.Chunk.Namespace] == 0 {
+		delete(h.counts, node.Chunk.Namespace)
+	}
+	h.deleted++
+}
+
+// maybeRebuild rebuilds the graph from live nodes once tombstones exceed
+// the configured ratio
+func (h *HNSWStore) maybeRebuild() {
+	if h.deleted == 0 || float64(h.deleted) < h.config.RebuildRatio*float64(len(h.nodes)) {
+		return
+	}
+
+	live := make([]Chunk, 0, len(h.ids))
+	for _, node := range h.nodes {
+		if !node.Deleted {
+			live = append(live, node.Chunk)
+		}
+	}
+
+	h.nodes = nil
+	h.ids = make(map[string]int, len(live))
+	h.counts = make(map[Namespace]int)
+	h.entry = -1
+	h.maxLevel = 0
+	h.deleted = 0
+	if len(live) == 0 {
+		h.dim = 0
+	}
+	for _, chunk := range live {
+		h.insert(chunk)
+	}
+}
+
+// hnswSnapshot is the on-disk form of the graph
+type hnswSnapshot struct {
+	Version  int
+	Config   HNSWConfig
+	Dim      int
+	Entry    int
+	MaxLevel int
+	Nodes    []*hnswNode
+}
+
+// Save writes a snapshot of the graph
+func (h *HNSWStore) Save(w io.Writer) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	snapshot := hnswSnapshot{
+		Version:  hnswSnapshotVersion,
+		Config:   h.config,
+		Dim:      h.dim,
+		Entry:    h.entry,
+		MaxLevel: h.maxLevel,
+		Nodes:    h.nodes,
+	}
+	if err := gob.NewEncoder(w).Encode(snapshot); err != nil {
+		return fmt.Errorf("failed to encode HNSW snapshot: %w", err)
+	}
+	return nil
+}
+
+// SaveSnapshot atomically writes a snapshot to path
+func (h *HNSWStore) SaveSnapshot(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	buf := bufio.NewWriter(tmp)
+	if err := h.Save(buf); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := buf.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadHNSWStore restores a store from a snapshot
+func LoadHNSWStore(r io.Reader) (*HNSWStore, error) {
+	var snapshot hnswSnapshot
+	if err := gob.NewDecoder(r).Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("failed to decode HNSW snapshot: %w", err)
+	}
+	if snapshot.Version != hnswSnapshotVersion {
+		return nil, fmt.Errorf("unsupported HNSW snapshot version %d", snapshot.Version)
+	}
+
+	h := newHNSWStore(snapshot.Config)
+	h.dim = snapshot.Dim
+	h.entry = snapshot.Entry
+	h.maxLevel = snapshot.MaxLevel
+	h.nodes = snapshot.Nodes
+	for idx, node := range h.nodes {
+		// gob drops empty slices, so restore one adjacency list per layer
+		for len(node.Neighbors) <= node.Level {
+			node.Neighbors = append(node.Neighbors, nil)
+		}
+		if node.Deleted {
+			h.deleted++
+		} else {
+			key := storeKey(node.Chunk.Namespace, node.Chunk.ID)
+			h.ids[key] = idx
+			h.counts[node.Chunk.Namespace]++
+			h.keywords.Add(key, node.Chunk.Content)
+		}
+	}
+	return h, nil
+}
+
+// LoadHNSWSnapshot restores a store from a snapshot file
+func LoadHNSWSnapshot(path string) (*HNSWStore, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer f.Close()
+	return LoadHNSWStore(bufio.NewReader(f))
+}
+
+type hnswCandidate struct {
+	id  int
+	sim float32
+}
+
+// candidateHeap is a min-heap on similarity, or a max-heap when max is set
+type candidateHeap struct {
+	items []hnswCandidate
+	max   bool
+}
+
+func (c candidateHeap) Len() int { return len(c.items) }
+func (c candidateHeap) Less(i, j int) bool {
+	if c.max {
+		return c.items[i].sim > c.items[j].sim
+	}
+	return c.items[i].sim < c.items[j].sim
+}
+func (c candidateHeap) Swap(i, j int)       { c.items[i], c.items[j] = c.items[j], c.items[i] }
+func (c *candidateHeap) Push(x interface{}) { c.items = append(c.items, x.(hnswCandidate)) }
+func (c *candidateHeap) Pop() interface{} {
+	last := c.items[len(c.items)-1]
+	c.items = c.items[:len(c.items)-1]
+	return last
+}
+
+// dot is a sequential dot product. Graph traversal computes many short
+// products, where DotProduct's goroutine fan-out costs more than it saves.
+func dot(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Configuration options
+
+// WithHNSWParams sets the graph degree and beam widths
+func WithHNSWParams(m, efConstruction, efSearch int) Option {
+	return func(cfg interface{}) {
+		if c, ok := cfg.(*HNSWConfig); ok {
+			c.M = m
+			c.EfConstruction = efConstruction
+			c.EfSearch = efSearch
+		}
+	}
+}
+
+// WithMinSimilarity sets the similarity cut-off for search results
+func WithMinSimilarity(min float32) Option {
+	return func(cfg interface{}) {
+		switch c := cfg.(type) {
+		case *HNSWConfig:
+			c.MinSimilarity = min
+		case *PostgresConfig:
+			c.MinSimilarity = min
+		}
+	}
+}
+
+// WithSeed makes level assignment deterministic
+func WithSeed(seed int64) Option {
+	return func(cfg interface{}) {
+		if c, ok := cfg.(*HNSWConfig); ok {
+			c.Seed = seed
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package memory
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func randomChunks(rng *rand.Rand, n, dim int) []Chunk {
+	chunks := make([]Chunk, n)
+	for i := range chunks {
+		vec := make([]float32, dim)
+		for j := range vec {
+			vec[j] = float32(rng.NormFloat64())
+		}
+		source := "spreadsheet"
+		if i%3 == 0 {
+			source = "document"
+		}
+		chunks[i] = Chunk{
+			ID:       fmt.Sprintf("chunk-%d", i),
+			Vector:   vec,
+			Content:  fmt.Sprintf("content %d", i),
+			Metadata: ChunkMetadata{Source: source},
+		}
+	}
+	return chunks
+}
+
+func randomQueries(rng *rand.Rand, n, dim int) [][]float32 {
+	queries := make([][]float32, n)
+	for i := range queries {
+		queries[i] = make([]float32, dim)
+		for j := range queries[i] {
+			queries[i][j] = float32(rng.NormFloat64())
+		}
+	}
+	return queries
+}
+
+// exactTopK is the brute-force ground truth
+func exactTopK(chunks []Chunk, query []float32, k int, filter FilterFunc) map[string]bool {
+	q := NormalizeVector(query)
+	type scored struct {
+		id  string
+		sim float32
+	}
+	var all []scored
+	for _, c := range chunks {
+		if filter != nil && !filter(c) {
+			continue
+		}
+		all = append(all, scored{c.ID, DotProduct(q, NormalizeVector(c.Vector))})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].sim > all[j].sim })
+	ids := make(map[string]bool, k)
+	for i := 0; i < k && i < len(all); i++ {
+		ids[all[i].id] = true
+	}
+	return ids
+}
+
+func measureRecall(store VectorStore, chunks []Chunk, queries [][]float32, k int, filter FilterFunc) float64 {
+	hits, total := 0, 0
+	for _, q := range queries {
+		want := exactTopK(chunks, q, k, filter)
+		got, _ := store.Search(testNS, append([]float32(nil), q...), k, filter)
+		for _, r := range got {
+			if want[r.Chunk.ID] {
+				hits++
+			}
+		}
+		total += len(want)
+	}
+	return float64(hits) / float64(total)
+}
+
+func newTestHNSW(chunks []Chunk) *HNSWStore {
+	store := NewHNSWStore(WithMinSimilarity(-1), WithSeed(42))
+	copied := make([]Chunk, len(chunks))
+	for i, c := range chunks {
+		c.Vector = append([]float32(nil), c.Vector...)
+		copied[i] = c
+	}
+	store.Add(testNS, copied)
+	return store
+}
+
+func TestHNSWStore_Recall(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	chunks := randomChunks(rng, 2000, 32)
+	queries := randomQueries(rng, 50, 32)
+	store := newTestHNSW(chunks)
+
+	if recall := measureRecall(store, chunks, queries, 10, nil); recall < 0.9 {
+		t.Errorf("recall@10 = %.3f, want >= 0.9", recall)
+	}
+
+	documentsOnly := func(c Chunk) bool { return c.Metadata.Source == "document" }
+	if recall := measureRecall(store, chunks, queries, 10, documentsOnly); recall < 0.9 {
+		t.Errorf("filtered recall@10 = %.3f, want >= 0.9", recall)
+	}
+}
+
+func TestHNSWStore_DeleteAndUpsert(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	chunks := randomChunks(rng, 500, 16)
+	store := newTestHNSW(chunks)
+
+	target := append([]float32(nil), chunks[7].Vector...)
+	results, err := store.Search(testNS, append([]float32(nil), target...), 1, nil)
+	if err != nil || len(results) != 1 || results[0].Chunk.ID != "chunk-7" {
+		t.Fatalf("exact match not found: %v %v", results, err)
+	}
+
+	store.DeleteByID(testNS, "chunk-7")
+	results, _ = store.Search(testNS, append([]float32(nil), target...), 5, nil)
+	for _, r := range results {
+		if r.Chunk.ID == "chunk-7" {
+			t.Fatal("deleted chunk returned")
+		}
+	}
+
+	// Re-adding an ID replaces the old vector
+	updated := chunks[8]
+	updated.Vector = append([]float32(nil), target...)
+	if err := store.Add(testNS, []Chunk{updated}); err != nil {
+		t.Fatal(err)
+	}
+	results, _ = store.Search(testNS, append([]float32(nil), target...), 1, nil)
+	if len(results) != 1 || results[0].Chunk.ID != "chunk-8" {
+		t.Fatalf("upserted chunk not found: %v", results)
+	}
+	if store.Size() != 499 {
+		t.Errorf("Size() = %d, want 499", store.Size())
+	}
+
+	// Deleting most of the graph triggers a rebuild
+	if err := store.Delete(testNS, func(c Chunk) bool { return c.Metadata.Source == "spreadsheet" }); err != nil {
+		t.Fatal(err)
+	}
+	if store.deleted != 0 {
+		t.Errorf("expected rebuild to clear tombstones, got %d", store.deleted)
+	}
+	if stats := store.GetStats(); stats.SpreadsheetChunks != 0 || stats.DocumentChunks != store.Size() {
+		t.Errorf("unexpected stats after delete: %+v", stats)
+	}
+}
+
+func TestHNSWStore_Snapshot(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	chunks := randomChunks(rng, 300, 16)
+	store := newTestHNSW(chunks)
+	store.DeleteByID(testNS, "chunk-1")
+
+	path := filepath.Join(t.TempDir(), "index.hnsw")
+	if err := store.SaveSnapshot(path); err != nil {
+		t.Fatalf("SaveSnapshot() error = %v", err)
+	}
+	restored, err := LoadHNSWSnapshot(path)
+	if err != nil {
+		t.Fatalf("LoadHNSWSnapshot() error = %v", err)
+	}
+	if restored.Size() != store.Size() {
+		t.Fatalf("restored Size() = %d, want %d", restored.Size(), store.Size())
+	}
+
+	query := randomQueries(rng, 1, 16)[0]
+	want, _ := store.Search(testNS, append([]float32(nil), query...), 5, nil)
+	got, _ := restored.Search(testNS, append([]float32(nil), query...), 5, nil)
+	if len(got) != len(want) {
+		t.Fatalf("restored search returned %d results, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Chunk.ID != want[i].Chunk.ID {
+			t.Errorf("result %d = %s, want %s", i, got[i].Chunk.ID, want[i].Chunk.ID)
+		}
+	}
+
+	// Restored graphs accept new inserts
+	if err := restored.Add(testNS, randomChunks(rng, 1, 16)); err != nil {
+		t.Errorf("Add() after restore error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("not a snapshot")
+	if _, err := LoadHNSWStore(&buf); err == nil {
+		t.Error("expected error for corrupt snapshot")
+	}
+}
+
+const (
+	benchChunks = 10000
+	benchDim    = 128
+)
+
+// benchmarkSearch measures top-10 latency. The brute-force stores apply a
+// fixed 0.7 similarity cut-off, which random vectors never reach, so recall
+// is only reported for the approximate index.
+func benchmarkSearch(b *testing.B, store VectorStore, chunks []Chunk, reportRecall bool) {
+	rng := rand.New(rand.NewSource(99))
+	queries := randomQueries(rng, 100, benchDim)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		q := queries[i%len(queries)]
+		store.Search(testNS, append([]float32(nil), q...), 10, nil)
+	}
+	b.StopTimer()
+
+	if reportRecall {
+		b.ReportMetric(measureRecall(store, chunks, queries[:20], 10, nil), "recall@10")
+	}
+}
+
+func BenchmarkHNSWStore_Search(b *testing.B) {
+	chunks := randomChunks(rand.New(rand.NewSource(7)), benchChunks, benchDim)
+	store := newTestHNSW(chunks)
+	for _, ef := range []int{64, 256} {
+		b.Run(fmt.Sprintf("ef=%d", ef), func(b *testing.B) {
+			store.SetEfSearch(ef)
+			benchmarkSearch(b, store, chunks, true)
+		})
+	}
+}
+
+func BenchmarkInMemoryStore_Search(b *testing.B) {
+	chunks := randomChunks(rand.New(rand.NewSource(7)), benchChunks, benchDim)
+	store := NewInMemoryStore(benchChunks)
+	store.Add(testNS, chunks)
+	benchmarkSearch(b, store, chunks, false)
+}
+
+func BenchmarkBoltDBStore_Search(b *testing.B) {
+	chunks := randomChunks(rand.New(rand.NewSource(7)), benchChunks, benchDim)
+	for i := range chunks {
+		chunks[i].Vector = NormalizeVector(chunks[i].Vector)
+	}
+	store := NewBoltDBStore(filepath.Join(b.TempDir(), "bench.db"))
+	defer store.Close()
+	store.Add(testNS, chunks)
+	benchmarkSearch(b, store, chunks, false)
+}
+
+func BenchmarkHNSWStore_Insert(b *testing.B) {
+	chunks := randomChunks(rand.New(rand.NewSource(7)), b.N, benchDim)
+	store := NewHNSWStore(WithSeed(42))
+
+	b.ResetTimer()
+	for i := range chunks {
+		store.Add(testNS, chunks[i:i+1])
+	}
+}
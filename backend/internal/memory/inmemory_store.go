@@ -7,35 +7,72 @@ import (
 	"time"
 )
 
+// InMemoryConfig tunes the in-memory store
+type InMemoryConfig struct {
+	NamespaceQuota int // Max chunks per namespace, 0 for no cap
+}
+
 // InMemoryStore provides fast vector search in memory
 type InMemoryStore struct {
 	chunks    []Chunk
 	maxChunks int
+	config    InMemoryConfig
 	mu        sync.RWMutex
 
 	// Optimization: pre-computed norms for cosine similarity
 	norms []float32
 
-	// Index for keyword search fallback
-	keywordIndex map[string][]int // word -> chunk indices
+	// Position of each chunk by store key, and chunk counts per namespace
+	positions map[string]int
+	counts    map[Namespace]int
+
+	// BM25 index over chunk content for keyword and hybrid search, keyed
+	// by store key
+	keywords *BM25Index
 }
 
 // NewInMemoryStore creates a new in-memory store
-func NewInMemoryStore(maxChunks int) *InMemoryStore {
+func NewInMemoryStore(maxChunks int, opts ...Option) *InMemoryStore {
+	config := InMemoryConfig{}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
 	return &InMemoryStore{
-		chunks:       make([]Chunk, 0, maxChunks),
-		maxChunks:    maxChunks,
-		norms:        make([]float32, 0, maxChunks),
-		keywordIndex: make(map[string][]int),
+		chunks:    make([]Chunk, 0, maxChunks),
+		maxChunks: maxChunks,
+		config:    config,
+		norms:     make([]float32, 0, maxChunks),
+		positions: make(map[string]int),
+		counts:    make(map[Namespace]int),
+		keywords:  NewBM25Index(),
 	}
 }
 
-// Add chunks to memory
-func (m *InMemoryStore) Add(chunks []Chunk) error {
+// Add chunks to a namespace. A chunk with an existing ID in the namespace
+// replaces the previous version.
+func (m *InMemoryStore) Add(ns Namespace, chunks []Chunk) error {
+	if err := ns.Validate(); err != nil {
+		return err
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	added := make(map[string]bool)
+	for _, chunk := range chunks {
+		if _, ok := m.positions[storeKey(ns, chunk.ID)]; !ok {
+			added[chunk.ID] = true
+		}
+	}
+	if err := checkQuota(ns, m.config.NamespaceQuota, m.counts[ns], len(added)); err != nil {
+		return err
+	}
+
 	for _, chunk := range chunks {
+		chunk.Namespace = ns
+		key := storeKey(ns, chunk.ID)
+
 		// Normalize vector for cosine similarity
 		norm := vectorNorm(chunk.Vector)
 		if norm > 0 {
@@ -44,14 +81,21 @@ func (m *InMemoryStore) Add(chunks []Chunk) error {
 			}
 		}
 
+		// Update keyword index
+		m.keywords.Add(key, chunk.Content)
+
+		if idx, ok := m.positions[key]; ok {
+			m.chunks[idx] = chunk
+			m.norms[idx] = norm
+			continue
+		}
+
 		// Add to chunks
-		idx := len(m.chunks)
+		m.positions[key] = len(m.chunks)
+		m.counts[ns]++
 		m.chunks = append(m.chunks, chunk)
 		m.norms = append(m.norms, norm)
 
-		// Update keyword index
-		m.indexKeywords(chunk.Content, idx)
-
 		// Evict oldest if over capacity
 		if len(m.chunks) > m.maxChunks {
 			m.evictOldest()
@@ -61,8 +105,12 @@ func (m *InMemoryStore) Add(chunks []Chunk) error {
 	return nil
 }
 
-// Search performs vector similarity search with fallback to keyword search
-func (m *InMemoryStore) Search(query []float32, topK int, filter FilterFunc) ([]SearchResult, error) {
+// Search performs vector similarity search over the chunks visible to ns
+func (m *InMemoryStore) Search(ns Namespace, query []float32, topK int, filter FilterFunc) ([]SearchResult, error) {
+	if err := ns.Validate(); err != nil {
+		return nil, err
+	}
+
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -82,6 +130,10 @@ func (m *InMemoryStore) Search(query []float32, topK int, filter FilterFunc) ([]
 	results := make([]SearchResult, 0, len(m.chunks))
 
 	for _, chunk := range m.chunks {
+		if !ns.Sees(chunk.Namespace) {
+			continue
+		}
+
 		// Apply filter if provided
 		if filter != nil && !filter(chunk) {
 			continue
@@ -113,39 +165,65 @@ func (m *InMemoryStore) Search(query []float32, topK int, filter FilterFunc) ([]
 	return results, nil
 }
 
-// Delete removes chunks matching the filter
-func (m *InMemoryStore) Delete(filter FilterFunc) error {
+// Delete removes chunks in ns matching the filter
+func (m *InMemoryStore) Delete(ns Namespace, filter FilterFunc) error {
+	if err := ns.Validate(); err != nil {
+		return err
+	}
+	if filter == nil {
+		return nil
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	newChunks := make([]Chunk, 0, len(m.chunks))
-	newNorms := make([]float32, 0, len(m.norms))
+	m.removeWhere(func(chunk Chunk) bool {
+		return chunk.Namespace == ns && filter(chunk)
+	})
+	return nil
+}
 
-	// Rebuild index
-	m.keywordIndex = make(map[string][]int)
+// DeleteByID removes a specific chunk
+func (m *InMemoryStore) DeleteByID(ns Namespace, id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	for i, chunk := range m.chunks {
-		if filter != nil && filter(chunk) {
-			continue // Skip chunks that match the filter
-		}
+	if _, ok := m.positions[storeKey(ns, id)]; !ok {
+		return
+	}
+	m.removeWhere(func(chunk Chunk) bool {
+		return chunk.Namespace == ns && chunk.ID == id
+	})
+}
 
-		idx := len(newChunks)
-		newChunks = append(newChunks, chunk)
-		newNorms = append(newNorms, m.norms[i])
-		m.indexKeywords(chunk.Content, idx)
+// Purge removes every chunk in ns, or in all of a tenant's namespaces when
+// ns has no session
+func (m *InMemoryStore) Purge(ns Namespace) (int, error) {
+	if err := ns.Validate(); err != nil {
+		return 0, err
 	}
 
-	m.chunks = newChunks
-	m.norms = newNorms
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	return nil
+	return m.removeWhere(func(chunk Chunk) bool {
+		return ns.Covers(chunk.Namespace)
+	}), nil
 }
 
-// DeleteByID removes a specific chunk
-func (m *InMemoryStore) DeleteByID(id string) {
-	m.Delete(func(chunk Chunk) bool {
-		return chunk.ID == id
-	})
+// NamespaceStats returns statistics for the chunks stored in ns
+func (m *InMemoryStore) NamespaceStats(ns Namespace) Stats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stats := Stats{LastUpdated: time.Now(), Quota: m.config.NamespaceQuota}
+	for _, chunk := range m.chunks {
+		if chunk.Namespace == ns {
+			stats.countSource(chunk.Metadata.Source)
+		}
+	}
+	stats.StorageSize = int64(stats.TotalChunks) * 2048
+	return stats
 }
 
 // GetStats returns statistics about the store
@@ -154,20 +232,13 @@ func (m *InMemoryStore) GetStats() Stats {
 	defer m.mu.RUnlock()
 
 	stats := Stats{
-		TotalChunks: len(m.chunks),
 		LastUpdated: time.Now(),
+		Quota:       m.config.NamespaceQuota,
 	}
 
 	// Count by source type
 	for _, chunk := range m.chunks {
-		switch chunk.Metadata.Source {
-		case "spreadsheet":
-			stats.SpreadsheetChunks++
-		case "document":
-			stats.DocumentChunks++
-		case "chat":
-			stats.ChatChunks++
-		}
+		stats.countSource(chunk.Metadata.Source)
 	}
 
 	// Estimate storage size (rough approximation)
@@ -244,86 +315,122 @@ func (m *InMemoryStore) evictOldest() {
 		evictCount = 1
 	}
 
-	// Sort by timestamp
-	sort.Slice(m.chunks, func(i, j int) bool {
-		return m.chunks[i].Timestamp.After(m.chunks[j].Timestamp)
+	// Sort by timestamp, newest first, keeping norms aligned
+	order := make([]int, len(m.chunks))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return m.chunks[order[i]].Timestamp.After(m.chunks[order[j]].Timestamp)
 	})
+	chunks := make([]Chunk, len(order))
+	norms := make([]float32, len(order))
+	for i, idx := range order {
+		chunks[i], norms[i] = m.chunks[idx], m.norms[idx]
+	}
 
 	// Keep only the newer chunks
-	m.chunks = m.chunks[:len(m.chunks)-evictCount]
-	m.norms = m.norms[:len(m.norms)-evictCount]
+	for _, chunk := range chunks[len(chunks)-evictCount:] {
+		m.keywords.Remove(storeKey(chunk.Namespace, chunk.ID))
+	}
+	m.chunks = chunks[:len(chunks)-evictCount]
+	m.norms = norms[:len(norms)-evictCount]
+	m.rebuildIndex()
+}
+
+// ExpireWith deletes chunks that have outlived policy
+func (m *InMemoryStore) ExpireWith(policy TTLPolicy, now time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	// Rebuild keyword index
-	m.rebuildKeywordIndex()
+	return m.removeWhere(func(chunk Chunk) bool {
+		return policy.Expired(chunk, now)
+	}), nil
 }
 
-// indexKeywords adds keywords from content to the index
-func (m *InMemoryStore) indexKeywords(content string, chunkIdx int) {
-	// Simple tokenization - can be improved
-	words := strings.Fields(strings.ToLower(content))
+// removeWhere drops matching chunks and returns how many were removed.
+// Callers hold the write lock.
+func (m *InMemoryStore) removeWhere(match func(Chunk) bool) int {
+	newChunks := make([]Chunk, 0, len(m.chunks))
+	newNorms := make([]float32, 0, len(m.norms))
 
-	for _, word := range words {
-		// Skip very short words
-		if len(word) < 3 {
+	removed := 0
+	for i, chunk := range m.chunks {
+		if match(chunk) {
+			m.keywords.Remove(storeKey(chunk.Namespace, chunk.ID))
+			removed++
 			continue
 		}
 
-		// Add to index
-		if m.keywordIndex[word] == nil {
-			m.keywordIndex[word] = []int{chunkIdx}
-		} else {
-			m.keywordIndex[word] = append(m.keywordIndex[word], chunkIdx)
-		}
+		newChunks = append(newChunks, chunk)
+		newNorms = append(newNorms, m.norms[i])
+	}
+
+	m.chunks = newChunks
+	m.norms = newNorms
+	if removed > 0 {
+		m.rebuildIndex()
 	}
+	return removed
 }
 
-// rebuildKeywordIndex rebuilds the entire keyword index
-func (m *InMemoryStore) rebuildKeywordIndex() {
-	m.keywordIndex = make(map[string][]int)
+// keys returns the store keys of chunks in namespaces accepted by match
+func (m *InMemoryStore) keys(match func(Namespace) bool) map[string]bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 
-	for idx, chunk := range m.chunks {
-		m.indexKeywords(chunk.Content, idx)
+	keys := make(map[string]bool)
+	for _, chunk := range m.chunks {
+		if match(chunk.Namespace) {
+			keys[storeKey(chunk.Namespace, chunk.ID)] = true
+		}
 	}
+	return keys
 }
 
-// KeywordSearch performs fallback keyword search
-func (m *InMemoryStore) KeywordSearch(keywords []string, topK int, filter FilterFunc) []SearchResult {
+// rebuildIndex recomputes positions and namespace counts after chunks move
+func (m *InMemoryStore) rebuildIndex() {
+	m.positions = make(map[string]int, len(m.chunks))
+	m.counts = make(map[Namespace]int)
+	for i, chunk := range m.chunks {
+		m.positions[storeKey(chunk.Namespace, chunk.ID)] = i
+		m.counts[chunk.Namespace]++
+	}
+}
+
+// KeywordSearch ranks chunks by BM25 over their content. Similarity is
+// left at zero since no vectors are compared; Score carries the BM25 score.
+func (m *InMemoryStore) KeywordSearch(ns Namespace, keywords []string, topK int, filter FilterFunc) []SearchResult {
+	if ns.Validate() != nil {
+		return nil
+	}
+
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	// Count keyword matches per chunk
-	matchCounts := make(map[int]int)
-
-	for _, keyword := range keywords {
-		keyword = strings.ToLower(keyword)
-		if indices, ok := m.keywordIndex[keyword]; ok {
-			for _, idx := range indices {
-				matchCounts[idx]++
-			}
-		}
+	scores := m.keywords.Score(Tokenize(strings.Join(keywords, " ")))
+	if len(scores) == 0 {
+		return nil
 	}
 
-	// Convert to results
-	results := make([]SearchResult, 0)
-	for idx, count := range matchCounts {
-		if idx >= len(m.chunks) {
+	results := make([]SearchResult, 0, len(scores))
+	for _, chunk := range m.chunks {
+		if !ns.Sees(chunk.Namespace) {
+			continue
+		}
+		score, ok := scores[storeKey(chunk.Namespace, chunk.ID)]
+		if !ok {
 			continue
 		}
-
-		chunk := m.chunks[idx]
 
 		// Apply filter
 		if filter != nil && !filter(chunk) {
 			continue
 		}
 
-		// Calculate simple relevance score
-		score := float32(count) / float32(len(keywords))
-
 		results = append(results, SearchResult{
-			Chunk:      chunk,
-			Similarity: score,
-			Score:      score,
+			Chunk: chunk,
+			Score: float32(score),
 		})
 	}
 
@@ -4,11 +4,15 @@ import (
 	"time"
 )
 
-// VectorStore interface for different implementations
+// VectorStore interface for different implementations. Every read and
+// write is scoped to a Namespace: Add and Delete touch only that namespace,
+// Search also sees chunks shared across the namespace's tenant.
 type VectorStore interface {
-	Add(chunks []Chunk) error
-	Search(query []float32, topK int, filter FilterFunc) ([]SearchResult, error)
-	Delete(filter FilterFunc) error
+	Add(ns Namespace, chunks []Chunk) error
+	Search(ns Namespace, query []float32, topK int, filter FilterFunc) ([]SearchResult, error)
+	Delete(ns Namespace, filter FilterFunc) error
+	NamespaceStats(ns Namespace) Stats
+	Purge(ns Namespace) (int, error) // Removes a namespace, or a whole tenant
 	GetStats() Stats
 	Close() error
 }
@@ -20,28 +24,29 @@ type Chunk struct {
 	Content   string
 	Metadata  ChunkMetadata
 	Timestamp time.Time
+	Namespace Namespace // Set by the store on Add
 }
 
 // ChunkMetadata contains source information
 type ChunkMetadata struct {
-	Source     string                 // "spreadsheet", "document", "chat"
-	SourceID   string                 // Sheet name, document ID, etc.
-	SourceMeta map[string]interface{} // Additional metadata
+	Source     string                 `json:"source"`                // "spreadsheet", "document", "chat"
+	SourceID   string                 `json:"source_id,omitempty"`   // Sheet name, document ID, etc.
+	SourceMeta map[string]interface{} `json:"source_meta,omitempty"` // Additional metadata
 
 	// Spreadsheet-specific
-	SheetName string
-	CellRange string
-	IsFormula bool
+	SheetName string `json:"sheet_name,omitempty"`
+	CellRange string `json:"cell_range,omitempty"`
+	IsFormula bool   `json:"is_formula,omitempty"`
 
 	// Document-specific
-	DocumentName string
-	PageNumber   int
-	Section      string
+	DocumentName string `json:"document_name,omitempty"`
+	PageNumber   int    `json:"page_number,omitempty"`
+	Section      string `json:"section,omitempty"`
 
 	// Chat-specific
-	MessageID string
-	Role      string // "user" or "assistant"
-	Turn      int
+	MessageID string `json:"message_id,omitempty"`
+	Role      string `json:"role,omitempty"` // "user" or "assistant"
+	Turn      int    `json:"turn,omitempty"`
 }
 
 // SearchResult contains search results with similarity scores
@@ -62,6 +67,7 @@ type Stats struct {
 	ChatChunks        int
 	StorageSize       int64
 	LastUpdated       time.Time
+	Quota             int // Per-namespace chunk cap, 0 when unlimited
 }
 
 // Option is a configuration option for vector stores
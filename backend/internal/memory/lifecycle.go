@@ -0,0 +1,470 @@
+package memory
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/sirupsen/logrus"
+)
+
+// TTLPolicy maps a chunk source ("chat", "document", "spreadsheet") to how
+// long its chunks are kept. Sources without an entry never expire.
+type TTLPolicy map[string]time.Duration
+
+// ParseTTLPolicy parses "chat=720h,document=8760h"
+func ParseTTLPolicy(s string) (TTLPolicy, error) {
+	policy := make(TTLPolicy)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		source, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid TTL %q, want source=duration", part)
+		}
+		ttl, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid TTL for %s: %w", source, err)
+		}
+		policy[strings.TrimSpace(source)] = ttl
+	}
+	return policy, nil
+}
+
+// Expired reports whether chunk has outlived its source's TTL at now
+func (p TTLPolicy) Expired(chunk Chunk, now time.Time) bool {
+	ttl := p[chunk.Metadata.Source]
+	return ttl > 0 && chunk.Timestamp.Before(now.Add(-ttl))
+}
+
+// CompactResult reports the file size around a compaction
+type CompactResult struct {
+	SizeBefore int64 `json:"sizeBefore"`
+	SizeAfter  int64 `json:"sizeAfter"`
+}
+
+// compactMinSize and compactFreeRatio decide when background maintenance
+// rewrites the file: Bolt reuses freed pages but never shrinks
+const (
+	compactMinSize   = 1 << 20
+	compactFreeRatio = 0.5
+)
+
+// Expire deletes chunks that have outlived the configured TTL policy,
+// walking the expiry index oldest first
+func (b *BoltDBStore) Expire(now time.Time) (int, error) {
+	return b.ExpireWith(b.config.TTL, now)
+}
+
+// ExpireWith deletes chunks that have outlived policy
+func (b *BoltDBStore) ExpireWith(policy TTLPolicy, now time.Time) (int, error) {
+	removed := 0
+	err := b.update(func(tx *bolt.Tx) error {
+		var expired []Chunk
+		cursor := tx.Bucket([]byte(expiryBucket)).Cursor()
+		for source, ttl := range policy {
+			if ttl <= 0 {
+				continue
+			}
+			prefix := append([]byte(source), 0)
+			cutoff := uint64(now.Add(-ttl).UnixNano())
+			for k, _ := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = cursor.Next() {
+				rest := k[len(prefix):]
+				if len(rest) < 8 || binary.BigEndian.Uint64(rest) >= cutoff {
+					break
+				}
+				nsKey, id, ok := strings.Cut(string(rest[8:]), "\x00")
+				if !ok {
+					continue
+				}
+				ns, err := ParseNamespace(nsKey)
+				if err != nil {
+					continue
+				}
+				chunk, err := chunkAt(tx, ns, id)
+				if err != nil {
+					return err
+				}
+				expired = append(expired, chunk)
+			}
+		}
+
+		delta, err := deleteChunks(tx, expired)
+		if err != nil {
+			return err
+		}
+		removed = -delta.total()
+		return applyStats(tx, delta)
+	})
+	return removed, err
+}
+
+// Compact rewrites the database into a fresh file, returning freed pages
+// to the filesystem. Reads and writes wait until it finishes.
+func (b *BoltDBStore) Compact() (CompactResult, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var result CompactResult
+	if info, err := os.Stat(b.path); err == nil {
+		result.SizeBefore = info.Size()
+	}
+
+	tmp := b.path + ".compact"
+	os.Remove(tmp)
+	dst, err := bolt.Open(tmp, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return result, fmt.Errorf("failed to create compacted file: %w", err)
+	}
+	err = b.db.View(func(src *bolt.Tx) error {
+		return dst.Update(func(tx *bolt.Tx) error {
+			return copyBuckets(src, tx)
+		})
+	})
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return result, fmt.Errorf("failed to compact store: %w", err)
+	}
+
+	if err := b.replaceFile(tmp); err != nil {
+		return result, err
+	}
+	if info, err := os.Stat(b.path); err == nil {
+		result.SizeAfter = info.Size()
+	}
+	return result, nil
+}
+
+// Snapshot writes a consistent copy of the database to w while reads and
+// writes continue
+func (b *BoltDBStore) Snapshot(w io.Writer) (int64, error) {
+	var n int64
+	err := b.view(func(tx *bolt.Tx) error {
+		var err error
+		n, err = tx.WriteTo(w)
+		return err
+	})
+	if err != nil {
+		return n, fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return n, nil
+}
+
+// SnapshotFile atomically writes a snapshot to path
+func (b *BoltDBStore) SnapshotFile(path string) (int64, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	n, err := b.Snapshot(tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return n, err
+	}
+	return n, os.Rename(tmp.Name(), path)
+}
+
+// Restore replaces the store's contents with a snapshot. The snapshot is
+// validated before the live file is swapped.
+func (b *BoltDBStore) Restore(r io.Reader) error {
+	tmp, err := os.CreateTemp(filepath.Dir(b.path), filepath.Base(b.path)+".restore-*")
+	if err != nil {
+		return fmt.Errorf("failed to create restore file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	// Opening builds any missing buckets and indexes
+	db, err := openBolt(tmp.Name())
+	if err != nil {
+		return fmt.Errorf("invalid snapshot: %w", err)
+	}
+	db.Close()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.replaceFile(tmp.Name())
+}
+
+// Export writes the chunks in namespaces covered by ns as JSON lines
+func (b *BoltDBStore) Export(ns Namespace, w io.Writer) (int, error) {
+	if err := ns.Validate(); err != nil {
+		return 0, err
+	}
+
+	buf := bufio.NewWriter(w)
+	enc := json.NewEncoder(buf)
+	exported := 0
+	err := b.view(func(tx *bolt.Tx) error {
+		return forEachNamespace(tx, func(space Namespace) error {
+			if !ns.Covers(space) {
+				return nil
+			}
+			return forEachChunk(tx, space, func(k []byte, chunk Chunk) error {
+				exported++
+				return enc.Encode(chunk)
+			})
+		})
+	})
+	if err != nil {
+		return exported, fmt.Errorf("failed to export chunks: %w", err)
+	}
+	return exported, buf.Flush()
+}
+
+// Import adds chunks exported with Export. Chunks keep their namespace
+// unless into names a tenant, which then receives them.
+func (b *BoltDBStore) Import(r io.Reader, into Namespace) (int, error) {
+	const batchSize = 500
+
+	dec := json.NewDecoder(bufio.NewReader(r))
+	imported := 0
+	var batch []Chunk
+	flush := func() error {
+		if err := addByNamespace(b, batch); err != nil {
+			return err
+		}
+		imported += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		var chunk Chunk
+		if err := dec.Decode(&chunk); err == io.EOF {
+			break
+		} else if err != nil {
+			return imported, fmt.Errorf("failed to decode chunk %d: %w", imported+len(batch)+1, err)
+		}
+		if into.Tenant != "" {
+			chunk.Namespace = into
+		}
+		batch = append(batch, chunk)
+		if len(batch) == batchSize {
+			if err := flush(); err != nil {
+				return imported, err
+			}
+		}
+	}
+	return imported, flush()
+}
+
+// RebuildIndexes recreates the expiry index and statistics from the
+// stored chunks
+func (b *BoltDBStore) RebuildIndexes() (Stats, error) {
+	var stats Stats
+	err := b.update(func(tx *bolt.Tx) error {
+		var err error
+		stats, err = rebuildIndexes(tx)
+		return err
+	})
+	return stats, err
+}
+
+// maintenanceRoutine periodically expires chunks and compacts the file
+// once most of it is free pages
+func (b *BoltDBStore) maintenanceRoutine() {
+	ticker := time.NewTicker(b.config.MaintenanceInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.stop:
+			return
+		case now := <-ticker.C:
+			if len(b.config.TTL) > 0 {
+				if removed, err := b.Expire(now); err != nil {
+					logrus.WithError(err).WithField("path", b.path).Warn("Failed to expire memory chunks")
+				} else if removed > 0 {
+					logrus.WithFields(logrus.Fields{"path": b.path, "removed": removed}).Info("Expired memory chunks")
+				}
+			}
+			if b.needsCompaction() {
+				if result, err := b.Compact(); err != nil {
+					logrus.WithError(err).WithField("path", b.path).Warn("Failed to compact memory store")
+				} else {
+					logrus.WithFields(logrus.Fields{
+						"path":   b.path,
+						"before": result.SizeBefore,
+						"after":  result.SizeAfter,
+					}).Info("Compacted memory store")
+				}
+			}
+		}
+	}
+}
+
+// needsCompaction reports whether free pages make up most of the file
+func (b *BoltDBStore) needsCompaction() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	info, err := os.Stat(b.path)
+	if err != nil || info.Size() < compactMinSize {
+		return false
+	}
+	stats := b.db.Stats()
+	free := int64(stats.FreePageN+stats.PendingPageN) * int64(b.db.Info().PageSize)
+	return float64(free)/float64(info.Size()) >= compactFreeRatio
+}
+
+// replaceFile swaps the live database for the file at path. Callers hold
+// the write lock.
+func (b *BoltDBStore) replaceFile(path string) error {
+	if err := b.db.Close(); err != nil {
+		return fmt.Errorf("failed to close store: %w", err)
+	}
+	if err := os.Rename(path, b.path); err != nil {
+		// Keep serving the old file
+		if db, openErr := openBolt(b.path); openErr == nil {
+			b.db = db
+		}
+		return fmt.Errorf("failed to replace store file: %w", err)
+	}
+	db, err := openBolt(b.path)
+	if err != nil {
+		return fmt.Errorf("failed to reopen store: %w", err)
+	}
+	b.db = db
+	return nil
+}
+
+// rebuildIndexes recreates the expiry bucket and statistics in tx
+func rebuildIndexes(tx *bolt.Tx) (Stats, error) {
+	if tx.Bucket([]byte(expiryBucket)) != nil {
+		if err := tx.DeleteBucket([]byte(expiryBucket)); err != nil {
+			return Stats{}, err
+		}
+	}
+	expiry, err := tx.CreateBucket([]byte(expiryBucket))
+	if err != nil {
+		return Stats{}, err
+	}
+
+	var stats Stats
+	err = forEachNamespace(tx, func(ns Namespace) error {
+		return forEachChunk(tx, ns, func(k []byte, chunk Chunk) error {
+			stats.countSource(chunk.Metadata.Source)
+			return expiry.Put(expiryKey(ns, chunk), nil)
+		})
+	})
+	if err != nil {
+		return Stats{}, err
+	}
+	stats.LastUpdated = time.Now()
+	return stats, writeStats(tx, stats)
+}
+
+// chunkAt decodes one chunk
+func chunkAt(tx *bolt.Tx, ns Namespace, id string) (Chunk, error) {
+	bucket := tx.Bucket([]byte(chunksBucket)).Bucket([]byte(ns.Key()))
+	if bucket == nil {
+		return Chunk{ID: id, Namespace: ns}, nil
+	}
+	v := bucket.Get([]byte(id))
+	if v == nil {
+		return Chunk{ID: id, Namespace: ns}, nil
+	}
+	chunk, err := decodeChunk(v)
+	chunk.Namespace = ns
+	return chunk, err
+}
+
+// copyBuckets copies every top-level bucket from src to dst
+func copyBuckets(src, dst *bolt.Tx) error {
+	return src.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+		copied, err := dst.CreateBucket(name)
+		if err != nil {
+			return err
+		}
+		return copyBucket(bucket, copied)
+	})
+}
+
+func copyBucket(src, dst *bolt.Bucket) error {
+	return src.ForEach(func(k, v []byte) error {
+		if v != nil {
+			return dst.Put(k, v)
+		}
+		nested, err := dst.CreateBucket(k)
+		if err != nil {
+			return err
+		}
+		return copyBucket(src.Bucket(k), nested)
+	})
+}
+
+func (d statsDelta) total() int {
+	total := 0
+	for _, n := range d {
+		total += n
+	}
+	return total
+}
+
+// Lifecycle options
+
+// WithTTL keeps chunks from source for ttl. The BoltDB store enforces it
+// during maintenance, the hybrid store on every auto-save.
+func WithTTL(source string, ttl time.Duration) Option {
+	return func(cfg interface{}) {
+		var policy *TTLPolicy
+		switch c := cfg.(type) {
+		case *Config:
+			policy = &c.TTL
+		case *BoltConfig:
+			policy = &c.TTL
+		default:
+			return
+		}
+		if *policy == nil {
+			*policy = make(TTLPolicy)
+		}
+		(*policy)[source] = ttl
+	}
+}
+
+// WithTTLPolicy sets the TTL for several sources at once
+func WithTTLPolicy(policy TTLPolicy) Option {
+	return func(cfg interface{}) {
+		for source, ttl := range policy {
+			WithTTL(source, ttl)(cfg)
+		}
+	}
+}
+
+// WithMaintenance runs TTL expiry and compaction every interval
+func WithMaintenance(interval time.Duration) Option {
+	return func(cfg interface{}) {
+		switch c := cfg.(type) {
+		case *Config:
+			c.MaintenanceInterval = interval
+		case *BoltConfig:
+			c.MaintenanceInterval = interval
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package memory
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseTTLPolicy(t *testing.T) {
+	policy, err := ParseTTLPolicy("chat=720h, document=8760h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy["chat"] != 720*time.Hour || policy["document"] != 8760*time.Hour || len(policy) != 2 {
+		t.Errorf("ParseTTLPolicy() = %v", policy)
+	}
+	if _, err := ParseTTLPolicy("chat"); err == nil {
+		t.Error("ParseTTLPolicy() without duration should fail")
+	}
+}
+
+func TestBoltDBStore_Lifecycle(t *testing.T) {
+	dir := t.TempDir()
+	store := NewBoltDBStore(filepath.Join(dir, "chunks.db"), WithTTL("chat", time.Hour))
+	defer store.Close()
+
+	now := time.Now()
+	chunk := func(id, source string, age time.Duration) Chunk {
+		c := namespaceChunk(id)
+		c.Metadata.Source = source
+		c.Timestamp = now.Add(-age)
+		return c
+	}
+	other := SessionNamespace("tenant", "s2")
+	if err := store.Add(testNS, []Chunk{
+		chunk("old-chat", "chat", 2*time.Hour),
+		chunk("new-chat", "chat", time.Minute),
+		chunk("old-doc", "document", 48*time.Hour),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Add(other, []Chunk{chunk("other-chat", "chat", 3*time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only chat chunks past their TTL expire, in every namespace
+	removed, err := store.Expire(now)
+	if err != nil || removed != 2 {
+		t.Fatalf("Expire() = %d, %v, want 2", removed, err)
+	}
+	if stats := store.GetStats(); stats.TotalChunks != 2 || stats.ChatChunks != 1 || stats.DocumentChunks != 1 {
+		t.Errorf("GetStats() after expiry = %+v", stats)
+	}
+	if removed, _ := store.Expire(now); removed != 0 {
+		t.Errorf("second Expire() = %d, want 0", removed)
+	}
+
+	// Export and import into another tenant
+	var exported bytes.Buffer
+	if n, err := store.Export(TenantNamespace("tenant"), &exported); err != nil || n != 2 {
+		t.Fatalf("Export() = %d, %v, want 2", n, err)
+	}
+	copyStore := NewBoltDBStore(filepath.Join(dir, "copy.db"))
+	defer copyStore.Close()
+	if n, err := copyStore.Import(&exported, SessionNamespace("moved", "s1")); err != nil || n != 2 {
+		t.Fatalf("Import() = %d, %v, want 2", n, err)
+	}
+	if got := copyStore.NamespaceStats(SessionNamespace("moved", "s1")).TotalChunks; got != 2 {
+		t.Errorf("imported chunks = %d, want 2", got)
+	}
+
+	// Snapshot, change the store, then restore the snapshot
+	var snapshot bytes.Buffer
+	if _, err := store.Snapshot(&snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Purge(TenantNamespace("tenant")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Restore(&snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if got := store.GetStats().TotalChunks; got != 2 {
+		t.Errorf("chunks after restore = %d, want 2", got)
+	}
+	if err := store.Restore(bytes.NewReader([]byte("not a database"))); err == nil {
+		t.Error("Restore() of garbage should fail")
+	}
+
+	// Compaction keeps every chunk and the expiry index
+	if _, err := store.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(searchIDs(t, store, testNS)); got != 2 {
+		t.Errorf("search after compaction found %d chunks, want 2", got)
+	}
+	if removed, err := store.Expire(now.Add(2 * time.Hour)); err != nil || removed != 1 {
+		t.Errorf("Expire() after compaction = %d, %v, want 1", removed, err)
+	}
+
+	stats, err := store.RebuildIndexes()
+	if err != nil || stats.TotalChunks != 1 || stats.DocumentChunks != 1 {
+		t.Errorf("RebuildIndexes() = %+v, %v", stats, err)
+	}
+}
@@ -0,0 +1,136 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrInvalidNamespace is returned when a call has no tenant
+	ErrInvalidNamespace = errors.New("memory namespace requires a tenant")
+	// ErrQuotaExceeded is returned when an Add would take a namespace past
+	// its chunk quota
+	ErrQuotaExceeded = errors.New("memory namespace quota exceeded")
+)
+
+// Namespace scopes every store operation to a tenant (a user or workspace)
+// and optionally one of its sessions. Chunks written without a session are
+// shared by all of the tenant's sessions.
+type Namespace struct {
+	Tenant  string
+	Session string
+}
+
+// TenantNamespace returns the namespace shared by all of a tenant's sessions
+func TenantNamespace(tenant string) Namespace {
+	return Namespace{Tenant: tenant}
+}
+
+// SessionNamespace returns the namespace private to one session
+func SessionNamespace(tenant, session string) Namespace {
+	return Namespace{Tenant: tenant, Session: session}
+}
+
+// Validate checks that the namespace names a tenant
+func (n Namespace) Validate() error {
+	if n.Tenant == "" || strings.Contains(n.Tenant, "/") {
+		return ErrInvalidNamespace
+	}
+	return nil
+}
+
+// Key is the namespace's storage key, "tenant/session"
+func (n Namespace) Key() string {
+	return n.Tenant + "/" + n.Session
+}
+
+// String formats the namespace for logs
+func (n Namespace) String() string {
+	if n.Session == "" {
+		return n.Tenant
+	}
+	return n.Tenant + "/" + n.Session
+}
+
+// Parent returns the tenant-wide namespace
+func (n Namespace) Parent() Namespace {
+	return Namespace{Tenant: n.Tenant}
+}
+
+// Sees reports whether searches in n return chunks stored in other: a
+// session sees its own chunks and those shared across its tenant, and a
+// tenant-wide search sees all of the tenant's chunks
+func (n Namespace) Sees(other Namespace) bool {
+	return other.Tenant == n.Tenant && (n.Session == "" || other.Session == "" || other.Session == n.Session)
+}
+
+// Covers reports whether purging n removes other. Purging a tenant removes
+// all of its sessions.
+func (n Namespace) Covers(other Namespace) bool {
+	return other.Tenant == n.Tenant && (n.Session == "" || other.Session == n.Session)
+}
+
+// ParseNamespace parses a Key
+func ParseNamespace(key string) (Namespace, error) {
+	tenant, session, ok := strings.Cut(key, "/")
+	ns := Namespace{Tenant: tenant, Session: session}
+	if !ok {
+		return ns, fmt.Errorf("invalid namespace key %q", key)
+	}
+	return ns, ns.Validate()
+}
+
+// storeKey identifies a chunk within a store, so equal IDs in different
+// namespaces never collide
+func storeKey(ns Namespace, id string) string {
+	return ns.Key() + "\x00" + id
+}
+
+// checkQuota returns ErrQuotaExceeded when adding added new chunks to a
+// namespace holding existing would pass quota (0 means unlimited)
+func checkQuota(ns Namespace, quota, existing, added int) error {
+	if quota > 0 && existing+added > quota {
+		return fmt.Errorf("%w: %s holds %d of %d chunks, adding %d", ErrQuotaExceeded, ns, existing, quota, added)
+	}
+	return nil
+}
+
+// countSource adds one chunk to the per-source counters
+func (s *Stats) countSource(source string) {
+	s.addSource(source, 1)
+}
+
+// addSource adjusts the per-source counters by n chunks
+func (s *Stats) addSource(source string, n int) {
+	s.TotalChunks += n
+	switch source {
+	case "spreadsheet":
+		s.SpreadsheetChunks += n
+	case "document":
+		s.DocumentChunks += n
+	case "chat":
+		s.ChatChunks += n
+	}
+}
+
+// Namespace options
+
+// WithNamespaceQuota caps the chunks each namespace may hold; 0 disables
+// the cap
+func WithNamespaceQuota(maxChunks int) Option {
+	return func(cfg interface{}) {
+		switch c := cfg.(type) {
+		case *Config:
+			c.NamespaceQuota = maxChunks
+		case *InMemoryConfig:
+			c.NamespaceQuota = maxChunks
+		case *BoltConfig:
+			c.NamespaceQuota = maxChunks
+		case *HNSWConfig:
+			c.NamespaceQuota = maxChunks
+		case *PostgresConfig:
+			c.NamespaceQuota = maxChunks
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package memory
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+var testNS = SessionNamespace("tenant", "s1")
+
+func namespaceChunk(id string) Chunk {
+	return Chunk{
+		ID:       id,
+		Content:  "EBITDA " + id,
+		Vector:   []float32{1, 0, 0},
+		Metadata: ChunkMetadata{Source: "spreadsheet"},
+	}
+}
+
+func searchIDs(t *testing.T, store VectorStore, ns Namespace) map[string]bool {
+	t.Helper()
+	results, err := store.Search(ns, []float32{1, 0, 0}, 10, nil)
+	if err != nil {
+		t.Fatalf("Search(%s) error = %v", ns, err)
+	}
+	ids := make(map[string]bool)
+	for _, r := range results {
+		ids[r.Chunk.Namespace.String()+":"+r.Chunk.ID] = true
+	}
+	return ids
+}
+
+func TestVectorStore_Namespaces(t *testing.T) {
+	stores := map[string]func(t *testing.T) VectorStore{
+		"inmemory": func(t *testing.T) VectorStore {
+			return NewInMemoryStore(100, WithNamespaceQuota(2))
+		},
+		"boltdb": func(t *testing.T) VectorStore {
+			return NewBoltDBStore(filepath.Join(t.TempDir(), "chunks.db"), WithNamespaceQuota(2))
+		},
+		"hybrid": func(t *testing.T) VectorStore {
+			return NewHybridVectorStore(
+				WithInMemoryCache(100),
+				WithDiskPersistence(filepath.Join(t.TempDir(), "chunks.db")),
+				WithAutoSave(0),
+				WithNamespaceQuota(2),
+			)
+		},
+		"hnsw": func(t *testing.T) VectorStore {
+			return NewHNSWStore(WithNamespaceQuota(2))
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+			defer store.Close()
+
+			shared := TenantNamespace("tenant")
+			other := SessionNamespace("tenant", "s2")
+			rival := SessionNamespace("rival", "s1")
+
+			for ns, ids := range map[Namespace][]string{
+				shared: {"a"},
+				testNS: {"a", "b"},
+				other:  {"c"},
+				rival:  {"a"},
+			} {
+				for _, id := range ids {
+					if err := store.Add(ns, []Chunk{namespaceChunk(id)}); err != nil {
+						t.Fatalf("Add(%s, %s) error = %v", ns, id, err)
+					}
+				}
+			}
+
+			// A session sees its own chunks and the tenant's shared ones only
+			got := searchIDs(t, store, testNS)
+			want := []string{"tenant:a", "tenant/s1:a", "tenant/s1:b"}
+			if len(got) != len(want) {
+				t.Errorf("Search(%s) = %v, want %v", testNS, got, want)
+			}
+			for _, key := range want {
+				if !got[key] {
+					t.Errorf("Search(%s) missing %s", testNS, key)
+				}
+			}
+			if got := searchIDs(t, store, rival); len(got) != 1 || !got["rival/s1:a"] {
+				t.Errorf("Search(%s) = %v, want only its own chunk", rival, got)
+			}
+			if got := searchIDs(t, store, shared); len(got) != 4 || got["rival/s1:a"] {
+				t.Errorf("Search(%s) = %v, want all of the tenant's chunks", shared, got)
+			}
+
+			// Upserts don't count against the quota, new chunks do
+			if err := store.Add(testNS, []Chunk{namespaceChunk("b")}); err != nil {
+				t.Errorf("upsert error = %v", err)
+			}
+			if err := store.Add(testNS, []Chunk{namespaceChunk("d")}); !errors.Is(err, ErrQuotaExceeded) {
+				t.Errorf("Add() past quota error = %v, want ErrQuotaExceeded", err)
+			}
+			if err := store.Add(Namespace{}, []Chunk{namespaceChunk("e")}); !errors.Is(err, ErrInvalidNamespace) {
+				t.Errorf("Add() without tenant error = %v, want ErrInvalidNamespace", err)
+			}
+
+			stats := store.NamespaceStats(testNS)
+			if stats.TotalChunks != 2 || stats.SpreadsheetChunks != 2 || stats.Quota != 2 {
+				t.Errorf("NamespaceStats(%s) = %+v", testNS, stats)
+			}
+
+			// Purging a tenant removes every session but leaves other tenants
+			removed, err := store.Purge(shared)
+			if err != nil || removed != 4 {
+				t.Errorf("Purge(%s) = %d, %v, want 4", shared, removed, err)
+			}
+			if got := searchIDs(t, store, testNS); len(got) != 0 {
+				t.Errorf("Search after purge = %v", got)
+			}
+			if got := store.NamespaceStats(rival).TotalChunks; got != 1 {
+				t.Errorf("rival chunks after purge = %d, want 1", got)
+			}
+		})
+	}
+}
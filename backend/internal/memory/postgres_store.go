@@ -0,0 +1,481 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+// PostgresConfig tunes the pgvector-backed store
+type PostgresConfig struct {
+	MinSimilarity  float32
+	MaxCandidates  int // Upper bound on rows scanned when a filter rejects results
+	Timeout        time.Duration
+	NamespaceQuota int // Max chunks per namespace, 0 for no cap
+}
+
+// PostgresStore is a VectorStore over the memory_chunks table. Document
+// filings, workbook chunks and chat turns share the table so a single query
+// spans everything visible to a namespace. Rows are owned by the
+// namespace key; the user_id and session_id columns are kept for joins and
+// cascading deletes. The store is safe for concurrent use.
+type PostgresStore struct {
+	db     *sqlx.DB
+	config PostgresConfig
+}
+
+// NewPostgresStore creates a store over db
+func NewPostgresStore(db *sqlx.DB, opts ...Option) *PostgresStore {
+	config := PostgresConfig{
+		MinSimilarity: 0.7, // Same cut-off as the in-process stores
+		MaxCandidates: 2000,
+		Timeout:       10 * time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return &PostgresStore{db: db, config: config}
+}
+
+// visibleOwners returns the owner keys a session search may read
+func visibleOwners(ns Namespace) pq.StringArray {
+	return pq.StringArray{ns.Key(), ns.Parent().Key()}
+}
+
+// tenantPrefix returns the owner prefix a tenant-wide search reads, or nil
+// for session searches
+func tenantPrefix(ns Namespace) interface{} {
+	if ns.Session != "" {
+		return nil
+	}
+	return ns.Key()
+}
+
+// tenantUserID returns the tenant as a nullable users.id argument
+func tenantUserID(ns Namespace) interface{} {
+	id, err := uuid.Parse(ns.Tenant)
+	if err != nil {
+		return nil
+	}
+	return id
+}
+
+// nullableSession returns the session as a nullable query argument
+func nullableSession(ns Namespace) interface{} {
+	if ns.Session == "" {
+		return nil
+	}
+	return ns.Session
+}
+
+// Add upserts chunks into a namespace
+func (p *PostgresStore) Add(ns Namespace, chunks []Chunk) error {
+	if err := ns.Validate(); err != nil {
+		return err
+	}
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	ctx, cancel := p.context()
+	defer cancel()
+
+	tx, err := p.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	owner := ns.Key()
+	if p.config.NamespaceQuota > 0 {
+		if err := p.checkQuota(ctx, tx, ns, chunks); err != nil {
+			return err
+		}
+	}
+
+	query := `
+		INSERT INTO memory_chunks (
+			owner, chunk_id, user_id, session_id, document_id,
+			source, content, embedding, metadata, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8::vector, $9, $10)
+		ON CONFLICT (owner, chunk_id) DO UPDATE SET
+			document_id = EXCLUDED.document_id,
+			source = EXCLUDED.source,
+			content = EXCLUDED.content,
+			embedding = EXCLUDED.embedding,
+			metadata = EXCLUDED.metadata,
+			created_at = EXCLUDED.created_at`
+
+	stmt, err := tx.PreparexContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare chunk insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, chunk := range chunks {
+		metadata, err := json.Marshal(chunk.Metadata)
+		if err != nil {
+			return fmt.Errorf("failed to marshal metadata for chunk %s: %w", chunk.ID, err)
+		}
+
+		var documentID interface{}
+		if chunk.Metadata.Source == "document" {
+			if id, err := uuid.Parse(chunk.Metadata.SourceID); err == nil {
+				documentID = id
+			}
+		}
+
+		var embedding interface{}
+		if len(chunk.Vector) > 0 {
+			embedding = vectorLiteral(chunk.Vector)
+		}
+
+		createdAt := chunk.Timestamp
+		if createdAt.IsZero() {
+			createdAt = time.Now()
+		}
+
+		if _, err := stmt.ExecContext(ctx,
+			owner, chunk.ID, tenantUserID(ns), nullableSession(ns), documentID,
+			chunk.Metadata.Source, chunk.Content, embedding, metadata, createdAt,
+		); err != nil {
+			return fmt.Errorf("failed to store chunk %s: %w", chunk.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit chunks: %w", err)
+	}
+	return nil
+}
+
+// Search returns the topK chunks visible to ns closest to query by cosine
+// similarity. Filters run in process, so the candidate window widens until
+// enough rows pass or MaxCandidates is reached.
+func (p *PostgresStore) Search(ns Namespace, query []float32, topK int, filter FilterFunc) ([]SearchResult, error) {
+	if err := ns.Validate(); err != nil {
+		return nil, err
+	}
+	if len(query) == 0 || topK <= 0 {
+		return nil, nil
+	}
+
+	ctx, cancel := p.context()
+	defer cancel()
+
+	sqlQuery := `
+		SELECT owner, chunk_id, content, metadata, created_at,
+			1 - (embedding <=> $1::vector) AS similarity
+		FROM memory_chunks
+		WHERE (owner = ANY($2) OR left(owner, char_length($5::text)) = $5::text)
+			AND embedding IS NOT NULL
+			AND 1 - (embedding <=> $1::vector) >= $3
+		ORDER BY embedding <=> $1::vector
+		LIMIT $4`
+
+	vector := vectorLiteral(query)
+	limit := topK
+	if filter != nil {
+		limit = topK * 4
+	}
+	for {
+		rows, err := p.db.QueryxContext(ctx, sqlQuery,
+			vector, visibleOwners(ns), p.config.MinSimilarity, limit, tenantPrefix(ns))
+		if err != nil {
+			return nil, fmt.Errorf("failed to search chunks: %w", err)
+		}
+		results, scanned, err := scanSearchResults(rows, filter, topK)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(results) >= topK || scanned < limit || limit >= p.config.MaxCandidates {
+			return results, nil
+		}
+		limit = min(limit*4, p.config.MaxCandidates)
+	}
+}
+
+// KeywordSearch ranks chunks visible to ns with Postgres full-text search,
+// giving the Retriever a keyword ranking without loading chunks into memory
+func (p *PostgresStore) KeywordSearch(ns Namespace, keywords []string, topK int, filter FilterFunc) []SearchResult {
+	terms := Tokenize(strings.Join(keywords, " "))
+	if ns.Validate() != nil || len(terms) == 0 || topK <= 0 {
+		return nil
+	}
+
+	ctx, cancel := p.context()
+	defer cancel()
+
+	limit := topK
+	if filter != nil {
+		limit = min(topK*4, p.config.MaxCandidates)
+	}
+
+	// Tokens are letters and digits only, so joining them is a safe tsquery
+	rows, err := p.db.QueryxContext(ctx, `
+		SELECT owner, chunk_id, content, metadata, created_at,
+			ts_rank_cd(to_tsvector('simple', content), query) AS similarity
+		FROM memory_chunks, to_tsquery('simple', $1) query
+		WHERE (owner = ANY($2) OR left(owner, char_length($4::text)) = $4::text)
+			AND to_tsvector('simple', content) @@ query
+		ORDER BY similarity DESC
+		LIMIT $3`,
+		strings.Join(terms, " | "), visibleOwners(ns), limit, tenantPrefix(ns))
+	if err != nil {
+		logrus.WithError(err).Warn("Memory keyword search failed")
+		return nil
+	}
+
+	results, _, err := scanSearchResults(rows, filter, topK)
+	if err != nil {
+		logrus.WithError(err).Warn("Memory keyword search failed")
+		return nil
+	}
+	for i := range results {
+		results[i].Similarity = 0
+	}
+	return results
+}
+
+// Delete removes chunks in ns matching the filter
+func (p *PostgresStore) Delete(ns Namespace, filter FilterFunc) error {
+	if err := ns.Validate(); err != nil {
+		return err
+	}
+	if filter == nil {
+		return nil
+	}
+
+	ctx, cancel := p.context()
+	defer cancel()
+
+	owner := ns.Key()
+	rows, err := p.db.QueryxContext(ctx, `
+		SELECT owner, chunk_id, content, metadata, created_at, 0::float8 AS similarity
+		FROM memory_chunks
+		WHERE owner = $1`, owner)
+	if err != nil {
+		return fmt.Errorf("failed to list chunks: %w", err)
+	}
+	matches, _, err := scanSearchResults(rows, filter, -1)
+	if err != nil {
+		return err
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(matches))
+	for i, match := range matches {
+		ids[i] = match.Chunk.ID
+	}
+	query, args, err := sqlx.In(`DELETE FROM memory_chunks WHERE owner = ? AND chunk_id IN (?)`, owner, ids)
+	if err != nil {
+		return fmt.Errorf("failed to build delete: %w", err)
+	}
+	if _, err := p.db.ExecContext(ctx, p.db.Rebind(query), args...); err != nil {
+		return fmt.Errorf("failed to delete chunks: %w", err)
+	}
+	return nil
+}
+
+// Purge removes every chunk in ns, or in all of a tenant's namespaces when
+// ns has no session
+func (p *PostgresStore) Purge(ns Namespace) (int, error) {
+	if err := ns.Validate(); err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := p.context()
+	defer cancel()
+
+	var result sql.Result
+	var err error
+	if ns.Session == "" {
+		prefix := ns.Key()
+		result, err = p.db.ExecContext(ctx,
+			`DELETE FROM memory_chunks WHERE left(owner, char_length($1)) = $1`, prefix)
+	} else {
+		result, err = p.db.ExecContext(ctx, `DELETE FROM memory_chunks WHERE owner = $1`, ns.Key())
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge namespace %s: %w", ns, err)
+	}
+
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count purged chunks: %w", err)
+	}
+	return int(removed), nil
+}
+
+// NamespaceStats counts the chunks stored in ns
+func (p *PostgresStore) NamespaceStats(ns Namespace) Stats {
+	stats := p.stats(`WHERE owner = $1`, ns.Key())
+	stats.Quota = p.config.NamespaceQuota
+	return stats
+}
+
+// GetStats counts every chunk in the table
+func (p *PostgresStore) GetStats() Stats {
+	stats := p.stats(``)
+	stats.Quota = p.config.NamespaceQuota
+	return stats
+}
+
+// stats groups chunk counts and sizes by source
+func (p *PostgresStore) stats(where string, args ...interface{}) Stats {
+	ctx, cancel := p.context()
+	defer cancel()
+
+	stats := Stats{LastUpdated: time.Now()}
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT source, COUNT(*), COALESCE(SUM(octet_length(content) + octet_length(metadata::text)), 0)
+		FROM memory_chunks `+where+`
+		GROUP BY source`, args...)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to get memory stats")
+		return stats
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var source string
+		var count int
+		var size int64
+		if err := rows.Scan(&source, &count, &size); err != nil {
+			logrus.WithError(err).Warn("Failed to scan memory stats")
+			return stats
+		}
+		stats.TotalChunks += count
+		stats.StorageSize += size
+		switch source {
+		case "spreadsheet":
+			stats.SpreadsheetChunks = count
+		case "document":
+			stats.DocumentChunks = count
+		case "chat":
+			stats.ChatChunks = count
+		}
+	}
+
+	return stats
+}
+
+// Close is a no-op; the connection pool is owned by the caller
+func (p *PostgresStore) Close() error {
+	return nil
+}
+
+// checkQuota locks the namespace for the transaction and rejects chunks
+// that would take it past its quota
+func (p *PostgresStore) checkQuota(ctx context.Context, tx *sqlx.Tx, ns Namespace, chunks []Chunk) error {
+	owner := ns.Key()
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, owner); err != nil {
+		return fmt.Errorf("failed to lock namespace: %w", err)
+	}
+
+	ids := make(pq.StringArray, 0, len(chunks))
+	seen := make(map[string]bool)
+	for _, chunk := range chunks {
+		if !seen[chunk.ID] {
+			seen[chunk.ID] = true
+			ids = append(ids, chunk.ID)
+		}
+	}
+
+	var existing, replaced int
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE chunk_id = ANY($2))
+		FROM memory_chunks
+		WHERE owner = $1`, owner, ids).Scan(&existing, &replaced); err != nil {
+		return fmt.Errorf("failed to count namespace chunks: %w", err)
+	}
+
+	return checkQuota(ns, p.config.NamespaceQuota, existing, len(ids)-replaced)
+}
+
+func (p *PostgresStore) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), p.config.Timeout)
+}
+
+// scanSearchResults reads chunk rows, applies filter and stops after limit
+// matches (limit < 0 reads everything). It returns the rows scanned.
+func scanSearchResults(rows *sqlx.Rows, filter FilterFunc, limit int) ([]SearchResult, int, error) {
+	defer rows.Close()
+
+	var results []SearchResult
+	scanned := 0
+	for rows.Next() {
+		var (
+			owner, id, content string
+			metadata           []byte
+			createdAt          sql.NullTime
+			similarity         float64
+		)
+		if err := rows.Scan(&owner, &id, &content, &metadata, &createdAt, &similarity); err != nil {
+			return nil, scanned, fmt.Errorf("failed to scan chunk: %w", err)
+		}
+		scanned++
+
+		ns, err := ParseNamespace(owner)
+		if err != nil {
+			return nil, scanned, fmt.Errorf("chunk %s has an invalid owner: %w", id, err)
+		}
+		chunk := Chunk{ID: id, Content: content, Timestamp: createdAt.Time, Namespace: ns}
+		if err := json.Unmarshal(metadata, &chunk.Metadata); err != nil {
+			return nil, scanned, fmt.Errorf("failed to decode metadata for chunk %s: %w", id, err)
+		}
+		if filter != nil && !filter(chunk) {
+			continue
+		}
+
+		if limit < 0 || len(results) < limit {
+			results = append(results, SearchResult{
+				Chunk:      chunk,
+				Similarity: float32(similarity),
+				Score:      float32(similarity),
+			})
+		}
+	}
+
+	return results, scanned, rows.Err()
+}
+
+// vectorLiteral formats a vector in pgvector's text representation
+func vectorLiteral(vector []float32) string {
+	var b strings.Builder
+	b.Grow(len(vector) * 10)
+	b.WriteByte('[')
+	for i, v := range vector {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(v), 'g', -1, 32))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// Postgres store options
+
+// WithMaxCandidates bounds how far a filtered search widens
+func WithMaxCandidates(n int) Option {
+	return func(cfg interface{}) {
+		if c, ok := cfg.(*PostgresConfig); ok {
+			c.MaxCandidates = n
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package memory
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FusionMethod selects how vector and keyword rankings are combined
+type FusionMethod string
+
+const (
+	// FusionRRF sums 1/(k+rank) across rankings, ignoring raw scores
+	FusionRRF FusionMethod = "rrf"
+	// FusionWeighted blends cosine similarity with max-normalized BM25
+	FusionWeighted FusionMethod = "weighted"
+)
+
+// KeywordSearcher is implemented by stores that keep a BM25 index
+type KeywordSearcher interface {
+	KeywordSearch(ns Namespace, keywords []string, topK int, filter FilterFunc) []SearchResult
+}
+
+// RetrieverConfig tunes hybrid retrieval
+type RetrieverConfig struct {
+	Fusion              FusionMethod
+	RRFConstant         float64 // k in 1/(k+rank)
+	VectorWeight        float64
+	KeywordWeight       float64
+	CandidateMultiplier int // Candidates fetched from each ranking, as a multiple of topK
+
+	// Multiplicative boosts applied after fusion
+	CellBoost  float64 // Query names a cell inside the chunk's range
+	SheetBoost float64 // Query names the chunk's sheet
+	TermBoost  float64 // Per financial term shared by query and chunk
+}
+
+// Retriever runs vector and BM25 search over a store and fuses the
+// rankings. SearchResult.Score carries the fused, boosted score while
+// Similarity keeps the raw cosine similarity when the chunk came from
+// vector search.
+type Retriever struct {
+	config RetrieverConfig
+}
+
+// NewRetriever creates a retriever using reciprocal rank fusion by default
+func NewRetriever(opts ...Option) *Retriever {
+	config := RetrieverConfig{
+		Fusion:              FusionRRF,
+		RRFConstant:         60,
+		VectorWeight:        1,
+		KeywordWeight:       1,
+		CandidateMultiplier: 4,
+		CellBoost:           1.0,
+		SheetBoost:          0.3,
+		TermBoost:           0.15,
+	}
+
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return &Retriever{config: config}
+}
+
+// Search returns the topK chunks visible to ns for query. queryVector may
+// be nil, in which case only the keyword ranking is used. Stores that do not
+// implement KeywordSearcher fall back to vector ranking alone.
+func (r *Retriever) Search(store VectorStore, ns Namespace, query string, queryVector []float32, topK int, filter FilterFunc) ([]SearchResult, error) {
+	if topK <= 0 {
+		return nil, nil
+	}
+	candidates := topK * r.config.CandidateMultiplier
+	if candidates < topK {
+		candidates = topK
+	}
+
+	var vectorResults, keywordResults []SearchResult
+	if len(queryVector) > 0 {
+		// Stores normalize the query in place
+		vector := append([]float32(nil), queryVector...)
+		results, err := store.Search(ns, vector, candidates, filter)
+		if err != nil {
+			return nil, err
+		}
+		vectorResults = results
+	}
+	if searcher, ok := store.(KeywordSearcher); ok {
+		keywordResults = searcher.KeywordSearch(ns, strings.Fields(query), candidates, filter)
+	}
+
+	fused := r.fuse(vectorResults, keywordResults)
+	if len(fused) == 0 {
+		return nil, nil
+	}
+
+	analyzed := analyzeQuery(query)
+	for i := range fused {
+		fused[i].Score *= float32(1 + r.boost(analyzed, fused[i].Chunk))
+	}
+
+	sort.SliceStable(fused, func(i, j int) bool {
+		return fused[i].Score > fused[j].Score
+	})
+	if len(fused) > topK {
+		fused = fused[:topK]
+	}
+	return fused, nil
+}
+
+// fuse merges the two rankings into one result per chunk
+func (r *Retriever) fuse(vectorResults, keywordResults []SearchResult) []SearchResult {
+	byKey := make(map[string]int)
+	var fused []SearchResult
+	add := func(result SearchResult, score float64) {
+		key := storeKey(result.Chunk.Namespace, result.Chunk.ID)
+		idx, ok := byKey[key]
+		if !ok {
+			idx = len(fused)
+			byKey[key] = idx
+			fused = append(fused, SearchResult{Chunk: result.Chunk})
+		}
+		fused[idx].Score += float32(score)
+	}
+
+	switch r.config.Fusion {
+	case FusionWeighted:
+		for _, result := range vectorResults {
+			add(result, r.config.VectorWeight*float64(max(result.Similarity, 0)))
+		}
+		var best float32
+		for _, result := range keywordResults {
+			best = max(best, result.Score)
+		}
+		for _, result := range keywordResults {
+			if best > 0 {
+				add(result, r.config.KeywordWeight*float64(result.Score/best))
+			}
+		}
+	default:
+		for rank, result := range vectorResults {
+			add(result, r.config.VectorWeight/(r.config.RRFConstant+float64(rank+1)))
+		}
+		for rank, result := range keywordResults {
+			add(result, r.config.KeywordWeight/(r.config.RRFConstant+float64(rank+1)))
+		}
+	}
+
+	for _, result := range vectorResults {
+		fused[byKey[storeKey(result.Chunk.Namespace, result.Chunk.ID)]].Similarity = result.Similarity
+	}
+	return fused
+}
+
+// boost returns the additive boost factor for chunk
+func (r *Retriever) boost(query analyzedQuery, chunk Chunk) float64 {
+	var boost float64
+	meta := chunk.Metadata
+
+	for _, cell := range query.cells {
+		if cell.sheet != "" && meta.SheetName != "" && !strings.EqualFold(cell.sheet, meta.SheetName) {
+			continue
+		}
+		if cellInRange(cell, meta.CellRange) || containsToken(chunk.Content, cell.address) {
+			boost += r.config.CellBoost
+			break
+		}
+	}
+
+	if meta.SheetName != "" && containsPhrase(query.text, strings.Join(Tokenize(meta.SheetName), " ")) {
+		boost += r.config.SheetBoost
+	}
+
+	if len(query.terms) > 0 {
+		content := " " + strings.Join(Tokenize(chunk.Content), " ") + " "
+		for _, term := range query.terms {
+			if containsPhrase(content, term) {
+				boost += r.config.TermBoost
+			}
+		}
+	}
+
+	return boost
+}
+
+// financialTerms are modelling vocabulary worth boosting on exact match.
+// Multi-word terms are matched as token phrases.
+var financialTerms = []string{
+	"revenue", "revenues", "sales", "cogs", "gross profit", "gross margin",
+	"opex", "ebitda", "ebit", "ebt", "net income", "eps", "margin",
+	"depreciation", "amortization", "interest expense", "tax rate",
+	"capex", "working capital", "nwc", "fcf", "free cash flow", "ufcf", "lfcf",
+	"dcf", "npv", "irr", "wacc", "cost of equity", "cost of debt", "beta",
+	"terminal value", "terminal growth", "exit multiple", "ev", "enterprise value",
+	"equity value", "net debt", "leverage", "dividends", "share count",
+	"diluted shares", "cagr", "yoy", "ltm", "ntm", "moic",
+}
+
+// cellPattern matches A1-style references with an optional sheet prefix
+var cellPattern = regexp.MustCompile(`(?:('[^']+'|[A-Za-z_][A-Za-z0-9_.]*)!)?\$?\b([A-Za-z]{1,3})\$?([1-9][0-9]{0,6})\b`)
+
+type cellRef struct {
+	sheet   string
+	address string // Lowercase, no sheet or $ markers
+	col     int    // 1-based
+	row     int
+}
+
+type analyzedQuery struct {
+	text  string // Tokenized query joined with single spaces and padded
+	cells []cellRef
+	terms []string
+}
+
+func analyzeQuery(query string) analyzedQuery {
+	analyzed := analyzedQuery{
+		text: " " + strings.Join(Tokenize(query), " ") + " ",
+	}
+
+	for _, match := range cellPattern.FindAllStringSubmatch(query, -1) {
+		col := columnNumber(match[2])
+		row, err := strconv.Atoi(match[3])
+		if err != nil || col == 0 || col > 16384 || row > 1048576 {
+			continue
+		}
+		analyzed.cells = append(analyzed.cells, cellRef{
+			sheet:   strings.Trim(match[1], "'"),
+			address: strings.ToLower(match[2] + match[3]),
+			col:     col,
+			row:     row,
+		})
+	}
+
+	for _, term := range financialTerms {
+		// Normalize the same way as content so stop words line up
+		term = strings.Join(Tokenize(term), " ")
+		if containsPhrase(analyzed.text, term) {
+			analyzed.terms = append(analyzed.terms, term)
+		}
+	}
+
+	return analyzed
+}
+
+// cellInRange reports whether cell lies inside an A1 or A1:B2 range
+func cellInRange(cell cellRef, cellRange string) bool {
+	bounds, ok := ParseCellRange(cellRange)
+	return ok && bounds.Contains(cell.col, cell.row)
+}
+
+func containsToken(content, token string) bool {
+	for _, t := range Tokenize(content) {
+		if t == token {
+			return true
+		}
+	}
+	return false
+}
+
+// containsPhrase matches a tokenized phrase inside padded tokenized text
+func containsPhrase(text, phrase string) bool {
+	return phrase != "" && strings.Contains(text, " "+phrase+" ")
+}
+
+// Retriever options
+
+func WithFusion(method FusionMethod) Option {
+	return func(cfg interface{}) {
+		if c, ok := cfg.(*RetrieverConfig); ok {
+			c.Fusion = method
+		}
+	}
+}
+
+func WithFusionWeights(vector, keyword float64) Option {
+	return func(cfg interface{}) {
+		if c, ok := cfg.(*RetrieverConfig); ok {
+			c.VectorWeight = vector
+			c.KeywordWeight = keyword
+		}
+	}
+}
+
+func WithMatchBoosts(cell, sheet, term float64) Option {
+	return func(cfg interface{}) {
+		if c, ok := cfg.(*RetrieverConfig); ok {
+			c.CellBoost = cell
+			c.SheetBoost = sheet
+			c.TermBoost = term
+		}
+	}
+}
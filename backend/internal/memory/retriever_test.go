@@ -0,0 +1,110 @@
+package memory
+
+import (
+	"testing"
+)
+
+func TestBM25Index(t *testing.T) {
+	index := NewBM25Index()
+	index.Add("short", "EBITDA margin")
+	index.Add("long", "EBITDA bridge from operating income including several adjustments and notes")
+	index.Add("other", "Revenue growth assumptions")
+
+	scores := index.Score(Tokenize("ebitda"))
+	if len(scores) != 2 {
+		t.Fatalf("got %d matches, want 2", len(scores))
+	}
+	if scores["short"] <= scores["long"] {
+		t.Errorf("shorter document should score higher: %v", scores)
+	}
+
+	index.Remove("short")
+	index.Add("other", "EBITDA")
+	scores = index.Score(Tokenize("ebitda"))
+	if _, ok := scores["short"]; ok || len(scores) != 2 || index.Len() != 2 {
+		t.Errorf("unexpected scores after update: %v", scores)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	got := Tokenize("What is the WACC in 'DCF Model'!$B$5:C7?")
+	want := []string{"wacc", "dcf", "model", "b5", "c7"}
+	if len(got) != len(want) {
+		t.Fatalf("Tokenize() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("token %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestRetriever_Search(t *testing.T) {
+	store := NewInMemoryStore(100)
+	store.Add(testNS, []Chunk{
+		{ID: "wacc", Vector: []float32{1, 0, 0}, Content: "WACC 8.5% cost of equity 11%",
+			Metadata: ChunkMetadata{Source: "spreadsheet", SheetName: "DCF", CellRange: "B2:B10"}},
+		{ID: "near", Vector: []float32{0.95, 0.3, 0}, Content: "Discount rate assumptions",
+			Metadata: ChunkMetadata{Source: "spreadsheet", SheetName: "Inputs", CellRange: "A1:D4"}},
+		{ID: "revenue", Vector: []float32{0, 1, 0}, Content: "Revenue 2023 1,200 2024 1,350",
+			Metadata: ChunkMetadata{Source: "spreadsheet", SheetName: "Model", CellRange: "C5:H5"}},
+		{ID: "memo", Vector: []float32{0, 0, 1}, Content: "Board memo on revenue recognition",
+			Metadata: ChunkMetadata{Source: "document"}},
+	})
+
+	retriever := NewRetriever()
+
+	// Vector ranking prefers "near" only slightly; the keyword hit decides
+	results, err := retriever.Search(store, testNS, "discount rate wacc", []float32{0.97, 0.25, 0}, 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Chunk.ID != "wacc" {
+		t.Fatalf("unexpected ranking: %+v", results)
+	}
+	if results[0].Score <= results[1].Score || results[0].Similarity == 0 {
+		t.Errorf("scores not set: %+v", results)
+	}
+
+	// Keyword only, with a cell reference boosting the chunk that covers it
+	results, _ = retriever.Search(store, testNS, "revenue in Model!E5", nil, 3, nil)
+	if len(results) == 0 || results[0].Chunk.ID != "revenue" {
+		t.Fatalf("cell boost not applied: %+v", results)
+	}
+
+	// Filters apply to both rankings
+	docsOnly := func(c Chunk) bool { return c.Metadata.Source == "document" }
+	results, _ = retriever.Search(store, testNS, "revenue", []float32{0, 1, 0}, 5, docsOnly)
+	if len(results) != 1 || results[0].Chunk.ID != "memo" {
+		t.Errorf("filter not applied: %+v", results)
+	}
+
+	weighted := NewRetriever(WithFusion(FusionWeighted))
+	results, _ = weighted.Search(store, testNS, "wacc", []float32{1, 0, 0}, 1, nil)
+	if len(results) != 1 || results[0].Chunk.ID != "wacc" {
+		t.Errorf("weighted fusion ranking: %+v", results)
+	}
+}
+
+func TestCellInRange(t *testing.T) {
+	ref := analyzeQuery("check Sheet1!AB12").cells
+	if len(ref) != 1 || ref[0].sheet != "Sheet1" || ref[0].col != 28 || ref[0].row != 12 {
+		t.Fatalf("analyzeQuery() cells = %+v", ref)
+	}
+
+	tests := []struct {
+		cellRange string
+		want      bool
+	}{
+		{"AA1:AC20", true},
+		{"Sheet1!$AB$12", true},
+		{"A1:Z100", false},
+		{"AB13:AB20", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := cellInRange(ref[0], tt.cellRange); got != tt.want {
+			t.Errorf("cellInRange(%q) = %v, want %v", tt.cellRange, got, tt.want)
+		}
+	}
+}
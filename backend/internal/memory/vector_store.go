@@ -26,6 +26,11 @@ type Config struct {
 	DiskPath         string
 	AutoSaveInterval time.Duration
 	UseCompression   bool
+	NamespaceQuota   int // Max chunks per namespace across both tiers, 0 for no cap
+
+	// Lifecycle
+	TTL                 TTLPolicy     // Chunk lifetime per source, applied to both tiers
+	MaintenanceInterval time.Duration // Disk expiry and compaction interval
 }
 
 // NewHybridVectorStore creates a new hybrid store
@@ -46,7 +51,10 @@ func NewHybridVectorStore(opts ...Option) *HybridVectorStore {
 	}
 
 	if config.DiskPath != "" {
-		store.disk = NewBoltDBStore(config.DiskPath)
+		store.disk = NewBoltDBStore(config.DiskPath,
+			WithTTLPolicy(config.TTL),
+			WithMaintenance(config.MaintenanceInterval),
+		)
 		store.loadFromDisk()
 	}
 
@@ -58,13 +66,30 @@ func NewHybridVectorStore(opts ...Option) *HybridVectorStore {
 	return store
 }
 
-// Add chunks to the store
-func (h *HybridVectorStore) Add(chunks []Chunk) error {
+// Add chunks to a namespace. The quota counts chunks in either tier once.
+func (h *HybridVectorStore) Add(ns Namespace, chunks []Chunk) error {
+	if err := ns.Validate(); err != nil {
+		return err
+	}
+
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
+	if h.config.NamespaceQuota > 0 {
+		existing := h.keys(func(space Namespace) bool { return space == ns })
+		added := make(map[string]bool)
+		for _, chunk := range chunks {
+			if key := storeKey(ns, chunk.ID); !existing[key] {
+				added[key] = true
+			}
+		}
+		if err := checkQuota(ns, h.config.NamespaceQuota, len(existing), len(added)); err != nil {
+			return err
+		}
+	}
+
 	// Add to memory
-	if err := h.memory.Add(chunks); err != nil {
+	if err := h.memory.Add(ns, chunks); err != nil {
 		return err
 	}
 
@@ -78,13 +103,13 @@ func (h *HybridVectorStore) Add(chunks []Chunk) error {
 	return nil
 }
 
-// Search performs hybrid search
-func (h *HybridVectorStore) Search(query []float32, topK int, filter FilterFunc) ([]SearchResult, error) {
+// Search performs hybrid search over the chunks visible to ns
+func (h *HybridVectorStore) Search(ns Namespace, query []float32, topK int, filter FilterFunc) ([]SearchResult, error) {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
 	// Search in memory first
-	memResults, err := h.memory.Search(query, topK, filter)
+	memResults, err := h.memory.Search(ns, query, topK, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -96,7 +121,7 @@ func (h *HybridVectorStore) Search(query []float32, topK int, filter FilterFunc)
 
 	// Search disk if needed
 	if h.disk != nil {
-		diskResults, err := h.disk.Search(query, topK-len(memResults), filter)
+		diskResults, err := h.disk.Search(ns, query, topK-len(memResults), filter)
 		if err != nil {
 			return memResults, nil // Return memory results even if disk fails
 		}
@@ -117,19 +142,27 @@ func (h *HybridVectorStore) Search(query []float32, topK int, filter FilterFunc)
 	return memResults, nil
 }
 
-// Delete removes chunks matching the filter
-func (h *HybridVectorStore) Delete(filter FilterFunc) error {
+// KeywordSearch runs BM25 over the chunks held in memory. Chunks evicted to
+// disk are only reachable through vector search.
+func (h *HybridVectorStore) KeywordSearch(ns Namespace, keywords []string, topK int, filter FilterFunc) []SearchResult {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.memory.KeywordSearch(ns, keywords, topK, filter)
+}
+
+// Delete removes chunks in ns matching the filter
+func (h *HybridVectorStore) Delete(ns Namespace, filter FilterFunc) error {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
 	// Delete from memory
-	if err := h.memory.Delete(filter); err != nil {
+	if err := h.memory.Delete(ns, filter); err != nil {
 		return err
 	}
 
 	// Delete from disk
 	if h.disk != nil {
-		if err := h.disk.Delete(filter); err != nil {
+		if err := h.disk.Delete(ns, filter); err != nil {
 			h.logger.WithError(err).Error("Failed to delete from disk")
 		}
 	}
@@ -138,6 +171,57 @@ func (h *HybridVectorStore) Delete(filter FilterFunc) error {
 	return nil
 }
 
+// Purge removes a namespace, or a whole tenant, from both tiers
+func (h *HybridVectorStore) Purge(ns Namespace) (int, error) {
+	if err := ns.Validate(); err != nil {
+		return 0, err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	removed := len(h.keys(ns.Covers))
+	if _, err := h.memory.Purge(ns); err != nil {
+		return 0, err
+	}
+	if h.disk != nil {
+		if _, err := h.disk.Purge(ns); err != nil {
+			return 0, err
+		}
+	}
+
+	return removed, nil
+}
+
+// NamespaceStats returns statistics for ns, counting chunks held in both
+// tiers once
+func (h *HybridVectorStore) NamespaceStats(ns Namespace) Stats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	stats := Stats{LastUpdated: time.Now(), Quota: h.config.NamespaceQuota}
+	seen := make(map[string]bool)
+	count := func(chunk Chunk) {
+		if chunk.Namespace != ns || seen[chunk.ID] {
+			return
+		}
+		seen[chunk.ID] = true
+		stats.countSource(chunk.Metadata.Source)
+	}
+
+	for _, chunk := range h.memory.GetAllChunks() {
+		count(chunk)
+	}
+	if h.disk != nil {
+		for _, chunk := range h.disk.Chunks(ns) {
+			count(chunk)
+		}
+	}
+
+	stats.StorageSize = int64(stats.TotalChunks) * 2048
+	return stats
+}
+
 // GetStats returns statistics about the store
 func (h *HybridVectorStore) GetStats() Stats {
 	h.mu.RLock()
@@ -154,6 +238,7 @@ func (h *HybridVectorStore) GetStats() Stats {
 		stats.ChatChunks += diskStats.ChatChunks
 		stats.StorageSize += diskStats.StorageSize
 	}
+	stats.Quota = h.config.NamespaceQuota
 
 	return stats
 }
@@ -191,14 +276,14 @@ func (h *HybridVectorStore) evictToDisk() {
 	chunks := h.memory.GetOldestChunks(evictCount)
 
 	// Add to disk
-	if err := h.disk.Add(chunks); err != nil {
+	if err := addByNamespace(h.disk, chunks); err != nil {
 		h.logger.WithError(err).Error("Failed to evict chunks to disk")
 		return
 	}
 
 	// Remove from memory
 	for _, chunk := range chunks {
-		h.memory.DeleteByID(chunk.ID)
+		h.memory.DeleteByID(chunk.Namespace, chunk.ID)
 	}
 }
 
@@ -210,7 +295,7 @@ func (h *HybridVectorStore) loadFromDisk() {
 
 	// Load most recent chunks into memory
 	chunks := h.disk.GetRecentChunks(h.config.MaxMemoryChunks / 2)
-	if err := h.memory.Add(chunks); err != nil {
+	if err := addByNamespace(h.memory, chunks); err != nil {
 		h.logger.WithError(err).Error("Failed to load chunks from disk")
 	}
 }
@@ -222,7 +307,7 @@ func (h *HybridVectorStore) saveToDisk() {
 	}
 
 	chunks := h.memory.GetAllChunks()
-	if err := h.disk.Add(chunks); err != nil {
+	if err := addByNamespace(h.disk, chunks); err != nil {
 		h.logger.WithError(err).Error("Failed to save chunks to disk")
 		return
 	}
@@ -231,18 +316,68 @@ func (h *HybridVectorStore) saveToDisk() {
 	h.lastSave = time.Now()
 }
 
+// keys returns the store keys in either tier for namespaces accepted by
+// match. Callers hold the lock.
+func (h *HybridVectorStore) keys(match func(Namespace) bool) map[string]bool {
+	keys := h.memory.keys(match)
+	if h.disk != nil {
+		for key := range h.disk.keys(match) {
+			keys[key] = true
+		}
+	}
+	return keys
+}
+
+// addByNamespace writes chunks that carry their namespace, such as those
+// moving between tiers
+func addByNamespace(store VectorStore, chunks []Chunk) error {
+	groups := make(map[Namespace][]Chunk)
+	for _, chunk := range chunks {
+		groups[chunk.Namespace] = append(groups[chunk.Namespace], chunk)
+	}
+	for ns, group := range groups {
+		if err := store.Add(ns, group); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // autoSaveRoutine periodically saves to disk
 func (h *HybridVectorStore) autoSaveRoutine() {
 	ticker := time.NewTicker(h.config.AutoSaveInterval)
 	defer ticker.Stop()
 
-	for range ticker.C {
+	for now := range ticker.C {
 		h.mu.Lock()
+		h.expire(now)
 		h.saveToDisk()
 		h.mu.Unlock()
 	}
 }
 
+// Expire deletes chunks in either tier that have outlived the TTL policy
+func (h *HybridVectorStore) Expire(now time.Time) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.expire(now)
+}
+
+// expire drops expired chunks from memory first so a save can't write
+// them back to disk. Callers hold the lock.
+func (h *HybridVectorStore) expire(now time.Time) (int, error) {
+	if len(h.config.TTL) == 0 {
+		return 0, nil
+	}
+
+	removed, _ := h.memory.ExpireWith(h.config.TTL, now)
+	if h.disk == nil {
+		return removed, nil
+	}
+	n, err := h.disk.Expire(now)
+	return removed + n, err
+}
+
 // Configuration options
 
 func WithInMemoryCache(maxChunks int) Option {
@@ -2,9 +2,11 @@ package middleware
 
 import (
 	"context"
+	"net"
 	"net/http"
 	"strings"
 
+	"github.com/google/uuid"
 	"github.com/gridmate/backend/internal/auth"
 	"github.com/gridmate/backend/internal/repository"
 	"github.com/sirupsen/logrus"
@@ -16,8 +18,13 @@ const (
 	UserIDKey    contextKey = "user_id"
 	UserEmailKey contextKey = "user_email"
 	UserRoleKey  contextKey = "user_role"
+	// APIKeyKey holds the *models.APIKey of requests authenticated by one
+	APIKeyKey contextKey = "api_key"
 )
 
+// apiPrefix is stripped from request paths before looking up API key scopes
+const apiPrefix = "/api/v1"
+
 type AuthMiddleware struct {
 	jwtManager *auth.JWTManager
 	apiKeyRepo repository.APIKeyRepository
@@ -32,9 +39,15 @@ func NewAuthMiddleware(jwtManager *auth.JWTManager, apiKeyRepo repository.APIKey
 	}
 }
 
-// Authenticate validates JWT tokens and adds user info to context
+// Authenticate validates JWT tokens or API keys sent in X-API-Key and adds
+// user info to context
 func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if apiKey := r.Header.Get("X-API-Key"); apiKey != "" {
+			m.authenticateAPIKey(w, r, next, apiKey)
+			return
+		}
+
 		token := extractToken(r)
 		if token == "" {
 			respondUnauthorized(w, "Missing authentication token")
@@ -98,38 +111,108 @@ func (m *AuthMiddleware) OptionalAuth(next http.Handler) http.Handler {
 	})
 }
 
-// APIKeyAuth validates API key authentication
+// APIKeyAuth validates API key authentication. Authenticate accepts API
+// keys itself; this is kept for routes that name the scheme explicitly.
 func (m *AuthMiddleware) APIKeyAuth(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		apiKey := r.Header.Get("X-API-Key")
-		if apiKey == "" {
-			// Fall back to Bearer token auth
-			m.Authenticate(next).ServeHTTP(w, r)
+	return m.Authenticate(next)
+}
+
+// authenticateAPIKey checks the key's expiry, address allowlist, scopes and
+// workspace binding before passing the request on as the key's owner
+func (m *AuthMiddleware) authenticateAPIKey(w http.ResponseWriter, r *http.Request, next http.Handler, plainKey string) {
+	apiKey, err := m.apiKeyRepo.ValidateAPIKey(r.Context(), plainKey)
+	if err != nil {
+		m.logger.WithError(err).Debug("Invalid API key")
+		respondUnauthorized(w, "Invalid API key")
+		return
+	}
+
+	logger := m.logger.WithField("api_key_id", apiKey.ID)
+	if !addressAllowed(apiKey.AllowedIPs, clientAddress(r)) {
+		logger.WithField("ip", clientAddress(r)).Warn("API key used from an address outside its allowlist")
+		respondForbidden(w, "API key may not be used from this address")
+		return
+	}
+
+	scope, ok := auth.RequiredScope(r.Method, strings.TrimPrefix(r.URL.Path, apiPrefix))
+	if !ok {
+		respondForbidden(w, "API keys cannot access this endpoint")
+		return
+	}
+	if !auth.HasScope(apiKey.Scopes, scope) {
+		respondForbidden(w, "API key lacks the "+scope+" scope")
+		return
+	}
+
+	// Services check workspaces taken from the body or a stored record
+	// against the binding placed on the context below
+	if apiKey.WorkspaceID != nil {
+		if ws := r.URL.Query().Get("workspace_id"); ws != "" && ws != apiKey.WorkspaceID.String() {
+			respondForbidden(w, "API key is bound to another workspace")
 			return
 		}
+	}
 
-		// Validate the API key
-		apiKeyInfo, err := m.apiKeyRepo.ValidateAPIKey(r.Context(), apiKey)
-		if err != nil {
-			m.logger.WithError(err).Debug("Invalid API key")
-			respondUnauthorized(w, "Invalid API key")
-			return
+	// Update last used timestamp
+	go func() {
+		if err := m.apiKeyRepo.UpdateLastUsed(context.Background(), apiKey.ID); err != nil {
+			logger.WithError(err).Error("Failed to update API key last used")
 		}
+	}()
+
+	// Add user info to context
+	ctx := context.WithValue(r.Context(), UserIDKey, apiKey.UserID.String())
+	ctx = context.WithValue(ctx, UserEmailKey, "") // API keys don't have email
+	ctx = context.WithValue(ctx, UserRoleKey, "api")
+	ctx = context.WithValue(ctx, APIKeyKey, apiKey)
+	if apiKey.WorkspaceID != nil {
+		ctx = auth.WithBoundWorkspace(ctx, *apiKey.WorkspaceID)
+	}
 
-		// Update last used timestamp
-		go func() {
-			if err := m.apiKeyRepo.UpdateLastUsed(context.Background(), apiKeyInfo.ID); err != nil {
-				m.logger.WithError(err).Error("Failed to update API key last used")
-			}
-		}()
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
 
-		// Add user info to context
-		ctx := context.WithValue(r.Context(), UserIDKey, apiKeyInfo.UserID)
-		ctx = context.WithValue(ctx, UserEmailKey, "") // API keys don't have email
-		ctx = context.WithValue(ctx, UserRoleKey, "api")
+// clientAddress returns the address the request came from. Forwarded
+// headers can be set by anyone, so X-Forwarded-For is only trusted from a
+// proxy on a loopback or private address, and only the entry that proxy
+// appended.
+func clientAddress(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	peer := net.ParseIP(host)
+	if peer == nil || !(peer.IsLoopback() || peer.IsPrivate()) {
+		return peer
+	}
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		hops := strings.Split(xff, ",")
+		if forwarded := net.ParseIP(strings.TrimSpace(hops[len(hops)-1])); forwarded != nil {
+			return forwarded
+		}
+	}
+	return peer
+}
 
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+// addressAllowed reports whether ip matches one of the addresses or CIDR
+// ranges in allowed; an empty allowlist allows every address
+func addressAllowed(allowed []string, ip net.IP) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	if ip == nil {
+		return false
+	}
+	for _, entry := range allowed {
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+		} else if net.ParseIP(entry).Equal(ip) {
+			return true
+		}
+	}
+	return false
 }
 
 // Helper functions
@@ -190,4 +273,10 @@ func GetUserEmail(ctx context.Context) (string, bool) {
 func GetUserRole(ctx context.Context) (string, bool) {
 	role, ok := ctx.Value(UserRoleKey).(string)
 	return role, ok
+}
+
+// APIKeyWorkspace returns the workspace the request's API key is bound to.
+// ok is false for requests not made with a workspace-bound key.
+func APIKeyWorkspace(ctx context.Context) (workspaceID uuid.UUID, ok bool) {
+	return auth.BoundWorkspace(ctx)
 }
\ No newline at end of file
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+
+	"github.com/gridmate/backend/internal/models"
+	"github.com/gridmate/backend/internal/repository"
+)
+
+type testAPIKeys struct {
+	repository.APIKeyRepository
+	keys map[string]*models.APIKey
+}
+
+func (r *testAPIKeys) ValidateAPIKey(ctx context.Context, plainKey string) (*models.APIKey, error) {
+	if key, ok := r.keys[plainKey]; ok {
+		return key, nil
+	}
+	return nil, errors.New("API key not found")
+}
+
+func (r *testAPIKeys) UpdateLastUsed(ctx context.Context, id uuid.UUID) error {
+	return nil
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	workspaceID := uuid.New()
+	userID := uuid.New()
+	keys := &testAPIKeys{keys: map[string]*models.APIKey{
+		"sk_reader": {ID: uuid.New(), UserID: userID, Scopes: pq.StringArray{"chat:read", "audit:write"}},
+		"sk_bound": {
+			ID: uuid.New(), UserID: userID, Scopes: pq.StringArray{"audit:read"},
+			WorkspaceID: &workspaceID, AllowedIPs: pq.StringArray{"203.0.113.0/24", "198.51.100.7"},
+		},
+	}}
+
+	var gotUser string
+	handler := NewAuthMiddleware(nil, keys, logger).Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUser, _ = GetUserID(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+	request := func(method, target, apiKey, remoteAddr, forwardedFor string) int {
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set("X-API-Key", apiKey)
+		req.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	tests := []struct {
+		name, method, target, key, remoteAddr, forwardedFor string
+		want                                                int
+	}{
+		{"chat needs write scope", "POST", "/api/v1/ai/chat", "sk_reader", "192.0.2.1:1", "", http.StatusForbidden},
+		{"suggestions with read scope", "POST", "/api/v1/ai/suggest", "sk_reader", "192.0.2.1:1", "", http.StatusOK},
+		{"reviews need their own scope", "POST", "/api/v1/ai/reviews", "sk_reader", "192.0.2.1:1", "", http.StatusForbidden},
+		{"write scope includes read", "GET", "/api/v1/audit/logs", "sk_reader", "192.0.2.1:1", "", http.StatusOK},
+		{"missing scope", "DELETE", "/api/v1/documents/1", "sk_reader", "192.0.2.1:1", "", http.StatusForbidden},
+		{"account endpoints", "POST", "/api/v1/auth/api-keys", "sk_reader", "192.0.2.1:1", "", http.StatusForbidden},
+		{"unknown key", "GET", "/api/v1/audit/logs", "sk_unknown", "192.0.2.1:1", "", http.StatusUnauthorized},
+		{"allowed range", "GET", "/api/v1/audit/logs", "sk_bound", "203.0.113.9:1", "", http.StatusOK},
+		{"allowed address behind proxy", "GET", "/api/v1/audit/logs", "sk_bound", "10.0.0.2:1", "1.2.3.4, 198.51.100.7", http.StatusOK},
+		{"forwarded address from the internet", "GET", "/api/v1/audit/logs", "sk_bound", "192.0.2.1:1", "198.51.100.7", http.StatusForbidden},
+		{"spoofed first hop", "GET", "/api/v1/audit/logs", "sk_bound", "10.0.0.2:1", "198.51.100.7, 1.2.3.4", http.StatusForbidden},
+		{"bound workspace", "GET", "/api/v1/audit/logs?workspace_id=" + workspaceID.String(), "sk_bound", "203.0.113.9:1", "", http.StatusOK},
+		{"other workspace", "GET", "/api/v1/audit/logs?workspace_id=" + uuid.NewString(), "sk_bound", "203.0.113.9:1", "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		gotUser = ""
+		if got := request(tt.method, tt.target, tt.key, tt.remoteAddr, tt.forwardedFor); got != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, got, tt.want)
+		}
+		if tt.want == http.StatusOK && gotUser != userID.String() {
+			t.Errorf("%s: user = %q, want the key's owner", tt.name, gotUser)
+		}
+	}
+}
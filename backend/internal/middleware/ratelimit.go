@@ -0,0 +1,204 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/gridmate/backend/internal/auth"
+	"github.com/gridmate/backend/internal/repository"
+	"github.com/gridmate/backend/internal/services/ratelimit"
+)
+
+// rateLimitedPaths maps the limited endpoints to their budget
+var rateLimitedPaths = map[string]ratelimit.Category{
+	"/api/chat":                 ratelimit.CategoryChat,
+	"/api/chat/streaming":       ratelimit.CategoryChat,
+	"/api/chat/stream":          ratelimit.CategoryChat,
+	"/api/v1/ai/chat":           ratelimit.CategoryChat,
+	"/api/tool-response":        ratelimit.CategoryToolResponse,
+	"/api/v1/documents/edgar":   ratelimit.CategoryUpload,
+	"/api/v1/auth/login":        ratelimit.CategoryAuth,
+	"/api/v1/auth/register":     ratelimit.CategoryAuth,
+	"/api/v1/auth/refresh":      ratelimit.CategoryAuth,
+	"/api/v1/auth/sso/login":    ratelimit.CategoryAuth,
+	"/api/v1/auth/sso/callback": ratelimit.CategoryAuth,
+}
+
+// hubRelayedPaths are the limited endpoints the SignalR hub calls on behalf
+// of add-in sessions. The hub forwards no user credentials, only the
+// session ID.
+var hubRelayedPaths = map[string]bool{
+	"/api/chat":           true,
+	"/api/chat/streaming": true,
+	"/api/chat/stream":    true,
+	"/api/tool-response":  true,
+}
+
+// SessionOwners reports the user an Excel session belongs to
+type SessionOwners interface {
+	SessionOwner(sessionID string) string
+}
+
+// RateLimitMiddleware rejects requests over their caller's budget with 429
+type RateLimitMiddleware struct {
+	limiter    *ratelimit.Limiter
+	jwtManager *auth.JWTManager
+	apiKeyRepo repository.APIKeyRepository
+	sessions   SessionOwners
+	logger     *logrus.Logger
+}
+
+func NewRateLimitMiddleware(limiter *ratelimit.Limiter, jwtManager *auth.JWTManager, apiKeyRepo repository.APIKeyRepository, logger *logrus.Logger) *RateLimitMiddleware {
+	return &RateLimitMiddleware{
+		limiter:    limiter,
+		jwtManager: jwtManager,
+		apiKeyRepo: apiKeyRepo,
+		logger:     logger,
+	}
+}
+
+// SetSessionOwners sets how requests relayed by the SignalR hub are traced
+// back to the session they were made for
+func (m *RateLimitMiddleware) SetSessionOwners(sessions SessionOwners) {
+	m.sessions = sessions
+}
+
+// Middleware counts requests to the limited endpoints against the API key,
+// user or IP making them. Auth endpoints are always counted per IP.
+func (m *RateLimitMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		category, ok := rateLimitedPaths[r.URL.Path]
+		if !ok || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		identity := m.identify(r, category)
+		result, err := m.limiter.Allow(r.Context(), category, identity)
+		if err != nil {
+			// Fail open: an unavailable store must not take the API down
+			m.logger.WithError(err).WithField("category", category).Error("Failed to check rate limit")
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if result.Limit > 0 {
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+		}
+		if !result.Allowed {
+			m.logger.WithFields(logrus.Fields{
+				"category": category,
+				"identity": identity.Key,
+				"path":     r.URL.Path,
+			}).Warn("Rate limit exceeded")
+
+			w.Header().Set("Retry-After", strconv.Itoa(max(1, ceilSeconds(result.RetryAfter))))
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error":"Rate limit exceeded"}`))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// identify returns who a request is counted against: its API key if the key
+// is valid, else its authenticated user, else the session the hub relayed
+// it for, else its IP. Made-up keys fall back to the IP so they cannot each
+// claim a fresh budget, and the IP ignores forwarding headers that did not
+// come from our own proxies.
+func (m *RateLimitMiddleware) identify(r *http.Request, category ratelimit.Category) ratelimit.Identity {
+	addr := clientAddress(r)
+	ip := ratelimit.Identity{Key: "ip:" + r.RemoteAddr}
+	if addr != nil {
+		ip.Key = "ip:" + addr.String()
+	}
+	if category == ratelimit.CategoryAuth {
+		return ip
+	}
+
+	if apiKey := r.Header.Get("X-API-Key"); apiKey != "" && m.apiKeyRepo != nil {
+		if key, err := m.apiKeyRepo.ValidateAPIKey(r.Context(), apiKey); err == nil {
+			return ratelimit.Identity{Key: "apikey:" + key.ID.String(), UserID: key.UserID}
+		}
+	}
+
+	if token := extractToken(r); token != "" && m.jwtManager != nil {
+		if claims, err := m.jwtManager.ValidateAccessToken(token); err == nil {
+			identity := ratelimit.Identity{Key: "user:" + claims.UserID}
+			identity.UserID, _ = uuid.Parse(claims.UserID)
+			return identity
+		}
+	}
+
+	if identity, ok := m.identifySession(r, addr); ok {
+		return identity
+	}
+
+	return ip
+}
+
+// identifySession counts a request the hub relayed against the user owning
+// its session, or the session itself while it has no user. Only requests
+// that reach us directly from our own network are trusted to be the hub's,
+// so outside callers cannot claim a fresh budget with each session ID.
+func (m *RateLimitMiddleware) identifySession(r *http.Request, addr net.IP) (ratelimit.Identity, bool) {
+	if m.sessions == nil || !hubRelayedPaths[r.URL.Path] || r.Header.Get("X-Forwarded-For") != "" {
+		return ratelimit.Identity{}, false
+	}
+	if addr == nil || !(addr.IsLoopback() || addr.IsPrivate()) {
+		return ratelimit.Identity{}, false
+	}
+
+	sessionID := relayedSessionID(r)
+	if sessionID == "" {
+		return ratelimit.Identity{}, false
+	}
+	owner := m.sessions.SessionOwner(sessionID)
+	if userID, err := uuid.Parse(owner); err == nil {
+		return ratelimit.Identity{Key: "user:" + owner, UserID: userID}, true
+	}
+	return ratelimit.Identity{Key: "session:" + sessionID}, true
+}
+
+// relayedSessionID reads the session ID from the query or the JSON body,
+// leaving the body for the handler to read again
+func relayedSessionID(r *http.Request) string {
+	if sessionID := r.URL.Query().Get("sessionId"); sessionID != "" {
+		return sessionID
+	}
+	if r.Body == nil {
+		return ""
+	}
+
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+
+	var req struct {
+		SessionID string `json:"sessionId"`
+	}
+	if json.Unmarshal(body, &req) != nil {
+		return ""
+	}
+	return req.SessionID
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
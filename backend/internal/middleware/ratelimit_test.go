@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/gridmate/backend/internal/models"
+	"github.com/gridmate/backend/internal/services/ratelimit"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	limiter := ratelimit.NewLimiter(logger, ratelimit.NewMemoryStore(), ratelimit.Budgets{
+		ratelimit.CategoryChat: {Requests: 1, Per: time.Minute},
+	})
+	keys := &testAPIKeys{keys: map[string]*models.APIKey{"sk_valid": {ID: uuid.New(), UserID: uuid.New()}}}
+	handler := NewRateLimitMiddleware(limiter, nil, keys, logger).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	request := func(path, apiKey string, forwardedFor ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		for _, hop := range forwardedFor {
+			req.Header.Add("X-Forwarded-For", hop)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := request("/api/chat/streaming", ""); rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Fatalf("first request = %d remaining %q, want 200 with none remaining", rec.Code, rec.Header().Get("X-RateLimit-Remaining"))
+	}
+	rec := request("/api/chat/streaming", "")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "60" {
+		t.Errorf("second request = %d, Retry-After %q; want 429 after 60", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	// Neither made-up keys nor forwarding headers from outside our proxies
+	// escape the caller's IP budget
+	if rec := request("/api/chat/streaming", "sk_made_up"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("unknown API key request = %d, want 429", rec.Code)
+	}
+	if rec := request("/api/chat/streaming", "", "203.0.113.9"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("spoofed forwarded request = %d, want 429", rec.Code)
+	}
+
+	// Valid API keys and unlimited endpoints have budgets of their own
+	if rec := request("/api/chat/streaming", "sk_valid"); rec.Code != http.StatusOK {
+		t.Errorf("API key request = %d, want 200", rec.Code)
+	}
+	if rec := request("/api/selection-update", ""); rec.Code != http.StatusOK {
+		t.Errorf("unlimited endpoint = %d, want 200", rec.Code)
+	}
+}
+
+type testSessionOwners map[string]string
+
+func (s testSessionOwners) SessionOwner(sessionID string) string {
+	return s[sessionID]
+}
+
+func TestRateLimitMiddlewareHubSessions(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	limiter := ratelimit.NewLimiter(logger, ratelimit.NewMemoryStore(), ratelimit.Budgets{
+		ratelimit.CategoryChat: {Requests: 1, Per: time.Minute},
+	})
+	middleware := NewRateLimitMiddleware(limiter, nil, nil, logger)
+	middleware.SetSessionOwners(testSessionOwners{"session_a": "signalr-user", "session_b": "signalr-user"})
+	var bodies []string
+	handler := middleware.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	chat := func(remoteAddr, sessionID string) int {
+		body := `{"sessionId":"` + sessionID + `","content":"hi"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/chat", strings.NewReader(body))
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Two add-in sessions relayed by the hub from one address have their
+	// own budgets
+	if code := chat("127.0.0.1:5252", "session_a"); code != http.StatusOK {
+		t.Fatalf("session a = %d, want 200", code)
+	}
+	if code := chat("127.0.0.1:5252", "session_b"); code != http.StatusOK {
+		t.Errorf("session b = %d, want 200", code)
+	}
+	if code := chat("127.0.0.1:5252", "session_a"); code != http.StatusTooManyRequests {
+		t.Errorf("second session a request = %d, want 429", code)
+	}
+	if len(bodies) != 2 || !strings.Contains(bodies[0], "session_a") {
+		t.Errorf("handler bodies = %q, want the relayed bodies intact", bodies)
+	}
+
+	// Outside callers cannot claim a budget per session ID
+	if code := chat("198.51.100.7:1234", "session_c"); code != http.StatusOK {
+		t.Errorf("outside caller = %d, want 200", code)
+	}
+	if code := chat("198.51.100.7:1234", "session_d"); code != http.StatusTooManyRequests {
+		t.Errorf("outside caller with another session = %d, want 429", code)
+	}
+}
@@ -1,7 +1,11 @@
 package models
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"net"
 	"time"
 
@@ -9,15 +13,90 @@ import (
 )
 
 type AuditLog struct {
-	ID         uuid.UUID       `json:"id" db:"id"`
-	UserID     *uuid.UUID      `json:"user_id" db:"user_id"`
-	Action     string          `json:"action" db:"action"`
-	EntityType *string         `json:"entity_type" db:"entity_type"`
-	EntityID   *uuid.UUID      `json:"entity_id" db:"entity_id"`
-	Changes    json.RawMessage `json:"changes" db:"changes"`
-	IPAddress  *net.IP         `json:"ip_address" db:"ip_address"`
-	UserAgent  *string         `json:"user_agent" db:"user_agent"`
-	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
+	ID          uuid.UUID       `json:"id" db:"id"`
+	WorkspaceID *uuid.UUID      `json:"workspace_id,omitempty" db:"workspace_id"`
+	Sequence    int64           `json:"sequence" db:"sequence"`
+	PrevHash    string          `json:"prev_hash" db:"prev_hash"`
+	Hash        string          `json:"hash" db:"hash"`
+	UserID      *uuid.UUID      `json:"user_id" db:"user_id"`
+	Action      string          `json:"action" db:"action"`
+	EntityType  *string         `json:"entity_type" db:"entity_type"`
+	EntityID    *uuid.UUID      `json:"entity_id" db:"entity_id"`
+	Changes     json.RawMessage `json:"changes" db:"changes"`
+	IPAddress   *net.IP         `json:"ip_address" db:"ip_address"`
+	UserAgent   *string         `json:"user_agent" db:"user_agent"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+}
+
+// auditHashInput is the content of an entry covered by its hash, in a fixed
+// field order
+type auditHashInput struct {
+	PrevHash    string          `json:"prev_hash"`
+	Sequence    int64           `json:"sequence"`
+	WorkspaceID *uuid.UUID      `json:"workspace_id"`
+	UserID      *uuid.UUID      `json:"user_id"`
+	Action      string          `json:"action"`
+	EntityType  *string         `json:"entity_type"`
+	EntityID    *uuid.UUID      `json:"entity_id"`
+	Changes     json.RawMessage `json:"changes"`
+	IPAddress   string          `json:"ip_address"`
+	UserAgent   *string         `json:"user_agent"`
+	CreatedAt   string          `json:"created_at"`
+}
+
+// ComputeHash returns the entry's chain hash: an HMAC-SHA256 over its content
+// and the previous entry's hash when key is set, a plain SHA-256 otherwise.
+func (l *AuditLog) ComputeHash(key []byte) (string, error) {
+	changes, err := CanonicalChanges(l.Changes)
+	if err != nil {
+		return "", err
+	}
+	input := auditHashInput{
+		PrevHash:    l.PrevHash,
+		Sequence:    l.Sequence,
+		WorkspaceID: l.WorkspaceID,
+		UserID:      l.UserID,
+		Action:      l.Action,
+		EntityType:  l.EntityType,
+		EntityID:    l.EntityID,
+		Changes:     changes,
+		UserAgent:   l.UserAgent,
+		CreatedAt:   l.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}
+	if len(input.Changes) == 0 {
+		input.Changes = json.RawMessage("null")
+	}
+	if l.IPAddress != nil {
+		input.IPAddress = l.IPAddress.String()
+	}
+
+	data, err := json.Marshal(input)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode audit log entry: %w", err)
+	}
+	if len(key) == 0 {
+		sum := sha256.Sum256(data)
+		return hex.EncodeToString(sum[:]), nil
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// CanonicalChanges re-encodes changes with sorted keys and normalized numbers
+// so hashes agree however JSONB storage reformats them
+func CanonicalChanges(changes json.RawMessage) (json.RawMessage, error) {
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(changes, &v); err != nil {
+		return nil, fmt.Errorf("invalid audit log changes: %w", err)
+	}
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
 }
 
 type CreateAuditLogRequest struct {
@@ -31,12 +110,13 @@ type CreateAuditLogRequest struct {
 }
 
 type AuditLogFilter struct {
-	UserID     *uuid.UUID `json:"user_id"`
-	EntityType *string    `json:"entity_type"`
-	EntityID   *uuid.UUID `json:"entity_id"`
-	Action     *string    `json:"action"`
-	StartDate  *time.Time `json:"start_date"`
-	EndDate    *time.Time `json:"end_date"`
-	Limit      int        `json:"limit"`
-	Offset     int        `json:"offset"`
-}
\ No newline at end of file
+	UserID      *uuid.UUID `json:"user_id"`
+	WorkspaceID *uuid.UUID `json:"workspace_id"`
+	EntityType  *string    `json:"entity_type"`
+	EntityID    *uuid.UUID `json:"entity_id"`
+	Action      *string    `json:"action"`
+	StartDate   *time.Time `json:"start_date"`
+	EndDate     *time.Time `json:"end_date"`
+	Limit       int        `json:"limit"`
+	Offset      int        `json:"offset"`
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type APIKey struct {
@@ -22,6 +23,16 @@ type APIKey struct {
 	IsActive   bool            `json:"is_active" db:"is_active"`
 	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
 	UpdatedAt  time.Time       `json:"updated_at" db:"updated_at"`
+	// Scopes lists what the key may do, e.g. chat:read or audit:read
+	Scopes pq.StringArray `json:"scopes" db:"scopes"`
+	// WorkspaceID binds the key to one workspace when set
+	WorkspaceID *uuid.UUID `json:"workspace_id,omitempty" db:"workspace_id"`
+	// AllowedIPs lists the addresses and CIDR ranges the key may be used
+	// from; any address is allowed when empty
+	AllowedIPs pq.StringArray `json:"allowed_ips" db:"allowed_ips"`
+	UsageCount int64          `json:"usage_count" db:"usage_count"`
+	// ReplacedBy is the key issued when this one was rotated
+	ReplacedBy *uuid.UUID `json:"replaced_by,omitempty" db:"replaced_by"`
 }
 
 type Session struct {
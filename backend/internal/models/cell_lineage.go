@@ -0,0 +1,43 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Cell lineage statuses
+const (
+	LineagePending  = "pending"
+	LineageApplied  = "applied"
+	LineageFailed   = "failed"
+	LineageRejected = "rejected"
+)
+
+// CellLineage records the origin of one AI write to a range of a user's
+// workbook: the chat message, tool call, model, inputs, sources and
+// approval behind it. Row and column bounds are zero-based and inclusive.
+type CellLineage struct {
+	ID          uuid.UUID       `json:"id" db:"id"`
+	UserID      uuid.UUID       `json:"user_id" db:"user_id"`
+	Workbook    string          `json:"workbook" db:"workbook"`
+	Sheet       string          `json:"sheet" db:"sheet"`
+	StartRow    int             `json:"start_row" db:"start_row"`
+	StartCol    int             `json:"start_col" db:"start_col"`
+	EndRow      int             `json:"end_row" db:"end_row"`
+	EndCol      int             `json:"end_col" db:"end_col"`
+	Address     string          `json:"address" db:"address"`
+	SessionID   string          `json:"session_id" db:"session_id"`
+	MessageID   *string         `json:"message_id" db:"message_id"`
+	ToolID      string          `json:"tool_id" db:"tool_id"`
+	OperationID string          `json:"operation_id" db:"operation_id"`
+	ToolName    string          `json:"tool_name" db:"tool_name"`
+	Model       *string         `json:"model" db:"model"`
+	Input       json.RawMessage `json:"input" db:"input"`
+	Sources     json.RawMessage `json:"sources" db:"sources"`
+	Status      string          `json:"status" db:"status"`
+	Approval    json.RawMessage `json:"approval" db:"approval"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+	AppliedAt   *time.Time      `json:"applied_at" db:"applied_at"`
+}
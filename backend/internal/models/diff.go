@@ -1,6 +1,10 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
 
 // CellKey uniquely identifies a cell within a workbook.
 type CellKey struct {
@@ -9,6 +13,15 @@ type CellKey struct {
 	Col   int    `json:"col"`
 }
 
+// Address formats the key in A1 notation, e.g. "Sheet1!B5"
+func (k CellKey) Address() string {
+	col := ""
+	for c := k.Col + 1; c > 0; c = (c - 1) / 26 {
+		col = string(rune('A'+(c-1)%26)) + col
+	}
+	return fmt.Sprintf("%s!%s%d", k.Sheet, col, k.Row+1)
+}
+
 // CellSnapshot holds the state of a single cell at a point in time.
 type CellSnapshot struct {
 	Value   *string `json:"v,omitempty"`
@@ -42,6 +55,7 @@ type DiffHunk struct {
 // DiffPayload is the structure received from the client for comparison.
 type DiffPayload struct {
 	WorkbookID uuid.UUID        `json:"workbookId"`
+	SessionID  string           `json:"sessionId,omitempty"` // Enables incremental memory re-indexing
 	Before     WorkbookSnapshot `json:"before"`
 	After      WorkbookSnapshot `json:"after"`
 }
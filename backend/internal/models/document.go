@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,7 +13,7 @@ type Document struct {
 	ID          uuid.UUID              `json:"id" db:"id"`
 	UserID      uuid.UUID              `json:"user_id" db:"user_id"`
 	Title       string                 `json:"title" db:"title"`
-	Type        string                 `json:"type" db:"type"` // 10-K, 10-Q, 8-K, etc
+	Type        string                 `json:"type" db:"type"`     // 10-K, 10-Q, 8-K, etc
 	Source      string                 `json:"source" db:"source"` // SEC EDGAR, Manual Upload, etc
 	URL         string                 `json:"url,omitempty" db:"url"`
 	CompanyName string                 `json:"company_name,omitempty" db:"company_name"`
@@ -34,4 +35,38 @@ type Embedding struct {
 	Metadata   map[string]interface{} `json:"metadata,omitempty" db:"metadata"`
 	Similarity float64                `json:"similarity,omitempty" db:"-"` // Used in search results
 	CreatedAt  time.Time              `json:"created_at" db:"created_at"`
-}
\ No newline at end of file
+}
+
+// FinancialFact is an XBRL fact extracted from a stored filing
+type FinancialFact struct {
+	ID          uuid.UUID       `json:"id" db:"id"`
+	DocumentID  uuid.UUID       `json:"document_id" db:"document_id"`
+	Concept     string          `json:"concept" db:"concept"`
+	LocalName   string          `json:"local_name" db:"local_name"`
+	ContextRef  string          `json:"context_ref" db:"context_ref"`
+	PeriodType  string          `json:"period_type" db:"period_type"`
+	PeriodStart *time.Time      `json:"period_start,omitempty" db:"period_start"`
+	PeriodEnd   *time.Time      `json:"period_end,omitempty" db:"period_end"`
+	Dimensions  json.RawMessage `json:"dimensions,omitempty" db:"dimensions"`
+	Unit        *string         `json:"unit,omitempty" db:"unit"`
+	Decimals    *string         `json:"decimals,omitempty" db:"decimals"`
+	Scale       int             `json:"scale" db:"scale"`
+	Value       *float64        `json:"value,omitempty" db:"value"`
+	TextValue   *string         `json:"text_value,omitempty" db:"text_value"`
+	Page        *int            `json:"page,omitempty" db:"page"` // Page of inline XBRL facts in the filing
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+}
+
+// FinancialFactFilter narrows a fact search. Concept matches either the
+// prefixed name (us-gaap:Revenues) or the local name (Revenues).
+type FinancialFactFilter struct {
+	UserID             uuid.UUID  `json:"-"`
+	DocumentID         *uuid.UUID `json:"document_id"`
+	Concept            *string    `json:"concept"`
+	PeriodType         *string    `json:"period_type"`
+	PeriodEndFrom      *time.Time `json:"period_end_from"`
+	PeriodEndTo        *time.Time `json:"period_end_to"`
+	IncludeDimensional bool       `json:"include_dimensional"`
+	Limit              int        `json:"limit"`
+	Offset             int        `json:"offset"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ProtectedRange marks cells of a workspace's workbook that the AI must
+// never modify. Kind is "range", "named_range" or "sheet" and decides how
+// Target is read.
+type ProtectedRange struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	WorkspaceID uuid.UUID  `json:"workspace_id" db:"workspace_id"`
+	Workbook    string     `json:"workbook" db:"workbook"`
+	Kind        string     `json:"kind" db:"kind"`
+	Target      string     `json:"target" db:"target"`
+	Reason      *string    `json:"reason" db:"reason"`
+	CreatedBy   *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
+
+type CreateProtectedRangeRequest struct {
+	WorkspaceID uuid.UUID `json:"workspace_id" validate:"required"`
+	Kind        string    `json:"kind" validate:"required,oneof=range named_range sheet"`
+	Target      string    `json:"target" validate:"required,max=255"`
+	Reason      *string   `json:"reason"`
+}
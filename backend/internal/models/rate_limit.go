@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RateLimit is the saved state of one token bucket: Requests tokens of the
+// bucket were spent at WindowStart, and it is full again at WindowEnd
+type RateLimit struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	Identifier  string    `json:"identifier" db:"identifier"`
+	Endpoint    string    `json:"endpoint" db:"endpoint"`
+	Requests    int       `json:"requests" db:"requests"`
+	WindowStart time.Time `json:"window_start" db:"window_start"`
+	WindowEnd   time.Time `json:"window_end" db:"window_end"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Operation review statuses
+const (
+	ReviewPending   = "pending"
+	ReviewCompleted = "completed"
+)
+
+// OperationReview is a batch of queued AI operations awaiting four-eyes
+// approval. Decisions maps each operation ID to its reviewers' decisions,
+// so the trail outlives the in-memory operation queue.
+type OperationReview struct {
+	ID                uuid.UUID       `json:"id" db:"id"`
+	SessionID         string          `json:"session_id" db:"session_id"`
+	WorkspaceID       uuid.UUID       `json:"workspace_id" db:"workspace_id"`
+	SubmittedBy       uuid.UUID       `json:"submitted_by" db:"submitted_by"`
+	Reviewers         pq.StringArray  `json:"reviewers" db:"reviewers"`
+	OperationIDs      pq.StringArray  `json:"operation_ids" db:"operation_ids"`
+	RequiredApprovals int             `json:"required_approvals" db:"required_approvals"`
+	Comment           string          `json:"comment,omitempty" db:"comment"`
+	Status            string          `json:"status" db:"status"`
+	Decisions         json.RawMessage `json:"decisions" db:"decisions"`
+	CreatedAt         time.Time       `json:"created_at" db:"created_at"`
+	CompletedAt       *time.Time      `json:"completed_at,omitempty" db:"completed_at"`
+}
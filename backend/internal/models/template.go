@@ -0,0 +1,84 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Template statuses
+const (
+	TemplateStatusActive     = "active"
+	TemplateStatusDeprecated = "deprecated"
+)
+
+// Template sources
+const (
+	TemplateSourceManual   = "manual"
+	TemplateSourceCaptured = "captured"
+	TemplateSourceCloned   = "cloned"
+	TemplateSourceBuiltin  = "builtin" // Shipped with the server, never stored
+)
+
+// ModelTemplate is a versioned financial model template owned by a workspace
+type ModelTemplate struct {
+	ID           uuid.UUID       `json:"id" db:"id"`
+	WorkspaceID  uuid.UUID       `json:"workspace_id" db:"workspace_id"`
+	TemplateKey  string          `json:"template_key" db:"template_key"`
+	Version      int             `json:"version" db:"version"`
+	Name         string          `json:"name" db:"name"`
+	Category     string          `json:"category" db:"category"`
+	Description  *string         `json:"description" db:"description"`
+	Definition   json.RawMessage `json:"definition" db:"definition"`
+	Status       string          `json:"status" db:"status"`
+	Source       string          `json:"source" db:"source"`
+	CreatedBy    *uuid.UUID      `json:"created_by" db:"created_by"`
+	DeprecatedAt *time.Time      `json:"deprecated_at,omitempty" db:"deprecated_at"`
+	CreatedAt    time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+type CreateModelTemplateRequest struct {
+	WorkspaceID uuid.UUID       `json:"workspace_id" validate:"required"`
+	TemplateKey string          `json:"template_key" validate:"required,max=100"`
+	Name        string          `json:"name" validate:"required,max=255"`
+	Category    string          `json:"category" validate:"required,max=100"`
+	Description *string         `json:"description"`
+	Definition  json.RawMessage `json:"definition" validate:"required"`
+}
+
+// UpdateModelTemplateRequest edits a template version in place. Changes to
+// the definition should go through a new version instead.
+type UpdateModelTemplateRequest struct {
+	Name        *string         `json:"name" validate:"max=255"`
+	Category    *string         `json:"category" validate:"max=100"`
+	Description *string         `json:"description"`
+	Definition  json.RawMessage `json:"definition"`
+}
+
+type CreateTemplateVersionRequest struct {
+	Name        *string         `json:"name" validate:"max=255"`
+	Category    *string         `json:"category" validate:"max=100"`
+	Description *string         `json:"description"`
+	Definition  json.RawMessage `json:"definition" validate:"required"`
+}
+
+type CaptureTemplateRequest struct {
+	WorkspaceID uuid.UUID `json:"workspace_id" validate:"required"`
+	SessionID   string    `json:"session_id" validate:"required"`
+	TemplateKey string    `json:"template_key" validate:"required,max=100"`
+	Name        string    `json:"name" validate:"required,max=255"`
+	Category    string    `json:"category" validate:"required,max=100"`
+	Description *string   `json:"description"`
+}
+
+type ModelTemplateFilter struct {
+	WorkspaceID       uuid.UUID `json:"workspace_id"`
+	Category          *string   `json:"category"`
+	TemplateKey       *string   `json:"template_key"`
+	IncludeDeprecated bool      `json:"include_deprecated"`
+	LatestOnly        bool      `json:"latest_only"`
+	Limit             int       `json:"limit"`
+	Offset            int       `json:"offset"`
+}
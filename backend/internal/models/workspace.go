@@ -40,6 +40,6 @@ type UpdateWorkspaceRequest struct {
 
 type AddWorkspaceMemberRequest struct {
 	UserID      uuid.UUID       `json:"user_id" validate:"required"`
-	Role        string          `json:"role" validate:"required,oneof=owner admin member viewer"`
+	Role        string          `json:"role" validate:"required,oneof=owner admin member reviewer viewer"`
 	Permissions json.RawMessage `json:"permissions"`
 }
\ No newline at end of file
@@ -2,13 +2,18 @@ package repository
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gridmate/backend/internal/database"
 	"github.com/gridmate/backend/internal/models"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type apiKeyRepository struct {
@@ -19,16 +24,13 @@ func NewAPIKeyRepository(db *database.DB) APIKeyRepository {
 	return &apiKeyRepository{db: db}
 }
 
+// apiKeyColumns are the columns apiKeyFields scans, in order
+const apiKeyColumns = `id, user_id, name, key_hash, key_prefix, permissions, rate_limit,
+			   last_used_at, expires_at, is_active, created_at, updated_at,
+			   scopes, workspace_id, allowed_ips, usage_count, replaced_by`
 
-func (r *apiKeyRepository) GetByKeyHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
-	key := &models.APIKey{}
-	query := `
-		SELECT id, user_id, name, key_hash, key_prefix, permissions, rate_limit, 
-			   last_used_at, expires_at, is_active, created_at, updated_at
-		FROM api_keys
-		WHERE key_hash = $1`
-
-	err := r.db.QueryRowContext(ctx, query, keyHash).Scan(
+func apiKeyFields(key *models.APIKey) []interface{} {
+	return []interface{}{
 		&key.ID,
 		&key.UserID,
 		&key.Name,
@@ -41,7 +43,22 @@ func (r *apiKeyRepository) GetByKeyHash(ctx context.Context, keyHash string) (*m
 		&key.IsActive,
 		&key.CreatedAt,
 		&key.UpdatedAt,
-	)
+		&key.Scopes,
+		&key.WorkspaceID,
+		&key.AllowedIPs,
+		&key.UsageCount,
+		&key.ReplacedBy,
+	}
+}
+
+func (r *apiKeyRepository) GetByKeyHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	key := &models.APIKey{}
+	query := `
+		SELECT ` + apiKeyColumns + `
+		FROM api_keys
+		WHERE key_hash = $1`
+
+	err := r.db.QueryRowContext(ctx, query, keyHash).Scan(apiKeyFields(key)...)
 
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("API key not found")
@@ -60,8 +77,7 @@ func (r *apiKeyRepository) GetByKeyHash(ctx context.Context, keyHash string) (*m
 
 func (r *apiKeyRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*models.APIKey, error) {
 	query := `
-		SELECT id, user_id, name, key_hash, key_prefix, permissions, rate_limit, 
-			   last_used_at, expires_at, is_active, created_at, updated_at
+		SELECT ` + apiKeyColumns + `
 		FROM api_keys
 		WHERE user_id = $1
 		ORDER BY created_at DESC`
@@ -75,20 +91,7 @@ func (r *apiKeyRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([
 	var keys []*models.APIKey
 	for rows.Next() {
 		key := &models.APIKey{}
-		err := rows.Scan(
-			&key.ID,
-			&key.UserID,
-			&key.Name,
-			&key.KeyHash,
-			&key.KeyPrefix,
-			&key.Permissions,
-			&key.RateLimit,
-			&key.LastUsedAt,
-			&key.ExpiresAt,
-			&key.IsActive,
-			&key.CreatedAt,
-			&key.UpdatedAt,
-		)
+		err := rows.Scan(apiKeyFields(key)...)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan API key: %w", err)
 		}
@@ -99,7 +102,7 @@ func (r *apiKeyRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([
 }
 
 func (r *apiKeyRepository) UpdateLastUsed(ctx context.Context, id uuid.UUID) error {
-	query := `UPDATE api_keys SET last_used_at = $1 WHERE id = $2`
+	query := `UPDATE api_keys SET last_used_at = $1, usage_count = usage_count + 1 WHERE id = $2`
 	
 	_, err := r.db.ExecContext(ctx, query, time.Now(), id)
 	if err != nil {
@@ -154,25 +157,11 @@ func (r *apiKeyRepository) IsActive(ctx context.Context, keyHash string) (bool,
 func (r *apiKeyRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.APIKey, error) {
 	key := &models.APIKey{}
 	query := `
-		SELECT id, user_id, name, key_hash, key_prefix, permissions, rate_limit, 
-			   last_used_at, expires_at, is_active, created_at, updated_at
-		FROM api_keys 
+		SELECT ` + apiKeyColumns + `
+		FROM api_keys
 		WHERE id = $1`
 
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&key.ID,
-		&key.UserID,
-		&key.Name,
-		&key.KeyHash,
-		&key.KeyPrefix,
-		&key.Permissions,
-		&key.RateLimit,
-		&key.LastUsedAt,
-		&key.ExpiresAt,
-		&key.IsActive,
-		&key.CreatedAt,
-		&key.UpdatedAt,
-	)
+	err := r.db.QueryRowContext(ctx, query, id).Scan(apiKeyFields(key)...)
 
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("API key not found")
@@ -185,32 +174,84 @@ func (r *apiKeyRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.A
 }
 
 func (r *apiKeyRepository) Create(ctx context.Context, apiKey *models.APIKey, plainKey string) error {
-	// Store the key hash
+	return insertAPIKey(ctx, r.db, apiKey, plainKey)
+}
+
+// Rotate stores newKey and expires the key it replaces at graceUntil, or
+// at its own expiry when that is sooner
+func (r *apiKeyRepository) Rotate(ctx context.Context, oldID uuid.UUID, newKey *models.APIKey, plainKey string, graceUntil time.Time) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertAPIKey(ctx, tx, newKey, plainKey); err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE api_keys
+		SET replaced_by = $1, expires_at = LEAST(COALESCE(expires_at, $2), $2)
+		WHERE id = $3 AND replaced_by IS NULL`
+	result, err := tx.ExecContext(ctx, query, newKey.ID, graceUntil, oldID)
+	if err != nil {
+		return fmt.Errorf("failed to expire rotated API key: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return fmt.Errorf("API key not found or already rotated")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit API key rotation: %w", err)
+	}
+	return nil
+}
+
+// insertAPIKey stores apiKey with the hash of plainKey
+func insertAPIKey(ctx context.Context, db sqlx.ExecerContext, apiKey *models.APIKey, plainKey string) error {
 	apiKey.KeyHash = hashAPIKey(plainKey)
 	apiKey.KeyPrefix = plainKey[:8] // Store first 8 chars as prefix
 	apiKey.ID = uuid.New()
-	
+	apiKey.RateLimit = 1000 // Default rate limit
+	apiKey.IsActive = true
+	apiKey.CreatedAt = time.Now()
+	if apiKey.Permissions == nil {
+		apiKey.Permissions = json.RawMessage(`{}`)
+	}
+	if apiKey.Scopes == nil {
+		apiKey.Scopes = pq.StringArray{}
+	}
+	if apiKey.AllowedIPs == nil {
+		apiKey.AllowedIPs = pq.StringArray{}
+	}
+
 	query := `
 		INSERT INTO api_keys (
-			id, user_id, name, key_hash, key_prefix, 
-			permissions, rate_limit, expires_at, is_active
+			id, user_id, name, key_hash, key_prefix, permissions, rate_limit,
+			expires_at, is_active, scopes, workspace_id, allowed_ips
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
 		)`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := db.ExecContext(ctx, query,
 		apiKey.ID,
 		apiKey.UserID,
 		apiKey.Name,
 		apiKey.KeyHash,
 		apiKey.KeyPrefix,
 		apiKey.Permissions,
-		1000, // Default rate limit
+		apiKey.RateLimit,
 		apiKey.ExpiresAt,
-		true, // Active by default
+		apiKey.IsActive,
+		apiKey.Scopes,
+		apiKey.WorkspaceID,
+		apiKey.AllowedIPs,
 	)
-
-	return err
+	if err != nil {
+		return fmt.Errorf("failed to create API key: %w", err)
+	}
+	return nil
 }
 
 func (r *apiKeyRepository) ValidateAPIKey(ctx context.Context, plainKey string) (*models.APIKey, error) {
@@ -234,9 +275,9 @@ func (r *apiKeyRepository) ValidateAPIKey(ctx context.Context, plainKey string)
 	return key, nil
 }
 
-// hashAPIKey creates a hash of the API key
+// hashAPIKey returns the hex SHA-256 digest keys are stored and looked up
+// by. Keys are 256 random bits, so an unsalted fast hash is enough.
 func hashAPIKey(key string) string {
-	// In production, use a proper hashing function
-	// For now, we'll use a simple placeholder
-	return fmt.Sprintf("hashed_%s", key)
-}
\ No newline at end of file
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
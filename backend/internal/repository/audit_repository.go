@@ -2,113 +2,287 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"fmt"
 	"net"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gridmate/backend/internal/database"
 	"github.com/gridmate/backend/internal/models"
+	"github.com/jmoiron/sqlx"
 )
 
+// RetentionCheckpointAction is the audit action recording that retention
+// pruned the start of a chain
+const RetentionCheckpointAction = "audit.retention.pruned"
+
 type auditLogRepository struct {
-	db *database.DB
+	db      *database.DB
+	hashKey []byte
 }
 
 func NewAuditLogRepository(db *database.DB) AuditLogRepository {
 	return &auditLogRepository{db: db}
 }
 
+// SetHashKey sets the HMAC key of new chain hashes
+func (r *auditLogRepository) SetHashKey(key []byte) {
+	r.hashKey = key
+}
+
+// Create appends an entry to its workspace's hash chain
 func (r *auditLogRepository) Create(ctx context.Context, log *models.AuditLog) error {
-	query := `
-		INSERT INTO audit_logs (user_id, action, entity_type, entity_id, changes, ip_address, user_agent)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING id, created_at`
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := r.lockChain(ctx, tx, log.WorkspaceID); err != nil {
+		return err
+	}
+	if err := r.appendEntry(ctx, tx, log); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit audit log: %w", err)
+	}
+	return nil
+}
+
+// lockChain serializes appends to a chain until the transaction ends
+func (r *auditLogRepository) lockChain(ctx context.Context, tx *sqlx.Tx, workspaceID *uuid.UUID) error {
+	chain := "audit:global"
+	if workspaceID != nil {
+		chain = "audit:" + workspaceID.String()
+	}
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, chain); err != nil {
+		return fmt.Errorf("failed to lock audit chain: %w", err)
+	}
+	return nil
+}
+
+// appendEntry links log to the last entry of its chain and inserts it. The
+// chain must be locked.
+func (r *auditLogRepository) appendEntry(ctx context.Context, tx *sqlx.Tx, log *models.AuditLog) error {
+	var prevSequence int64
+	var prevHash string
+	err := tx.QueryRowContext(ctx, `
+		SELECT sequence, hash FROM audit_logs
+		WHERE workspace_id IS NOT DISTINCT FROM $1 AND sequence IS NOT NULL
+		ORDER BY sequence DESC
+		LIMIT 1`, log.WorkspaceID).Scan(&prevSequence, &prevHash)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to get audit chain head: %w", err)
+	}
 
-	err := r.db.QueryRowContext(ctx, query,
+	changes, err := models.CanonicalChanges(log.Changes)
+	if err != nil {
+		return err
+	}
+
+	log.ID = uuid.New()
+	log.Sequence = prevSequence + 1
+	log.PrevHash = prevHash
+	log.Changes = changes
+	// Postgres keeps microseconds, so hash the time as it will read back
+	log.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	if log.Hash, err = log.ComputeHash(r.hashKey); err != nil {
+		return err
+	}
+
+	var ip *string
+	if log.IPAddress != nil {
+		s := log.IPAddress.String()
+		ip = &s
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO audit_logs (id, workspace_id, sequence, prev_hash, hash, user_id, action,
+			entity_type, entity_id, changes, ip_address, user_agent, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+		log.ID,
+		log.WorkspaceID,
+		log.Sequence,
+		log.PrevHash,
+		log.Hash,
 		log.UserID,
 		log.Action,
 		log.EntityType,
 		log.EntityID,
-		log.Changes,
-		log.IPAddress,
+		nullableJSON(log.Changes),
+		ip,
 		log.UserAgent,
-	).Scan(&log.ID, &log.CreatedAt)
-
+		log.CreatedAt,
+	)
 	if err != nil {
 		return fmt.Errorf("failed to create audit log: %w", err)
 	}
-
 	return nil
 }
 
 func (r *auditLogRepository) List(ctx context.Context, filter *models.AuditLogFilter) ([]*models.AuditLog, error) {
-	query := `
-		SELECT id, user_id, action, entity_type, entity_id, changes, ip_address, user_agent, created_at
-		FROM audit_logs
-		WHERE 1=1`
-	
-	args := []interface{}{}
-	argIndex := 1
-
-	// Apply filters
-	if filter.UserID != nil {
-		query += fmt.Sprintf(" AND user_id = $%d", argIndex)
-		args = append(args, *filter.UserID)
-		argIndex++
-	}
-	if filter.EntityType != nil {
-		query += fmt.Sprintf(" AND entity_type = $%d", argIndex)
-		args = append(args, *filter.EntityType)
-		argIndex++
-	}
-	if filter.EntityID != nil {
-		query += fmt.Sprintf(" AND entity_id = $%d", argIndex)
-		args = append(args, *filter.EntityID)
-		argIndex++
-	}
-	if filter.Action != nil {
-		query += fmt.Sprintf(" AND action = $%d", argIndex)
-		args = append(args, *filter.Action)
-		argIndex++
-	}
-	if filter.StartDate != nil {
-		query += fmt.Sprintf(" AND created_at >= $%d", argIndex)
-		args = append(args, *filter.StartDate)
-		argIndex++
-	}
-	if filter.EndDate != nil {
-		query += fmt.Sprintf(" AND created_at <= $%d", argIndex)
-		args = append(args, *filter.EndDate)
-		argIndex++
-	}
+	where, args := auditLogWhere(filter)
+	query := `SELECT ` + auditLogColumns + ` FROM audit_logs WHERE 1=1` + where
 
 	// Add ordering and pagination
 	query += " ORDER BY created_at DESC"
-	
+
 	if filter.Limit > 0 {
-		query += fmt.Sprintf(" LIMIT $%d", argIndex)
 		args = append(args, filter.Limit)
-		argIndex++
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
 	}
 	if filter.Offset > 0 {
-		query += fmt.Sprintf(" OFFSET $%d", argIndex)
 		args = append(args, filter.Offset)
-		argIndex++
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
 	}
 
-	rows, err := r.db.QueryContext(ctx, query, args...)
+	var logs []*models.AuditLog
+	err := r.query(ctx, query, args, func(log *models.AuditLog) error {
+		logs = append(logs, log)
+		return nil
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list audit logs: %w", err)
 	}
+	return logs, nil
+}
+
+func (r *auditLogRepository) Count(ctx context.Context, filter *models.AuditLogFilter) (int64, error) {
+	where, args := auditLogWhere(filter)
+	query := `SELECT COUNT(*) FROM audit_logs WHERE 1=1` + where
+
+	var count int64
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count audit logs: %w", err)
+	}
+
+	return count, nil
+}
+
+// Stream calls fn for every entry matching filter, oldest first per chain,
+// without loading them all into memory. Limit and Offset are ignored.
+func (r *auditLogRepository) Stream(ctx context.Context, filter *models.AuditLogFilter, fn func(*models.AuditLog) error) error {
+	where, args := auditLogWhere(filter)
+	query := `SELECT ` + auditLogColumns + ` FROM audit_logs WHERE 1=1` + where +
+		` ORDER BY workspace_id NULLS FIRST, sequence NULLS FIRST, created_at`
+
+	if err := r.query(ctx, query, args, fn); err != nil {
+		return fmt.Errorf("failed to stream audit logs: %w", err)
+	}
+	return nil
+}
+
+// StreamChain calls fn for every chained entry of a workspace in sequence
+// order. A nil workspaceID streams the chain of entries outside workspaces.
+func (r *auditLogRepository) StreamChain(ctx context.Context, workspaceID *uuid.UUID, fn func(*models.AuditLog) error) error {
+	query := `SELECT ` + auditLogColumns + ` FROM audit_logs
+		WHERE workspace_id IS NOT DISTINCT FROM $1 AND sequence IS NOT NULL
+		ORDER BY sequence`
+
+	if err := r.query(ctx, query, []interface{}{workspaceID}, fn); err != nil {
+		return fmt.Errorf("failed to stream audit chain: %w", err)
+	}
+	return nil
+}
+
+// Chains returns the workspaces that have audit entries, nil standing for
+// entries outside any workspace
+func (r *auditLogRepository) Chains(ctx context.Context) ([]*uuid.UUID, error) {
+	var chains []*uuid.UUID
+	if err := r.db.SelectContext(ctx, &chains, `SELECT DISTINCT workspace_id FROM audit_logs`); err != nil {
+		return nil, fmt.Errorf("failed to list audit chains: %w", err)
+	}
+	return chains, nil
+}
+
+// Prune deletes a workspace's entries created before the cutoff. The chain
+// first gets a checkpoint entry naming the last pruned entry so verification
+// can tell retention from tampering. It returns how many entries it deleted.
+func (r *auditLogRepository) Prune(ctx context.Context, workspaceID *uuid.UUID, before time.Time) (int64, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := r.lockChain(ctx, tx, workspaceID); err != nil {
+		return 0, err
+	}
+
+	var through sql.NullInt64
+	var throughHash sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		SELECT sequence, hash FROM audit_logs
+		WHERE workspace_id IS NOT DISTINCT FROM $1 AND sequence IS NOT NULL AND created_at < $2
+		ORDER BY sequence DESC
+		LIMIT 1`, workspaceID, before).Scan(&through, &throughHash)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to find prunable audit logs: %w", err)
+	}
+
+	if through.Valid {
+		changes, err := json.Marshal(map[string]interface{}{
+			"pruned_through_sequence": through.Int64,
+			"pruned_through_hash":     throughHash.String,
+			"before":                  before.UTC().Format(time.RFC3339),
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to encode retention checkpoint: %w", err)
+		}
+		checkpoint := &models.AuditLog{
+			WorkspaceID: workspaceID,
+			Action:      RetentionCheckpointAction,
+			Changes:     changes,
+		}
+		if err := r.appendEntry(ctx, tx, checkpoint); err != nil {
+			return 0, err
+		}
+	}
+
+	// Entries written before chaining was introduced are pruned by age alone
+	result, err := tx.ExecContext(ctx, `
+		DELETE FROM audit_logs
+		WHERE workspace_id IS NOT DISTINCT FROM $1
+		AND ((sequence IS NOT NULL AND sequence <= $2) OR (sequence IS NULL AND created_at < $3))`,
+		workspaceID, through.Int64, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune audit logs: %w", err)
+	}
+	deleted, _ := result.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit audit log pruning: %w", err)
+	}
+	return deleted, nil
+}
+
+const auditLogColumns = `id, workspace_id, sequence, prev_hash, hash, user_id, action,
+	entity_type, entity_id, changes, ip_address, user_agent, created_at`
+
+// query runs an audit log query and calls fn for every row
+func (r *auditLogRepository) query(ctx context.Context, query string, args []interface{}, fn func(*models.AuditLog) error) error {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
 	defer rows.Close()
 
-	var logs []*models.AuditLog
 	for rows.Next() {
 		log := &models.AuditLog{}
-		var ipStr *string
-		
+		var sequence sql.NullInt64
+		var prevHash, hash, ipStr sql.NullString
+
 		err := rows.Scan(
 			&log.ID,
+			&log.WorkspaceID,
+			&sequence,
+			&prevHash,
+			&hash,
 			&log.UserID,
 			&log.Action,
 			&log.EntityType,
@@ -119,90 +293,88 @@ func (r *auditLogRepository) List(ctx context.Context, filter *models.AuditLogFi
 			&log.CreatedAt,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan audit log: %w", err)
+			return fmt.Errorf("failed to scan audit log: %w", err)
 		}
-		
+		log.Sequence = sequence.Int64
+		log.PrevHash = prevHash.String
+		log.Hash = hash.String
+
 		// Convert IP string to net.IP
-		if ipStr != nil && *ipStr != "" {
-			ip := net.ParseIP(*ipStr)
+		if ipStr.Valid && ipStr.String != "" {
+			ip := net.ParseIP(ipStr.String)
 			log.IPAddress = &ip
 		}
-		
-		logs = append(logs, log)
-	}
 
-	return logs, nil
+		if err := fn(log); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
 }
 
-func (r *auditLogRepository) Count(ctx context.Context, filter *models.AuditLogFilter) (int64, error) {
-	query := `SELECT COUNT(*) FROM audit_logs WHERE 1=1`
-	
-	args := []interface{}{}
-	argIndex := 1
+// auditLogWhere builds the conditions and arguments of a filter
+func auditLogWhere(filter *models.AuditLogFilter) (string, []interface{}) {
+	var where string
+	var args []interface{}
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		where += fmt.Sprintf(" AND "+condition, len(args))
+	}
 
-	// Apply same filters as List method
 	if filter.UserID != nil {
-		query += fmt.Sprintf(" AND user_id = $%d", argIndex)
-		args = append(args, *filter.UserID)
-		argIndex++
+		add("user_id = $%d", *filter.UserID)
+	}
+	if filter.WorkspaceID != nil {
+		add("workspace_id = $%d", *filter.WorkspaceID)
 	}
 	if filter.EntityType != nil {
-		query += fmt.Sprintf(" AND entity_type = $%d", argIndex)
-		args = append(args, *filter.EntityType)
-		argIndex++
+		add("entity_type = $%d", *filter.EntityType)
 	}
 	if filter.EntityID != nil {
-		query += fmt.Sprintf(" AND entity_id = $%d", argIndex)
-		args = append(args, *filter.EntityID)
-		argIndex++
+		add("entity_id = $%d", *filter.EntityID)
 	}
 	if filter.Action != nil {
-		query += fmt.Sprintf(" AND action = $%d", argIndex)
-		args = append(args, *filter.Action)
-		argIndex++
+		add("action = $%d", *filter.Action)
 	}
 	if filter.StartDate != nil {
-		query += fmt.Sprintf(" AND created_at >= $%d", argIndex)
-		args = append(args, *filter.StartDate)
-		argIndex++
+		add("created_at >= $%d", *filter.StartDate)
 	}
 	if filter.EndDate != nil {
-		query += fmt.Sprintf(" AND created_at <= $%d", argIndex)
-		args = append(args, *filter.EndDate)
-		argIndex++
+		add("created_at <= $%d", *filter.EndDate)
 	}
+	return where, args
+}
 
-	var count int64
-	err := r.db.QueryRowContext(ctx, query, args...).Scan(&count)
-	if err != nil {
-		return 0, fmt.Errorf("failed to count audit logs: %w", err)
+// nullableJSON stores empty changes as NULL
+func nullableJSON(data json.RawMessage) interface{} {
+	if len(data) == 0 {
+		return nil
 	}
-
-	return count, nil
+	return []byte(data)
 }
 
 // Helper function to create audit logs from request context
 func CreateAuditLog(ctx context.Context, repo AuditLogRepository, action, entityType string, entityID *uuid.UUID, changes interface{}) error {
 	// Extract user ID from context (set by auth middleware)
 	userID, _ := ctx.Value("user_id").(uuid.UUID)
-	
+
 	// Extract IP and user agent from context (should be set by middleware)
 	ipStr, _ := ctx.Value("client_ip").(string)
 	userAgent, _ := ctx.Value("user_agent").(string)
-	
+
 	var ip *net.IP
 	if ipStr != "" {
 		parsedIP := net.ParseIP(ipStr)
 		ip = &parsedIP
 	}
-	
+
 	// Convert changes to JSON
 	var changesJSON []byte
 	if changes != nil {
 		// In production, properly marshal the changes
 		changesJSON = []byte("{}")
 	}
-	
+
 	log := &models.AuditLog{
 		UserID:     &userID,
 		Action:     action,
@@ -212,6 +384,6 @@ func CreateAuditLog(ctx context.Context, repo AuditLogRepository, action, entity
 		IPAddress:  ip,
 		UserAgent:  &userAgent,
 	}
-	
+
 	return repo.Create(ctx, log)
-}
\ No newline at end of file
+}
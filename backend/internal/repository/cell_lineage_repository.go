@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/gridmate/backend/internal/database"
+	"github.com/gridmate/backend/internal/models"
+)
+
+type cellLineageRepository struct {
+	db *database.DB
+}
+
+func NewCellLineageRepository(db *database.DB) CellLineageRepository {
+	return &cellLineageRepository{db: db}
+}
+
+func (r *cellLineageRepository) Create(ctx context.Context, l *models.CellLineage) error {
+	query := `
+		INSERT INTO cell_lineage (user_id, workbook, sheet, start_row, start_col, end_row, end_col,
+			address, session_id, message_id, tool_id, operation_id, tool_name, model, input,
+			sources, status, approval, applied_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
+		RETURNING id, created_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		l.UserID,
+		l.Workbook,
+		l.Sheet,
+		l.StartRow,
+		l.StartCol,
+		l.EndRow,
+		l.EndCol,
+		l.Address,
+		l.SessionID,
+		l.MessageID,
+		l.ToolID,
+		l.OperationID,
+		l.ToolName,
+		l.Model,
+		nullableJSON(l.Input),
+		nullableJSON(l.Sources),
+		l.Status,
+		nullableJSON(l.Approval),
+		l.AppliedAt,
+	).Scan(&l.ID, &l.CreatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create cell lineage: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateStatus sets the status of the writes of a queued operation, unless
+// status is empty, and merges approval into their approval details
+func (r *cellLineageRepository) UpdateStatus(ctx context.Context, operationID, status string, approval json.RawMessage) error {
+	query := `
+		UPDATE cell_lineage
+		SET status = COALESCE(NULLIF($2, ''), status),
+			approval = COALESCE(approval, '{}'::jsonb) || COALESCE($3::jsonb, '{}'::jsonb),
+			applied_at = CASE WHEN $2 = 'applied' THEN CURRENT_TIMESTAMP ELSE applied_at END
+		WHERE operation_id = $1`
+
+	if _, err := r.db.ExecContext(ctx, query, operationID, status, nullableJSON(approval)); err != nil {
+		return fmt.Errorf("failed to update cell lineage: %w", err)
+	}
+
+	return nil
+}
+
+// ListForCell returns the writes covering a cell, newest first
+func (r *cellLineageRepository) ListForCell(ctx context.Context, userID uuid.UUID, workbook, sheet string, row, col, limit int) ([]*models.CellLineage, error) {
+	query := `
+		SELECT id, user_id, workbook, sheet, start_row, start_col, end_row, end_col, address,
+			session_id, message_id, tool_id, operation_id, tool_name, model, input, sources,
+			status, approval, created_at, applied_at
+		FROM cell_lineage
+		WHERE user_id = $1 AND workbook = $2 AND LOWER(sheet) = LOWER($3)
+			AND start_row <= $4 AND end_row >= $4 AND start_col <= $5 AND end_col >= $5
+		ORDER BY created_at DESC
+		LIMIT $6`
+
+	var lineage []*models.CellLineage
+	if err := r.db.SelectContext(ctx, &lineage, query, userID, workbook, sheet, row, col, limit); err != nil {
+		return nil, fmt.Errorf("failed to list cell lineage: %w", err)
+	}
+
+	return lineage, nil
+}
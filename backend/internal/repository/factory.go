@@ -7,12 +7,19 @@ import (
 // NewRepositories creates and returns all repository instances
 func NewRepositories(db *database.DB) *Repositories {
 	return &Repositories{
-		Users:      NewUserRepository(db),
-		Workspaces: NewWorkspaceRepository(db),
-		AuditLogs:  NewAuditLogRepository(db),
-		Sessions:   NewSessionRepository(db),
-		APIKeys:    NewAPIKeyRepository(db),
-		Documents:  NewDocumentRepository(db),
-		Embeddings: NewEmbeddingRepository(db),
+		Users:           NewUserRepository(db),
+		Workspaces:      NewWorkspaceRepository(db),
+		AuditLogs:       NewAuditLogRepository(db),
+		Sessions:        NewSessionRepository(db),
+		APIKeys:         NewAPIKeyRepository(db),
+		Documents:       NewDocumentRepository(db),
+		Embeddings:      NewEmbeddingRepository(db),
+		Facts:           NewFinancialFactRepository(db),
+		Templates:       NewTemplateRepository(db),
+		ProtectedRanges: NewProtectedRangeRepository(db),
+		CellLineage:     NewCellLineageRepository(db),
+		Reviews:         NewOperationReviewRepository(db),
+		RateLimits:      NewRateLimitRepository(db),
+		OAuthProviders:  NewOAuthProviderRepository(db),
 	}
 }
\ No newline at end of file
@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/gridmate/backend/internal/database"
+	"github.com/gridmate/backend/internal/models"
+)
+
+// factInsertBatchSize keeps batch inserts under the Postgres parameter limit
+const factInsertBatchSize = 500
+
+type financialFactRepository struct {
+	db *database.DB
+}
+
+func NewFinancialFactRepository(db *database.DB) FinancialFactRepository {
+	return &financialFactRepository{db: db}
+}
+
+func (r *financialFactRepository) BatchCreate(ctx context.Context, facts []*models.FinancialFact) error {
+	for start := 0; start < len(facts); start += factInsertBatchSize {
+		end := start + factInsertBatchSize
+		if end > len(facts) {
+			end = len(facts)
+		}
+		if err := r.insertBatch(ctx, facts[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *financialFactRepository) insertBatch(ctx context.Context, facts []*models.FinancialFact) error {
+	const columns = 15
+
+	valueStrings := make([]string, 0, len(facts))
+	valueArgs := make([]interface{}, 0, len(facts)*columns)
+
+	for i, fact := range facts {
+		fact.ID = uuid.New()
+		if fact.Dimensions == nil {
+			fact.Dimensions = []byte("{}")
+		}
+
+		placeholders := make([]string, columns)
+		for j := range placeholders {
+			placeholders[j] = fmt.Sprintf("$%d", i*columns+j+1)
+		}
+		valueStrings = append(valueStrings, "("+strings.Join(placeholders, ", ")+")")
+		valueArgs = append(valueArgs,
+			fact.ID,
+			fact.DocumentID,
+			fact.Concept,
+			fact.LocalName,
+			fact.ContextRef,
+			fact.PeriodType,
+			fact.PeriodStart,
+			fact.PeriodEnd,
+			fact.Dimensions,
+			fact.Unit,
+			fact.Decimals,
+			fact.Scale,
+			fact.Value,
+			fact.TextValue,
+			fact.Page,
+		)
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO financial_facts (
+			id, document_id, concept, local_name, context_ref, period_type, period_start,
+			period_end, dimensions, unit, decimals, scale, value, text_value, page
+		) VALUES %s`, strings.Join(valueStrings, ","))
+
+	if _, err := r.db.ExecContext(ctx, query, valueArgs...); err != nil {
+		return fmt.Errorf("failed to create financial facts: %w", err)
+	}
+
+	return nil
+}
+
+func (r *financialFactRepository) GetByDocumentID(ctx context.Context, documentID uuid.UUID) ([]*models.FinancialFact, error) {
+	var facts []*models.FinancialFact
+	query := `
+		SELECT * FROM financial_facts
+		WHERE document_id = $1
+		ORDER BY concept, period_end DESC NULLS LAST
+	`
+
+	if err := r.db.SelectContext(ctx, &facts, query, documentID); err != nil {
+		return nil, fmt.Errorf("failed to get financial facts: %w", err)
+	}
+	return facts, nil
+}
+
+func (r *financialFactRepository) Search(ctx context.Context, filter *models.FinancialFactFilter) ([]*models.FinancialFact, error) {
+	query := `
+		SELECT f.* FROM financial_facts f
+		JOIN documents d ON d.id = f.document_id
+		WHERE d.user_id = $1`
+
+	args := []interface{}{filter.UserID}
+	argIndex := 2
+
+	if filter.DocumentID != nil {
+		query += fmt.Sprintf(" AND f.document_id = $%d", argIndex)
+		args = append(args, *filter.DocumentID)
+		argIndex++
+	}
+	if filter.Concept != nil {
+		if strings.Contains(*filter.Concept, ":") {
+			query += fmt.Sprintf(" AND f.concept = $%d", argIndex)
+		} else {
+			query += fmt.Sprintf(" AND f.local_name = $%d", argIndex)
+		}
+		args = append(args, *filter.Concept)
+		argIndex++
+	}
+	if filter.PeriodType != nil {
+		query += fmt.Sprintf(" AND f.period_type = $%d", argIndex)
+		args = append(args, *filter.PeriodType)
+		argIndex++
+	}
+	if filter.PeriodEndFrom != nil {
+		query += fmt.Sprintf(" AND f.period_end >= $%d", argIndex)
+		args = append(args, *filter.PeriodEndFrom)
+		argIndex++
+	}
+	if filter.PeriodEndTo != nil {
+		query += fmt.Sprintf(" AND f.period_end <= $%d", argIndex)
+		args = append(args, *filter.PeriodEndTo)
+		argIndex++
+	}
+	if !filter.IncludeDimensional {
+		query += " AND f.dimensions = '{}'::jsonb"
+	}
+
+	query += " ORDER BY f.period_end DESC NULLS LAST, f.concept"
+
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d", argIndex)
+		args = append(args, filter.Limit)
+		argIndex++
+	}
+	if filter.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET $%d", argIndex)
+		args = append(args, filter.Offset)
+		argIndex++
+	}
+
+	var facts []*models.FinancialFact
+	if err := r.db.SelectContext(ctx, &facts, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to search financial facts: %w", err)
+	}
+	return facts, nil
+}
+
+func (r *financialFactRepository) DeleteByDocumentID(ctx context.Context, documentID uuid.UUID) error {
+	query := `DELETE FROM financial_facts WHERE document_id = $1`
+
+	if _, err := r.db.ExecContext(ctx, query, documentID); err != nil {
+		return fmt.Errorf("failed to delete financial facts: %w", err)
+	}
+	return nil
+}
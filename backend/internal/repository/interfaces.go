@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gridmate/backend/internal/models"
@@ -38,6 +40,11 @@ type AuditLogRepository interface {
 	Create(ctx context.Context, log *models.AuditLog) error
 	List(ctx context.Context, filter *models.AuditLogFilter) ([]*models.AuditLog, error)
 	Count(ctx context.Context, filter *models.AuditLogFilter) (int64, error)
+	Stream(ctx context.Context, filter *models.AuditLogFilter, fn func(*models.AuditLog) error) error
+	StreamChain(ctx context.Context, workspaceID *uuid.UUID, fn func(*models.AuditLog) error) error
+	Chains(ctx context.Context) ([]*uuid.UUID, error)
+	Prune(ctx context.Context, workspaceID *uuid.UUID, before time.Time) (int64, error)
+	SetHashKey(key []byte)
 }
 
 type SessionRepository interface {
@@ -58,6 +65,7 @@ type APIKeyRepository interface {
 	Delete(ctx context.Context, id uuid.UUID) error
 	IsActive(ctx context.Context, keyHash string) (bool, error)
 	ValidateAPIKey(ctx context.Context, plainKey string) (*models.APIKey, error)
+	Rotate(ctx context.Context, oldID uuid.UUID, newKey *models.APIKey, plainKey string, graceUntil time.Time) error
 }
 
 type DocumentRepository interface {
@@ -77,12 +85,71 @@ type EmbeddingRepository interface {
 	DeleteByDocumentID(ctx context.Context, documentID uuid.UUID) error
 }
 
+type FinancialFactRepository interface {
+	BatchCreate(ctx context.Context, facts []*models.FinancialFact) error
+	GetByDocumentID(ctx context.Context, documentID uuid.UUID) ([]*models.FinancialFact, error)
+	Search(ctx context.Context, filter *models.FinancialFactFilter) ([]*models.FinancialFact, error)
+	DeleteByDocumentID(ctx context.Context, documentID uuid.UUID) error
+}
+
+type TemplateRepository interface {
+	Create(ctx context.Context, tmpl *models.ModelTemplate) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.ModelTemplate, error)
+	GetLatest(ctx context.Context, workspaceID uuid.UUID, templateKey string) (*models.ModelTemplate, error)
+	List(ctx context.Context, filter *models.ModelTemplateFilter) ([]*models.ModelTemplate, error)
+	Update(ctx context.Context, id uuid.UUID, updates *models.UpdateModelTemplateRequest) error
+	Deprecate(ctx context.Context, id uuid.UUID) error
+}
+
+type ProtectedRangeRepository interface {
+	Create(ctx context.Context, pr *models.ProtectedRange) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.ProtectedRange, error)
+	ListByWorkbook(ctx context.Context, workspaceID uuid.UUID, workbook string) ([]*models.ProtectedRange, error)
+	ListForMember(ctx context.Context, userID uuid.UUID, workbook string) ([]*models.ProtectedRange, error)
+	ListByWorkbookName(ctx context.Context, workbook string) ([]*models.ProtectedRange, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+type CellLineageRepository interface {
+	Create(ctx context.Context, l *models.CellLineage) error
+	UpdateStatus(ctx context.Context, operationID, status string, approval json.RawMessage) error
+	ListForCell(ctx context.Context, userID uuid.UUID, workbook, sheet string, row, col, limit int) ([]*models.CellLineage, error)
+}
+
+type OperationReviewRepository interface {
+	Create(ctx context.Context, review *models.OperationReview) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.OperationReview, error)
+	ListPendingForReviewer(ctx context.Context, reviewerID uuid.UUID) ([]*models.OperationReview, error)
+	AddDecision(ctx context.Context, id uuid.UUID, operationID string, decision json.RawMessage) error
+	Complete(ctx context.Context, id uuid.UUID) (bool, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+type RateLimitRepository interface {
+	Latest(ctx context.Context, identifier, endpoint string, now time.Time) (*models.RateLimit, error)
+	Save(ctx context.Context, limits []*models.RateLimit) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+type OAuthProviderRepository interface {
+	Create(ctx context.Context, link *models.OAuthProvider) error
+	GetByProviderUserID(ctx context.Context, provider, providerUserID string) (*models.OAuthProvider, error)
+	Update(ctx context.Context, id uuid.UUID, email *string, metadata json.RawMessage) error
+}
+
 type Repositories struct {
-	Users      UserRepository
-	Workspaces WorkspaceRepository
-	AuditLogs  AuditLogRepository
-	Sessions   SessionRepository
-	APIKeys    APIKeyRepository
-	Documents  DocumentRepository
-	Embeddings EmbeddingRepository
+	Users           UserRepository
+	Workspaces      WorkspaceRepository
+	AuditLogs       AuditLogRepository
+	Sessions        SessionRepository
+	APIKeys         APIKeyRepository
+	Documents       DocumentRepository
+	Embeddings      EmbeddingRepository
+	Facts           FinancialFactRepository
+	Templates       TemplateRepository
+	ProtectedRanges ProtectedRangeRepository
+	CellLineage     CellLineageRepository
+	Reviews         OperationReviewRepository
+	RateLimits      RateLimitRepository
+	OAuthProviders  OAuthProviderRepository
 }
\ No newline at end of file
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/gridmate/backend/internal/database"
+	"github.com/gridmate/backend/internal/models"
+)
+
+type oauthProviderRepository struct {
+	db *database.DB
+}
+
+func NewOAuthProviderRepository(db *database.DB) OAuthProviderRepository {
+	return &oauthProviderRepository{db: db}
+}
+
+func (r *oauthProviderRepository) Create(ctx context.Context, link *models.OAuthProvider) error {
+	query := `
+		INSERT INTO oauth_providers (user_id, provider, provider_user_id, email, metadata)
+		VALUES ($1, $2, $3, $4, COALESCE($5, '{}'::jsonb))
+		RETURNING id, created_at, updated_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		link.UserID,
+		link.Provider,
+		link.ProviderUserID,
+		link.Email,
+		nullableJSON(link.Metadata),
+	).Scan(&link.ID, &link.CreatedAt, &link.UpdatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create oauth provider link: %w", err)
+	}
+
+	return nil
+}
+
+// GetByProviderUserID returns the account linked to a provider's user, or
+// nil if there is none
+func (r *oauthProviderRepository) GetByProviderUserID(ctx context.Context, provider, providerUserID string) (*models.OAuthProvider, error) {
+	link := &models.OAuthProvider{}
+	query := `
+		SELECT id, user_id, provider, provider_user_id, email, metadata, created_at, updated_at
+		FROM oauth_providers
+		WHERE provider = $1 AND provider_user_id = $2`
+
+	err := r.db.GetContext(ctx, link, query, provider, providerUserID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get oauth provider link: %w", err)
+	}
+
+	return link, nil
+}
+
+func (r *oauthProviderRepository) Update(ctx context.Context, id uuid.UUID, email *string, metadata json.RawMessage) error {
+	query := `UPDATE oauth_providers SET email = $2, metadata = COALESCE($3, metadata) WHERE id = $1`
+
+	if _, err := r.db.ExecContext(ctx, query, id, email, nullableJSON(metadata)); err != nil {
+		return fmt.Errorf("failed to update oauth provider link: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/gridmate/backend/internal/database"
+	"github.com/gridmate/backend/internal/models"
+)
+
+// ErrOperationReviewNotFound is returned when no review has the given ID
+var ErrOperationReviewNotFound = errors.New("operation review not found")
+
+type operationReviewRepository struct {
+	db *database.DB
+}
+
+func NewOperationReviewRepository(db *database.DB) OperationReviewRepository {
+	return &operationReviewRepository{db: db}
+}
+
+const operationReviewColumns = `id, session_id, workspace_id, submitted_by, reviewers, operation_ids,
+		required_approvals, comment, status, decisions, created_at, completed_at`
+
+func (r *operationReviewRepository) Create(ctx context.Context, review *models.OperationReview) error {
+	if review.Status == "" {
+		review.Status = models.ReviewPending
+	}
+	if len(review.Decisions) == 0 {
+		review.Decisions = json.RawMessage(`{}`)
+	}
+
+	query := `
+		INSERT INTO operation_reviews (session_id, workspace_id, submitted_by, reviewers, operation_ids, required_approvals, comment, status, decisions)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		review.SessionID,
+		review.WorkspaceID,
+		review.SubmittedBy,
+		review.Reviewers,
+		review.OperationIDs,
+		review.RequiredApprovals,
+		review.Comment,
+		review.Status,
+		review.Decisions,
+	).Scan(&review.ID, &review.CreatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create operation review: %w", err)
+	}
+
+	return nil
+}
+
+func (r *operationReviewRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.OperationReview, error) {
+	query := `SELECT ` + operationReviewColumns + ` FROM operation_reviews WHERE id = $1`
+
+	review, err := scanOperationReview(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrOperationReviewNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get operation review: %w", err)
+	}
+
+	return review, nil
+}
+
+// ListPendingForReviewer returns the pending reviews assigned to a reviewer,
+// oldest first
+func (r *operationReviewRepository) ListPendingForReviewer(ctx context.Context, reviewerID uuid.UUID) ([]*models.OperationReview, error) {
+	query := `SELECT ` + operationReviewColumns + `
+		FROM operation_reviews
+		WHERE status = 'pending' AND $1 = ANY(reviewers)
+		ORDER BY created_at`
+
+	rows, err := r.db.QueryContext(ctx, query, reviewerID.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list operation reviews: %w", err)
+	}
+	defer rows.Close()
+
+	var reviews []*models.OperationReview
+	for rows.Next() {
+		review, err := scanOperationReview(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan operation review: %w", err)
+		}
+		reviews = append(reviews, review)
+	}
+
+	return reviews, rows.Err()
+}
+
+// AddDecision appends a reviewer's decision to an operation's trail
+func (r *operationReviewRepository) AddDecision(ctx context.Context, id uuid.UUID, operationID string, decision json.RawMessage) error {
+	query := `
+		UPDATE operation_reviews
+		SET decisions = jsonb_set(decisions, ARRAY[$2], COALESCE(decisions->$2, '[]'::jsonb) || jsonb_build_array($3::jsonb))
+		WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query, id, operationID, decision)
+	if err != nil {
+		return fmt.Errorf("failed to record review decision: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrOperationReviewNotFound
+	}
+
+	return nil
+}
+
+// Complete closes a pending review. It reports false if the review was
+// already completed.
+func (r *operationReviewRepository) Complete(ctx context.Context, id uuid.UUID) (bool, error) {
+	query := `
+		UPDATE operation_reviews
+		SET status = 'completed', completed_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'pending'`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to complete operation review: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+func (r *operationReviewRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM operation_reviews WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete operation review: %w", err)
+	}
+	return nil
+}
+
+func scanOperationReview(row rowScanner) (*models.OperationReview, error) {
+	review := &models.OperationReview{}
+	err := row.Scan(
+		&review.ID,
+		&review.SessionID,
+		&review.WorkspaceID,
+		&review.SubmittedBy,
+		&review.Reviewers,
+		&review.OperationIDs,
+		&review.RequiredApprovals,
+		&review.Comment,
+		&review.Status,
+		&review.Decisions,
+		&review.CreatedAt,
+		&review.CompletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return review, nil
+}
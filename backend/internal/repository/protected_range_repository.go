@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/gridmate/backend/internal/database"
+	"github.com/gridmate/backend/internal/models"
+)
+
+// ErrProtectedRangeNotFound is returned when no protected range has the given ID
+var ErrProtectedRangeNotFound = errors.New("protected range not found")
+
+type protectedRangeRepository struct {
+	db *database.DB
+}
+
+func NewProtectedRangeRepository(db *database.DB) ProtectedRangeRepository {
+	return &protectedRangeRepository{db: db}
+}
+
+const protectedRangeColumns = `pr.id, pr.workspace_id, pr.workbook, pr.kind, pr.target, pr.reason, pr.created_by, pr.created_at`
+
+func (r *protectedRangeRepository) Create(ctx context.Context, pr *models.ProtectedRange) error {
+	query := `
+		INSERT INTO protected_ranges (workspace_id, workbook, kind, target, reason, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		pr.WorkspaceID,
+		pr.Workbook,
+		pr.Kind,
+		pr.Target,
+		pr.Reason,
+		pr.CreatedBy,
+	).Scan(&pr.ID, &pr.CreatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create protected range: %w", err)
+	}
+
+	return nil
+}
+
+func (r *protectedRangeRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.ProtectedRange, error) {
+	query := `SELECT ` + protectedRangeColumns + ` FROM protected_ranges pr WHERE pr.id = $1`
+
+	pr, err := scanProtectedRange(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrProtectedRangeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get protected range: %w", err)
+	}
+
+	return pr, nil
+}
+
+// ListByWorkbook returns the protections a workspace has on a workbook
+func (r *protectedRangeRepository) ListByWorkbook(ctx context.Context, workspaceID uuid.UUID, workbook string) ([]*models.ProtectedRange, error) {
+	query := `SELECT ` + protectedRangeColumns + `
+		FROM protected_ranges pr
+		WHERE pr.workspace_id = $1 AND pr.workbook = $2
+		ORDER BY pr.created_at`
+
+	return r.list(ctx, query, workspaceID, workbook)
+}
+
+// ListForMember returns the protections on a workbook in every workspace
+// the user belongs to
+func (r *protectedRangeRepository) ListForMember(ctx context.Context, userID uuid.UUID, workbook string) ([]*models.ProtectedRange, error) {
+	query := `SELECT ` + protectedRangeColumns + `
+		FROM protected_ranges pr
+		JOIN workspace_members wm ON wm.workspace_id = pr.workspace_id
+		WHERE wm.user_id = $1 AND pr.workbook = $2
+		ORDER BY pr.created_at`
+
+	return r.list(ctx, query, userID, workbook)
+}
+
+// ListByWorkbookName returns the protections on a workbook in any workspace
+func (r *protectedRangeRepository) ListByWorkbookName(ctx context.Context, workbook string) ([]*models.ProtectedRange, error) {
+	query := `SELECT ` + protectedRangeColumns + `
+		FROM protected_ranges pr
+		WHERE pr.workbook = $1
+		ORDER BY pr.created_at`
+
+	return r.list(ctx, query, workbook)
+}
+
+func (r *protectedRangeRepository) list(ctx context.Context, query string, args ...interface{}) ([]*models.ProtectedRange, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list protected ranges: %w", err)
+	}
+	defer rows.Close()
+
+	var ranges []*models.ProtectedRange
+	for rows.Next() {
+		pr, err := scanProtectedRange(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan protected range: %w", err)
+		}
+		ranges = append(ranges, pr)
+	}
+
+	return ranges, rows.Err()
+}
+
+func (r *protectedRangeRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	query := `DELETE FROM protected_ranges WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete protected range: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrProtectedRangeNotFound
+	}
+
+	return nil
+}
+
+func scanProtectedRange(row rowScanner) (*models.ProtectedRange, error) {
+	pr := &models.ProtectedRange{}
+	err := row.Scan(
+		&pr.ID,
+		&pr.WorkspaceID,
+		&pr.Workbook,
+		&pr.Kind,
+		&pr.Target,
+		&pr.Reason,
+		&pr.CreatedBy,
+		&pr.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return pr, nil
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/gridmate/backend/internal/database"
+	"github.com/gridmate/backend/internal/models"
+)
+
+type rateLimitRepository struct {
+	db *database.DB
+}
+
+func NewRateLimitRepository(db *database.DB) RateLimitRepository {
+	return &rateLimitRepository{db: db}
+}
+
+// Latest returns the newest saved bucket of an identifier and endpoint that
+// is not yet full again at now, or nil if there is none
+func (r *rateLimitRepository) Latest(ctx context.Context, identifier, endpoint string, now time.Time) (*models.RateLimit, error) {
+	limit := &models.RateLimit{}
+	query := `
+		SELECT id, identifier, endpoint, requests, window_start, window_end, created_at
+		FROM rate_limits
+		WHERE identifier = $1 AND endpoint = $2 AND window_end > $3
+		ORDER BY window_start DESC
+		LIMIT 1`
+
+	err := r.db.GetContext(ctx, limit, query, identifier, endpoint, now)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rate limit: %w", err)
+	}
+
+	return limit, nil
+}
+
+// Save replaces the saved bucket of each identifier and endpoint
+func (r *rateLimitRepository) Save(ctx context.Context, limits []*models.RateLimit) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, l := range limits {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM rate_limits WHERE identifier = $1 AND endpoint = $2`, l.Identifier, l.Endpoint); err != nil {
+			return fmt.Errorf("failed to replace rate limit: %w", err)
+		}
+		query := `
+			INSERT INTO rate_limits (identifier, endpoint, requests, window_start, window_end)
+			VALUES ($1, $2, $3, $4, $5)`
+		if _, err := tx.ExecContext(ctx, query, l.Identifier, l.Endpoint, l.Requests, l.WindowStart, l.WindowEnd); err != nil {
+			return fmt.Errorf("failed to save rate limit: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit rate limits: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpired removes the buckets that are full again before the given time
+func (r *rateLimitRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM rate_limits WHERE window_end <= $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired rate limits: %w", err)
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,252 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/gridmate/backend/internal/database"
+	"github.com/gridmate/backend/internal/models"
+)
+
+// ErrTemplateNotFound is returned when no template has the given ID or key
+var ErrTemplateNotFound = errors.New("template not found")
+
+type templateRepository struct {
+	db *database.DB
+}
+
+func NewTemplateRepository(db *database.DB) TemplateRepository {
+	return &templateRepository{db: db}
+}
+
+const templateColumns = `id, workspace_id, template_key, version, name, category, description,
+		definition, status, source, created_by, deprecated_at, created_at, updated_at`
+
+func (r *templateRepository) Create(ctx context.Context, tmpl *models.ModelTemplate) error {
+	if tmpl.Status == "" {
+		tmpl.Status = models.TemplateStatusActive
+	}
+	if tmpl.Source == "" {
+		tmpl.Source = models.TemplateSourceManual
+	}
+
+	query := `
+		INSERT INTO model_templates (workspace_id, template_key, version, name, category, description, definition, status, source, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at, updated_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		tmpl.WorkspaceID,
+		tmpl.TemplateKey,
+		tmpl.Version,
+		tmpl.Name,
+		tmpl.Category,
+		tmpl.Description,
+		tmpl.Definition,
+		tmpl.Status,
+		tmpl.Source,
+		tmpl.CreatedBy,
+	).Scan(&tmpl.ID, &tmpl.CreatedAt, &tmpl.UpdatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create template: %w", err)
+	}
+
+	return nil
+}
+
+func (r *templateRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.ModelTemplate, error) {
+	query := `SELECT ` + templateColumns + ` FROM model_templates WHERE id = $1`
+
+	tmpl, err := scanTemplate(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrTemplateNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+func (r *templateRepository) GetLatest(ctx context.Context, workspaceID uuid.UUID, templateKey string) (*models.ModelTemplate, error) {
+	query := `SELECT ` + templateColumns + `
+		FROM model_templates
+		WHERE workspace_id = $1 AND template_key = $2
+		ORDER BY version DESC
+		LIMIT 1`
+
+	tmpl, err := scanTemplate(r.db.QueryRowContext(ctx, query, workspaceID, templateKey))
+	if err == sql.ErrNoRows {
+		return nil, ErrTemplateNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+func (r *templateRepository) List(ctx context.Context, filter *models.ModelTemplateFilter) ([]*models.ModelTemplate, error) {
+	query := `SELECT ` + templateColumns + ` FROM model_templates t WHERE workspace_id = $1`
+
+	args := []interface{}{filter.WorkspaceID}
+	argIndex := 2
+
+	if filter.Category != nil {
+		query += fmt.Sprintf(" AND category = $%d", argIndex)
+		args = append(args, *filter.Category)
+		argIndex++
+	}
+	if filter.TemplateKey != nil {
+		query += fmt.Sprintf(" AND template_key = $%d", argIndex)
+		args = append(args, *filter.TemplateKey)
+		argIndex++
+	}
+	if !filter.IncludeDeprecated {
+		query += fmt.Sprintf(" AND status = '%s'", models.TemplateStatusActive)
+	}
+	if filter.LatestOnly {
+		query += ` AND version = (
+			SELECT MAX(version) FROM model_templates v
+			WHERE v.workspace_id = t.workspace_id AND v.template_key = t.template_key)`
+	}
+
+	query += " ORDER BY category, name, version DESC"
+
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d", argIndex)
+		args = append(args, filter.Limit)
+		argIndex++
+	}
+	if filter.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET $%d", argIndex)
+		args = append(args, filter.Offset)
+		argIndex++
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list templates: %w", err)
+	}
+	defer rows.Close()
+
+	var templates []*models.ModelTemplate
+	for rows.Next() {
+		tmpl, err := scanTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan template: %w", err)
+		}
+		templates = append(templates, tmpl)
+	}
+
+	return templates, nil
+}
+
+func (r *templateRepository) Update(ctx context.Context, id uuid.UUID, updates *models.UpdateModelTemplateRequest) error {
+	query := `UPDATE model_templates SET `
+	args := []interface{}{}
+	argIndex := 1
+
+	if updates.Name != nil {
+		query += fmt.Sprintf("name = $%d, ", argIndex)
+		args = append(args, *updates.Name)
+		argIndex++
+	}
+	if updates.Category != nil {
+		query += fmt.Sprintf("category = $%d, ", argIndex)
+		args = append(args, *updates.Category)
+		argIndex++
+	}
+	if updates.Description != nil {
+		query += fmt.Sprintf("description = $%d, ", argIndex)
+		args = append(args, *updates.Description)
+		argIndex++
+	}
+	if updates.Definition != nil {
+		query += fmt.Sprintf("definition = $%d, ", argIndex)
+		args = append(args, updates.Definition)
+		argIndex++
+	}
+
+	if len(args) == 0 {
+		return nil
+	}
+
+	// Remove trailing comma and space
+	query = query[:len(query)-2]
+
+	query += fmt.Sprintf(" WHERE id = $%d", argIndex)
+	args = append(args, id)
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update template: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrTemplateNotFound
+	}
+
+	return nil
+}
+
+func (r *templateRepository) Deprecate(ctx context.Context, id uuid.UUID) error {
+	query := `
+		UPDATE model_templates
+		SET status = $1, deprecated_at = CURRENT_TIMESTAMP
+		WHERE id = $2 AND status <> $1`
+
+	result, err := r.db.ExecContext(ctx, query, models.TemplateStatusDeprecated, id)
+	if err != nil {
+		return fmt.Errorf("failed to deprecate template: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrTemplateNotFound
+	}
+
+	return nil
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTemplate(row rowScanner) (*models.ModelTemplate, error) {
+	tmpl := &models.ModelTemplate{}
+	err := row.Scan(
+		&tmpl.ID,
+		&tmpl.WorkspaceID,
+		&tmpl.TemplateKey,
+		&tmpl.Version,
+		&tmpl.Name,
+		&tmpl.Category,
+		&tmpl.Description,
+		&tmpl.Definition,
+		&tmpl.Status,
+		&tmpl.Source,
+		&tmpl.CreatedBy,
+		&tmpl.DeprecatedAt,
+		&tmpl.CreatedAt,
+		&tmpl.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
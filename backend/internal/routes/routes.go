@@ -12,8 +12,11 @@ import (
 	"github.com/gridmate/backend/internal/middleware"
 	"github.com/gridmate/backend/internal/repository"
 	"github.com/gridmate/backend/internal/services"
+	"github.com/gridmate/backend/internal/services/audit"
 	"github.com/gridmate/backend/internal/services/diff"
 	"github.com/gridmate/backend/internal/services/documents"
+	"github.com/gridmate/backend/internal/services/review"
+	"github.com/gridmate/backend/internal/services/templates"
 )
 
 func RegisterAPIRoutes(
@@ -23,6 +26,8 @@ func RegisterAPIRoutes(
 	excelBridge *services.ExcelBridge,
 	docService *documents.DocumentService,
 	signalRBridge *handlers.SignalRBridge,
+	auditService *audit.Service,
+	ssoHandler *handlers.SSOHandler,
 	logger *logrus.Logger,
 ) {
 	// Initialize handlers
@@ -32,12 +37,21 @@ func RegisterAPIRoutes(
 	documentHandler := handlers.NewDocumentHandler(docService, logger)
 	chatHandler := handlers.NewChatHandler(excelBridge, docService, logger)
 	excelHandler := handlers.NewExcelHandler(excelBridge, logger)
-	modelsHandler := handlers.NewModelsHandler(logger)
-	auditHandler := handlers.NewAuditHandler(repos, logger)
+	templateService := templates.NewService(logger, repos.Templates, repos.Workspaces)
+	if excelBridge != nil {
+		templateService.SetWorkbookSource(excelBridge)
+	}
+	modelsHandler := handlers.NewModelsHandler(templateService, logger)
+	auditHandler := handlers.NewAuditHandler(repos, auditService, logger)
+	protectedRangeHandler := handlers.NewProtectedRangeHandler(services.NewProtectedRangeStore(repos.ProtectedRanges, repos.Workspaces), logger)
+	cellLineageHandler := handlers.NewCellLineageHandler(services.NewCellLineageStore(repos.CellLineage), logger)
 	
 	// Initialize diff service and handler
 	diffService := diff.NewService()
 	diffHandler := handlers.NewDiffHandler(diffService, signalRBridge, logger)
+	if excelBridge != nil {
+		diffHandler.SetReindexer(excelBridge)
+	}
 	
 	// Initialize middleware
 	authMiddleware := middleware.NewAuthMiddleware(jwtManager, repos.APIKeys, logger)
@@ -56,6 +70,10 @@ func RegisterAPIRoutes(
 	authRoutes.HandleFunc("/login", authHandler.Login).Methods("POST")
 	authRoutes.HandleFunc("/register", authHandler.Register).Methods("POST")
 	authRoutes.HandleFunc("/refresh", authHandler.RefreshToken).Methods("POST")
+	if ssoHandler != nil {
+		authRoutes.HandleFunc("/sso/login", ssoHandler.Login).Methods("GET")
+		authRoutes.HandleFunc("/sso/callback", ssoHandler.Callback).Methods("GET")
+	}
 	
 	// Protected routes
 	protected := api.PathPrefix("").Subrouter()
@@ -77,12 +95,14 @@ func RegisterAPIRoutes(
 	apiKeyRoutes.HandleFunc("", apiKeyHandler.Create).Methods("POST")
 	apiKeyRoutes.HandleFunc("", apiKeyHandler.List).Methods("GET")
 	apiKeyRoutes.HandleFunc("/{id}", apiKeyHandler.Delete).Methods("DELETE")
+	apiKeyRoutes.HandleFunc("/{id}/rotate", apiKeyHandler.Rotate).Methods("POST")
 	
 	// Document routes (protected)
 	docRoutes := protected.PathPrefix("/documents").Subrouter()
 	docRoutes.HandleFunc("/edgar", documentHandler.UploadEDGARDocument).Methods("POST")
 	docRoutes.HandleFunc("/search", documentHandler.SearchDocuments).Methods("POST")
 	docRoutes.HandleFunc("/context", documentHandler.GetDocumentContext).Methods("GET")
+	docRoutes.HandleFunc("/facts", documentHandler.SearchFacts).Methods("GET")
 	docRoutes.HandleFunc("", documentHandler.ListDocuments).Methods("GET")
 	docRoutes.HandleFunc("/{id}", documentHandler.DeleteDocument).Methods("DELETE")
 	
@@ -92,6 +112,19 @@ func RegisterAPIRoutes(
 	chatRoutes.HandleFunc("/suggestions", chatHandler.GetChatSuggestions).Methods("GET")
 	chatRoutes.HandleFunc("/suggest", chatHandler.SuggestFormula).Methods("POST")
 	
+	// Four-eyes review of queued AI changes (protected)
+	if excelBridge != nil {
+		reviewService := review.NewService(logger, excelBridge.GetQueuedOperationRegistry(), excelBridge.SessionOwner, repos.Reviews, repos.Workspaces, repos.AuditLogs)
+		if signalRBridge != nil {
+			reviewService.SetNotifier(signalRBridge, excelBridge.UserSessions)
+		}
+		reviewHandler := handlers.NewReviewHandler(reviewService, logger)
+		chatRoutes.HandleFunc("/reviews", reviewHandler.Submit).Methods("POST")
+		chatRoutes.HandleFunc("/reviews", reviewHandler.ListAssigned).Methods("GET")
+		chatRoutes.HandleFunc("/reviews/{id}", reviewHandler.Get).Methods("GET")
+		chatRoutes.HandleFunc("/reviews/{id}/operations/{operationId}/decision", reviewHandler.Decide).Methods("POST")
+	}
+	
 	// Excel routes (protected)
 	excelRoutes := protected.PathPrefix("/excel").Subrouter()
 	excelRoutes.HandleFunc("/context", excelHandler.SendContext).Methods("POST")
@@ -101,9 +134,40 @@ func RegisterAPIRoutes(
 	modelsRoutes := protected.PathPrefix("/models").Subrouter()
 	modelsRoutes.HandleFunc("/templates", modelsHandler.GetTemplates).Methods("GET")
 	modelsRoutes.HandleFunc("/template", modelsHandler.GetTemplate).Methods("GET")
+	modelsRoutes.HandleFunc("/templates", modelsHandler.CreateTemplate).Methods("POST")
+	modelsRoutes.HandleFunc("/templates/schema", modelsHandler.GetTemplateSchema).Methods("GET")
+	modelsRoutes.HandleFunc("/templates/capture", modelsHandler.CaptureTemplate).Methods("POST")
+	modelsRoutes.HandleFunc("/templates/{id}", modelsHandler.GetTemplate).Methods("GET")
+	modelsRoutes.HandleFunc("/templates/{id}", modelsHandler.UpdateTemplate).Methods("PUT")
+	modelsRoutes.HandleFunc("/templates/{id}/versions", modelsHandler.ListTemplateVersions).Methods("GET")
+	modelsRoutes.HandleFunc("/templates/{id}/versions", modelsHandler.CreateTemplateVersion).Methods("POST")
+	modelsRoutes.HandleFunc("/templates/{id}/deprecate", modelsHandler.DeprecateTemplate).Methods("POST")
+	
+	// Workbook protected range routes (protected)
+	workbookRoutes := protected.PathPrefix("/workbooks/{workbook}").Subrouter()
+	workbookRoutes.HandleFunc("/protected-ranges", protectedRangeHandler.List).Methods("GET")
+	workbookRoutes.HandleFunc("/protected-ranges", protectedRangeHandler.Create).Methods("POST")
+	workbookRoutes.HandleFunc("/protected-ranges/{id}", protectedRangeHandler.Delete).Methods("DELETE")
+	workbookRoutes.HandleFunc("/lineage", cellLineageHandler.CellHistory).Methods("GET")
 	
 	// Audit routes (protected)
 	auditRoutes := protected.PathPrefix("/audit").Subrouter()
 	auditRoutes.HandleFunc("/log", auditHandler.LogAction).Methods("POST")
 	auditRoutes.HandleFunc("/logs", auditHandler.GetLogs).Methods("GET")
+	
+	// MCP server for other agent clients (protected)
+	if excelBridge != nil && excelBridge.GetToolExecutor() != nil {
+		protected.Handle("/mcp", handlers.NewMCPHandler(excelBridge, logger)).Methods("POST", "GET", "DELETE")
+	}
+	
+	// Admin routes (protected)
+	adminRoutes := protected.PathPrefix("/admin").Subrouter()
+	adminRoutes.Use(authMiddleware.RequireRole("admin"))
+	adminRoutes.HandleFunc("/audit/verify", auditHandler.Verify).Methods("GET")
+	adminRoutes.HandleFunc("/audit/export", auditHandler.Export).Methods("GET")
+	adminRoutes.HandleFunc("/audit/retention", auditHandler.ApplyRetention).Methods("POST")
+	if excelBridge != nil {
+		memoryHandler := handlers.NewMemoryHandler(nil, excelBridge, logger)
+		adminRoutes.HandleFunc("/memory/namespaces/{tenant}", memoryHandler.PurgeNamespace).Methods("DELETE")
+	}
 }
\ No newline at end of file
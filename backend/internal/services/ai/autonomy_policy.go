@@ -0,0 +1,429 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gridmate/backend/internal/services/spreadsheet"
+	"github.com/rs/zerolog/log"
+)
+
+// Autonomy modes chosen by the user in the add-in
+const (
+	AutonomyAsk          = "ask"
+	AutonomyReadOnly     = "read-only"
+	AutonomyAgentDefault = "agent-default"
+	AutonomyFull         = "full-autonomy"
+)
+
+// PolicyOutcome is what the autonomy policy decided for a write
+type PolicyOutcome string
+
+const (
+	PolicyAutoApply       PolicyOutcome = "auto_apply"
+	PolicyRequireApproval PolicyOutcome = "require_approval"
+	PolicyDeny            PolicyOutcome = "deny"
+)
+
+// severity orders outcomes from least to most restrictive
+func (o PolicyOutcome) severity() int {
+	switch o {
+	case PolicyDeny:
+		return 2
+	case PolicyRequireApproval:
+		return 1
+	}
+	return 0
+}
+
+// AutonomyPolicy is a workspace's rules for AI writes. Rules can only make
+// the user's autonomy mode stricter.
+type AutonomyPolicy struct {
+	// AutoApplyTools are the write tools that may apply without approval.
+	// Empty allows every tool the autonomy mode would apply.
+	AutoApplyTools []string `json:"auto_apply_tools,omitempty"`
+
+	// MaxCellsPerWrite sends larger writes for approval; 0 is no limit
+	MaxCellsPerWrite int `json:"max_cells_per_write,omitempty"`
+
+	// ApprovalRanges always need approval. Entries are sheet names or
+	// ranges such as "Assumptions!B2:F20".
+	ApprovalRanges []string `json:"approval_ranges,omitempty"`
+
+	// ProtectFormulas rejects writes that replace formulas with constants
+	ProtectFormulas bool `json:"protect_formulas,omitempty"`
+
+	// ConfirmInputs sends writes to input cells for approval
+	ConfirmInputs bool `json:"confirm_inputs,omitempty"`
+}
+
+// PolicyConfig holds the default policy and overrides keyed by workspace ID
+type PolicyConfig struct {
+	Default    AutonomyPolicy            `json:"default"`
+	Workspaces map[string]AutonomyPolicy `json:"workspaces,omitempty"`
+}
+
+// LoadPolicyConfig reads a JSON policy configuration from path
+func LoadPolicyConfig(path string) (PolicyConfig, error) {
+	var cfg PolicyConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read autonomy policy: %w", err)
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("failed to parse autonomy policy: %w", err)
+	}
+	for workspace, policy := range cfg.Workspaces {
+		if _, err := policy.approvalRanges(); err != nil {
+			return cfg, fmt.Errorf("workspace %s: %w", workspace, err)
+		}
+	}
+	if _, err := cfg.Default.approvalRanges(); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+func (p AutonomyPolicy) approvalRanges() ([]rangeAddress, error) {
+	ranges := make([]rangeAddress, 0, len(p.ApprovalRanges))
+	for _, entry := range p.ApprovalRanges {
+		if !strings.Contains(entry, "!") && !a1Reference.MatchString(strings.ToUpper(strings.ReplaceAll(entry, "$", ""))) {
+			// Not an A1 reference, so a sheet name
+			ranges = append(ranges, wholeSheet(unquoteSheetName(entry)))
+			continue
+		}
+		r, err := parseRangeAddress(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid approval range %q: %w", entry, err)
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+// PolicyReason is one rule's contribution to a decision
+type PolicyReason struct {
+	Rule    string        `json:"rule"`
+	Outcome PolicyOutcome `json:"outcome"`
+	Message string        `json:"message"`
+}
+
+// PolicyDecision is the outcome of evaluating a write against the policy,
+// with the reasons that led to it
+type PolicyDecision struct {
+	Outcome      PolicyOutcome  `json:"outcome"`
+	Tool         string         `json:"tool"`
+	ToolID       string         `json:"tool_id"`
+	SessionID    string         `json:"session_id"`
+	User         string         `json:"user,omitempty"`
+	Workspace    string         `json:"workspace,omitempty"`
+	AutonomyMode string         `json:"autonomy_mode"`
+	Range        string         `json:"range,omitempty"`
+	Reasons      []PolicyReason `json:"reasons"`
+	DecidedAt    time.Time      `json:"decided_at"`
+}
+
+// Explain describes the decision in a sentence per deciding rule
+func (d *PolicyDecision) Explain() string {
+	var messages []string
+	for _, reason := range d.Reasons {
+		if reason.Outcome == d.Outcome {
+			messages = append(messages, reason.Message)
+		}
+	}
+	return strings.Join(messages, " ")
+}
+
+func (d *PolicyDecision) add(rule string, outcome PolicyOutcome, format string, args ...interface{}) {
+	d.Reasons = append(d.Reasons, PolicyReason{Rule: rule, Outcome: outcome, Message: fmt.Sprintf(format, args...)})
+	if outcome.severity() > d.Outcome.severity() {
+		d.Outcome = outcome
+	}
+}
+
+// PolicyAuditor records policy decisions
+type PolicyAuditor interface {
+	RecordPolicyDecision(ctx context.Context, decision *PolicyDecision) error
+}
+
+// PolicyEngine decides whether AI writes apply directly, wait for the
+// user's approval or are refused
+type PolicyEngine struct {
+	config     PolicyConfig
+	auditor    PolicyAuditor
+	classifier *spreadsheet.CellClassifier
+}
+
+// NewPolicyEngine creates an engine for cfg. auditor may be nil.
+func NewPolicyEngine(cfg PolicyConfig, auditor PolicyAuditor) *PolicyEngine {
+	return &PolicyEngine{
+		config:     cfg,
+		auditor:    auditor,
+		classifier: spreadsheet.NewCellClassifier(),
+	}
+}
+
+// Policy returns the policy that applies to a workspace
+func (p *PolicyEngine) Policy(workspace string) AutonomyPolicy {
+	if policy, ok := p.config.Workspaces[workspace]; ok {
+		return policy
+	}
+	return p.config.Default
+}
+
+// maxInspectedCells caps the cells read to check formula and input rules
+const maxInspectedCells = 10000
+
+// writeTargetKeys are the inputs that name the cells a write tool changes
+var writeTargetKeys = []string{"range", "data_range", "target_range", "position"}
+
+// Evaluate decides how a write tool call may proceed and records the
+// decision. queueable reports whether the add-in can preview the tool's
+// changes and queue them for approval. When workspaces have policies of
+// their own, writes of sessions whose workspace is unknown need approval.
+func (p *PolicyEngine) Evaluate(ctx context.Context, te *ToolExecutor, sessionID string, call ToolCall, autonomyMode string, queueable bool) *PolicyDecision {
+	decision := &PolicyDecision{
+		Outcome:      PolicyAutoApply,
+		Tool:         call.Name,
+		ToolID:       call.ID,
+		SessionID:    sessionID,
+		AutonomyMode: autonomyMode,
+		DecidedAt:    time.Now(),
+	}
+
+	workspaceErr := fmt.Errorf("the session is not known")
+	if te != nil {
+		if te.excelBridge != nil {
+			if session := te.excelBridge.GetSession(sessionID); session != nil {
+				decision.User = session.UserID
+			}
+		}
+		decision.Workspace, workspaceErr = te.SessionWorkspace(ctx, sessionID)
+	}
+	policy := p.config.Default
+	if workspaceErr == nil {
+		policy = p.Policy(decision.Workspace)
+	}
+
+	p.evaluate(ctx, te, sessionID, call, policy, queueable, decision)
+	if workspaceErr != nil && len(p.config.Workspaces) > 0 {
+		decision.add("workspace", PolicyRequireApproval,
+			"The session's workspace policy could not be applied (%v), so changes need approval.", workspaceErr)
+	}
+
+	if decision.Outcome == PolicyRequireApproval && !queueable {
+		decision.add("approval_unavailable", PolicyDeny,
+			"%s cannot be previewed for approval, so the user has to make this change.", call.Name)
+	}
+
+	log.Info().
+		Str("tool", call.Name).
+		Str("tool_id", call.ID).
+		Str("session", sessionID).
+		Str("workspace", decision.Workspace).
+		Str("outcome", string(decision.Outcome)).
+		Str("reason", decision.Explain()).
+		Msg("Autonomy policy decision")
+
+	if p.auditor != nil {
+		if err := p.auditor.RecordPolicyDecision(ctx, decision); err != nil {
+			log.Error().Err(err).Str("tool_id", call.ID).Msg("Failed to record autonomy policy decision")
+		}
+	}
+	return decision
+}
+
+func (p *PolicyEngine) evaluate(ctx context.Context, te *ToolExecutor, sessionID string, call ToolCall, policy AutonomyPolicy, queueable bool, decision *PolicyDecision) {
+	switch decision.AutonomyMode {
+	case AutonomyAsk, AutonomyReadOnly:
+		decision.add("autonomy_mode", PolicyDeny, "Writes are not allowed in %s mode.", decision.AutonomyMode)
+		return
+	case AutonomyAgentDefault, "default":
+		if queueable {
+			decision.add("autonomy_mode", PolicyRequireApproval, "Changes need approval in %s mode.", AutonomyAgentDefault)
+		} else {
+			decision.add("autonomy_mode", PolicyAutoApply, "%s cannot be previewed and applies directly in %s mode.", call.Name, AutonomyAgentDefault)
+		}
+	default:
+		decision.add("autonomy_mode", PolicyAutoApply, "Changes apply directly in %s mode.", modeName(decision.AutonomyMode))
+	}
+
+	if decision.Outcome == PolicyAutoApply && len(policy.AutoApplyTools) > 0 && !containsName(policy.AutoApplyTools, call.Name) {
+		decision.add("auto_apply_tools", PolicyRequireApproval, "%s is not allowed to apply without approval in this workspace.", call.Name)
+	}
+
+	var target rangeAddress
+	targetFound := false
+	for _, key := range writeTargetKeys {
+		if addr, ok := call.Input[key].(string); ok && addr != "" {
+			if parsed, err := parseRangeAddress(addr); err == nil {
+				target, targetFound = parsed, true
+				decision.Range = addr
+			}
+			break
+		}
+	}
+	if !targetFound {
+		return
+	}
+
+	if policy.MaxCellsPerWrite > 0 && target.Cells() > policy.MaxCellsPerWrite {
+		decision.add("max_cells_per_write", PolicyRequireApproval,
+			"%s changes %d cells, more than the %d allowed without approval.", target, target.Cells(), policy.MaxCellsPerWrite)
+	}
+
+	approvalRanges, _ := policy.approvalRanges()
+	for i, protected := range approvalRanges {
+		if target.Overlaps(protected) {
+			decision.add("approval_ranges", PolicyRequireApproval, "%s overlaps %s, which always needs approval.", target, policy.ApprovalRanges[i])
+			break
+		}
+	}
+
+	// The remaining rules look at the cells being overwritten
+	_, writesValues := call.Input["values"]
+	_, writesFormula := call.Input["formula"]
+	if !(policy.ProtectFormulas && writesValues) && !(policy.ConfirmInputs && (writesValues || writesFormula)) {
+		return
+	}
+	if te == nil || te.excelBridge == nil || target.Cells() > maxInspectedCells {
+		decision.add("cell_inspection", PolicyRequireApproval, "The cells in %s could not be inspected.", target)
+		return
+	}
+	current, err := te.excelBridge.ReadRange(ctx, sessionID, decision.Range, true, policy.ConfirmInputs)
+	if err != nil {
+		decision.add("cell_inspection", PolicyRequireApproval, "The cells in %s could not be inspected: %v.", target, err)
+		return
+	}
+
+	if policy.ProtectFormulas && writesValues {
+		if cells := overwrittenFormulas(target, current, call.Input["values"]); len(cells) > 0 {
+			decision.add("protect_formulas", PolicyDeny, "%s would replace formulas with constants in %s.", call.Name, summarizeCells(cells))
+		}
+	}
+	if policy.ConfirmInputs {
+		if cells := p.inputCells(target, current); len(cells) > 0 {
+			decision.add("confirm_inputs", PolicyRequireApproval, "%s would change input cells %s.", call.Name, summarizeCells(cells))
+		}
+	}
+}
+
+// overwrittenFormulas returns the cells whose formulas a write_range call
+// would replace with constants
+func overwrittenFormulas(target rangeAddress, current *RangeData, values interface{}) []string {
+	rows := toValueRows(values)
+	if len(rows) == 0 {
+		return nil
+	}
+	var cells []string
+	for r, row := range current.Formulas {
+		for c, formula := range row {
+			if f, _ := formula.(string); !strings.HasPrefix(f, "=") {
+				continue
+			}
+			newValue := valueAt(rows, r, c)
+			if s, ok := newValue.(string); ok && strings.HasPrefix(s, "=") {
+				continue
+			}
+			cells = append(cells, target.CellAddress(r, c))
+		}
+	}
+	return cells
+}
+
+// inputRegionSheet matches sheets that by convention hold model inputs
+var inputRegionSheet = regexp.MustCompile(`(?i)input|assumption|driver`)
+
+// inputCells returns the cells in current that the classifier sees as
+// inputs. Numbers are inputs on input sheets or in blue font, the usual
+// convention for hard-coded inputs in financial models.
+func (p *PolicyEngine) inputCells(target rangeAddress, current *RangeData) []string {
+	inputSheet := target.Sheet != "" && inputRegionSheet.MatchString(target.Sheet)
+	var cells []string
+	for r, row := range current.Values {
+		for c, value := range row {
+			formula := ""
+			if r < len(current.Formulas) && c < len(current.Formulas[r]) {
+				formula, _ = current.Formulas[r][c].(string)
+			}
+			inputRegion := inputSheet
+			if r < len(current.Formatting) && c < len(current.Formatting[r]) {
+				if font := current.Formatting[r][c].Font; font != nil && isInputFontColor(font.Color) {
+					inputRegion = true
+				}
+			}
+			classification := p.classifier.ClassifyCell(value, formula, r, c, spreadsheet.CellContext{IsInputRegion: inputRegion})
+			if classification.Purpose == spreadsheet.PurposeInput {
+				cells = append(cells, target.CellAddress(r, c))
+			}
+		}
+	}
+	return cells
+}
+
+func isInputFontColor(color string) bool {
+	switch strings.ToUpper(strings.TrimSpace(color)) {
+	case "#0000FF", "#0070C0", "#0000CC", "BLUE":
+		return true
+	}
+	return false
+}
+
+// toValueRows converts a values input to rows, as write_range accepts
+func toValueRows(values interface{}) [][]interface{} {
+	switch v := values.(type) {
+	case [][]interface{}:
+		return v
+	case []interface{}:
+		rows := make([][]interface{}, 0, len(v))
+		for _, row := range v {
+			if cells, ok := row.([]interface{}); ok {
+				rows = append(rows, cells)
+			}
+		}
+		return rows
+	}
+	return nil
+}
+
+// valueAt returns the value written to a cell. A single value fills the
+// whole range.
+func valueAt(rows [][]interface{}, r, c int) interface{} {
+	if len(rows) == 1 && len(rows[0]) == 1 {
+		return rows[0][0]
+	}
+	if r < len(rows) && c < len(rows[r]) {
+		return rows[r][c]
+	}
+	return nil
+}
+
+// summarizeCells lists the first few cells and counts the rest
+func summarizeCells(cells []string) string {
+	const shown = 5
+	if len(cells) <= shown {
+		return strings.Join(cells, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(cells[:shown], ", "), len(cells)-shown)
+}
+
+func modeName(mode string) string {
+	if mode == "" {
+		return AutonomyFull
+	}
+	return mode
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,221 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/gridmate/backend/internal/memory"
+)
+
+type policyTestBridge struct {
+	ExcelBridge
+	current *RangeData
+	user    string
+}
+
+func (b *policyTestBridge) ReadRange(ctx context.Context, sessionID, rangeAddr string, includeFormulas, includeFormatting bool) (*RangeData, error) {
+	return b.current, nil
+}
+
+func (b *policyTestBridge) GetSession(sessionID string) *Session {
+	return &Session{UserID: b.user, MemoryNamespace: memory.SessionNamespace(b.user, sessionID)}
+}
+
+type staticWorkspaces map[string]string
+
+func (w staticWorkspaces) UserWorkspace(ctx context.Context, userID string) (string, error) {
+	if workspace, ok := w[userID]; ok {
+		return workspace, nil
+	}
+	return "", fmt.Errorf("user %s belongs to no workspace", userID)
+}
+
+type recordingAuditor struct {
+	decisions []*PolicyDecision
+}
+
+func (a *recordingAuditor) RecordPolicyDecision(ctx context.Context, d *PolicyDecision) error {
+	a.decisions = append(a.decisions, d)
+	return nil
+}
+
+func TestPolicyEngine_Evaluate(t *testing.T) {
+	formulaCell := &RangeData{Values: [][]interface{}{{10.0}}, Formulas: [][]interface{}{{"=A1*2"}}}
+	blueInput := &RangeData{
+		Values:     [][]interface{}{{0.05}},
+		Formulas:   [][]interface{}{{""}},
+		Formatting: [][]CellFormat{{{Font: &FontStyle{Color: "#0000FF"}}}},
+	}
+
+	tests := []struct {
+		name      string
+		policy    AutonomyPolicy
+		user      string
+		mode      string
+		tool      string
+		input     map[string]interface{}
+		current   *RangeData
+		queueable bool
+		want      PolicyOutcome
+		rule      string
+	}{
+		{
+			name: "read-only mode refuses writes",
+			mode: AutonomyReadOnly, tool: "write_range", queueable: true,
+			input: map[string]interface{}{"range": "A1", "values": []interface{}{[]interface{}{1.0}}},
+			want:  PolicyDeny, rule: "autonomy_mode",
+		},
+		{
+			name: "agent-default queues previewable writes",
+			mode: AutonomyAgentDefault, tool: "write_range", queueable: true,
+			input: map[string]interface{}{"range": "A1", "values": []interface{}{[]interface{}{1.0}}},
+			want:  PolicyRequireApproval, rule: "autonomy_mode",
+		},
+		{
+			name: "agent-default applies tools that cannot be previewed",
+			mode: AutonomyAgentDefault, tool: "insert_rows_columns",
+			input: map[string]interface{}{"position": "A5", "count": 1.0, "type": "rows"},
+			want:  PolicyAutoApply,
+		},
+		{
+			name:   "tools outside the auto-apply list need approval",
+			policy: AutonomyPolicy{AutoApplyTools: []string{"format_range"}},
+			mode:   AutonomyFull, tool: "write_range", queueable: true,
+			input: map[string]interface{}{"range": "A1", "values": []interface{}{[]interface{}{1.0}}},
+			want:  PolicyRequireApproval, rule: "auto_apply_tools",
+		},
+		{
+			name:   "large writes need approval",
+			policy: AutonomyPolicy{MaxCellsPerWrite: 10},
+			mode:   AutonomyFull, tool: "format_range", queueable: true,
+			input: map[string]interface{}{"range": "A1:C5"},
+			want:  PolicyRequireApproval, rule: "max_cells_per_write",
+		},
+		{
+			name:   "approval sheets need approval",
+			policy: AutonomyPolicy{ApprovalRanges: []string{"Assumptions"}},
+			mode:   AutonomyFull, tool: "format_range", queueable: true,
+			input: map[string]interface{}{"range": "assumptions!B2"},
+			want:  PolicyRequireApproval, rule: "approval_ranges",
+		},
+		{
+			name:   "other sheets apply directly",
+			policy: AutonomyPolicy{ApprovalRanges: []string{"Assumptions", "Model!A1:B2"}},
+			mode:   AutonomyFull, tool: "format_range", queueable: true,
+			input: map[string]interface{}{"range": "Model!C3"},
+			want:  PolicyAutoApply,
+		},
+		{
+			name:   "approval that cannot be previewed is refused",
+			policy: AutonomyPolicy{ApprovalRanges: []string{"B:B"}},
+			mode:   AutonomyFull, tool: "insert_rows_columns",
+			input: map[string]interface{}{"position": "B7", "count": 1.0, "type": "rows"},
+			want:  PolicyDeny, rule: "approval_unavailable",
+		},
+		{
+			name:   "constants never replace formulas",
+			policy: AutonomyPolicy{ProtectFormulas: true},
+			mode:   AutonomyFull, tool: "write_range", queueable: true, current: formulaCell,
+			input: map[string]interface{}{"range": "B2", "values": []interface{}{[]interface{}{20.0}}},
+			want:  PolicyDeny, rule: "protect_formulas",
+		},
+		{
+			name:   "formulas may replace formulas",
+			policy: AutonomyPolicy{ProtectFormulas: true},
+			mode:   AutonomyFull, tool: "write_range", queueable: true, current: formulaCell,
+			input: map[string]interface{}{"range": "B2", "values": []interface{}{[]interface{}{"=A1*3"}}},
+			want:  PolicyAutoApply,
+		},
+		{
+			name:   "input cells need confirmation",
+			policy: AutonomyPolicy{ConfirmInputs: true},
+			mode:   AutonomyFull, tool: "apply_formula", queueable: true, current: blueInput,
+			input: map[string]interface{}{"range": "C4", "formula": "=C3*1.1"},
+			want:  PolicyRequireApproval, rule: "confirm_inputs",
+		},
+		{
+			name:   "workspace policies override the default",
+			policy: AutonomyPolicy{MaxCellsPerWrite: 1},
+			user:   "acme-analyst",
+			mode:   AutonomyFull, tool: "format_range", queueable: true,
+			input: map[string]interface{}{"range": "A1:C5"},
+			want:  PolicyAutoApply,
+		},
+		{
+			name: "sessions without a workspace need approval",
+			user: "signalr-user",
+			mode: AutonomyFull, tool: "format_range", queueable: true,
+			input: map[string]interface{}{"range": "A1"},
+			want:  PolicyRequireApproval, rule: "workspace",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auditor := &recordingAuditor{}
+			engine := NewPolicyEngine(PolicyConfig{
+				Default:    tt.policy,
+				Workspaces: map[string]AutonomyPolicy{"acme": {}},
+			}, auditor)
+			user := tt.user
+			if user == "" {
+				user = "analyst"
+			}
+			te := &ToolExecutor{
+				excelBridge: &policyTestBridge{current: tt.current, user: user},
+				workspaces:  staticWorkspaces{"analyst": "other", "acme-analyst": "acme"},
+			}
+
+			call := ToolCall{ID: "toolu_1", Name: tt.tool, Input: tt.input}
+			decision := engine.Evaluate(context.Background(), te, "s1", call, tt.mode, tt.queueable)
+
+			if decision.Outcome != tt.want {
+				t.Fatalf("outcome = %s, want %s (%+v)", decision.Outcome, tt.want, decision.Reasons)
+			}
+			if tt.rule != "" {
+				found := false
+				for _, reason := range decision.Reasons {
+					found = found || (reason.Rule == tt.rule && reason.Outcome == tt.want)
+				}
+				if !found {
+					t.Errorf("reasons %+v do not include %s", decision.Reasons, tt.rule)
+				}
+			}
+			if decision.Explain() == "" {
+				t.Error("decision has no explanation")
+			}
+			if len(auditor.decisions) != 1 || auditor.decisions[0] != decision {
+				t.Errorf("audited decisions = %d, want 1", len(auditor.decisions))
+			}
+		})
+	}
+}
+
+func TestParseRangeAddress(t *testing.T) {
+	tests := []struct {
+		addr  string
+		want  rangeAddress
+		cells int
+	}{
+		{"B2", rangeAddress{StartRow: 1, StartCol: 1, EndRow: 1, EndCol: 1}, 1},
+		{"Sheet1!$A$1:C10", rangeAddress{Sheet: "Sheet1", EndRow: 9, EndCol: 2}, 30},
+		{"'Q1 ''Plan'''!C3:A1", rangeAddress{Sheet: "Q1 'Plan'", EndRow: 2, EndCol: 2}, 9},
+		{"B:C", rangeAddress{StartCol: 1, EndRow: maxSheetRows - 1, EndCol: 2}, 2 * maxSheetRows},
+		{"3:4", rangeAddress{StartRow: 2, EndRow: 3, EndCol: maxSheetCols - 1}, 2 * maxSheetCols},
+	}
+	for _, tt := range tests {
+		got, err := parseRangeAddress(tt.addr)
+		if err != nil {
+			t.Fatalf("parseRangeAddress(%q): %v", tt.addr, err)
+		}
+		if got != tt.want || got.Cells() != tt.cells {
+			t.Errorf("parseRangeAddress(%q) = %+v (%d cells), want %+v (%d cells)", tt.addr, got, got.Cells(), tt.want, tt.cells)
+		}
+	}
+	for _, addr := range []string{"", "Revenue", "A", "1"} {
+		if _, err := parseRangeAddress(addr); err == nil {
+			t.Errorf("parseRangeAddress(%q) should fail", addr)
+		}
+	}
+}
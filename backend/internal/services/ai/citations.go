@@ -0,0 +1,267 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/gridmate/backend/internal/memory"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	// citationPattern matches inline citations such as [S1] or [S1, S3]
+	citationPattern        = regexp.MustCompile(`\[\s*S\d+(?:\s*[,;]\s*S\d+)*\s*\]`)
+	citationIDPattern      = regexp.MustCompile(`S\d+`)
+	droppedCitationPattern = regexp.MustCompile(`[ \t]*\x00`)
+	// partialCitationPattern matches text that may still become a citation
+	partialCitationPattern = regexp.MustCompile(`\[[\sS\d,;]*$`)
+)
+
+// maxPartialCitation bounds how much streamed text is held back while it
+// may still become a citation
+const maxPartialCitation = 64
+
+// citationExcerptLength caps the excerpt returned with each citation
+const citationExcerptLength = 280
+
+// Citation is a structured reference to a memory chunk the model was given
+// and cited in its answer
+type Citation struct {
+	ID           string `json:"id"`
+	Source       string `json:"source"`
+	ChunkID      string `json:"chunk_id"`
+	Reference    string `json:"reference"`
+	DocumentName string `json:"document_name,omitempty"`
+	PageNumber   int    `json:"page_number,omitempty"`
+	Section      string `json:"section,omitempty"`
+	SheetName    string `json:"sheet_name,omitempty"`
+	CellRange    string `json:"cell_range,omitempty"`
+	MessageID    string `json:"message_id,omitempty"`
+	Turn         int    `json:"turn,omitempty"`
+	Excerpt      string `json:"excerpt,omitempty"`
+}
+
+// CitationSet assigns citation IDs to the chunks provided to the model
+// while answering one message. The same chunk keeps its ID across tool
+// rounds.
+type CitationSet struct {
+	mu      sync.Mutex
+	byChunk map[string]string
+	byID    map[string]Citation
+}
+
+// NewCitationSet creates an empty citation set
+func NewCitationSet() *CitationSet {
+	return &CitationSet{
+		byChunk: make(map[string]string),
+		byID:    make(map[string]Citation),
+	}
+}
+
+// Add registers a chunk and returns its citation ID
+func (cs *CitationSet) Add(chunk memory.Chunk) string {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if id, ok := cs.byChunk[chunk.ID]; ok {
+		return id
+	}
+
+	id := fmt.Sprintf("S%d", len(cs.byID)+1)
+	meta := chunk.Metadata
+	cs.byChunk[chunk.ID] = id
+	cs.byID[id] = Citation{
+		ID:           id,
+		Source:       meta.Source,
+		ChunkID:      chunk.ID,
+		Reference:    formatChunkReference(chunk),
+		DocumentName: meta.DocumentName,
+		PageNumber:   meta.PageNumber,
+		Section:      meta.Section,
+		SheetName:    meta.SheetName,
+		CellRange:    meta.CellRange,
+		MessageID:    meta.MessageID,
+		Turn:         meta.Turn,
+		Excerpt:      truncateExcerpt(chunk.Content, citationExcerptLength),
+	}
+	return id
+}
+
+// Get returns the citation with the given ID
+func (cs *CitationSet) Get(id string) (Citation, bool) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	citation, ok := cs.byID[id]
+	return citation, ok
+}
+
+// All returns every citation in the order the chunks were provided
+func (cs *CitationSet) All() []Citation {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	all := make([]Citation, 0, len(cs.byID))
+	for i := 1; i <= len(cs.byID); i++ {
+		all = append(all, cs.byID[fmt.Sprintf("S%d", i)])
+	}
+	return all
+}
+
+// Len returns the number of chunks provided so far
+func (cs *CitationSet) Len() int {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return len(cs.byID)
+}
+
+// Resolve parses the citations in text and validates them against the
+// provided chunks. Citations the model invented are removed from the text
+// and returned as dropped; the rest are returned once each, in order of
+// first use.
+func (cs *CitationSet) Resolve(text string) (string, []Citation, []string) {
+	var citations []Citation
+	var dropped []string
+	seen := make(map[string]bool)
+
+	resolved := citationPattern.ReplaceAllStringFunc(text, func(marker string) string {
+		var valid []string
+		for _, id := range citationIDPattern.FindAllString(marker, -1) {
+			citation, ok := cs.Get(id)
+			if !ok {
+				dropped = append(dropped, id)
+				continue
+			}
+			valid = append(valid, id)
+			if !seen[id] {
+				seen[id] = true
+				citations = append(citations, citation)
+			}
+		}
+		if len(valid) == 0 {
+			return "\x00"
+		}
+		return "[" + strings.Join(valid, ", ") + "]"
+	})
+
+	// Drop invalid markers along with the space before them
+	return droppedCitationPattern.ReplaceAllString(resolved, ""), citations, dropped
+}
+
+// resolveCitations validates the citations of a final answer
+func resolveCitations(text string, set *CitationSet, sessionID string) (string, []Citation) {
+	resolved, citations, dropped := set.Resolve(text)
+	if len(dropped) > 0 {
+		log.Warn().
+			Str("session_id", sessionID).
+			Strs("dropped", dropped).
+			Int("provided", set.Len()).
+			Msg("Removed citations of chunks that were not provided")
+	}
+	if len(citations) > 0 {
+		log.Debug().
+			Str("session_id", sessionID).
+			Int("cited", len(citations)).
+			Int("provided", set.Len()).
+			Msg("Resolved answer citations")
+	}
+	return resolved, citations
+}
+
+// citationStream validates the citations of a streamed answer. Text that
+// may still become a citation is held back until it is complete, so
+// invented citations are removed before they reach the client.
+type citationStream struct {
+	set     *CitationSet
+	pending string
+	text    strings.Builder
+}
+
+func newCitationStream(set *CitationSet) *citationStream {
+	return &citationStream{set: set}
+}
+
+// Write takes the next piece of the answer and returns the text that can
+// be sent
+func (cs *citationStream) Write(delta string) string {
+	cs.text.WriteString(delta)
+	cs.pending += delta
+
+	// Hold back a possible citation along with the space before it, which
+	// is removed with the citation if it turns out to be invented
+	cut := len(cs.pending)
+	if loc := partialCitationPattern.FindStringIndex(cs.pending); loc != nil && loc[1]-loc[0] <= maxPartialCitation {
+		cut = loc[0]
+	}
+	cut = len(strings.TrimRight(cs.pending[:cut], " \t"))
+
+	resolved, _, _ := cs.set.Resolve(cs.pending[:cut])
+	cs.pending = cs.pending[cut:]
+	return resolved
+}
+
+// Close returns the text still held back and the citations of the whole
+// answer
+func (cs *citationStream) Close(sessionID string) (string, []Citation) {
+	rest, _, _ := cs.set.Resolve(cs.pending)
+	cs.pending = ""
+	_, citations := resolveCitations(cs.text.String(), cs.set, sessionID)
+	return rest, citations
+}
+
+// filter removes invented citations from a streamed text chunk. It
+// returns false when all of the chunk's text is held back.
+func (cs *citationStream) filter(chunk *CompletionChunk) bool {
+	if chunk.Type != "text" {
+		return true
+	}
+	text := chunk.Delta
+	if text == "" {
+		text = chunk.Content
+	}
+	resolved := cs.Write(text)
+	chunk.Delta = resolved
+	if chunk.Content != "" {
+		chunk.Content = resolved
+	}
+	return resolved != ""
+}
+
+// finish adds the answer's citations to its final chunk, returning a text
+// chunk with any text still held back, which is sent first
+func (cs *citationStream) finish(sessionID string, final *CompletionChunk) *CompletionChunk {
+	rest, citations := cs.Close(sessionID)
+	final.Citations = citations
+	if rest == "" {
+		return nil
+	}
+	return &CompletionChunk{ID: final.ID, Type: "text", Delta: rest}
+}
+
+type citationSetKey struct{}
+
+// WithCitations returns a context whose memory searches register their
+// results in set
+func WithCitations(ctx context.Context, set *CitationSet) context.Context {
+	return context.WithValue(ctx, citationSetKey{}, set)
+}
+
+// CitationsFromContext returns the citation set of the current message,
+// or nil when citations are not being collected
+func CitationsFromContext(ctx context.Context) *CitationSet {
+	set, _ := ctx.Value(citationSetKey{}).(*CitationSet)
+	return set
+}
+
+func truncateExcerpt(content string, limit int) string {
+	content = strings.Join(strings.Fields(content), " ")
+	if len(content) <= limit {
+		return content
+	}
+	cut := strings.LastIndex(content[:limit], " ")
+	if cut <= 0 {
+		cut = limit
+	}
+	return content[:cut] + "..."
+}
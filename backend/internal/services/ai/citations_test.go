@@ -0,0 +1,106 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gridmate/backend/internal/memory"
+)
+
+func TestCitationSet_Resolve(t *testing.T) {
+	set := NewCitationSet()
+	report := memory.Chunk{
+		ID:      "q4.pdf_section_2",
+		Content: "Revenue grew 12% year over year.",
+		Metadata: memory.ChunkMetadata{
+			Source:       "document",
+			DocumentName: "q4.pdf",
+			PageNumber:   3,
+			Section:      "Results",
+		},
+	}
+	model := memory.Chunk{
+		ID:      "table_Model_0_0",
+		Content: "Table: Year | Revenue",
+		Metadata: memory.ChunkMetadata{
+			Source:    "spreadsheet",
+			SheetName: "Model",
+			CellRange: "A1:B3",
+		},
+	}
+
+	if id := set.Add(report); id != "S1" {
+		t.Fatalf("Add() = %q, want S1", id)
+	}
+	if id := set.Add(model); id != "S2" {
+		t.Fatalf("Add() = %q, want S2", id)
+	}
+	if id := set.Add(report); id != "S1" {
+		t.Errorf("re-adding a chunk = %q, want S1", id)
+	}
+
+	text, citations, dropped := set.Resolve("Revenue grew 12% [S2; S1]. Margins held [S7]. See the model [S2].")
+	if want := "Revenue grew 12% [S2, S1]. Margins held. See the model [S2]."; text != want {
+		t.Errorf("text = %q, want %q", text, want)
+	}
+	if len(dropped) != 1 || dropped[0] != "S7" {
+		t.Errorf("dropped = %v, want [S7]", dropped)
+	}
+	if len(citations) != 2 || citations[0].ID != "S2" || citations[1].ID != "S1" {
+		t.Fatalf("citations = %+v", citations)
+	}
+	if c := citations[0]; c.SheetName != "Model" || c.CellRange != "A1:B3" || c.Reference != "Model!A1:B3" {
+		t.Errorf("sheet citation = %+v", c)
+	}
+	if c := citations[1]; c.DocumentName != "q4.pdf" || c.PageNumber != 3 || c.Section != "Results" || c.ChunkID != report.ID {
+		t.Errorf("document citation = %+v", c)
+	}
+}
+
+func TestCitationStream(t *testing.T) {
+	set := NewCitationSet()
+	set.Add(memory.Chunk{ID: "q4.pdf_section_2", Content: "Revenue grew 12% year over year."})
+	stream := newCitationStream(set)
+
+	var sent []string
+	for _, delta := range []string{"Revenue grew 12% [", "S1]. Margins held", " [S", "7]. See the filing [S1", "]."} {
+		chunk := CompletionChunk{Type: "text", Delta: delta}
+		if stream.filter(&chunk) {
+			sent = append(sent, chunk.Delta)
+		}
+	}
+	final := CompletionChunk{Done: true}
+	if rest := stream.finish("session", &final); rest != nil {
+		sent = append(sent, rest.Delta)
+	}
+
+	if got, want := strings.Join(sent, ""), "Revenue grew 12% [S1]. Margins held. See the filing [S1]."; got != want {
+		t.Errorf("streamed text = %q, want %q", got, want)
+	}
+	for _, delta := range sent {
+		if strings.Contains(delta, "S7") {
+			t.Errorf("invented citation was streamed in %q", delta)
+		}
+	}
+	if len(final.Citations) != 1 || final.Citations[0].ID != "S1" {
+		t.Errorf("final chunk citations = %+v, want [S1]", final.Citations)
+	}
+
+	// Brackets that never become a citation are not held back for long
+	stream = newCitationStream(set)
+	chunk := CompletionChunk{Type: "text", Delta: "Range [A1:B3] and [" + strings.Repeat("1", maxPartialCitation)}
+	if !stream.filter(&chunk) || chunk.Delta != "Range [A1:B3] and ["+strings.Repeat("1", maxPartialCitation) {
+		t.Errorf("streamed text = %q, want it unchanged", chunk.Delta)
+	}
+}
+
+func TestCitationsFromContext(t *testing.T) {
+	if CitationsFromContext(context.Background()) != nil {
+		t.Error("expected no citation set on a bare context")
+	}
+	set := NewCitationSet()
+	if CitationsFromContext(WithCitations(context.Background(), set)) != set {
+		t.Error("citation set not carried by context")
+	}
+}
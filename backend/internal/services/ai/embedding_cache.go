@@ -0,0 +1,345 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// batchEmbedder is implemented by providers that expose their raw batch
+// call, letting the cache merge requests from many callers into one
+type batchEmbedder interface {
+	EmbeddingModel() string
+	MaxBatchSize() int
+	embedBatch(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// EmbeddingModel identifies the OpenAI model in cache keys
+func (p *OpenAIEmbeddingProvider) EmbeddingModel() string {
+	return p.model
+}
+
+// MaxBatchSize is the number of inputs OpenAI accepts per request
+func (p *OpenAIEmbeddingProvider) MaxBatchSize() int {
+	return 2048
+}
+
+func (p *OpenAIEmbeddingProvider) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	if err := p.rateLimiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return p.callAPI(ctx, texts)
+}
+
+// EmbeddingModel identifies the local embedder and its dimensionality
+func (l *LocalEmbeddingProvider) EmbeddingModel() string {
+	return fmt.Sprintf("local-hashed-tfidf-v1-%d", l.dimensions)
+}
+
+// MaxBatchSize bounds local batches so one flush stays short
+func (l *LocalEmbeddingProvider) MaxBatchSize() int {
+	return 512
+}
+
+func (l *LocalEmbeddingProvider) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	return l.GetEmbeddings(ctx, texts)
+}
+
+// genericEmbedder adapts any EmbeddingProvider to batchEmbedder
+type genericEmbedder struct {
+	EmbeddingProvider
+	model string
+}
+
+func (g genericEmbedder) EmbeddingModel() string { return g.model }
+func (g genericEmbedder) MaxBatchSize() int      { return 256 }
+func (g genericEmbedder) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	return g.GetEmbeddings(ctx, texts)
+}
+
+// EmbeddingCacheStats reports cache effectiveness
+type EmbeddingCacheStats struct {
+	Model     string  `json:"model"`
+	Hits      int64   `json:"hits"`
+	Misses    int64   `json:"misses"`
+	Coalesced int64   `json:"coalesced"` // Misses served by another caller's in-flight request
+	Batches   int64   `json:"batches"`   // Provider calls made
+	Embedded  int64   `json:"embedded"`  // Texts sent to the provider
+	Errors    int64   `json:"errors"`
+	HitRate   float64 `json:"hit_rate"`
+	Entries   int     `json:"entries"`
+}
+
+// pendingEmbedding is a text waiting for, or being sent to, the provider
+type pendingEmbedding struct {
+	text   string
+	done   chan struct{}
+	vector []float32
+	err    error
+}
+
+// CachedEmbeddingProvider decorates an EmbeddingProvider with a persistent
+// content-addressed cache. Vectors are keyed by model and SHA-256 of the
+// text, concurrent requests for the same text share one provider call, and
+// misses from all callers are batched up to the provider's limit.
+type CachedEmbeddingProvider struct {
+	backend     batchEmbedder
+	db          *bolt.DB
+	bucket      []byte
+	batchWindow time.Duration
+	callTimeout time.Duration
+
+	mu      sync.Mutex
+	pending map[[sha256.Size]byte]*pendingEmbedding
+	queue   [][sha256.Size]byte
+	timer   *time.Timer
+
+	hits, misses, coalesced, batches, embedded, errors atomic.Int64
+}
+
+// NewCachedEmbeddingProvider opens (or creates) the cache at path
+func NewCachedEmbeddingProvider(provider EmbeddingProvider, path string) (*CachedEmbeddingProvider, error) {
+	backend, ok := provider.(batchEmbedder)
+	if !ok {
+		backend = genericEmbedder{EmbeddingProvider: provider, model: fmt.Sprintf("%T", provider)}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create embedding cache directory: %w", err)
+	}
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open embedding cache: %w", err)
+	}
+
+	// One bucket per model so switching models never returns stale vectors
+	bucket := []byte(backend.EmbeddingModel())
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create embedding cache bucket: %w", err)
+	}
+
+	return &CachedEmbeddingProvider{
+		backend:     backend,
+		db:          db,
+		bucket:      bucket,
+		batchWindow: 20 * time.Millisecond,
+		callTimeout: 2 * time.Minute,
+		pending:     make(map[[sha256.Size]byte]*pendingEmbedding),
+	}, nil
+}
+
+// GetEmbedding gets embedding for a single text
+func (c *CachedEmbeddingProvider) GetEmbedding(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := c.GetEmbeddings(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// GetEmbeddings returns cached vectors and waits for the rest
+func (c *CachedEmbeddingProvider) GetEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	keys := make([][sha256.Size]byte, len(texts))
+	for i, text := range texts {
+		keys[i] = sha256.Sum256([]byte(text))
+	}
+
+	results := make([][]float32, len(texts))
+	if err := c.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(c.bucket)
+		for i, key := range keys {
+			if value := bucket.Get(key[:]); value != nil {
+				results[i] = decodeVector(value)
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to read embedding cache: %w", err)
+	}
+
+	waits := make(map[int]*pendingEmbedding)
+	c.mu.Lock()
+	for i, key := range keys {
+		if results[i] != nil {
+			c.hits.Add(1)
+			continue
+		}
+		c.misses.Add(1)
+
+		if p, ok := c.pending[key]; ok {
+			c.coalesced.Add(1)
+			waits[i] = p
+			continue
+		}
+		p := &pendingEmbedding{text: texts[i], done: make(chan struct{})}
+		c.pending[key] = p
+		c.queue = append(c.queue, key)
+		waits[i] = p
+	}
+	c.scheduleLocked()
+	c.mu.Unlock()
+
+	for i, p := range waits {
+		select {
+		case <-p.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if p.err != nil {
+			return nil, p.err
+		}
+		// Stores normalize vectors in place, so waiters get their own copy
+		results[i] = append([]float32(nil), p.vector...)
+	}
+
+	return results, nil
+}
+
+// scheduleLocked flushes full batches now and arms a short timer for the
+// remainder so concurrent callers can join it. Callers hold c.mu.
+func (c *CachedEmbeddingProvider) scheduleLocked() {
+	limit := c.backend.MaxBatchSize()
+	for len(c.queue) >= limit {
+		c.flushLocked(limit)
+	}
+	if len(c.queue) > 0 && c.timer == nil {
+		c.timer = time.AfterFunc(c.batchWindow, func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			c.timer = nil
+			for len(c.queue) > 0 {
+				c.flushLocked(limit)
+			}
+		})
+	}
+}
+
+// flushLocked sends up to n queued texts to the provider in the background.
+// Callers hold c.mu.
+func (c *CachedEmbeddingProvider) flushLocked(n int) {
+	n = min(n, len(c.queue))
+	keys := append([][sha256.Size]byte(nil), c.queue[:n]...)
+	c.queue = c.queue[n:]
+
+	batch := make([]*pendingEmbedding, n)
+	texts := make([]string, n)
+	for i, key := range keys {
+		batch[i] = c.pending[key]
+		texts[i] = batch[i].text
+	}
+
+	go c.embed(keys, batch, texts)
+}
+
+// embed calls the provider for one batch, persists the vectors and wakes
+// every waiter. It runs detached from any caller's context because the
+// batch may serve several requests.
+func (c *CachedEmbeddingProvider) embed(keys [][sha256.Size]byte, batch []*pendingEmbedding, texts []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.callTimeout)
+	defer cancel()
+
+	c.batches.Add(1)
+	c.embedded.Add(int64(len(texts)))
+	vectors, err := c.backend.embedBatch(ctx, texts)
+	if err == nil && len(vectors) != len(texts) {
+		err = fmt.Errorf("embedding provider returned %d vectors for %d texts", len(vectors), len(texts))
+	}
+	if err == nil {
+		err = c.db.Update(func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(c.bucket)
+			for i, key := range keys {
+				if vectors[i] == nil {
+					continue
+				}
+				if err := bucket.Put(key[:], encodeVector(vectors[i])); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			// The vectors are still good; only persistence failed
+			c.errors.Add(1)
+			err = nil
+		}
+	} else {
+		c.errors.Add(1)
+		err = fmt.Errorf("embedding API error: %w", err)
+	}
+
+	c.mu.Lock()
+	for i, key := range keys {
+		p := batch[i]
+		if err != nil {
+			p.err = err
+		} else if vectors[i] == nil {
+			p.err = fmt.Errorf("no embedding returned")
+		} else {
+			p.vector = vectors[i]
+		}
+		delete(c.pending, key)
+		close(p.done)
+	}
+	c.mu.Unlock()
+}
+
+// Stats returns hit-rate and batching counters
+func (c *CachedEmbeddingProvider) Stats() EmbeddingCacheStats {
+	stats := EmbeddingCacheStats{
+		Model:     string(c.bucket),
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Coalesced: c.coalesced.Load(),
+		Batches:   c.batches.Load(),
+		Embedded:  c.embedded.Load(),
+		Errors:    c.errors.Load(),
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
+	c.db.View(func(tx *bolt.Tx) error {
+		stats.Entries = tx.Bucket(c.bucket).Stats().KeyN
+		return nil
+	})
+	return stats
+}
+
+// Close closes the cache database
+func (c *CachedEmbeddingProvider) Close() error {
+	return c.db.Close()
+}
+
+func encodeVector(vector []float32) []byte {
+	buf := make([]byte, len(vector)*4)
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(v))
+	}
+	return buf
+}
+
+// decodeVector copies out of bolt's mmap, which is only valid inside the
+// transaction
+func decodeVector(buf []byte) []float32 {
+	vector := make([]float32, len(buf)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[i*4:]))
+	}
+	return vector
+}
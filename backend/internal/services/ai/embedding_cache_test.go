@@ -0,0 +1,103 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingEmbedder records every batch it is asked to embed
+type countingEmbedder struct {
+	mu      sync.Mutex
+	batches [][]string
+	delay   time.Duration
+}
+
+func (c *countingEmbedder) EmbeddingModel() string { return "test-model" }
+func (c *countingEmbedder) MaxBatchSize() int      { return 4 }
+
+func (c *countingEmbedder) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	time.Sleep(c.delay)
+	c.mu.Lock()
+	c.batches = append(c.batches, texts)
+	c.mu.Unlock()
+
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = []float32{float32(len(text)), 1}
+	}
+	return vectors, nil
+}
+
+func (c *countingEmbedder) GetEmbedding(ctx context.Context, text string) ([]float32, error) {
+	vectors, err := c.embedBatch(ctx, []string{text})
+	return vectors[0], err
+}
+
+func (c *countingEmbedder) GetEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	return c.embedBatch(ctx, texts)
+}
+
+func TestCachedEmbeddingProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "embeddings.db")
+	backend := &countingEmbedder{delay: 10 * time.Millisecond}
+	cached, err := NewCachedEmbeddingProvider(backend, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// Concurrent requests for the same text coalesce into one provider call
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := cached.GetEmbedding(ctx, "revenue"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if len(backend.batches) != 1 || len(backend.batches[0]) != 1 {
+		t.Fatalf("batches = %v, want one batch with one text", backend.batches)
+	}
+
+	// Misses are split at the provider's batch limit; cached texts are skipped
+	texts := []string{"revenue"}
+	for i := 0; i < 9; i++ {
+		texts = append(texts, fmt.Sprintf("text %d", i))
+	}
+	vectors, err := cached.GetEmbeddings(ctx, texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vectors[0][0] != 7 || vectors[9][0] != 6 {
+		t.Errorf("vectors returned out of order: %v", vectors)
+	}
+	if len(backend.batches) != 4 {
+		t.Errorf("got %d provider calls, want 4 (1 + batches of 4, 4, 1)", len(backend.batches))
+	}
+
+	stats := cached.Stats()
+	if stats.Hits != 1 || stats.Misses != 17 || stats.Coalesced != 7 || stats.Entries != 10 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	cached.Close()
+
+	// Vectors survive a restart
+	reopened, err := NewCachedEmbeddingProvider(backend, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if _, err := reopened.GetEmbeddings(ctx, texts); err != nil {
+		t.Fatal(err)
+	}
+	if len(backend.batches) != 4 || reopened.Stats().HitRate != 1 {
+		t.Errorf("expected all hits after reopen: %+v", reopened.Stats())
+	}
+}
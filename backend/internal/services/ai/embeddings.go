@@ -245,49 +245,3 @@ func (r *RateLimiter) Wait(ctx context.Context) error {
 		return ctx.Err()
 	}
 }
-
-// LocalEmbeddingProvider provides fallback embeddings using a local model
-type LocalEmbeddingProvider struct {
-	// This would use something like sentence-transformers or a local ONNX model
-	// For now, it's a stub that generates random embeddings
-}
-
-// NewLocalEmbeddingProvider creates a new local embedding provider
-func NewLocalEmbeddingProvider() *LocalEmbeddingProvider {
-	return &LocalEmbeddingProvider{}
-}
-
-// GetEmbedding generates a local embedding
-func (l *LocalEmbeddingProvider) GetEmbedding(ctx context.Context, text string) ([]float32, error) {
-	// In production, this would use a real local model
-	// For now, return a deterministic fake embedding based on text length
-	embedding := make([]float32, 1536) // Same size as OpenAI ada-002
-	
-	// Simple hash-based embedding for testing
-	hash := 0
-	for _, ch := range text {
-		hash = (hash*31 + int(ch)) % 1000000
-	}
-	
-	for i := range embedding {
-		embedding[i] = float32(hash%100) / 100.0
-		hash = (hash * 7) % 1000000
-	}
-	
-	return embedding, nil
-}
-
-// GetEmbeddings batch processes multiple texts
-func (l *LocalEmbeddingProvider) GetEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
-	results := make([][]float32, len(texts))
-	
-	for i, text := range texts {
-		embedding, err := l.GetEmbedding(ctx, text)
-		if err != nil {
-			return nil, err
-		}
-		results[i] = embedding
-	}
-	
-	return results, nil
-}
\ No newline at end of file
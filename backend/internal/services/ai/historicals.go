@@ -0,0 +1,828 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gridmate/backend/internal/services/spreadsheet"
+	"github.com/rs/zerolog/log"
+)
+
+// FilingFact is a reported value from an ingested filing
+type FilingFact struct {
+	DocumentID   string     `json:"document_id"`
+	DocumentName string     `json:"document_name,omitempty"`
+	FiledAt      *time.Time `json:"filed_at,omitempty"`
+	Concept      string     `json:"concept"`     // Prefixed name, e.g. us-gaap:Revenues
+	PeriodType   string     `json:"period_type"` // "duration" or "instant"
+	PeriodStart  *time.Time `json:"period_start,omitempty"`
+	PeriodEnd    *time.Time `json:"period_end,omitempty"`
+	Unit         string     `json:"unit,omitempty"`
+	Value        float64    `json:"value"`
+	Page         int        `json:"page,omitempty"`
+}
+
+// FactQuery selects filing facts by concept local name
+type FactQuery struct {
+	SessionID  string
+	DocumentID string
+	Concepts   []string
+}
+
+// FactSource looks up filing facts for a session's user. It is implemented
+// outside this package because the document service depends on ai.
+type FactSource interface {
+	FindFacts(ctx context.Context, query FactQuery) ([]FilingFact, error)
+	// Serves reports whether facts can be looked up for a session at all.
+	// Sessions without an authenticated user, such as those of the Excel
+	// add-in connected over SignalR, have no facts.
+	Serves(sessionID string) bool
+}
+
+// SetFactSource sets the source used to populate historicals from filings.
+// populate_historicals is only offered in sessions the source serves.
+func (te *ToolExecutor) SetFactSource(source FactSource) {
+	te.factSource = source
+	if err := te.registry.SetAvailable("populate_historicals", source.Serves); err != nil {
+		log.Warn().Err(err).Msg("Failed to limit populate_historicals to sessions with filing facts")
+	}
+}
+
+// HistoricalCell is a proposed value for one model cell
+type HistoricalCell struct {
+	Cell       string         `json:"cell"`
+	Label      string         `json:"label"`
+	Period     string         `json:"period"`
+	Value      float64        `json:"value"`
+	Confidence float64        `json:"confidence"`
+	Provenance FactProvenance `json:"provenance"`
+	Previous   interface{}    `json:"previous,omitempty"`
+}
+
+// FactProvenance records where a proposed value came from
+type FactProvenance struct {
+	DocumentID   string  `json:"document_id"`
+	DocumentName string  `json:"document_name,omitempty"`
+	Page         int     `json:"page,omitempty"`
+	Concept      string  `json:"concept"`
+	PeriodStart  string  `json:"period_start,omitempty"`
+	PeriodEnd    string  `json:"period_end"`
+	Unit         string  `json:"unit,omitempty"`
+	Reported     float64 `json:"reported_value"`
+}
+
+// UnmatchedLabel is a model row that could not be mapped to a filing fact
+type UnmatchedLabel struct {
+	Row    int    `json:"row"`
+	Label  string `json:"label"`
+	Reason string `json:"reason"`
+}
+
+// conceptMapping maps common model line items to us-gaap concepts, in
+// order of preference
+type conceptMapping struct {
+	labels   []string
+	concepts []string
+}
+
+var historicalConceptMappings = []conceptMapping{
+	{[]string{"revenue", "revenues", "total revenue", "total revenues", "net revenue", "net revenues", "sales", "net sales", "total net sales"},
+		[]string{"Revenues", "RevenueFromContractWithCustomerExcludingAssessedTax", "SalesRevenueNet"}},
+	{[]string{"cost of revenue", "cost of revenues", "cost of sales", "cost of goods sold", "cogs"},
+		[]string{"CostOfRevenue", "CostOfGoodsAndServicesSold"}},
+	{[]string{"gross profit", "gross margin $"},
+		[]string{"GrossProfit"}},
+	{[]string{"research and development", "r&d", "research & development"},
+		[]string{"ResearchAndDevelopmentExpense"}},
+	{[]string{"selling general and administrative", "sg&a", "selling general & administrative"},
+		[]string{"SellingGeneralAndAdministrativeExpense"}},
+	{[]string{"operating expenses", "total operating expenses", "opex"},
+		[]string{"OperatingExpenses", "CostsAndExpenses"}},
+	{[]string{"operating income", "operating profit", "income from operations", "ebit"},
+		[]string{"OperatingIncomeLoss"}},
+	{[]string{"interest expense"},
+		[]string{"InterestExpense", "InterestExpenseNonoperating"}},
+	{[]string{"pretax income", "pre-tax income", "income before taxes", "income before income taxes", "ebt"},
+		[]string{"IncomeLossFromContinuingOperationsBeforeIncomeTaxesExtraordinaryItemsNoncontrollingInterest"}},
+	{[]string{"income tax expense", "income taxes", "tax expense", "provision for income taxes", "taxes"},
+		[]string{"IncomeTaxExpenseBenefit"}},
+	{[]string{"net income", "net earnings", "net loss", "net income loss", "net profit"},
+		[]string{"NetIncomeLoss", "ProfitLoss"}},
+	{[]string{"basic eps", "eps basic", "earnings per share basic"},
+		[]string{"EarningsPerShareBasic"}},
+	{[]string{"diluted eps", "eps diluted", "eps", "earnings per share diluted", "earnings per share"},
+		[]string{"EarningsPerShareDiluted", "EarningsPerShareBasicAndDiluted"}},
+	{[]string{"diluted shares", "diluted shares outstanding", "weighted average diluted shares"},
+		[]string{"WeightedAverageNumberOfDilutedSharesOutstanding"}},
+	{[]string{"depreciation and amortization", "d&a", "depreciation & amortization", "depreciation"},
+		[]string{"DepreciationDepletionAndAmortization", "DepreciationAndAmortization", "DepreciationAmortizationAndAccretionNet"}},
+	{[]string{"cash", "cash and cash equivalents", "cash and equivalents", "cash & equivalents"},
+		[]string{"CashAndCashEquivalentsAtCarryingValue"}},
+	{[]string{"accounts receivable", "receivables", "accounts receivable net"},
+		[]string{"AccountsReceivableNetCurrent"}},
+	{[]string{"inventory", "inventories"},
+		[]string{"InventoryNet"}},
+	{[]string{"total current assets", "current assets"},
+		[]string{"AssetsCurrent"}},
+	{[]string{"total assets", "assets"},
+		[]string{"Assets"}},
+	{[]string{"accounts payable"},
+		[]string{"AccountsPayableCurrent"}},
+	{[]string{"total current liabilities", "current liabilities"},
+		[]string{"LiabilitiesCurrent"}},
+	{[]string{"total liabilities", "liabilities"},
+		[]string{"Liabilities"}},
+	{[]string{"long-term debt", "long term debt", "total debt", "debt"},
+		[]string{"LongTermDebt", "LongTermDebtNoncurrent"}},
+	{[]string{"total equity", "shareholders equity", "stockholders equity", "total shareholders equity", "total stockholders equity"},
+		[]string{"StockholdersEquity", "StockholdersEquityIncludingPortionAttributableToNoncontrollingInterest"}},
+	{[]string{"cash from operations", "operating cash flow", "cash flow from operations", "net cash from operating activities", "cfo"},
+		[]string{"NetCashProvidedByUsedInOperatingActivities"}},
+	{[]string{"capital expenditures", "capex", "purchases of property and equipment"},
+		[]string{"PaymentsToAcquirePropertyPlantAndEquipment"}},
+	{[]string{"share repurchases", "stock repurchases", "buybacks"},
+		[]string{"PaymentsForRepurchaseOfCommonStock"}},
+	{[]string{"dividends", "dividends paid"},
+		[]string{"PaymentsOfDividends", "PaymentsOfDividendsCommonStock"}},
+}
+
+var (
+	labelNoisePattern   = regexp.MustCompile(`\(([^)]*)\)|[$,:]`)
+	labelSpacePattern   = regexp.MustCompile(`\s+`)
+	derivedLabelPattern = regexp.MustCompile(`(?i)(%|margin|growth|ratio|yoy|y/y|multiple|per cent)`)
+	annualHeaderPattern = regexp.MustCompile(`(?i)^(?:FY\s*)?'?((?:19|20)?\d{2})\s*([AEPFB])?$`)
+	quarterHeaderFirst  = regexp.MustCompile(`(?i)^Q([1-4])\s*[-' ]?\s*(?:FY)?\s*'?((?:19|20)?\d{2})\s*([AEPFB])?$`)
+	quarterHeaderLast   = regexp.MustCompile(`(?i)^([1-4])Q\s*'?((?:19|20)?\d{2})\s*([AEPFB])?$`)
+	unitsPattern        = regexp.MustCompile(`(?i)(in\s+)?(thousands|millions|billions|\$\s*m{1,2}\b|\$\s*000s?|\$\s*bn?\b|000s)`)
+)
+
+// modelPeriod is a parsed model column header
+type modelPeriod struct {
+	Year      int
+	Quarter   int // 0 for annual
+	Projected bool
+}
+
+func (p modelPeriod) String() string {
+	if p.Quarter > 0 {
+		return fmt.Sprintf("Q%d %d", p.Quarter, p.Year)
+	}
+	return fmt.Sprintf("FY%d", p.Year)
+}
+
+// parseModelPeriod recognizes headers like 2023, FY2023A, FY23, Q1 2024 and 1Q24
+func parseModelPeriod(header string) (modelPeriod, bool) {
+	header = strings.TrimSpace(header)
+
+	var period modelPeriod
+	var year, suffix string
+	if m := quarterHeaderFirst.FindStringSubmatch(header); m != nil {
+		period.Quarter, _ = strconv.Atoi(m[1])
+		year, suffix = m[2], m[3]
+	} else if m := quarterHeaderLast.FindStringSubmatch(header); m != nil {
+		period.Quarter, _ = strconv.Atoi(m[1])
+		year, suffix = m[2], m[3]
+	} else if m := annualHeaderPattern.FindStringSubmatch(header); m != nil {
+		// A bare two digit number is too ambiguous to be a year
+		if len(m[1]) == 2 && !strings.HasPrefix(strings.ToUpper(header), "FY") && m[2] == "" {
+			return period, false
+		}
+		year, suffix = m[1], m[2]
+	} else {
+		return period, false
+	}
+
+	period.Year, _ = strconv.Atoi(year)
+	if period.Year < 100 {
+		period.Year += 2000
+	}
+
+	switch strings.ToUpper(suffix) {
+	case "A":
+		period.Projected = false
+	case "E", "P", "F", "B":
+		period.Projected = true
+	default:
+		now := time.Now()
+		if period.Quarter > 0 {
+			periodEnd := time.Date(period.Year, time.Month(period.Quarter*3), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, -1)
+			period.Projected = periodEnd.After(now)
+		} else {
+			period.Projected = period.Year >= now.Year()
+		}
+	}
+
+	return period, true
+}
+
+// normalizeLabel lowercases a row label and strips units, punctuation and
+// footnote markers
+func normalizeLabel(label string) string {
+	label = strings.ToLower(strings.TrimSpace(label))
+	label = labelNoisePattern.ReplaceAllString(label, " ")
+	label = strings.NewReplacer("/", " ", ".", "", "*", "", "'", "").Replace(label)
+	return strings.TrimSpace(labelSpacePattern.ReplaceAllString(label, " "))
+}
+
+// matchLabel returns the concept mapping that best matches a row label and a
+// confidence for the match
+func matchLabel(label string) (*conceptMapping, float64) {
+	normalized := normalizeLabel(label)
+	if normalized == "" {
+		return nil, 0
+	}
+	stripped := strings.TrimPrefix(normalized, "total ")
+
+	var best *conceptMapping
+	bestScore := 0.0
+	for i := range historicalConceptMappings {
+		mapping := &historicalConceptMappings[i]
+		for _, synonym := range mapping.labels {
+			score := 0.0
+			switch {
+			case normalized == synonym:
+				score = 1.0
+			case stripped == synonym:
+				score = 0.95
+			case strings.HasPrefix(normalized, synonym+" ") || strings.HasSuffix(normalized, " "+synonym):
+				score = 0.8
+			default:
+				score = 0.75 * tokenOverlap(normalized, synonym)
+			}
+			if score > bestScore {
+				best, bestScore = mapping, score
+			}
+		}
+	}
+	return best, bestScore
+}
+
+// tokenOverlap is the Jaccard similarity of two labels' words
+func tokenOverlap(a, b string) float64 {
+	aTokens := strings.Fields(a)
+	bSet := make(map[string]bool)
+	for _, t := range strings.Fields(b) {
+		bSet[t] = true
+	}
+	union := make(map[string]bool, len(aTokens)+len(bSet))
+	shared := 0
+	for _, t := range aTokens {
+		if bSet[t] && !union[t] {
+			shared++
+		}
+		union[t] = true
+	}
+	for t := range bSet {
+		union[t] = true
+	}
+	if len(union) == 0 {
+		return 0
+	}
+	return float64(shared) / float64(len(union))
+}
+
+// matchFactPeriod scores how well a fact's period lines up with a model
+// column. Annual columns take 12 month durations or the year-end instant;
+// quarterly columns take 3 month durations or the quarter-end instant.
+func matchFactPeriod(fact FilingFact, period modelPeriod) float64 {
+	if fact.PeriodEnd == nil {
+		return 0
+	}
+	end := *fact.PeriodEnd
+
+	if fact.PeriodType == "instant" {
+		if end.Year() != period.Year {
+			return 0
+		}
+		if period.Quarter == 0 {
+			return 0.95
+		}
+		if (int(end.Month())-1)/3+1 == period.Quarter {
+			return 0.9
+		}
+		return 0
+	}
+
+	if fact.PeriodStart == nil {
+		return 0
+	}
+	months := int(math.Round(end.Sub(*fact.PeriodStart).Hours() / 24 / 30.44))
+	if period.Quarter == 0 {
+		if months < 11 || months > 13 || end.Year() != period.Year {
+			return 0
+		}
+		return 1.0
+	}
+	if months < 2 || months > 4 || end.Year() != period.Year {
+		return 0
+	}
+	if (int(end.Month())-1)/3+1 == period.Quarter {
+		// Fiscal quarters may not line up with calendar quarters
+		return 0.9
+	}
+	return 0
+}
+
+// detectUnitScale finds a "(in millions)" style marker in the model
+func detectUnitScale(values [][]interface{}) (string, float64) {
+	for _, row := range values {
+		for _, cell := range row {
+			text, ok := cell.(string)
+			if !ok {
+				continue
+			}
+			m := unitsPattern.FindStringSubmatch(text)
+			if m == nil {
+				continue
+			}
+			marker := strings.ToLower(strings.ReplaceAll(m[2], " ", ""))
+			switch {
+			case strings.Contains(marker, "thousand") || strings.Contains(marker, "000"):
+				return "thousands", 1e3
+			case strings.Contains(marker, "billion") || strings.HasPrefix(marker, "$b"):
+				return "billions", 1e9
+			default:
+				return "millions", 1e6
+			}
+		}
+	}
+	return "units", 1
+}
+
+func unitScale(units string) float64 {
+	switch units {
+	case "thousands":
+		return 1e3
+	case "millions":
+		return 1e6
+	case "billions":
+		return 1e9
+	default:
+		return 1
+	}
+}
+
+// executePopulateHistoricals maps model rows and period columns onto filing
+// facts and queues the resulting values for approval
+func (te *ToolExecutor) executePopulateHistoricals(ctx context.Context, sessionID string, toolCall ToolCall) (map[string]interface{}, error) {
+	input := toolCall.Input
+	rangeAddr, ok := input["range"].(string)
+	if !ok || rangeAddr == "" {
+		return nil, fmt.Errorf("range parameter is required")
+	}
+	if te.factSource == nil {
+		return nil, newEnhancedError(
+			"Filing facts are not available",
+			"No document store is configured for this server",
+			"Ingest a filing with XBRL data before populating historicals",
+		)
+	}
+
+	documentID, _ := input["document_id"].(string)
+	overwrite, _ := input["overwrite"].(bool)
+	minConfidence := 0.6
+	if mc, ok := input["min_confidence"].(float64); ok && mc > 0 {
+		minConfidence = mc
+	}
+
+	data, err := te.excelBridge.ReadRange(ctx, sessionID, rangeAddr, true, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model range: %w", err)
+	}
+	if len(data.Values) == 0 {
+		return nil, fmt.Errorf("range %s is empty", rangeAddr)
+	}
+
+	sheetPrefix := ""
+	origin := rangeAddr
+	if idx := strings.LastIndex(rangeAddr, "!"); idx >= 0 {
+		sheetPrefix = rangeAddr[:idx+1]
+		origin = rangeAddr[idx+1:]
+	}
+	startCol, startRow, err := parseCell(strings.ReplaceAll(strings.Split(origin, ":")[0], "$", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid range %s: %w", rangeAddr, err)
+	}
+
+	units, _ := input["units"].(string)
+	scale := unitScale(units)
+	if units == "" || units == "auto" {
+		units, scale = detectUnitScale(data.Values)
+	}
+
+	structure, headerRow, periods := detectPeriodHeaders(data, startRow, startCol)
+	if headerRow < 0 {
+		return nil, newEnhancedError(
+			"No period headers found",
+			fmt.Sprintf("Could not find a row of year or quarter headers in %s", rangeAddr),
+			"Include the header row (e.g. FY2022A, FY2023A) in the range",
+		)
+	}
+
+	// Map each row label to a concept before fetching facts, so a single
+	// lookup covers the whole model
+	classifier := spreadsheet.NewCellClassifier()
+	firstPeriodCol := len(data.Values[headerRow])
+	for col := range periods {
+		if col < firstPeriodCol {
+			firstPeriodCol = col
+		}
+	}
+
+	type rowMatch struct {
+		row        int
+		label      string
+		mapping    *conceptMapping
+		confidence float64
+	}
+	var rows []rowMatch
+	var unmatched []UnmatchedLabel
+	conceptSet := make(map[string]bool)
+
+	for r := headerRow + 1; r < len(data.Values); r++ {
+		label := ""
+		for c := 0; c < firstPeriodCol && c < len(data.Values[r]); c++ {
+			if text, ok := data.Values[r][c].(string); ok && strings.TrimSpace(text) != "" {
+				label = strings.TrimSpace(text)
+				break
+			}
+		}
+		if label == "" {
+			continue
+		}
+
+		classification := classifier.ClassifyCell(label, "", r, 0, spreadsheet.CellContext{})
+		if classification.Purpose == spreadsheet.PurposeHeader {
+			continue
+		}
+		if !rowHasPeriodCells(data.Values[r], periods) && !rowHasFormulaCells(data.Formulas, r, periods) {
+			// Section titles have no cells under the period columns
+			if _, score := matchLabel(label); score < minConfidence {
+				continue
+			}
+		}
+		if derivedLabelPattern.MatchString(label) {
+			unmatched = append(unmatched, UnmatchedLabel{Row: startRow + r + 1, Label: label, Reason: "derived metric; not reported in filings"})
+			continue
+		}
+
+		mapping, score := matchLabel(label)
+		if mapping == nil || score < minConfidence {
+			unmatched = append(unmatched, UnmatchedLabel{Row: startRow + r + 1, Label: label, Reason: "no matching filing concept"})
+			continue
+		}
+		rows = append(rows, rowMatch{row: r, label: label, mapping: mapping, confidence: score})
+		for _, concept := range mapping.concepts {
+			conceptSet[concept] = true
+		}
+	}
+
+	concepts := make([]string, 0, len(conceptSet))
+	for concept := range conceptSet {
+		concepts = append(concepts, concept)
+	}
+	sort.Strings(concepts)
+
+	var facts []FilingFact
+	if len(concepts) > 0 {
+		facts, err = te.factSource.FindFacts(ctx, FactQuery{SessionID: sessionID, DocumentID: documentID, Concepts: concepts})
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up filing facts: %w", err)
+		}
+	}
+
+	factsByConcept := make(map[string][]FilingFact)
+	for _, fact := range facts {
+		local := fact.Concept
+		if idx := strings.Index(local, ":"); idx >= 0 {
+			local = local[idx+1:]
+		}
+		factsByConcept[local] = append(factsByConcept[local], fact)
+	}
+
+	periodCols := make([]int, 0, len(periods))
+	for col := range periods {
+		periodCols = append(periodCols, col)
+	}
+	sort.Ints(periodCols)
+
+	var cells []HistoricalCell
+	skipped := 0
+	for _, match := range rows {
+		matchedAny := false
+		for _, col := range periodCols {
+			period := periods[col]
+			if period.Projected {
+				continue
+			}
+
+			fact, factScore := bestFact(match.mapping, factsByConcept, period)
+			if factScore == 0 {
+				continue
+			}
+			matchedAny = true
+
+			var value, formula interface{}
+			if col < len(data.Values[match.row]) {
+				value = data.Values[match.row][col]
+			}
+			if match.row < len(data.Formulas) && col < len(data.Formulas[match.row]) {
+				formula = data.Formulas[match.row][col]
+			}
+			formulaStr, _ := formula.(string)
+			if !strings.HasPrefix(formulaStr, "=") {
+				formulaStr = ""
+			}
+
+			classification := classifier.ClassifyCell(value, formulaStr, match.row, col, spreadsheet.CellContext{IsInputRegion: true})
+			switch classification.Purpose {
+			case spreadsheet.PurposeEmpty:
+			case spreadsheet.PurposeInput, spreadsheet.PurposeConstant:
+				if !overwrite {
+					skipped++
+					continue
+				}
+			default:
+				// Never replace formulas with hard-coded values
+				skipped++
+				continue
+			}
+
+			reported := fact.Value
+			cellValue := reported
+			if !strings.Contains(fact.Unit, "/") {
+				// Per-share amounts are never scaled
+				cellValue = reported / scale
+			}
+
+			provenance := FactProvenance{
+				DocumentID:   fact.DocumentID,
+				DocumentName: fact.DocumentName,
+				Page:         fact.Page,
+				Concept:      fact.Concept,
+				PeriodEnd:    fact.PeriodEnd.Format("2006-01-02"),
+				Unit:         fact.Unit,
+				Reported:     reported,
+			}
+			if fact.PeriodStart != nil {
+				provenance.PeriodStart = fact.PeriodStart.Format("2006-01-02")
+			}
+
+			cells = append(cells, HistoricalCell{
+				Cell:       sheetPrefix + getCellAddress(startRow+match.row+1, startCol+col+1),
+				Label:      match.label,
+				Period:     period.String(),
+				Value:      cellValue,
+				Confidence: math.Round(match.confidence*factScore*100) / 100,
+				Provenance: provenance,
+				Previous:   value,
+			})
+		}
+		if !matchedAny {
+			unmatched = append(unmatched, UnmatchedLabel{Row: startRow + match.row + 1, Label: match.label, Reason: "no filing fact for the model's historical periods"})
+		}
+	}
+
+	result := map[string]interface{}{
+		"status":          "proposed",
+		"units":           units,
+		"model_structure": structure,
+		"cells":           cells,
+		"unmatched":       unmatched,
+		"skipped_cells":   skipped,
+	}
+
+	if len(cells) == 0 {
+		result["status"] = "no_matches"
+		result["message"] = "No historical values could be matched to filing facts"
+		return result, nil
+	}
+
+	operations := te.queueHistoricalWrites(ctx, sessionID, toolCall.ID, cells)
+	result["status"] = "queued"
+	result["operations"] = operations
+	result["message"] = fmt.Sprintf("Proposed %d historical values across %d write operations for approval", len(cells), len(operations))
+
+	log.Info().
+		Str("session", sessionID).
+		Int("cells", len(cells)).
+		Int("unmatched", len(unmatched)).
+		Int("operations", len(operations)).
+		Msg("Historicals proposed from filing facts")
+
+	return result, nil
+}
+
+// bestFact picks the fact for a mapping and period, preferring earlier
+// concepts in the mapping and the most recent filing
+func bestFact(mapping *conceptMapping, factsByConcept map[string][]FilingFact, period modelPeriod) (FilingFact, float64) {
+	for i, concept := range mapping.concepts {
+		var best FilingFact
+		bestScore := 0.0
+		for _, fact := range factsByConcept[concept] {
+			score := matchFactPeriod(fact, period)
+			if score == 0 {
+				continue
+			}
+			if score > bestScore || (score == bestScore && filedAfter(fact, best)) {
+				best, bestScore = fact, score
+			}
+		}
+		if bestScore > 0 {
+			// Fallback concepts are slightly less certain
+			return best, bestScore * math.Pow(0.95, float64(i))
+		}
+	}
+	return FilingFact{}, 0
+}
+
+func filedAfter(a, b FilingFact) bool {
+	if a.FiledAt == nil || b.FiledAt == nil {
+		return false
+	}
+	return a.FiledAt.After(*b.FiledAt)
+}
+
+// detectPeriodHeaders finds the header row with the most period labels in
+// the first rows of the range. Columns are keyed by index within the range.
+func detectPeriodHeaders(data *RangeData, startRow, startCol int) (*ModelStructure, int, map[int]modelPeriod) {
+	headerRow := -1
+	var periods map[int]modelPeriod
+
+	limit := len(data.Values)
+	if limit > 10 {
+		limit = 10
+	}
+	for r := 0; r < limit; r++ {
+		found := make(map[int]modelPeriod)
+		for c, cell := range data.Values[r] {
+			if cell == nil {
+				continue
+			}
+			if period, ok := parseModelPeriod(fmt.Sprintf("%v", cell)); ok {
+				found[c] = period
+			}
+		}
+		if len(found) > len(periods) {
+			headerRow, periods = r, found
+		}
+	}
+
+	structure := &ModelStructure{
+		DataDirection:   "horizontal",
+		TimeOrientation: "columns",
+		PeriodHeaders:   []PeriodInfo{},
+		PeriodColumns:   []string{},
+		LabelColumns:    []string{},
+	}
+	if headerRow < 0 {
+		return structure, headerRow, nil
+	}
+
+	cols := make([]int, 0, len(periods))
+	for c := range periods {
+		cols = append(cols, c)
+	}
+	sort.Ints(cols)
+
+	for order, c := range cols {
+		period := periods[c]
+		column := strings.TrimRight(getCellAddress(1, startCol+c+1), "0123456789")
+		periodType := "year"
+		if period.Quarter > 0 {
+			periodType = "quarter"
+		}
+		structure.PeriodHeaders = append(structure.PeriodHeaders, PeriodInfo{
+			Column:       column,
+			Header:       fmt.Sprintf("%v", data.Values[headerRow][c]),
+			PeriodType:   periodType,
+			IsHistorical: !period.Projected,
+			IsProjected:  period.Projected,
+			Order:        order,
+		})
+		structure.PeriodColumns = append(structure.PeriodColumns, column)
+	}
+	for c := 0; c < cols[0]; c++ {
+		structure.LabelColumns = append(structure.LabelColumns, strings.TrimRight(getCellAddress(1, startCol+c+1), "0123456789"))
+	}
+	if headerRow+1 < len(data.Values) {
+		structure.FirstDataCell = getCellAddress(startRow+headerRow+2, startCol+cols[0]+1)
+	}
+
+	return structure, headerRow, periods
+}
+
+func rowHasPeriodCells(row []interface{}, periods map[int]modelPeriod) bool {
+	for col := range periods {
+		if col < len(row) && row[col] != nil && fmt.Sprintf("%v", row[col]) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func rowHasFormulaCells(formulas [][]interface{}, r int, periods map[int]modelPeriod) bool {
+	if r >= len(formulas) {
+		return false
+	}
+	for col := range periods {
+		if col < len(formulas[r]) {
+			if f, ok := formulas[r][col].(string); ok && strings.HasPrefix(f, "=") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// queueHistoricalWrites groups proposed cells into one write_range per
+// contiguous run in a row and queues them as a single batch
+func (te *ToolExecutor) queueHistoricalWrites(ctx context.Context, sessionID, toolID string, cells []HistoricalCell) []map[string]interface{} {
+	messageID, _ := ctx.Value("message_id").(string)
+
+	var operations []map[string]interface{}
+	for start := 0; start < len(cells); {
+		end := start + 1
+		for end < len(cells) && isNextCellInRow(cells[end-1].Cell, cells[end].Cell) {
+			end++
+		}
+		run := cells[start:end]
+
+		rangeAddr := run[0].Cell
+		if len(run) > 1 {
+			last := run[len(run)-1].Cell
+			if idx := strings.LastIndex(last, "!"); idx >= 0 {
+				last = last[idx+1:]
+			}
+			rangeAddr += ":" + last
+		}
+
+		values := make([]interface{}, len(run))
+		provenance := make([]map[string]interface{}, len(run))
+		minConfidence := 1.0
+		for i, cell := range run {
+			values[i] = cell.Value
+			provenance[i] = map[string]interface{}{
+				"cell":       cell.Cell,
+				"confidence": cell.Confidence,
+				"source":     cell.Provenance,
+			}
+			if cell.Confidence < minConfidence {
+				minConfidence = cell.Confidence
+			}
+		}
+
+		opInput := map[string]interface{}{
+			"range":               rangeAddr,
+			"values":              [][]interface{}{values},
+			"preserve_formatting": true,
+			"_provenance":         provenance,
+		}
+		opID := fmt.Sprintf("%s_%d", toolID, len(operations))
+		structuredPreview := te.registry.StructuredPreview("write_range", opInput)
+
+		if te.queuedOpsRegistry != nil {
+			queuedOp := map[string]interface{}{
+				"ID":          opID,
+				"SessionID":   sessionID,
+				"Type":        "write_range",
+				"Input":       opInput,
+				"Preview":     structuredPreview,
+				"PreviewType": structuredPreview["preview_type"].(string),
+				"Context":     fmt.Sprintf("Historical %s from filings (confidence %.2f)", run[0].Label, minConfidence),
+				"Priority":    50,
+				"BatchID":     toolID,
+				"MessageID":   messageID,
+			}
+			if registry, ok := te.queuedOpsRegistry.(interface {
+				QueueOperation(interface{}) error
+			}); ok {
+				if err := registry.QueueOperation(queuedOp); err != nil {
+					log.Error().Err(err).Msg("Failed to register queued operation")
+				}
+			}
+		}
+
+		operations = append(operations, map[string]interface{}{
+			"operation_id": opID,
+			"range":        rangeAddr,
+			"label":        run[0].Label,
+			"confidence":   minConfidence,
+		})
+		start = end
+	}
+	return operations
+}
+
+// isNextCellInRow reports whether b is immediately right of a
+func isNextCellInRow(a, b string) bool {
+	aCol, aRow, errA := parseCell(a)
+	bCol, bRow, errB := parseCell(b)
+	return errA == nil && errB == nil && aRow == bRow && bCol == aCol+1
+}
@@ -0,0 +1,233 @@
+package ai
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type historicalsTestBridge struct {
+	ExcelBridge
+	data *RangeData
+}
+
+func (b *historicalsTestBridge) ReadRange(ctx context.Context, sessionID, rangeAddr string, includeFormulas, includeFormatting bool) (*RangeData, error) {
+	return b.data, nil
+}
+
+type staticFacts []FilingFact
+
+func (s staticFacts) FindFacts(ctx context.Context, query FactQuery) ([]FilingFact, error) {
+	return s, nil
+}
+
+func (s staticFacts) Serves(sessionID string) bool {
+	return sessionID != "signalr-session"
+}
+
+func factDate(year int, month time.Month, day int) *time.Time {
+	t := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	return &t
+}
+
+func TestParseModelPeriod(t *testing.T) {
+	tests := []struct {
+		header string
+		want   modelPeriod
+		ok     bool
+	}{
+		{"2023", modelPeriod{Year: 2023}, true},
+		{"FY2023A", modelPeriod{Year: 2023}, true},
+		{"FY23", modelPeriod{Year: 2023}, true},
+		{"FY2030E", modelPeriod{Year: 2030, Projected: true}, true},
+		{"Q1 2024A", modelPeriod{Year: 2024, Quarter: 1}, true},
+		{"3Q22", modelPeriod{Year: 2022, Quarter: 3}, true},
+		{"23", modelPeriod{}, false},
+		{"Revenue", modelPeriod{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseModelPeriod(tt.header)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("parseModelPeriod(%q) = %+v, %v, want %+v, %v", tt.header, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestMatchLabel(t *testing.T) {
+	tests := []struct {
+		label   string
+		concept string
+		minimum float64
+	}{
+		{"Revenue", "Revenues", 1.0},
+		{"Total Revenues ($m)", "Revenues", 1.0},
+		{"Net income (loss)*", "NetIncomeLoss", 1.0},
+		{"SG&A", "SellingGeneralAndAdministrativeExpense", 1.0},
+		{"Total research and development", "ResearchAndDevelopmentExpense", 0.95},
+		{"Capex, net", "PaymentsToAcquirePropertyPlantAndEquipment", 0.8},
+	}
+	for _, tt := range tests {
+		mapping, score := matchLabel(tt.label)
+		if mapping == nil || mapping.concepts[0] != tt.concept || score < tt.minimum {
+			t.Errorf("matchLabel(%q) = %v, %.2f, want %s with at least %.2f", tt.label, mapping, score, tt.concept, tt.minimum)
+		}
+	}
+	if _, score := matchLabel("Widgets shipped"); score >= 0.6 {
+		t.Errorf("unrelated label scored %.2f", score)
+	}
+}
+
+func TestMatchFactPeriod(t *testing.T) {
+	annual := FilingFact{PeriodType: "duration", PeriodStart: factDate(2023, 1, 1), PeriodEnd: factDate(2023, 12, 31)}
+	quarter := FilingFact{PeriodType: "duration", PeriodStart: factDate(2023, 4, 1), PeriodEnd: factDate(2023, 6, 30)}
+	instant := FilingFact{PeriodType: "instant", PeriodEnd: factDate(2023, 9, 30)}
+
+	tests := []struct {
+		name   string
+		fact   FilingFact
+		period modelPeriod
+		want   float64
+	}{
+		{"annual duration", annual, modelPeriod{Year: 2023}, 1.0},
+		{"annual duration in a quarter", annual, modelPeriod{Year: 2023, Quarter: 4}, 0},
+		{"other year", annual, modelPeriod{Year: 2022}, 0},
+		{"quarter duration", quarter, modelPeriod{Year: 2023, Quarter: 2}, 0.9},
+		{"quarter duration in a year", quarter, modelPeriod{Year: 2023}, 0},
+		{"instant in a year", instant, modelPeriod{Year: 2023}, 0.95},
+		{"instant in its quarter", instant, modelPeriod{Year: 2023, Quarter: 3}, 0.9},
+		{"instant in another quarter", instant, modelPeriod{Year: 2023, Quarter: 1}, 0},
+		{"no period end", FilingFact{PeriodType: "instant"}, modelPeriod{Year: 2023}, 0},
+	}
+	for _, tt := range tests {
+		if got := matchFactPeriod(tt.fact, tt.period); got != tt.want {
+			t.Errorf("%s: score = %.2f, want %.2f", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDetectUnitScale(t *testing.T) {
+	tests := []struct {
+		marker string
+		units  string
+		scale  float64
+	}{
+		{"(in thousands)", "thousands", 1e3},
+		{"$000s", "thousands", 1e3},
+		{"USD in millions", "millions", 1e6},
+		{"$mm", "millions", 1e6},
+		{"($bn)", "billions", 1e9},
+		{"Income statement", "units", 1},
+	}
+	for _, tt := range tests {
+		units, scale := detectUnitScale([][]interface{}{{1.0, tt.marker}})
+		if units != tt.units || scale != tt.scale {
+			t.Errorf("detectUnitScale(%q) = %s, %g, want %s, %g", tt.marker, units, scale, tt.units, tt.scale)
+		}
+	}
+}
+
+func TestIsNextCellInRow(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"B3", "C3", true},
+		{"Model!Z3", "Model!AA3", true},
+		{"B3", "D3", false},
+		{"B3", "C4", false},
+		{"C3", "B3", false},
+	}
+	for _, tt := range tests {
+		if got := isNextCellInRow(tt.a, tt.b); got != tt.want {
+			t.Errorf("isNextCellInRow(%s, %s) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestExecutePopulateHistoricals(t *testing.T) {
+	bridge := &historicalsTestBridge{data: &RangeData{
+		Values: [][]interface{}{
+			{"(in millions)", nil, nil, nil},
+			{"", "FY2022A", "FY2023A", "FY2030E"},
+			{"Revenue", nil, nil, nil},
+			{"Gross margin %", 0.4, 0.42, nil},
+			{"Widgets shipped", 10.0, 12.0, nil},
+			{"Net income", nil, 5.0, nil},
+		},
+		Formulas: [][]interface{}{
+			{"", "", "", ""},
+			{"", "", "", ""},
+			{"", "", "", ""},
+			{"", "", "", ""},
+			{"", "", "", ""},
+			{"", "", "=C3*0.1", ""},
+		},
+	}}
+	facts := staticFacts{
+		{DocumentID: "10k-2023", Concept: "us-gaap:Revenues", PeriodType: "duration", PeriodStart: factDate(2022, 1, 1), PeriodEnd: factDate(2022, 12, 31), Unit: "USD", Value: 1000e6},
+		{DocumentID: "10k-2023", Concept: "us-gaap:Revenues", PeriodType: "duration", PeriodStart: factDate(2023, 1, 1), PeriodEnd: factDate(2023, 12, 31), Unit: "USD", Value: 1200e6, Page: 42},
+		{DocumentID: "10k-2023", Concept: "us-gaap:NetIncomeLoss", PeriodType: "duration", PeriodStart: factDate(2022, 1, 1), PeriodEnd: factDate(2022, 12, 31), Unit: "USD", Value: 90e6},
+		{DocumentID: "10k-2023", Concept: "us-gaap:NetIncomeLoss", PeriodType: "duration", PeriodStart: factDate(2023, 1, 1), PeriodEnd: factDate(2023, 12, 31), Unit: "USD", Value: 110e6},
+	}
+	te := &ToolExecutor{excelBridge: bridge, registry: NewToolRegistry(), factSource: facts}
+
+	result, err := te.executePopulateHistoricals(context.Background(), "session", ToolCall{ID: "tool", Input: map[string]interface{}{"range": "Model!A1:D6"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result["status"] != "queued" || result["units"] != "millions" {
+		t.Fatalf("result = %v, want queued in millions", result)
+	}
+
+	cells := result["cells"].([]HistoricalCell)
+	want := map[string]float64{"Model!B3": 1000, "Model!C3": 1200, "Model!B6": 90}
+	if len(cells) != len(want) {
+		t.Fatalf("cells = %+v, want %v", cells, want)
+	}
+	for _, cell := range cells {
+		if value, ok := want[cell.Cell]; !ok || cell.Value != value {
+			t.Errorf("cell %s = %g, want %v", cell.Cell, cell.Value, want)
+		}
+		if cell.Cell == "Model!C3" && cell.Provenance.Page != 42 {
+			t.Errorf("provenance page = %d, want 42", cell.Provenance.Page)
+		}
+	}
+
+	// Revenue B3:C3 is one contiguous write, net income B6 another
+	if operations := result["operations"].([]map[string]interface{}); len(operations) != 2 || operations[0]["range"] != "Model!B3:C3" {
+		t.Errorf("operations = %v, want Model!B3:C3 and Model!B6", operations)
+	}
+	if skipped := result["skipped_cells"].(int); skipped != 1 {
+		t.Errorf("skipped = %d, want the net income formula", skipped)
+	}
+	reasons := make(map[string]string)
+	for _, label := range result["unmatched"].([]UnmatchedLabel) {
+		reasons[label.Label] = label.Reason
+	}
+	if len(reasons) != 2 || reasons["Gross margin %"] == "" || reasons["Widgets shipped"] == "" {
+		t.Errorf("unmatched = %v, want the margin and widgets rows", reasons)
+	}
+}
+
+func TestPopulateHistoricalsNeedsFacts(t *testing.T) {
+	te := &ToolExecutor{registry: NewBuiltinToolRegistry()}
+	te.SetFactSource(staticFacts{})
+	service := &Service{toolExecutor: te}
+
+	for _, tt := range []struct {
+		sessionID string
+		offered   bool
+	}{
+		{"session", true},
+		{"signalr-session", false},
+	} {
+		_, found := te.registry.LookupForSession("populate_historicals", tt.sessionID)
+		offered := false
+		for _, tool := range service.selectRelevantTools(tt.sessionID, "Populate the historicals from the 10-K", nil) {
+			offered = offered || tool.Name == "populate_historicals"
+		}
+		if found != tt.offered || offered != tt.offered {
+			t.Errorf("session %s: lookup found = %v, offered = %v, want %v", tt.sessionID, found, offered, tt.offered)
+		}
+	}
+}
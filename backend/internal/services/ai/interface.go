@@ -53,17 +53,19 @@ type CompletionResponse struct {
 	Actions  []Action   `json:"actions,omitempty"` // Parsed suggested actions
 	ToolCalls []ToolCall `json:"tool_calls,omitempty"` // Tool calls requested by the AI
 	IsFinal   bool       `json:"is_final,omitempty"` // Indicates if this is the final response (no more tool calls expected)
+	Citations []Citation `json:"citations,omitempty"` // Validated citations of memory results in Content
 }
 
 // CompletionChunk represents a streaming chunk
 type CompletionChunk struct {
-	ID       string     `json:"id"`
-	Content  string     `json:"content"`
-	Delta    string     `json:"delta"`
-	Done     bool       `json:"done"`
-	Error    error      `json:"error,omitempty"`
-	Type     string     `json:"type,omitempty"` // "text", "tool_start", "tool_progress", "tool_complete"
-	ToolCall *ToolCall  `json:"tool_call,omitempty"`
+	ID        string     `json:"id"`
+	Content   string     `json:"content"`
+	Delta     string     `json:"delta"`
+	Done      bool       `json:"done"`
+	Error     error      `json:"error,omitempty"`
+	Type      string     `json:"type,omitempty"` // "text", "tool_start", "tool_progress", "tool_complete"
+	ToolCall  *ToolCall  `json:"tool_call,omitempty"`
+	Citations []Citation `json:"citations,omitempty"` // Validated citations of the answer, sent with its final chunk
 }
 
 // Message represents a conversation message
@@ -105,6 +107,7 @@ type FinancialContext struct {
 	NamedRanges       map[string]NamedRangeInfo `json:"named_ranges,omitempty"` // Named ranges in the workbook
 	CrossSheetRefs    map[string]interface{} `json:"cross_sheet_refs,omitempty"` // Cross-sheet references and their values
 	DataSummary       map[string]interface{} `json:"data_summary,omitempty"` // Statistical summary for large datasets
+	ProtectedRanges   []ProtectedRange       `json:"protected_ranges,omitempty"` // Cells the AI must never modify
 }
 
 // NamedRangeInfo represents information about a named range
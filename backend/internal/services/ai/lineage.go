@@ -0,0 +1,271 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Lineage statuses of a write
+const (
+	LineagePending = "pending"
+	LineageApplied = "applied"
+)
+
+// ErrInvalidCell is returned when a cell history is asked for something
+// other than one cell of a known sheet
+var ErrInvalidCell = errors.New("invalid cell")
+
+// Cell history limits
+const (
+	defaultCellHistoryLimit = 20
+	maxCellHistoryLimit     = 100
+)
+
+// LineageRecord is the origin of one write to a range: the message, tool
+// call, model, inputs, sources and approval behind it
+type LineageRecord struct {
+	ID          string                 `json:"id,omitempty"`
+	Workbook    string                 `json:"workbook"`
+	Range       string                 `json:"range"`
+	Value       interface{}            `json:"value,omitempty"`
+	Status      string                 `json:"status"`
+	SessionID   string                 `json:"session_id"`
+	MessageID   string                 `json:"message_id,omitempty"`
+	ToolID      string                 `json:"tool_id"`
+	OperationID string                 `json:"operation_id"`
+	Tool        string                 `json:"tool"`
+	Model       string                 `json:"model,omitempty"`
+	Input       map[string]interface{} `json:"input,omitempty"`
+	Sources     []Citation             `json:"sources,omitempty"`
+	Approval    map[string]interface{} `json:"approval,omitempty"`
+	CreatedAt   time.Time              `json:"created_at"`
+	AppliedAt   *time.Time             `json:"applied_at,omitempty"`
+
+	// Zero-based, inclusive bounds of Range
+	Sheet    string `json:"-"`
+	StartRow int    `json:"-"`
+	StartCol int    `json:"-"`
+	EndRow   int    `json:"-"`
+	EndCol   int    `json:"-"`
+}
+
+// LineageStore keeps the lineage of AI writes per tenant and workbook
+type LineageStore interface {
+	RecordLineage(ctx context.Context, tenant string, records []*LineageRecord) error
+	// CellHistory returns the writes covering a cell, newest first
+	CellHistory(ctx context.Context, tenant, workbook, sheet string, row, col, limit int) ([]*LineageRecord, error)
+}
+
+// SetLineageStore sets where the executor records the lineage of writes
+func (te *ToolExecutor) SetLineageStore(store LineageStore) {
+	te.lineage = store
+}
+
+type modelKey struct{}
+
+// withModel returns a context whose tool calls are attributed to model
+func withModel(ctx context.Context, model string) context.Context {
+	return context.WithValue(ctx, modelKey{}, model)
+}
+
+func modelFromContext(ctx context.Context) string {
+	model, _ := ctx.Value(modelKey{}).(string)
+	return model
+}
+
+// lineageWrite is a range a tool call writes and the queued operation that
+// applies it
+type lineageWrite struct {
+	addr        string
+	operationID string
+}
+
+// lineageWrites returns the ranges a successful write tool call changes.
+// populate_historicals queues one operation per run of cells it fills.
+func lineageWrites(call ToolCall, result *ToolResult) []lineageWrite {
+	if call.Name == "populate_historicals" {
+		content, _ := result.Content.(map[string]interface{})
+		operations, _ := content["operations"].([]map[string]interface{})
+		writes := make([]lineageWrite, 0, len(operations))
+		for _, op := range operations {
+			addr, _ := op["range"].(string)
+			id, _ := op["operation_id"].(string)
+			if addr != "" && id != "" {
+				writes = append(writes, lineageWrite{addr: addr, operationID: id})
+			}
+		}
+		return writes
+	}
+
+	var writes []lineageWrite
+	for _, target := range protectedWriteTargets(call) {
+		writes = append(writes, lineageWrite{addr: target.addr, operationID: call.ID})
+	}
+	return writes
+}
+
+// recordLineage stores the origin of every range a successful write tool
+// call changed. Queued writes are pending until the add-in applies them.
+// Failures are logged and never fail the call.
+func (te *ToolExecutor) recordLineage(ctx context.Context, sessionID string, def *ToolDefinition, call ToolCall, autonomyMode string, result *ToolResult) {
+	if te.lineage == nil || te.excelBridge == nil || def.Permission != "write" || result.IsError {
+		return
+	}
+	session := te.excelBridge.GetSession(sessionID)
+	if session == nil || session.Workbook == "" {
+		return
+	}
+
+	status := LineageApplied
+	if result.Status == "queued" {
+		status = LineagePending
+	}
+	messageID, _ := ctx.Value("message_id").(string)
+	approval := map[string]interface{}{"autonomy_mode": autonomyMode}
+	if policy, ok := result.Details["policy"]; ok {
+		approval["policy"] = policy
+	}
+	var sources []Citation
+	if citations := CitationsFromContext(ctx); citations != nil {
+		sources = citations.All()
+	}
+
+	resolver := &rangeResolver{te: te, sessionID: sessionID}
+	var records []*LineageRecord
+	for _, write := range lineageWrites(call, result) {
+		written, err := resolver.resolve(ctx, write.addr)
+		if err != nil {
+			log.Warn().Err(err).Str("tool", call.Name).Str("range", write.addr).Msg("Cannot record lineage of write")
+			continue
+		}
+		if written.Sheet == "" {
+			written.Sheet = session.ActiveSheet
+		}
+		records = append(records, &LineageRecord{
+			Workbook:    session.Workbook,
+			Range:       written.String(),
+			Status:      status,
+			SessionID:   sessionID,
+			MessageID:   messageID,
+			ToolID:      call.ID,
+			OperationID: write.operationID,
+			Tool:        call.Name,
+			Model:       modelFromContext(ctx),
+			Input:       publicInput(call.Input),
+			Sources:     sources,
+			Approval:    approval,
+			Sheet:       written.Sheet,
+			StartRow:    written.StartRow,
+			StartCol:    written.StartCol,
+			EndRow:      written.EndRow,
+			EndCol:      written.EndCol,
+		})
+	}
+	if len(records) == 0 {
+		return
+	}
+
+	if err := te.lineage.RecordLineage(context.WithoutCancel(ctx), session.MemoryNamespace.Tenant, records); err != nil {
+		log.Error().Err(err).
+			Str("tool", call.Name).
+			Str("tool_id", call.ID).
+			Msg("Failed to record lineage of write")
+	}
+}
+
+// ExplainCell returns the recorded AI writes to a cell, newest first, with
+// the value each put in the cell where the input shows it. Cells without a
+// sheet name are on defaultSheet.
+func ExplainCell(ctx context.Context, store LineageStore, tenant, workbook, cell, defaultSheet string, limit int) ([]*LineageRecord, error) {
+	addr, err := parseRangeAddress(cell)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCell, err)
+	}
+	if addr.Cells() != 1 {
+		return nil, fmt.Errorf("%w: %q is not a single cell", ErrInvalidCell, cell)
+	}
+	if addr.Sheet == "" {
+		addr.Sheet = defaultSheet
+	}
+	if addr.Sheet == "" {
+		return nil, fmt.Errorf("%w: %q needs a sheet name, e.g. Valuation!D15", ErrInvalidCell, cell)
+	}
+	if limit <= 0 || limit > maxCellHistoryLimit {
+		limit = defaultCellHistoryLimit
+	}
+
+	records, err := store.CellHistory(ctx, tenant, workbook, addr.Sheet, addr.StartRow, addr.StartCol, limit)
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		record.Value = record.valueAt(addr.StartRow, addr.StartCol)
+	}
+	return records, nil
+}
+
+// valueAt returns the value or formula the write put in a cell of its range
+func (r *LineageRecord) valueAt(row, col int) interface{} {
+	if formula, ok := r.Input["formula"].(string); ok {
+		return formula
+	}
+
+	var rows []interface{}
+	switch values := r.Input["values"].(type) {
+	case []interface{}:
+		rows = values
+	case [][]interface{}:
+		for _, v := range values {
+			rows = append(rows, v)
+		}
+	}
+	i, j := row-r.StartRow, col-r.StartCol
+	if i < 0 || i >= len(rows) {
+		return nil
+	}
+	cells, ok := rows[i].([]interface{})
+	if !ok || j < 0 || j >= len(cells) {
+		return nil
+	}
+	return cells[j]
+}
+
+// executeExplainCellHistory answers who changed a cell of the session's
+// workbook and why
+func (te *ToolExecutor) executeExplainCellHistory(ctx context.Context, sessionID string, input map[string]interface{}) (map[string]interface{}, error) {
+	if te.lineage == nil {
+		return nil, fmt.Errorf("cell history is not available")
+	}
+	var session *Session
+	if te.excelBridge != nil {
+		session = te.excelBridge.GetSession(sessionID)
+	}
+	if session == nil || session.Workbook == "" {
+		return nil, fmt.Errorf("the workbook of this session is not known yet")
+	}
+
+	cell, _ := input["cell"].(string)
+	limit := 0
+	if l, ok := input["limit"].(float64); ok {
+		limit = int(l)
+	}
+	records, err := ExplainCell(ctx, te.lineage, session.MemoryNamespace.Tenant, session.Workbook, cell, session.ActiveSheet, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	content := map[string]interface{}{
+		"cell":     cell,
+		"workbook": session.Workbook,
+		"history":  records,
+		"count":    len(records),
+	}
+	if len(records) == 0 {
+		content["note"] = "No AI changes are recorded for this cell; its content was entered by a user or before lineage was recorded."
+	}
+	return content, nil
+}
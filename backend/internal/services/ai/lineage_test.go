@@ -0,0 +1,68 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type staticLineage struct {
+	records []*LineageRecord
+	sheet   string
+	row     int
+	col     int
+}
+
+func (s *staticLineage) RecordLineage(ctx context.Context, tenant string, records []*LineageRecord) error {
+	s.records = append(s.records, records...)
+	return nil
+}
+
+func (s *staticLineage) CellHistory(ctx context.Context, tenant, workbook, sheet string, row, col, limit int) ([]*LineageRecord, error) {
+	s.sheet, s.row, s.col = sheet, row, col
+	return s.records, nil
+}
+
+func TestExplainCell(t *testing.T) {
+	store := &staticLineage{records: []*LineageRecord{
+		{Range: "Valuation!C14:D15", StartRow: 13, StartCol: 2, Input: map[string]interface{}{
+			"values": []interface{}{[]interface{}{1.0, 2.0}, []interface{}{3.0, 4.0}},
+		}},
+		{Range: "Valuation!D15", StartRow: 14, StartCol: 3, Input: map[string]interface{}{"formula": "=D14*2"}},
+	}}
+
+	records, err := ExplainCell(context.Background(), store, "tenant", "model.xlsx", "Valuation!D15", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.sheet != "Valuation" || store.row != 14 || store.col != 3 {
+		t.Errorf("looked up %s row %d col %d, want Valuation row 14 col 3", store.sheet, store.row, store.col)
+	}
+	if records[0].Value != 4.0 || records[1].Value != "=D14*2" {
+		t.Errorf("values = %v, %v; want 4 and =D14*2", records[0].Value, records[1].Value)
+	}
+
+	for _, cell := range []string{"Valuation!D15:D16", "D15", "not a cell"} {
+		if _, err := ExplainCell(context.Background(), store, "tenant", "model.xlsx", cell, "", 0); !errors.Is(err, ErrInvalidCell) {
+			t.Errorf("ExplainCell(%q) error = %v, want ErrInvalidCell", cell, err)
+		}
+	}
+}
+
+func TestLineageWrites(t *testing.T) {
+	write := ToolCall{ID: "tool-1", Name: "write_range", Input: map[string]interface{}{"range": "Sheet1!A1:B2"}}
+	writes := lineageWrites(write, &ToolResult{})
+	if len(writes) != 1 || writes[0].addr != "Sheet1!A1:B2" || writes[0].operationID != "tool-1" {
+		t.Errorf("write_range writes = %+v", writes)
+	}
+
+	populate := ToolCall{ID: "tool-2", Name: "populate_historicals"}
+	result := &ToolResult{Content: map[string]interface{}{"operations": []map[string]interface{}{
+		{"operation_id": "op-1", "range": "Sheet1!B2:D2"},
+		{"operation_id": "op-2", "range": "Sheet1!B3:D3"},
+	}}}
+	writes = lineageWrites(populate, result)
+	if len(writes) != 2 || writes[1].operationID != "op-2" || writes[1].addr != "Sheet1!B3:D3" {
+		t.Errorf("populate_historicals writes = %+v", writes)
+	}
+}
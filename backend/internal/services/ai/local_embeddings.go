@@ -0,0 +1,144 @@
+package ai
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/gridmate/backend/internal/memory"
+)
+
+// Feature weights for the local embedder. Whole words dominate; bigrams add
+// phrase order and character trigrams let "revenues" sit near "revenue".
+const (
+	localUnigramWeight = 1.0
+	localBigramWeight  = 0.6
+	localTrigramWeight = 0.25
+	localNumberWeight  = 0.3
+	localTermWeight    = 2.0 // Multiplier for financial vocabulary
+)
+
+// localFinancialVocabulary maps modelling terms and their synonyms to a
+// canonical feature, so "sales" and "turnover" land on "revenue"
+var localFinancialVocabulary = map[string]string{
+	"revenue": "revenue", "revenues": "revenue", "sales": "revenue", "turnover": "revenue", "topline": "revenue",
+	"cogs": "cogs", "cos": "cogs",
+	"ebitda": "ebitda", "ebit": "ebit", "ebt": "ebt",
+	"opex": "opex", "sga": "opex", "overheads": "opex",
+	"capex": "capex", "capital": "capital",
+	"depreciation": "depreciation", "amortization": "amortization", "amortisation": "amortization",
+	"profit": "profit", "income": "income", "earnings": "income", "eps": "eps",
+	"margin": "margin", "margins": "margin",
+	"cash": "cash", "fcf": "fcf", "ufcf": "fcf", "lfcf": "fcf",
+	"debt": "debt", "borrowings": "debt", "leverage": "leverage",
+	"equity": "equity", "dividend": "dividend", "dividends": "dividend",
+	"wacc": "wacc", "irr": "irr", "npv": "npv", "dcf": "dcf", "moic": "moic",
+	"tax": "tax", "taxes": "tax", "interest": "interest",
+	"assets": "assets", "liabilities": "liabilities", "inventory": "inventory",
+	"receivables": "receivables", "payables": "payables", "nwc": "nwc",
+	"growth": "growth", "cagr": "cagr", "yoy": "growth",
+	"valuation": "valuation", "multiple": "multiple", "multiples": "multiple",
+	"terminal": "terminal", "beta": "beta", "discount": "discount",
+}
+
+// LocalEmbeddingProvider generates embeddings offline with signed feature
+// hashing over TF-weighted word unigrams, bigrams and character trigrams.
+// It captures lexical rather than semantic similarity, but is deterministic,
+// needs no network and ranks related spreadsheet and document text sensibly.
+type LocalEmbeddingProvider struct {
+	dimensions int
+}
+
+// NewLocalEmbeddingProvider creates a local embedder with the same
+// dimensionality as OpenAI ada-002, so vectors fit the same stores
+func NewLocalEmbeddingProvider() *LocalEmbeddingProvider {
+	return NewLocalEmbeddingProviderWithDimensions(1536)
+}
+
+// NewLocalEmbeddingProviderWithDimensions creates a local embedder with a
+// custom vector size
+func NewLocalEmbeddingProviderWithDimensions(dimensions int) *LocalEmbeddingProvider {
+	if dimensions < 16 {
+		dimensions = 16
+	}
+	return &LocalEmbeddingProvider{dimensions: dimensions}
+}
+
+// GetEmbedding generates a local embedding. Text without any indexable
+// terms yields a zero vector.
+func (l *LocalEmbeddingProvider) GetEmbedding(ctx context.Context, text string) ([]float32, error) {
+	features := make(map[string]float64)
+	tokens := memory.Tokenize(text)
+
+	for i, token := range tokens {
+		weight := localUnigramWeight
+		if isNumericToken(token) {
+			weight = localNumberWeight
+		}
+		if canonical, ok := localFinancialVocabulary[token]; ok {
+			token = canonical
+			weight *= localTermWeight
+		}
+		features["w:"+token] += weight
+
+		if i > 0 {
+			features["b:"+canonicalToken(tokens[i-1])+" "+token] += localBigramWeight
+		}
+
+		if len(token) >= 4 && !isNumericToken(token) {
+			padded := "#" + token + "#"
+			for j := 0; j+3 <= len(padded); j++ {
+				features["c:"+padded[j:j+3]] += localTrigramWeight
+			}
+		}
+	}
+
+	embedding := make([]float32, l.dimensions)
+	for feature, tf := range features {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+
+		// Sublinear term frequency; the hash's top bit picks the sign so
+		// collisions cancel out on average instead of accumulating
+		value := float32(1 + math.Log(tf))
+		if tf < 1 {
+			value = float32(tf)
+		}
+		if sum>>63 == 1 {
+			value = -value
+		}
+		embedding[sum%uint64(l.dimensions)] += value
+	}
+
+	return memory.NormalizeVector(embedding), nil
+}
+
+// GetEmbeddings batch processes multiple texts
+func (l *LocalEmbeddingProvider) GetEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	results := make([][]float32, len(texts))
+	for i, text := range texts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		embedding, err := l.GetEmbedding(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = embedding
+	}
+	return results, nil
+}
+
+func canonicalToken(token string) string {
+	if canonical, ok := localFinancialVocabulary[token]; ok {
+		return canonical
+	}
+	return token
+}
+
+func isNumericToken(token string) bool {
+	return strings.IndexFunc(token, func(r rune) bool { return !unicode.IsDigit(r) }) < 0
+}
@@ -0,0 +1,79 @@
+package ai
+
+import (
+	"context"
+	"sort"
+	"testing"
+)
+
+func cosine(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+func TestLocalEmbeddingProvider_Deterministic(t *testing.T) {
+	provider := NewLocalEmbeddingProvider()
+	ctx := context.Background()
+
+	a, _ := provider.GetEmbedding(ctx, "EBITDA margin by segment")
+	b, _ := provider.GetEmbedding(ctx, "EBITDA margin by segment")
+	if len(a) != 1536 {
+		t.Fatalf("len = %d, want 1536", len(a))
+	}
+	if sim := cosine(a, b); sim < 0.9999 {
+		t.Errorf("identical text similarity = %f, want 1", sim)
+	}
+
+	empty, _ := provider.GetEmbedding(ctx, "the of and")
+	if sim := cosine(a, empty); sim != 0 {
+		t.Errorf("stop-word-only text similarity = %f, want 0", sim)
+	}
+}
+
+func TestLocalEmbeddingProvider_Ranking(t *testing.T) {
+	provider := NewLocalEmbeddingProvider()
+	ctx := context.Background()
+
+	docs := []string{
+		"Employee headcount by department and office location",
+		"Revenue growth assumptions for FY2024 and FY2025",
+		"Sales grew 12% year over year driven by pricing",
+		"Weighted average cost of capital (WACC) inputs: beta, risk-free rate",
+	}
+	vectors, err := provider.GetEmbeddings(ctx, docs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rank := func(query string) []int {
+		q, _ := provider.GetEmbedding(ctx, query)
+		order := []int{0, 1, 2, 3}
+		sort.SliceStable(order, func(i, j int) bool {
+			return cosine(q, vectors[order[i]]) > cosine(q, vectors[order[j]])
+		})
+		return order
+	}
+
+	tests := []struct {
+		query string
+		top   int
+	}{
+		{"revenue growth", 1},
+		{"discount rate WACC beta", 3},
+		{"headcount per department", 0},
+		{"sales grew", 2},
+	}
+	for _, tt := range tests {
+		if order := rank(tt.query); order[0] != tt.top {
+			t.Errorf("%q: top = %d, want %d (order %v)", tt.query, order[0], tt.top, order)
+		}
+	}
+
+	// Synonyms meet through the financial vocabulary
+	if order := rank("turnover"); order[0] != 2 && order[1] != 2 {
+		t.Errorf("sales document not matched by a revenue synonym: %v", order)
+	}
+}
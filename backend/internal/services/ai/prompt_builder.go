@@ -126,6 +126,11 @@ func (pb *PromptBuilder) buildContextPrompt(context *FinancialContext) string {
 		parts = append(parts, fmt.Sprintf("  <selection>%s</selection>", context.SelectedRange))
 	}
 
+	// Protected ranges - the model must plan around them
+	if len(context.ProtectedRanges) > 0 {
+		parts = append(parts, pb.buildProtectedRangesSection(context.ProtectedRanges))
+	}
+
 	// For empty spreadsheets, provide minimal context
 	if context.ModelType == "Empty" {
 		parts = append(parts, "  <status>The spreadsheet is currently empty.</status>")
@@ -388,6 +393,22 @@ func (pb *PromptBuilder) buildDocumentContextSection(docs []string) string {
 	return strings.Join(parts, "\n")
 }
 
+// buildProtectedRangesSection lists the cells writes will be refused for
+func (pb *PromptBuilder) buildProtectedRangesSection(protections []ProtectedRange) string {
+	var parts []string
+	parts = append(parts, "  <protected_ranges>")
+	parts = append(parts, "    <rule>Never write to, format, or insert rows or columns that shift these cells. Such changes are refused; plan around them or ask the user.</rule>")
+	for _, protection := range protections {
+		entry := protection.Target
+		if protection.Reason != "" {
+			entry += " - " + protection.Reason
+		}
+		parts = append(parts, fmt.Sprintf("    <protected kind=\"%s\">%s</protected>", protection.Kind, entry))
+	}
+	parts = append(parts, "  </protected_ranges>")
+	return strings.Join(parts, "\n")
+}
+
 // buildPendingOperationsSection builds the pending operations section with enhanced context
 func (pb *PromptBuilder) buildPendingOperationsSection(pendingOps interface{}) string {
 	// Type assert to map for flexibility
@@ -512,7 +533,7 @@ When information is not in the immediate context, use the search_memory tool to:
 - Recall earlier discussions or decisions
 - Locate complex calculations or model sections
 
-Always cite sources when using retrieved information (e.g., "According to the Q4 report..." or "As we discussed earlier...")
+Every search_memory result has a citation_id such as S1. When a statement relies on a retrieved result, cite it inline with that ID in square brackets, e.g. "Revenue grew 12% in Q4 [S1]" or "[S1, S3]". Only cite IDs that search_memory returned.
 </memory_capabilities>
 
 <communication_standards>
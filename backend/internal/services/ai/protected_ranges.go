@@ -0,0 +1,264 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Protected range kinds
+const (
+	ProtectedKindRange      = "range"
+	ProtectedKindNamedRange = "named_range"
+	ProtectedKindSheet      = "sheet"
+)
+
+// defaultOrganizeRange is the range organize_financial_model scans when
+// none is given
+const defaultOrganizeRange = "A1:Z100"
+
+// ProtectedRange is a part of a workbook the AI must never modify
+type ProtectedRange struct {
+	ID     string `json:"id,omitempty"`
+	Kind   string `json:"kind"`
+	Target string `json:"target"`
+	Reason string `json:"reason,omitempty"`
+}
+
+func (p ProtectedRange) String() string {
+	switch p.Kind {
+	case ProtectedKindSheet:
+		return fmt.Sprintf("sheet %q", p.Target)
+	case ProtectedKindNamedRange:
+		return fmt.Sprintf("named range %q", p.Target)
+	}
+	return p.Target
+}
+
+// ProtectedRangeSource loads the protected ranges of a tenant's workbook
+type ProtectedRangeSource interface {
+	ProtectedRanges(ctx context.Context, tenant, workbook string) ([]ProtectedRange, error)
+}
+
+// ValidateProtectedRange checks that target can be read as kind
+func ValidateProtectedRange(kind, target string) error {
+	if strings.TrimSpace(target) == "" {
+		return fmt.Errorf("target is required")
+	}
+	switch kind {
+	case ProtectedKindRange:
+		if _, err := parseRangeAddress(target); err != nil {
+			return err
+		}
+	case ProtectedKindNamedRange, ProtectedKindSheet:
+		if strings.ContainsAny(target, "!:") {
+			return fmt.Errorf("invalid %s %q", strings.ReplaceAll(kind, "_", " "), target)
+		}
+	default:
+		return fmt.Errorf("unknown protected range kind %q", kind)
+	}
+	return nil
+}
+
+// SetProtectedRangeSource sets where the executor loads protected ranges from
+func (te *ToolExecutor) SetProtectedRangeSource(source ProtectedRangeSource) {
+	te.protectedRanges = source
+}
+
+// ProtectedRanges returns the protected ranges of the session's workbook.
+// The protections of a session whose workbook is not known cannot be
+// loaded, so its writes are refused.
+func (te *ToolExecutor) ProtectedRanges(ctx context.Context, sessionID string) ([]ProtectedRange, error) {
+	if te.protectedRanges == nil || te.excelBridge == nil {
+		return nil, nil
+	}
+	session := te.excelBridge.GetSession(sessionID)
+	if session == nil {
+		return nil, fmt.Errorf("session %s not found", sessionID)
+	}
+	if session.Workbook == "" {
+		return nil, fmt.Errorf("the workbook of session %s is not known", sessionID)
+	}
+	return te.protectedRanges.ProtectedRanges(ctx, session.MemoryNamespace.Tenant, session.Workbook)
+}
+
+// checkProtectedRanges refuses write tool calls that would change a
+// protected range. It fails closed: a write is refused when the
+// protections or the cells it changes cannot be resolved.
+func (te *ToolExecutor) checkProtectedRanges(ctx context.Context, sessionID string, def *ToolDefinition, call ToolCall) error {
+	if def.Permission != "write" {
+		return nil
+	}
+	protections, err := te.ProtectedRanges(ctx, sessionID)
+	if err != nil {
+		log.Error().Err(err).Str("session", sessionID).Msg("Failed to load protected ranges")
+		return newEnhancedError(
+			"Write refused: the workbook's protected ranges could not be loaded",
+			err.Error(),
+			"Try again later, or ask the user to make this change.",
+		)
+	}
+	if len(protections) == 0 {
+		return nil
+	}
+
+	activeSheet := ""
+	if session := te.excelBridge.GetSession(sessionID); session != nil {
+		activeSheet = session.ActiveSheet
+	}
+	resolver := &rangeResolver{te: te, sessionID: sessionID}
+
+	for _, target := range protectedWriteTargets(call) {
+		written, err := resolver.resolve(ctx, target.addr)
+		if err != nil {
+			return newEnhancedError(
+				fmt.Sprintf("Write refused: could not tell which cells %s changes", call.Name),
+				err.Error(),
+				"Use an A1 address such as Sheet1!B2:D10 so the change can be checked against the protected ranges.",
+			)
+		}
+		if written.Sheet == "" {
+			written.Sheet = activeSheet
+		}
+		written = written.shiftedBy(target.shift)
+
+		for _, protection := range protections {
+			protected, err := resolver.protectedRange(ctx, protection)
+			if err != nil {
+				return newEnhancedError(
+					fmt.Sprintf("Write refused: protected %s could not be resolved", protection),
+					err.Error(),
+					"Ask the user to check or remove this protection.",
+				)
+			}
+			if !written.Overlaps(protected) {
+				continue
+			}
+			detail := fmt.Sprintf("%s is protected and must never be modified by the assistant.", protection)
+			if protection.Reason != "" {
+				detail = fmt.Sprintf("%s is protected: %s", protection, protection.Reason)
+			}
+			log.Warn().
+				Str("tool", call.Name).
+				Str("session", sessionID).
+				Str("target", target.addr).
+				Str("protected", protection.String()).
+				Msg("Refused write to protected range")
+			return newEnhancedError(
+				fmt.Sprintf("Write refused: %s would change %s, which is protected", call.Name, protection),
+				detail,
+				"Leave the protected cells unchanged: write outside them, or ask the user to make this change.",
+			)
+		}
+	}
+	return nil
+}
+
+// writeTarget is an address a write changes. shift is "rows" or
+// "columns" when the write also moves every row or column after it.
+type writeTarget struct {
+	addr  string
+	shift string
+}
+
+// protectedWriteTargets returns the cells a write tool call changes.
+// Inserting rows or columns, and organizing a model into sections, moves
+// the cells after the insertion point as well.
+func protectedWriteTargets(call ToolCall) []writeTarget {
+	switch call.Name {
+	case "create_named_range", "create_chart":
+		// Names and charts sit on top of cells without changing them
+		return nil
+	case "insert_rows_columns":
+		position, _ := call.Input["position"].(string)
+		shift, _ := call.Input["type"].(string)
+		if upper := strings.ToUpper(position); isLetters(upper) || isDigits(upper) {
+			position += ":" + position
+		}
+		return []writeTarget{{addr: position, shift: shift}}
+	case "organize_financial_model":
+		addr, _ := call.Input["analysis_range"].(string)
+		if addr == "" {
+			addr = defaultOrganizeRange
+		}
+		return []writeTarget{{addr: addr, shift: "rows"}}
+	}
+
+	var targets []writeTarget
+	for _, key := range []string{"range", "target_range", "target_cell"} {
+		if addr, ok := call.Input[key].(string); ok && addr != "" {
+			targets = append(targets, writeTarget{addr: addr})
+		}
+	}
+	return targets
+}
+
+// shiftedBy extends a range over the rows or columns an insertion moves
+func (r rangeAddress) shiftedBy(shift string) rangeAddress {
+	switch shift {
+	case "rows":
+		r.EndRow = maxSheetRows - 1
+		r.StartCol, r.EndCol = 0, maxSheetCols-1
+	case "columns":
+		r.EndCol = maxSheetCols - 1
+		r.StartRow, r.EndRow = 0, maxSheetRows-1
+	}
+	return r
+}
+
+// rangeResolver turns addresses and protections into cell ranges, loading
+// the workbook's named ranges once when they are needed
+type rangeResolver struct {
+	te          *ToolExecutor
+	sessionID   string
+	namedRanges []NamedRange
+	loaded      bool
+}
+
+func (r *rangeResolver) resolve(ctx context.Context, addr string) (rangeAddress, error) {
+	if parsed, err := parseRangeAddress(addr); err == nil {
+		return parsed, nil
+	}
+	return r.named(ctx, addr)
+}
+
+func (r *rangeResolver) protectedRange(ctx context.Context, protection ProtectedRange) (rangeAddress, error) {
+	switch protection.Kind {
+	case ProtectedKindSheet:
+		return wholeSheet(protection.Target), nil
+	case ProtectedKindNamedRange:
+		return r.named(ctx, protection.Target)
+	}
+	return parseRangeAddress(protection.Target)
+}
+
+// named resolves a workbook named range
+func (r *rangeResolver) named(ctx context.Context, name string) (rangeAddress, error) {
+	if !r.loaded {
+		namedRanges, err := r.te.excelBridge.GetNamedRanges(ctx, r.sessionID, "workbook")
+		if err != nil {
+			return rangeAddress{}, fmt.Errorf("failed to get named ranges: %w", err)
+		}
+		r.namedRanges, r.loaded = namedRanges, true
+	}
+	for _, nr := range r.namedRanges {
+		if !strings.EqualFold(nr.Name, name) {
+			continue
+		}
+		addr := nr.Address
+		if addr == "" {
+			addr = nr.Range
+		}
+		parsed, err := parseRangeAddress(addr)
+		if err != nil {
+			return rangeAddress{}, fmt.Errorf("named range %q has invalid address: %w", name, err)
+		}
+		if parsed.Sheet == "" {
+			parsed.Sheet = nr.Sheet
+		}
+		return parsed, nil
+	}
+	return rangeAddress{}, fmt.Errorf("%q is neither a cell address nor a named range", name)
+}
@@ -0,0 +1,100 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+type protectedTestBridge struct {
+	ExcelBridge
+	workbook    string
+	namedRanges []NamedRange
+}
+
+func (b *protectedTestBridge) GetSession(sessionID string) *Session {
+	return &Session{Workbook: b.workbook, ActiveSheet: "Model"}
+}
+
+func (b *protectedTestBridge) GetNamedRanges(ctx context.Context, sessionID, scope string) ([]NamedRange, error) {
+	return b.namedRanges, nil
+}
+
+type staticProtectedRanges []ProtectedRange
+
+func (s staticProtectedRanges) ProtectedRanges(ctx context.Context, tenant, workbook string) ([]ProtectedRange, error) {
+	return s, nil
+}
+
+func TestCheckProtectedRanges(t *testing.T) {
+	protections := staticProtectedRanges{
+		{Kind: ProtectedKindSheet, Target: "Assumptions", Reason: "Reviewed by the deal team"},
+		{Kind: ProtectedKindRange, Target: "Model!B2:D10"},
+		{Kind: ProtectedKindNamedRange, Target: "Historicals"},
+	}
+	bridge := &protectedTestBridge{workbook: "Model.xlsx", namedRanges: []NamedRange{
+		{Name: "Historicals", Address: "$F$2:$H$20", Sheet: "Model"},
+		{Name: "Outputs", Address: "Model!J2:J5"},
+	}}
+	te := &ToolExecutor{excelBridge: bridge, protectedRanges: protections}
+	write := &ToolDefinition{ExcelTool: ExcelTool{Permission: "write"}}
+
+	tests := []struct {
+		name    string
+		tool    string
+		input   map[string]interface{}
+		refused string
+	}{
+		{"protected sheet", "write_range", map[string]interface{}{"range": "'assumptions'!A1"}, `sheet "Assumptions"`},
+		{"active sheet address", "apply_formula", map[string]interface{}{"range": "C5"}, "Model!B2:D10"},
+		{"other sheet", "write_range", map[string]interface{}{"range": "Summary!C5"}, ""},
+		{"outside the range", "format_range", map[string]interface{}{"range": "E1:E20"}, ""},
+		{"named range protection", "write_range", map[string]interface{}{"range": "Model!G3"}, `named range "Historicals"`},
+		{"named range target", "write_range", map[string]interface{}{"range": "Outputs"}, ""},
+		{"unknown target", "write_range", map[string]interface{}{"range": "Revenue"}, "could not tell"},
+		{"inserted rows shift protected cells", "insert_rows_columns", map[string]interface{}{"position": "8", "type": "rows"}, "Model!B2:D10"},
+		{"inserted rows below", "insert_rows_columns", map[string]interface{}{"position": "A30", "type": "rows"}, ""},
+		{"organize the model", "organize_financial_model", map[string]interface{}{}, "Model!B2:D10"},
+		{"named range creation", "create_named_range", map[string]interface{}{"name": "Growth", "range": "B2"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := te.checkProtectedRanges(context.Background(), "s1", write, ToolCall{Name: tt.tool, Input: tt.input})
+			switch {
+			case tt.refused == "" && err != nil:
+				t.Errorf("write refused: %v", err)
+			case tt.refused != "" && err == nil:
+				t.Errorf("write allowed, want refusal mentioning %s", tt.refused)
+			case err != nil && !strings.Contains(err.Error(), tt.refused):
+				t.Errorf("error %q does not mention %s", err, tt.refused)
+			}
+		})
+	}
+
+	read := &ToolDefinition{ExcelTool: ExcelTool{Permission: "read"}}
+	if err := te.checkProtectedRanges(context.Background(), "s1", read, ToolCall{Name: "read_range", Input: map[string]interface{}{"range": "Assumptions!A1"}}); err != nil {
+		t.Errorf("reads should never be refused: %v", err)
+	}
+
+	// Without the workbook name the protections cannot be loaded
+	bridge.workbook = ""
+	if err := te.checkProtectedRanges(context.Background(), "s1", write, ToolCall{Name: "write_range", Input: map[string]interface{}{"range": "Summary!C5"}}); err == nil {
+		t.Error("write allowed in a session without a workbook, want refusal")
+	}
+}
+
+func TestValidateProtectedRange(t *testing.T) {
+	valid := [][2]string{{"range", "Model!B2:D10"}, {"range", "C:C"}, {"named_range", "Historicals"}, {"sheet", "Q1 Plan"}}
+	for _, v := range valid {
+		if err := ValidateProtectedRange(v[0], v[1]); err != nil {
+			t.Errorf("ValidateProtectedRange(%q, %q): %v", v[0], v[1], err)
+		}
+	}
+	invalid := [][2]string{{"range", "Revenue"}, {"sheet", ""}, {"sheet", "Model!A1"}, {"cell", "A1"}}
+	for _, v := range invalid {
+		if err := ValidateProtectedRange(v[0], v[1]); err == nil {
+			t.Errorf("ValidateProtectedRange(%q, %q) should fail", v[0], v[1])
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package ai
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Excel's sheet limits, used for whole-row and whole-column references
+const (
+	maxSheetRows = 1048576
+	maxSheetCols = 16384
+)
+
+// a1Reference matches cell, cell range, whole-column and whole-row
+// references without a sheet name
+var a1Reference = regexp.MustCompile(`^([A-Z]{1,3}[0-9]+(:[A-Z]{1,3}[0-9]+)?|[A-Z]{1,3}:[A-Z]{1,3}|[0-9]+:[0-9]+)$`)
+
+// rangeAddress is a parsed A1 reference. Rows and columns are zero-based
+// and inclusive. An empty Sheet means the sheet is not known.
+type rangeAddress struct {
+	Sheet    string
+	StartRow int
+	StartCol int
+	EndRow   int
+	EndCol   int
+}
+
+// parseRangeAddress parses references such as "B2", "Sheet1!A1:C10",
+// "'Q1 Plan'!A:A" and "3:5"
+func parseRangeAddress(addr string) (rangeAddress, error) {
+	var r rangeAddress
+	addr = strings.TrimSpace(addr)
+	if idx := strings.LastIndex(addr, "!"); idx >= 0 {
+		r.Sheet = unquoteSheetName(addr[:idx])
+		addr = addr[idx+1:]
+	}
+	addr = strings.ToUpper(strings.ReplaceAll(addr, "$", ""))
+	if !a1Reference.MatchString(addr) {
+		return r, fmt.Errorf("invalid range address %q", addr)
+	}
+
+	start, end, isRange := strings.Cut(addr, ":")
+	if !isRange {
+		end = start
+	}
+
+	switch {
+	case isLetters(start) && isLetters(end):
+		// Whole columns
+		r.StartCol, r.EndCol = columnIndex(start), columnIndex(end)
+		r.StartRow, r.EndRow = 0, maxSheetRows-1
+	case isDigits(start) && isDigits(end):
+		// Whole rows
+		var err error
+		if _, err = fmt.Sscan(start, &r.StartRow); err == nil {
+			_, err = fmt.Sscan(end, &r.EndRow)
+		}
+		if err != nil || r.StartRow < 1 || r.EndRow < 1 {
+			return r, fmt.Errorf("invalid row reference %q", addr)
+		}
+		r.StartRow--
+		r.EndRow--
+		r.StartCol, r.EndCol = 0, maxSheetCols-1
+	default:
+		var err error
+		if r.StartCol, r.StartRow, err = parseCell(start); err != nil {
+			return r, err
+		}
+		if r.EndCol, r.EndRow, err = parseCell(end); err != nil {
+			return r, err
+		}
+	}
+
+	r.StartRow, r.EndRow = min(r.StartRow, r.EndRow), max(r.StartRow, r.EndRow)
+	r.StartCol, r.EndCol = min(r.StartCol, r.EndCol), max(r.StartCol, r.EndCol)
+	return r, nil
+}
+
+// wholeSheet is a reference to every cell of a sheet
+func wholeSheet(sheet string) rangeAddress {
+	return rangeAddress{Sheet: sheet, EndRow: maxSheetRows - 1, EndCol: maxSheetCols - 1}
+}
+
+// Cells returns the number of cells in the range
+func (r rangeAddress) Cells() int {
+	return (r.EndRow - r.StartRow + 1) * (r.EndCol - r.StartCol + 1)
+}
+
+// Overlaps reports whether two ranges share a cell. Ranges on an unknown
+// sheet are assumed to be on the same sheet as the other.
+func (r rangeAddress) Overlaps(o rangeAddress) bool {
+	if r.Sheet != "" && o.Sheet != "" && !strings.EqualFold(r.Sheet, o.Sheet) {
+		return false
+	}
+	return r.StartRow <= o.EndRow && o.StartRow <= r.EndRow &&
+		r.StartCol <= o.EndCol && o.StartCol <= r.EndCol
+}
+
+// CellAddress returns the A1 address of the cell at an offset from the
+// range's top-left corner
+func (r rangeAddress) CellAddress(rowOffset, colOffset int) string {
+	cell := getCellAddress(r.StartRow+rowOffset+1, r.StartCol+colOffset+1)
+	if r.Sheet != "" {
+		return r.Sheet + "!" + cell
+	}
+	return cell
+}
+
+func (r rangeAddress) String() string {
+	addr := r.CellAddress(0, 0)
+	if r.Cells() > 1 {
+		addr += ":" + getCellAddress(r.EndRow+1, r.EndCol+1)
+	}
+	return addr
+}
+
+func unquoteSheetName(name string) string {
+	if len(name) >= 2 && name[0] == '\'' && name[len(name)-1] == '\'' {
+		name = strings.ReplaceAll(name[1:len(name)-1], "''", "'")
+	}
+	return name
+}
+
+// columnIndex converts column letters to a zero-based index
+func columnIndex(letters string) int {
+	col := 0
+	for i := 0; i < len(letters); i++ {
+		col = col*26 + int(letters[i]-'A') + 1
+	}
+	return col - 1
+}
+
+func isLetters(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 'A' || s[i] > 'Z' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -23,6 +23,7 @@ type StreamingSession struct {
 	CurrentPhase     StreamPhase // "initial" | "tool_execution" | "final"
 	MessageID        string
 	AutonomyMode     string
+	citations        *citationStream
 }
 
 // StreamPhase represents the current phase of a streaming session
@@ -131,12 +132,12 @@ func (s *Service) ProcessChatMessage(ctx context.Context, userMessage string, co
 
 	// Add Excel tools if enabled - use smart selection for better token efficiency
 	if s.config.EnableActions && s.toolExecutor != nil {
-		request.Tools = s.selectRelevantTools(userMessage, context)
+		request.Tools = s.selectRelevantTools("", userMessage, context)
 		log.Info().
 			Bool("actions_enabled", s.config.EnableActions).
 			Bool("tool_executor_available", s.toolExecutor != nil).
 			Int("tools_count", len(request.Tools)).
-			Int("total_available_tools", len(GetExcelTools())).
+			Int("total_available_tools", len(s.toolRegistry().Tools())).
 			Msg("Adding relevant Excel tools to request using smart selection")
 	} else {
 		log.Warn().
@@ -173,6 +174,11 @@ func (s *Service) ProcessChatMessageStreaming(ctx context.Context, sessionID str
 		Int("history_length", len(chatHistory)).
 		Msg("Starting ProcessChatMessageStreaming")
 
+	// Memory results retrieved while answering get citation IDs, which
+	// are validated as the answer streams
+	citations := NewCitationSet()
+	ctx = WithCitations(ctx, citations)
+
 	// Create streaming session
 	session := &StreamingSession{
 		ID:               sessionID,
@@ -183,6 +189,7 @@ func (s *Service) ProcessChatMessageStreaming(ctx context.Context, sessionID str
 		CurrentPhase:     StreamPhaseInitial,
 		MessageID:        messageID,
 		AutonomyMode:     autonomyMode,
+		citations:        newCitationStream(citations),
 	}
 
 	// Add message ID to context for tool executor
@@ -269,9 +276,9 @@ func (s *Service) processStreamingWithContinuation(session *StreamingSession, ou
 		if s.config.EnableActions && s.toolExecutor != nil {
 			if round == 0 && len(session.Messages) > 0 {
 				lastMsg := session.Messages[len(session.Messages)-1]
-				request.Tools = s.selectRelevantTools(lastMsg.Content, session.FinancialContext)
+				request.Tools = s.selectRelevantTools(session.ID, lastMsg.Content, session.FinancialContext)
 			} else {
-				request.Tools = GetExcelTools()
+				request.Tools = s.toolRegistry().SessionTools(session.ID)
 			}
 			log.Info().
 				Int("tools_count", len(request.Tools)).
@@ -311,6 +318,14 @@ func (s *Service) processStreamingWithContinuation(session *StreamingSession, ou
 		if len(toolCalls) == 0 {
 			if finalChunk != nil {
 				outChan <- *finalChunk
+			} else {
+				// The provider closed without a done chunk; send what was
+				// held back and the citations with one
+				final := CompletionChunk{Done: true}
+				if rest := session.citations.finish(session.ID, &final); rest != nil {
+					outChan <- *rest
+				}
+				outChan <- final
 			}
 			return
 		}
@@ -389,6 +404,21 @@ func (s *Service) processStreamingChunks(chunks <-chan CompletionChunk, outChan
 			Bool("has_content", chunk.Content != "").
 			Bool("is_done", chunk.Done).
 			Msg("[STREAMING] Processing chunk from provider")
+		// Remove invented citations as the answer streams, and send the
+		// answer's citations with its final chunk
+		if !session.citations.filter(&chunk) {
+			continue
+		}
+		if chunk.Done && chunk.Type == "" && len(toolCalls) == 0 && currentToolCall == nil {
+			if rest := session.citations.finish(session.ID, &chunk); rest != nil {
+				select {
+				case outChan <- *rest:
+				case <-session.Context.Done():
+					return nil, nil
+				}
+			}
+		}
+
 		// Forward the chunk (except for internal processing chunks)
 		if chunk.Type != "tool_internal" {
 			select {
@@ -712,6 +742,13 @@ func (s *Service) ProcessToolCalls(ctx context.Context, sessionID string, toolCa
 		return nil, fmt.Errorf("tool executor not initialized")
 	}
 
+	// Attribute the calls' writes to the model that made them
+	model := s.config.DefaultModel
+	if model == "" && s.provider != nil {
+		model = s.provider.GetProviderName()
+	}
+	ctx = withModel(ctx, model)
+
 	// Detect batchable operations for efficiency
 	batches := s.toolExecutor.DetectBatchableOperations(toolCalls)
 	log.Info().
@@ -814,8 +851,8 @@ func countSuccessful(results []ToolResult) int {
 
 // selectRelevantTools intelligently selects which tools to include based on the user's message
 // This reduces token usage by only including tools that are likely to be needed
-func (s *Service) selectRelevantTools(userMessage string, context *FinancialContext) []ExcelTool {
-	allTools := GetExcelTools()
+func (s *Service) selectRelevantTools(sessionID string, userMessage string, context *FinancialContext) []ExcelTool {
+	registry := s.toolRegistry()
 
 	// Convert message to lowercase for easier matching
 	msgLower := strings.ToLower(userMessage)
@@ -856,57 +893,40 @@ func (s *Service) selectRelevantTools(userMessage string, context *FinancialCont
 		}
 	}
 
-	// Build tool list based on request type
-	var selectedTools []ExcelTool
-
-	// If the spreadsheet is empty and user wants to create something, include write tools
+	// Pick the tool group for the request type
+	group := ToolGroupBasic
 	if context != nil && len(context.CellValues) == 0 && (isWriteRequest || isModelRequest) {
 		// For empty spreadsheet, include only essential tools for creation
-		for _, tool := range allTools {
-			switch tool.Name {
-			case "write_range", "apply_formula", "format_range", "apply_layout":
-				selectedTools = append(selectedTools, tool)
-			}
-		}
+		group = ToolGroupStarter
 	} else if isReadOnly && !isWriteRequest {
-		// Read-only request - include only read tools
-		for _, tool := range allTools {
-			switch tool.Name {
-			case "read_range", "analyze_data", "get_named_ranges", "validate_model":
-				selectedTools = append(selectedTools, tool)
-			}
-		}
+		group = ToolGroupRead
 	} else if isModelRequest {
-		// Model creation - include most tools except chart creation
-		for _, tool := range allTools {
-			if tool.Name != "create_chart" {
-				selectedTools = append(selectedTools, tool)
-			}
-		}
-	} else {
-		// General request - include basic read/write tools
-		for _, tool := range allTools {
-			switch tool.Name {
-			case "read_range", "write_range", "apply_formula", "analyze_data", "format_range", "apply_layout":
-				selectedTools = append(selectedTools, tool)
-			}
+		group = ToolGroupModeling
+	}
+	selectedTools := registry.SessionGroup(group, sessionID)
+
+	// Filing-driven requests need the filing tools regardless of the
+	// read/write classification above
+	for _, keyword := range []string{"historical", "actuals", "10-k", "10-q", "filing", "xbrl"} {
+		if strings.Contains(msgLower, keyword) {
+			selectedTools = appendMissingTools(selectedTools, registry.SessionGroup(ToolGroupFilings, sessionID))
+			break
 		}
 	}
 
 	// If no tools were selected, include a minimal set
 	if len(selectedTools) == 0 {
-		for _, tool := range allTools {
-			switch tool.Name {
-			case "read_range", "write_range":
-				selectedTools = append(selectedTools, tool)
-			}
-		}
+		selectedTools = registry.SessionGroup(ToolGroupMinimal, sessionID)
 	}
 
+	// Tools from external MCP servers are offered with every group
+	selectedTools = appendMissingTools(selectedTools, registry.SessionGroup(ToolGroupExternal, sessionID))
+
 	log.Info().
 		Str("message", userMessage).
 		Int("selected_tools", len(selectedTools)).
-		Int("total_tools", len(allTools)).
+		Int("total_tools", len(registry.Tools())).
+		Str("tool_group", group).
 		Bool("is_read_only", isReadOnly).
 		Bool("is_write_request", isWriteRequest).
 		Bool("is_model_request", isModelRequest).
@@ -915,6 +935,28 @@ func (s *Service) selectRelevantTools(userMessage string, context *FinancialCont
 	return selectedTools
 }
 
+// appendMissingTools appends the tools from extra not already in tools
+func appendMissingTools(tools, extra []ExcelTool) []ExcelTool {
+	for _, tool := range extra {
+		present := false
+		for _, t := range tools {
+			present = present || t.Name == tool.Name
+		}
+		if !present {
+			tools = append(tools, tool)
+		}
+	}
+	return tools
+}
+
+// toolRegistry returns the tools available to the model
+func (s *Service) toolRegistry() *ToolRegistry {
+	if s.toolExecutor != nil {
+		return s.toolExecutor.Registry()
+	}
+	return builtinToolRegistry()
+}
+
 // ProcessChatWithTools processes a chat message and handles tool calls automatically
 func (s *Service) ProcessChatWithTools(ctx context.Context, sessionID string, userMessage string, context *FinancialContext) (*CompletionResponse, error) {
 	log.Info().
@@ -957,14 +999,14 @@ func (s *Service) ProcessChatWithTools(ctx context.Context, sessionID string, us
 		// Add Excel tools if enabled - use smart selection for round 0, all tools for subsequent rounds
 		if s.config.EnableActions && s.toolExecutor != nil {
 			if round == 0 {
-				request.Tools = s.selectRelevantTools(userMessage, context)
+				request.Tools = s.selectRelevantTools(sessionID, userMessage, context)
 			} else {
 				// For subsequent rounds, include all tools since we're in execution mode
-				request.Tools = GetExcelTools()
+				request.Tools = s.toolRegistry().SessionTools(sessionID)
 			}
 			log.Info().
 				Int("tools_count", len(request.Tools)).
-				Int("total_available_tools", len(GetExcelTools())).
+				Int("total_available_tools", len(s.toolRegistry().Tools())).
 				Int("round", round).
 				Msg("Added relevant tools to ProcessChatWithTools request")
 		} else {
@@ -1045,6 +1087,11 @@ func (s *Service) ProcessChatWithToolsAndHistory(ctx context.Context, sessionID
 		Str("autonomy_mode", autonomyMode).
 		Msg("Starting ProcessChatWithToolsAndHistory")
 
+	// Memory results retrieved while answering get citation IDs, which
+	// are validated against the final answer
+	citations := NewCitationSet()
+	ctx = WithCitations(ctx, citations)
+
 	// Build messages array with fresh context every time
 	messages := make([]Message, 0)
 
@@ -1111,10 +1158,10 @@ func (s *Service) ProcessChatWithToolsAndHistory(ctx context.Context, sessionID
 			// In "ask" mode, don't provide any tools to the AI
 			// For first round, analyze the request to determine what tools are needed
 			if round == 0 {
-				request.Tools = s.selectRelevantTools(userMessage, context)
+				request.Tools = s.selectRelevantTools(sessionID, userMessage, context)
 			} else {
 				// For subsequent rounds, include all tools since we're in execution mode
-				request.Tools = GetExcelTools()
+				request.Tools = s.toolRegistry().SessionTools(sessionID)
 			}
 			log.Info().
 				Int("tools_count", len(request.Tools)).
@@ -1142,6 +1189,7 @@ func (s *Service) ProcessChatWithToolsAndHistory(ctx context.Context, sessionID
 		if len(response.ToolCalls) == 0 {
 			log.Info().Msg("No tool calls in response, returning final answer")
 			response.IsFinal = true
+			response.Content, response.Citations = resolveCitations(response.Content, citations, sessionID)
 			return response, nil
 		}
 
@@ -1234,6 +1282,8 @@ func (s *Service) ProcessChatWithToolsAndHistory(ctx context.Context, sessionID
 				},
 			}
 
+			finalResponse.Content, finalResponse.Citations = resolveCitations(finalResponse.Content, citations, sessionID)
+
 			// Important: Return here to exit the loop and prevent further processing
 			return finalResponse, nil
 		}
@@ -1344,7 +1394,7 @@ func (s *Service) streamWithToolContinuation(
 	// The AI can continue generating content while tools are being approved
 	
 	// Get relevant tools
-	tools := s.getRelevantTools(messages[len(messages)-1].Content, financialContext)
+	tools := s.getRelevantTools(sessionID, messages[len(messages)-1].Content, financialContext)
 
 	log.Info().
 		Str("session", sessionID).
@@ -1375,6 +1425,12 @@ func (s *Service) streamWithToolContinuation(
 		request.ToolChoice = &ToolChoice{Type: "auto"}
 	}
 
+	// Memory results retrieved while answering get citation IDs, which
+	// are validated as the answer streams
+	citationSet := NewCitationSet()
+	ctx = WithCitations(ctx, citationSet)
+	citations := newCitationStream(citationSet)
+
 	// Get streaming response
 	providerChan, err := s.provider.GetStreamingCompletion(ctx, request)
 	if err != nil {
@@ -1389,6 +1445,19 @@ func (s *Service) streamWithToolContinuation(
 
 	// Forward chunks - the AI can continue even if tools are queued
 	for chunk := range providerChan {
+		if !citations.filter(&chunk) {
+			continue
+		}
+		if chunk.Done {
+			if rest := citations.finish(sessionID, &chunk); rest != nil {
+				select {
+				case outChan <- *rest:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+
 		// Forward the chunk
 		select {
 		case outChan <- chunk:
@@ -1404,10 +1473,14 @@ func (s *Service) streamWithToolContinuation(
 	
 	// If we reach here, the provider channel closed without a done chunk
 	// Send a done chunk to complete the stream
-	outChan <- CompletionChunk{
+	final := CompletionChunk{
 		Type: "",
 		Done: true,
 	}
+	if rest := citations.finish(sessionID, &final); rest != nil {
+		outChan <- *rest
+	}
+	outChan <- final
 }
 
 // waitForToolResponses waits for tool responses or timeout
@@ -1607,10 +1680,10 @@ func (s *Service) buildSystemPrompt(context *FinancialContext) string {
 }
 
 // getRelevantTools returns tools relevant to the user message and context
-func (s *Service) getRelevantTools(userMessage string, context *FinancialContext) []ExcelTool {
+func (s *Service) getRelevantTools(sessionID string, userMessage string, context *FinancialContext) []ExcelTool {
 	// For now, return all available Excel tools
 	// TODO: Implement intelligent tool selection based on message content and context
-	return GetExcelTools()
+	return s.toolRegistry().SessionTools(sessionID)
 }
 
 // GetContextAnalyzer returns the context analyzer
@@ -0,0 +1,92 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ToolAuditor records tool executions in the audit log
+type ToolAuditor interface {
+	RecordToolExecution(ctx context.Context, execution *ToolExecution) error
+}
+
+// ToolExecution describes one finished tool call
+type ToolExecution struct {
+	SessionID  string                 `json:"session_id"`
+	Tenant     string                 `json:"tenant,omitempty"`
+	Workspace  string                 `json:"workspace,omitempty"`
+	Workbook   string                 `json:"workbook,omitempty"`
+	ToolID     string                 `json:"tool_id"`
+	Tool       string                 `json:"tool"`
+	Input      map[string]interface{} `json:"input"`
+	Status     string                 `json:"status"`
+	Error      string                 `json:"error,omitempty"`
+	DurationMS int64                  `json:"duration_ms"`
+}
+
+// Failed reports whether the call did not succeed
+func (e *ToolExecution) Failed() bool {
+	return e.Status == "error" || e.Status == "failed"
+}
+
+// publicInput copies a tool input without internal markers such as _tool_id
+func publicInput(input map[string]interface{}) map[string]interface{} {
+	public := make(map[string]interface{}, len(input))
+	for key, value := range input {
+		if !strings.HasPrefix(key, "_") {
+			public[key] = value
+		}
+	}
+	return public
+}
+
+// SetToolAuditor sets where the executor records tool executions
+func (te *ToolExecutor) SetToolAuditor(auditor ToolAuditor) {
+	te.auditor = auditor
+}
+
+// auditToolExecution records a finished call. result is nil when the tool
+// failed with execErr. Audit failures are logged and never fail the call.
+func (te *ToolExecutor) auditToolExecution(ctx context.Context, sessionID string, toolCall ToolCall, result *ToolResult, execErr error, duration time.Duration) {
+	if te.auditor == nil {
+		return
+	}
+
+	execution := &ToolExecution{
+		SessionID:  sessionID,
+		ToolID:     toolCall.ID,
+		Tool:       toolCall.Name,
+		Input:      publicInput(toolCall.Input),
+		DurationMS: duration.Milliseconds(),
+	}
+	if te.excelBridge != nil {
+		if session := te.excelBridge.GetSession(sessionID); session != nil {
+			execution.Tenant = session.MemoryNamespace.Tenant
+			execution.Workbook = session.Workbook
+		}
+	}
+	execution.Workspace, _ = te.SessionWorkspace(context.WithoutCancel(ctx), sessionID)
+
+	switch {
+	case execErr != nil:
+		execution.Status = "failed"
+		execution.Error = execErr.Error()
+	case result.IsError:
+		execution.Status = "error"
+	case result.Status != "":
+		execution.Status = result.Status
+	default:
+		execution.Status = "success"
+	}
+
+	// Record the call even when its request was cancelled
+	if err := te.auditor.RecordToolExecution(context.WithoutCancel(ctx), execution); err != nil {
+		log.Error().Err(err).
+			Str("tool", toolCall.Name).
+			Str("tool_id", toolCall.ID).
+			Msg("Failed to audit tool execution")
+	}
+}
@@ -0,0 +1,1196 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// registerBuiltinTools registers the Excel tools. Each tool declares its
+// schema, read/write permission, tool groups, preview, inverse and
+// executor here; manifest.json fills in metadata a definition leaves unset.
+func registerBuiltinTools(r *ToolRegistry) {
+	r.mustRegister(ToolDefinition{
+		ExcelTool: ExcelTool{
+			Name:        "read_range",
+			Description: "Read cell values, formulas, and formatting from a specified range in the Excel spreadsheet. Returns detailed information about each cell including values, formulas, formatting, and data types.",
+			Permission:  "read",
+			PreviewType: "none",
+			Category:    "data_access",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"range": map[string]interface{}{
+						"type":        "string",
+						"description": "The Excel range to read (e.g., 'A1:D10', 'Sheet1!A1:B5', 'A:A' for entire column)",
+					},
+					"include_formulas": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Whether to include formulas in the response",
+						"default":     true,
+					},
+					"include_formatting": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Whether to include cell formatting information",
+						"default":     false,
+					},
+				},
+				"required": []string{"range"},
+			},
+		},
+		Groups:  []string{ToolGroupRead, ToolGroupBasic, ToolGroupModeling, ToolGroupMinimal},
+		Execute: executeReadRangeTool,
+	})
+
+	r.mustRegister(ToolDefinition{
+		ExcelTool: ExcelTool{
+			Name:            "write_range",
+			Description:     "Write values to a specified range in the Excel spreadsheet. Can write single values or arrays of values. Preserves existing formatting unless specified otherwise.",
+			Permission:      "write",
+			PreviewType:     "excel_diff",
+			Category:        "data_modification",
+			RequiresPreview: true,
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"range": map[string]interface{}{
+						"type":        "string",
+						"description": "The Excel range to write to (e.g., 'A1:D10', 'Sheet1!A1:B5')",
+					},
+					"values": map[string]interface{}{
+						"type":        "array",
+						"description": "2D array of values to write. IMPORTANT: Use exactly 2 levels of nesting. Examples: [[\"single value\"]] for A1, [[\"a\",\"b\",\"c\"]] for A1:C1, [[\"a\"],[\"b\"],[\"c\"]] for A1:A3, [[\"a\",\"b\"],[\"c\",\"d\"]] for A1:B2",
+						"items": map[string]interface{}{
+							"type": "array",
+							"items": map[string]interface{}{
+								"oneOf": []map[string]interface{}{
+									{"type": "string"},
+									{"type": "number"},
+									{"type": "boolean"},
+									{"type": "null"},
+								},
+							},
+						},
+					},
+					"preserve_formatting": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Whether to preserve existing cell formatting",
+						"default":     true,
+					},
+				},
+				"required": []string{"range", "values"},
+			},
+		},
+		Groups:    []string{ToolGroupStarter, ToolGroupBasic, ToolGroupModeling, ToolGroupMinimal},
+		Preview:   previewWriteRange,
+		Inverse:   inverseWriteRange,
+		Queueable: true,
+		Execute:   queuedWriteTool((*ToolExecutor).executeWriteRange, writeRangeQueue),
+	})
+
+	r.mustRegister(ToolDefinition{
+		ExcelTool: ExcelTool{
+			Name:            "apply_formula",
+			Description:     "Apply a formula to one or more cells. Handles relative and absolute references correctly when applying to multiple cells.",
+			Permission:      "write",
+			PreviewType:     "excel_diff",
+			Category:        "formula_modification",
+			RequiresPreview: true,
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"range": map[string]interface{}{
+						"type":        "string",
+						"description": "The cell or range to apply the formula to",
+					},
+					"formula": map[string]interface{}{
+						"type":        "string",
+						"description": "The Excel formula to apply (e.g., '=SUM(A1:A10)', '=VLOOKUP(A2,Sheet2!A:B,2,FALSE)')",
+					},
+					"relative_references": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Whether to adjust references when applying to multiple cells",
+						"default":     true,
+					},
+				},
+				"required": []string{"range", "formula"},
+			},
+		},
+		Groups:    []string{ToolGroupStarter, ToolGroupBasic, ToolGroupModeling},
+		Preview:   previewApplyFormula,
+		Inverse:   inverseApplyFormula,
+		Queueable: true,
+		Execute:   queuedWriteTool((*ToolExecutor).executeApplyFormula, applyFormulaQueue),
+	})
+
+	r.mustRegister(ToolDefinition{
+		ExcelTool: ExcelTool{
+			Name:        "analyze_data",
+			Description: "Analyze a data range to understand its structure, data types, and patterns. Useful for understanding data before performing operations.",
+			Permission:  "read",
+			PreviewType: "json",
+			Category:    "data_analysis",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"range": map[string]interface{}{
+						"type":        "string",
+						"description": "The Excel range to analyze",
+					},
+					"include_statistics": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Include basic statistics for numeric columns",
+						"default":     true,
+					},
+					"detect_headers": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Attempt to detect column headers",
+						"default":     true,
+					},
+				},
+				"required": []string{"range"},
+			},
+		},
+		Groups:  []string{ToolGroupRead, ToolGroupBasic, ToolGroupModeling},
+		Execute: contentTool((*ToolExecutor).executeAnalyzeData),
+	})
+
+	r.mustRegister(ToolDefinition{
+		ExcelTool: ExcelTool{
+			Name:            "format_range",
+			Description:     "Apply formatting to a range of cells including number formats, colors, borders, and alignment.",
+			Permission:      "write",
+			PreviewType:     "excel_diff",
+			Category:        "formatting",
+			RequiresPreview: true,
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"range": map[string]interface{}{
+						"type":        "string",
+						"description": "The Excel range to format",
+					},
+					"number_format": map[string]interface{}{
+						"type":        "string",
+						"description": "Number format string (e.g., '#,##0.00', '0.00%', '$#,##0.00')",
+					},
+					"font": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"bold":   map[string]interface{}{"type": "boolean"},
+							"italic": map[string]interface{}{"type": "boolean"},
+							"size":   map[string]interface{}{"type": "number"},
+							"color":  map[string]interface{}{"type": "string"},
+						},
+					},
+					"fill_color": map[string]interface{}{
+						"type":        "string",
+						"description": "Background color in hex format (e.g., '#FFFF00')",
+					},
+					"alignment": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"horizontal": map[string]interface{}{
+								"type": "string",
+								"enum": []string{"left", "center", "right", "fill", "justify"},
+							},
+							"vertical": map[string]interface{}{
+								"type": "string",
+								"enum": []string{"top", "middle", "bottom"},
+							},
+						},
+					},
+				},
+				"required": []string{"range"},
+			},
+		},
+		Groups:    []string{ToolGroupStarter, ToolGroupBasic, ToolGroupModeling},
+		Preview:   previewFormatRange,
+		Inverse:   inverseFormatRange,
+		Queueable: true,
+		Execute:   queuedWriteTool((*ToolExecutor).executeFormatRange, formatRangeQueue),
+	})
+
+	r.mustRegister(ToolDefinition{
+		ExcelTool: ExcelTool{
+			Name:        "apply_layout",
+			Permission:  "write",
+			Description: "Apply visual layout changes to cells, including merging, unmerging, and future layout features.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"range": map[string]interface{}{
+						"type":        "string",
+						"description": "The Excel range to apply layout changes to (e.g., 'A1:E1', 'Sheet1!A1:G1'). Must be a rectangular range.",
+					},
+					"merge": map[string]interface{}{
+						"type":        "string",
+						"description": "Merge operation type",
+						"enum":        []string{"all", "across", "unmerge"},
+					},
+					"preserve_content": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Whether to preserve content in top-left cell when merging",
+						"default":     true,
+					},
+				},
+				"required": []string{"range"},
+			},
+		},
+		Groups: []string{ToolGroupStarter, ToolGroupBasic, ToolGroupModeling},
+		// Layout changes are applied by the add-in; there is no server-side executor
+	})
+
+	r.mustRegister(ToolDefinition{
+		ExcelTool: ExcelTool{
+			Name:            "create_chart",
+			Description:     "Create a chart based on data in the spreadsheet. Supports various chart types commonly used in financial modeling.",
+			Permission:      "write",
+			PreviewType:     "image",
+			Category:        "visualization",
+			RequiresPreview: true,
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"data_range": map[string]interface{}{
+						"type":        "string",
+						"description": "The data range for the chart",
+					},
+					"chart_type": map[string]interface{}{
+						"type":        "string",
+						"description": "Type of chart to create",
+						"enum":        []string{"column", "bar", "line", "pie", "scatter", "area", "combo"},
+					},
+					"title": map[string]interface{}{
+						"type":        "string",
+						"description": "Chart title",
+					},
+					"position": map[string]interface{}{
+						"type":        "string",
+						"description": "Where to place the chart (e.g., 'F5')",
+					},
+					"include_legend": map[string]interface{}{
+						"type":    "boolean",
+						"default": true,
+					},
+				},
+				"required": []string{"data_range", "chart_type"},
+			},
+		},
+		Preview: previewCreateChart,
+		Execute: actionTool((*ToolExecutor).executeCreateChart, "Chart created successfully"),
+	})
+
+	r.mustRegister(ToolDefinition{
+		ExcelTool: ExcelTool{
+			Name:        "validate_model",
+			Description: "Validate a financial model by checking for common issues like circular references, broken formulas, inconsistent formulas in ranges, and #REF! errors.",
+			Permission:  "read",
+			PreviewType: "json",
+			Category:    "validation",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"range": map[string]interface{}{
+						"type":        "string",
+						"description": "The range to validate (leave empty for entire worksheet)",
+					},
+					"check_circular_refs": map[string]interface{}{
+						"type":    "boolean",
+						"default": true,
+					},
+					"check_formula_consistency": map[string]interface{}{
+						"type":    "boolean",
+						"default": true,
+					},
+					"check_errors": map[string]interface{}{
+						"type":    "boolean",
+						"default": true,
+					},
+				},
+				"required": []string{},
+			},
+		},
+		Groups:  []string{ToolGroupRead, ToolGroupModeling},
+		Execute: contentTool((*ToolExecutor).executeValidateModel),
+	})
+
+	r.mustRegister(ToolDefinition{
+		ExcelTool: ExcelTool{
+			Name:        "get_named_ranges",
+			Description: "Get all named ranges in the workbook or worksheet. Named ranges are commonly used in financial models for important values and ranges.",
+			Permission:  "read",
+			PreviewType: "json",
+			Category:    "metadata",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"scope": map[string]interface{}{
+						"type":        "string",
+						"description": "Scope of named ranges to retrieve",
+						"enum":        []string{"workbook", "worksheet"},
+						"default":     "workbook",
+					},
+				},
+				"required": []string{},
+			},
+		},
+		Groups:  []string{ToolGroupRead, ToolGroupModeling},
+		Execute: contentTool((*ToolExecutor).executeGetNamedRanges),
+	})
+
+	r.mustRegister(ToolDefinition{
+		ExcelTool: ExcelTool{
+			Name:            "create_named_range",
+			Description:     "Create a named range for easier reference in formulas and navigation.",
+			Permission:      "write",
+			PreviewType:     "json",
+			Category:        "metadata",
+			RequiresPreview: true,
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name for the range (e.g., 'Revenue', 'WACC', 'Assumptions')",
+					},
+					"range": map[string]interface{}{
+						"type":        "string",
+						"description": "The Excel range to name (e.g., 'Sheet1!A1:A10')",
+					},
+				},
+				"required": []string{"name", "range"},
+			},
+		},
+		Groups:  []string{ToolGroupModeling},
+		Preview: previewCreateNamedRange,
+		Inverse: inverseCreateNamedRange,
+		Execute: actionTool((*ToolExecutor).executeCreateNamedRange, "Named range created successfully"),
+	})
+
+	r.mustRegister(ToolDefinition{
+		ExcelTool: ExcelTool{
+			Name:            "insert_rows_columns",
+			Description:     "Insert rows or columns at a specified position, shifting existing data as needed.",
+			Permission:      "write",
+			PreviewType:     "excel_diff",
+			Category:        "structure_modification",
+			RequiresPreview: true,
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"position": map[string]interface{}{
+						"type":        "string",
+						"description": "Where to insert (e.g., 'A5' for row 5, 'C' for column C)",
+					},
+					"count": map[string]interface{}{
+						"type":        "integer",
+						"description": "Number of rows or columns to insert",
+						"default":     1,
+					},
+					"type": map[string]interface{}{
+						"type":        "string",
+						"description": "Whether to insert rows or columns",
+						"enum":        []string{"rows", "columns"},
+					},
+				},
+				"required": []string{"position", "type"},
+			},
+		},
+		Groups:  []string{ToolGroupModeling},
+		Preview: previewInsertRowsColumns,
+		Inverse: inverseInsertRowsColumns,
+		Execute: actionTool((*ToolExecutor).executeInsertRowsColumns, "Rows/columns inserted successfully"),
+	})
+
+	r.mustRegister(ToolDefinition{
+		ExcelTool: ExcelTool{
+			Name:        "build_financial_formula",
+			Permission:  "read",
+			Description: "Intelligently builds financial formulas with proper error handling and context awareness. Handles first period vs subsequent periods, prevents #DIV/0! errors, and applies financial modeling best practices.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"formula_type": map[string]interface{}{
+						"type":        "string",
+						"description": "Type of financial formula to build",
+						"enum":        []string{"growth_rate", "ratio", "sum", "average", "npv", "irr", "percentage", "lookup", "conditional"},
+					},
+					"target_cell": map[string]interface{}{
+						"type":        "string",
+						"description": "The cell where the formula will be applied (e.g., 'B5')",
+					},
+					"inputs": map[string]interface{}{
+						"type":        "object",
+						"description": "Context-aware parameters for formula generation",
+						"properties": map[string]interface{}{
+							"current_period_cell": map[string]interface{}{
+								"type":        "string",
+								"description": "Cell containing current period value",
+							},
+							"previous_period_cell": map[string]interface{}{
+								"type":        "string",
+								"description": "Cell containing previous period value (for growth calculations)",
+							},
+							"numerator_cells": map[string]interface{}{
+								"type":        "array",
+								"description": "Cells for numerator in ratio calculations",
+								"items":       map[string]interface{}{"type": "string"},
+							},
+							"denominator_cells": map[string]interface{}{
+								"type":        "array",
+								"description": "Cells for denominator in ratio calculations",
+								"items":       map[string]interface{}{"type": "string"},
+							},
+							"range_cells": map[string]interface{}{
+								"type":        "string",
+								"description": "Range for sum/average calculations (e.g., 'A1:A10')",
+							},
+							"lookup_table": map[string]interface{}{
+								"type":        "string",
+								"description": "Table range for lookup formulas",
+							},
+							"condition": map[string]interface{}{
+								"type":        "string",
+								"description": "Condition for conditional formulas",
+							},
+						},
+					},
+					"error_handling": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Whether to wrap formula in IFERROR for safety",
+						"default":     true,
+					},
+					"is_first_period": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Whether this is the first period in a time series (affects growth rate formulas)",
+						"default":     false,
+					},
+				},
+				"required": []string{"formula_type", "target_cell", "inputs"},
+			},
+		},
+		Groups:  []string{ToolGroupModeling},
+		Execute: contentTool((*ToolExecutor).executeBuildFinancialFormula),
+	})
+
+	r.mustRegister(ToolDefinition{
+		ExcelTool: ExcelTool{
+			Name:        "analyze_model_structure",
+			Permission:  "read",
+			Description: "Analyzes the structure and layout of financial models to understand sections, time periods, and data flow. Identifies assumptions, calculations, outputs, and key financial metrics.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"analysis_range": map[string]interface{}{
+						"type":        "string",
+						"description": "Range to analyze for model structure (e.g., 'A1:Z100')",
+					},
+					"focus_area": map[string]interface{}{
+						"type":        "string",
+						"description": "Specific area to focus analysis on",
+						"enum":        []string{"entire_model", "assumptions", "calculations", "outputs", "time_periods", "key_metrics"},
+						"default":     "entire_model",
+					},
+					"model_type_hint": map[string]interface{}{
+						"type":        "string",
+						"description": "Hint about expected model type to improve analysis",
+						"enum":        []string{"DCF", "LBO", "M&A", "Comps", "Budget", "General"},
+					},
+				},
+				"required": []string{"analysis_range"},
+			},
+		},
+		Groups:  []string{ToolGroupModeling},
+		Execute: contentTool((*ToolExecutor).executeAnalyzeModelStructure),
+	})
+
+	r.mustRegister(ToolDefinition{
+		ExcelTool: ExcelTool{
+			Name:        "smart_format_cells",
+			Permission:  "write",
+			Description: "Applies intelligent formatting to cells based on their content and role in financial models. Includes standard financial formatting, conditional formatting, and model styling best practices.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"range": map[string]interface{}{
+						"type":        "string",
+						"description": "Range of cells to format",
+					},
+					"style_type": map[string]interface{}{
+						"type":        "string",
+						"description": "Type of financial styling to apply",
+						"enum":        []string{"financial_input", "financial_calculation", "financial_output", "header", "assumption", "percentage", "currency", "multiple", "basis_points"},
+					},
+					"conditional_rules": map[string]interface{}{
+						"type":        "array",
+						"description": "Conditional formatting rules to apply",
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"condition": map[string]interface{}{
+									"type":        "string",
+									"description": "Condition for formatting (e.g., '>0', '<0', '=0')",
+								},
+								"format": map[string]interface{}{
+									"type":        "object",
+									"description": "Format to apply when condition is met",
+									"properties": map[string]interface{}{
+										"font_color":       map[string]interface{}{"type": "string"},
+										"background_color": map[string]interface{}{"type": "string"},
+										"font_style":       map[string]interface{}{"type": "string", "enum": []string{"bold", "italic", "normal"}},
+									},
+								},
+							},
+						},
+					},
+					"number_format": map[string]interface{}{
+						"type":        "string",
+						"description": "Specific number format to apply (overrides style_type default)",
+					},
+				},
+				"required": []string{"range", "style_type"},
+			},
+		},
+		Groups:  []string{ToolGroupModeling},
+		Execute: actionTool((*ToolExecutor).executeSmartFormatCells, "Smart formatting applied successfully"),
+	})
+
+	r.mustRegister(ToolDefinition{
+		ExcelTool: ExcelTool{
+			Name:        "create_audit_trail",
+			Permission:  "write",
+			Description: "Creates comprehensive audit trail documentation for financial models including formula explanations, assumptions documentation, and change tracking.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"target_range": map[string]interface{}{
+						"type":        "string",
+						"description": "Range to document and create audit trail for",
+					},
+					"documentation_type": map[string]interface{}{
+						"type":        "string",
+						"description": "Type of documentation to create",
+						"enum":        []string{"formula_explanations", "assumptions_summary", "model_overview", "change_log", "validation_notes"},
+					},
+					"add_comments": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Whether to add cell comments explaining formulas",
+						"default":     true,
+					},
+					"create_documentation_sheet": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Whether to create a separate documentation worksheet",
+						"default":     false,
+					},
+					"include_sources": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Whether to include source references and citations",
+						"default":     true,
+					},
+				},
+				"required": []string{"target_range", "documentation_type"},
+			},
+		},
+		Groups:  []string{ToolGroupModeling},
+		Execute: contentTool((*ToolExecutor).executeCreateAuditTrail),
+	})
+
+	r.mustRegister(ToolDefinition{
+		ExcelTool: ExcelTool{
+			Name:        "organize_financial_model",
+			Permission:  "write",
+			Description: "Creates professional section organization for any financial model type with intelligent model detection, industry-specific templates, and professional standards. Features automatic model type detection, context-aware sections, and customizable professional formatting for Investment Banking, Private Equity, Hedge Funds, and Corporate environments.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"model_type": map[string]interface{}{
+						"type":        "string",
+						"description": "Type of financial model (dcf, lbo, merger, comps, credit, universal). If not provided, will auto-detect from model content.",
+						"enum":        []string{"dcf", "lbo", "merger", "m&a", "comps", "trading_comps", "credit", "universal"},
+					},
+					"sections": map[string]interface{}{
+						"type":        "array",
+						"description": "Array of section types to create. If not provided, will generate intelligent sections based on model type and context.",
+						"items": map[string]interface{}{
+							"type": "string",
+						},
+					},
+					"layout": map[string]interface{}{
+						"type":        "string",
+						"description": "Model layout orientation",
+						"enum":        []string{"horizontal", "vertical"},
+						"default":     "horizontal",
+					},
+					"analysis_range": map[string]interface{}{
+						"type":        "string",
+						"description": "Range to analyze for current model structure and intelligent detection",
+						"default":     "A1:Z100",
+					},
+					"professional_standards": map[string]interface{}{
+						"type":        "string",
+						"description": "Professional industry standards for formatting and organization",
+						"enum":        []string{"investment_banking", "private_equity", "hedge_fund", "corporate"},
+					},
+					"industry_context": map[string]interface{}{
+						"type":        "string",
+						"description": "Industry context for specialized sections and terminology",
+						"enum":        []string{"technology", "healthcare", "energy", "real_estate", "financial_services", "manufacturing"},
+					},
+				},
+				"required": []string{},
+			},
+		},
+		Groups:  []string{ToolGroupModeling},
+		Execute: contentTool((*ToolExecutor).executeOrganizeFinancialModel),
+	})
+
+	r.mustRegister(ToolDefinition{
+		ExcelTool: ExcelTool{
+			Name:        "search_memory",
+			Permission:  "read",
+			Description: "Search long-term memory for relevant information from spreadsheets, documents, or past conversations. Use this when you need to recall information that's not in the current context.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "Natural language search query",
+					},
+					"source_filter": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"all", "spreadsheet", "document", "chat"},
+						"description": "Filter results by source type",
+						"default":     "all",
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum number of results to return",
+						"default":     5,
+						"minimum":     1,
+						"maximum":     10,
+					},
+					"include_context": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Include surrounding context for each result",
+						"default":     true,
+					},
+				},
+				"required": []string{"query"},
+			},
+		},
+		Groups:  []string{ToolGroupModeling},
+		Execute: contentTool((*ToolExecutor).executeMemorySearch),
+	})
+
+	r.mustRegister(ToolDefinition{
+		ExcelTool: ExcelTool{
+			Name:        "trace_precedents",
+			Permission:  "read",
+			Description: "Trace the precedent cells (cells that feed into) a given formula cell. Returns all cells that the target cell depends on, helping understand calculation flow.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"cell": map[string]interface{}{
+						"type":        "string",
+						"description": "The cell address to trace precedents for (e.g., 'B5', 'Sheet1!C10')",
+					},
+					"include_values": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Whether to include current values of precedent cells",
+						"default":     true,
+					},
+					"include_formulas": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Whether to include formulas of precedent cells",
+						"default":     true,
+					},
+					"max_depth": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum depth to trace (1 = direct precedents only)",
+						"default":     2,
+						"minimum":     1,
+						"maximum":     5,
+					},
+				},
+				"required": []string{"cell"},
+			},
+		},
+		Groups:  []string{ToolGroupModeling},
+		Execute: contentTool((*ToolExecutor).executeTracePrecedents),
+	})
+
+	r.mustRegister(ToolDefinition{
+		ExcelTool: ExcelTool{
+			Name:        "explain_cell_history",
+			Permission:  "read",
+			Description: "Explain who put the content of a cell there and why. Returns every recorded AI change to the cell, newest first, with the value written, the chat message, tool call, model, inputs, source documents and who approved it. Cells with no history were entered by users.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"cell": map[string]interface{}{
+						"type":        "string",
+						"description": "The cell to explain (e.g., 'Valuation!D15', or 'D15' on the active sheet)",
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum number of changes to return",
+						"default":     20,
+						"minimum":     1,
+						"maximum":     100,
+					},
+				},
+				"required": []string{"cell"},
+			},
+		},
+		Groups:  []string{ToolGroupRead, ToolGroupModeling},
+		Execute: contentTool((*ToolExecutor).executeExplainCellHistory),
+	})
+
+	r.mustRegister(ToolDefinition{
+		ExcelTool: ExcelTool{
+			Name:        "trace_dependents",
+			Permission:  "read",
+			Description: "Trace the dependent cells (cells that use) a given cell. Returns all cells that depend on the target cell, helping understand impact of changes.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"cell": map[string]interface{}{
+						"type":        "string",
+						"description": "The cell address to trace dependents for (e.g., 'B5', 'Sheet1!C10')",
+					},
+					"include_values": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Whether to include current values of dependent cells",
+						"default":     true,
+					},
+					"include_formulas": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Whether to include formulas of dependent cells",
+						"default":     true,
+					},
+					"search_all_sheets": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Whether to search for dependents across all sheets",
+						"default":     false,
+					},
+				},
+				"required": []string{"cell"},
+			},
+		},
+		Groups:  []string{ToolGroupModeling},
+		Execute: contentTool((*ToolExecutor).executeTraceDependents),
+	})
+
+	r.mustRegister(ToolDefinition{
+		ExcelTool: ExcelTool{
+			Name:        "populate_historicals",
+			Permission:  "write",
+			Description: "Fill historical periods of a model from ingested SEC filings. Maps row labels (Revenue, COGS, Net income, ...) and period headers (FY2022A, Q1 2024, ...) onto reported XBRL facts and queues the values as a batch write for user approval. Each proposed cell includes its source document, concept and a confidence score; labels that cannot be matched are reported. Cells with formulas are never overwritten.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"range": map[string]interface{}{
+						"type":        "string",
+						"description": "Model range including the label column and the period header row (e.g., 'IS!A1:H40')",
+					},
+					"document_id": map[string]interface{}{
+						"type":        "string",
+						"description": "Limit facts to a single ingested filing",
+					},
+					"units": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"auto", "units", "thousands", "millions", "billions"},
+						"description": "Units the model is presented in. 'auto' looks for markers like '($ in millions)'",
+						"default":     "auto",
+					},
+					"overwrite": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Propose values for historical cells that already contain hard-coded numbers",
+						"default":     false,
+					},
+					"min_confidence": map[string]interface{}{
+						"type":        "number",
+						"description": "Minimum label match confidence",
+						"default":     0.6,
+						"minimum":     0,
+						"maximum":     1,
+					},
+				},
+				"required": []string{"range"},
+			},
+		},
+		Groups:    []string{ToolGroupModeling, ToolGroupFilings},
+		Queueable: true,
+		Execute:   executePopulateHistoricalsTool,
+	})
+}
+
+// errQueuedForApproval is the message write executors return when an
+// operation was queued for preview instead of applied
+const errQueuedForApproval = "Tool execution queued for user approval"
+
+// queuedWrite describes how a previewable write tool is queued
+type queuedWrite struct {
+	queuedMessage  string // Reported to the model when queued
+	context        string // Context of the queued operation
+	successMessage string
+	priority       int
+}
+
+var (
+	writeRangeQueue = queuedWrite{
+		queuedMessage:  "Write range operation queued for user approval",
+		context:        "Excel write operation requested by AI",
+		successMessage: "Range written successfully",
+		priority:       50,
+	}
+	applyFormulaQueue = queuedWrite{
+		queuedMessage:  "Formula application queued for user approval",
+		context:        "Excel formula application requested by AI",
+		successMessage: "Formula applied successfully",
+		priority:       50,
+	}
+	formatRangeQueue = queuedWrite{
+		queuedMessage:  "Format operation queued for user approval",
+		context:        "Excel formatting requested by AI",
+		successMessage: "Formatting applied successfully",
+		priority:       40, // Lower priority for formatting
+	}
+)
+
+// contentTool runs a tool whose output is returned to the model as is
+func contentTool[T any](fn func(*ToolExecutor, context.Context, string, map[string]interface{}) (T, error)) ToolHandler {
+	return func(ctx context.Context, te *ToolExecutor, sessionID string, call ToolCall, autonomyMode string, result *ToolResult) error {
+		content, err := fn(te, ctx, sessionID, call.Input)
+		if err != nil {
+			result.IsError = true
+			result.Content = formatToolError(err)
+			return nil
+		}
+		result.Content = content
+		return nil
+	}
+}
+
+// actionTool runs a tool that reports only success or failure
+func actionTool(fn func(*ToolExecutor, context.Context, string, map[string]interface{}) error, message string) ToolHandler {
+	return func(ctx context.Context, te *ToolExecutor, sessionID string, call ToolCall, autonomyMode string, result *ToolResult) error {
+		if err := fn(te, ctx, sessionID, call.Input); err != nil {
+			result.IsError = true
+			result.Content = formatToolError(err)
+			return nil
+		}
+		result.Content = map[string]string{"status": "success", "message": message}
+		return nil
+	}
+}
+
+// queuedWriteTool runs a write tool that may be queued for user approval
+// instead of applied
+func queuedWriteTool(fn func(*ToolExecutor, context.Context, string, map[string]interface{}) error, q queuedWrite) ToolHandler {
+	return func(ctx context.Context, te *ToolExecutor, sessionID string, call ToolCall, autonomyMode string, result *ToolResult) error {
+		// The autonomy policy has set preview_mode if approval is needed
+		err := fn(te, ctx, sessionID, call.Input)
+		if err != nil && err.Error() == errQueuedForApproval {
+			te.queueForApproval(ctx, sessionID, call, q, result)
+			return nil
+		}
+		if err != nil {
+			result.IsError = true
+			result.Status = "error"
+			result.Content = formatToolError(err)
+			return nil
+		}
+
+		result.Status = "success"
+		result.Content = map[string]string{"status": "success", "message": q.successMessage}
+		result.Details = map[string]interface{}{
+			"operation": call.Name,
+			"range":     call.Input["range"],
+			"timestamp": time.Now().Format(time.RFC3339),
+		}
+		return nil
+	}
+}
+
+// queueForApproval reports a write as queued and registers it with the
+// queued operations registry
+func (te *ToolExecutor) queueForApproval(ctx context.Context, sessionID string, call ToolCall, q queuedWrite, result *ToolResult) {
+	structuredPreview := te.registry.StructuredPreview(call.Name, call.Input)
+	preview, _ := structuredPreview["text"].(string)
+	previewType, _ := structuredPreview["preview_type"].(string)
+
+	result.IsError = false
+	result.Status = "queued"
+	result.Content = map[string]interface{}{
+		"status":  "queued",
+		"message": q.queuedMessage,
+		"preview": preview,
+		"action": map[string]interface{}{
+			"type":         "preview_queued",
+			"operation_id": call.ID,
+			"tool_type":    call.Name,
+			"input":        call.Input,
+			"preview":      preview,
+			"preview_type": previewType,
+			"description":  preview,
+		},
+	}
+	result.Details = map[string]interface{}{
+		"operation": call.Name,
+		"range":     call.Input["range"],
+		"preview":   preview,
+		"timestamp": time.Now().Format(time.RFC3339),
+	}
+	if formula, ok := call.Input["formula"]; ok {
+		result.Details["formula"] = formula
+	}
+
+	if te.queuedOpsRegistry == nil {
+		return
+	}
+
+	// Operations after the first in a batch depend on the one before
+	var batchID string
+	var dependencies []string
+	if bid, ok := call.Input["_batch_id"].(string); ok {
+		batchID = bid
+		if batchIndex, ok := call.Input["_batch_index"].(int); ok && batchIndex > 0 {
+			if batchToolIDs, ok := call.Input["_batch_tool_ids"].([]string); ok && batchIndex < len(batchToolIDs) {
+				dependencies = append(dependencies, batchToolIDs[batchIndex-1])
+			}
+		}
+	}
+
+	messageID, _ := ctx.Value("message_id").(string)
+
+	// Always use the tool call ID so operations match the request mapper
+	queuedOp := map[string]interface{}{
+		"ID":           call.ID,
+		"SessionID":    sessionID,
+		"Type":         call.Name,
+		"Input":        call.Input,
+		"Preview":      structuredPreview,
+		"PreviewType":  previewType,
+		"Context":      q.context,
+		"Priority":     q.priority,
+		"BatchID":      batchID,
+		"Dependencies": dependencies,
+		"MessageID":    messageID,
+	}
+	if registry, ok := te.queuedOpsRegistry.(interface {
+		QueueOperation(interface{}) error
+	}); ok {
+		if err := registry.QueueOperation(queuedOp); err != nil {
+			log.Error().Err(err).Msg("Failed to register queued operation")
+		}
+	}
+}
+
+// executeReadRangeTool fails the call outright on errors so they are
+// categorized for retry
+func executeReadRangeTool(ctx context.Context, te *ToolExecutor, sessionID string, call ToolCall, autonomyMode string, result *ToolResult) error {
+	content, err := te.executeReadRange(ctx, sessionID, call.Input)
+	if err != nil {
+		return err
+	}
+	result.Status = "success"
+	result.Content = content
+	result.Details = map[string]interface{}{
+		"operation": "read_range",
+		"range":     call.Input["range"],
+		"timestamp": time.Now().Format(time.RFC3339),
+	}
+	return nil
+}
+
+func executePopulateHistoricalsTool(ctx context.Context, te *ToolExecutor, sessionID string, call ToolCall, autonomyMode string, result *ToolResult) error {
+	content, err := te.executePopulateHistoricals(ctx, sessionID, call)
+	if err != nil {
+		result.IsError = true
+		result.Content = formatToolError(err)
+		return nil
+	}
+	if content["status"] == "queued" {
+		result.Status = "queued"
+	} else {
+		result.Status = "success"
+	}
+	result.Content = content
+	result.Details = map[string]interface{}{
+		"operation": "populate_historicals",
+		"range":     call.Input["range"],
+		"timestamp": time.Now().Format(time.RFC3339),
+	}
+	return nil
+}
+
+func previewWriteRange(input map[string]interface{}) (string, map[string]interface{}) {
+	rangeAddr, _ := input["range"].(string)
+	details := map[string]interface{}{"affected_range": input["range"]}
+
+	// Try to create a concise preview of the values
+	text := fmt.Sprintf("Write values to %s", rangeAddr)
+	if vals, ok := input["values"].([][]interface{}); ok {
+		dimensions := map[string]int{"rows": len(vals), "columns": 0}
+		if len(vals) > 0 {
+			dimensions["columns"] = len(vals[0])
+		}
+		details["dimensions"] = dimensions
+		if len(vals) > 0 && len(vals[0]) > 0 {
+			details["sample_value"] = vals[0][0]
+			firstValue := fmt.Sprintf("%v", vals[0][0])
+			if len(vals) == 1 && len(vals[0]) == 1 {
+				text = fmt.Sprintf("Write '%s' to %s", firstValue, rangeAddr)
+			} else {
+				text = fmt.Sprintf("Write %dx%d values starting with '%s' to %s",
+					len(vals), len(vals[0]), firstValue, rangeAddr)
+			}
+		}
+	}
+	return text, details
+}
+
+func previewApplyFormula(input map[string]interface{}) (string, map[string]interface{}) {
+	rangeAddr, _ := input["range"].(string)
+	formula, _ := input["formula"].(string)
+	if len(formula) > 50 {
+		formula = formula[:47] + "..."
+	}
+	return fmt.Sprintf("Apply formula '%s' to %s", formula, rangeAddr), map[string]interface{}{
+		"affected_range":      input["range"],
+		"formula":             input["formula"],
+		"relative_references": input["relative_references"],
+	}
+}
+
+func previewFormatRange(input map[string]interface{}) (string, map[string]interface{}) {
+	rangeAddr, _ := input["range"].(string)
+	details := map[string]interface{}{
+		"affected_range": input["range"],
+		"formatting":     input["format"],
+	}
+
+	var parts []string
+	if f, ok := input["format"].(map[string]interface{}); ok {
+		if numFmt, ok := f["number_format"].(string); ok {
+			parts = append(parts, fmt.Sprintf("format: %s", numFmt))
+		}
+		if bold, ok := f["bold"].(bool); ok && bold {
+			parts = append(parts, "bold")
+		}
+		if color, ok := f["background_color"].(string); ok {
+			parts = append(parts, fmt.Sprintf("bg: %s", color))
+		}
+	}
+	if len(parts) > 0 {
+		return fmt.Sprintf("Format %s (%s)", rangeAddr, strings.Join(parts, ", ")), details
+	}
+	return fmt.Sprintf("Format %s", rangeAddr), details
+}
+
+func previewCreateChart(input map[string]interface{}) (string, map[string]interface{}) {
+	chartType, _ := input["chart_type"].(string)
+	dataRange, _ := input["data_range"].(string)
+	return fmt.Sprintf("Create %s chart from %s", chartType, dataRange), map[string]interface{}{
+		"chart_type": input["chart_type"],
+		"data_range": input["data_range"],
+		"position":   input["position"],
+	}
+}
+
+func previewCreateNamedRange(input map[string]interface{}) (string, map[string]interface{}) {
+	name, _ := input["name"].(string)
+	rangeAddr, _ := input["range"].(string)
+	return fmt.Sprintf("Create named range '%s' for %s", name, rangeAddr), map[string]interface{}{
+		"name":  input["name"],
+		"range": input["range"],
+	}
+}
+
+func previewInsertRowsColumns(input map[string]interface{}) (string, map[string]interface{}) {
+	return fmt.Sprintf("Insert %v %v at %v", input["count"], input["type"], input["position"]), map[string]interface{}{
+		"position": input["position"],
+		"count":    input["count"],
+		"type":     input["type"],
+	}
+}
+
+// undoInput starts the input of an inverse operation
+func undoInput(input map[string]interface{}, keys ...string) map[string]interface{} {
+	inverse := map[string]interface{}{"_is_undo": true}
+	for _, key := range keys {
+		inverse[key] = input[key]
+	}
+	return inverse
+}
+
+func inverseWriteRange(input, result map[string]interface{}) *InverseOperation {
+	inverse := undoInput(input)
+	for k, v := range input {
+		inverse[k] = v
+	}
+	inverse["_is_undo"] = true
+	// The client reports the values it overwrote when the write completes
+	if prevValues, ok := result["previous_values"]; ok {
+		inverse["values"] = prevValues
+	}
+	return &InverseOperation{
+		Type:        "write_range",
+		Input:       inverse,
+		Description: fmt.Sprintf("Undo write to %v", input["range"]),
+		Preview:     fmt.Sprintf("Restore previous values to %v", input["range"]),
+	}
+}
+
+func inverseApplyFormula(input, result map[string]interface{}) *InverseOperation {
+	inverse := undoInput(input, "range")
+	if result != nil {
+		// Without a previous formula, the undo clears the cells
+		inverse["formula"] = ""
+		if prevFormula, ok := result["previous_formula"]; ok {
+			inverse["formula"] = prevFormula
+		}
+	}
+	return &InverseOperation{
+		Type:        "apply_formula",
+		Input:       inverse,
+		Description: fmt.Sprintf("Undo formula in %v", input["range"]),
+		Preview:     fmt.Sprintf("Restore previous formula to %v", input["range"]),
+	}
+}
+
+func inverseFormatRange(input, result map[string]interface{}) *InverseOperation {
+	inverse := undoInput(input, "range")
+	if prevFormat, ok := result["previous_format"]; ok {
+		inverse["format"] = prevFormat
+	}
+	return &InverseOperation{
+		Type:        "format_range",
+		Input:       inverse,
+		Description: fmt.Sprintf("Undo formatting in %v", input["range"]),
+		Preview:     fmt.Sprintf("Restore previous format to %v", input["range"]),
+	}
+}
+
+func inverseInsertRowsColumns(input, result map[string]interface{}) *InverseOperation {
+	return &InverseOperation{
+		Type:        "delete_rows_columns",
+		Input:       undoInput(input, "position", "count", "type"),
+		Description: fmt.Sprintf("Undo insert %v %v", input["count"], input["type"]),
+		Preview:     fmt.Sprintf("Delete %v %v at %v", input["count"], input["type"], input["position"]),
+	}
+}
+
+func inverseCreateNamedRange(input, result map[string]interface{}) *InverseOperation {
+	return &InverseOperation{
+		Type:        "delete_named_range",
+		Input:       undoInput(input, "name"),
+		Description: fmt.Sprintf("Undo create named range '%v'", input["name"]),
+		Preview:     fmt.Sprintf("Delete named range '%v'", input["name"]),
+	}
+}
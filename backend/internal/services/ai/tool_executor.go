@@ -23,7 +23,11 @@ const (
 
 // Session represents a minimal session interface for AI services
 type Session struct {
-	MemoryStore *memory.VectorStore
+	UserID          string
+	MemoryStore     *memory.VectorStore
+	MemoryNamespace memory.Namespace
+	Workbook        string
+	ActiveSheet     string
 }
 
 // ToolExecutor handles the execution of Excel tools
@@ -43,6 +47,20 @@ type ToolExecutor struct {
 	queuedOpsRegistry interface{} // Will be set to *services.QueuedOperationRegistry
 	// Embedding provider for memory search
 	embeddingProvider EmbeddingProvider
+	// Filing facts for populating historicals
+	factSource FactSource
+	// Tools available to the model
+	registry *ToolRegistry
+	// Decides whether writes apply, need approval or are refused
+	policy *PolicyEngine
+	// Cells the AI must never modify
+	protectedRanges ProtectedRangeSource
+	// Records every tool execution in the audit log
+	auditor ToolAuditor
+	// Records where every AI-written cell came from
+	lineage LineageStore
+	// Finds the workspace whose settings apply to a session
+	workspaces WorkspaceSource
 }
 
 // ExcelBridge interface for interacting with Excel
@@ -224,186 +242,6 @@ func formatToolError(err error) map[string]interface{} {
 	return map[string]interface{}{"error": err.Error()}
 }
 
-// generateOperationPreview creates a human-readable preview of what an operation will do
-func generateOperationPreview(toolName string, input map[string]interface{}) string {
-	switch toolName {
-	case "write_range":
-		rangeAddr, _ := input["range"].(string)
-		values := input["values"]
-
-		// Try to create a concise preview of the values
-		var preview string
-		if vals, ok := values.([][]interface{}); ok && len(vals) > 0 && len(vals[0]) > 0 {
-			firstValue := fmt.Sprintf("%v", vals[0][0])
-			if len(vals) == 1 && len(vals[0]) == 1 {
-				preview = fmt.Sprintf("Write '%s' to %s", firstValue, rangeAddr)
-			} else {
-				preview = fmt.Sprintf("Write %dx%d values starting with '%s' to %s",
-					len(vals), len(vals[0]), firstValue, rangeAddr)
-			}
-		} else {
-			preview = fmt.Sprintf("Write values to %s", rangeAddr)
-		}
-		return preview
-
-	case "apply_formula":
-		rangeAddr, _ := input["range"].(string)
-		formula, _ := input["formula"].(string)
-		if len(formula) > 50 {
-			formula = formula[:47] + "..."
-		}
-		return fmt.Sprintf("Apply formula '%s' to %s", formula, rangeAddr)
-
-	case "format_range":
-		rangeAddr, _ := input["range"].(string)
-		format := input["format"]
-
-		var formatDesc string
-		if f, ok := format.(map[string]interface{}); ok {
-			parts := []string{}
-			if numFmt, ok := f["number_format"].(string); ok {
-				parts = append(parts, fmt.Sprintf("format: %s", numFmt))
-			}
-			if bold, ok := f["bold"].(bool); ok && bold {
-				parts = append(parts, "bold")
-			}
-			if color, ok := f["background_color"].(string); ok {
-				parts = append(parts, fmt.Sprintf("bg: %s", color))
-			}
-			if len(parts) > 0 {
-				formatDesc = strings.Join(parts, ", ")
-			}
-		}
-
-		if formatDesc != "" {
-			return fmt.Sprintf("Format %s (%s)", rangeAddr, formatDesc)
-		}
-		return fmt.Sprintf("Format %s", rangeAddr)
-
-	case "create_chart":
-		chartType, _ := input["chart_type"].(string)
-		dataRange, _ := input["data_range"].(string)
-		return fmt.Sprintf("Create %s chart from %s", chartType, dataRange)
-
-	case "create_named_range":
-		name, _ := input["name"].(string)
-		rangeAddr, _ := input["range"].(string)
-		return fmt.Sprintf("Create named range '%s' for %s", name, rangeAddr)
-
-	case "insert_rows_columns":
-		position, _ := input["position"].(string)
-		count, _ := input["count"].(int)
-		insertType, _ := input["type"].(string)
-		return fmt.Sprintf("Insert %d %s at %s", count, insertType, position)
-
-	case "clear_range":
-		rangeAddr, _ := input["range"].(string)
-		clearType, _ := input["clear_type"].(string)
-		if clearType == "" {
-			clearType = "all"
-		}
-		return fmt.Sprintf("Clear %s from %s", clearType, rangeAddr)
-
-	case "copy_range":
-		sourceRange, _ := input["source_range"].(string)
-		destRange, _ := input["destination_range"].(string)
-		return fmt.Sprintf("Copy %s to %s", sourceRange, destRange)
-
-	default:
-		// Generic preview for unknown tools
-		rangeAddr, hasRange := input["range"].(string)
-		if hasRange {
-			return fmt.Sprintf("Execute %s on %s", toolName, rangeAddr)
-		}
-		return fmt.Sprintf("Execute %s", toolName)
-	}
-}
-
-// generateStructuredPreview creates a structured preview with additional metadata
-func generateStructuredPreview(toolName string, input map[string]interface{}) map[string]interface{} {
-	// Get basic preview text
-	previewText := generateOperationPreview(toolName, input)
-
-	// Build structured preview
-	preview := map[string]interface{}{
-		"text":         previewText,
-		"tool":         toolName,
-		"preview_type": getPreviewType(toolName),
-	}
-
-	// Add tool-specific preview data
-	switch toolName {
-	case "write_range":
-		preview["affected_range"] = input["range"]
-		if values, ok := input["values"].([][]interface{}); ok {
-			preview["dimensions"] = map[string]int{
-				"rows":    len(values),
-				"columns": 0,
-			}
-			if len(values) > 0 {
-				preview["dimensions"].(map[string]int)["columns"] = len(values[0])
-			}
-			// Add sample values for preview
-			if len(values) > 0 && len(values[0]) > 0 {
-				preview["sample_value"] = values[0][0]
-			}
-		}
-
-	case "apply_formula":
-		preview["affected_range"] = input["range"]
-		preview["formula"] = input["formula"]
-		preview["relative_references"] = input["relative_references"]
-
-	case "format_range":
-		preview["affected_range"] = input["range"]
-		preview["formatting"] = input["format"]
-
-	case "create_chart":
-		preview["chart_type"] = input["chart_type"]
-		preview["data_range"] = input["data_range"]
-		preview["position"] = input["position"]
-
-	case "create_named_range":
-		preview["name"] = input["name"]
-		preview["range"] = input["range"]
-
-	case "insert_rows_columns":
-		preview["position"] = input["position"]
-		preview["count"] = input["count"]
-		preview["type"] = input["type"]
-	}
-
-	return preview
-}
-
-// getPreviewType returns the preview type for a given tool
-func getPreviewType(toolName string) string {
-	// Get tools with enriched manifest data
-	tools := GetExcelTools()
-
-	// Find the tool and return its preview type
-	for _, tool := range tools {
-		if tool.Name == toolName {
-			if tool.PreviewType != "" {
-				return tool.PreviewType
-			}
-			break
-		}
-	}
-
-	// Default preview types based on tool category
-	switch toolName {
-	case "read_range", "analyze_data", "validate_model", "get_named_ranges":
-		return "json"
-	case "write_range", "apply_formula", "format_range", "insert_rows_columns", "clear_range", "copy_range":
-		return "excel_diff"
-	case "create_chart":
-		return "image"
-	default:
-		return "text"
-	}
-}
-
 // NewToolExecutor creates a new tool executor
 func NewToolExecutor(bridge ExcelBridge, formulaValidator *formula.FormulaIntelligence) *ToolExecutor {
 	return &ToolExecutor{
@@ -413,9 +251,21 @@ func NewToolExecutor(bridge ExcelBridge, formulaValidator *formula.FormulaIntell
 		modelDataCache:   make(map[string]*CachedModelData),
 		parallelWorkers:  4, // Configurable based on system
 		cacheExpiry:      10 * time.Minute,
+		registry:         NewBuiltinToolRegistry(),
+		policy:           NewPolicyEngine(PolicyConfig{}, nil),
 	}
 }
 
+// Registry returns the executor's tool registry
+func (te *ToolExecutor) Registry() *ToolRegistry {
+	return te.registry
+}
+
+// SetPolicyEngine replaces the autonomy policy applied to writes
+func (te *ToolExecutor) SetPolicyEngine(engine *PolicyEngine) {
+	te.policy = engine
+}
+
 // SetEmbeddingProvider sets the embedding provider for memory search
 func (te *ToolExecutor) SetEmbeddingProvider(provider EmbeddingProvider) {
 	te.embeddingProvider = provider
@@ -514,440 +364,52 @@ func (te *ToolExecutor) executeWithErrorHandling(ctx context.Context, sessionID
 
 	startTime := time.Now()
 
-	// Extract message ID from context if available
-	messageID := ""
-	if msgID, ok := ctx.Value("message_id").(string); ok {
-		messageID = msgID
-	}
-
 	// Add tool ID to input for tracking
 	toolCall.Input["_tool_id"] = toolCall.ID
 
-	switch toolCall.Name {
-	case "read_range":
-		content, err := te.executeReadRange(ctx, sessionID, toolCall.Input)
-		if err != nil {
-			return nil, err
-		}
-		result.Status = "success"
-		result.Content = content
-		result.Details = map[string]interface{}{
-			"operation": "read_range",
-			"range":     toolCall.Input["range"],
-			"timestamp": time.Now().Format(time.RFC3339),
-		}
-
-	case "write_range":
-		// Add preview mode flag for agent-default autonomy mode
-		if autonomyMode == "agent-default" {
-			toolCall.Input["preview_mode"] = true
-		}
-		err := te.executeWriteRange(ctx, sessionID, toolCall.Input)
-		if err != nil {
-			// Check if it's a queued error
-			if err.Error() == "Tool execution queued for user approval" {
-				result.IsError = false
-				result.Status = "queued"
-				preview := generateOperationPreview("write_range", toolCall.Input)
-				result.Content = map[string]interface{}{
-					"status":  "queued",
-					"message": "Write range operation queued for user approval",
-					"preview": preview,
-					"action": map[string]interface{}{
-						"type":         "preview_queued",
-						"operation_id": toolCall.ID,
-						"tool_type":    "write_range",
-						"input":        toolCall.Input,
-						"preview":      preview,
-						"preview_type": "excel_diff",
-						"description":  preview,
-					},
-				}
-				result.Details = map[string]interface{}{
-					"operation": "write_range",
-					"range":     toolCall.Input["range"],
-					"preview":   preview,
-					"timestamp": time.Now().Format(time.RFC3339),
-				}
-
-				// Queue the operation with structured preview
-				structuredPreview := generateStructuredPreview("write_range", toolCall.Input)
-				preview = structuredPreview["text"].(string)
-				if preview == "" {
-					preview = "Write data to Excel range"
-				}
-
-				// Extract batch information if present
-				var batchID string
-				var dependencies []string
-
-				if bid, ok := toolCall.Input["_batch_id"].(string); ok {
-					batchID = bid
-
-					// If this is not the first in the batch, add dependency on previous operation
-					if batchIndex, ok := toolCall.Input["_batch_index"].(int); ok && batchIndex > 0 {
-						// Get the tool IDs from the batch
-						if batchToolIDs, ok := toolCall.Input["_batch_tool_ids"].([]string); ok && batchIndex < len(batchToolIDs) {
-							// Use the actual tool ID of the previous operation
-							prevOpID := batchToolIDs[batchIndex-1]
-							dependencies = append(dependencies, prevOpID)
-						}
-					}
-				}
-
-				// Always use the toolCall.ID for operation tracking
-				// This ensures consistency with the request mapper
-				opID := toolCall.ID
-
-				// Register with queued operations if available
-				if te.queuedOpsRegistry != nil {
-					queuedOp := map[string]interface{}{
-						"ID":           opID,
-						"SessionID":    sessionID,
-						"Type":         "write_range",
-						"Input":        toolCall.Input,
-						"Preview":      structuredPreview,
-						"PreviewType":  structuredPreview["preview_type"].(string),
-						"Context":      "Excel write operation requested by AI",
-						"Priority":     50, // Normal priority
-						"BatchID":      batchID,
-						"Dependencies": dependencies,
-						"MessageID":    messageID, // Add message ID
-					}
-
-					// Try to queue the operation
-					if registry, ok := te.queuedOpsRegistry.(interface {
-						QueueOperation(interface{}) error
-					}); ok {
-						if err := registry.QueueOperation(queuedOp); err != nil {
-							log.Error().Err(err).Msg("Failed to register queued operation")
-						}
-					}
-				}
-
-				// Remove preview callback code that was accidentally added
-				// This functionality is already handled by the queuedOpsRegistry above
-
-				return result, nil
-			}
-			result.IsError = true
-			result.Status = "error"
-			result.Content = formatToolError(err)
-			return result, nil
-		}
-		result.Status = "success"
-		result.Content = map[string]string{"status": "success", "message": "Range written successfully"}
-		result.Details = map[string]interface{}{
-			"operation": "write_range",
-			"range":     toolCall.Input["range"],
-			"timestamp": time.Now().Format(time.RFC3339),
-		}
-
-	case "apply_formula":
-		// Add preview mode flag for agent-default autonomy mode
-		if autonomyMode == "agent-default" {
-			toolCall.Input["preview_mode"] = true
-		}
-		err := te.executeApplyFormula(ctx, sessionID, toolCall.Input)
-		if err != nil {
-			// Check if it's a queued error
-			if err.Error() == "Tool execution queued for user approval" {
-				result.IsError = false
-				result.Status = "queued"
-				preview := generateOperationPreview("apply_formula", toolCall.Input)
-				result.Content = map[string]interface{}{
-					"status":  "queued",
-					"message": "Formula application queued for user approval",
-					"preview": preview,
-				}
-				result.Details = map[string]interface{}{
-					"operation": "apply_formula",
-					"range":     toolCall.Input["range"],
-					"formula":   toolCall.Input["formula"],
-					"preview":   preview,
-					"timestamp": time.Now().Format(time.RFC3339),
-				}
-
-				// Register in queued operations registry if available
-				if te.queuedOpsRegistry != nil {
-					// Generate structured preview for the operation
-					structuredPreview := generateStructuredPreview("apply_formula", toolCall.Input)
-
-					// Extract batch information if present
-					var batchID string
-					var dependencies []string
-
-					if bid, ok := toolCall.Input["_batch_id"].(string); ok {
-						batchID = bid
-
-						// If this is not the first in the batch, add dependency on previous operation
-						if batchIndex, ok := toolCall.Input["_batch_index"].(int); ok && batchIndex > 0 {
-							// Create dependency on the previous operation in the batch
-							prevOpID := fmt.Sprintf("%s_%s_%d", batchID, sessionID, batchIndex-1)
-							dependencies = append(dependencies, prevOpID)
-						}
-					}
-
-					// Always use the toolCall.ID for operation tracking
-					// This ensures consistency with the request mapper
-					opID := toolCall.ID
-
-					// Create a properly structured operation for the registry
-					queuedOp := map[string]interface{}{
-						"ID":           opID,
-						"SessionID":    sessionID,
-						"Type":         "apply_formula",
-						"Input":        toolCall.Input,
-						"Preview":      structuredPreview,
-						"PreviewType":  structuredPreview["preview_type"].(string),
-						"Context":      "Excel formula application requested by AI",
-						"Priority":     50, // Normal priority
-						"BatchID":      batchID,
-						"Dependencies": dependencies,
-						"MessageID":    messageID, // Add message ID
-					}
-
-					// Try to queue the operation
-					if registry, ok := te.queuedOpsRegistry.(interface {
-						QueueOperation(interface{}) error
-					}); ok {
-						if err := registry.QueueOperation(queuedOp); err != nil {
-							log.Error().Err(err).Msg("Failed to register queued operation")
-						}
-					}
-				}
-
-				return result, nil
-			}
-			result.IsError = true
-			result.Status = "error"
-			result.Content = formatToolError(err)
-			return result, nil
-		}
-		result.Content = map[string]string{"status": "success", "message": "Formula applied successfully"}
-
-	case "analyze_data":
-		content, err := te.executeAnalyzeData(ctx, sessionID, toolCall.Input)
-		if err != nil {
-			result.IsError = true
-			result.Content = formatToolError(err)
-			return result, nil
-		}
-		result.Content = content
-
-	case "format_range":
-		// Add preview mode flag for agent-default autonomy mode
-		if autonomyMode == "agent-default" {
-			toolCall.Input["preview_mode"] = true
-		}
-		err := te.executeFormatRange(ctx, sessionID, toolCall.Input)
-		if err != nil {
-			// Check if it's a queued error
-			if err.Error() == "Tool execution queued for user approval" {
-				result.IsError = false
-				result.Status = "queued"
-				preview := generateOperationPreview("format_range", toolCall.Input)
-				result.Content = map[string]interface{}{
-					"status":  "queued",
-					"message": "Format operation queued for user approval",
-					"preview": preview,
-				}
-				result.Details = map[string]interface{}{
-					"operation": "format_range",
-					"range":     toolCall.Input["range"],
-					"preview":   preview,
-					"timestamp": time.Now().Format(time.RFC3339),
-				}
-
-				// Register in queued operations registry if available
-				if te.queuedOpsRegistry != nil {
-					// Generate structured preview for the operation
-					structuredPreview := generateStructuredPreview("format_range", toolCall.Input)
-
-					// Extract batch information if present
-					var batchID string
-					var dependencies []string
-
-					if bid, ok := toolCall.Input["_batch_id"].(string); ok {
-						batchID = bid
-
-						// If this is not the first in the batch, add dependency on previous operation
-						if batchIndex, ok := toolCall.Input["_batch_index"].(int); ok && batchIndex > 0 {
-							// Create dependency on the previous operation in the batch
-							prevOpID := fmt.Sprintf("%s_%s_%d", batchID, sessionID, batchIndex-1)
-							dependencies = append(dependencies, prevOpID)
-						}
-					}
-
-					// Always use the toolCall.ID for operation tracking
-					// This ensures consistency with the request mapper
-					opID := toolCall.ID
-
-					// Create a properly structured operation for the registry
-					queuedOp := map[string]interface{}{
-						"ID":           opID,
-						"SessionID":    sessionID,
-						"Type":         "format_range",
-						"Input":        toolCall.Input,
-						"Preview":      structuredPreview,
-						"PreviewType":  structuredPreview["preview_type"].(string),
-						"Context":      "Excel formatting requested by AI",
-						"Priority":     40, // Lower priority for formatting
-						"BatchID":      batchID,
-						"Dependencies": dependencies,
-						"MessageID":    messageID, // Add message ID
-					}
-
-					// Try to queue the operation
-					if registry, ok := te.queuedOpsRegistry.(interface {
-						QueueOperation(interface{}) error
-					}); ok {
-						if err := registry.QueueOperation(queuedOp); err != nil {
-							log.Error().Err(err).Msg("Failed to register queued operation")
-						}
-					}
-				}
-
-				return result, nil
-			}
-			result.IsError = true
-			result.Status = "error"
-			result.Content = formatToolError(err)
-			return result, nil
-		}
-		result.Status = "success"
-		result.Content = map[string]string{"status": "success", "message": "Formatting applied successfully"}
-		result.Details = map[string]interface{}{
-			"operation": "format_range",
-			"range":     toolCall.Input["range"],
-			"timestamp": time.Now().Format(time.RFC3339),
-		}
-
-	case "create_chart":
-		err := te.executeCreateChart(ctx, sessionID, toolCall.Input)
-		if err != nil {
-			result.IsError = true
-			result.Content = formatToolError(err)
-			return result, nil
-		}
-		result.Content = map[string]string{"status": "success", "message": "Chart created successfully"}
-
-	case "validate_model":
-		content, err := te.executeValidateModel(ctx, sessionID, toolCall.Input)
-		if err != nil {
-			result.IsError = true
-			result.Content = formatToolError(err)
-			return result, nil
-		}
-		result.Content = content
-
-	case "get_named_ranges":
-		content, err := te.executeGetNamedRanges(ctx, sessionID, toolCall.Input)
-		if err != nil {
-			result.IsError = true
-			result.Content = formatToolError(err)
-			return result, nil
-		}
-		result.Content = content
-
-	case "create_named_range":
-		err := te.executeCreateNamedRange(ctx, sessionID, toolCall.Input)
-		if err != nil {
-			result.IsError = true
-			result.Content = formatToolError(err)
-			return result, nil
-		}
-		result.Content = map[string]string{"status": "success", "message": "Named range created successfully"}
-
-	case "insert_rows_columns":
-		err := te.executeInsertRowsColumns(ctx, sessionID, toolCall.Input)
-		if err != nil {
-			result.IsError = true
-			result.Content = formatToolError(err)
-			return result, nil
-		}
-		result.Content = map[string]string{"status": "success", "message": "Rows/columns inserted successfully"}
-
-	case "build_financial_formula":
-		content, err := te.executeBuildFinancialFormula(ctx, sessionID, toolCall.Input)
-		if err != nil {
-			result.IsError = true
-			result.Content = formatToolError(err)
-			return result, nil
-		}
-		result.Content = content
-
-	case "analyze_model_structure":
-		content, err := te.executeAnalyzeModelStructure(ctx, sessionID, toolCall.Input)
-		if err != nil {
-			result.IsError = true
-			result.Content = formatToolError(err)
-			return result, nil
-		}
-		result.Content = content
-
-	case "smart_format_cells":
-		err := te.executeSmartFormatCells(ctx, sessionID, toolCall.Input)
-		if err != nil {
-			result.IsError = true
-			result.Content = formatToolError(err)
-			return result, nil
-		}
-		result.Content = map[string]string{"status": "success", "message": "Smart formatting applied successfully"}
-
-	case "create_audit_trail":
-		content, err := te.executeCreateAuditTrail(ctx, sessionID, toolCall.Input)
-		if err != nil {
-			result.IsError = true
-			result.Content = formatToolError(err)
-			return result, nil
-		}
-		result.Content = content
-
-	case "organize_financial_model":
-		content, err := te.executeOrganizeFinancialModel(ctx, sessionID, toolCall.Input)
-		if err != nil {
-			result.IsError = true
-			result.Content = formatToolError(err)
-			return result, nil
-		}
-		result.Content = content
-
-	case "search_memory":
-		content, err := te.executeMemorySearch(ctx, sessionID, toolCall.Input)
-		if err != nil {
-			result.IsError = true
-			result.Content = formatToolError(err)
-			return result, nil
-		}
-		result.Content = content
-
-	case "trace_precedents":
-		content, err := te.executeTracePrecedents(ctx, sessionID, toolCall.Input)
-		if err != nil {
-			result.IsError = true
-			result.Content = formatToolError(err)
-			return result, nil
-		}
-		result.Content = content
-
-	case "trace_dependents":
-		content, err := te.executeTraceDependents(ctx, sessionID, toolCall.Input)
-		if err != nil {
-			result.IsError = true
-			result.Content = formatToolError(err)
-			return result, nil
-		}
-		result.Content = content
-
-	default:
+	def, ok := te.registry.LookupForSession(toolCall.Name, sessionID)
+	if !ok || def.Execute == nil {
 		result.IsError = true
 		unknownToolErr := newEnhancedError(
 			fmt.Sprintf("Unknown tool: %s", toolCall.Name),
 			"The requested tool is not available in the Excel integration",
-			"Available tools include: read_range, write_range, apply_formula, analyze_data, format_range, create_chart, validate_model, and more",
+			fmt.Sprintf("Available tools: %s", strings.Join(te.registry.Names(), ", ")),
 		)
 		result.Content = formatToolError(unknownToolErr)
+	} else if input, fieldErrs := def.schema.validate(toolCall.Input); len(fieldErrs) > 0 {
+		// Report every invalid field so the model can correct the call
+		log.Warn().
+			Str("tool", toolCall.Name).
+			Str("tool_id", toolCall.ID).
+			Interface("fields", fieldErrs).
+			Msg("Tool input failed schema validation")
+		result.IsError = true
+		result.Status = "error"
+		result.Content = newInputValidationError(toolCall, fieldErrs)
+	} else if err := te.checkProtectedRanges(ctx, sessionID, def, ToolCall{ID: toolCall.ID, Name: toolCall.Name, Input: input}); err != nil {
+		result.IsError = true
+		result.Status = "error"
+		result.Content = formatToolError(err)
+	} else if decision := te.applyPolicy(ctx, sessionID, toolCall, autonomyMode, def, input); decision != nil && decision.Outcome == PolicyDeny {
+		result.IsError = true
+		result.Status = "error"
+		result.Content = map[string]interface{}{
+			"error":  "Write blocked by the autonomy policy: " + decision.Explain(),
+			"policy": decision,
+		}
+	} else {
+		toolCall.Input = input
+		if err := def.Execute(ctx, te, sessionID, toolCall, autonomyMode, result); err != nil {
+			te.auditToolExecution(ctx, sessionID, toolCall, nil, err, time.Since(startTime))
+			return nil, err
+		}
+		if decision != nil {
+			if result.Details == nil {
+				result.Details = make(map[string]interface{})
+			}
+			result.Details["policy"] = decision
+		}
+		te.recordLineage(ctx, sessionID, def, toolCall, autonomyMode, result)
 	}
 
 	// Validate response size before returning
@@ -966,10 +428,28 @@ func (te *ToolExecutor) executeWithErrorHandling(ctx context.Context, sessionID
 		Dur("duration_ms", duration).
 		Interface("result", result.Content).
 		Msg("Excel tool execution completed")
+	te.auditToolExecution(ctx, sessionID, toolCall, result, nil, duration)
 
 	return result, nil
 }
 
+// applyPolicy evaluates a write against the autonomy policy and marks the
+// input for preview when it needs approval. Reads return nil.
+func (te *ToolExecutor) applyPolicy(ctx context.Context, sessionID string, toolCall ToolCall, autonomyMode string, def *ToolDefinition, input map[string]interface{}) *PolicyDecision {
+	if def.Permission != "write" || te.policy == nil {
+		return nil
+	}
+	toolCall.Input = input
+	decision := te.policy.Evaluate(ctx, te, sessionID, toolCall, autonomyMode, def.Queueable)
+	switch decision.Outcome {
+	case PolicyRequireApproval:
+		input["preview_mode"] = true
+	case PolicyAutoApply:
+		delete(input, "preview_mode")
+	}
+	return decision
+}
+
 // ExecuteParallelTools executes multiple tools simultaneously for 3-5x performance improvement
 func (te *ToolExecutor) ExecuteParallelTools(ctx context.Context, sessionID string, toolCalls []ToolCall) ([]ParallelToolResult, error) {
 	if len(toolCalls) == 0 {
@@ -3400,11 +2880,12 @@ func parseCell(cell string) (col, row int, err error) {
 		}
 	}
 
-	// Convert column letters to number (A=0, B=1, etc.)
+	// Convert column letters to number (A=0, B=1, ..., AA=26, etc.)
 	col = 0
 	for i := 0; i < len(colStr); i++ {
-		col = col*26 + int(colStr[i]-'A')
+		col = col*26 + int(colStr[i]-'A') + 1
 	}
+	col--
 
 	// Convert row string to number (1-based to 0-based)
 	row64, err := strconv.ParseInt(rowStr, 10, 32)
@@ -3476,56 +2957,46 @@ func (te *ToolExecutor) executeMemorySearch(ctx context.Context, sessionID strin
 		}
 	}
 
-	// Perform search
-	var searchResults []memory.SearchResult
-
+	// Embed the query when possible; the retriever fuses vector and BM25
+	// rankings and falls back to keywords alone without a vector
+	var queryVector []float32
 	if te.embeddingProvider != nil {
-		// Use vector search with embeddings
-		log.Info().
-			Str("session", sessionID).
-			Str("query", query).
-			Str("source_filter", sourceFilter).
-			Int("limit", limit).
-			Msg("Performing vector search with embeddings")
-
-		queryVector, err := te.embeddingProvider.GetEmbedding(ctx, query)
+		vector, err := te.embeddingProvider.GetEmbedding(ctx, query)
 		if err != nil {
-			log.Warn().Err(err).Msg("Failed to get query embedding, falling back to keyword search")
-			// Fall back to keyword search
-			if inMemStore, ok := memStore.(*memory.InMemoryStore); ok {
-				keywords := strings.Fields(query)
-				searchResults = inMemStore.KeywordSearch(keywords, limit, filter)
-			}
+			log.Warn().Err(err).Msg("Failed to get query embedding, using keyword retrieval only")
 		} else {
-			searchResults, err = memStore.Search(queryVector, limit, filter)
-			if err != nil {
-				return nil, fmt.Errorf("vector search failed: %w", err)
-			}
+			queryVector = vector
 		}
-	} else {
-		// Fallback to keyword search
-		log.Info().
-			Str("session", sessionID).
-			Str("query", query).
-			Msg("Performing keyword search (no embedding provider)")
+	}
 
-		if inMemStore, ok := memStore.(*memory.InMemoryStore); ok {
-			keywords := strings.Fields(query)
-			searchResults = inMemStore.KeywordSearch(keywords, limit, filter)
-		} else {
-			return nil, fmt.Errorf("keyword search not supported for this memory store type")
-		}
+	log.Info().
+		Str("session", sessionID).
+		Str("query", query).
+		Str("source_filter", sourceFilter).
+		Int("limit", limit).
+		Bool("vector", queryVector != nil).
+		Msg("Performing hybrid memory search")
+
+	searchResults, err := memory.NewRetriever().Search(memStore, session.MemoryNamespace, query, queryVector, limit, filter)
+	if err != nil {
+		return nil, fmt.Errorf("memory search failed: %w", err)
 	}
 
-	// Format results
+	// Format results. When the chat pipeline collects citations, each
+	// result gets an ID the model cites in its answer.
+	citations := CitationsFromContext(ctx)
 	results := make([]map[string]interface{}, 0, len(searchResults))
 	for _, result := range searchResults {
 		resultMap := map[string]interface{}{
 			"source":     result.Chunk.Metadata.Source,
 			"content":    result.Chunk.Content,
 			"similarity": result.Similarity,
+			"score":      result.Score,
 			"reference":  formatChunkReference(result.Chunk),
 		}
+		if citations != nil {
+			resultMap["citation_id"] = citations.Add(result.Chunk)
+		}
 
 		// Add source-specific metadata
 		switch result.Chunk.Metadata.Source {
@@ -3558,12 +3029,16 @@ func (te *ToolExecutor) executeMemorySearch(ctx context.Context, sessionID strin
 		Int("results_count", len(results)).
 		Msg("Memory search completed")
 
-	return map[string]interface{}{
+	response := map[string]interface{}{
 		"results":       results,
 		"query":         query,
 		"source_filter": sourceFilter,
 		"total_results": len(results),
-	}, nil
+	}
+	if citations != nil && len(results) > 0 {
+		response["citation_format"] = "Cite results inline by citation_id, e.g. [S1] or [S1, S2]"
+	}
+	return response, nil
 }
 
 // formatChunkReference formats a chunk's metadata into a readable reference
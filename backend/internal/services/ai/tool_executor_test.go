@@ -0,0 +1,26 @@
+package ai
+
+import "testing"
+
+func TestParseCell(t *testing.T) {
+	tests := []struct {
+		cell     string
+		col, row int
+	}{
+		{"A1", 0, 0},
+		{"Z5", 25, 4},
+		{"AA1", 26, 0},
+		{"AZ12", 51, 11},
+		{"BA3", 52, 2},
+		{"Sheet1!AB10", 27, 9},
+	}
+	for _, tt := range tests {
+		col, row, err := parseCell(tt.cell)
+		if err != nil || col != tt.col || row != tt.row {
+			t.Errorf("parseCell(%q) = %d, %d, %v, want %d, %d", tt.cell, col, row, err, tt.col, tt.row)
+		}
+	}
+	if _, _, err := parseCell("B"); err == nil {
+		t.Error("parseCell without a row returned no error")
+	}
+}
@@ -0,0 +1,458 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Tool groups used by selectRelevantTools to offer the model a focused
+// subset of tools for each kind of request
+const (
+	ToolGroupRead     = "read"     // Read-only questions
+	ToolGroupStarter  = "starter"  // Building on an empty sheet
+	ToolGroupBasic    = "basic"    // General requests
+	ToolGroupModeling = "modeling" // Model building and review
+	ToolGroupFilings  = "filings"  // Requests about filings and historicals
+	ToolGroupMinimal  = "minimal"  // Fallback when nothing else matched
+
+	// ToolGroupExternal holds tools served by external MCP servers. They are
+	// offered alongside whichever group a request selects.
+	ToolGroupExternal = "external"
+)
+
+// ToolHandler executes a tool call and fills in result. A returned error
+// fails the call outright; tool-level failures are reported by setting
+// result.IsError instead.
+type ToolHandler func(ctx context.Context, te *ToolExecutor, sessionID string, call ToolCall, autonomyMode string, result *ToolResult) error
+
+// ToolPreviewFunc describes what a call will do, as a one-line summary and
+// tool-specific fields for the structured preview
+type ToolPreviewFunc func(input map[string]interface{}) (string, map[string]interface{})
+
+// InverseFunc builds the operation that undoes a completed call. result is
+// what the client reported on completion and may be nil.
+type InverseFunc func(input, result map[string]interface{}) *InverseOperation
+
+// InverseOperation is a tool call that undoes another
+type InverseOperation struct {
+	Type        string
+	Input       map[string]interface{}
+	Description string
+	Preview     string
+}
+
+// ToolDefinition is everything the pipeline needs to know about a tool
+type ToolDefinition struct {
+	ExcelTool // Schema and manifest data; Permission is "read" or "write"
+
+	Groups  []string
+	Preview ToolPreviewFunc
+	Inverse InverseFunc
+	Execute ToolHandler
+
+	// Queueable tools can be previewed in the add-in and queued for the
+	// user's approval
+	Queueable bool
+
+	// Available reports whether the tool may be offered and called in a
+	// session. Nil means everywhere.
+	Available func(sessionID string) bool
+
+	schema inputSchema // Normalized InputSchema used to validate calls
+}
+
+// ToolConfig enables or disables tools by name. A non-empty Enabled list
+// is an allowlist; Disabled wins over it. Workspaces further restrict the
+// tools of sessions in a workspace, keyed by workspace ID.
+type ToolConfig struct {
+	Enabled    []string              `json:"enabled,omitempty"`
+	Disabled   []string              `json:"disabled,omitempty"`
+	Workspaces map[string]ToolConfig `json:"workspaces,omitempty"`
+}
+
+// ToolConfigFromEnv reads the JSON configuration at AI_TOOLS_CONFIG, if
+// set, and adds the comma-separated tool names from AI_TOOLS_ENABLED and
+// AI_TOOLS_DISABLED
+func ToolConfigFromEnv() (ToolConfig, error) {
+	var cfg ToolConfig
+	if path := os.Getenv("AI_TOOLS_CONFIG"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return cfg, fmt.Errorf("failed to read tool configuration: %w", err)
+		}
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return cfg, fmt.Errorf("failed to parse tool configuration: %w", err)
+		}
+	}
+	cfg.Enabled = append(cfg.Enabled, splitToolNames(os.Getenv("AI_TOOLS_ENABLED"))...)
+	cfg.Disabled = append(cfg.Disabled, splitToolNames(os.Getenv("AI_TOOLS_DISABLED"))...)
+	return cfg, nil
+}
+
+func splitToolNames(value string) []string {
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ToolRegistry holds the tools available to the model, in registration
+// order
+type ToolRegistry struct {
+	mu       sync.RWMutex
+	tools    map[string]*ToolDefinition
+	order    []string
+	disabled map[string]bool
+
+	// workspaceDisabled holds the tools each configured workspace disables.
+	// Sessions whose workspace is unknown get every tool any workspace
+	// disables disabled, in unknownDisabled.
+	workspaceDisabled map[string]map[string]bool
+	unknownDisabled   map[string]bool
+	workspaceOf       func(sessionID string) (string, error)
+}
+
+// NewToolRegistry creates an empty registry
+func NewToolRegistry() *ToolRegistry {
+	return &ToolRegistry{
+		tools:    make(map[string]*ToolDefinition),
+		disabled: make(map[string]bool),
+	}
+}
+
+// NewBuiltinToolRegistry creates a registry with the built-in Excel tools
+func NewBuiltinToolRegistry() *ToolRegistry {
+	r := NewToolRegistry()
+	registerBuiltinTools(r)
+	return r
+}
+
+var (
+	builtinToolsOnce sync.Once
+	builtinTools     *ToolRegistry
+)
+
+// builtinToolRegistry returns a shared, unconfigured registry of the
+// built-in tools. It must not be modified.
+func builtinToolRegistry() *ToolRegistry {
+	builtinToolsOnce.Do(func() {
+		builtinTools = NewBuiltinToolRegistry()
+	})
+	return builtinTools
+}
+
+// Register adds a tool. Manifest data fills in metadata the definition
+// leaves unset.
+func (r *ToolRegistry) Register(def ToolDefinition) error {
+	if def.Name == "" {
+		return fmt.Errorf("tool name is required")
+	}
+	def.ExcelTool = enrichToolsWithManifest([]ExcelTool{def.ExcelTool})[0]
+	if def.Permission != "read" && def.Permission != "write" {
+		return fmt.Errorf("tool %s: permission must be read or write, got %q", def.Name, def.Permission)
+	}
+	schema, err := compileInputSchema(def.InputSchema)
+	if err != nil {
+		return fmt.Errorf("tool %s: %w", def.Name, err)
+	}
+	def.schema = schema
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.tools[def.Name]; exists {
+		return fmt.Errorf("tool %s is already registered", def.Name)
+	}
+	r.tools[def.Name] = &def
+	r.order = append(r.order, def.Name)
+	return nil
+}
+
+func (r *ToolRegistry) mustRegister(def ToolDefinition) {
+	if err := r.Register(def); err != nil {
+		panic(err)
+	}
+}
+
+// SetAvailable sets the sessions a registered tool is available in
+func (r *ToolRegistry) SetAvailable(name string, available func(sessionID string) bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	def, exists := r.tools[name]
+	if !exists {
+		return fmt.Errorf("tool %s is not registered", name)
+	}
+	// Replace the definition so callers holding the old one are unaffected
+	updated := *def
+	updated.Available = available
+	r.tools[name] = &updated
+	return nil
+}
+
+// Unregister removes a tool
+func (r *ToolRegistry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.tools[name]; !exists {
+		return
+	}
+	delete(r.tools, name)
+	for i, n := range r.order {
+		if n == name {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// Configure applies cfg, replacing any earlier configuration. Unknown
+// tool names are an error so typos do not silently leave tools enabled.
+func (r *ToolRegistry) Configure(cfg ToolConfig) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	disabled, err := r.disabledBy(cfg)
+	if err != nil {
+		return err
+	}
+
+	workspaceDisabled := make(map[string]map[string]bool, len(cfg.Workspaces))
+	unknownDisabled := make(map[string]bool)
+	for workspace, workspaceCfg := range cfg.Workspaces {
+		if len(workspaceCfg.Workspaces) > 0 {
+			return fmt.Errorf("workspace %s: tool configuration cannot be nested", workspace)
+		}
+		off, err := r.disabledBy(workspaceCfg)
+		if err != nil {
+			return fmt.Errorf("workspace %s: %w", workspace, err)
+		}
+		workspaceDisabled[workspace] = off
+		for name := range off {
+			unknownDisabled[name] = true
+		}
+	}
+
+	r.disabled = disabled
+	r.workspaceDisabled = workspaceDisabled
+	r.unknownDisabled = unknownDisabled
+	return nil
+}
+
+// disabledBy returns the tools cfg disables. r.mu must be held.
+func (r *ToolRegistry) disabledBy(cfg ToolConfig) (map[string]bool, error) {
+	for _, name := range append(append([]string{}, cfg.Enabled...), cfg.Disabled...) {
+		if _, exists := r.tools[name]; !exists {
+			return nil, fmt.Errorf("unknown tool %q in tool configuration", name)
+		}
+	}
+
+	disabled := make(map[string]bool)
+	if len(cfg.Enabled) > 0 {
+		allowed := make(map[string]bool, len(cfg.Enabled))
+		for _, name := range cfg.Enabled {
+			allowed[name] = true
+		}
+		for name := range r.tools {
+			if !allowed[name] {
+				disabled[name] = true
+			}
+		}
+	}
+	for _, name := range cfg.Disabled {
+		disabled[name] = true
+	}
+	return disabled, nil
+}
+
+// SetWorkspaceResolver sets how the registry finds the workspace of a
+// session to apply workspace tool configuration
+func (r *ToolRegistry) SetWorkspaceResolver(workspaceOf func(sessionID string) (string, error)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.workspaceOf = workspaceOf
+}
+
+// sessionDisabled returns the tools the configuration of a session's
+// workspace disables
+func (r *ToolRegistry) sessionDisabled(sessionID string) map[string]bool {
+	r.mu.RLock()
+	configured, workspaceOf := len(r.workspaceDisabled) > 0, r.workspaceOf
+	r.mu.RUnlock()
+	if !configured {
+		return nil
+	}
+
+	workspace, err := "", fmt.Errorf("workspaces are not configured")
+	if workspaceOf != nil {
+		workspace, err = workspaceOf(sessionID)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if err != nil {
+		return r.unknownDisabled
+	}
+	return r.workspaceDisabled[workspace]
+}
+
+// Lookup returns an enabled tool
+func (r *ToolRegistry) Lookup(name string) (*ToolDefinition, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	def, ok := r.tools[name]
+	if !ok || r.disabled[name] {
+		return nil, false
+	}
+	return def, true
+}
+
+// LookupForSession returns a tool that is enabled and available in a
+// session, including in the configuration of the session's workspace
+func (r *ToolRegistry) LookupForSession(name, sessionID string) (*ToolDefinition, bool) {
+	def, ok := r.Lookup(name)
+	if !ok || r.sessionDisabled(sessionID)[name] || !def.availableIn(sessionID) {
+		return nil, false
+	}
+	return def, true
+}
+
+func (def *ToolDefinition) availableIn(sessionID string) bool {
+	return def.Available == nil || def.Available(sessionID)
+}
+
+// definition returns a tool whether or not it is enabled
+func (r *ToolRegistry) definition(name string) (*ToolDefinition, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	def, ok := r.tools[name]
+	return def, ok
+}
+
+// Tools returns the schemas of the enabled tools
+func (r *ToolRegistry) Tools() []ExcelTool {
+	return r.filter(func(*ToolDefinition) bool { return true })
+}
+
+// SessionTools returns the schemas of the tools available in a session
+func (r *ToolRegistry) SessionTools(sessionID string) []ExcelTool {
+	disabled := r.sessionDisabled(sessionID)
+	return r.filter(func(def *ToolDefinition) bool { return !disabled[def.Name] && def.availableIn(sessionID) })
+}
+
+// Group returns the schemas of the enabled tools in a group
+func (r *ToolRegistry) Group(group string) []ExcelTool {
+	return r.filter(func(def *ToolDefinition) bool { return def.inGroup(group) })
+}
+
+// SessionGroup returns the schemas of the tools in a group that are
+// available in a session
+func (r *ToolRegistry) SessionGroup(group, sessionID string) []ExcelTool {
+	disabled := r.sessionDisabled(sessionID)
+	return r.filter(func(def *ToolDefinition) bool {
+		return def.inGroup(group) && !disabled[def.Name] && def.availableIn(sessionID)
+	})
+}
+
+func (def *ToolDefinition) inGroup(group string) bool {
+	for _, g := range def.Groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *ToolRegistry) filter(keep func(*ToolDefinition) bool) []ExcelTool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tools := make([]ExcelTool, 0, len(r.order))
+	for _, name := range r.order {
+		if def := r.tools[name]; !r.disabled[name] && keep(def) {
+			tools = append(tools, def.ExcelTool)
+		}
+	}
+	return tools
+}
+
+// Names returns the names of the enabled tools, sorted
+func (r *ToolRegistry) Names() []string {
+	tools := r.Tools()
+	names := make([]string, len(tools))
+	for i, tool := range tools {
+		names[i] = tool.Name
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsWrite reports whether a tool modifies the workbook
+func (r *ToolRegistry) IsWrite(name string) bool {
+	def, ok := r.definition(name)
+	return ok && def.Permission == "write"
+}
+
+// PreviewType returns how the client should preview a tool's calls
+func (r *ToolRegistry) PreviewType(name string) string {
+	if def, ok := r.definition(name); ok && def.PreviewType != "" {
+		return def.PreviewType
+	}
+	return "text"
+}
+
+// Preview returns a one-line description of what a call will do
+func (r *ToolRegistry) Preview(name string, input map[string]interface{}) string {
+	text, _ := r.preview(name, input)
+	return text
+}
+
+// StructuredPreview returns the preview text along with tool-specific
+// details for the client
+func (r *ToolRegistry) StructuredPreview(name string, input map[string]interface{}) map[string]interface{} {
+	text, details := r.preview(name, input)
+	preview := map[string]interface{}{
+		"text":         text,
+		"tool":         name,
+		"preview_type": r.PreviewType(name),
+	}
+	for k, v := range details {
+		preview[k] = v
+	}
+	return preview
+}
+
+func (r *ToolRegistry) preview(name string, input map[string]interface{}) (string, map[string]interface{}) {
+	if def, ok := r.definition(name); ok && def.Preview != nil {
+		return def.Preview(input)
+	}
+	if rangeAddr, ok := input["range"].(string); ok {
+		return fmt.Sprintf("Execute %s on %s", name, rangeAddr), nil
+	}
+	return fmt.Sprintf("Execute %s", name), nil
+}
+
+// ValidateInput checks a call's input against the tool's schema and
+// returns the coerced input
+func (r *ToolRegistry) ValidateInput(name string, input map[string]interface{}) (map[string]interface{}, []FieldError) {
+	def, ok := r.definition(name)
+	if !ok {
+		return input, nil
+	}
+	return def.schema.validate(input)
+}
+
+// Inverse returns the operation that undoes a completed call, or nil when
+// the tool has no inverse
+func (r *ToolRegistry) Inverse(name string, input, result map[string]interface{}) *InverseOperation {
+	def, ok := r.definition(name)
+	if !ok || def.Inverse == nil {
+		return nil
+	}
+	return def.Inverse(input, result)
+}
@@ -0,0 +1,124 @@
+package ai
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestToolRegistry_Register(t *testing.T) {
+	r := NewToolRegistry()
+	if err := r.Register(ToolDefinition{ExcelTool: ExcelTool{Name: "highlight_cells"}}); err == nil {
+		t.Error("expected an error for a tool without a permission")
+	}
+
+	def := ToolDefinition{ExcelTool: ExcelTool{Name: "highlight_cells", Permission: "write"}, Groups: []string{ToolGroupBasic}}
+	if err := r.Register(def); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if err := r.Register(def); err == nil {
+		t.Error("expected an error for a duplicate tool")
+	}
+	if !r.IsWrite("highlight_cells") || r.IsWrite("unknown") {
+		t.Error("IsWrite() misclassified tools")
+	}
+	if got := r.Preview("highlight_cells", map[string]interface{}{"range": "A1:B2"}); got != "Execute highlight_cells on A1:B2" {
+		t.Errorf("Preview() = %q", got)
+	}
+}
+
+func TestToolRegistry_Configure(t *testing.T) {
+	r := NewBuiltinToolRegistry()
+	if err := r.Configure(ToolConfig{Disabled: []string{"not_a_tool"}}); err == nil {
+		t.Error("expected an error for an unknown tool")
+	}
+
+	if err := r.Configure(ToolConfig{Enabled: []string{"read_range", "write_range", "create_chart"}, Disabled: []string{"create_chart"}}); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+	names := r.Names()
+	if len(names) != 2 || names[0] != "read_range" || names[1] != "write_range" {
+		t.Errorf("Names() = %v", names)
+	}
+	if _, ok := r.Lookup("create_chart"); ok {
+		t.Error("disabled tool is still executable")
+	}
+	if tools := r.Group(ToolGroupStarter); len(tools) != 1 || tools[0].Name != "write_range" {
+		t.Errorf("Group(starter) = %+v", tools)
+	}
+	// Disabled tools keep their classification for operations already queued
+	if !r.IsWrite("create_chart") {
+		t.Error("IsWrite() should still know disabled tools")
+	}
+
+	if err := r.Configure(ToolConfig{}); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+	if len(r.Tools()) != len(builtinToolRegistry().Tools()) {
+		t.Error("empty configuration should enable every tool")
+	}
+}
+
+func TestToolRegistry_ConfigureWorkspaces(t *testing.T) {
+	r := NewBuiltinToolRegistry()
+	err := r.Configure(ToolConfig{Workspaces: map[string]ToolConfig{
+		"acme":   {Disabled: []string{"create_chart"}},
+		"globex": {Enabled: []string{"read_range", "write_range"}},
+	}})
+	if err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+	workspaces := map[string]string{"s-acme": "acme", "s-globex": "globex", "s-other": "other"}
+	r.SetWorkspaceResolver(func(sessionID string) (string, error) {
+		if workspace, ok := workspaces[sessionID]; ok {
+			return workspace, nil
+		}
+		return "", fmt.Errorf("session %s is not linked to a user", sessionID)
+	})
+
+	if _, ok := r.LookupForSession("create_chart", "s-acme"); ok {
+		t.Error("create_chart should be disabled in acme")
+	}
+	if _, ok := r.LookupForSession("create_chart", "s-other"); !ok {
+		t.Error("create_chart should be available in workspaces without configuration")
+	}
+	if tools := r.SessionTools("s-globex"); len(tools) != 2 {
+		t.Errorf("SessionTools(globex) = %d tools, want the 2 enabled", len(tools))
+	}
+	if _, ok := r.LookupForSession("format_range", "s-globex"); ok {
+		t.Error("format_range should not be callable outside the globex allowlist")
+	}
+
+	// Sessions whose workspace is unknown get what every workspace allows
+	if tools := r.SessionTools("s-unlinked"); len(tools) != 2 {
+		t.Errorf("SessionTools(unknown workspace) = %d tools, want 2", len(tools))
+	}
+	if len(r.Tools()) != len(builtinToolRegistry().Tools()) {
+		t.Error("workspace configuration should not disable tools deployment-wide")
+	}
+
+	if err := r.Configure(ToolConfig{Workspaces: map[string]ToolConfig{"acme": {Disabled: []string{"not_a_tool"}}}}); err == nil {
+		t.Error("expected an error for an unknown tool in a workspace")
+	}
+}
+
+func TestToolRegistry_BuiltinPreviewsAndInverses(t *testing.T) {
+	r := builtinToolRegistry()
+
+	input := map[string]interface{}{"range": "B2:C3", "values": [][]interface{}{{1, 2}, {3, 4}}}
+	preview := r.StructuredPreview("write_range", input)
+	if preview["text"] != "Write 2x2 values starting with '1' to B2:C3" || preview["preview_type"] != "excel_diff" {
+		t.Errorf("StructuredPreview() = %+v", preview)
+	}
+
+	inverse := r.Inverse("insert_rows_columns", map[string]interface{}{"position": "A5", "count": 2, "type": "rows"}, nil)
+	if inverse == nil || inverse.Type != "delete_rows_columns" || inverse.Input["count"] != 2 || inverse.Input["_is_undo"] != true {
+		t.Errorf("Inverse(insert_rows_columns) = %+v", inverse)
+	}
+	inverse = r.Inverse("apply_formula", map[string]interface{}{"range": "D4", "formula": "=B4*2"}, map[string]interface{}{})
+	if inverse == nil || inverse.Input["formula"] != "" {
+		t.Errorf("Inverse(apply_formula) should clear the formula: %+v", inverse)
+	}
+	if r.Inverse("read_range", map[string]interface{}{"range": "A1"}, nil) != nil {
+		t.Error("read tools have no inverse")
+	}
+}
@@ -0,0 +1,386 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FieldError describes one invalid field of a tool call
+type FieldError struct {
+	Field    string      `json:"field"`
+	Message  string      `json:"message"`
+	Expected string      `json:"expected,omitempty"`
+	Got      interface{} `json:"got,omitempty"`
+}
+
+// inputSchema is a tool's InputSchema normalized to the types
+// encoding/json produces, so Go literals and decoded schemas validate alike
+type inputSchema map[string]interface{}
+
+// compileInputSchema normalizes a tool's input schema
+func compileInputSchema(schema map[string]interface{}) (inputSchema, error) {
+	if schema == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode input schema: %w", err)
+	}
+	var normalized map[string]interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil, fmt.Errorf("failed to decode input schema: %w", err)
+	}
+	return normalized, nil
+}
+
+// ValidateToolInput checks input against a JSON Schema and applies safe
+// coercions, such as numbers sent as strings. It returns the coerced input
+// and every field that is still invalid.
+func ValidateToolInput(schema map[string]interface{}, input map[string]interface{}) (map[string]interface{}, []FieldError) {
+	compiled, err := compileInputSchema(schema)
+	if err != nil {
+		return input, []FieldError{{Field: "", Message: err.Error()}}
+	}
+	return compiled.validate(input)
+}
+
+// validate checks a tool call's input. Fields starting with an underscore
+// are added by the pipeline and are not validated.
+func (s inputSchema) validate(input map[string]interface{}) (map[string]interface{}, []FieldError) {
+	if s == nil {
+		return input, nil
+	}
+	if input == nil {
+		input = map[string]interface{}{}
+	}
+	out, errs := validateObject("", s, input)
+	return out.(map[string]interface{}), errs
+}
+
+func validateValue(path string, schema map[string]interface{}, value interface{}) (interface{}, []FieldError) {
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if branches, ok := schema[key].([]interface{}); ok {
+			return validateAlternatives(path, branches, value)
+		}
+	}
+
+	var errs []FieldError
+	switch schemaType(schema) {
+	case "string":
+		switch v := value.(type) {
+		case string:
+		case float64, int, int64, bool:
+			value = fmt.Sprint(v)
+		default:
+			return value, []FieldError{typeError(path, "string", value)}
+		}
+
+	case "number", "integer":
+		n, ok := toNumber(value)
+		if !ok {
+			return value, []FieldError{typeError(path, schemaType(schema), value)}
+		}
+		if schemaType(schema) == "integer" && n != math.Trunc(n) {
+			return value, []FieldError{typeError(path, "integer", value)}
+		}
+		if minimum, ok := schema["minimum"].(float64); ok && n < minimum {
+			errs = append(errs, FieldError{Field: path, Message: fmt.Sprintf("must be at least %v", minimum), Got: value})
+		}
+		if maximum, ok := schema["maximum"].(float64); ok && n > maximum {
+			errs = append(errs, FieldError{Field: path, Message: fmt.Sprintf("must be at most %v", maximum), Got: value})
+		}
+		value = n
+
+	case "boolean":
+		switch v := value.(type) {
+		case bool:
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				return value, []FieldError{typeError(path, "boolean", value)}
+			}
+			value = b
+		default:
+			return value, []FieldError{typeError(path, "boolean", value)}
+		}
+
+	case "null":
+		if value != nil {
+			return value, []FieldError{typeError(path, "null", value)}
+		}
+
+	case "array":
+		return validateArray(path, schema, decodeJSONString(value, "["))
+
+	case "object":
+		obj, ok := decodeJSONString(value, "{").(map[string]interface{})
+		if !ok {
+			if isMap(value) {
+				return value, nil // Typed maps come from the pipeline, not the model
+			}
+			return value, []FieldError{typeError(path, "object", value)}
+		}
+		return validateObject(path, schema, obj)
+	}
+
+	if allowed, ok := schema["enum"].([]interface{}); ok {
+		matched, ok := matchEnum(allowed, value)
+		if !ok {
+			return value, append(errs, FieldError{
+				Field:    path,
+				Message:  "must be one of the allowed values",
+				Expected: formatEnum(allowed),
+				Got:      value,
+			})
+		}
+		value = matched
+	}
+	return value, errs
+}
+
+func validateObject(path string, schema map[string]interface{}, obj map[string]interface{}) (interface{}, []FieldError) {
+	properties, _ := schema["properties"].(map[string]interface{})
+	required := make(map[string]bool)
+	if names, ok := schema["required"].([]interface{}); ok {
+		for _, name := range names {
+			if s, ok := name.(string); ok {
+				required[s] = true
+			}
+		}
+	}
+
+	out := make(map[string]interface{}, len(obj))
+	var errs []FieldError
+	for _, name := range sortedKeys(obj) {
+		value := obj[name]
+		propSchema, known := properties[name].(map[string]interface{})
+		if !known || strings.HasPrefix(name, "_") {
+			out[name] = value
+			continue
+		}
+		if value == nil && schemaType(propSchema) != "null" {
+			continue // An explicit null means the field was omitted
+		}
+		coerced, fieldErrs := validateValue(joinPath(path, name), propSchema, value)
+		out[name] = coerced
+		errs = append(errs, fieldErrs...)
+	}
+
+	for _, name := range sortedKeys(required) {
+		if out[name] == nil {
+			field := FieldError{Field: joinPath(path, name), Message: "is required"}
+			if propSchema, ok := properties[name].(map[string]interface{}); ok {
+				field.Expected = schemaType(propSchema)
+			}
+			errs = append(errs, field)
+		}
+	}
+	return out, errs
+}
+
+func validateArray(path string, schema map[string]interface{}, value interface{}) (interface{}, []FieldError) {
+	items, _ := schema["items"].(map[string]interface{})
+	if list, ok := value.([]interface{}); ok {
+		out := make([]interface{}, len(list))
+		var errs []FieldError
+		for i, item := range list {
+			out[i] = item
+			if items != nil {
+				coerced, itemErrs := validateValue(fmt.Sprintf("%s[%d]", path, i), items, item)
+				out[i] = coerced
+				errs = append(errs, itemErrs...)
+			}
+		}
+		return out, errs
+	}
+
+	// Typed slices come from the pipeline and are validated but left as is
+	rv := reflect.ValueOf(value)
+	if !rv.IsValid() || rv.Kind() != reflect.Slice {
+		return value, []FieldError{typeError(path, "array", value)}
+	}
+	var errs []FieldError
+	for i := 0; items != nil && i < rv.Len(); i++ {
+		_, itemErrs := validateValue(fmt.Sprintf("%s[%d]", path, i), items, rv.Index(i).Interface())
+		errs = append(errs, itemErrs...)
+	}
+	return value, errs
+}
+
+// validateAlternatives accepts a value matching any of the schemas
+func validateAlternatives(path string, branches []interface{}, value interface{}) (interface{}, []FieldError) {
+	var expected []string
+	for _, branch := range branches {
+		branchSchema, ok := branch.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if schemaType(branchSchema) != "" {
+			expected = append(expected, schemaType(branchSchema))
+		}
+		if !matchesType(branchSchema, value) {
+			continue
+		}
+		if coerced, errs := validateValue(path, branchSchema, value); len(errs) == 0 {
+			return coerced, nil
+		}
+	}
+	return value, []FieldError{typeError(path, strings.Join(expected, " or "), value)}
+}
+
+// matchesType reports whether value already has the schema's type, so
+// alternatives are matched before any coercion is tried
+func matchesType(schema map[string]interface{}, value interface{}) bool {
+	switch schemaType(schema) {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number", "integer":
+		_, isString := value.(string)
+		_, ok := toNumber(value)
+		return ok && !isString
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	case "array":
+		rv := reflect.ValueOf(value)
+		return rv.IsValid() && rv.Kind() == reflect.Slice
+	case "object":
+		return isMap(value)
+	}
+	return true
+}
+
+func schemaType(schema map[string]interface{}) string {
+	t, _ := schema["type"].(string)
+	return t
+}
+
+func typeError(path, expected string, got interface{}) FieldError {
+	return FieldError{
+		Field:    path,
+		Message:  fmt.Sprintf("must be %s %s", article(expected), expected),
+		Expected: expected,
+		Got:      got,
+	}
+}
+
+func article(word string) string {
+	if word != "" && strings.ContainsRune("aeiou", rune(word[0])) {
+		return "an"
+	}
+	return "a"
+}
+
+// toNumber converts numbers of any Go type, and strings holding a number
+func toNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case json.Number:
+		n, err := v.Float64()
+		return n, err == nil
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return n, err == nil && !math.IsNaN(n) && !math.IsInf(n, 0)
+	case bool, nil:
+		return 0, false
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+// decodeJSONString decodes arrays and objects the model sent as JSON text
+func decodeJSONString(value interface{}, prefix string) interface{} {
+	s, ok := value.(string)
+	if !ok || !strings.HasPrefix(strings.TrimSpace(s), prefix) {
+		return value
+	}
+	var decoded interface{}
+	if err := json.Unmarshal([]byte(s), &decoded); err != nil {
+		return value
+	}
+	return decoded
+}
+
+func isMap(value interface{}) bool {
+	rv := reflect.ValueOf(value)
+	return rv.IsValid() && rv.Kind() == reflect.Map
+}
+
+// matchEnum matches enum strings case-insensitively and returns the
+// canonical spelling
+func matchEnum(allowed []interface{}, value interface{}) (interface{}, bool) {
+	for _, a := range allowed {
+		if a == value {
+			return a, true
+		}
+	}
+	if s, ok := value.(string); ok {
+		for _, a := range allowed {
+			if as, ok := a.(string); ok && strings.EqualFold(as, strings.TrimSpace(s)) {
+				return as, true
+			}
+		}
+	}
+	return value, false
+}
+
+func formatEnum(allowed []interface{}) string {
+	values := make([]string, len(allowed))
+	for i, a := range allowed {
+		values[i] = fmt.Sprint(a)
+	}
+	return strings.Join(values, ", ")
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// newInputValidationError reports invalid fields back to the model so it
+// can correct the call
+func newInputValidationError(toolCall ToolCall, fields []FieldError) *ToolError {
+	problems := make([]string, len(fields))
+	for i, f := range fields {
+		problems[i] = strings.TrimSpace(f.Field + " " + f.Message)
+	}
+	return &ToolError{
+		Type:      ToolErrorTypeInvalidInput,
+		Message:   fmt.Sprintf("Invalid input for %s: %s", toolCall.Name, strings.Join(problems, "; ")),
+		ToolName:  toolCall.Name,
+		ToolID:    toolCall.ID,
+		Retryable: true,
+		Details: map[string]interface{}{
+			"fields": fields,
+			"hint":   "Fix the listed fields and call the tool again.",
+		},
+	}
+}
@@ -0,0 +1,72 @@
+package ai
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestToolRegistry_ValidateInput(t *testing.T) {
+	r := builtinToolRegistry()
+
+	tests := []struct {
+		name   string
+		tool   string
+		input  map[string]interface{}
+		want   map[string]interface{}
+		fields []string
+	}{
+		{
+			name:  "coerces strings to numbers and enums to canonical case",
+			tool:  "insert_rows_columns",
+			input: map[string]interface{}{"position": "A5", "count": "3", "type": "Rows"},
+			want:  map[string]interface{}{"position": "A5", "count": 3.0, "type": "rows"},
+		},
+		{
+			name:  "decodes arrays sent as JSON text",
+			tool:  "write_range",
+			input: map[string]interface{}{"range": "A1:B1", "values": `[["Revenue", 100]]`, "preserve_formatting": "false"},
+			want: map[string]interface{}{
+				"range":               "A1:B1",
+				"values":              []interface{}{[]interface{}{"Revenue", 100.0}},
+				"preserve_formatting": false,
+			},
+		},
+		{
+			name:  "keeps pipeline fields and drops null optionals",
+			tool:  "read_range",
+			input: map[string]interface{}{"range": "A1", "include_formulas": nil, "_tool_id": 7},
+			want:  map[string]interface{}{"range": "A1", "_tool_id": 7},
+		},
+		{
+			name:   "reports every invalid field",
+			tool:   "insert_rows_columns",
+			input:  map[string]interface{}{"count": "2.5", "type": "cells"},
+			fields: []string{"count", "type", "position"},
+		},
+		{
+			name:   "reports nested item paths",
+			tool:   "write_range",
+			input:  map[string]interface{}{"range": "A1:B1", "values": []interface{}{[]interface{}{"ok", map[string]interface{}{}}}},
+			fields: []string{"values[0][1]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errs := r.ValidateInput(tt.tool, tt.input)
+			fields := make([]string, len(errs))
+			for i, e := range errs {
+				fields[i] = e.Field
+			}
+			if len(tt.fields) > 0 || len(errs) > 0 {
+				if !reflect.DeepEqual(fields, tt.fields) {
+					t.Fatalf("invalid fields = %v, want %v (%+v)", fields, tt.fields, errs)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidateInput() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+)
+
+// WorkspaceSource finds the workspace a user's sessions act in
+type WorkspaceSource interface {
+	UserWorkspace(ctx context.Context, userID string) (string, error)
+}
+
+// SetWorkspaceSource sets how the executor finds the workspace of a
+// session, which also decides the session's workspace tool configuration
+func (te *ToolExecutor) SetWorkspaceSource(source WorkspaceSource) {
+	te.workspaces = source
+	te.registry.SetWorkspaceResolver(func(sessionID string) (string, error) {
+		return te.SessionWorkspace(context.Background(), sessionID)
+	})
+}
+
+// SessionWorkspace returns the workspace of the session's user. It fails
+// when the session has no user, as sessions relayed by the SignalR hub do,
+// or when the user's workspace cannot be told.
+func (te *ToolExecutor) SessionWorkspace(ctx context.Context, sessionID string) (string, error) {
+	if te.workspaces == nil || te.excelBridge == nil {
+		return "", fmt.Errorf("workspaces are not configured")
+	}
+	session := te.excelBridge.GetSession(sessionID)
+	if session == nil {
+		return "", fmt.Errorf("session %s not found", sessionID)
+	}
+	if session.UserID == "" {
+		return "", fmt.Errorf("session %s is not linked to a user", sessionID)
+	}
+	return te.workspaces.UserWorkspace(ctx, session.UserID)
+}
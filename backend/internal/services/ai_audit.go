@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/gridmate/backend/internal/models"
+	"github.com/gridmate/backend/internal/repository"
+	"github.com/gridmate/backend/internal/services/ai"
+)
+
+// Audit log entity types of AI activity
+const (
+	toolCallEntity  = "ai_tool_call"
+	operationEntity = "ai_operation"
+)
+
+// AIAuditLog records AI activity in the audit log: autonomy policy
+// decisions, tool executions and the lifecycle of queued operations
+type AIAuditLog struct {
+	repo repository.AuditLogRepository
+}
+
+// NewAIAuditLog creates an auditor writing to repo
+func NewAIAuditLog(repo repository.AuditLogRepository) *AIAuditLog {
+	return &AIAuditLog{repo: repo}
+}
+
+// RecordPolicyDecision stores a decision with its reasons
+func (a *AIAuditLog) RecordPolicyDecision(ctx context.Context, decision *ai.PolicyDecision) error {
+	return a.record(ctx, "ai.policy."+string(decision.Outcome), toolCallEntity, decision.User, decision.Workspace, decision)
+}
+
+// RecordToolExecution stores a finished tool call with its input, status
+// and duration
+func (a *AIAuditLog) RecordToolExecution(ctx context.Context, execution *ai.ToolExecution) error {
+	action := "ai.tool.executed"
+	if execution.Failed() {
+		action = "ai.tool.failed"
+	}
+	return a.record(ctx, action, toolCallEntity, execution.Tenant, execution.Workspace, execution)
+}
+
+// RecordOperation stores a state change of a queued operation
+func (a *AIAuditLog) RecordOperation(ctx context.Context, event *OperationEvent) error {
+	return a.record(ctx, event.Action, operationEntity, event.UserID, event.WorkspaceID, event)
+}
+
+// record stores an entry, attributing it to user and workspace when they
+// are IDs. Entries without a workspace go to the global chain.
+func (a *AIAuditLog) record(ctx context.Context, action, entityType, user, workspace string, changes interface{}) error {
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", action, err)
+	}
+
+	entry := &models.AuditLog{
+		Action:     action,
+		EntityType: &entityType,
+		Changes:    data,
+	}
+	if userID, err := uuid.Parse(user); err == nil {
+		entry.UserID = &userID
+	}
+	if workspaceID, err := uuid.Parse(workspace); err == nil {
+		entry.WorkspaceID = &workspaceID
+	}
+	return a.repo.Create(ctx, entry)
+}
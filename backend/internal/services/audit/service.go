@@ -0,0 +1,240 @@
+// Package audit verifies, exports and prunes the hash-chained audit log.
+package audit
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/gridmate/backend/internal/models"
+	"github.com/gridmate/backend/internal/repository"
+)
+
+// Export formats
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// retentionSetting is the workspace setting overriding the default retention
+const retentionSetting = "audit_retention_days"
+
+// ErrUnsupportedFormat is returned for export formats other than CSV and JSONL
+var ErrUnsupportedFormat = errors.New("unsupported export format")
+
+// csvHeader lists the exported columns
+var csvHeader = []string{
+	"id", "workspace_id", "sequence", "created_at", "user_id", "action", "entity_type",
+	"entity_id", "changes", "ip_address", "user_agent", "prev_hash", "hash",
+}
+
+// Pruned reports what retention removed from one chain
+type Pruned struct {
+	WorkspaceID   *uuid.UUID `json:"workspace_id"`
+	RetentionDays int        `json:"retention_days"`
+	Deleted       int64      `json:"deleted"`
+}
+
+// Service verifies, exports and applies retention to the audit log
+type Service struct {
+	logger        *logrus.Logger
+	repo          repository.AuditLogRepository
+	workspaceRepo repository.WorkspaceRepository
+	hashKey       []byte
+	retentionDays int
+}
+
+// NewService creates an audit service. New entries are signed with hashKey;
+// retentionDays is how long entries are kept when their workspace sets no
+// retention of its own, zero keeping them forever.
+func NewService(
+	logger *logrus.Logger,
+	repo repository.AuditLogRepository,
+	workspaceRepo repository.WorkspaceRepository,
+	hashKey []byte,
+	retentionDays int,
+) *Service {
+	repo.SetHashKey(hashKey)
+	return &Service{
+		logger:        logger,
+		repo:          repo,
+		workspaceRepo: workspaceRepo,
+		hashKey:       hashKey,
+		retentionDays: retentionDays,
+	}
+}
+
+// Verify checks a workspace's chain for missing, reordered or edited
+// entries. A nil workspaceID checks the entries outside any workspace.
+func (s *Service) Verify(ctx context.Context, workspaceID *uuid.UUID) (*Verification, error) {
+	verifier := newChainVerifier(workspaceID, s.hashKey)
+	err := s.repo.StreamChain(ctx, workspaceID, func(entry *models.AuditLog) error {
+		verifier.add(entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return verifier.finish(), nil
+}
+
+// VerifyAll checks every chain
+func (s *Service) VerifyAll(ctx context.Context) ([]*Verification, error) {
+	chains, err := s.repo.Chains(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*Verification, 0, len(chains))
+	for _, workspaceID := range chains {
+		result, err := s.Verify(ctx, workspaceID)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// Export writes the entries matching filter to w as CSV or JSON lines,
+// streaming them from the database
+func (s *Service) Export(ctx context.Context, w io.Writer, format string, filter *models.AuditLogFilter) error {
+	switch format {
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(csvHeader); err != nil {
+			return err
+		}
+		err := s.repo.Stream(ctx, filter, func(entry *models.AuditLog) error {
+			return writer.Write(csvRecord(entry))
+		})
+		if err != nil {
+			return err
+		}
+		writer.Flush()
+		return writer.Error()
+	case FormatJSONL:
+		encoder := json.NewEncoder(w)
+		return s.repo.Stream(ctx, filter, func(entry *models.AuditLog) error {
+			return encoder.Encode(entry)
+		})
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedFormat, format)
+	}
+}
+
+// ApplyRetention prunes every chain by its workspace's retention
+func (s *Service) ApplyRetention(ctx context.Context) ([]Pruned, error) {
+	chains, err := s.repo.Chains(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pruned []Pruned
+	for _, workspaceID := range chains {
+		days := s.RetentionDays(ctx, workspaceID)
+		if days <= 0 {
+			continue
+		}
+
+		before := time.Now().AddDate(0, 0, -days)
+		deleted, err := s.repo.Prune(ctx, workspaceID, before)
+		if err != nil {
+			return pruned, err
+		}
+		if deleted > 0 {
+			pruned = append(pruned, Pruned{WorkspaceID: workspaceID, RetentionDays: days, Deleted: deleted})
+		}
+	}
+	return pruned, nil
+}
+
+// RetentionDays returns how many days a workspace keeps its audit entries,
+// zero meaning forever
+func (s *Service) RetentionDays(ctx context.Context, workspaceID *uuid.UUID) int {
+	if workspaceID == nil || s.workspaceRepo == nil {
+		return s.retentionDays
+	}
+
+	workspace, err := s.workspaceRepo.GetByID(ctx, *workspaceID)
+	if err != nil || len(workspace.Settings) == 0 {
+		return s.retentionDays
+	}
+	var settings map[string]interface{}
+	if err := json.Unmarshal(workspace.Settings, &settings); err != nil {
+		return s.retentionDays
+	}
+	if days, ok := settings[retentionSetting].(float64); ok && days >= 0 {
+		return int(days)
+	}
+	return s.retentionDays
+}
+
+// RunRetention applies retention every interval until ctx is done
+func (s *Service) RunRetention(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			pruned, err := s.ApplyRetention(ctx)
+			if err != nil {
+				s.logger.WithError(err).Error("Failed to apply audit log retention")
+				continue
+			}
+			for _, p := range pruned {
+				s.logger.WithFields(logrus.Fields{
+					"workspace_id":   p.WorkspaceID,
+					"retention_days": p.RetentionDays,
+					"deleted":        p.Deleted,
+				}).Info("Pruned audit log entries")
+			}
+		}
+	}
+}
+
+func csvRecord(entry *models.AuditLog) []string {
+	optional := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	}
+	optionalID := func(id *uuid.UUID) string {
+		if id == nil {
+			return ""
+		}
+		return id.String()
+	}
+
+	var ip string
+	if entry.IPAddress != nil {
+		ip = entry.IPAddress.String()
+	}
+	return []string{
+		entry.ID.String(),
+		optionalID(entry.WorkspaceID),
+		strconv.FormatInt(entry.Sequence, 10),
+		entry.CreatedAt.UTC().Format(time.RFC3339Nano),
+		optionalID(entry.UserID),
+		entry.Action,
+		optional(entry.EntityType),
+		optionalID(entry.EntityID),
+		string(entry.Changes),
+		ip,
+		optional(entry.UserAgent),
+		entry.PrevHash,
+		entry.Hash,
+	}
+}
@@ -0,0 +1,124 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/gridmate/backend/internal/models"
+	"github.com/gridmate/backend/internal/repository"
+)
+
+// maxIssues caps the issues reported for one chain
+const maxIssues = 100
+
+// Issue is a problem found in a chain
+type Issue struct {
+	Sequence int64  `json:"sequence"`
+	EntryID  string `json:"entry_id,omitempty"`
+	Problem  string `json:"problem"`
+}
+
+// Verification is the result of checking one workspace's chain. LastHash
+// can be recorded elsewhere to later detect removal of the newest entries.
+type Verification struct {
+	WorkspaceID   *uuid.UUID `json:"workspace_id"`
+	Valid         bool       `json:"valid"`
+	Entries       int64      `json:"entries"`
+	FirstSequence int64      `json:"first_sequence"`
+	LastSequence  int64      `json:"last_sequence"`
+	LastHash      string     `json:"last_hash"`
+	PrunedThrough int64      `json:"pruned_through,omitempty"`
+	Issues        []Issue    `json:"issues"`
+	VerifiedAt    time.Time  `json:"verified_at"`
+}
+
+// chainVerifier checks entries of one chain fed in sequence order
+type chainVerifier struct {
+	key    []byte
+	result *Verification
+	first  *models.AuditLog
+	prev   *models.AuditLog
+	// Retention checkpoints: last pruned sequence to its hash
+	checkpoints map[int64]string
+}
+
+func newChainVerifier(workspaceID *uuid.UUID, key []byte) *chainVerifier {
+	return &chainVerifier{
+		key:         key,
+		result:      &Verification{WorkspaceID: workspaceID, Issues: []Issue{}},
+		checkpoints: make(map[int64]string),
+	}
+}
+
+func (v *chainVerifier) add(entry *models.AuditLog) {
+	v.result.Entries++
+
+	hash, err := entry.ComputeHash(v.key)
+	intact := err == nil && hash == entry.Hash
+	if !intact {
+		v.issue(entry, "entry content does not match its hash")
+	}
+
+	if v.prev == nil {
+		v.first = entry
+	} else {
+		switch {
+		case entry.Sequence <= v.prev.Sequence:
+			v.issue(entry, fmt.Sprintf("sequence %d follows %d", entry.Sequence, v.prev.Sequence))
+		case entry.Sequence > v.prev.Sequence+1:
+			v.issue(entry, fmt.Sprintf("entries %d to %d are missing", v.prev.Sequence+1, entry.Sequence-1))
+		}
+		if entry.PrevHash != v.prev.Hash {
+			v.issue(entry, fmt.Sprintf("previous hash does not match entry %d", v.prev.Sequence))
+		}
+	}
+
+	if intact && entry.Action == repository.RetentionCheckpointAction {
+		var checkpoint struct {
+			Sequence int64  `json:"pruned_through_sequence"`
+			Hash     string `json:"pruned_through_hash"`
+		}
+		if json.Unmarshal(entry.Changes, &checkpoint) == nil && checkpoint.Sequence > 0 {
+			v.checkpoints[checkpoint.Sequence] = checkpoint.Hash
+		}
+	}
+
+	v.prev = entry
+}
+
+// finish checks the start of the chain against the retention checkpoints
+// and returns the result
+func (v *chainVerifier) finish() *Verification {
+	if v.first != nil {
+		v.result.FirstSequence = v.first.Sequence
+		v.result.LastSequence = v.prev.Sequence
+		v.result.LastHash = v.prev.Hash
+
+		if v.first.Sequence != 1 || v.first.PrevHash != "" {
+			through := v.first.Sequence - 1
+			if hash, ok := v.checkpoints[through]; ok && hash == v.first.PrevHash {
+				v.result.PrunedThrough = through
+			} else {
+				v.issue(v.first, fmt.Sprintf("entries 1 to %d are missing without a retention checkpoint", through))
+			}
+		}
+	}
+
+	v.result.Valid = len(v.result.Issues) == 0
+	v.result.VerifiedAt = time.Now()
+	return v.result
+}
+
+func (v *chainVerifier) issue(entry *models.AuditLog, problem string) {
+	if len(v.result.Issues) >= maxIssues {
+		return
+	}
+	v.result.Issues = append(v.result.Issues, Issue{
+		Sequence: entry.Sequence,
+		EntryID:  entry.ID.String(),
+		Problem:  problem,
+	})
+}
@@ -0,0 +1,119 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/gridmate/backend/internal/models"
+	"github.com/gridmate/backend/internal/repository"
+)
+
+var testKey = []byte("test-key")
+
+// testChain appends entries the way the repository does
+func testChain(t *testing.T, workspaceID *uuid.UUID, actions ...string) []*models.AuditLog {
+	t.Helper()
+	var chain []*models.AuditLog
+	for i, action := range actions {
+		entry := &models.AuditLog{
+			ID:          uuid.New(),
+			WorkspaceID: workspaceID,
+			Sequence:    int64(i + 1),
+			Action:      action,
+			Changes:     json.RawMessage(fmt.Sprintf(`{"step":%d,"amount":2.5}`, i)),
+			CreatedAt:   time.Now().UTC().Truncate(time.Microsecond),
+		}
+		if i > 0 {
+			entry.PrevHash = chain[i-1].Hash
+		}
+		hash, err := entry.ComputeHash(testKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry.Hash = hash
+		chain = append(chain, entry)
+	}
+	return chain
+}
+
+func verify(workspaceID *uuid.UUID, entries []*models.AuditLog) *Verification {
+	v := newChainVerifier(workspaceID, testKey)
+	for _, entry := range entries {
+		v.add(entry)
+	}
+	return v.finish()
+}
+
+func TestVerifyChain(t *testing.T) {
+	workspaceID := uuid.New()
+
+	t.Run("intact", func(t *testing.T) {
+		chain := testChain(t, &workspaceID, "a", "b", "c")
+		// JSONB reorders keys and adds spaces when the entry reads back
+		chain[1].Changes = json.RawMessage(`{"step": 1, "amount": 2.50}`)
+		result := verify(&workspaceID, chain)
+		if !result.Valid || result.Entries != 3 || result.LastHash != chain[2].Hash {
+			t.Errorf("verification = %+v, want a valid chain of 3", result)
+		}
+	})
+
+	t.Run("edited entry", func(t *testing.T) {
+		chain := testChain(t, &workspaceID, "a", "b", "c")
+		chain[1].Action = "b-edited"
+		assertIssue(t, verify(&workspaceID, chain), 2, "does not match its hash")
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		chain := testChain(t, &workspaceID, "a")
+		v := newChainVerifier(&workspaceID, []byte("other-key"))
+		v.add(chain[0])
+		assertIssue(t, v.finish(), 1, "does not match its hash")
+	})
+
+	t.Run("deleted entry", func(t *testing.T) {
+		chain := testChain(t, &workspaceID, "a", "b", "c", "d")
+		assertIssue(t, verify(&workspaceID, append(chain[:1:1], chain[2:]...)), 3, "entries 2 to 2 are missing")
+	})
+
+	t.Run("moved to another workspace", func(t *testing.T) {
+		chain := testChain(t, &workspaceID, "a", "b")
+		other := uuid.New()
+		chain[1].WorkspaceID = &other
+		assertIssue(t, verify(&workspaceID, chain), 2, "does not match its hash")
+	})
+
+	t.Run("pruned with checkpoint", func(t *testing.T) {
+		chain := testChain(t, &workspaceID, "a", "b", "c", repository.RetentionCheckpointAction)
+		checkpoint := chain[3]
+		checkpoint.Changes = json.RawMessage(fmt.Sprintf(`{"pruned_through_sequence":2,"pruned_through_hash":%q}`, chain[1].Hash))
+		checkpoint.Hash, _ = checkpoint.ComputeHash(testKey)
+
+		result := verify(&workspaceID, chain[2:])
+		if !result.Valid || result.PrunedThrough != 2 || result.FirstSequence != 3 {
+			t.Errorf("verification = %+v, want a valid chain pruned through 2", result)
+		}
+	})
+
+	t.Run("pruned without checkpoint", func(t *testing.T) {
+		chain := testChain(t, &workspaceID, "a", "b", "c")
+		assertIssue(t, verify(&workspaceID, chain[2:]), 3, "without a retention checkpoint")
+	})
+}
+
+func assertIssue(t *testing.T, result *Verification, sequence int64, problem string) {
+	t.Helper()
+	if result.Valid {
+		t.Fatalf("verification passed, want an issue at %d", sequence)
+	}
+	for _, issue := range result.Issues {
+		if issue.Sequence == sequence && strings.Contains(issue.Problem, problem) {
+			return
+		}
+	}
+	t.Errorf("issues = %+v, want %q at %d", result.Issues, problem, sequence)
+}
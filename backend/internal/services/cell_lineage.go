@@ -0,0 +1,186 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/gridmate/backend/internal/models"
+	"github.com/gridmate/backend/internal/repository"
+	"github.com/gridmate/backend/internal/services/ai"
+)
+
+// CellLineageStore keeps the lineage of AI writes to a user's workbooks and
+// follows their queued operations until they are applied
+type CellLineageStore struct {
+	repo repository.CellLineageRepository
+}
+
+// NewCellLineageStore creates a lineage store writing to repo
+func NewCellLineageStore(repo repository.CellLineageRepository) *CellLineageStore {
+	return &CellLineageStore{repo: repo}
+}
+
+// RecordLineage stores the origin of writes. Sessions are scoped to their
+// user, so writes of tenants that are not user IDs are not recorded.
+func (s *CellLineageStore) RecordLineage(ctx context.Context, tenant string, records []*ai.LineageRecord) error {
+	userID, err := uuid.Parse(tenant)
+	if err != nil {
+		return nil
+	}
+
+	for _, record := range records {
+		entry := &models.CellLineage{
+			UserID:      userID,
+			Workbook:    record.Workbook,
+			Sheet:       record.Sheet,
+			StartRow:    record.StartRow,
+			StartCol:    record.StartCol,
+			EndRow:      record.EndRow,
+			EndCol:      record.EndCol,
+			Address:     record.Range,
+			SessionID:   record.SessionID,
+			MessageID:   optionalString(record.MessageID),
+			ToolID:      record.ToolID,
+			OperationID: record.OperationID,
+			ToolName:    record.Tool,
+			Model:       optionalString(record.Model),
+			Status:      record.Status,
+		}
+		if entry.Input, err = marshalOptional(record.Input); err != nil {
+			return err
+		}
+		if entry.Sources, err = marshalOptional(record.Sources); err != nil {
+			return err
+		}
+		if entry.Approval, err = marshalOptional(record.Approval); err != nil {
+			return err
+		}
+		if record.Status == ai.LineageApplied {
+			now := time.Now()
+			entry.AppliedAt = &now
+		}
+
+		if err := s.repo.Create(ctx, entry); err != nil {
+			return err
+		}
+		record.ID = entry.ID.String()
+		record.CreatedAt = entry.CreatedAt
+	}
+	return nil
+}
+
+// CellHistory returns the writes covering a cell, newest first
+func (s *CellLineageStore) CellHistory(ctx context.Context, tenant, workbook, sheet string, row, col, limit int) ([]*ai.LineageRecord, error) {
+	userID, err := uuid.Parse(tenant)
+	if err != nil {
+		return nil, nil
+	}
+
+	stored, err := s.repo.ListForCell(ctx, userID, workbook, sheet, row, col, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*ai.LineageRecord, 0, len(stored))
+	for _, l := range stored {
+		record := &ai.LineageRecord{
+			ID:          l.ID.String(),
+			Workbook:    l.Workbook,
+			Range:       l.Address,
+			Status:      l.Status,
+			SessionID:   l.SessionID,
+			ToolID:      l.ToolID,
+			OperationID: l.OperationID,
+			Tool:        l.ToolName,
+			CreatedAt:   l.CreatedAt,
+			AppliedAt:   l.AppliedAt,
+			Sheet:       l.Sheet,
+			StartRow:    l.StartRow,
+			StartCol:    l.StartCol,
+			EndRow:      l.EndRow,
+			EndCol:      l.EndCol,
+		}
+		if l.MessageID != nil {
+			record.MessageID = *l.MessageID
+		}
+		if l.Model != nil {
+			record.Model = *l.Model
+		}
+		for _, field := range []struct {
+			data json.RawMessage
+			dest interface{}
+		}{
+			{l.Input, &record.Input},
+			{l.Sources, &record.Sources},
+			{l.Approval, &record.Approval},
+		} {
+			if len(field.data) == 0 {
+				continue
+			}
+			if err := json.Unmarshal(field.data, field.dest); err != nil {
+				return nil, fmt.Errorf("failed to decode cell lineage %s: %w", l.ID, err)
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// RecordOperation follows a queued write: the lineage of its cells becomes
+// applied, failed or rejected, along with who approved or rejected it
+func (s *CellLineageStore) RecordOperation(ctx context.Context, event *OperationEvent) error {
+	var status string
+	switch event.Action {
+	case OperationApplied:
+		status = models.LineageApplied
+	case OperationFailed:
+		status = models.LineageFailed
+	case OperationRejected:
+		status = models.LineageRejected
+	case OperationApproved:
+		// Approval alone does not change the cells
+	default:
+		return nil
+	}
+
+	approval := map[string]interface{}{}
+	if event.UserID != "" {
+		key := "approved_by"
+		if event.Action == OperationRejected {
+			key = "rejected_by"
+		}
+		approval[key] = event.UserID
+	}
+	if len(event.Reviews) > 0 {
+		approval["reviews"] = event.Reviews
+	}
+	data, err := json.Marshal(approval)
+	if err != nil {
+		return fmt.Errorf("failed to encode approval: %w", err)
+	}
+	return s.repo.UpdateStatus(ctx, event.OperationID, status, data)
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// marshalOptional encodes v, leaving empty values out
+func marshalOptional(v interface{}) (json.RawMessage, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode cell lineage: %w", err)
+	}
+	switch string(data) {
+	case "null", "{}", "[]":
+		return nil, nil
+	}
+	return data, nil
+}
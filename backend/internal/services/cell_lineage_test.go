@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/gridmate/backend/internal/models"
+)
+
+type lineageUpdate struct {
+	operationID string
+	status      string
+	approval    map[string]interface{}
+}
+
+type fakeLineageRepo struct {
+	updates chan lineageUpdate
+}
+
+func (r *fakeLineageRepo) Create(ctx context.Context, l *models.CellLineage) error {
+	return nil
+}
+
+func (r *fakeLineageRepo) UpdateStatus(ctx context.Context, operationID, status string, approval json.RawMessage) error {
+	update := lineageUpdate{operationID: operationID, status: status}
+	if err := json.Unmarshal(approval, &update.approval); err != nil {
+		return err
+	}
+	r.updates <- update
+	return nil
+}
+
+func (r *fakeLineageRepo) ListForCell(ctx context.Context, userID uuid.UUID, workbook, sheet string, row, col, limit int) ([]*models.CellLineage, error) {
+	return nil, nil
+}
+
+func TestCellLineageRecordsApprover(t *testing.T) {
+	tests := []struct {
+		name    string
+		approve func(t *testing.T, registry *QueuedOperationRegistry, operationID string)
+		want    string
+	}{
+		{
+			name: "approved in the add-in",
+			approve: func(t *testing.T, registry *QueuedOperationRegistry, operationID string) {
+				bridge := &ExcelBridge{logger: logrus.New(), queuedOpsRegistry: registry}
+				response, err := bridge.ApplyChanges(context.Background(), "approver", "preview", []string{operationID})
+				if err != nil || response.AppliedCount != 1 {
+					t.Fatalf("ApplyChanges = %+v, %v", response, err)
+				}
+			},
+			want: "approver",
+		},
+		{
+			name: "approved in review",
+			approve: func(t *testing.T, registry *QueuedOperationRegistry, operationID string) {
+				if err := registry.SubmitForReview("review-1", []string{operationID}); err != nil {
+					t.Fatal(err)
+				}
+				decision := ReviewDecision{ReviewerID: "reviewer", Decision: DecisionApprove, DecidedAt: time.Now()}
+				if state, err := registry.RecordReviewDecision(operationID, decision, 1); err != nil || state != ApprovalApproved {
+					t.Fatalf("RecordReviewDecision = %s, %v", state, err)
+				}
+			},
+			want: "reviewer",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeLineageRepo{updates: make(chan lineageUpdate, 4)}
+			registry := NewQueuedOperationRegistry()
+			registry.SetAuditor(NewCellLineageStore(repo))
+
+			op := &QueuedOperation{ID: "op-1", SessionID: "session-1", Type: "write_range"}
+			if err := registry.QueueOperation(op); err != nil {
+				t.Fatal(err)
+			}
+			tt.approve(t, registry, op.ID)
+			if err := registry.MarkOperationComplete(op.ID, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			// The approval is recorded first without changing the cells,
+			// then the applied operation credits the approver
+			for {
+				select {
+				case update := <-repo.updates:
+					if update.operationID != op.ID {
+						t.Fatalf("lineage update = %+v", update)
+					}
+					if got := update.approval["approved_by"]; got != tt.want {
+						t.Errorf("%q update approved_by = %v, want %s", update.status, got, tt.want)
+					}
+					if update.status == models.LineageApplied {
+						return
+					}
+				case <-time.After(5 * time.Second):
+					t.Fatal("applied operation was not recorded in the lineage")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package document
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gridmate/backend/internal/memory"
+)
+
+// block is one heading, paragraph or table of a structured document, in
+// reading order. HTML, DOCX and Markdown parsers reduce their input to
+// blocks and share the sectioning and chunking below.
+type block struct {
+	Heading bool
+	Text    string
+	Rows    [][]string // Set for tables
+}
+
+// structuredChunker turns blocks into document chunks. Headings open
+// sections, text under a heading is split with overlap, and tables become
+// their own chunks with the header row repeated in every part.
+type structuredChunker struct {
+	format       string
+	chunkSize    int
+	chunkOverlap int
+}
+
+func newStructuredChunker(format string) structuredChunker {
+	return structuredChunker{
+		format:       format,
+		chunkSize:    300, // ~300 tokens per chunk
+		chunkOverlap: 50,  // 50 token overlap
+	}
+}
+
+// sections groups blocks under their headings. Each table becomes a
+// section of its own, titled after the heading it sits under.
+func (c structuredChunker) sections(blocks []block) []Section {
+	var sections []Section
+	title := ""
+	var text []string
+	tables := 0
+
+	flush := func() {
+		body := strings.TrimSpace(strings.Join(text, "\n"))
+		text = nil
+		if body == "" {
+			return
+		}
+		sectionTitle := title
+		if sectionTitle == "" {
+			sectionTitle = "Document Content"
+		}
+		sections = append(sections, Section{
+			ID:    fmt.Sprintf("section_%d", len(sections)+1),
+			Title: sectionTitle,
+			Type:  "text",
+			Text:  body,
+		})
+	}
+
+	for _, b := range blocks {
+		switch {
+		case b.Heading:
+			flush()
+			title = b.Text
+		case b.Rows != nil:
+			if len(b.Rows) == 0 {
+				continue
+			}
+			flush()
+			tables++
+			tableTitle := fmt.Sprintf("Table %d", tables)
+			if title != "" {
+				tableTitle = fmt.Sprintf("%s - Table %d", title, tables)
+			}
+			sections = append(sections, Section{
+				ID:    fmt.Sprintf("section_%d", len(sections)+1),
+				Title: tableTitle,
+				Type:  "table",
+				Text:  tableText(b.Rows),
+			})
+		default:
+			if t := strings.TrimSpace(b.Text); t != "" {
+				text = append(text, t)
+			}
+		}
+	}
+	flush()
+
+	return sections
+}
+
+// chunk converts blocks into chunks for filename
+func (c structuredChunker) chunk(filename string, blocks []block) []memory.Chunk {
+	chunks := []memory.Chunk{}
+	for _, section := range c.sections(blocks) {
+		var parts []string
+		if section.Type == "table" {
+			parts = splitTableText(section.Text, c.chunkSize*4)
+		} else if len(section.Text) <= c.chunkSize*4 { // Rough token estimate
+			parts = []string{section.Text}
+		} else {
+			parts = splitWithOverlap(section.Text, c.chunkSize, c.chunkOverlap)
+		}
+
+		for i, part := range parts {
+			id := fmt.Sprintf("%s_%s", filename, section.ID)
+			content := fmt.Sprintf("%s\n\n%s", section.Title, part)
+			meta := map[string]interface{}{
+				"format":       c.format,
+				"section_type": section.Type,
+			}
+			if len(parts) > 1 {
+				id = fmt.Sprintf("%s_part_%d", id, i+1)
+				content = fmt.Sprintf("[%s - Part %d/%d]\n%s", section.Title, i+1, len(parts), part)
+				meta["part"] = i + 1
+				meta["total_parts"] = len(parts)
+			}
+			chunks = append(chunks, memory.Chunk{
+				ID:      id,
+				Content: content,
+				Metadata: memory.ChunkMetadata{
+					Source:       "document",
+					SourceID:     filename,
+					DocumentName: filename,
+					Section:      section.Title,
+					SourceMeta:   meta,
+				},
+			})
+		}
+	}
+	return chunks
+}
+
+// tableText renders rows as "cell | cell" lines
+func tableText(rows [][]string) string {
+	lines := make([]string, 0, len(rows))
+	for _, row := range rows {
+		lines = append(lines, strings.Join(row, " | "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// splitTableText splits a rendered table into parts of about maxChars,
+// never inside a row, repeating the header row at the top of each part
+func splitTableText(text string, maxChars int) []string {
+	if len(text) <= maxChars {
+		return []string{text}
+	}
+
+	lines := strings.Split(text, "\n")
+	header := lines[0]
+	var parts []string
+	var current []string
+	size := len(header)
+	for _, line := range lines[1:] {
+		if len(current) > 0 && size+len(line)+1 > maxChars {
+			parts = append(parts, header+"\n"+strings.Join(current, "\n"))
+			current = nil
+			size = len(header)
+		}
+		current = append(current, line)
+		size += len(line) + 1
+	}
+	if len(current) > 0 {
+		parts = append(parts, header+"\n"+strings.Join(current, "\n"))
+	}
+	return parts
+}
+
+// splitWithOverlap splits text into overlapping chunks of about chunkSize
+// tokens
+func splitWithOverlap(text string, chunkSize, overlap int) []string {
+	words := strings.Fields(text)
+	chunks := []string{}
+
+	// Estimate words per chunk (rough approximation: 1.5 words per token)
+	wordsPerChunk := chunkSize * 3 / 2
+	overlapWords := overlap * 3 / 2
+
+	for i := 0; i < len(words); i += (wordsPerChunk - overlapWords) {
+		end := min(i+wordsPerChunk, len(words))
+		chunks = append(chunks, strings.Join(words[i:end], " "))
+		if end >= len(words) {
+			break
+		}
+	}
+
+	return chunks
+}
+
+// collapseSpace joins runs of whitespace into single spaces
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
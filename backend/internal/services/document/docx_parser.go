@@ -0,0 +1,184 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gridmate/backend/internal/memory"
+)
+
+// DOCXParser handles Word documents. Paragraphs styled as headings or
+// titles start sections, and tables keep their rows and columns.
+type DOCXParser struct {
+	chunker structuredChunker
+}
+
+// NewDOCXParser creates a new DOCX parser
+func NewDOCXParser() *DOCXParser {
+	return &DOCXParser{chunker: newStructuredChunker("docx")}
+}
+
+// SupportedTypes returns the file types this parser supports
+func (d *DOCXParser) SupportedTypes() []string {
+	return []string{".docx"}
+}
+
+// Parse extracts and chunks DOCX content
+func (d *DOCXParser) Parse(ctx context.Context, reader io.Reader, filename string) ([]memory.Chunk, error) {
+	archive, err := openZip(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DOCX: %w", err)
+	}
+	body, err := readZipFile(archive, "word/document.xml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read DOCX body: %w", err)
+	}
+
+	blocks, err := docxBlocks(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse DOCX: %w", err)
+	}
+	return d.chunker.chunk(filename, blocks), nil
+}
+
+// docxBlocks walks word/document.xml. Text inside table cells joins the
+// cell; nested tables flatten into their outer cell.
+func docxBlocks(body []byte) ([]block, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+
+	var blocks []block
+	var para strings.Builder
+	heading := false
+	inText := false
+
+	var rows [][]string
+	var row []string
+	var cell []string
+	tableDepth := 0
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				para.Reset()
+				heading = false
+			case "pStyle":
+				heading = heading || isHeadingStyle(xmlAttr(t, "val"))
+			case "outlineLvl":
+				heading = true
+			case "t":
+				inText = true
+			case "tab":
+				para.WriteString("\t")
+			case "br", "cr":
+				para.WriteString(" ")
+			case "tbl":
+				tableDepth++
+				if tableDepth == 1 {
+					rows = nil
+				}
+			case "tr":
+				if tableDepth == 1 {
+					row = nil
+				}
+			case "tc":
+				if tableDepth == 1 {
+					cell = nil
+				}
+			}
+
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				text := collapseSpace(para.String())
+				if text == "" {
+					continue
+				}
+				if tableDepth > 0 {
+					cell = append(cell, text)
+				} else {
+					blocks = append(blocks, block{Heading: heading, Text: text})
+				}
+			case "tc":
+				if tableDepth == 1 {
+					row = append(row, strings.Join(cell, " "))
+				}
+			case "tr":
+				if tableDepth == 1 {
+					if cleaned := cleanTableRow(row); len(cleaned) > 0 {
+						rows = append(rows, cleaned)
+					}
+				}
+			case "tbl":
+				tableDepth--
+				// A nested table's paragraphs already joined the outer cell
+				if tableDepth == 0 {
+					blocks = append(blocks, block{Rows: rows})
+				}
+			}
+
+		case xml.CharData:
+			if inText {
+				para.Write(t)
+			}
+		}
+	}
+
+	return blocks, nil
+}
+
+// isHeadingStyle matches Word's built-in heading and title style IDs
+func isHeadingStyle(style string) bool {
+	style = strings.ToLower(strings.ReplaceAll(style, " ", ""))
+	return strings.HasPrefix(style, "heading") || style == "title" || style == "subtitle"
+}
+
+func xmlAttr(t xml.StartElement, name string) string {
+	for _, attr := range t.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// openZip reads an OOXML package into memory
+func openZip(reader io.Reader) (*zip.Reader, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	return zip.NewReader(bytes.NewReader(data), int64(len(data)))
+}
+
+// readZipFile returns the contents of one package part
+func readZipFile(archive *zip.Reader, name string) ([]byte, error) {
+	for _, f := range archive.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+	return nil, fmt.Errorf("%s not found", name)
+}
@@ -0,0 +1,299 @@
+package document
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/gridmate/backend/internal/memory"
+)
+
+var (
+	// EDGAR filings mark sections with bold paragraphs rather than headings
+	edgarItemPattern = regexp.MustCompile(`(?i)^(item\s+\d+[a-z]?\b|part\s+[ivx]+\b)`)
+
+	htmlSkipElements = map[string]bool{
+		"script": true, "style": true, "head": true, "noscript": true, "template": true,
+	}
+	htmlBlockElements = map[string]bool{
+		"p": true, "div": true, "li": true, "ul": true, "ol": true, "section": true,
+		"article": true, "blockquote": true, "pre": true, "hr": true, "dt": true,
+		"dd": true, "center": true,
+	}
+)
+
+// HTMLParser handles HTML documents, including EDGAR filings and inline
+// XBRL. Tables keep their row and column layout as "cell | cell" lines.
+type HTMLParser struct {
+	chunker structuredChunker
+}
+
+// NewHTMLParser creates a new HTML parser
+func NewHTMLParser() *HTMLParser {
+	return &HTMLParser{chunker: newStructuredChunker("html")}
+}
+
+// SupportedTypes returns the file types this parser supports
+func (h *HTMLParser) SupportedTypes() []string {
+	return []string{".html", ".htm", ".xhtml"}
+}
+
+// Parse extracts and chunks HTML content
+func (h *HTMLParser) Parse(ctx context.Context, reader io.Reader, filename string) ([]memory.Chunk, error) {
+	blocks, err := htmlBlocks(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+	return h.chunker.chunk(filename, blocks), nil
+}
+
+// htmlTable collects the rows of a table being read
+type htmlTable struct {
+	rows   [][]string
+	row    []string
+	cell   strings.Builder
+	inCell bool
+}
+
+// htmlWalker reduces an HTML token stream to blocks
+type htmlWalker struct {
+	blocks  []block
+	text    strings.Builder
+	heading bool
+	skip    int
+	tables  []*htmlTable
+}
+
+// htmlBlocks reads an HTML document into blocks. Malformed markup ends the
+// walk early rather than failing once some content was read.
+func htmlBlocks(reader io.Reader) ([]block, error) {
+	decoder := xml.NewDecoder(reader)
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	w := &htmlWalker{}
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if len(w.blocks) == 0 && w.text.Len() == 0 {
+				return nil, err
+			}
+			break
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			w.start(t)
+		case xml.EndElement:
+			w.end(strings.ToLower(t.Name.Local))
+		case xml.CharData:
+			w.write(string(t))
+		}
+	}
+	for len(w.tables) > 0 {
+		w.closeTable()
+	}
+	w.flush()
+
+	return w.blocks, nil
+}
+
+func (w *htmlWalker) start(t xml.StartElement) {
+	name := strings.ToLower(t.Name.Local)
+	if w.skip > 0 || htmlSkipElements[name] || isHiddenElement(t) {
+		w.skip++
+		return
+	}
+
+	switch {
+	case name == "table":
+		if table := w.table(); table != nil && table.inCell {
+			// Nested tables read as text inside the outer cell
+			table.cell.WriteString(" ")
+		} else {
+			w.flush()
+		}
+		w.tables = append(w.tables, &htmlTable{})
+	case name == "tr":
+		if table := w.table(); table != nil {
+			w.closeRow(table)
+		}
+	case name == "td" || name == "th":
+		if table := w.table(); table != nil {
+			w.closeCell(table)
+			table.inCell = true
+		}
+	case len(name) == 2 && name[0] == 'h' && name[1] >= '1' && name[1] <= '6':
+		if w.table() == nil {
+			w.flush()
+			w.heading = true
+		}
+	case name == "br":
+		w.write("\n")
+	case htmlBlockElements[name]:
+		if w.table() == nil {
+			w.flush()
+		} else {
+			w.write(" ")
+		}
+	}
+}
+
+func (w *htmlWalker) end(name string) {
+	if w.skip > 0 {
+		w.skip--
+		return
+	}
+
+	switch {
+	case name == "table":
+		w.closeTable()
+	case name == "tr":
+		if table := w.table(); table != nil {
+			w.closeRow(table)
+		}
+	case name == "td" || name == "th":
+		if table := w.table(); table != nil {
+			w.closeCell(table)
+		}
+	case len(name) == 2 && name[0] == 'h' && name[1] >= '1' && name[1] <= '6':
+		if w.table() == nil {
+			w.flush()
+		}
+	case htmlBlockElements[name]:
+		if w.table() == nil {
+			w.flush()
+		}
+	}
+}
+
+func (w *htmlWalker) write(s string) {
+	if w.skip > 0 {
+		return
+	}
+	if table := w.table(); table != nil {
+		if table.inCell {
+			table.cell.WriteString(s)
+		}
+		return
+	}
+	w.text.WriteString(s)
+}
+
+// flush ends the current paragraph or heading
+func (w *htmlWalker) flush() {
+	text := collapseSpace(w.text.String())
+	w.text.Reset()
+	heading := w.heading
+	w.heading = false
+	if text == "" {
+		return
+	}
+	if !heading && len(text) < 150 && edgarItemPattern.MatchString(text) {
+		heading = true
+	}
+	w.blocks = append(w.blocks, block{Heading: heading, Text: text})
+}
+
+func (w *htmlWalker) table() *htmlTable {
+	if len(w.tables) == 0 {
+		return nil
+	}
+	return w.tables[len(w.tables)-1]
+}
+
+func (w *htmlWalker) closeCell(table *htmlTable) {
+	if !table.inCell {
+		return
+	}
+	table.row = append(table.row, collapseSpace(table.cell.String()))
+	table.cell.Reset()
+	table.inCell = false
+}
+
+func (w *htmlWalker) closeRow(table *htmlTable) {
+	w.closeCell(table)
+	if row := cleanTableRow(table.row); len(row) > 0 {
+		table.rows = append(table.rows, row)
+	}
+	table.row = nil
+}
+
+// closeTable emits the innermost table. Nested tables fold into the outer
+// cell, and single-column layout tables read as paragraphs.
+func (w *htmlWalker) closeTable() {
+	table := w.table()
+	if table == nil {
+		return
+	}
+	w.closeRow(table)
+	w.tables = w.tables[:len(w.tables)-1]
+
+	if outer := w.table(); outer != nil {
+		if outer.inCell {
+			outer.cell.WriteString(" " + tableText(table.rows) + " ")
+		}
+		return
+	}
+
+	columns := 0
+	for _, row := range table.rows {
+		columns = max(columns, len(row))
+	}
+	if columns > 1 {
+		w.blocks = append(w.blocks, block{Rows: table.rows})
+		return
+	}
+	for _, row := range table.rows {
+		for _, cell := range row {
+			w.text.WriteString(cell)
+			w.flush()
+		}
+	}
+}
+
+// cleanTableRow drops the empty spacer cells EDGAR tables use for layout
+// and rejoins currency symbols and closing parentheses with their numbers
+func cleanTableRow(cells []string) []string {
+	var row []string
+	pending := ""
+	for _, cell := range cells {
+		switch {
+		case cell == "":
+			continue
+		case cell == "$" || cell == "(" || cell == "$(":
+			pending += cell
+			continue
+		case (cell == ")" || cell == "%" || cell == ")%") && len(row) > 0:
+			row[len(row)-1] += cell
+			continue
+		}
+		row = append(row, pending+cell)
+		pending = ""
+	}
+	return row
+}
+
+// isHiddenElement reports hidden markup, such as the ix:header block that
+// carries inline XBRL contexts
+func isHiddenElement(t xml.StartElement) bool {
+	if strings.EqualFold(t.Name.Local, "header") && t.Name.Space != "" {
+		return true
+	}
+	for _, attr := range t.Attr {
+		if strings.EqualFold(attr.Name.Local, "style") {
+			style := strings.ReplaceAll(strings.ToLower(attr.Value), " ", "")
+			if strings.Contains(style, "display:none") {
+				return true
+			}
+		}
+	}
+	return false
+}
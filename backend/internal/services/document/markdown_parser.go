@@ -0,0 +1,132 @@
+package document
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/gridmate/backend/internal/memory"
+)
+
+var (
+	markdownHeadingPattern   = regexp.MustCompile(`^ {0,3}(#{1,6})\s+(.*?)\s*#*\s*$`)
+	markdownSeparatorPattern = regexp.MustCompile(`^\s*\|?\s*:?-{3,}:?\s*(\|\s*:?-{3,}:?\s*)*\|?\s*$`)
+	markdownSetextPattern    = regexp.MustCompile(`^ {0,3}(=+|-+)\s*$`)
+	markdownRulePattern      = regexp.MustCompile(`^ {0,3}([-*_]\s*){3,}$`)
+)
+
+// MarkdownParser handles Markdown documents. ATX and setext headings open
+// sections, pipe tables keep their rows, and fenced code stays verbatim.
+type MarkdownParser struct {
+	chunker structuredChunker
+}
+
+// NewMarkdownParser creates a new Markdown parser
+func NewMarkdownParser() *MarkdownParser {
+	return &MarkdownParser{chunker: newStructuredChunker("markdown")}
+}
+
+// SupportedTypes returns the file types this parser supports
+func (m *MarkdownParser) SupportedTypes() []string {
+	return []string{".md", ".markdown"}
+}
+
+// Parse extracts and chunks Markdown content
+func (m *MarkdownParser) Parse(ctx context.Context, reader io.Reader, filename string) ([]memory.Chunk, error) {
+	blocks, err := markdownBlocks(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Markdown: %w", err)
+	}
+	return m.chunker.chunk(filename, blocks), nil
+}
+
+// markdownBlocks reads Markdown into blocks, one per paragraph, heading,
+// table or code fence
+func markdownBlocks(reader io.Reader) ([]block, error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimRight(scanner.Text(), " \t\r"))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var blocks []block
+	var para []string
+	flush := func() {
+		if text := collapseSpace(strings.Join(para, " ")); text != "" {
+			blocks = append(blocks, block{Text: text})
+		}
+		para = nil
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			flush()
+
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			flush()
+			fence := trimmed[:3]
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, lines[i])
+			}
+			if text := strings.TrimSpace(strings.Join(code, "\n")); text != "" {
+				blocks = append(blocks, block{Text: text})
+			}
+
+		case markdownHeadingPattern.MatchString(line):
+			flush()
+			heading := markdownHeadingPattern.FindStringSubmatch(line)[2]
+			if heading != "" {
+				blocks = append(blocks, block{Heading: true, Text: heading})
+			}
+
+		case isPipeRow(trimmed) && i+1 < len(lines) && markdownSeparatorPattern.MatchString(lines[i+1]):
+			flush()
+			rows := [][]string{splitPipeRow(trimmed)}
+			for i += 2; i < len(lines) && isPipeRow(strings.TrimSpace(lines[i])); i++ {
+				rows = append(rows, splitPipeRow(strings.TrimSpace(lines[i])))
+			}
+			i--
+			blocks = append(blocks, block{Rows: rows})
+
+		case len(para) == 1 && markdownSetextPattern.MatchString(line):
+			blocks = append(blocks, block{Heading: true, Text: collapseSpace(para[0])})
+			para = nil
+
+		case markdownRulePattern.MatchString(line):
+			flush()
+
+		default:
+			para = append(para, strings.TrimLeft(trimmed, "> "))
+		}
+	}
+	flush()
+
+	return blocks, nil
+}
+
+func isPipeRow(line string) bool {
+	return strings.Count(line, "|") >= 1 && !markdownSeparatorPattern.MatchString(line)
+}
+
+// splitPipeRow splits "| a | b |" into its cells
+func splitPipeRow(line string) []string {
+	line = strings.TrimSuffix(strings.TrimPrefix(line, "|"), "|")
+	cells := strings.Split(line, "|")
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+	}
+	return cells
+}
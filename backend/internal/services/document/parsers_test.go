@@ -0,0 +1,202 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gridmate/backend/internal/memory"
+)
+
+const testFiling = `<!DOCTYPE html>
+<html><head><title>10-K</title><style>p { color: red }</style></head>
+<body>
+<p><b>PART II</b></p>
+<p><b>Item 7. Management's Discussion and Analysis</b></p>
+<p>Revenue grew on cloud demand.</p>
+<table>
+	<tr><td></td><td>2023</td><td></td><td>2022</td></tr>
+	<tr><td>Revenue</td><td>$</td><td>1,234</td><td>$</td><td>1,100</td></tr>
+	<tr><td>Net loss</td><td>(45</td><td>)</td></tr>
+</table>
+<table><tr><td>Layout paragraph</td></tr></table>
+</body></html>`
+
+const testDocumentXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:r><w:t>Overview</w:t></w:r></w:p>
+<w:p><w:r><w:t xml:space="preserve">The model covers </w:t></w:r><w:r><w:t>five years.</w:t></w:r></w:p>
+<w:tbl>
+	<w:tr><w:tc><w:p><w:r><w:t>Metric</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Value</w:t></w:r></w:p></w:tc></w:tr>
+	<w:tr><w:tc><w:p><w:r><w:t>WACC</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>8%</w:t></w:r></w:p></w:tc></w:tr>
+</w:tbl>
+</w:body></w:document>`
+
+func buildZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func testXLSX(t *testing.T) []byte {
+	return buildZip(t, map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Model" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="worksheet" Target="worksheets/sheet1.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst><si><t>Year</t></si><si><t>Revenue</t></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row>
+<row r="2"><c r="A2"><v>2023</v></c><c r="B2"><v>100</v></c></row>
+<row r="3"><c r="A3"><v>2024</v></c><c r="B3"><f>B2*1.1</f><v>110</v></c></row>
+</sheetData></worksheet>`,
+	})
+}
+
+func chunkContaining(chunks []memory.Chunk, text string) *memory.Chunk {
+	for i := range chunks {
+		if strings.Contains(chunks[i].Content, text) {
+			return &chunks[i]
+		}
+	}
+	return nil
+}
+
+func TestDetectFormat(t *testing.T) {
+	docx := buildZip(t, map[string]string{"word/document.xml": testDocumentXML})
+
+	tests := []struct {
+		name     string
+		content  []byte
+		filename string
+		want     Format
+	}{
+		{"pdf", []byte("%PDF-1.7\n..."), "report.bin", FormatPDF},
+		{"html by content", []byte(testFiling), "filing.txt", FormatHTML},
+		{"edgar submission", []byte("<SEC-DOCUMENT>0001.txt\n<DOCUMENT>\n<TYPE>10-K"), "0001.txt", FormatHTML},
+		{"docx", docx, "upload", FormatDOCX},
+		{"xlsx", testXLSX(t), "model.docx", FormatXLSX},
+		{"csv", []byte("year,revenue\n2023,100\n2024,110\n"), "data.txt", FormatCSV},
+		{"semicolon csv", []byte("year;revenue\n2023;100\n"), "data", FormatCSV},
+		{"markdown", []byte("# Notes\n\nSome text.\n"), "notes.txt", FormatMarkdown},
+		{"markdown by extension", []byte("Plain words only."), "notes.md", FormatMarkdown},
+		{"text", []byte("Plain words only.\nAnother line."), "notes.csv", FormatText},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectFormat(tt.content, tt.filename); got != tt.want {
+				t.Errorf("DetectFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTMLParser_Filing(t *testing.T) {
+	chunks, err := NewHTMLParser().Parse(context.Background(), strings.NewReader(testFiling), "10k.htm")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	table := chunkContaining(chunks, "Revenue | $1,234 | $1,100")
+	if table == nil {
+		t.Fatalf("table rows not preserved: %+v", chunks)
+	}
+	if !strings.Contains(table.Content, "Net loss | (45)") {
+		t.Errorf("parenthesised cell not rejoined: %q", table.Content)
+	}
+	if table.Metadata.Section != "Item 7. Management's Discussion and Analysis - Table 1" {
+		t.Errorf("table section = %q", table.Metadata.Section)
+	}
+
+	text := chunkContaining(chunks, "Revenue grew")
+	if text == nil || text.Metadata.Section != "Item 7. Management's Discussion and Analysis" {
+		t.Fatalf("text not sectioned under Item 7: %+v", text)
+	}
+	if c := chunkContaining(chunks, "Layout paragraph"); c == nil || c.Metadata.SourceMeta["section_type"] != "text" {
+		t.Errorf("single-column table should read as text: %+v", c)
+	}
+	if chunkContaining(chunks, "color: red") != nil {
+		t.Error("style content was indexed")
+	}
+}
+
+func TestDOCXParser_HeadingsAndTables(t *testing.T) {
+	content := buildZip(t, map[string]string{"word/document.xml": testDocumentXML})
+	chunks, err := NewDOCXParser().Parse(context.Background(), bytes.NewReader(content), "memo.docx")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	text := chunkContaining(chunks, "The model covers five years.")
+	if text == nil || text.Metadata.Section != "Overview" {
+		t.Fatalf("paragraph not under heading: %+v", chunks)
+	}
+	table := chunkContaining(chunks, "Metric | Value\nWACC | 8%")
+	if table == nil || table.Metadata.SourceMeta["section_type"] != "table" {
+		t.Fatalf("table not preserved: %+v", chunks)
+	}
+}
+
+func TestMarkdownParser(t *testing.T) {
+	input := "# Assumptions\n\nGrowth is steady.\n\n| Driver | Rate |\n|---|---:|\n| Growth | 5% |\n\nRisks\n-----\n\n```\n=SUM(A1:A3)\n```\n"
+	chunks, err := NewMarkdownParser().Parse(context.Background(), strings.NewReader(input), "notes.md")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if c := chunkContaining(chunks, "Growth is steady."); c == nil || c.Metadata.Section != "Assumptions" {
+		t.Errorf("ATX heading not applied: %+v", c)
+	}
+	if chunkContaining(chunks, "Driver | Rate\nGrowth | 5%") == nil {
+		t.Errorf("pipe table not preserved: %+v", chunks)
+	}
+	if c := chunkContaining(chunks, "=SUM(A1:A3)"); c == nil || c.Metadata.Section != "Risks" {
+		t.Errorf("setext heading or code fence not applied: %+v", c)
+	}
+}
+
+func TestSpreadsheetParser(t *testing.T) {
+	parsers := NewParsers()
+
+	chunks, err := parsers.Parse(context.Background(), bytes.NewReader(testXLSX(t)), "model.xlsx")
+	if err != nil {
+		t.Fatalf("Parse(xlsx) error = %v", err)
+	}
+	if len(chunks) == 0 {
+		t.Fatal("no chunks for xlsx")
+	}
+	for _, c := range chunks {
+		if c.Metadata.Source != "document" || c.Metadata.DocumentName != "model.xlsx" || c.Metadata.SheetName != "Model" {
+			t.Errorf("unexpected metadata: %+v", c.Metadata)
+		}
+		if c.Metadata.SourceMeta["format"] != "xlsx" || !strings.HasPrefix(c.ID, "model.xlsx_") {
+			t.Errorf("unexpected chunk %q: %+v", c.ID, c.Metadata.SourceMeta)
+		}
+	}
+	if c := chunkContaining(chunks, "Used Range: A1:B3"); c == nil || !strings.Contains(c.Content, "Formulas: 1") {
+		t.Errorf("sheet not read: %+v", chunks)
+	}
+
+	chunks, err = parsers.Parse(context.Background(), strings.NewReader("Year;Revenue\n2023;100\n2024;110\n"), "export.txt")
+	if err != nil {
+		t.Fatalf("Parse(csv) error = %v", err)
+	}
+	if c := chunkContaining(chunks, "Spreadsheet: export"); c == nil || c.Metadata.SourceMeta["format"] != "csv" {
+		t.Errorf("csv not read as a sheet: %+v", chunks)
+	}
+}
@@ -0,0 +1,203 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gridmate/backend/internal/memory"
+)
+
+// Format identifies a document format
+type Format string
+
+const (
+	FormatPDF      Format = "pdf"
+	FormatHTML     Format = "html"
+	FormatDOCX     Format = "docx"
+	FormatXLSX     Format = "xlsx"
+	FormatCSV      Format = "csv"
+	FormatMarkdown Format = "markdown"
+	FormatText     Format = "text"
+)
+
+// sniffLength is how much of a file DetectFormat inspects for text formats
+const sniffLength = 8192
+
+var (
+	htmlMarkers = []string{
+		"<!doctype html", "<html", "<sec-document>", "<document>", "<body", "<table", "<div", "<p>",
+	}
+	markdownLinePattern = regexp.MustCompile(`(?m)^( {0,3}#{1,6}\s+\S|\s*\|.*\|\s*$|\s*(\x60{3}|~{3}))`)
+)
+
+// DetectFormat identifies a document by its content. The filename
+// extension only breaks ties between the plain text formats, so a
+// mislabelled upload is still parsed by the right parser.
+func DetectFormat(content []byte, filename string) Format {
+	ext := strings.ToLower(filepath.Ext(filename))
+
+	if bytes.HasPrefix(content, []byte("%PDF-")) {
+		return FormatPDF
+	}
+	if bytes.HasPrefix(content, []byte("PK\x03\x04")) {
+		if archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content))); err == nil {
+			for _, f := range archive.File {
+				switch f.Name {
+				case "word/document.xml":
+					return FormatDOCX
+				case "xl/workbook.xml":
+					return FormatXLSX
+				}
+			}
+		}
+		return FormatText
+	}
+
+	head := content[:min(len(content), sniffLength)]
+	head = bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))
+	lower := strings.ToLower(string(bytes.TrimSpace(head)))
+	for _, marker := range htmlMarkers {
+		if strings.HasPrefix(lower, marker) || (marker != "<p>" && strings.Contains(lower, marker)) {
+			return FormatHTML
+		}
+	}
+	if strings.HasPrefix(lower, "<?xml") && strings.Contains(lower, "<html") {
+		return FormatHTML
+	}
+
+	switch ext {
+	case ".csv", ".tsv":
+		if looksDelimited(head) {
+			return FormatCSV
+		}
+	case ".md", ".markdown":
+		return FormatMarkdown
+	}
+	if markdownLinePattern.Match(head) {
+		return FormatMarkdown
+	}
+	if looksDelimited(head) {
+		return FormatCSV
+	}
+	return FormatText
+}
+
+// sniffDelimiter picks the CSV delimiter used most consistently across
+// the first lines
+func sniffDelimiter(content []byte) rune {
+	best, bestCount := ',', 0
+	lines := sampleLines(content)
+	for _, delim := range []rune{',', ';', '\t', '|'} {
+		if count := consistentCount(lines, delim); count > bestCount {
+			best, bestCount = delim, count
+		}
+	}
+	return best
+}
+
+// looksDelimited reports whether at least two lines split into the same
+// number of fields on some delimiter
+func looksDelimited(content []byte) bool {
+	lines := sampleLines(content)
+	if len(lines) < 2 {
+		return false
+	}
+	for _, delim := range []rune{',', ';', '\t'} {
+		if consistentCount(lines, delim) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// consistentCount returns the per-line delimiter count when every sampled
+// line has the same count, and 0 otherwise
+func consistentCount(lines []string, delim rune) int {
+	count := -1
+	for _, line := range lines {
+		n := strings.Count(line, string(delim))
+		if count >= 0 && n != count {
+			return 0
+		}
+		count = n
+	}
+	return max(count, 0)
+}
+
+func sampleLines(content []byte) []string {
+	head := string(content[:min(len(content), sniffLength)])
+	lines := strings.Split(strings.ReplaceAll(head, "\r\n", "\n"), "\n")
+	if len(content) > sniffLength && len(lines) > 1 {
+		lines = lines[:len(lines)-1] // Last line may be cut off
+	}
+	var sample []string
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			sample = append(sample, line)
+		}
+		if len(sample) == 10 {
+			break
+		}
+	}
+	return sample
+}
+
+// Parsers picks a parser for each document by sniffing its content
+type Parsers struct {
+	parsers map[Format]DocumentParser
+}
+
+// NewParsers creates a registry with a parser for every known format
+func NewParsers() *Parsers {
+	spreadsheet := NewSpreadsheetParser()
+	return &Parsers{
+		parsers: map[Format]DocumentParser{
+			FormatPDF:      NewPDFParser(),
+			FormatHTML:     NewHTMLParser(),
+			FormatDOCX:     NewDOCXParser(),
+			FormatXLSX:     spreadsheet,
+			FormatCSV:      spreadsheet,
+			FormatMarkdown: NewMarkdownParser(),
+			FormatText:     NewTextDocumentParser(),
+		},
+	}
+}
+
+// For returns the parser for a format, falling back to plain text
+func (p *Parsers) For(format Format) DocumentParser {
+	if parser, ok := p.parsers[format]; ok {
+		return parser
+	}
+	return p.parsers[FormatText]
+}
+
+// SupportedTypes returns the file types of all registered parsers
+func (p *Parsers) SupportedTypes() []string {
+	seen := make(map[string]bool)
+	var types []string
+	for _, parser := range p.parsers {
+		for _, t := range parser.SupportedTypes() {
+			if !seen[t] {
+				seen[t] = true
+				types = append(types, t)
+			}
+		}
+	}
+	return types
+}
+
+// Parse detects the document's format and parses it with the matching
+// parser
+func (p *Parsers) Parse(ctx context.Context, reader io.Reader, filename string) ([]memory.Chunk, error) {
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document: %w", err)
+	}
+	return p.For(DetectFormat(content, filename)).Parse(ctx, bytes.NewReader(content), filename)
+}
@@ -0,0 +1,320 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gridmate/backend/internal/memory"
+	"github.com/gridmate/backend/internal/memory/chunkers"
+	"github.com/gridmate/backend/internal/models"
+)
+
+// Cells past these bounds are dropped so a stray far-off cell cannot
+// allocate a huge grid
+const (
+	maxSheetRows    = 100000
+	maxSheetColumns = 500
+)
+
+// SpreadsheetParser handles CSV and XLSX attachments. Sheets are read into
+// the same model the Excel add-in sends and chunked by SpreadsheetChunker,
+// so attached workbooks are searchable like the live one.
+type SpreadsheetParser struct {
+	chunker *chunkers.SpreadsheetChunker
+}
+
+// NewSpreadsheetParser creates a new CSV and XLSX parser
+func NewSpreadsheetParser() *SpreadsheetParser {
+	return &SpreadsheetParser{chunker: chunkers.NewSpreadsheetChunker()}
+}
+
+// SupportedTypes returns the file types this parser supports
+func (s *SpreadsheetParser) SupportedTypes() []string {
+	return []string{".csv", ".tsv", ".xlsx", ".xlsm"}
+}
+
+// Parse reads the sheets of a CSV or XLSX file and chunks them
+func (s *SpreadsheetParser) Parse(ctx context.Context, reader io.Reader, filename string) ([]memory.Chunk, error) {
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spreadsheet: %w", err)
+	}
+
+	format := DetectFormat(content, filename)
+	var sheets []*models.Sheet
+	switch format {
+	case FormatXLSX:
+		sheets, err = readXLSX(content)
+	default:
+		format = FormatCSV
+		var sheet *models.Sheet
+		sheet, err = readCSV(content, strings.TrimSuffix(path.Base(filename), path.Ext(filename)))
+		sheets = []*models.Sheet{sheet}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", format, err)
+	}
+
+	chunks := []memory.Chunk{}
+	for _, sheet := range sheets {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		for _, chunk := range s.chunker.ChunkSpreadsheet(sheet) {
+			chunk.ID = fmt.Sprintf("%s_%s", filename, chunk.ID)
+			chunk.Metadata.Source = "document"
+			chunk.Metadata.SourceID = filename
+			chunk.Metadata.DocumentName = filename
+			chunk.Metadata.Section = sheet.Name
+			if chunk.Metadata.SourceMeta == nil {
+				chunk.Metadata.SourceMeta = map[string]interface{}{}
+			}
+			chunk.Metadata.SourceMeta["format"] = string(format)
+			chunks = append(chunks, chunk)
+		}
+	}
+	return chunks, nil
+}
+
+// readCSV reads delimited text into a single sheet
+func readCSV(content []byte, name string) (*models.Sheet, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))))
+	reader.Comma = sniffDelimiter(content)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := 0
+	for _, record := range records {
+		columns = max(columns, len(record))
+	}
+	values := make([][]interface{}, len(records))
+	for i, record := range records {
+		values[i] = make([]interface{}, columns)
+		for j := range values[i] {
+			values[i][j] = ""
+		}
+		for j, field := range record {
+			values[i][j] = cellValue(field)
+		}
+	}
+	return newSheet(name, values, nil, len(records), columns), nil
+}
+
+// cellValue keeps numbers numeric so the chunker can recognise totals
+func cellValue(field string) interface{} {
+	field = strings.TrimSpace(field)
+	if f, err := strconv.ParseFloat(strings.ReplaceAll(field, ",", ""), 64); err == nil && field != "" {
+		return f
+	}
+	return field
+}
+
+// xlsxCell is a <c> element of a worksheet
+type xlsxCell struct {
+	Ref     string `xml:"r,attr"`
+	Type    string `xml:"t,attr"`
+	Value   string `xml:"v"`
+	Formula string `xml:"f"`
+	Inline  struct {
+		Text string   `xml:"t"`
+		Runs []string `xml:"r>t"`
+	} `xml:"is"`
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		Cells []xlsxCell `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxSharedStrings struct {
+	Items []struct {
+		Text string   `xml:"t"`
+		Runs []string `xml:"r>t"`
+	} `xml:"si"`
+}
+
+// readXLSX reads every worksheet of a workbook, in workbook order
+func readXLSX(content []byte) ([]*models.Sheet, error) {
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, err
+	}
+
+	var workbook xlsxWorkbook
+	if err := readZipXML(archive, "xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	var rels xlsxRelationships
+	if err := readZipXML(archive, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	targets := make(map[string]string, len(rels.Relationships))
+	for _, rel := range rels.Relationships {
+		target := strings.TrimPrefix(rel.Target, "/")
+		if !strings.HasPrefix(target, "xl/") {
+			target = path.Join("xl", target)
+		}
+		targets[rel.ID] = target
+	}
+
+	// Workbooks without text cells have no shared strings part
+	var shared xlsxSharedStrings
+	_ = readZipXML(archive, "xl/sharedStrings.xml", &shared)
+	strs := make([]string, len(shared.Items))
+	for i, item := range shared.Items {
+		strs[i] = item.Text + strings.Join(item.Runs, "")
+	}
+
+	var sheets []*models.Sheet
+	for _, entry := range workbook.Sheets {
+		target, ok := targets[entry.RID]
+		if !ok {
+			continue
+		}
+		var ws xlsxWorksheet
+		if err := readZipXML(archive, target, &ws); err != nil {
+			return nil, fmt.Errorf("sheet %s: %w", entry.Name, err)
+		}
+		sheets = append(sheets, xlsxSheet(entry.Name, ws, strs))
+	}
+	return sheets, nil
+}
+
+// xlsxSheet lays the cells of a worksheet out as a dense grid from A1
+func xlsxSheet(name string, ws xlsxWorksheet, strs []string) *models.Sheet {
+	type cell struct {
+		row, col int
+		value    interface{}
+		formula  string
+	}
+	var cells []cell
+	rows, columns := 0, 0
+	hasFormulas := false
+
+	for _, r := range ws.Rows {
+		for _, c := range r.Cells {
+			bounds, ok := memory.ParseCellRange(c.Ref)
+			if !ok {
+				continue
+			}
+			row, col := bounds.StartRow-1, bounds.StartCol-1
+			if row >= maxSheetRows || col >= maxSheetColumns {
+				continue
+			}
+
+			var value interface{}
+			switch c.Type {
+			case "s":
+				if i, err := strconv.Atoi(c.Value); err == nil && i >= 0 && i < len(strs) {
+					value = strs[i]
+				}
+			case "inlineStr":
+				value = c.Inline.Text + strings.Join(c.Inline.Runs, "")
+			case "b":
+				value = c.Value == "1"
+			case "str", "e":
+				value = c.Value
+			default:
+				if f, err := strconv.ParseFloat(c.Value, 64); err == nil {
+					value = f
+				} else {
+					value = c.Value
+				}
+			}
+			formula := ""
+			if c.Formula != "" {
+				formula = "=" + c.Formula
+				hasFormulas = true
+			}
+
+			cells = append(cells, cell{row, col, value, formula})
+			rows = max(rows, row+1)
+			columns = max(columns, col+1)
+		}
+	}
+
+	values := make([][]interface{}, rows)
+	for i := range values {
+		values[i] = make([]interface{}, columns)
+		for j := range values[i] {
+			values[i][j] = ""
+		}
+	}
+	var formulas [][]string
+	if hasFormulas {
+		formulas = make([][]string, rows)
+		for i := range formulas {
+			formulas[i] = make([]string, columns)
+		}
+	}
+	for _, c := range cells {
+		values[c.row][c.col] = c.value
+		if formulas != nil {
+			formulas[c.row][c.col] = c.formula
+		}
+	}
+	return newSheet(name, values, formulas, rows, columns)
+}
+
+func newSheet(name string, values [][]interface{}, formulas [][]string, rows, columns int) *models.Sheet {
+	used := ""
+	if rows > 0 && columns > 0 {
+		used = fmt.Sprintf("A1:%s%d", columnName(columns-1), rows)
+	}
+	return &models.Sheet{
+		Name:      name,
+		UsedRange: used,
+		Data: &models.RangeData{
+			Sheet:    name,
+			Range:    used,
+			Values:   values,
+			Formulas: formulas,
+		},
+	}
+}
+
+// columnName converts a 0-based column index to its letters
+func columnName(col int) string {
+	name := ""
+	for col >= 0 {
+		name = string(rune('A'+col%26)) + name
+		col = col/26 - 1
+	}
+	return name
+}
+
+func readZipXML(archive *zip.Reader, name string, v interface{}) error {
+	data, err := readZipFile(archive, name)
+	if err != nil {
+		return err
+	}
+	return xml.Unmarshal(data, v)
+}
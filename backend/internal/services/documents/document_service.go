@@ -2,12 +2,14 @@ package documents
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	
+	"github.com/gridmate/backend/internal/memory"
 	"github.com/gridmate/backend/internal/models"
 	"github.com/gridmate/backend/internal/repository"
 	"github.com/gridmate/backend/internal/services/ai"
@@ -20,6 +22,8 @@ type DocumentService struct {
 	aiService      ai.AIProvider
 	docRepo        repository.DocumentRepository
 	embeddingRepo  repository.EmbeddingRepository
+	factRepo       repository.FinancialFactRepository
+	chunkStore     *memory.PostgresStore
 }
 
 // NewDocumentService creates a new document service
@@ -28,6 +32,7 @@ func NewDocumentService(
 	aiService ai.AIProvider,
 	docRepo repository.DocumentRepository,
 	embeddingRepo repository.EmbeddingRepository,
+	factRepo repository.FinancialFactRepository,
 ) *DocumentService {
 	return &DocumentService{
 		logger:         logger,
@@ -35,9 +40,16 @@ func NewDocumentService(
 		aiService:      aiService,
 		docRepo:        docRepo,
 		embeddingRepo:  embeddingRepo,
+		factRepo:       factRepo,
 	}
 }
 
+// SetChunkStore routes filing chunks through the shared memory store, so
+// document search and workbook memory search query the same index
+func (s *DocumentService) SetChunkStore(store *memory.PostgresStore) {
+	s.chunkStore = store
+}
+
 // ProcessEDGARDocument processes an EDGAR document and stores it with embeddings
 func (s *DocumentService) ProcessEDGARDocument(ctx context.Context, userID uuid.UUID, content string, docType DocumentType, url string) (*FinancialDocument, error) {
 	// Process the document
@@ -64,6 +76,7 @@ func (s *DocumentService) ProcessEDGARDocument(ctx context.Context, userID uuid.
 			"cik":         doc.CIK,
 			"sections":    len(doc.Sections),
 			"tables":      len(doc.Tables),
+			"facts":       len(doc.Facts),
 			"key_metrics": doc.KeyMetrics,
 		},
 	}
@@ -72,8 +85,14 @@ func (s *DocumentService) ProcessEDGARDocument(ctx context.Context, userID uuid.
 		return nil, fmt.Errorf("failed to store document: %w", err)
 	}
 	
+	// Store tagged XBRL facts alongside the document
+	if err := s.storeFacts(ctx, docRecord.ID, doc.Facts); err != nil {
+		s.logger.WithError(err).Error("Failed to store financial facts")
+		// Continue even if fact storage fails
+	}
+	
 	// Generate and store embeddings for all chunks
-	if err := s.generateAndStoreEmbeddings(ctx, docRecord.ID, doc); err != nil {
+	if err := s.generateAndStoreEmbeddings(ctx, docRecord, doc); err != nil {
 		s.logger.WithError(err).Error("Failed to generate embeddings")
 		// Continue even if embeddings fail
 	}
@@ -82,7 +101,8 @@ func (s *DocumentService) ProcessEDGARDocument(ctx context.Context, userID uuid.
 }
 
 // generateAndStoreEmbeddings creates embeddings for all document chunks
-func (s *DocumentService) generateAndStoreEmbeddings(ctx context.Context, docID uuid.UUID, doc *FinancialDocument) error {
+func (s *DocumentService) generateAndStoreEmbeddings(ctx context.Context, docRecord *models.Document, doc *FinancialDocument) error {
+	docID := docRecord.ID
 	var embeddings []*models.Embedding
 	
 	// Process each section
@@ -110,7 +130,11 @@ func (s *DocumentService) generateAndStoreEmbeddings(ctx context.Context, docID
 	
 	// Batch store embeddings
 	if len(embeddings) > 0 {
-		if err := s.embeddingRepo.BatchCreate(ctx, embeddings); err != nil {
+		if s.chunkStore != nil {
+			if err := s.storeMemoryChunks(docRecord, embeddings); err != nil {
+				return err
+			}
+		} else if err := s.embeddingRepo.BatchCreate(ctx, embeddings); err != nil {
 			return fmt.Errorf("failed to store embeddings: %w", err)
 		}
 	}
@@ -123,10 +147,124 @@ func (s *DocumentService) generateAndStoreEmbeddings(ctx context.Context, docID
 	return nil
 }
 
+// storeMemoryChunks writes filing chunks to the shared memory store under
+// the document owner
+func (s *DocumentService) storeMemoryChunks(docRecord *models.Document, embeddings []*models.Embedding) error {
+	chunks := make([]memory.Chunk, 0, len(embeddings))
+	for _, emb := range embeddings {
+		section, _ := emb.Metadata["section"].(string)
+		chunks = append(chunks, memory.Chunk{
+			ID:      emb.ChunkID,
+			Vector:  emb.Embedding,
+			Content: emb.Content,
+			Metadata: memory.ChunkMetadata{
+				Source:       "document",
+				SourceID:     docRecord.ID.String(),
+				SourceMeta:   emb.Metadata,
+				DocumentName: docRecord.Title,
+				Section:      section,
+			},
+			Timestamp: time.Now(),
+		})
+	}
+
+	if err := s.chunkStore.Add(memory.TenantNamespace(docRecord.UserID.String()), chunks); err != nil {
+		return fmt.Errorf("failed to store document chunks: %w", err)
+	}
+	return nil
+}
+
+// storeFacts persists XBRL facts for a stored document
+func (s *DocumentService) storeFacts(ctx context.Context, docID uuid.UUID, facts []Fact) error {
+	if len(facts) == 0 || s.factRepo == nil {
+		return nil
+	}
+	
+	records := make([]*models.FinancialFact, 0, len(facts))
+	for _, fact := range facts {
+		record := &models.FinancialFact{
+			DocumentID: docID,
+			Concept:    fact.Concept,
+			LocalName:  fact.LocalName(),
+			ContextRef: fact.ContextID,
+			PeriodType: fact.Period.Type,
+			Scale:      fact.Scale,
+		}
+		if fact.Period.Type == "" {
+			record.PeriodType = PeriodForever
+		}
+		if !fact.Period.Start.IsZero() {
+			start := fact.Period.Start
+			record.PeriodStart = &start
+		}
+		if !fact.Period.End.IsZero() {
+			end := fact.Period.End
+			record.PeriodEnd = &end
+		}
+		if len(fact.Dimensions) > 0 {
+			dims, err := json.Marshal(fact.Dimensions)
+			if err != nil {
+				return fmt.Errorf("failed to encode fact dimensions: %w", err)
+			}
+			record.Dimensions = dims
+		}
+		if fact.Unit != "" {
+			unit := fact.Unit
+			record.Unit = &unit
+		}
+		if fact.Page > 0 {
+			page := fact.Page
+			record.Page = &page
+		}
+		if fact.Decimals != "" {
+			decimals := fact.Decimals
+			record.Decimals = &decimals
+		}
+		if fact.IsNumeric && !fact.IsNil {
+			value := fact.Value
+			record.Value = &value
+		}
+		if !fact.IsNumeric {
+			text := fact.Text
+			record.TextValue = &text
+		}
+		records = append(records, record)
+	}
+	
+	if err := s.factRepo.BatchCreate(ctx, records); err != nil {
+		return err
+	}
+	
+	s.logger.WithFields(logrus.Fields{
+		"document_id": docID,
+		"facts":       len(records),
+	}).Info("Stored financial facts")
+	
+	return nil
+}
+
+// SearchFacts finds XBRL facts across a user's documents by concept and period
+func (s *DocumentService) SearchFacts(ctx context.Context, filter *models.FinancialFactFilter) ([]*models.FinancialFact, error) {
+	if s.factRepo == nil {
+		return nil, fmt.Errorf("fact storage not configured")
+	}
+	if filter.Limit <= 0 || filter.Limit > 1000 {
+		filter.Limit = 100
+	}
+	return s.factRepo.Search(ctx, filter)
+}
+
 // SearchDocuments searches for relevant document chunks based on query
 func (s *DocumentService) SearchDocuments(ctx context.Context, userID uuid.UUID, query string, limit int) ([]SearchResult, error) {
 	// Generate embedding for query
 	queryEmbedding, err := s.aiService.GetEmbedding(ctx, query)
+	if s.chunkStore != nil {
+		if err != nil {
+			s.logger.WithError(err).Warn("Failed to generate query embedding, using keyword search only")
+			queryEmbedding = nil
+		}
+		return s.searchMemoryChunks(ctx, userID, query, queryEmbedding, limit)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate query embedding: %w", err)
 	}
@@ -168,6 +306,73 @@ func (s *DocumentService) SearchDocuments(ctx context.Context, userID uuid.UUID,
 	return results, nil
 }
 
+// searchMemoryChunks runs hybrid retrieval over everything the user can see
+// in the shared memory store, including workbook and chat chunks
+func (s *DocumentService) searchMemoryChunks(ctx context.Context, userID uuid.UUID, query string, queryEmbedding []float32, limit int) ([]SearchResult, error) {
+	ns := memory.TenantNamespace(userID.String())
+	found, err := memory.NewRetriever().Search(s.chunkStore, ns, query, queryEmbedding, limit, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search memory chunks: %w", err)
+	}
+
+	documents := make(map[string]*models.Document)
+	results := make([]SearchResult, 0, len(found))
+	for _, item := range found {
+		meta := item.Chunk.Metadata
+		metadata := make(map[string]interface{}, len(meta.SourceMeta)+3)
+		for k, v := range meta.SourceMeta {
+			metadata[k] = v
+		}
+		metadata["source"] = meta.Source
+
+		result := SearchResult{
+			ChunkID:  item.Chunk.ID,
+			Content:  item.Chunk.Content,
+			Score:    float64(item.Score),
+			Metadata: metadata,
+		}
+
+		switch meta.Source {
+		case "document":
+			doc, ok := documents[meta.SourceID]
+			if !ok {
+				if id, err := uuid.Parse(meta.SourceID); err == nil {
+					if doc, err = s.docRepo.GetByID(ctx, id); err != nil {
+						s.logger.WithError(err).Warn("Failed to get document")
+						doc = nil
+					}
+				}
+				documents[meta.SourceID] = doc
+			}
+			result.DocumentID = meta.SourceID
+			result.DocumentInfo = DocumentInfo{Title: meta.DocumentName, Type: meta.Source}
+			if doc != nil {
+				result.DocumentInfo = DocumentInfo{
+					Title:       doc.Title,
+					Type:        doc.Type,
+					CompanyName: doc.CompanyName,
+					FilingDate:  doc.FilingDate,
+					URL:         doc.URL,
+				}
+			}
+		case "spreadsheet":
+			metadata["sheet_name"] = meta.SheetName
+			metadata["cell_range"] = meta.CellRange
+			title := meta.SheetName
+			if meta.CellRange != "" {
+				title += "!" + meta.CellRange
+			}
+			result.DocumentInfo = DocumentInfo{Title: title, Type: "workbook"}
+		default:
+			result.DocumentInfo = DocumentInfo{Title: fmt.Sprintf("Chat turn %d", meta.Turn), Type: meta.Source}
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
 // GetDocumentContext retrieves relevant context for a financial modeling query
 func (s *DocumentService) GetDocumentContext(ctx context.Context, userID uuid.UUID, query string, maxChunks int) (*FinancialContext, error) {
 	// Search for relevant chunks
@@ -276,6 +481,13 @@ func (s *DocumentService) DeleteDocument(ctx context.Context, userID uuid.UUID,
 		return fmt.Errorf("failed to delete embeddings: %w", err)
 	}
 	
+	// Delete extracted facts
+	if s.factRepo != nil {
+		if err := s.factRepo.DeleteByDocumentID(ctx, documentID); err != nil {
+			return fmt.Errorf("failed to delete financial facts: %w", err)
+		}
+	}
+	
 	// Delete document
 	if err := s.docRepo.Delete(ctx, documentID); err != nil {
 		return fmt.Errorf("failed to delete document: %w", err)
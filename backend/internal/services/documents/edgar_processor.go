@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,13 +13,15 @@ import (
 
 // EDGARProcessor handles SEC EDGAR document processing
 type EDGARProcessor struct {
-	logger *logrus.Logger
+	logger     *logrus.Logger
+	xbrlParser *XBRLParser
 }
 
 // NewEDGARProcessor creates a new EDGAR document processor
 func NewEDGARProcessor(logger *logrus.Logger) *EDGARProcessor {
 	return &EDGARProcessor{
-		logger: logger,
+		logger:     logger,
+		xbrlParser: NewXBRLParser(),
 	}
 }
 
@@ -47,6 +50,7 @@ type FinancialDocument struct {
 	Sections     map[string]Section     `json:"sections"`
 	Tables       []FinancialTable       `json:"tables"`
 	KeyMetrics   map[string]interface{} `json:"key_metrics"`
+	Facts        []Fact                 `json:"-"` // XBRL facts, stored separately
 	ProcessedAt  time.Time              `json:"processed_at"`
 }
 
@@ -68,13 +72,15 @@ type Chunk struct {
 
 // FinancialTable represents extracted financial data tables
 type FinancialTable struct {
-	ID      string          `json:"id"`
-	Title   string          `json:"title"`
-	Headers []string        `json:"headers"`
-	Rows    [][]string      `json:"rows"`
-	Type    string          `json:"type"` // income_statement, balance_sheet, cash_flow
-	Period  string          `json:"period"`
-	Units   string          `json:"units"`
+	ID       string     `json:"id"`
+	Title    string     `json:"title"`
+	Headers  []string   `json:"headers"`
+	Rows     [][]string `json:"rows"`
+	Type     string     `json:"type"` // income_statement, balance_sheet, cash_flow
+	Period   string     `json:"period"`
+	Units    string     `json:"units"`
+	Source   string     `json:"source,omitempty"`   // xbrl or text
+	Concepts []string   `json:"concepts,omitempty"` // XBRL concept for each row
 }
 
 // ProcessDocument processes a raw EDGAR document
@@ -106,11 +112,26 @@ func (p *EDGARProcessor) ProcessDocument(ctx context.Context, rawContent string,
 		p.processGeneric(doc)
 	}
 
-	// Extract financial tables
-	p.extractFinancialTables(doc)
+	// Prefer tagged XBRL facts; fall back to text heuristics for untagged filings
+	if IsXBRL(rawContent) {
+		facts, err := p.xbrlParser.Parse(rawContent)
+		if err != nil {
+			p.logger.WithError(err).Warn("Failed to parse XBRL facts, falling back to text extraction")
+		}
+		doc.Facts = facts
+	}
 
-	// Extract key metrics
-	p.extractKeyMetrics(doc)
+	if len(doc.Facts) > 0 {
+		p.applyFactMetadata(doc)
+		doc.Tables = BuildFactTables(doc.Facts)
+		p.extractKeyMetricsFromFacts(doc)
+	} else {
+		// Extract financial tables
+		p.extractFinancialTables(doc)
+
+		// Extract key metrics
+		p.extractKeyMetrics(doc)
+	}
 
 	// Create semantic chunks for each section
 	p.createSemanticChunks(doc)
@@ -424,6 +445,70 @@ func (p *EDGARProcessor) extractKeyMetrics(doc *FinancialDocument) {
 	doc.KeyMetrics = metrics
 }
 
+// keyMetricConcepts lists the concepts checked, in order, for each key metric
+var keyMetricConcepts = map[string][]string{
+	"revenue":           {"Revenues", "RevenueFromContractWithCustomerExcludingAssessedTax", "SalesRevenueNet", "Revenue"},
+	"net_income":        {"NetIncomeLoss", "ProfitLoss", "ProfitLossAttributableToOwnersOfParent"},
+	"eps":               {"EarningsPerShareDiluted", "EarningsPerShareBasic", "DilutedEarningsLossPerShare"},
+	"total_assets":      {"Assets"},
+	"total_liabilities": {"Liabilities"},
+}
+
+// applyFactMetadata fills company and period details from dei facts
+func (p *EDGARProcessor) applyFactMetadata(doc *FinancialDocument) {
+	for _, fact := range doc.Facts {
+		if fact.Prefix() != "dei" || fact.Text == "" {
+			continue
+		}
+		switch fact.LocalName() {
+		case "EntityRegistrantName":
+			if doc.CompanyName == "" {
+				doc.CompanyName = fact.Text
+			}
+		case "EntityCentralIndexKey":
+			if doc.CIK == "" {
+				doc.CIK = fact.Text
+			}
+		case "TradingSymbol":
+			if doc.Ticker == "" {
+				doc.Ticker = fact.Text
+			}
+		case "DocumentPeriodEndDate":
+			if date := parseXBRLDate(fact.Text); doc.PeriodEnd.IsZero() && !date.IsZero() {
+				doc.PeriodEnd = date
+			}
+		}
+	}
+}
+
+// extractKeyMetricsFromFacts picks key metrics for the filing's primary
+// period: the latest period end, preferring the longest duration
+func (p *EDGARProcessor) extractKeyMetricsFromFacts(doc *FinancialDocument) {
+	metrics := make(map[string]interface{})
+
+	for metric, concepts := range keyMetricConcepts {
+		for _, concept := range concepts {
+			var best *Fact
+			for i := range doc.Facts {
+				fact := &doc.Facts[i]
+				if !fact.IsNumeric || fact.IsNil || len(fact.Dimensions) > 0 || fact.LocalName() != concept {
+					continue
+				}
+				if best == nil || fact.Period.End.After(best.Period.End) ||
+					(fact.Period.End.Equal(best.Period.End) && fact.Period.Months() > best.Period.Months()) {
+					best = fact
+				}
+			}
+			if best != nil {
+				metrics[metric] = strconv.FormatFloat(best.Value, 'f', -1, 64)
+				break
+			}
+		}
+	}
+
+	doc.KeyMetrics = metrics
+}
+
 // createSemanticChunks creates semantic chunks for vector storage
 func (p *EDGARProcessor) createSemanticChunks(doc *FinancialDocument) {
 	for sectionKey, section := range doc.Sections {
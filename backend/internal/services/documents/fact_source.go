@@ -0,0 +1,114 @@
+package documents
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/gridmate/backend/internal/models"
+	"github.com/gridmate/backend/internal/services/ai"
+)
+
+// SessionUserResolver maps an Excel session to the user that owns it
+type SessionUserResolver func(sessionID string) (uuid.UUID, bool)
+
+// factSource exposes stored filing facts to AI tools
+type factSource struct {
+	service     *DocumentService
+	resolveUser SessionUserResolver
+}
+
+// NewFactSource adapts the document service to ai.FactSource. Facts are
+// scoped to the session's user, so sessions without an authenticated user
+// cannot read any. The SignalR hub only relays a session ID, not the user
+// behind it, so add-in sessions connected through it are not served.
+func NewFactSource(service *DocumentService, resolveUser SessionUserResolver) ai.FactSource {
+	return &factSource{service: service, resolveUser: resolveUser}
+}
+
+func (f *factSource) Serves(sessionID string) bool {
+	if f.resolveUser == nil {
+		return false
+	}
+	_, ok := f.resolveUser(sessionID)
+	return ok
+}
+
+func (f *factSource) FindFacts(ctx context.Context, query ai.FactQuery) ([]ai.FilingFact, error) {
+	if f.service.factRepo == nil {
+		return nil, fmt.Errorf("fact storage not configured")
+	}
+
+	var documentID *uuid.UUID
+	if query.DocumentID != "" {
+		parsed, err := uuid.Parse(query.DocumentID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid document ID: %s", query.DocumentID)
+		}
+		documentID = &parsed
+	}
+
+	if f.resolveUser == nil {
+		return nil, fmt.Errorf("session is not linked to a user")
+	}
+	userID, ok := f.resolveUser(query.SessionID)
+	if !ok {
+		return nil, fmt.Errorf("session is not linked to a user")
+	}
+
+	var records []*models.FinancialFact
+	for _, concept := range query.Concepts {
+		concept := concept
+		found, err := f.service.SearchFacts(ctx, &models.FinancialFactFilter{
+			UserID:     userID,
+			DocumentID: documentID,
+			Concept:    &concept,
+			Limit:      1000,
+		})
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, found...)
+	}
+
+	documents := make(map[uuid.UUID]*models.Document)
+	facts := make([]ai.FilingFact, 0, len(records))
+	for _, record := range records {
+		if record.Value == nil {
+			continue
+		}
+
+		doc, ok := documents[record.DocumentID]
+		if !ok {
+			var err error
+			if doc, err = f.service.docRepo.GetByID(ctx, record.DocumentID); err != nil {
+				f.service.logger.WithError(err).Warn("Failed to get document for fact")
+				doc = nil
+			}
+			documents[record.DocumentID] = doc
+		}
+
+		fact := ai.FilingFact{
+			DocumentID:  record.DocumentID.String(),
+			Concept:     record.Concept,
+			PeriodType:  record.PeriodType,
+			PeriodStart: record.PeriodStart,
+			PeriodEnd:   record.PeriodEnd,
+			Value:       *record.Value,
+		}
+		if record.Unit != nil {
+			fact.Unit = *record.Unit
+		}
+		if record.Page != nil {
+			fact.Page = *record.Page
+		}
+		if doc != nil {
+			fact.DocumentName = doc.Title
+			fact.FiledAt = doc.FilingDate
+		}
+		facts = append(facts, fact)
+	}
+
+	return facts, nil
+}
@@ -0,0 +1,580 @@
+package documents
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Period types for XBRL contexts
+const (
+	PeriodInstant  = "instant"
+	PeriodDuration = "duration"
+	PeriodForever  = "forever"
+)
+
+// maxTextFactLength bounds stored non-numeric facts so text blocks (whole
+// notes tagged as a single fact) are not duplicated into the fact store
+const maxTextFactLength = 1024
+
+// Fact is a single tagged value from an XBRL or inline XBRL filing
+type Fact struct {
+	Concept    string            `json:"concept"` // prefixed QName, e.g. us-gaap:Revenues
+	ContextID  string            `json:"context_id"`
+	Period     FactPeriod        `json:"period"`
+	Dimensions map[string]string `json:"dimensions,omitempty"` // axis -> member
+	UnitID     string            `json:"unit_id,omitempty"`
+	Unit       string            `json:"unit,omitempty"` // e.g. USD, USD/shares
+	Decimals   string            `json:"decimals,omitempty"`
+	Scale      int               `json:"scale,omitempty"`
+	IsNumeric  bool              `json:"is_numeric"`
+	Value      float64           `json:"value,omitempty"` // scaled and signed
+	Text       string            `json:"text,omitempty"`  // non-numeric facts only
+	IsNil      bool              `json:"is_nil,omitempty"`
+	Page       int               `json:"page,omitempty"` // 1-based page of inline facts
+}
+
+// FactPeriod is the reporting period of a fact's context
+type FactPeriod struct {
+	Type  string    `json:"type"`
+	Start time.Time `json:"start,omitempty"`
+	End   time.Time `json:"end,omitempty"` // instant date for instant periods
+}
+
+// Label returns a short column header for the period, e.g. "12M ended 2023-12-31"
+func (p FactPeriod) Label() string {
+	switch p.Type {
+	case PeriodInstant:
+		return "As of " + p.End.Format("2006-01-02")
+	case PeriodDuration:
+		return fmt.Sprintf("%dM ended %s", p.Months(), p.End.Format("2006-01-02"))
+	default:
+		return "Forever"
+	}
+}
+
+// Months returns the approximate length of a duration period in months
+func (p FactPeriod) Months() int {
+	if p.Type != PeriodDuration {
+		return 0
+	}
+	days := p.End.Sub(p.Start).Hours() / 24
+	return int(math.Round(days / 30.44))
+}
+
+// LocalName returns the concept name without its namespace prefix
+func (f Fact) LocalName() string {
+	if idx := strings.Index(f.Concept, ":"); idx >= 0 {
+		return f.Concept[idx+1:]
+	}
+	return f.Concept
+}
+
+// Prefix returns the concept's namespace prefix
+func (f Fact) Prefix() string {
+	if idx := strings.Index(f.Concept, ":"); idx >= 0 {
+		return f.Concept[:idx]
+	}
+	return ""
+}
+
+// XBRLParser extracts facts from XBRL instance documents and inline XBRL
+// (iXBRL) HTML filings
+type XBRLParser struct{}
+
+// NewXBRLParser creates a new XBRL parser
+func NewXBRLParser() *XBRLParser {
+	return &XBRLParser{}
+}
+
+// IsXBRL reports whether content looks like an XBRL instance or iXBRL filing
+func IsXBRL(content string) bool {
+	head := content
+	if len(head) > 200000 {
+		head = head[:200000]
+	}
+	lower := strings.ToLower(head)
+	return strings.Contains(lower, "<ix:nonfraction") ||
+		strings.Contains(lower, "<ix:header") ||
+		strings.Contains(lower, "<xbrli:xbrl") ||
+		strings.Contains(lower, "xmlns=\"http://www.xbrl.org/2003/instance\"")
+}
+
+type xbrlContext struct {
+	period     FactPeriod
+	dimensions map[string]string
+}
+
+// factCapture collects the text of a fact element while it is open
+type factCapture struct {
+	depth   int
+	attrs   map[string]string
+	name    string
+	inline  bool
+	numeric bool
+	page    int
+	text    strings.Builder
+}
+
+// Parse extracts all facts from the content
+func (p *XBRLParser) Parse(content string) ([]Fact, error) {
+	decoder := xml.NewDecoder(strings.NewReader(content))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	contexts := make(map[string]*xbrlContext)
+	units := make(map[string]string)
+	prefixes := make(map[string]string) // namespace URI -> prefix
+
+	var (
+		captures    []*factCapture
+		raw         []*factCapture
+		depth       int
+		curContext  *xbrlContext
+		curUnitID   string
+		numerators  []string
+		denominator []string
+		inDenom     bool
+		curDim      string
+		field       string
+		text        strings.Builder
+		page        = 1
+		breakAfter  []int // Depths of open elements that end a page
+	)
+
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse XBRL: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			attrs := make(map[string]string, len(t.Attr))
+			for _, a := range t.Attr {
+				if a.Name.Space == "xmlns" {
+					prefixes[a.Value] = a.Name.Local
+					continue
+				}
+				attrs[a.Name.Local] = a.Value
+			}
+
+			// Inline filings mark printed pages with CSS page breaks
+			if style := attrs["style"]; style != "" {
+				before, after := pageBreaks(style)
+				if before {
+					page++
+				}
+				if after {
+					breakAfter = append(breakAfter, depth)
+				}
+			}
+
+			switch t.Name.Local {
+			case "context":
+				if id := attrs["id"]; id != "" {
+					curContext = &xbrlContext{dimensions: make(map[string]string)}
+					contexts[id] = curContext
+				}
+				continue
+			case "unit":
+				curUnitID = attrs["id"]
+				numerators, denominator, inDenom = nil, nil, false
+				continue
+			case "unitDenominator":
+				inDenom = true
+				continue
+			case "instant", "startDate", "endDate", "measure":
+				field = t.Name.Local
+				text.Reset()
+				continue
+			case "explicitMember", "typedMember":
+				curDim = attrs["dimension"]
+				field = "member"
+				text.Reset()
+				continue
+			case "forever":
+				if curContext != nil {
+					curContext.period.Type = PeriodForever
+				}
+				continue
+			}
+
+			isInline := t.Name.Local == "nonFraction" || t.Name.Local == "nonNumeric"
+			if isInline && attrs["name"] != "" && attrs["contextRef"] != "" {
+				captures = append(captures, &factCapture{
+					depth:   depth,
+					attrs:   attrs,
+					name:    attrs["name"],
+					inline:  true,
+					numeric: t.Name.Local == "nonFraction",
+					page:    page,
+				})
+			} else if !isInline && attrs["contextRef"] != "" {
+				prefix := prefixes[t.Name.Space]
+				if prefix == "" {
+					prefix = t.Name.Space
+				}
+				captures = append(captures, &factCapture{
+					depth:   depth,
+					attrs:   attrs,
+					name:    prefix + ":" + t.Name.Local,
+					numeric: attrs["unitRef"] != "",
+				})
+			}
+
+		case xml.CharData:
+			if field != "" {
+				text.Write(t)
+			}
+			for _, c := range captures {
+				c.text.Write(t)
+			}
+
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "context":
+				curContext = nil
+			case "unit":
+				unit := strings.Join(numerators, "*")
+				if len(denominator) > 0 {
+					unit += "/" + strings.Join(denominator, "*")
+				}
+				if curUnitID != "" {
+					units[curUnitID] = unit
+				}
+				curUnitID = ""
+			case "unitDenominator":
+				inDenom = false
+			case "instant", "startDate", "endDate":
+				if curContext != nil {
+					date := parseXBRLDate(strings.TrimSpace(text.String()))
+					switch t.Name.Local {
+					case "instant":
+						curContext.period = FactPeriod{Type: PeriodInstant, End: date}
+					case "startDate":
+						curContext.period.Type = PeriodDuration
+						curContext.period.Start = date
+					case "endDate":
+						curContext.period.Type = PeriodDuration
+						curContext.period.End = date
+					}
+				}
+				field = ""
+			case "measure":
+				measure := stripMeasurePrefix(strings.TrimSpace(text.String()))
+				if inDenom {
+					denominator = append(denominator, measure)
+				} else {
+					numerators = append(numerators, measure)
+				}
+				field = ""
+			case "explicitMember", "typedMember":
+				if curContext != nil && curDim != "" {
+					curContext.dimensions[curDim] = strings.TrimSpace(text.String())
+				}
+				field, curDim = "", ""
+			}
+
+			if n := len(captures); n > 0 && captures[n-1].depth == depth {
+				raw = append(raw, captures[n-1])
+				captures = captures[:n-1]
+			}
+			if n := len(breakAfter); n > 0 && breakAfter[n-1] == depth {
+				page++
+				breakAfter = breakAfter[:n-1]
+			}
+			depth--
+		}
+	}
+
+	facts := make([]Fact, 0, len(raw))
+	for _, c := range raw {
+		fact, ok := p.buildFact(c, contexts, units)
+		if ok {
+			facts = append(facts, fact)
+		}
+	}
+
+	return facts, nil
+}
+
+// pageBreaks reports whether a CSS style starts a new page before or after
+// its element
+func pageBreaks(style string) (before, after bool) {
+	style = strings.ToLower(strings.ReplaceAll(style, " ", ""))
+	before = strings.Contains(style, "page-break-before:always") || strings.Contains(style, "break-before:page")
+	after = strings.Contains(style, "page-break-after:always") || strings.Contains(style, "break-after:page")
+	return before, after
+}
+
+func (p *XBRLParser) buildFact(c *factCapture, contexts map[string]*xbrlContext, units map[string]string) (Fact, bool) {
+	fact := Fact{
+		Page:      c.page,
+		Concept:   c.name,
+		ContextID: c.attrs["contextRef"],
+		UnitID:    c.attrs["unitRef"],
+		Decimals:  c.attrs["decimals"],
+		IsNumeric: c.numeric,
+		IsNil:     c.attrs["nil"] == "true",
+	}
+
+	if ctx, ok := contexts[fact.ContextID]; ok {
+		fact.Period = ctx.period
+		if len(ctx.dimensions) > 0 {
+			fact.Dimensions = ctx.dimensions
+		}
+	}
+	if fact.UnitID != "" {
+		fact.Unit = units[fact.UnitID]
+		if fact.Unit == "" {
+			fact.Unit = fact.UnitID
+		}
+	}
+
+	raw := strings.TrimSpace(c.text.String())
+
+	if !fact.IsNumeric {
+		if len(raw) > maxTextFactLength {
+			return fact, false
+		}
+		fact.Text = collapseWhitespace(raw)
+		return fact, true
+	}
+
+	if fact.IsNil {
+		return fact, true
+	}
+
+	if c.attrs["scale"] != "" {
+		scale, err := strconv.Atoi(c.attrs["scale"])
+		if err == nil {
+			fact.Scale = scale
+		}
+	}
+
+	value, ok := parseFactNumber(raw, c.attrs["format"])
+	if !ok {
+		return fact, false
+	}
+	if fact.Scale != 0 {
+		value *= math.Pow10(fact.Scale)
+	}
+	if c.inline && c.attrs["sign"] == "-" {
+		value = -value
+	}
+	fact.Value = value
+
+	return fact, true
+}
+
+var nonNumericChars = regexp.MustCompile(`[^0-9.,\-]`)
+
+// parseFactNumber converts a displayed value to a number using the iXBRL
+// transformation format. Values shown in parentheses are treated as negative.
+func parseFactNumber(raw, format string) (float64, bool) {
+	s := strings.TrimSpace(raw)
+	format = strings.ToLower(format)
+
+	if strings.Contains(format, "zerodash") || strings.Contains(format, "fixed-zero") ||
+		s == "-" || s == "—" || s == "–" {
+		return 0, true
+	}
+
+	negative := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative = true
+		s = strings.TrimSuffix(strings.TrimPrefix(s, "("), ")")
+	}
+
+	s = nonNumericChars.ReplaceAllString(s, "")
+	if strings.HasPrefix(s, "-") {
+		negative = !negative
+		s = strings.TrimPrefix(s, "-")
+	}
+
+	if strings.Contains(format, "numcommadecimal") || strings.Contains(format, "num-comma-decimal") {
+		s = strings.ReplaceAll(s, ".", "")
+		s = strings.ReplaceAll(s, ",", ".")
+	} else {
+		s = strings.ReplaceAll(s, ",", "")
+	}
+
+	if s == "" {
+		return 0, false
+	}
+
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	if negative {
+		value = -value
+	}
+	return value, true
+}
+
+func parseXBRLDate(s string) time.Time {
+	layouts := []string{"2006-01-02", "2006-01-02T15:04:05", "January 2, 2006", "Jan. 2, 2006", "Jan 2, 2006", "01/02/2006"}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func stripMeasurePrefix(measure string) string {
+	if idx := strings.Index(measure, ":"); idx >= 0 {
+		return measure[idx+1:]
+	}
+	return measure
+}
+
+func collapseWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// cashFlowConceptMarkers identify duration concepts that belong on the cash
+// flow statement rather than the income statement
+var cashFlowConceptMarkers = []string{
+	"NetCashProvidedBy", "CashProvidedBy", "CashUsedIn", "PaymentsTo", "PaymentsFor",
+	"PaymentsOf", "ProceedsFrom", "RepaymentsOf", "IncreaseDecreaseIn",
+	"CashAndCashEquivalentsPeriodIncreaseDecrease", "CashCashEquivalentsRestrictedCashAndRestrictedCashEquivalentsPeriodIncreaseDecrease",
+}
+
+// statementType assigns a fact to income_statement, balance_sheet or cash_flow
+func statementType(fact Fact) string {
+	if fact.Period.Type == PeriodInstant {
+		return "balance_sheet"
+	}
+	local := fact.LocalName()
+	for _, marker := range cashFlowConceptMarkers {
+		if strings.Contains(local, marker) {
+			return "cash_flow"
+		}
+	}
+	return "income_statement"
+}
+
+var statementTitles = map[string]string{
+	"income_statement": "Income Statement",
+	"balance_sheet":    "Balance Sheet",
+	"cash_flow":        "Cash Flow Statement",
+}
+
+// BuildFactTables arranges non-dimensional numeric facts into one table per
+// statement, with a column per period (most recent first) and a row per concept
+func BuildFactTables(facts []Fact) []FinancialTable {
+	type cellKey struct{ concept, period string }
+
+	grouped := make(map[string][]Fact)
+	for _, fact := range facts {
+		if !fact.IsNumeric || fact.IsNil || len(fact.Dimensions) > 0 || fact.Prefix() == "dei" {
+			continue
+		}
+		if fact.Period.Type != PeriodInstant && fact.Period.Type != PeriodDuration {
+			continue
+		}
+		grouped[statementType(fact)] = append(grouped[statementType(fact)], fact)
+	}
+
+	var tables []FinancialTable
+	for _, tableType := range []string{"income_statement", "balance_sheet", "cash_flow"} {
+		group := grouped[tableType]
+		if len(group) == 0 {
+			continue
+		}
+
+		periods := make(map[string]FactPeriod)
+		values := make(map[cellKey]float64)
+		unitCounts := make(map[string]int)
+		var concepts []string
+		seenConcept := make(map[string]bool)
+
+		for _, fact := range group {
+			label := fact.Period.Label()
+			key := cellKey{fact.Concept, label}
+			if _, dup := values[key]; dup {
+				continue
+			}
+			values[key] = fact.Value
+			periods[label] = fact.Period
+			unitCounts[fact.Unit]++
+			if !seenConcept[fact.Concept] {
+				seenConcept[fact.Concept] = true
+				concepts = append(concepts, fact.Concept)
+			}
+		}
+
+		periodLabels := make([]string, 0, len(periods))
+		for label := range periods {
+			periodLabels = append(periodLabels, label)
+		}
+		sort.Slice(periodLabels, func(i, j int) bool {
+			pi, pj := periods[periodLabels[i]], periods[periodLabels[j]]
+			if !pi.End.Equal(pj.End) {
+				return pi.End.After(pj.End)
+			}
+			return pi.Months() > pj.Months()
+		})
+
+		table := FinancialTable{
+			ID:       generateTableID(),
+			Title:    statementTitles[tableType],
+			Headers:  append([]string{"Item"}, periodLabels...),
+			Type:     tableType,
+			Period:   periodLabels[0],
+			Units:    dominantUnit(unitCounts),
+			Source:   "xbrl",
+			Concepts: concepts,
+		}
+
+		for _, concept := range concepts {
+			row := []string{humanizeConcept(concept)}
+			for _, label := range periodLabels {
+				if v, ok := values[cellKey{concept, label}]; ok {
+					row = append(row, strconv.FormatFloat(v, 'f', -1, 64))
+				} else {
+					row = append(row, "")
+				}
+			}
+			table.Rows = append(table.Rows, row)
+		}
+
+		tables = append(tables, table)
+	}
+
+	return tables
+}
+
+func dominantUnit(counts map[string]int) string {
+	best, bestCount := "", 0
+	for unit, count := range counts {
+		if count > bestCount || (count == bestCount && unit < best) {
+			best, bestCount = unit, count
+		}
+	}
+	return best
+}
+
+var camelBoundary = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+
+// humanizeConcept turns us-gaap:NetIncomeLoss into "Net Income Loss"
+func humanizeConcept(concept string) string {
+	if idx := strings.Index(concept, ":"); idx >= 0 {
+		concept = concept[idx+1:]
+	}
+	return camelBoundary.ReplaceAllString(concept, "$1 $2")
+}
@@ -0,0 +1,179 @@
+package documents
+
+import (
+	"testing"
+)
+
+const testInlineXBRL = `<html xmlns="http://www.w3.org/1999/xhtml"
+	xmlns:ix="http://www.xbrl.org/2013/inlineXBRL"
+	xmlns:xbrli="http://www.xbrl.org/2003/instance"
+	xmlns:xbrldi="http://xbrl.org/2006/xbrldi"
+	xmlns:iso4217="http://www.xbrl.org/2003/iso4217"
+	xmlns:us-gaap="http://fasb.org/us-gaap/2023"
+	xmlns:dei="http://xbrl.sec.gov/dei/2023">
+<body>
+<div style="display:none"><ix:header><ix:hidden>
+	<ix:nonNumeric name="dei:EntityRegistrantName" contextRef="FY2023">Example Corp</ix:nonNumeric>
+	<ix:nonNumeric name="dei:DocumentPeriodEndDate" contextRef="FY2023">2023-12-31</ix:nonNumeric>
+</ix:hidden><ix:resources>
+	<xbrli:context id="FY2023"><xbrli:entity><xbrli:identifier scheme="http://www.sec.gov/CIK">0000123</xbrli:identifier></xbrli:entity>
+		<xbrli:period><xbrli:startDate>2023-01-01</xbrli:startDate><xbrli:endDate>2023-12-31</xbrli:endDate></xbrli:period></xbrli:context>
+	<xbrli:context id="FY2022"><xbrli:entity><xbrli:identifier scheme="http://www.sec.gov/CIK">0000123</xbrli:identifier></xbrli:entity>
+		<xbrli:period><xbrli:startDate>2022-01-01</xbrli:startDate><xbrli:endDate>2022-12-31</xbrli:endDate></xbrli:period></xbrli:context>
+	<xbrli:context id="I2023"><xbrli:entity><xbrli:identifier scheme="http://www.sec.gov/CIK">0000123</xbrli:identifier></xbrli:entity>
+		<xbrli:period><xbrli:instant>2023-12-31</xbrli:instant></xbrli:period></xbrli:context>
+	<xbrli:context id="FY2023_Seg"><xbrli:entity><xbrli:identifier scheme="http://www.sec.gov/CIK">0000123</xbrli:identifier>
+		<xbrli:segment><xbrldi:explicitMember dimension="us-gaap:StatementBusinessSegmentsAxis">ex:CloudMember</xbrldi:explicitMember></xbrli:segment></xbrli:entity>
+		<xbrli:period><xbrli:startDate>2023-01-01</xbrli:startDate><xbrli:endDate>2023-12-31</xbrli:endDate></xbrli:period></xbrli:context>
+	<xbrli:unit id="USD"><xbrli:measure>iso4217:USD</xbrli:measure></xbrli:unit>
+	<xbrli:unit id="USDPerShare"><xbrli:divide><xbrli:unitNumerator><xbrli:measure>iso4217:USD</xbrli:measure></xbrli:unitNumerator>
+		<xbrli:unitDenominator><xbrli:measure>xbrli:shares</xbrli:measure></xbrli:unitDenominator></xbrli:divide></xbrli:unit>
+</ix:resources></ix:header></div>
+<table>
+	<tr><td>Revenue</td>
+		<td>$<ix:nonFraction name="us-gaap:Revenues" contextRef="FY2023" unitRef="USD" decimals="-6" scale="6" format="ixt:num-dot-decimal">1,234.5</ix:nonFraction></td>
+		<td>$<ix:nonFraction name="us-gaap:Revenues" contextRef="FY2022" unitRef="USD" decimals="-6" scale="6">1,100</ix:nonFraction></td></tr>
+	<tr><td>Net loss</td>
+		<td>(<ix:nonFraction name="us-gaap:NetIncomeLoss" contextRef="FY2023" unitRef="USD" decimals="-6" scale="6" sign="-">45</ix:nonFraction>)</td></tr>
+</table>
+<hr style="page-break-after: always"/>
+<table>
+	<tr><td>Diluted EPS</td>
+		<td><ix:nonFraction name="us-gaap:EarningsPerShareDiluted" contextRef="FY2023" unitRef="USDPerShare" decimals="2">(0.12)</ix:nonFraction></td></tr>
+	<tr><td>Cloud revenue</td>
+		<td><ix:nonFraction name="us-gaap:Revenues" contextRef="FY2023_Seg" unitRef="USD" decimals="-6" scale="6">800</ix:nonFraction></td></tr>
+	<tr><td>Total assets</td>
+		<td><ix:nonFraction name="us-gaap:Assets" contextRef="I2023" unitRef="USD" decimals="-6" scale="6"><span>9,876</span></ix:nonFraction></td></tr>
+	<tr><td>Share repurchases</td>
+		<td><ix:nonFraction name="us-gaap:PaymentsForRepurchaseOfCommonStock" contextRef="FY2023" unitRef="USD" scale="6" format="ixt:fixed-zero">—</ix:nonFraction></td></tr>
+</table>
+</body></html>`
+
+func TestXBRLParser_InlineFacts(t *testing.T) {
+	facts, err := NewXBRLParser().Parse(testInlineXBRL)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	find := func(concept, contextID string) *Fact {
+		for i := range facts {
+			if facts[i].Concept == concept && facts[i].ContextID == contextID {
+				return &facts[i]
+			}
+		}
+		return nil
+	}
+
+	tests := []struct {
+		name      string
+		concept   string
+		contextID string
+		value     float64
+		unit      string
+		months    int
+		page      int
+	}{
+		{"scaled value", "us-gaap:Revenues", "FY2023", 1234500000, "USD", 12, 1},
+		{"prior period", "us-gaap:Revenues", "FY2022", 1100000000, "USD", 12, 1},
+		{"sign attribute", "us-gaap:NetIncomeLoss", "FY2023", -45000000, "USD", 12, 1},
+		{"parentheses", "us-gaap:EarningsPerShareDiluted", "FY2023", -0.12, "USD/shares", 12, 2},
+		{"nested markup", "us-gaap:Assets", "I2023", 9876000000, "USD", 0, 2},
+		{"zero dash", "us-gaap:PaymentsForRepurchaseOfCommonStock", "FY2023", 0, "USD", 12, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fact := find(tt.concept, tt.contextID)
+			if fact == nil {
+				t.Fatalf("fact %s/%s not found", tt.concept, tt.contextID)
+			}
+			if fact.Value != tt.value {
+				t.Errorf("Value = %v, want %v", fact.Value, tt.value)
+			}
+			if fact.Unit != tt.unit {
+				t.Errorf("Unit = %q, want %q", fact.Unit, tt.unit)
+			}
+			if fact.Period.Months() != tt.months {
+				t.Errorf("Months() = %d, want %d", fact.Period.Months(), tt.months)
+			}
+			if fact.Page != tt.page {
+				t.Errorf("Page = %d, want %d", fact.Page, tt.page)
+			}
+		})
+	}
+
+	segment := find("us-gaap:Revenues", "FY2023_Seg")
+	if segment == nil || segment.Dimensions["us-gaap:StatementBusinessSegmentsAxis"] != "ex:CloudMember" {
+		t.Errorf("dimensional fact not parsed: %+v", segment)
+	}
+
+	name := find("dei:EntityRegistrantName", "FY2023")
+	if name == nil || name.Text != "Example Corp" {
+		t.Errorf("dei:EntityRegistrantName not parsed: %+v", name)
+	}
+}
+
+func TestXBRLParser_InstanceDocument(t *testing.T) {
+	instance := `<?xml version="1.0" encoding="UTF-8"?>
+<xbrli:xbrl xmlns:xbrli="http://www.xbrl.org/2003/instance" xmlns:us-gaap="http://fasb.org/us-gaap/2023">
+	<xbrli:context id="c1"><xbrli:entity><xbrli:identifier scheme="http://www.sec.gov/CIK">1</xbrli:identifier></xbrli:entity>
+		<xbrli:period><xbrli:startDate>2023-07-01</xbrli:startDate><xbrli:endDate>2023-09-30</xbrli:endDate></xbrli:period></xbrli:context>
+	<xbrli:unit id="usd"><xbrli:measure>iso4217:USD</xbrli:measure></xbrli:unit>
+	<us-gaap:Revenues contextRef="c1" unitRef="usd" decimals="-3">-2500000</us-gaap:Revenues>
+</xbrli:xbrl>`
+
+	facts, err := NewXBRLParser().Parse(instance)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(facts) != 1 {
+		t.Fatalf("got %d facts, want 1", len(facts))
+	}
+
+	fact := facts[0]
+	if fact.Concept != "us-gaap:Revenues" || fact.Value != -2500000 || fact.Decimals != "-3" || fact.Period.Months() != 3 {
+		t.Errorf("unexpected fact: %+v", fact)
+	}
+}
+
+func TestBuildFactTables(t *testing.T) {
+	facts, err := NewXBRLParser().Parse(testInlineXBRL)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	tables := BuildFactTables(facts)
+	byType := make(map[string]FinancialTable)
+	for _, table := range tables {
+		byType[table.Type] = table
+	}
+
+	income, ok := byType["income_statement"]
+	if !ok {
+		t.Fatal("income statement not built")
+	}
+	wantHeaders := []string{"Item", "12M ended 2023-12-31", "12M ended 2022-12-31"}
+	if len(income.Headers) != len(wantHeaders) {
+		t.Fatalf("Headers = %v, want %v", income.Headers, wantHeaders)
+	}
+	for i := range wantHeaders {
+		if income.Headers[i] != wantHeaders[i] {
+			t.Errorf("Headers[%d] = %q, want %q", i, income.Headers[i], wantHeaders[i])
+		}
+	}
+	if income.Rows[0][0] != "Revenues" || income.Rows[0][1] != "1234500000" || income.Rows[0][2] != "1100000000" {
+		t.Errorf("unexpected revenue row: %v", income.Rows[0])
+	}
+	for _, concept := range income.Concepts {
+		if concept == "us-gaap:PaymentsForRepurchaseOfCommonStock" {
+			t.Error("cash flow concept placed on income statement")
+		}
+	}
+
+	if _, ok := byType["balance_sheet"]; !ok {
+		t.Error("balance sheet not built")
+	}
+	if _, ok := byType["cash_flow"]; !ok {
+		t.Error("cash flow statement not built")
+	}
+}
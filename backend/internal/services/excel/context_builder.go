@@ -30,6 +30,8 @@ type ContextBuilder struct {
 	semanticAnalyzer *spreadsheet.SemanticAnalyzer
 	// Cached context provider for streaming mode
 	cachedContextProvider *CachedContextProvider
+	// Notified with the cells that changed between incremental builds
+	changeListener func(sessionID string, changes []ai.CellChange)
 }
 
 // CellChangeInfo tracks changes to individual cells
@@ -55,6 +57,12 @@ func NewContextBuilder(bridge ai.ExcelBridge) *ContextBuilder {
 	}
 }
 
+// SetChangeListener registers a callback for cell changes detected by
+// BuildIncrementalContext
+func (cb *ContextBuilder) SetChangeListener(listener func(sessionID string, changes []ai.CellChange)) {
+	cb.changeListener = listener
+}
+
 // UpdateCachedContext updates the cached context based on tool execution results
 func (cb *ContextBuilder) UpdateCachedContext(sessionID string, toolName string, result interface{}) {
 	if cb.cachedContextProvider != nil {
@@ -1400,7 +1408,10 @@ func (cb *ContextBuilder) BuildIncrementalContext(ctx context.Context, sessionID
 	}
 
 	// Track changes
-	cb.trackCellChanges(newContext, currentContext)
+	changes := cb.trackCellChanges(newContext, currentContext)
+	if cb.changeListener != nil && len(changes) > 0 {
+		cb.changeListener(sessionID, changes)
+	}
 
 	// Re-analyze if structure changed significantly
 	if cb.hasSignificantChanges(newContext.RecentChanges) {
@@ -1519,7 +1530,7 @@ func (cb *ContextBuilder) updateCellInContext(ctx context.Context, sessionID str
 	return nil
 }
 
-func (cb *ContextBuilder) trackCellChanges(newContext, oldContext *ai.FinancialContext) {
+func (cb *ContextBuilder) trackCellChanges(newContext, oldContext *ai.FinancialContext) []ai.CellChange {
 	changes := cb.TrackCellChanges(newContext, oldContext)
 
 	// Keep only recent changes (last 10)
@@ -1527,6 +1538,8 @@ func (cb *ContextBuilder) trackCellChanges(newContext, oldContext *ai.FinancialC
 	if len(newContext.RecentChanges) > 10 {
 		newContext.RecentChanges = newContext.RecentChanges[:10]
 	}
+
+	return changes
 }
 
 func (cb *ContextBuilder) hasSignificantChanges(changes []ai.CellChange) bool {
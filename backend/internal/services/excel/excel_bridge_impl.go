@@ -16,6 +16,7 @@ import (
 // BridgeImpl implements the ExcelBridge interface for AI tool execution
 type BridgeImpl struct {
 	getClientID   func(sessionID string) string
+	getSession    func(sessionID string) *ai.Session
 	signalRBridge interface{}
 	logger        zerolog.Logger
 	// Tool handlers for managing async responses
@@ -52,6 +53,11 @@ func (b *BridgeImpl) SetClientIDResolver(resolver func(sessionID string) string)
 	b.getClientID = resolver
 }
 
+// SetSessionResolver sets the function to look up sessions by ID
+func (b *BridgeImpl) SetSessionResolver(resolver func(sessionID string) *ai.Session) {
+	b.getSession = resolver
+}
+
 // SetSignalRBridge sets the SignalR bridge for sending tool requests
 func (b *BridgeImpl) SetSignalRBridge(bridge interface{}) {
 	b.signalRBridge = bridge
@@ -818,9 +824,10 @@ func (b *BridgeImpl) cleanupQueuedResponses() {
 	}
 }
 
-// GetSession returns a session (placeholder implementation)
+// GetSession returns a session, or nil when it is not known
 func (b *BridgeImpl) GetSession(sessionID string) *ai.Session {
-	// TODO: Implement actual session retrieval logic
-	// For now, return nil or a basic session
-	return nil
+	if b.getSession == nil {
+		return nil
+	}
+	return b.getSession(sessionID)
 }
@@ -15,6 +15,7 @@ import (
 	"github.com/gridmate/backend/internal/services/chat"
 	"github.com/gridmate/backend/internal/services/excel"
 	"github.com/gridmate/backend/internal/services/formula"
+	"github.com/gridmate/backend/internal/services/indexing"
 	"github.com/sirupsen/logrus"
 )
 
@@ -55,6 +56,12 @@ type ExcelBridge struct {
 	// Indexing service for vector memory
 	indexingService interface{} // Will be set by main.go
 
+	// Shared Postgres memory store; when set, sessions use scoped views of it
+	sharedMemory *memory.PostgresStore
+
+	// Extra options for per-session memory stores, such as TTL policies
+	memoryOptions []memory.Option
+
 	// Tool response handler for streaming
 	toolResponseHandler interface{} // Will be set by main.go
 
@@ -78,6 +85,7 @@ type ExcelSession struct {
 	UserID       string
 	ClientID     string // SignalR client ID for routing messages
 	ActiveSheet  string
+	Workbook     string // Workbook name reported with chat messages
 	Selection    SelectionChanged
 	Context      map[string]interface{}
 	LastActivity time.Time
@@ -85,8 +93,9 @@ type ExcelSession struct {
 	LastRefresh  time.Time
 
 	// Memory support
-	MemoryStore *memory.VectorStore `json:"-"` // Don't serialize
-	MemoryStats *MemoryStats        // Statistics for UI
+	MemoryStore     *memory.VectorStore `json:"-"` // Don't serialize
+	MemoryNamespace memory.Namespace    `json:"-"` // Scope of the session's chunks in MemoryStore
+	MemoryStats     *MemoryStats        // Statistics for UI
 }
 
 // MemoryStats contains memory statistics for the session
@@ -157,6 +166,24 @@ func NewExcelBridge(logger *logrus.Logger, aiService *ai.Service) *ExcelBridge {
 		return ""
 	})
 
+	// Set session resolver for tools that need the session's memory or workbook
+	excelBridgeImpl.SetSessionResolver(func(sessionID string) *ai.Session {
+		bridge.sessionMutex.RLock()
+		defer bridge.sessionMutex.RUnlock()
+
+		session, ok := bridge.sessions[sessionID]
+		if !ok {
+			return nil
+		}
+		return &ai.Session{
+			UserID:          session.UserID,
+			MemoryStore:     session.MemoryStore,
+			MemoryNamespace: session.MemoryNamespace,
+			Workbook:        session.Workbook,
+			ActiveSheet:     session.ActiveSheet,
+		}
+	})
+
 	// Create formula validator
 	formulaValidator := formula.NewFormulaIntelligence(logger)
 
@@ -168,6 +195,11 @@ func NewExcelBridge(logger *logrus.Logger, aiService *ai.Service) *ExcelBridge {
 
 	// Set the queued operations registry on the tool executor
 	bridge.toolExecutor.SetQueuedOperationRegistry(bridge.queuedOpsRegistry)
+	bridge.queuedOpsRegistry.SetToolRegistry(bridge.toolExecutor.Registry())
+	bridge.queuedOpsRegistry.SetSessionResolver(func(sessionID string) (string, string) {
+		workspaceID, _ := bridge.toolExecutor.SessionWorkspace(context.Background(), sessionID)
+		return bridge.SessionOwner(sessionID), workspaceID
+	})
 
 	// Set tool executor in AI service
 	if aiService != nil {
@@ -209,21 +241,25 @@ func (eb *ExcelBridge) CreateSignalRSession(sessionID string) {
 		now := time.Now()
 
 		// Initialize with memory store
-		var memStore memory.VectorStore = memory.NewHybridVectorStore(
-			memory.WithInMemoryCache(10000), // 10k chunks max
-			memory.WithDiskPersistence(fmt.Sprintf("./data/sessions/%s.db", sessionID)),
-			memory.WithAutoSave(5*time.Minute),
-		)
+		memStore := eb.newSessionMemoryStore(func() memory.VectorStore {
+			opts := []memory.Option{
+				memory.WithInMemoryCache(10000), // 10k chunks max
+				memory.WithDiskPersistence(fmt.Sprintf("./data/sessions/%s.db", sessionID)),
+				memory.WithAutoSave(5*time.Minute),
+			}
+			return memory.NewHybridVectorStore(append(opts, eb.memoryOptions...)...)
+		})
 
 		eb.sessions[sessionID] = &ExcelSession{
-			ID:           sessionID,
-			UserID:       "signalr-user",
-			ClientID:     sessionID, // Use session ID as client ID for SignalR
-			ActiveSheet:  "Sheet1",
-			Context:      make(map[string]interface{}),
-			LastActivity: now,
-			CreatedAt:    now,
-			MemoryStore:  &memStore,
+			ID:              sessionID,
+			UserID:          "signalr-user",
+			ClientID:        sessionID, // Use session ID as client ID for SignalR
+			ActiveSheet:     "Sheet1",
+			Context:         make(map[string]interface{}),
+			LastActivity:    now,
+			CreatedAt:       now,
+			MemoryStore:     memStore,
+			MemoryNamespace: sessionNamespace(sessionID, "signalr-user"),
 			MemoryStats:  &MemoryStats{IndexVersion: "1.0"},
 		}
 
@@ -294,6 +330,97 @@ func (eb *ExcelBridge) SetSignalRBridge(bridge interface{}) {
 // SetIndexingService sets the indexing service instance
 func (eb *ExcelBridge) SetIndexingService(service interface{}) {
 	eb.indexingService = service
+	if eb.contextBuilder != nil {
+		eb.contextBuilder.SetChangeListener(func(sessionID string, changes []ai.CellChange) {
+			cells := make([]string, 0, len(changes))
+			for _, change := range changes {
+				cells = append(cells, change.Address)
+			}
+			go eb.ReindexChangedCells(sessionID, cells)
+		})
+	}
+}
+
+// SetSharedMemoryStore makes new sessions store memory in the shared
+// Postgres index instead of per-session in-process stores
+func (eb *ExcelBridge) SetSharedMemoryStore(store *memory.PostgresStore) {
+	eb.sharedMemory = store
+}
+
+// SetMemoryOptions adds options to the stores created for new sessions
+func (eb *ExcelBridge) SetMemoryOptions(opts ...memory.Option) {
+	eb.memoryOptions = opts
+}
+
+// newSessionMemoryStore returns the shared store, or fallback when no
+// shared store is configured. Sessions stay isolated through their
+// MemoryNamespace.
+func (eb *ExcelBridge) newSessionMemoryStore(fallback func() memory.VectorStore) *memory.VectorStore {
+	var store memory.VectorStore
+	if eb.sharedMemory != nil {
+		store = eb.sharedMemory
+	} else {
+		store = fallback()
+	}
+	return &store
+}
+
+// sessionNamespace scopes a session's memory to its user
+func sessionNamespace(sessionID, userID string) memory.Namespace {
+	if userID == "" {
+		userID = "anonymous"
+	}
+	return memory.SessionNamespace(userID, sessionID)
+}
+
+// PurgeMemory deletes every memory chunk in ns from the shared store and
+// from the stores of live sessions it covers. Purging a tenant namespace
+// removes all of the tenant's sessions.
+func (eb *ExcelBridge) PurgeMemory(ns memory.Namespace) (int, error) {
+	if err := ns.Validate(); err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	if eb.sharedMemory != nil {
+		n, err := eb.sharedMemory.Purge(ns)
+		if err != nil {
+			return 0, err
+		}
+		removed += n
+	}
+
+	eb.sessionMutex.Lock()
+	defer eb.sessionMutex.Unlock()
+
+	for _, session := range eb.sessions {
+		if !ns.Covers(session.MemoryNamespace) {
+			continue
+		}
+		if session.MemoryStore != nil && eb.sharedMemory == nil {
+			n, err := (*session.MemoryStore).Purge(session.MemoryNamespace)
+			if err != nil {
+				return removed, err
+			}
+			removed += n
+		}
+		if forgetter, ok := eb.indexingService.(interface{ ForgetSession(memory.Namespace) }); ok {
+			forgetter.ForgetSession(session.MemoryNamespace)
+		}
+		if session.MemoryStats != nil {
+			session.MemoryStats.TotalChunks = 0
+			session.MemoryStats.SpreadsheetChunks = 0
+			session.MemoryStats.DocumentChunks = 0
+			session.MemoryStats.ChatChunks = 0
+		}
+	}
+
+	eb.logger.WithFields(logrus.Fields{
+		"namespace": ns.String(),
+		"removed":   removed,
+	}).Info("Purged memory namespace")
+
+	return removed, nil
 }
 
 // InjectToolResultToStream injects a tool result into an active streaming session
@@ -465,19 +592,10 @@ func (eb *ExcelBridge) GetQueuedOperationRegistry() *QueuedOperationRegistry {
 	return eb.queuedOpsRegistry
 }
 
-// isWriteTool checks if a tool name represents a write operation
-func isWriteTool(toolName string) bool {
-	writeTools := []string{
-		"write_cell", "write_range", "write_formula",
-		"write_multiple_cells", "create_chart", "format_cells",
-		"insert_rows", "insert_columns", "delete_rows", "delete_columns",
-	}
-	for _, wt := range writeTools {
-		if toolName == wt {
-			return true
-		}
-	}
-	return false
+// isWriteTool checks if a tool modifies the workbook, using the executor's
+// registry so external tools are classified too
+func (eb *ExcelBridge) isWriteTool(toolName string) bool {
+	return eb.toolExecutor != nil && eb.toolExecutor.Registry().IsWrite(toolName)
 }
 
 // hasRecentUserSelection checks if the user has made a recent selection
@@ -514,6 +632,7 @@ func (eb *ExcelBridge) ProcessChatMessage(clientID string, message ChatMessage)
 
 	// Get or create session
 	session := eb.getOrCreateSession(clientID, message.SessionID)
+	eb.trackWorkbook(session, message.Context)
 
 	// Build comprehensive context BEFORE adding message to history
 	var financialContext *ai.FinancialContext
@@ -540,6 +659,9 @@ func (eb *ExcelBridge) ProcessChatMessage(clientID string, message ChatMessage)
 		financialContext = eb.buildFinancialContext(session, msgContext)
 	}
 
+	// Add the workbook's protected ranges so the model plans around them
+	eb.addProtectedRanges(context.Background(), session, financialContext)
+
 	// Add any pending operations to the context
 	if pendingOps := eb.queuedOpsRegistry.GetPendingOperations(session.ID); len(pendingOps) > 0 {
 		financialContext.PendingOperations = pendingOps
@@ -566,6 +688,7 @@ func (eb *ExcelBridge) ProcessChatMessage(clientID string, message ChatMessage)
 	var content string
 	var suggestions []string
 	var actions []ProposedAction
+	var citations []ai.Citation
 	var aiResponse *ai.CompletionResponse // Track AI response for IsFinal flag
 
 	if eb.aiService != nil {
@@ -625,13 +748,14 @@ func (eb *ExcelBridge) ProcessChatMessage(clientID string, message ChatMessage)
 			content = "I encountered an error processing your request. Please try again."
 		} else {
 			content = aiResponse.Content
+			citations = aiResponse.Citations
 
 			// Track AI-edited ranges for context expansion
 			if len(aiResponse.ToolCalls) > 0 {
 				var editedRanges []string
 				for _, toolCall := range aiResponse.ToolCalls {
 					// Check if this is a write operation
-					if isWriteTool(toolCall.Name) {
+					if eb.isWriteTool(toolCall.Name) {
 						// Extract range from tool input
 						if rangeVal, ok := toolCall.Input["range"]; ok {
 							if rangeStr, ok := rangeVal.(string); ok {
@@ -727,6 +851,7 @@ func (eb *ExcelBridge) ProcessChatMessage(clientID string, message ChatMessage)
 		Actions:     actions,
 		SessionID:   session.ID,
 		IsFinal:     isFinal,
+		Citations:   citations,
 	}
 
 	// Add token usage if available from AI response
@@ -761,6 +886,7 @@ func (eb *ExcelBridge) ProcessChatMessage(clientID string, message ChatMessage)
 func (eb *ExcelBridge) ProcessChatMessageStreaming(ctx context.Context, clientID string, message ChatMessage) (<-chan ai.CompletionChunk, error) {
 	// Get or create session
 	session := eb.getOrCreateSession(clientID, message.SessionID)
+	eb.trackWorkbook(session, message.Context)
 	
 	// Build comprehensive context BEFORE adding message to history
 	var financialContext *ai.FinancialContext
@@ -795,6 +921,9 @@ func (eb *ExcelBridge) ProcessChatMessageStreaming(ctx context.Context, clientID
 		financialContext = eb.buildFinancialContext(session, msgContext)
 	}
 	
+	// Add the workbook's protected ranges so the model plans around them
+	eb.addProtectedRanges(ctx, session, financialContext)
+	
 	// Get existing history BEFORE adding new message
 	history := eb.chatHistory.GetHistory(session.ID)
 	
@@ -838,7 +967,7 @@ func (eb *ExcelBridge) ProcessChatMessageStreaming(ctx context.Context, clientID
 		eb.logger.Info("[STREAMING] Got streaming chunks channel from AI service, starting processStreamingChunksWithTools")
 		
 		// Process chunks and handle tool execution
-		eb.processStreamingChunksWithTools(ctx, clientID, chunks, outChan, message.AutonomyMode)
+		eb.processStreamingChunksWithTools(ctx, clientID, chunks, outChan, message.AutonomyMode, financialContext)
 	}()
 	
 	return outChan, nil
@@ -854,6 +983,7 @@ type StreamingState struct {
 	ContentBuffer   strings.Builder
 	StartTime       time.Time
 	ToolResponseMap sync.Map // thread-safe map for tool responses
+	Context         *ai.FinancialContext // Context of the message, used to infer missing tool parameters
 }
 
 // processStreamingChunksWithTools handles streaming chunks and executes tools through SignalR
@@ -863,6 +993,7 @@ func (eb *ExcelBridge) processStreamingChunksWithTools(
 	inChan <-chan ai.CompletionChunk,
 	outChan chan<- ai.CompletionChunk,
 	autonomyMode string,
+	financialContext *ai.FinancialContext,
 ) {
 	var currentToolCall *ai.ToolCall
 	var pendingToolCalls []ai.ToolCall
@@ -876,6 +1007,7 @@ func (eb *ExcelBridge) processStreamingChunksWithTools(
 		PendingTools:  []ai.ToolCall{},
 		ExecutedTools: make(map[string]ai.ToolResult),
 		StartTime:     time.Now(),
+		Context:       financialContext,
 	}
 	
 	// Register the streaming session
@@ -1175,9 +1307,10 @@ func (eb *ExcelBridge) getOrCreateSession(clientID, sessionID string) *ExcelSess
 	}
 
 	// Initialize in-memory vector store for the session
-	inMemStore := memory.NewInMemoryStore(1000) // Max 1000 chunks per session
-	var vectorStore memory.VectorStore = inMemStore
-	session.MemoryStore = &vectorStore
+	session.MemoryNamespace = sessionNamespace(newSessionID, session.UserID)
+	session.MemoryStore = eb.newSessionMemoryStore(func() memory.VectorStore {
+		return memory.NewInMemoryStore(1000) // Max 1000 chunks per session
+	})
 
 	eb.sessions[newSessionID] = session
 
@@ -1215,6 +1348,57 @@ func (eb *ExcelBridge) GetSession(sessionID string) *ExcelSession {
 	return nil
 }
 
+// trackWorkbook records the workbook a session's messages come from
+func (eb *ExcelBridge) trackWorkbook(session *ExcelSession, msgContext map[string]interface{}) {
+	workbook, ok := msgContext["workbook"].(string)
+	if !ok || workbook == "" {
+		return
+	}
+
+	eb.sessionMutex.Lock()
+	session.Workbook = workbook
+	eb.sessionMutex.Unlock()
+}
+
+// addProtectedRanges adds the protected ranges of the session's workbook
+// to the prompt context
+func (eb *ExcelBridge) addProtectedRanges(ctx context.Context, session *ExcelSession, financialContext *ai.FinancialContext) {
+	if eb.toolExecutor == nil || financialContext == nil {
+		return
+	}
+
+	protections, err := eb.toolExecutor.ProtectedRanges(ctx, session.ID)
+	if err != nil {
+		eb.logger.WithError(err).WithField("session_id", session.ID).Warn("Failed to load protected ranges for context")
+		return
+	}
+	financialContext.ProtectedRanges = protections
+}
+
+// UserSessions returns the IDs of the sessions a user is connected with
+func (eb *ExcelBridge) UserSessions(userID string) []string {
+	eb.sessionMutex.RLock()
+	defer eb.sessionMutex.RUnlock()
+
+	var sessionIDs []string
+	for id, session := range eb.sessions {
+		if session.UserID == userID {
+			sessionIDs = append(sessionIDs, id)
+		}
+	}
+	return sessionIDs
+}
+
+// SessionOwner returns the user an Excel session belongs to, or "" if the
+// session does not exist
+func (eb *ExcelBridge) SessionOwner(sessionID string) string {
+	session := eb.GetSession(sessionID)
+	if session == nil {
+		return ""
+	}
+	return session.UserID
+}
+
 // GetWorkbookData retrieves all workbook data for indexing
 func (eb *ExcelBridge) GetWorkbookData(sessionID string) *models.Workbook {
 	session := eb.GetSession(sessionID)
@@ -1403,6 +1587,9 @@ func (eb *ExcelBridge) cleanupSessions() {
 		for id, session := range eb.sessions {
 			if now.Sub(session.LastActivity) > 30*time.Minute {
 				delete(eb.sessions, id)
+				if forgetter, ok := eb.indexingService.(interface{ ForgetSession(memory.Namespace) }); ok {
+					forgetter.ForgetSession(session.MemoryNamespace)
+				}
 				eb.logger.WithField("sessionID", id).Info("Cleaned up inactive session")
 			}
 		}
@@ -1916,13 +2103,24 @@ func (eb *ExcelBridge) ApplyChanges(ctx context.Context, userID, previewID strin
 	// 5. Update the audit trail
 
 	response := &ApplyChangesResponse{
-		Success:      true,
-		AppliedCount: len(changeIDs),
-		FailedCount:  0,
-		BackupID:     generateBackupID(),
-		Errors:       []string{},
+		Success:  true,
+		BackupID: generateBackupID(),
+		Errors:   []string{},
 	}
 
+	// Changes under four-eyes review, or every change when review is
+	// required, apply only once reviewers approve them
+	for _, changeID := range changeIDs {
+		if block := eb.queuedOpsRegistry.ApprovalBlock(changeID); block != "" {
+			response.FailedCount++
+			response.Errors = append(response.Errors, fmt.Sprintf("%s: %s, waiting for reviewer approval", changeID, block))
+			continue
+		}
+		response.AppliedCount++
+		eb.queuedOpsRegistry.ApproveOperation(changeID, userID)
+	}
+	response.Success = response.FailedCount == 0
+
 	// For MVP, we'll simulate applying changes
 	eb.logger.WithFields(logrus.Fields{
 		"userID":    userID,
@@ -1930,21 +2128,28 @@ func (eb *ExcelBridge) ApplyChanges(ctx context.Context, userID, previewID strin
 		"changeIDs": changeIDs,
 	}).Info("Applying changes from preview")
 
-	// TODO: Record in audit log when audit service is integrated
-
 	return response, nil
 }
 
-// RejectChanges records the rejection of proposed changes
+// RejectChanges records the rejection of proposed changes. Changes under
+// four-eyes review are rejected by their reviewers instead.
 func (eb *ExcelBridge) RejectChanges(ctx context.Context, userID, previewID, reason string) error {
+	if op, ok := eb.queuedOpsRegistry.GetOperation(previewID); ok && op.ApprovalState == ApprovalPending {
+		return fmt.Errorf("operation %s is awaiting review %s", previewID, op.ReviewID)
+	}
+
 	// Record rejection in audit trail
 	eb.logger.WithFields(logrus.Fields{
 		"userID":    userID,
 		"previewID": previewID,
 		"reason":    reason,
 	}).Info("Changes rejected by user")
-
-	// TODO: Record in audit log when audit service is integrated
+	eb.queuedOpsRegistry.recordEvent(&OperationEvent{
+		Action:      OperationRejected,
+		UserID:      userID,
+		OperationID: previewID,
+		Reason:      reason,
+	})
 
 	return nil
 }
@@ -2200,12 +2405,12 @@ func (eb *ExcelBridge) indexInitialWorkbook(sessionID string) {
 
 	// Use type assertion to call the indexing service
 	type indexingServiceInterface interface {
-		IndexWorkbook(ctx context.Context, sessionID string, workbook *models.Workbook, store memory.VectorStore) error
+		IndexWorkbook(ctx context.Context, ns memory.Namespace, workbook *models.Workbook, store memory.VectorStore) error
 	}
 
 	if indexer, ok := eb.indexingService.(indexingServiceInterface); ok {
 		ctx := context.Background()
-		err := indexer.IndexWorkbook(ctx, sessionID, workbook, *session.MemoryStore)
+		err := indexer.IndexWorkbook(ctx, session.MemoryNamespace, workbook, *session.MemoryStore)
 
 		if err != nil {
 			eb.logger.WithError(err).WithField("session_id", sessionID).Error("Failed to index workbook")
@@ -2215,11 +2420,9 @@ func (eb *ExcelBridge) indexInitialWorkbook(sessionID string) {
 			if session.MemoryStats != nil {
 				session.MemoryStats.LastIndexed = time.Now()
 				if store := *session.MemoryStore; store != nil {
-					if statsGetter, ok := store.(interface{ GetStats() memory.Stats }); ok {
-						stats := statsGetter.GetStats()
-						session.MemoryStats.TotalChunks = stats.TotalChunks
-						session.MemoryStats.SpreadsheetChunks = stats.SpreadsheetChunks
-					}
+					stats := store.NamespaceStats(session.MemoryNamespace)
+					session.MemoryStats.TotalChunks = stats.TotalChunks
+					session.MemoryStats.SpreadsheetChunks = stats.SpreadsheetChunks
 				}
 			}
 			eb.sessionMutex.Unlock()
@@ -2240,12 +2443,12 @@ func (eb *ExcelBridge) indexChatExchange(sessionID, userMessage, assistantRespon
 
 	// Use type assertion to call the indexing service
 	type chatIndexerInterface interface {
-		IndexChatMessages(ctx context.Context, sessionID string, userMessage, assistantResponse string, turn int, store memory.VectorStore) error
+		IndexChatMessages(ctx context.Context, ns memory.Namespace, userMessage, assistantResponse string, turn int, store memory.VectorStore) error
 	}
 
 	if indexer, ok := eb.indexingService.(chatIndexerInterface); ok {
 		ctx := context.Background()
-		err := indexer.IndexChatMessages(ctx, sessionID, userMessage, assistantResponse, turn, *session.MemoryStore)
+		err := indexer.IndexChatMessages(ctx, session.MemoryNamespace, userMessage, assistantResponse, turn, *session.MemoryStore)
 
 		if err != nil {
 			eb.logger.WithError(err).Error("Failed to index chat messages")
@@ -2317,17 +2520,17 @@ func (eb *ExcelBridge) IndexWorkbookForSession(sessionID string) error {
 	// Perform indexing
 	ctx := context.Background()
 	indexer := eb.indexingService.(interface {
-		IndexWorkbook(ctx context.Context, sessionID string, workbook *models.Workbook, store memory.VectorStore) error
+		IndexWorkbook(ctx context.Context, ns memory.Namespace, workbook *models.Workbook, store memory.VectorStore) error
 	})
 
-	err := indexer.IndexWorkbook(ctx, sessionID, workbook, *session.MemoryStore)
+	err := indexer.IndexWorkbook(ctx, session.MemoryNamespace, workbook, *session.MemoryStore)
 	if err != nil {
 		return fmt.Errorf("failed to index workbook: %w", err)
 	}
 
 	// Update memory stats
 	if session.MemoryStats != nil {
-		stats := (*session.MemoryStore).GetStats()
+		stats := (*session.MemoryStore).NamespaceStats(session.MemoryNamespace)
 		session.MemoryStats.TotalChunks = stats.TotalChunks
 		session.MemoryStats.SpreadsheetChunks = stats.SpreadsheetChunks
 		session.MemoryStats.DocumentChunks = stats.DocumentChunks
@@ -2343,6 +2546,55 @@ func (eb *ExcelBridge) IndexWorkbookForSession(sessionID string) error {
 	return nil
 }
 
+// ReindexChangedCells refreshes the memory chunks covering the given cells
+// ("B5", "A1:D10" or "Sheet1!C3") instead of re-indexing the whole workbook
+func (eb *ExcelBridge) ReindexChangedCells(sessionID string, cells []string) {
+	session := eb.GetSession(sessionID)
+	if session == nil || session.MemoryStore == nil || len(cells) == 0 {
+		return
+	}
+
+	type rangeIndexer interface {
+		ReindexRanges(ctx context.Context, ns memory.Namespace, sheet *models.Sheet, changed []string, store memory.VectorStore) (*indexing.ReindexResult, error)
+	}
+	indexer, ok := eb.indexingService.(rangeIndexer)
+	if !ok {
+		return
+	}
+
+	workbook := eb.GetWorkbookData(sessionID)
+	if workbook == nil {
+		return
+	}
+
+	ctx := context.Background()
+	for _, sheet := range workbook.Sheets {
+		if sheet.Data == nil {
+			continue
+		}
+		result, err := indexer.ReindexRanges(ctx, session.MemoryNamespace, sheet, cells, *session.MemoryStore)
+		if err != nil {
+			eb.logger.WithError(err).WithFields(logrus.Fields{
+				"session_id": sessionID,
+				"sheet":      sheet.Name,
+			}).Error("Failed to re-index changed cells")
+			continue
+		}
+		if result.ChunksAdded == 0 && result.ChunksRemoved == 0 {
+			continue
+		}
+
+		eb.sessionMutex.Lock()
+		if session.MemoryStats != nil {
+			stats := (*session.MemoryStore).NamespaceStats(session.MemoryNamespace)
+			session.MemoryStats.TotalChunks = stats.TotalChunks
+			session.MemoryStats.SpreadsheetChunks = stats.SpreadsheetChunks
+			session.MemoryStats.LastIndexed = time.Now()
+		}
+		eb.sessionMutex.Unlock()
+	}
+}
+
 // inferToolParameters attempts to infer tool parameters when they are empty
 func (eb *ExcelBridge) inferToolParameters(toolName string, context *ai.FinancialContext) map[string]interface{} {
 	params := make(map[string]interface{})
@@ -0,0 +1,184 @@
+package indexing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gridmate/backend/internal/memory"
+	"github.com/gridmate/backend/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// indexedChunk records where a spreadsheet chunk came from
+type indexedChunk struct {
+	ID        string
+	Bounds    memory.CellBounds
+	HasBounds bool // Overview chunks cover the whole sheet
+}
+
+// ReindexResult summarises an incremental re-index
+type ReindexResult struct {
+	Sheet         string `json:"sheet"`
+	ChunksAdded   int    `json:"chunksAdded"`
+	ChunksRemoved int    `json:"chunksRemoved"`
+}
+
+// ReindexRanges re-indexes only the parts of sheet touched by changed
+// cells or ranges ("B5", "A1:D10", "Sheet1!C3"). Affected tables, sections
+// and formulas are re-chunked and re-embedded, and the chunks they replace
+// are deleted by ID. A sheet that was never indexed is indexed in full.
+func (s *IndexingService) ReindexRanges(ctx context.Context, ns memory.Namespace, sheet *models.Sheet, changed []string, store memory.VectorStore) (*ReindexResult, error) {
+	if sheet == nil {
+		return nil, fmt.Errorf("sheet is required")
+	}
+	result := &ReindexResult{Sheet: sheet.Name}
+
+	var bounds []memory.CellBounds
+	for _, address := range changed {
+		b, ok := memory.ParseCellRange(address)
+		if !ok || (b.Sheet != "" && b.Sheet != sheet.Name) {
+			continue
+		}
+		b.Sheet = ""
+		bounds = append(bounds, b)
+	}
+	if len(bounds) == 0 {
+		return result, nil
+	}
+
+	// Serialise updates so concurrent edits never delete each other's chunks
+	s.chunkMu.Lock()
+	defer s.chunkMu.Unlock()
+
+	tracked, indexed := s.sheetChunks[ns.Key()][sheet.Name]
+	var chunks []memory.Chunk
+	if indexed {
+		chunks = s.spreadsheetChunker.ChunkRanges(sheet, bounds)
+	} else {
+		chunks = s.spreadsheetChunker.ChunkSpreadsheet(sheet)
+	}
+
+	// A tracked chunk is stale if an edit or a regenerated region overlaps it
+	regions := append([]memory.CellBounds(nil), bounds...)
+	for _, chunk := range chunks {
+		if b, ok := memory.ParseCellRange(chunk.Metadata.CellRange); ok {
+			regions = append(regions, b)
+		}
+	}
+	stale := make(map[string]bool)
+	var kept []indexedChunk
+	for _, entry := range tracked {
+		if !entry.HasBounds || overlapsAny(entry.Bounds, regions) {
+			stale[entry.ID] = true
+			continue
+		}
+		kept = append(kept, entry)
+	}
+
+	if len(chunks) > 0 {
+		texts := make([]string, len(chunks))
+		for i, chunk := range chunks {
+			texts[i] = chunk.Content
+		}
+		embeddings, err := s.embeddingProvider.GetEmbeddings(ctx, texts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get embeddings: %w", err)
+		}
+		now := time.Now()
+		for i, embedding := range embeddings {
+			chunks[i].Vector = embedding
+			chunks[i].Timestamp = now
+		}
+
+		// Add before deleting so searches never see the region missing
+		if err := store.Add(ns, chunks); err != nil {
+			return nil, fmt.Errorf("failed to add chunks to store: %w", err)
+		}
+	}
+
+	if len(stale) > 0 {
+		if err := store.Delete(ns, func(chunk memory.Chunk) bool { return stale[chunk.ID] }); err != nil {
+			return nil, fmt.Errorf("failed to delete stale chunks: %w", err)
+		}
+	}
+
+	s.trackChunks(ns, sheet.Name, append(kept, toIndexedChunks(chunks)...))
+	result.ChunksAdded = len(chunks)
+	result.ChunksRemoved = len(stale)
+
+	s.logger.WithFields(logrus.Fields{
+		"namespace": ns.String(),
+		"sheet":     sheet.Name,
+		"changed":   len(bounds),
+		"added":     result.ChunksAdded,
+		"removed":   result.ChunksRemoved,
+	}).Debug("Incremental workbook re-index completed")
+
+	return result, nil
+}
+
+// replaceSheetChunks records a full index of sheets and deletes whatever
+// was tracked for them before
+func (s *IndexingService) replaceSheetChunks(ns memory.Namespace, sheets []*models.Sheet, chunks []memory.Chunk, store memory.VectorStore) error {
+	s.chunkMu.Lock()
+	defer s.chunkMu.Unlock()
+
+	bySheet := make(map[string][]memory.Chunk)
+	for _, chunk := range chunks {
+		bySheet[chunk.Metadata.SheetName] = append(bySheet[chunk.Metadata.SheetName], chunk)
+	}
+
+	stale := make(map[string]bool)
+	for _, sheet := range sheets {
+		for _, entry := range s.sheetChunks[ns.Key()][sheet.Name] {
+			stale[entry.ID] = true
+		}
+		s.trackChunks(ns, sheet.Name, toIndexedChunks(bySheet[sheet.Name]))
+	}
+
+	if len(stale) == 0 {
+		return nil
+	}
+	return store.Delete(ns, func(chunk memory.Chunk) bool { return stale[chunk.ID] })
+}
+
+// ForgetSession drops the chunk registry for a session
+func (s *IndexingService) ForgetSession(ns memory.Namespace) {
+	s.chunkMu.Lock()
+	delete(s.sheetChunks, ns.Key())
+	s.chunkMu.Unlock()
+
+	s.mu.Lock()
+	delete(s.progress, ns.Session)
+	s.mu.Unlock()
+}
+
+// trackChunks replaces the registry entry for a sheet. Callers hold chunkMu.
+func (s *IndexingService) trackChunks(ns memory.Namespace, sheet string, entries []indexedChunk) {
+	sheets, ok := s.sheetChunks[ns.Key()]
+	if !ok {
+		sheets = make(map[string][]indexedChunk)
+		s.sheetChunks[ns.Key()] = sheets
+	}
+	sheets[sheet] = entries
+}
+
+func toIndexedChunks(chunks []memory.Chunk) []indexedChunk {
+	entries := make([]indexedChunk, len(chunks))
+	for i, chunk := range chunks {
+		bounds, ok := memory.ParseCellRange(chunk.Metadata.CellRange)
+		bounds.Sheet = ""
+		entries[i] = indexedChunk{ID: chunk.ID, Bounds: bounds, HasBounds: ok}
+	}
+	return entries
+}
+
+func overlapsAny(bounds memory.CellBounds, regions []memory.CellBounds) bool {
+	for _, region := range regions {
+		if bounds.Overlaps(region) {
+			return true
+		}
+	}
+	return false
+}
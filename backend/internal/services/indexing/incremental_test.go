@@ -0,0 +1,92 @@
+package indexing
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/gridmate/backend/internal/memory"
+	"github.com/gridmate/backend/internal/models"
+	"github.com/gridmate/backend/internal/services/ai"
+	"github.com/sirupsen/logrus"
+)
+
+func testSheet(revenue string) *models.Sheet {
+	values := [][]interface{}{
+		{"Item", "FY2023", "FY2024"},
+		{"Revenue", revenue, "120"},
+		{"Costs", "60", "70"},
+		{"Total", "40", "50"},
+		{nil, nil, nil},
+		{nil, nil, nil},
+		{nil, nil, nil},
+		{nil, nil, nil},
+		{"Notes", "Units", "Source"},
+		{"a", "b", "c"},
+		{"d", "e", "f"},
+	}
+	return &models.Sheet{
+		Name: "Model",
+		Data: &models.RangeData{Sheet: "Model", Range: "A1:C11", Values: values},
+	}
+}
+
+func chunkContents(store *memory.InMemoryStore) map[string]string {
+	chunks := make(map[string]string)
+	for _, chunk := range store.GetAllChunks() {
+		chunks[chunk.ID] = chunk.Content
+	}
+	return chunks
+}
+
+func TestReindexRanges(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	service := NewIndexingService(ai.NewLocalEmbeddingProvider(), logger)
+	store := memory.NewInMemoryStore(1000)
+	ctx := context.Background()
+	ns := memory.SessionNamespace("tenant", "s1")
+
+	workbook := &models.Workbook{Sheets: []*models.Sheet{testSheet("100")}}
+	if err := service.IndexWorkbook(ctx, ns, workbook, store); err != nil {
+		t.Fatal(err)
+	}
+	before := chunkContents(store)
+	total := store.GetStats().TotalChunks
+
+	// Editing B2 replaces the first table, sections over it and the overview
+	result, err := service.ReindexRanges(ctx, ns, testSheet("999"), []string{"Model!B2"}, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ChunksAdded == 0 || result.ChunksAdded != result.ChunksRemoved {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if got := store.GetStats().TotalChunks; got != total {
+		t.Errorf("total chunks = %d, want %d", got, total)
+	}
+
+	after := chunkContents(store)
+	var notesKept, revenueUpdated bool
+	for id, content := range after {
+		if strings.Contains(content, "Notes") && before[id] != "" {
+			notesKept = true
+		}
+		if strings.Contains(content, "999") {
+			revenueUpdated = true
+		}
+	}
+	if !notesKept {
+		t.Error("untouched table was re-chunked")
+	}
+	if !revenueUpdated {
+		t.Error("edited table was not re-chunked")
+	}
+
+	// Edits on other sheets are ignored
+	result, err = service.ReindexRanges(ctx, ns, testSheet("999"), []string{"Other!B2"}, store)
+	if err != nil || result.ChunksAdded != 0 {
+		t.Errorf("other sheet edit: %+v, %v", result, err)
+	}
+}
@@ -1,6 +1,7 @@
 package indexing
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -19,13 +20,18 @@ import (
 type IndexingService struct {
 	embeddingProvider  ai.EmbeddingProvider
 	spreadsheetChunker *chunkers.SpreadsheetChunker
-	documentParser     document.DocumentParser
+	documentParsers    *document.Parsers
 	
 	logger *logrus.Logger
 	
 	// Track indexing progress
 	mu       sync.RWMutex
 	progress map[string]*IndexingProgress
+
+	// Spreadsheet chunks indexed per namespace and sheet, so edits can
+	// replace just the chunks they touch
+	chunkMu     sync.Mutex
+	sheetChunks map[string]map[string][]indexedChunk
 }
 
 // IndexingProgress tracks the progress of an indexing operation
@@ -44,14 +50,16 @@ func NewIndexingService(embeddingProvider ai.EmbeddingProvider, logger *logrus.L
 	return &IndexingService{
 		embeddingProvider:  embeddingProvider,
 		spreadsheetChunker: chunkers.NewSpreadsheetChunker(),
-		documentParser:     document.NewPDFParser(),
+		documentParsers:    document.NewParsers(),
 		logger:            logger,
 		progress:          make(map[string]*IndexingProgress),
+		sheetChunks:       make(map[string]map[string][]indexedChunk),
 	}
 }
 
 // IndexWorkbook indexes an entire workbook
-func (s *IndexingService) IndexWorkbook(ctx context.Context, sessionID string, workbook *models.Workbook, store memory.VectorStore) error {
+func (s *IndexingService) IndexWorkbook(ctx context.Context, ns memory.Namespace, workbook *models.Workbook, store memory.VectorStore) error {
+	sessionID := ns.Session
 	progress := &IndexingProgress{
 		SessionID: sessionID,
 		Status:    "running",
@@ -99,7 +107,7 @@ func (s *IndexingService) IndexWorkbook(ctx context.Context, sessionID string, w
 	}
 	
 	// Add to vector store
-	if err := store.Add(allChunks); err != nil {
+	if err := store.Add(ns, allChunks); err != nil {
 		progress.Status = "failed"
 		progress.Error = err
 		progress.EndTime = time.Now()
@@ -107,6 +115,11 @@ func (s *IndexingService) IndexWorkbook(ctx context.Context, sessionID string, w
 		return fmt.Errorf("failed to add chunks to store: %w", err)
 	}
 	
+	// Drop chunks from any previous index of these sheets
+	if err := s.replaceSheetChunks(ns, workbook.Sheets, allChunks, store); err != nil {
+		s.logger.WithError(err).WithField("session_id", sessionID).Warn("Failed to remove chunks from previous index")
+	}
+	
 	progress.Status = "completed"
 	progress.EndTime = time.Now()
 	s.setProgress(sessionID, progress)
@@ -121,7 +134,8 @@ func (s *IndexingService) IndexWorkbook(ctx context.Context, sessionID string, w
 }
 
 // IndexDocument indexes a document
-func (s *IndexingService) IndexDocument(ctx context.Context, sessionID string, reader io.Reader, filename string, store memory.VectorStore) error {
+func (s *IndexingService) IndexDocument(ctx context.Context, ns memory.Namespace, reader io.Reader, filename string, store memory.VectorStore) error {
+	sessionID := ns.Session
 	progress := &IndexingProgress{
 		SessionID: sessionID,
 		Status:    "running",
@@ -130,8 +144,24 @@ func (s *IndexingService) IndexDocument(ctx context.Context, sessionID string, r
 	
 	s.setProgress(sessionID, progress)
 	
+	// Pick the parser by content, not by extension alone
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		progress.Status = "failed"
+		progress.Error = err
+		progress.EndTime = time.Now()
+		s.setProgress(sessionID, progress)
+		return fmt.Errorf("failed to read document: %w", err)
+	}
+	format := document.DetectFormat(content, filename)
+	s.logger.WithFields(logrus.Fields{
+		"session_id": sessionID,
+		"filename":   filename,
+		"format":     format,
+	}).Debug("Detected document format")
+
 	// Parse document
-	chunks, err := s.documentParser.Parse(ctx, reader, filename)
+	chunks, err := s.documentParsers.For(format).Parse(ctx, bytes.NewReader(content), filename)
 	if err != nil {
 		progress.Status = "failed"
 		progress.Error = err
@@ -174,7 +204,7 @@ func (s *IndexingService) IndexDocument(ctx context.Context, sessionID string, r
 		}
 		
 		// Add to store
-		if err := store.Add(batch); err != nil {
+		if err := store.Add(ns, batch); err != nil {
 			progress.Status = "failed"
 			progress.Error = err
 			progress.EndTime = time.Now()
@@ -201,7 +231,8 @@ func (s *IndexingService) IndexDocument(ctx context.Context, sessionID string, r
 }
 
 // IndexChatHistory indexes chat messages
-func (s *IndexingService) IndexChatHistory(ctx context.Context, sessionID string, messages []ChatMessage, store memory.VectorStore) error {
+func (s *IndexingService) IndexChatHistory(ctx context.Context, ns memory.Namespace, messages []ChatMessage, store memory.VectorStore) error {
+	sessionID := ns.Session
 	progress := &IndexingProgress{
 		SessionID:  sessionID,
 		Status:     "running",
@@ -254,7 +285,7 @@ func (s *IndexingService) IndexChatHistory(ctx context.Context, sessionID string
 	}
 	
 	// Add to store
-	if err := store.Add(chunks); err != nil {
+	if err := store.Add(ns, chunks); err != nil {
 		progress.Status = "failed"
 		progress.Error = err
 		progress.EndTime = time.Now()
@@ -269,6 +300,21 @@ func (s *IndexingService) IndexChatHistory(ctx context.Context, sessionID string
 	return nil
 }
 
+// EmbedQuery embeds a search query with the same provider used for indexing
+func (s *IndexingService) EmbedQuery(ctx context.Context, query string) ([]float32, error) {
+	return s.embeddingProvider.GetEmbedding(ctx, query)
+}
+
+// EmbeddingCacheStats reports the embedding cache counters when the
+// provider is cached
+func (s *IndexingService) EmbeddingCacheStats() (ai.EmbeddingCacheStats, bool) {
+	cached, ok := s.embeddingProvider.(interface{ Stats() ai.EmbeddingCacheStats })
+	if !ok {
+		return ai.EmbeddingCacheStats{}, false
+	}
+	return cached.Stats(), true
+}
+
 // GetProgress returns the indexing progress for a session
 func (s *IndexingService) GetProgress(sessionID string) *IndexingProgress {
 	s.mu.RLock()
@@ -283,7 +329,8 @@ func (s *IndexingService) setProgress(sessionID string, progress *IndexingProgre
 }
 
 // IndexChatMessages indexes chat messages directly
-func (s *IndexingService) IndexChatMessages(ctx context.Context, sessionID string, userMessage, assistantResponse string, turn int, store memory.VectorStore) error {
+func (s *IndexingService) IndexChatMessages(ctx context.Context, ns memory.Namespace, userMessage, assistantResponse string, turn int, store memory.VectorStore) error {
+	sessionID := ns.Session
 	// Create memory chunks for the messages
 	chunks := []memory.Chunk{
 		{
@@ -331,7 +378,7 @@ func (s *IndexingService) IndexChatMessages(ctx context.Context, sessionID strin
 	}
 
 	// Add to store
-	if err := store.Add(chunks); err != nil {
+	if err := store.Add(ns, chunks); err != nil {
 		return fmt.Errorf("failed to add chat messages to store: %w", err)
 	}
 
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Audited operation actions
+const (
+	OperationQueued   = "ai.operation.queued"
+	OperationApplied  = "ai.operation.applied"
+	OperationFailed   = "ai.operation.failed"
+	OperationApproved = "ai.operation.approved"
+	OperationRejected = "ai.operation.rejected"
+)
+
+// operationAuditBuffer is how many events may wait for the auditor before
+// further events are dropped
+const operationAuditBuffer = 1024
+
+// OperationEvent is an audited change of a queued operation
+type OperationEvent struct {
+	Action        string                 `json:"-"`
+	UserID        string                 `json:"-"`
+	WorkspaceID   string                 `json:"-"`
+	OperationID   string                 `json:"operation_id"`
+	SessionID     string                 `json:"session_id,omitempty"`
+	Type          string                 `json:"type,omitempty"`
+	MessageID     string                 `json:"message_id,omitempty"`
+	ReviewID      string                 `json:"review_id,omitempty"`
+	ApprovalState ApprovalState          `json:"approval_state,omitempty"`
+	Reviews       []ReviewDecision       `json:"reviews,omitempty"`
+	Input         map[string]interface{} `json:"input,omitempty"`
+	Error         string                 `json:"error,omitempty"`
+	Reason        string                 `json:"reason,omitempty"`
+}
+
+// OperationAuditor records queued operation events in the audit log
+type OperationAuditor interface {
+	RecordOperation(ctx context.Context, event *OperationEvent) error
+}
+
+// SetAuditor records every queued, approved, applied, failed and rejected
+// operation with each auditor. Events are written in order by a background
+// worker so the registry lock is never held during a database write; when
+// the worker falls behind by a full buffer, events are dropped and counted
+// rather than stalling the registry.
+func (r *QueuedOperationRegistry) SetAuditor(auditors ...OperationAuditor) {
+	events := make(chan *OperationEvent, operationAuditBuffer)
+	go func() {
+		for event := range events {
+			r.attribute(event)
+			for _, auditor := range auditors {
+				if err := auditor.RecordOperation(context.Background(), event); err != nil {
+					log.Error().Err(err).
+						Str("operation_id", event.OperationID).
+						Str("action", event.Action).
+						Msg("Failed to audit queued operation")
+				}
+			}
+		}
+	}()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.auditEvents = events
+}
+
+// SetSessionResolver sets how audited events find the user and workspace
+// of the session they happened in
+func (r *QueuedOperationRegistry) SetSessionResolver(resolve func(sessionID string) (userID, workspaceID string)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.resolveSession = resolve
+}
+
+// attribute fills in the workspace an event is recorded in and, unless the
+// event names who acted, credits the session's user. It runs on the audit
+// worker so resolving never holds the registry lock.
+func (r *QueuedOperationRegistry) attribute(event *OperationEvent) {
+	r.mu.RLock()
+	resolve := r.resolveSession
+	r.mu.RUnlock()
+	if resolve == nil || event.SessionID == "" {
+		return
+	}
+
+	userID, workspaceID := resolve(event.SessionID)
+	if event.UserID == "" {
+		event.UserID = userID
+	}
+	event.WorkspaceID = workspaceID
+}
+
+// auditOperation records an operation's current state. userID is who acted,
+// or "" for the session's user. The caller must hold r.mu.
+func (r *QueuedOperationRegistry) auditOperation(action string, op *QueuedOperation, userID string) {
+	event := &OperationEvent{
+		Action:        action,
+		UserID:        userID,
+		OperationID:   op.ID,
+		SessionID:     op.SessionID,
+		Type:          op.Type,
+		MessageID:     op.MessageID,
+		ReviewID:      op.ReviewID,
+		ApprovalState: op.ApprovalState,
+		Reviews:       append([]ReviewDecision(nil), op.ReviewDecisions...),
+		Error:         op.Error,
+	}
+	if action == OperationQueued {
+		event.Input = op.Input
+	}
+	r.auditEvent(event)
+}
+
+// recordEvent hands an event to the audit worker, filling in the session
+// of the operation it is about
+func (r *QueuedOperationRegistry) recordEvent(event *OperationEvent) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if op, exists := r.operations[event.OperationID]; exists && event.SessionID == "" {
+		event.SessionID = op.SessionID
+	}
+	r.auditEvent(event)
+}
+
+// auditEvent hands an event to the audit worker without waiting for it.
+// The caller must hold r.mu.
+func (r *QueuedOperationRegistry) auditEvent(event *OperationEvent) {
+	if r.auditEvents == nil {
+		return
+	}
+	select {
+	case r.auditEvents <- event:
+	default:
+		dropped := r.auditDropped.Add(1)
+		log.Error().
+			Str("operation_id", event.OperationID).
+			Str("action", event.Action).
+			Uint64("dropped", dropped).
+			Msg("Audit worker is behind, dropped queued operation event")
+	}
+}
+
+// DroppedAuditEvents returns how many operation events were dropped because
+// the audit worker fell behind
+func (r *QueuedOperationRegistry) DroppedAuditEvents() uint64 {
+	return r.auditDropped.Load()
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type blockedAuditor struct {
+	release chan struct{}
+}
+
+func (a *blockedAuditor) RecordOperation(ctx context.Context, event *OperationEvent) error {
+	<-a.release
+	return nil
+}
+
+func TestAuditEventsNeverStallTheRegistry(t *testing.T) {
+	registry := NewQueuedOperationRegistry()
+	auditor := &blockedAuditor{release: make(chan struct{})}
+	defer close(auditor.release)
+	registry.SetAuditor(auditor)
+
+	done := make(chan error, 1)
+	go func() {
+		for i := 0; i < operationAuditBuffer+10; i++ {
+			op := &QueuedOperation{ID: fmt.Sprintf("op-%d", i), SessionID: "s1", Type: "write_range"}
+			if err := registry.QueueOperation(op); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("queueing operations blocked on the audit worker")
+	}
+	if dropped := registry.DroppedAuditEvents(); dropped == 0 {
+		t.Error("dropped events were not counted")
+	}
+}
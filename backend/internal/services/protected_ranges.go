@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+
+	"github.com/gridmate/backend/internal/auth"
+	"github.com/gridmate/backend/internal/models"
+	"github.com/gridmate/backend/internal/repository"
+	"github.com/gridmate/backend/internal/services/ai"
+)
+
+// ErrProtectedRangeForbidden is returned when the caller may not manage the
+// protections of a workspace
+var ErrProtectedRangeForbidden = errors.New("not permitted to manage protected ranges in this workspace")
+
+// ProtectedRangeStore manages the protected ranges of a workspace's
+// workbooks and serves them to the tool executor
+type ProtectedRangeStore struct {
+	repo          repository.ProtectedRangeRepository
+	workspaceRepo repository.WorkspaceRepository
+}
+
+// NewProtectedRangeStore creates a protected range store reading from repo
+func NewProtectedRangeStore(repo repository.ProtectedRangeRepository, workspaceRepo repository.WorkspaceRepository) *ProtectedRangeStore {
+	return &ProtectedRangeStore{repo: repo, workspaceRepo: workspaceRepo}
+}
+
+// ProtectedRanges returns the protections of a workbook in every workspace
+// of the session's user. A session whose tenant is not a user ID cannot be
+// placed in a workspace, so every workspace's protections of the workbook
+// apply to it.
+func (s *ProtectedRangeStore) ProtectedRanges(ctx context.Context, tenant, workbook string) ([]ai.ProtectedRange, error) {
+	var stored []*models.ProtectedRange
+	var err error
+	if userID, parseErr := uuid.Parse(tenant); parseErr == nil {
+		stored, err = s.repo.ListForMember(ctx, userID, workbook)
+	} else {
+		stored, err = s.repo.ListByWorkbookName(ctx, workbook)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	protections := make([]ai.ProtectedRange, 0, len(stored))
+	for _, pr := range stored {
+		protection := ai.ProtectedRange{ID: pr.ID.String(), Kind: pr.Kind, Target: pr.Target}
+		if pr.Reason != nil {
+			protection.Reason = *pr.Reason
+		}
+		protections = append(protections, protection)
+	}
+	return protections, nil
+}
+
+// List returns a workspace's protections of a workbook
+func (s *ProtectedRangeStore) List(ctx context.Context, userID, workspaceID uuid.UUID, workbook string) ([]*models.ProtectedRange, error) {
+	if err := s.requireMember(ctx, workspaceID, userID, false); err != nil {
+		return nil, err
+	}
+	return s.repo.ListByWorkbook(ctx, workspaceID, workbook)
+}
+
+// Create protects part of a workspace's workbook. Viewers may not add
+// protections.
+func (s *ProtectedRangeStore) Create(ctx context.Context, userID uuid.UUID, workbook string, req *models.CreateProtectedRangeRequest) (*models.ProtectedRange, error) {
+	if err := s.requireMember(ctx, req.WorkspaceID, userID, true); err != nil {
+		return nil, err
+	}
+
+	pr := &models.ProtectedRange{
+		WorkspaceID: req.WorkspaceID,
+		Workbook:    workbook,
+		Kind:        req.Kind,
+		Target:      req.Target,
+		Reason:      req.Reason,
+		CreatedBy:   &userID,
+	}
+	if err := s.repo.Create(ctx, pr); err != nil {
+		return nil, err
+	}
+	return pr, nil
+}
+
+// Delete removes a protection. Protections of workspaces the caller cannot
+// see are reported as not found.
+func (s *ProtectedRangeStore) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	pr, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	role, err := auth.WorkspaceRole(ctx, s.workspaceRepo, pr.WorkspaceID, userID)
+	if err != nil {
+		return repository.ErrProtectedRangeNotFound
+	}
+	if !auth.CanWrite(role) {
+		return ErrProtectedRangeForbidden
+	}
+	return s.repo.Delete(ctx, id)
+}
+
+// requireMember refuses non-members, and viewers when write is set
+func (s *ProtectedRangeStore) requireMember(ctx context.Context, workspaceID, userID uuid.UUID, write bool) error {
+	role, err := auth.WorkspaceRole(ctx, s.workspaceRepo, workspaceID, userID)
+	if err != nil || write && !auth.CanWrite(role) {
+		return ErrProtectedRangeForbidden
+	}
+	return nil
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/gridmate/backend/internal/services/ai"
 	"github.com/rs/zerolog/log"
 )
 
@@ -24,6 +26,19 @@ type QueuedOperationRegistry struct {
 	// Message tracking
 	messageOperations  map[string][]string // message ID -> operation IDs
 	operationCallbacks map[string]func()   // message ID -> callback when all ops complete
+
+	// Tool definitions used to build undo operations
+	tools *ai.ToolRegistry
+
+	// Events waiting for the audit worker, nil without an auditor
+	auditEvents chan *OperationEvent
+	// Events dropped while the audit worker was behind
+	auditDropped atomic.Uint64
+	// Finds the user and workspace of a session for audited events
+	resolveSession func(sessionID string) (userID, workspaceID string)
+
+	// Whether operations must be approved in review before they apply
+	reviewRequired bool
 }
 
 // QueuedOperation represents a pending operation
@@ -49,6 +64,14 @@ type QueuedOperation struct {
 
 	// Message tracking
 	MessageID string `json:"message_id,omitempty"` // ID of the chat message that triggered this operation
+
+	// Four-eyes review: operations under review wait for reviewers to approve them
+	ReviewID        string           `json:"review_id,omitempty"`
+	ApprovalState   ApprovalState    `json:"approval_state,omitempty"`
+	ReviewDecisions []ReviewDecision `json:"review_decisions,omitempty"`
+
+	// ApprovedBy is the user whose approval let the operation apply
+	ApprovedBy string `json:"approved_by,omitempty"`
 }
 
 type OperationStatus string
@@ -61,6 +84,33 @@ const (
 	StatusCancelled  OperationStatus = "cancelled"
 )
 
+// ApprovalState is the review state of a queued operation. Operations that
+// were never submitted for review have no approval state.
+type ApprovalState string
+
+const (
+	ApprovalPending  ApprovalState = "pending_review"
+	ApprovalApproved ApprovalState = "approved"
+	ApprovalRejected ApprovalState = "rejected"
+	// ApprovalExpired marks operations of a review that are no longer
+	// queued, such as after a restart, and so can never be decided
+	ApprovalExpired ApprovalState = "expired"
+)
+
+// Review decisions
+const (
+	DecisionApprove = "approve"
+	DecisionReject  = "reject"
+)
+
+// ReviewDecision is one reviewer's decision on an operation
+type ReviewDecision struct {
+	ReviewerID string    `json:"reviewer_id"`
+	Decision   string    `json:"decision"`
+	Comment    string    `json:"comment,omitempty"`
+	DecidedAt  time.Time `json:"decided_at"`
+}
+
 // NewQueuedOperationRegistry creates a new registry
 func NewQueuedOperationRegistry() *QueuedOperationRegistry {
 	return &QueuedOperationRegistry{
@@ -71,7 +121,51 @@ func NewQueuedOperationRegistry() *QueuedOperationRegistry {
 		redoStack:          make([]string, 0),
 		messageOperations:  make(map[string][]string),
 		operationCallbacks: make(map[string]func()),
+		tools:              ai.NewBuiltinToolRegistry(),
+	}
+}
+
+// SetToolRegistry sets the tool definitions used to build undo operations
+func (r *QueuedOperationRegistry) SetToolRegistry(tools *ai.ToolRegistry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tools = tools
+}
+
+// SetReviewRequired makes four-eyes review mandatory: operations that were
+// never submitted for review are held like those awaiting a decision
+func (r *QueuedOperationRegistry) SetReviewRequired(required bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reviewRequired = required
+}
+
+// ApprovalBlock returns why review holds back the operation with the given
+// ID, or "" if it may be applied. Unknown operations are held when review is
+// required, since they cannot have been approved.
+func (r *QueuedOperationRegistry) ApprovalBlock(operationID string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	op, exists := r.operations[operationID]
+	if !exists {
+		op = &QueuedOperation{ID: operationID}
 	}
+	return r.approvalBlock(op)
+}
+
+// approvalBlock is ApprovalBlock for an operation. Callers hold r.mu.
+func (r *QueuedOperationRegistry) approvalBlock(op *QueuedOperation) string {
+	switch op.ApprovalState {
+	case ApprovalApproved:
+		return ""
+	case ApprovalPending, ApprovalRejected:
+		return string(op.ApprovalState)
+	}
+	if r.reviewRequired {
+		return "not submitted for review"
+	}
+	return ""
 }
 
 // QueueOperation adds a new operation to the queue
@@ -155,6 +249,7 @@ func (r *QueuedOperationRegistry) QueueOperation(op interface{}) error {
 		Interface("preview", operation.Preview).
 		Str("message_id", operation.MessageID).
 		Msg("Operation queued")
+	r.auditOperation(OperationQueued, operation, "")
 
 	return nil
 }
@@ -262,6 +357,11 @@ func (r *QueuedOperationRegistry) CanExecute(operationID string) bool {
 		return false
 	}
 
+	// Can't execute until reviewers approve it
+	if r.approvalBlock(op) != "" {
+		return false
+	}
+
 	// Check if all dependencies are completed
 	for _, depID := range op.Dependencies {
 		depOp, exists := r.operations[depID]
@@ -395,6 +495,9 @@ func (r *QueuedOperationRegistry) MarkOperationComplete(operationID string, resu
 	if !exists {
 		return fmt.Errorf("operation %s not found", operationID)
 	}
+	if block := r.approvalBlock(op); block != "" {
+		return fmt.Errorf("operation %s was applied without review approval (%s)", operationID, block)
+	}
 
 	// Count operations for this message
 	totalOps := 0
@@ -433,6 +536,7 @@ func (r *QueuedOperationRegistry) MarkOperationComplete(operationID string, resu
 		Str("type", op.Type).
 		Str("message_id", op.MessageID).
 		Msg("Operation completed")
+	r.auditOperation(OperationApplied, op, op.ApprovedBy)
 
 	// Check if all operations for the message are completed
 	if op.MessageID != "" {
@@ -466,6 +570,7 @@ func (r *QueuedOperationRegistry) MarkOperationFailed(operationID string, err er
 		Str("message_id", op.MessageID).
 		Err(err).
 		Msg("Operation failed")
+	r.auditOperation(OperationFailed, op, "")
 
 	// Check if all operations for the message are completed
 	if op.MessageID != "" {
@@ -517,136 +622,165 @@ func (r *QueuedOperationRegistry) CreateBatch(operations []*QueuedOperation) (st
 	return batchID, nil
 }
 
-// GetOperationStatus returns the status of a specific operation
-func (r *QueuedOperationRegistry) GetOperationStatus(operationID string) (OperationStatus, error) {
+// GetOperation returns a copy of a queued operation by ID, so callers can
+// read it while reviewers and the executor keep updating the original
+func (r *QueuedOperationRegistry) GetOperation(operationID string) (*QueuedOperation, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	op, exists := r.operations[operationID]
 	if !exists {
-		return "", fmt.Errorf("operation %s not found", operationID)
+		return nil, false
 	}
-
-	return op.Status, nil
+	copied := *op
+	copied.Dependencies = append([]string(nil), op.Dependencies...)
+	copied.ReviewDecisions = append([]ReviewDecision(nil), op.ReviewDecisions...)
+	return &copied, true
 }
 
-// generateInverseOperation creates an inverse operation that undoes the given operation
-func (r *QueuedOperationRegistry) generateInverseOperation(op *QueuedOperation) (*QueuedOperation, error) {
-	inverseOp := &QueuedOperation{
-		ID:        uuid.New().String(),
-		SessionID: op.SessionID,
-		Priority:  100, // High priority for undo operations
-		CreatedAt: time.Now(),
-		Status:    StatusQueued,
-	}
-
-	// Generate inverse based on operation type
-	switch op.Type {
-	case "write_range":
-		// For write_range, we need to restore the previous values
-		// Store the previous state in the inverse operation
-		inverseOp.Type = "write_range"
+// SubmitForReview holds queued operations until reviewers approve them.
+// Operations that are not queued or are already under review are refused.
+func (r *QueuedOperationRegistry) SubmitForReview(reviewID string, operationIDs []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-		// Copy the input but add a flag to indicate this is an undo
-		inverseInput := make(map[string]interface{})
-		for k, v := range op.Input {
-			inverseInput[k] = v
+	for _, id := range operationIDs {
+		op, exists := r.operations[id]
+		if !exists {
+			return fmt.Errorf("operation %s not found", id)
 		}
-
-		// Add metadata to indicate this is an undo operation
-		inverseInput["_is_undo"] = true
-		inverseInput["_original_op_id"] = op.ID
-
-		// The actual previous values should be stored when the operation completes
-		if op.Result != nil {
-			if resultMap, ok := op.Result.(map[string]interface{}); ok {
-				if prevValues, exists := resultMap["previous_values"]; exists {
-					inverseInput["values"] = prevValues
-				}
-			}
+		if op.Status != StatusQueued {
+			return fmt.Errorf("operation %s is %s, not queued", id, op.Status)
 		}
+		if op.ApprovalState != "" {
+			return fmt.Errorf("operation %s is already under review", id)
+		}
+	}
 
-		inverseOp.Input = inverseInput
-		inverseOp.Context = fmt.Sprintf("Undo write to %v", op.Input["range"])
-		inverseOp.Preview = fmt.Sprintf("Restore previous values to %v", op.Input["range"])
-
-	case "apply_formula":
-		// For formulas, restore the previous formula or clear it
-		inverseOp.Type = "apply_formula"
-
-		inverseInput := make(map[string]interface{})
-		inverseInput["range"] = op.Input["range"]
-		inverseInput["_is_undo"] = true
-		inverseInput["_original_op_id"] = op.ID
+	for _, id := range operationIDs {
+		op := r.operations[id]
+		op.ReviewID = reviewID
+		op.ApprovalState = ApprovalPending
+	}
 
-		// Check if there was a previous formula
-		if op.Result != nil {
-			if resultMap, ok := op.Result.(map[string]interface{}); ok {
-				if prevFormula, exists := resultMap["previous_formula"]; exists {
-					inverseInput["formula"] = prevFormula
-				} else {
-					// If no previous formula, clear the cell
-					inverseInput["formula"] = ""
-				}
-			}
-		}
+	log.Info().
+		Str("review_id", reviewID).
+		Int("operations", len(operationIDs)).
+		Msg("Operations submitted for review")
 
-		inverseOp.Input = inverseInput
-		inverseOp.Context = fmt.Sprintf("Undo formula in %v", op.Input["range"])
-		inverseOp.Preview = fmt.Sprintf("Restore previous formula to %v", op.Input["range"])
+	return nil
+}
 
-	case "format_range":
-		// For formatting, restore previous format
-		inverseOp.Type = "format_range"
+// RecordReviewDecision adds a reviewer's decision to an operation under
+// review. The operation is approved once requiredApprovals reviewers
+// approve it, and rejected, with its dependents cancelled, as soon as one
+// reviewer rejects it.
+func (r *QueuedOperationRegistry) RecordReviewDecision(operationID string, decision ReviewDecision, requiredApprovals int) (ApprovalState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-		inverseInput := make(map[string]interface{})
-		inverseInput["range"] = op.Input["range"]
-		inverseInput["_is_undo"] = true
-		inverseInput["_original_op_id"] = op.ID
+	op, exists := r.operations[operationID]
+	if !exists {
+		return "", fmt.Errorf("operation %s not found", operationID)
+	}
+	if op.ApprovalState != ApprovalPending {
+		return op.ApprovalState, fmt.Errorf("operation %s is not awaiting review", operationID)
+	}
+	for _, previous := range op.ReviewDecisions {
+		if previous.ReviewerID == decision.ReviewerID {
+			return op.ApprovalState, fmt.Errorf("reviewer %s already decided on operation %s", decision.ReviewerID, operationID)
+		}
+	}
 
-		// Restore previous format from result
-		if op.Result != nil {
-			if resultMap, ok := op.Result.(map[string]interface{}); ok {
-				if prevFormat, exists := resultMap["previous_format"]; exists {
-					inverseInput["format"] = prevFormat
-				}
+	op.ReviewDecisions = append(op.ReviewDecisions, decision)
+
+	switch decision.Decision {
+	case DecisionReject:
+		now := time.Now()
+		op.ApprovalState = ApprovalRejected
+		op.Status = StatusCancelled
+		op.CompletedAt = &now
+		op.Error = "Rejected in review"
+		if decision.Comment != "" {
+			op.Error += ": " + decision.Comment
+		}
+		r.cancelDependentOperations(operationID)
+		r.auditOperation(OperationRejected, op, decision.ReviewerID)
+	case DecisionApprove:
+		approvals := 0
+		for _, d := range op.ReviewDecisions {
+			if d.Decision == DecisionApprove {
+				approvals++
 			}
 		}
+		if approvals >= requiredApprovals {
+			op.ApprovalState = ApprovalApproved
+			op.ApprovedBy = decision.ReviewerID
+			r.auditOperation(OperationApproved, op, decision.ReviewerID)
+		}
+	default:
+		op.ReviewDecisions = op.ReviewDecisions[:len(op.ReviewDecisions)-1]
+		return op.ApprovalState, fmt.Errorf("unknown review decision %q", decision.Decision)
+	}
 
-		inverseOp.Input = inverseInput
-		inverseOp.Context = fmt.Sprintf("Undo formatting in %v", op.Input["range"])
-		inverseOp.Preview = fmt.Sprintf("Restore previous format to %v", op.Input["range"])
+	log.Info().
+		Str("operation_id", operationID).
+		Str("review_id", op.ReviewID).
+		Str("reviewer_id", decision.ReviewerID).
+		Str("decision", decision.Decision).
+		Str("approval_state", string(op.ApprovalState)).
+		Msg("Review decision recorded")
 
-	case "insert_rows_columns":
-		// For insert, the inverse is delete
-		inverseOp.Type = "delete_rows_columns"
+	return op.ApprovalState, nil
+}
 
-		inverseInput := make(map[string]interface{})
-		inverseInput["position"] = op.Input["position"]
-		inverseInput["count"] = op.Input["count"]
-		inverseInput["type"] = op.Input["type"] // rows or columns
-		inverseInput["_is_undo"] = true
-		inverseInput["_original_op_id"] = op.ID
+// ApproveOperation records that userID approved an operation, who is then
+// credited when the operation is applied
+func (r *QueuedOperationRegistry) ApproveOperation(operationID, userID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-		inverseOp.Input = inverseInput
-		inverseOp.Context = fmt.Sprintf("Undo insert %v %v", op.Input["count"], op.Input["type"])
-		inverseOp.Preview = fmt.Sprintf("Delete %v %v at %v", op.Input["count"], op.Input["type"], op.Input["position"])
+	op, exists := r.operations[operationID]
+	if !exists {
+		r.auditEvent(&OperationEvent{Action: OperationApproved, UserID: userID, OperationID: operationID})
+		return
+	}
+	op.ApprovedBy = userID
+	r.auditOperation(OperationApproved, op, userID)
+}
 
-	case "create_named_range":
-		// For create named range, the inverse is delete
-		inverseOp.Type = "delete_named_range"
+// GetOperationStatus returns the status of a specific operation
+func (r *QueuedOperationRegistry) GetOperationStatus(operationID string) (OperationStatus, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 
-		inverseInput := make(map[string]interface{})
-		inverseInput["name"] = op.Input["name"]
-		inverseInput["_is_undo"] = true
-		inverseInput["_original_op_id"] = op.ID
+	op, exists := r.operations[operationID]
+	if !exists {
+		return "", fmt.Errorf("operation %s not found", operationID)
+	}
 
-		inverseOp.Input = inverseInput
-		inverseOp.Context = fmt.Sprintf("Undo create named range '%v'", op.Input["name"])
-		inverseOp.Preview = fmt.Sprintf("Delete named range '%v'", op.Input["name"])
+	return op.Status, nil
+}
 
-	default:
-		// For unknown operations, create a generic undo
+// generateInverseOperation creates an inverse operation that undoes the given operation
+func (r *QueuedOperationRegistry) generateInverseOperation(op *QueuedOperation) (*QueuedOperation, error) {
+	inverseOp := &QueuedOperation{
+		ID:        uuid.New().String(),
+		SessionID: op.SessionID,
+		Priority:  100, // High priority for undo operations
+		CreatedAt: time.Now(),
+		Status:    StatusQueued,
+	}
+
+	resultMap, _ := op.Result.(map[string]interface{})
+	if inverse := r.tools.Inverse(op.Type, op.Input, resultMap); inverse != nil {
+		inverse.Input["_original_op_id"] = op.ID
+		inverseOp.Type = inverse.Type
+		inverseOp.Input = inverse.Input
+		inverseOp.Context = inverse.Description
+		inverseOp.Preview = inverse.Preview
+	} else {
+		// For operations without an inverse, create a generic undo
 		inverseOp.Type = "undo_" + op.Type
 		inverseOp.Input = op.Input
 		inverseOp.Context = fmt.Sprintf("Undo: %s", op.Context)
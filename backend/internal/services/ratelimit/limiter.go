@@ -0,0 +1,330 @@
+// Package ratelimit limits requests with token buckets keyed by user, API
+// key or IP, with a separate budget per category of endpoint.
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// Category is a group of endpoints sharing a budget
+type Category string
+
+// Rate limited categories
+const (
+	CategoryChat         Category = "chat"
+	CategoryToolResponse Category = "tool_response"
+	CategoryUpload       Category = "upload"
+	CategoryAuth         Category = "auth"
+)
+
+// DefaultPlan is the plan of callers without one
+const DefaultPlan = "default"
+
+// policyTTL is how long a user's budgets are cached
+const policyTTL = time.Minute
+
+// Budget allows Requests requests per Per. The bucket holds Requests
+// tokens and refills completely in Per. A zero budget is unlimited.
+type Budget struct {
+	Requests int
+	Per      time.Duration
+}
+
+// Unlimited reports whether the budget does not limit requests
+func (b Budget) Unlimited() bool {
+	return b.Requests <= 0 || b.Per <= 0
+}
+
+// rate returns the tokens added per second
+func (b Budget) rate() float64 {
+	return float64(b.Requests) / b.Per.Seconds()
+}
+
+func (b Budget) String() string {
+	if b.Unlimited() {
+		return "unlimited"
+	}
+	for unit, d := range map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour, "d": 24 * time.Hour} {
+		if b.Per == d {
+			return fmt.Sprintf("%d/%s", b.Requests, unit)
+		}
+	}
+	return fmt.Sprintf("%d/%s", b.Requests, b.Per)
+}
+
+// ParseBudget parses budgets such as "30/m", "500/h" or "10/30s".
+// "unlimited" and "0" do not limit requests.
+func ParseBudget(s string) (Budget, error) {
+	s = strings.TrimSpace(s)
+	if s == "unlimited" || s == "0" {
+		return Budget{}, nil
+	}
+
+	count, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Budget{}, fmt.Errorf("invalid budget %q, want requests/period such as 30/m", s)
+	}
+	requests, err := strconv.Atoi(strings.TrimSpace(count))
+	if err != nil || requests < 0 {
+		return Budget{}, fmt.Errorf("invalid request count in budget %q", s)
+	}
+
+	period = strings.TrimSpace(period)
+	var per time.Duration
+	switch period {
+	case "s":
+		per = time.Second
+	case "m":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	case "d":
+		per = 24 * time.Hour
+	default:
+		per, err = time.ParseDuration(period)
+		if err != nil || per <= 0 {
+			return Budget{}, fmt.Errorf("invalid period in budget %q", s)
+		}
+	}
+	return Budget{Requests: requests, Per: per}, nil
+}
+
+func (b *Budget) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("budget must be a string such as \"30/m\": %w", err)
+	}
+	parsed, err := ParseBudget(s)
+	if err != nil {
+		return err
+	}
+	*b = parsed
+	return nil
+}
+
+func (b Budget) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.String())
+}
+
+// Budgets are the budgets of each category
+type Budgets map[Category]Budget
+
+// generous reports whether b allows more requests over time than other
+func (b Budget) generous(other Budget) bool {
+	if b.Unlimited() || other.Unlimited() {
+		return b.Unlimited() && !other.Unlimited()
+	}
+	return b.rate() > other.rate() || (b.rate() == other.rate() && b.Requests > other.Requests)
+}
+
+// with returns a copy of b overridden by overrides
+func (b Budgets) with(overrides Budgets) Budgets {
+	merged := make(Budgets, len(b)+len(overrides))
+	for category, budget := range b {
+		merged[category] = budget
+	}
+	for category, budget := range overrides {
+		merged[category] = budget
+	}
+	return merged
+}
+
+// ParseBudgets parses a budget per category
+func ParseBudgets(specs map[Category]string) (Budgets, error) {
+	budgets := make(Budgets, len(specs))
+	for category, spec := range specs {
+		budget, err := ParseBudget(spec)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", category, err)
+		}
+		budgets[category] = budget
+	}
+	return budgets, nil
+}
+
+// ParsePlans parses budgets per plan from JSON such as
+// {"pro": {"chat": "120/m", "upload": "100/h"}}
+func ParsePlans(s string) (map[string]Budgets, error) {
+	plans := map[string]Budgets{}
+	if strings.TrimSpace(s) == "" {
+		return plans, nil
+	}
+	if err := json.Unmarshal([]byte(s), &plans); err != nil {
+		return nil, fmt.Errorf("invalid rate limit plans: %w", err)
+	}
+	return plans, nil
+}
+
+// Identity is who a request is counted against
+type Identity struct {
+	// Key identifies the bucket owner, e.g. "user:<id>", "apikey:<digest>"
+	// or "ip:<address>"
+	Key string
+	// UserID is the authenticated user, if known
+	UserID uuid.UUID
+}
+
+// Policy is a plan and budget overrides, such as those of a workspace
+type Policy struct {
+	Plan    string  `json:"plan,omitempty"`
+	Budgets Budgets `json:"budgets,omitempty"`
+}
+
+// PolicySource looks up the policies that apply to a user. A user with
+// several policies gets the most generous budget of each category.
+type PolicySource interface {
+	Policies(ctx context.Context, userID uuid.UUID) ([]Policy, error)
+}
+
+// Result is the outcome of counting one request
+type Result struct {
+	Allowed bool
+	// Limit is the bucket size, zero when the budget is unlimited
+	Limit     int
+	Remaining int
+	// RetryAfter is how long until the next request is allowed
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+}
+
+// Key identifies a bucket
+type Key struct {
+	Identifier string
+	Endpoint   string
+}
+
+// Store keeps token buckets
+type Store interface {
+	// Take spends a token of a bucket if one is left
+	Take(ctx context.Context, key Key, budget Budget, now time.Time) (*Result, error)
+	// Sync drops idle buckets and saves the others, if the store persists
+	Sync(ctx context.Context, now time.Time) error
+}
+
+type cachedPolicy struct {
+	budgets Budgets
+	expires time.Time
+}
+
+// Limiter counts requests against the budgets of their caller
+type Limiter struct {
+	logger   *logrus.Logger
+	store    Store
+	defaults Budgets
+	plans    map[string]Budgets
+	source   PolicySource
+
+	mu       sync.Mutex
+	policies map[uuid.UUID]cachedPolicy
+}
+
+// NewLimiter creates a limiter whose callers get the defaults budgets
+// unless their plan or policy says otherwise
+func NewLimiter(logger *logrus.Logger, store Store, defaults Budgets) *Limiter {
+	return &Limiter{
+		logger:   logger,
+		store:    store,
+		defaults: defaults,
+		plans:    map[string]Budgets{},
+		policies: make(map[uuid.UUID]cachedPolicy),
+	}
+}
+
+// SetPlans sets the budgets of each plan, overriding the defaults
+func (l *Limiter) SetPlans(plans map[string]Budgets) {
+	l.plans = plans
+}
+
+// SetPolicySource sets where the plan and overrides of users are looked up
+func (l *Limiter) SetPolicySource(source PolicySource) {
+	l.source = source
+}
+
+// Allow counts a request of a category against the caller's budget
+func (l *Limiter) Allow(ctx context.Context, category Category, identity Identity) (*Result, error) {
+	budget := l.budgets(ctx, identity.UserID)[category]
+	if budget.Unlimited() {
+		return &Result{Allowed: true}, nil
+	}
+	return l.store.Take(ctx, Key{Identifier: identity.Key, Endpoint: string(category)}, budget, time.Now())
+}
+
+// budgets returns the budgets of a user, or the defaults for anonymous callers
+func (l *Limiter) budgets(ctx context.Context, userID uuid.UUID) Budgets {
+	budgets := l.defaults.with(l.plans[DefaultPlan])
+	if userID == uuid.Nil || l.source == nil {
+		return budgets
+	}
+
+	now := time.Now()
+	l.mu.Lock()
+	cached, ok := l.policies[userID]
+	l.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.budgets
+	}
+
+	policies, err := l.source.Policies(ctx, userID)
+	if err != nil {
+		l.logger.WithError(err).WithField("user_id", userID).Warn("Failed to look up rate limit policies, using defaults")
+		return budgets
+	}
+	if len(policies) > 0 {
+		budgets = l.merge(budgets, policies)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for id, p := range l.policies {
+		if now.After(p.expires) {
+			delete(l.policies, id)
+		}
+	}
+	l.policies[userID] = cachedPolicy{budgets: budgets, expires: now.Add(policyTTL)}
+	return budgets
+}
+
+// merge returns the most generous budget of each category across policies
+func (l *Limiter) merge(base Budgets, policies []Policy) Budgets {
+	var merged Budgets
+	for _, policy := range policies {
+		budgets := base.with(l.plans[policy.Plan]).with(policy.Budgets)
+		if merged == nil {
+			merged = budgets
+			continue
+		}
+		for category, budget := range budgets {
+			if current, ok := merged[category]; !ok || budget.generous(current) {
+				merged[category] = budget
+			}
+		}
+	}
+	return merged
+}
+
+// Run syncs the store every interval until ctx is done
+func (l *Limiter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := l.store.Sync(ctx, now); err != nil {
+				l.logger.WithError(err).Error("Failed to sync rate limits")
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+func TestParseBudget(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Budget
+	}{
+		{"30/m", Budget{30, time.Minute}},
+		{"500/h", Budget{500, time.Hour}},
+		{"10/30s", Budget{10, 30 * time.Second}},
+		{"unlimited", Budget{}},
+	}
+	for _, tt := range tests {
+		got, err := ParseBudget(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseBudget(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"30", "x/m", "30/fortnight", "-1/m"} {
+		if _, err := ParseBudget(in); err == nil {
+			t.Errorf("ParseBudget(%q) succeeded, want an error", in)
+		}
+	}
+}
+
+func TestMemoryStoreTake(t *testing.T) {
+	store := NewMemoryStore()
+	key := Key{Identifier: "user:1", Endpoint: string(CategoryChat)}
+	budget := Budget{Requests: 2, Per: time.Minute}
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if result, _ := store.Take(context.Background(), key, budget, now); !result.Allowed {
+			t.Fatalf("request %d rejected, want the burst of 2 allowed", i+1)
+		}
+	}
+	result, _ := store.Take(context.Background(), key, budget, now)
+	if result.Allowed || result.RetryAfter != 30*time.Second || result.Reset != time.Minute {
+		t.Errorf("third request = %+v, want rejected with retry after 30s and reset in 1m", result)
+	}
+
+	// One token refills every 30 seconds
+	if result, _ := store.Take(context.Background(), key, budget, now.Add(30*time.Second)); !result.Allowed {
+		t.Error("request after refill rejected")
+	}
+
+	store.Sync(context.Background(), now.Add(2*time.Minute))
+	if store.has(key) {
+		t.Error("full bucket kept after sync")
+	}
+}
+
+type staticPolicies []Policy
+
+func (p staticPolicies) Policies(ctx context.Context, userID uuid.UUID) ([]Policy, error) {
+	return p, nil
+}
+
+func TestLimiterBudgets(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	limiter := NewLimiter(logger, NewMemoryStore(), Budgets{
+		CategoryChat:   {Requests: 30, Per: time.Minute},
+		CategoryUpload: {Requests: 20, Per: time.Hour},
+	})
+	limiter.SetPlans(map[string]Budgets{"pro": {CategoryChat: {Requests: 120, Per: time.Minute}}})
+	limiter.SetPolicySource(staticPolicies{
+		{Plan: "pro"},
+		{Budgets: Budgets{CategoryChat: {Requests: 60, Per: time.Minute}, CategoryUpload: {}}},
+	})
+
+	if got := limiter.budgets(context.Background(), uuid.Nil)[CategoryChat]; got.Requests != 30 {
+		t.Errorf("anonymous chat budget = %v, want the default 30/m", got)
+	}
+	budgets := limiter.budgets(context.Background(), uuid.New())
+	if got := budgets[CategoryChat]; got.Requests != 120 {
+		t.Errorf("chat budget = %v, want the pro plan's 120/m", got)
+	}
+	if got := budgets[CategoryUpload]; !got.Unlimited() {
+		t.Errorf("upload budget = %v, want the workspace's unlimited override", got)
+	}
+}
@@ -0,0 +1,50 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/gridmate/backend/internal/repository"
+)
+
+// WorkspacePolicies reads rate limit policies from the settings of the
+// workspaces a user belongs to: "rate_limit_plan" names a plan and
+// "rate_limits" overrides budgets, e.g. {"chat": "120/m"}
+type WorkspacePolicies struct {
+	logger *logrus.Logger
+	repo   repository.WorkspaceRepository
+}
+
+// NewWorkspacePolicies creates a policy source backed by workspace settings
+func NewWorkspacePolicies(logger *logrus.Logger, repo repository.WorkspaceRepository) *WorkspacePolicies {
+	return &WorkspacePolicies{logger: logger, repo: repo}
+}
+
+func (p *WorkspacePolicies) Policies(ctx context.Context, userID uuid.UUID) ([]Policy, error) {
+	workspaces, err := p.repo.GetUserWorkspaces(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var policies []Policy
+	for _, workspace := range workspaces {
+		if len(workspace.Settings) == 0 {
+			continue
+		}
+		var settings struct {
+			Plan    string  `json:"rate_limit_plan"`
+			Budgets Budgets `json:"rate_limits"`
+		}
+		if err := json.Unmarshal(workspace.Settings, &settings); err != nil {
+			p.logger.WithError(err).WithField("workspace_id", workspace.ID).Warn("Ignoring invalid rate limit settings")
+			continue
+		}
+		if settings.Plan != "" || len(settings.Budgets) > 0 {
+			policies = append(policies, Policy{Plan: settings.Plan, Budgets: settings.Budgets})
+		}
+	}
+	return policies, nil
+}
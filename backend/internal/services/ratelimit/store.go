@@ -0,0 +1,183 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/gridmate/backend/internal/models"
+	"github.com/gridmate/backend/internal/repository"
+)
+
+// bucket is a token bucket as of updated
+type bucket struct {
+	budget  Budget
+	tokens  float64
+	updated time.Time
+}
+
+// newBucket returns a full bucket
+func newBucket(budget Budget, now time.Time) *bucket {
+	return &bucket{budget: budget, tokens: float64(budget.Requests), updated: now}
+}
+
+// take refills the bucket up to now and spends a token if one is left
+func (b *bucket) take(budget Budget, now time.Time) *Result {
+	if b.budget != budget {
+		// The budget changed: keep the share of the bucket that was spent
+		b.tokens = b.tokens / float64(b.budget.Requests) * float64(budget.Requests)
+		b.budget = budget
+	}
+	capacity := float64(budget.Requests)
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(capacity, b.tokens+elapsed*budget.rate())
+		b.updated = now
+	}
+
+	result := &Result{Limit: budget.Requests}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / budget.rate())
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = b.fullAt().Sub(now)
+	return result
+}
+
+// fullAt returns when the bucket is full again
+func (b *bucket) fullAt() time.Time {
+	missing := float64(b.budget.Requests) - b.tokens
+	return b.updated.Add(seconds(missing / b.budget.rate()))
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// MemoryStore keeps buckets in process
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[Key]*bucket
+}
+
+// NewMemoryStore creates an in-process store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[Key]*bucket)}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key Key, budget Budget, now time.Time) (*Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.take(key, budget, now, nil), nil
+}
+
+// take spends a token of a bucket, starting it from seed, or full, if the
+// store does not have it. The caller must hold s.mu.
+func (s *MemoryStore) take(key Key, budget Budget, now time.Time, seed *bucket) *Result {
+	b, ok := s.buckets[key]
+	if !ok {
+		b = seed
+		if b == nil {
+			b = newBucket(budget, now)
+		}
+		s.buckets[key] = b
+	}
+	return b.take(budget, now)
+}
+
+// has reports whether the store has a bucket
+func (s *MemoryStore) has(key Key) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.buckets[key]
+	return ok
+}
+
+// Sync drops the buckets that are full again, which behave like new ones
+func (s *MemoryStore) Sync(ctx context.Context, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, b := range s.buckets {
+		if !b.fullAt().After(now) {
+			delete(s.buckets, key)
+		}
+	}
+	return nil
+}
+
+// PersistentStore keeps buckets in process and saves them to the
+// rate_limits table on every sync, so limits survive restarts and are
+// shared, with a delay of one sync, by instances that start later
+type PersistentStore struct {
+	mem   *MemoryStore
+	repo  repository.RateLimitRepository
+	dirty map[Key]bool
+}
+
+// NewPersistentStore creates a store saved to repo
+func NewPersistentStore(repo repository.RateLimitRepository) *PersistentStore {
+	return &PersistentStore{
+		mem:   NewMemoryStore(),
+		repo:  repo,
+		dirty: make(map[Key]bool),
+	}
+}
+
+// Take spends a token of a bucket, loading the bucket from the database the
+// first time this process sees it
+func (s *PersistentStore) Take(ctx context.Context, key Key, budget Budget, now time.Time) (*Result, error) {
+	var seed *bucket
+	if !s.mem.has(key) {
+		saved, err := s.repo.Latest(ctx, key.Identifier, key.Endpoint, now)
+		if err != nil {
+			return nil, err
+		}
+		if saved != nil {
+			seed = &bucket{
+				budget:  budget,
+				tokens:  math.Max(0, float64(budget.Requests-saved.Requests)),
+				updated: saved.WindowStart,
+			}
+		}
+	}
+
+	s.mem.mu.Lock()
+	defer s.mem.mu.Unlock()
+	s.dirty[key] = true
+	return s.mem.take(key, budget, now, seed), nil
+}
+
+// Sync saves the buckets used since the last sync, removes the saved
+// buckets that are full again and drops idle buckets from memory
+func (s *PersistentStore) Sync(ctx context.Context, now time.Time) error {
+	s.mem.mu.Lock()
+	limits := make([]*models.RateLimit, 0, len(s.dirty))
+	for key := range s.dirty {
+		b, ok := s.mem.buckets[key]
+		if !ok {
+			continue
+		}
+		limits = append(limits, &models.RateLimit{
+			Identifier:  key.Identifier,
+			Endpoint:    key.Endpoint,
+			Requests:    int(math.Ceil(float64(b.budget.Requests) - b.tokens)),
+			WindowStart: b.updated,
+			WindowEnd:   b.fullAt(),
+		})
+	}
+	s.dirty = make(map[Key]bool)
+	s.mem.mu.Unlock()
+
+	if len(limits) > 0 {
+		if err := s.repo.Save(ctx, limits); err != nil {
+			return err
+		}
+	}
+	if _, err := s.repo.DeleteExpired(ctx, now); err != nil {
+		return err
+	}
+	return s.mem.Sync(ctx, now)
+}
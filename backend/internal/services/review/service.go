@@ -0,0 +1,466 @@
+// Package review implements four-eyes review of queued AI changes: a batch
+// of operations is submitted, workspace reviewers approve or reject each
+// operation, and only approved operations may be applied.
+package review
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/gridmate/backend/internal/auth"
+	"github.com/gridmate/backend/internal/models"
+	"github.com/gridmate/backend/internal/repository"
+	"github.com/gridmate/backend/internal/services"
+)
+
+// ReviewerRole is the workspace role allowed to review AI changes
+const ReviewerRole = "reviewer"
+
+// Review statuses
+const (
+	StatusPending   = models.ReviewPending
+	StatusCompleted = models.ReviewCompleted
+)
+
+// reviewEntity is the audit log entity type of review events
+const reviewEntity = "ai_operation_review"
+
+var (
+	// ErrReviewNotFound is returned when a review does not exist or is not visible to the caller
+	ErrReviewNotFound = errors.New("review not found")
+	// ErrForbidden is returned when the caller may not submit or decide on the review
+	ErrForbidden = errors.New("not permitted to review these changes")
+	// ErrInvalidReview is returned when a submission or decision cannot be accepted
+	ErrInvalidReview = errors.New("invalid review")
+)
+
+// Notifier delivers review events to Excel sessions
+type Notifier interface {
+	ForwardToClient(sessionID string, messageType string, data interface{}) error
+}
+
+// Review is a batch of queued operations awaiting four-eyes approval
+type Review = models.OperationReview
+
+// SubmitRequest submits a session's queued operations for review. All
+// queued operations of the session are submitted when OperationIDs is
+// empty, and all workspace reviewers are assigned when ReviewerIDs is.
+type SubmitRequest struct {
+	SessionID         string      `json:"session_id" validate:"required"`
+	WorkspaceID       uuid.UUID   `json:"workspace_id" validate:"required"`
+	OperationIDs      []string    `json:"operation_ids"`
+	ReviewerIDs       []uuid.UUID `json:"reviewer_ids"`
+	RequiredApprovals int         `json:"required_approvals"`
+	Comment           string      `json:"comment"`
+}
+
+// DecisionRequest approves or rejects one operation of a review
+type DecisionRequest struct {
+	Decision string `json:"decision" validate:"required,oneof=approve reject"`
+	Comment  string `json:"comment"`
+}
+
+// Detail is a review together with the current state of its operations
+type Detail struct {
+	*Review
+	Operations []*services.QueuedOperation `json:"operations"`
+}
+
+// Service tracks reviews of queued operations
+type Service struct {
+	logger        *logrus.Logger
+	registry      *services.QueuedOperationRegistry
+	sessionOwner  func(sessionID string) string
+	reviewRepo    repository.OperationReviewRepository
+	workspaceRepo repository.WorkspaceRepository
+	auditRepo     repository.AuditLogRepository
+	notifier      Notifier
+	userSessions  func(userID string) []string
+}
+
+// NewService creates a review service for the operations in registry.
+// sessionOwner returns the user an Excel session belongs to.
+func NewService(
+	logger *logrus.Logger,
+	registry *services.QueuedOperationRegistry,
+	sessionOwner func(sessionID string) string,
+	reviewRepo repository.OperationReviewRepository,
+	workspaceRepo repository.WorkspaceRepository,
+	auditRepo repository.AuditLogRepository,
+) *Service {
+	return &Service{
+		logger:        logger,
+		registry:      registry,
+		sessionOwner:  sessionOwner,
+		reviewRepo:    reviewRepo,
+		workspaceRepo: workspaceRepo,
+		auditRepo:     auditRepo,
+	}
+}
+
+// SetNotifier sets how review events reach users. userSessions returns the
+// Excel sessions a user is connected with.
+func (s *Service) SetNotifier(notifier Notifier, userSessions func(userID string) []string) {
+	s.notifier = notifier
+	s.userSessions = userSessions
+}
+
+// Submit places queued operations under review and notifies the assigned
+// reviewers. The submitter can never review their own changes. Sessions are
+// not tied to a workspace, so only the session's own user may submit its
+// operations, and only to a workspace they belong to.
+func (s *Service) Submit(ctx context.Context, userID uuid.UUID, req *SubmitRequest) (*Detail, error) {
+	if req.SessionID == "" {
+		return nil, fmt.Errorf("%w: session_id is required", ErrInvalidReview)
+	}
+	if s.sessionOwner == nil || s.sessionOwner(req.SessionID) != userID.String() {
+		return nil, ErrForbidden
+	}
+	if _, err := auth.WorkspaceRole(ctx, s.workspaceRepo, req.WorkspaceID, userID); err != nil {
+		return nil, ErrForbidden
+	}
+
+	reviewers, err := s.assignReviewers(ctx, userID, req)
+	if err != nil {
+		return nil, err
+	}
+	required := req.RequiredApprovals
+	if required <= 0 {
+		required = 1
+	}
+	if required > len(reviewers) {
+		return nil, fmt.Errorf("%w: %d approvals required but only %d reviewers assigned", ErrInvalidReview, required, len(reviewers))
+	}
+
+	operationIDs := req.OperationIDs
+	if len(operationIDs) == 0 {
+		for _, op := range s.registry.GetPendingOperations(req.SessionID) {
+			if op.ApprovalState == "" {
+				operationIDs = append(operationIDs, op.ID)
+			}
+		}
+	}
+	if len(operationIDs) == 0 {
+		return nil, fmt.Errorf("%w: no queued operations to review", ErrInvalidReview)
+	}
+	for _, id := range operationIDs {
+		if op, ok := s.registry.GetOperation(id); !ok || op.SessionID != req.SessionID {
+			return nil, fmt.Errorf("%w: operation %s is not queued in session %s", ErrInvalidReview, id, req.SessionID)
+		}
+	}
+
+	review := &Review{
+		SessionID:         req.SessionID,
+		WorkspaceID:       req.WorkspaceID,
+		SubmittedBy:       userID,
+		Reviewers:         reviewers,
+		OperationIDs:      operationIDs,
+		RequiredApprovals: required,
+		Comment:           req.Comment,
+		Status:            StatusPending,
+	}
+	if err := s.reviewRepo.Create(ctx, review); err != nil {
+		return nil, err
+	}
+
+	if err := s.registry.SubmitForReview(review.ID.String(), operationIDs); err != nil {
+		if deleteErr := s.reviewRepo.Delete(ctx, review.ID); deleteErr != nil {
+			s.logger.WithError(deleteErr).WithField("review_id", review.ID).Error("Failed to remove refused review")
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidReview, err)
+	}
+	s.audit(ctx, userID, review, "ai.review.submitted", review)
+
+	detail := s.detail(review)
+	for _, reviewer := range reviewers {
+		s.notifyUser(reviewer, "reviewRequested", detail)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"review_id":    review.ID,
+		"session_id":   review.SessionID,
+		"workspace_id": review.WorkspaceID,
+		"operations":   len(operationIDs),
+		"reviewers":    len(reviewers),
+	}).Info("AI changes submitted for review")
+
+	return detail, nil
+}
+
+// Decide records a reviewer's decision on one operation. Reviewers must
+// still hold the reviewer role in the workspace. The submitter's session is
+// told when an operation is approved, so the add-in can apply it, or
+// rejected.
+func (s *Service) Decide(ctx context.Context, userID uuid.UUID, reviewID, operationID string, req *DecisionRequest) (*Detail, error) {
+	review, err := s.review(ctx, reviewID)
+	if err != nil {
+		return nil, err
+	}
+	if !contains(review.Reviewers, userID.String()) {
+		return nil, ErrForbidden
+	}
+	if role, err := auth.WorkspaceRole(ctx, s.workspaceRepo, review.WorkspaceID, userID); err != nil || role != ReviewerRole {
+		return nil, ErrForbidden
+	}
+	if !contains(review.OperationIDs, operationID) {
+		return nil, fmt.Errorf("%w: operation %s is not part of review %s", ErrInvalidReview, operationID, reviewID)
+	}
+	if req.Decision != services.DecisionApprove && req.Decision != services.DecisionReject {
+		return nil, fmt.Errorf("%w: decision must be %q or %q", ErrInvalidReview, services.DecisionApprove, services.DecisionReject)
+	}
+	if _, ok := s.registry.GetOperation(operationID); !ok {
+		s.completeIfDecided(ctx, review)
+		return nil, fmt.Errorf("%w: operation %s has expired", ErrInvalidReview, operationID)
+	}
+
+	decision := services.ReviewDecision{
+		ReviewerID: userID.String(),
+		Decision:   req.Decision,
+		Comment:    req.Comment,
+		DecidedAt:  time.Now(),
+	}
+	state, err := s.registry.RecordReviewDecision(operationID, decision, review.RequiredApprovals)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidReview, err)
+	}
+	if data, err := json.Marshal(decision); err != nil {
+		s.logger.WithError(err).WithField("review_id", review.ID).Error("Failed to encode review decision")
+	} else if err := s.reviewRepo.AddDecision(ctx, review.ID, operationID, data); err != nil {
+		// The operation holds the decision either way; the audit log
+		// below keeps the trail if the review row could not be updated
+		s.logger.WithError(err).WithField("review_id", review.ID).Error("Failed to store review decision")
+	}
+
+	trail := map[string]interface{}{
+		"operation_id":   operationID,
+		"decision":       decision,
+		"approval_state": state,
+	}
+	s.audit(ctx, userID, review, "ai.review."+req.Decision, trail)
+
+	if state != services.ApprovalPending {
+		op, _ := s.registry.GetOperation(operationID)
+		s.notifySession(review.SessionID, "operationReviewed", map[string]interface{}{
+			"review_id": reviewID,
+			"operation": op,
+		})
+	}
+	s.completeIfDecided(ctx, review)
+
+	return s.detail(review), nil
+}
+
+// Get returns a review to its submitter or one of its reviewers
+func (s *Service) Get(ctx context.Context, userID uuid.UUID, reviewID string) (*Detail, error) {
+	review, err := s.review(ctx, reviewID)
+	if err != nil {
+		return nil, err
+	}
+	if review.SubmittedBy != userID && !contains(review.Reviewers, userID.String()) {
+		return nil, ErrReviewNotFound
+	}
+	return s.detail(review), nil
+}
+
+// ListAssigned returns the pending reviews assigned to a reviewer
+func (s *Service) ListAssigned(ctx context.Context, userID uuid.UUID) ([]*Detail, error) {
+	assigned, err := s.reviewRepo.ListPendingForReviewer(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	details := make([]*Detail, 0, len(assigned))
+	for _, review := range assigned {
+		if auth.WorkspaceAllowed(ctx, review.WorkspaceID) {
+			details = append(details, s.detail(review))
+		}
+	}
+	return details, nil
+}
+
+// assignReviewers checks the requested reviewers, or picks every reviewer
+// of the workspace, leaving out the submitter
+func (s *Service) assignReviewers(ctx context.Context, submitter uuid.UUID, req *SubmitRequest) ([]string, error) {
+	var reviewers []string
+	if len(req.ReviewerIDs) == 0 {
+		members, err := s.workspaceRepo.GetMembers(ctx, req.WorkspaceID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get workspace members: %w", err)
+		}
+		for _, member := range members {
+			if member.Role == ReviewerRole && member.UserID != submitter {
+				reviewers = append(reviewers, member.UserID.String())
+			}
+		}
+	} else {
+		for _, id := range req.ReviewerIDs {
+			if id == submitter {
+				return nil, fmt.Errorf("%w: submitters cannot review their own changes", ErrInvalidReview)
+			}
+			role, err := s.workspaceRepo.GetMemberRole(ctx, req.WorkspaceID, id)
+			if err != nil || role != ReviewerRole {
+				return nil, fmt.Errorf("%w: %s is not a reviewer in this workspace", ErrInvalidReview, id)
+			}
+			if !contains(reviewers, id.String()) {
+				reviewers = append(reviewers, id.String())
+			}
+		}
+	}
+
+	if len(reviewers) == 0 {
+		return nil, fmt.Errorf("%w: the workspace has no other members with the %s role", ErrInvalidReview, ReviewerRole)
+	}
+	return reviewers, nil
+}
+
+// completeIfDecided closes a review once every operation is decided.
+// Operations no longer in the registry can never be decided, so they count
+// as expired and are recorded as such.
+func (s *Service) completeIfDecided(ctx context.Context, review *Review) {
+	summary := map[services.ApprovalState]int{}
+	var expired []string
+	for _, id := range review.OperationIDs {
+		op, ok := s.registry.GetOperation(id)
+		if !ok {
+			expired = append(expired, id)
+			summary[services.ApprovalExpired]++
+			continue
+		}
+		if op.ApprovalState == services.ApprovalPending {
+			return
+		}
+		summary[op.ApprovalState]++
+	}
+
+	completed, err := s.reviewRepo.Complete(ctx, review.ID)
+	if err != nil {
+		s.logger.WithError(err).WithField("review_id", review.ID).Error("Failed to complete review")
+		return
+	}
+	if !completed {
+		return
+	}
+	now := time.Now()
+	review.Status = StatusCompleted
+	review.CompletedAt = &now
+
+	for _, id := range expired {
+		s.expire(ctx, review, id, now)
+	}
+	s.audit(ctx, uuid.Nil, review, "ai.review.completed", summary)
+	s.notifySession(review.SessionID, "reviewCompleted", s.detail(review))
+}
+
+// expire records that an operation of a review expired before it was decided
+func (s *Service) expire(ctx context.Context, review *Review, operationID string, at time.Time) {
+	decision := services.ReviewDecision{Decision: string(services.ApprovalExpired), DecidedAt: at}
+	if data, err := json.Marshal(decision); err != nil {
+		s.logger.WithError(err).WithField("review_id", review.ID).Error("Failed to encode expired operation")
+	} else if err := s.reviewRepo.AddDecision(ctx, review.ID, operationID, data); err != nil {
+		s.logger.WithError(err).WithField("review_id", review.ID).Error("Failed to store expired operation")
+	}
+	s.audit(ctx, uuid.Nil, review, "ai.review.expired", map[string]interface{}{
+		"operation_id":   operationID,
+		"approval_state": services.ApprovalExpired,
+	})
+}
+
+// review loads a review visible to the caller's workspace
+func (s *Service) review(ctx context.Context, reviewID string) (*Review, error) {
+	id, err := uuid.Parse(reviewID)
+	if err != nil {
+		return nil, ErrReviewNotFound
+	}
+	review, err := s.reviewRepo.GetByID(ctx, id)
+	if errors.Is(err, repository.ErrOperationReviewNotFound) {
+		return nil, ErrReviewNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !auth.WorkspaceAllowed(ctx, review.WorkspaceID) {
+		return nil, ErrReviewNotFound
+	}
+	return review, nil
+}
+
+func (s *Service) detail(review *Review) *Detail {
+	detail := &Detail{Review: review}
+	for _, id := range review.OperationIDs {
+		if op, ok := s.registry.GetOperation(id); ok {
+			detail.Operations = append(detail.Operations, op)
+		}
+	}
+	return detail
+}
+
+// audit records a review event in the audit log. Decisions also stay on
+// their operations, so a failed write is logged rather than returned.
+func (s *Service) audit(ctx context.Context, userID uuid.UUID, review *Review, action string, changes interface{}) {
+	if s.auditRepo == nil {
+		return
+	}
+	reviewID := review.ID.String()
+
+	data, err := json.Marshal(changes)
+	if err != nil {
+		s.logger.WithError(err).WithField("review_id", reviewID).Error("Failed to encode review event")
+		return
+	}
+
+	entityType := reviewEntity
+	workspaceID := review.WorkspaceID
+	entry := &models.AuditLog{
+		WorkspaceID: &workspaceID,
+		Action:      action,
+		EntityType:  &entityType,
+		Changes:     data,
+	}
+	entityID := review.ID
+	entry.EntityID = &entityID
+	if userID != uuid.Nil {
+		entry.UserID = &userID
+	}
+	if err := s.auditRepo.Create(ctx, entry); err != nil {
+		s.logger.WithError(err).WithFields(logrus.Fields{
+			"review_id": reviewID,
+			"action":    action,
+		}).Error("Failed to record review event")
+	}
+}
+
+func (s *Service) notifyUser(userID, messageType string, data interface{}) {
+	if s.userSessions == nil {
+		return
+	}
+	for _, sessionID := range s.userSessions(userID) {
+		s.notifySession(sessionID, messageType, data)
+	}
+}
+
+func (s *Service) notifySession(sessionID, messageType string, data interface{}) {
+	if s.notifier == nil || sessionID == "" {
+		return
+	}
+	if err := s.notifier.ForwardToClient(sessionID, messageType, data); err != nil {
+		s.logger.WithError(err).WithFields(logrus.Fields{
+			"session_id": sessionID,
+			"type":       messageType,
+		}).Warn("Failed to send review notification")
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,282 @@
+package review
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/gridmate/backend/internal/auth"
+	"github.com/gridmate/backend/internal/models"
+	"github.com/gridmate/backend/internal/repository"
+	"github.com/gridmate/backend/internal/services"
+)
+
+type testWorkspaces struct {
+	repository.WorkspaceRepository
+	roles map[uuid.UUID]string
+}
+
+func (w *testWorkspaces) GetMemberRole(ctx context.Context, workspaceID, userID uuid.UUID) (string, error) {
+	if role, ok := w.roles[userID]; ok {
+		return role, nil
+	}
+	return "", errors.New("not a member")
+}
+
+func (w *testWorkspaces) GetMembers(ctx context.Context, workspaceID uuid.UUID) ([]*models.WorkspaceMember, error) {
+	var members []*models.WorkspaceMember
+	for id, role := range w.roles {
+		members = append(members, &models.WorkspaceMember{WorkspaceID: workspaceID, UserID: id, Role: role})
+	}
+	return members, nil
+}
+
+type testReviews struct {
+	reviews map[uuid.UUID]*models.OperationReview
+}
+
+func (r *testReviews) Create(ctx context.Context, review *models.OperationReview) error {
+	review.ID = uuid.New()
+	review.Decisions = json.RawMessage(`{}`)
+	r.reviews[review.ID] = review
+	return nil
+}
+
+func (r *testReviews) GetByID(ctx context.Context, id uuid.UUID) (*models.OperationReview, error) {
+	review, ok := r.reviews[id]
+	if !ok {
+		return nil, repository.ErrOperationReviewNotFound
+	}
+	stored := *review
+	return &stored, nil
+}
+
+func (r *testReviews) ListPendingForReviewer(ctx context.Context, reviewerID uuid.UUID) ([]*models.OperationReview, error) {
+	var pending []*models.OperationReview
+	for _, review := range r.reviews {
+		if review.Status == models.ReviewPending && contains(review.Reviewers, reviewerID.String()) {
+			pending = append(pending, review)
+		}
+	}
+	return pending, nil
+}
+
+func (r *testReviews) AddDecision(ctx context.Context, id uuid.UUID, operationID string, decision json.RawMessage) error {
+	review := r.reviews[id]
+	decisions := map[string][]json.RawMessage{}
+	json.Unmarshal(review.Decisions, &decisions)
+	decisions[operationID] = append(decisions[operationID], decision)
+	review.Decisions, _ = json.Marshal(decisions)
+	return nil
+}
+
+func (r *testReviews) Complete(ctx context.Context, id uuid.UUID) (bool, error) {
+	review := r.reviews[id]
+	if review.Status == models.ReviewCompleted {
+		return false, nil
+	}
+	review.Status = models.ReviewCompleted
+	return true, nil
+}
+
+func (r *testReviews) Delete(ctx context.Context, id uuid.UUID) error {
+	delete(r.reviews, id)
+	return nil
+}
+
+type recordingNotifier struct {
+	messages []string
+}
+
+func (n *recordingNotifier) ForwardToClient(sessionID, messageType string, data interface{}) error {
+	n.messages = append(n.messages, sessionID+" "+messageType)
+	return nil
+}
+
+func TestFourEyesReview(t *testing.T) {
+	ctx := context.Background()
+	analyst, reviewer, member := uuid.New(), uuid.New(), uuid.New()
+	workspaces := &testWorkspaces{roles: map[uuid.UUID]string{
+		analyst:  ReviewerRole,
+		reviewer: ReviewerRole,
+		member:   "member",
+	}}
+
+	registry := services.NewQueuedOperationRegistry()
+	for _, id := range []string{"op1", "op2"} {
+		if err := registry.QueueOperation(&services.QueuedOperation{ID: id, SessionID: "s1", Type: "write_range"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	notifier := &recordingNotifier{}
+	sessionOwner := func(sessionID string) string {
+		if sessionID == "s1" {
+			return analyst.String()
+		}
+		return ""
+	}
+	reviews := &testReviews{reviews: make(map[uuid.UUID]*models.OperationReview)}
+	service := NewService(logrus.New(), registry, sessionOwner, reviews, workspaces, nil)
+	service.SetNotifier(notifier, func(userID string) []string {
+		if userID == reviewer.String() {
+			return []string{"reviewer-session"}
+		}
+		return nil
+	})
+
+	if _, err := service.Submit(ctx, member, &SubmitRequest{SessionID: "s1", WorkspaceID: uuid.New()}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("submission of another user's session error = %v, want ErrForbidden", err)
+	}
+	otherWorkspace := auth.WithBoundWorkspace(ctx, uuid.New())
+	if _, err := service.Submit(otherWorkspace, analyst, &SubmitRequest{SessionID: "s1", WorkspaceID: uuid.New()}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("submission bound to another workspace error = %v, want ErrForbidden", err)
+	}
+	if _, err := service.Submit(ctx, analyst, &SubmitRequest{SessionID: "s1", WorkspaceID: uuid.New(), ReviewerIDs: []uuid.UUID{analyst}}); !errors.Is(err, ErrInvalidReview) {
+		t.Errorf("self-review error = %v, want ErrInvalidReview", err)
+	}
+
+	detail, err := service.Submit(ctx, analyst, &SubmitRequest{SessionID: "s1", WorkspaceID: uuid.New()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(detail.Reviewers) != 1 || detail.Reviewers[0] != reviewer.String() || len(detail.OperationIDs) != 2 {
+		t.Fatalf("review = %+v, want both operations assigned to the other reviewer", detail.Review)
+	}
+	reviewID := detail.ID.String()
+	if assigned, err := service.ListAssigned(ctx, reviewer); err != nil || len(assigned) != 1 {
+		t.Errorf("assigned reviews = %v, %v, want the submitted review", assigned, err)
+	}
+	if _, err := service.Get(otherWorkspace, analyst, reviewID); !errors.Is(err, ErrReviewNotFound) {
+		t.Errorf("get bound to another workspace error = %v, want ErrReviewNotFound", err)
+	}
+	if registry.CanExecute("op1") {
+		t.Error("operations under review should not be executable")
+	}
+	if err := registry.MarkOperationComplete("op1", nil); err == nil {
+		t.Error("unapproved operations should not complete")
+	}
+
+	if _, err := service.Decide(ctx, member, reviewID, "op1", &DecisionRequest{Decision: services.DecisionApprove}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("decision by unassigned member error = %v, want ErrForbidden", err)
+	}
+	if _, err := service.Decide(ctx, reviewer, reviewID, "op1", &DecisionRequest{Decision: services.DecisionApprove, Comment: "Ties to the 10-K"}); err != nil {
+		t.Fatal(err)
+	}
+	if !registry.CanExecute("op1") {
+		t.Error("approved operations should be executable")
+	}
+	if _, err := service.Decide(ctx, reviewer, reviewID, "op1", &DecisionRequest{Decision: services.DecisionReject}); !errors.Is(err, ErrInvalidReview) {
+		t.Errorf("second decision error = %v, want ErrInvalidReview", err)
+	}
+
+	detail, err = service.Decide(ctx, reviewer, reviewID, "op2", &DecisionRequest{Decision: services.DecisionReject, Comment: "Wrong period"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if detail.Status != StatusCompleted {
+		t.Errorf("review status = %s, want %s", detail.Status, StatusCompleted)
+	}
+	stored := reviews.reviews[detail.ID]
+	var trail map[string][]services.ReviewDecision
+	if err := json.Unmarshal(stored.Decisions, &trail); err != nil || len(trail["op1"]) != 1 || trail["op2"][0].Comment != "Wrong period" {
+		t.Errorf("stored decisions = %s, want one per operation", stored.Decisions)
+	}
+	if stored.Status != StatusCompleted {
+		t.Errorf("stored status = %s, want %s", stored.Status, StatusCompleted)
+	}
+	op2, _ := registry.GetOperation("op2")
+	if op2.ApprovalState != services.ApprovalRejected || op2.Status != services.StatusCancelled || len(op2.ReviewDecisions) != 1 {
+		t.Errorf("rejected operation = %+v", op2)
+	}
+	op2.Status = services.StatusQueued
+	if again, _ := registry.GetOperation("op2"); again.Status != services.StatusCancelled {
+		t.Error("GetOperation should return a copy of the operation")
+	}
+
+	// With review required, changes never submitted for review are held too
+	if err := registry.QueueOperation(&services.QueuedOperation{ID: "op3", SessionID: "s1", Type: "write_range"}); err != nil {
+		t.Fatal(err)
+	}
+	registry.SetReviewRequired(true)
+	if registry.CanExecute("op3") || registry.ApprovalBlock("op3") == "" || registry.ApprovalBlock("unknown") == "" {
+		t.Error("unreviewed operations should be held when review is required")
+	}
+	if !registry.CanExecute("op1") {
+		t.Error("approved operations should stay executable when review is required")
+	}
+
+	want := []string{"reviewer-session reviewRequested", "s1 operationReviewed", "s1 operationReviewed", "s1 reviewCompleted"}
+	if len(notifier.messages) != len(want) {
+		t.Fatalf("notifications = %v, want %v", notifier.messages, want)
+	}
+	for i := range want {
+		if notifier.messages[i] != want[i] {
+			t.Errorf("notification %d = %s, want %s", i, notifier.messages[i], want[i])
+		}
+	}
+}
+
+func TestReviewRechecksReviewersAndExpiresLostOperations(t *testing.T) {
+	ctx := context.Background()
+	analyst, reviewer := uuid.New(), uuid.New()
+	sessionOwner := func(sessionID string) string { return analyst.String() }
+
+	submit := func(t *testing.T) (*testWorkspaces, *testReviews, string) {
+		workspaces := &testWorkspaces{roles: map[uuid.UUID]string{analyst: "member", reviewer: ReviewerRole}}
+		reviews := &testReviews{reviews: make(map[uuid.UUID]*models.OperationReview)}
+		registry := services.NewQueuedOperationRegistry()
+		for _, id := range []string{"op1", "op2"} {
+			if err := registry.QueueOperation(&services.QueuedOperation{ID: id, SessionID: "s1", Type: "write_range"}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		detail, err := NewService(logrus.New(), registry, sessionOwner, reviews, workspaces, nil).
+			Submit(ctx, analyst, &SubmitRequest{SessionID: "s1", WorkspaceID: uuid.New()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return workspaces, reviews, detail.ID.String()
+	}
+
+	t.Run("demoted reviewers cannot decide", func(t *testing.T) {
+		workspaces, reviews, reviewID := submit(t)
+		service := NewService(logrus.New(), services.NewQueuedOperationRegistry(), sessionOwner, reviews, workspaces, nil)
+
+		workspaces.roles[reviewer] = "member"
+		if _, err := service.Decide(ctx, reviewer, reviewID, "op1", &DecisionRequest{Decision: services.DecisionApprove}); !errors.Is(err, ErrForbidden) {
+			t.Errorf("decision by demoted reviewer error = %v, want ErrForbidden", err)
+		}
+		delete(workspaces.roles, reviewer)
+		if _, err := service.Decide(ctx, reviewer, reviewID, "op1", &DecisionRequest{Decision: services.DecisionApprove}); !errors.Is(err, ErrForbidden) {
+			t.Errorf("decision by removed reviewer error = %v, want ErrForbidden", err)
+		}
+	})
+
+	t.Run("operations lost in a restart expire", func(t *testing.T) {
+		workspaces, reviews, reviewID := submit(t)
+		// A restart empties the registry while the review row survives
+		service := NewService(logrus.New(), services.NewQueuedOperationRegistry(), sessionOwner, reviews, workspaces, nil)
+
+		if _, err := service.Decide(ctx, reviewer, reviewID, "op1", &DecisionRequest{Decision: services.DecisionApprove}); !errors.Is(err, ErrInvalidReview) {
+			t.Errorf("decision on a lost operation error = %v, want ErrInvalidReview", err)
+		}
+		stored := reviews.reviews[uuid.MustParse(reviewID)]
+		if stored.Status != StatusCompleted {
+			t.Errorf("stored status = %s, want %s", stored.Status, StatusCompleted)
+		}
+		var trail map[string][]services.ReviewDecision
+		if err := json.Unmarshal(stored.Decisions, &trail); err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range []string{"op1", "op2"} {
+			if len(trail[id]) != 1 || trail[id][0].Decision != string(services.ApprovalExpired) {
+				t.Errorf("stored decisions for %s = %+v, want one expiry", id, trail[id])
+			}
+		}
+	})
+}
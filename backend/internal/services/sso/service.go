@@ -0,0 +1,311 @@
+// Package sso signs users in with an OpenID Connect provider: it links
+// provider accounts to users, provisions new users on first sign-in and
+// adds them to workspaces according to their group claims.
+package sso
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/gridmate/backend/internal/auth/oidc"
+	"github.com/gridmate/backend/internal/models"
+	"github.com/gridmate/backend/internal/repository"
+)
+
+// loginTimeout is how long a started sign-in may take to complete
+const loginTimeout = 10 * time.Minute
+
+// maxPendingLogins caps the sign-ins awaiting their callback, so
+// unauthenticated login requests cannot grow memory without bound
+const maxPendingLogins = 10000
+
+// defaultGroupRole is the workspace role of a group mapping without one
+const defaultGroupRole = "member"
+
+var (
+	// ErrInvalidState is returned for callbacks of unknown, used or expired
+	// sign-ins, and for callbacks in a browser other than the one that began them
+	ErrInvalidState = errors.New("unknown or expired sign-in")
+	// ErrTooManyLogins is returned when too many sign-ins are in progress
+	ErrTooManyLogins = errors.New("too many sign-ins in progress")
+	// ErrEmailNotVerified is returned when an unverified email would be
+	// linked to or provision an account
+	ErrEmailNotVerified = errors.New("the identity provider has not verified this email address")
+	// ErrNotProvisioned is returned when a new user signs in and provisioning is off
+	ErrNotProvisioned = errors.New("no account exists for this user")
+)
+
+// GroupMapping adds the members of a provider group to a workspace
+type GroupMapping struct {
+	WorkspaceID uuid.UUID `json:"workspace_id"`
+	Role        string    `json:"role"`
+}
+
+// Config configures sign-in with one provider
+type Config struct {
+	// Provider names the provider in linked accounts, e.g. "azure_ad"
+	Provider string
+	// GroupsClaim is the ID token claim listing the user's groups
+	GroupsClaim string
+	// GroupWorkspaces maps provider groups to workspace memberships
+	GroupWorkspaces map[string]GroupMapping
+	// AutoProvision creates users signing in for the first time
+	AutoProvision bool
+}
+
+// ParseGroupWorkspaces parses group mappings from JSON such as
+// {"finance-analysts": {"workspace_id": "...", "role": "member"}}
+func ParseGroupWorkspaces(s string) (map[string]GroupMapping, error) {
+	mappings := map[string]GroupMapping{}
+	if strings.TrimSpace(s) == "" {
+		return mappings, nil
+	}
+	if err := json.Unmarshal([]byte(s), &mappings); err != nil {
+		return nil, fmt.Errorf("invalid group workspace mappings: %w", err)
+	}
+	return mappings, nil
+}
+
+// pendingLogin is a started sign-in awaiting its callback
+type pendingLogin struct {
+	nonce    string
+	verifier string
+	expires  time.Time
+}
+
+// Login is the user a sign-in completed for
+type Login struct {
+	User *models.User
+	// Created reports whether the user was provisioned by this sign-in
+	Created bool
+}
+
+// Service runs sign-ins with an OpenID Connect provider
+type Service struct {
+	logger     *logrus.Logger
+	provider   *oidc.Provider
+	config     Config
+	users      repository.UserRepository
+	links      repository.OAuthProviderRepository
+	workspaces repository.WorkspaceRepository
+
+	mu      sync.Mutex
+	pending map[string]pendingLogin
+}
+
+// NewService creates a sign-in service for provider
+func NewService(
+	logger *logrus.Logger,
+	provider *oidc.Provider,
+	config Config,
+	users repository.UserRepository,
+	links repository.OAuthProviderRepository,
+	workspaces repository.WorkspaceRepository,
+) *Service {
+	if config.GroupsClaim == "" {
+		config.GroupsClaim = "groups"
+	}
+	return &Service{
+		logger:     logger,
+		provider:   provider,
+		config:     config,
+		users:      users,
+		links:      links,
+		workspaces: workspaces,
+		pending:    make(map[string]pendingLogin),
+	}
+}
+
+// Begin starts a sign-in and returns the provider URL to send the user to
+// and the sign-in's state. The caller must bind the state to the browser,
+// e.g. in a cookie, and pass it back to Complete.
+func (s *Service) Begin(ctx context.Context) (authURL, state string, err error) {
+	state, err = oidc.RandomToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := oidc.RandomToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := oidc.RandomToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err = s.provider.AuthCodeURL(ctx, state, nonce, oidc.Challenge(verifier))
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, login := range s.pending {
+		if now.After(login.expires) {
+			delete(s.pending, key)
+		}
+	}
+	if len(s.pending) >= maxPendingLogins {
+		return "", "", ErrTooManyLogins
+	}
+	s.pending[state] = pendingLogin{nonce: nonce, verifier: verifier, expires: now.Add(loginTimeout)}
+	return authURL, state, nil
+}
+
+// Complete finishes the sign-in of state with the code the provider sent
+// back and returns the signed-in user. browserState is the state bound to
+// the browser at Begin; a mismatch means the callback was started in
+// another browser, as in login CSRF, and is refused.
+func (s *Service) Complete(ctx context.Context, state, browserState, code string) (*Login, error) {
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(browserState)) != 1 {
+		return nil, ErrInvalidState
+	}
+
+	s.mu.Lock()
+	login, ok := s.pending[state]
+	delete(s.pending, state)
+	s.mu.Unlock()
+	if !ok || time.Now().After(login.expires) {
+		return nil, ErrInvalidState
+	}
+
+	tokens, err := s.provider.Exchange(ctx, code, login.verifier)
+	if err != nil {
+		return nil, err
+	}
+	idToken, err := s.provider.Verify(ctx, tokens.IDToken, login.nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.resolveUser(ctx, idToken)
+	if err != nil {
+		return nil, err
+	}
+	s.syncGroups(ctx, result.User.ID, idToken.Strings(s.config.GroupsClaim))
+	return result, nil
+}
+
+// resolveUser finds the user linked to the provider account, links an
+// existing user with the same verified email, or provisions a new user
+func (s *Service) resolveUser(ctx context.Context, idToken *oidc.IDToken) (*Login, error) {
+	metadata, err := json.Marshal(map[string]interface{}{
+		"groups":        idToken.Strings(s.config.GroupsClaim),
+		"last_login_at": time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	email := optionalString(idToken.Email)
+
+	link, err := s.links.GetByProviderUserID(ctx, s.config.Provider, idToken.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if link != nil {
+		user, err := s.users.GetByID(ctx, link.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.links.Update(ctx, link.ID, email, metadata); err != nil {
+			s.logger.WithError(err).WithField("user_id", user.ID).Warn("Failed to update linked account")
+		}
+		return &Login{User: user}, nil
+	}
+
+	if idToken.Email == "" || !idToken.EmailVerified {
+		return nil, ErrEmailNotVerified
+	}
+
+	result := &Login{}
+	if existing, _ := s.users.GetByEmail(ctx, idToken.Email); existing != nil {
+		result.User = existing
+	} else {
+		if !s.config.AutoProvision {
+			return nil, ErrNotProvisioned
+		}
+		user := &models.User{
+			Email:         idToken.Email,
+			FirstName:     optionalString(idToken.GivenName),
+			LastName:      optionalString(idToken.FamilyName),
+			Role:          "user",
+			IsActive:      true,
+			EmailVerified: true,
+			ExternalID:    optionalString(idToken.Subject),
+		}
+		if err := s.users.Create(ctx, user); err != nil {
+			return nil, err
+		}
+		result.User, result.Created = user, true
+	}
+
+	err = s.links.Create(ctx, &models.OAuthProvider{
+		UserID:         result.User.ID,
+		Provider:       s.config.Provider,
+		ProviderUserID: idToken.Subject,
+		Email:          email,
+		Metadata:       metadata,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":  result.User.ID,
+		"provider": s.config.Provider,
+		"created":  result.Created,
+	}).Info("Linked single sign-on account")
+	return result, nil
+}
+
+// syncGroups adds the user to the workspaces mapped from their groups.
+// Memberships are only added; removing them is left to workspace admins.
+func (s *Service) syncGroups(ctx context.Context, userID uuid.UUID, groups []string) {
+	for _, group := range groups {
+		mapping, ok := s.config.GroupWorkspaces[group]
+		if !ok {
+			continue
+		}
+		member, err := s.workspaces.IsMember(ctx, mapping.WorkspaceID, userID)
+		if err != nil || member {
+			if err != nil {
+				s.logger.WithError(err).WithField("workspace_id", mapping.WorkspaceID).Warn("Failed to check workspace membership")
+			}
+			continue
+		}
+
+		role := mapping.Role
+		if role == "" {
+			role = defaultGroupRole
+		}
+		err = s.workspaces.AddMember(ctx, &models.WorkspaceMember{
+			WorkspaceID: mapping.WorkspaceID,
+			UserID:      userID,
+			Role:        role,
+			Permissions: json.RawMessage(`{}`),
+		})
+		if err != nil {
+			s.logger.WithError(err).WithFields(logrus.Fields{
+				"workspace_id": mapping.WorkspaceID,
+				"group":        group,
+			}).Warn("Failed to add user to workspace of group")
+		}
+	}
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,194 @@
+package sso
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/gridmate/backend/internal/auth/oidc"
+	"github.com/gridmate/backend/internal/auth/oidc/oidctest"
+	"github.com/gridmate/backend/internal/models"
+	"github.com/gridmate/backend/internal/repository"
+)
+
+type testUsers struct {
+	repository.UserRepository
+	users map[uuid.UUID]*models.User
+}
+
+func (r *testUsers) Create(ctx context.Context, user *models.User) error {
+	user.ID = uuid.New()
+	r.users[user.ID] = user
+	return nil
+}
+
+func (r *testUsers) GetByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	if user, ok := r.users[id]; ok {
+		return user, nil
+	}
+	return nil, fmt.Errorf("user not found")
+}
+
+func (r *testUsers) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	for _, user := range r.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, fmt.Errorf("user not found")
+}
+
+type testLinks struct {
+	links []*models.OAuthProvider
+}
+
+func (r *testLinks) Create(ctx context.Context, link *models.OAuthProvider) error {
+	link.ID = uuid.New()
+	r.links = append(r.links, link)
+	return nil
+}
+
+func (r *testLinks) GetByProviderUserID(ctx context.Context, provider, providerUserID string) (*models.OAuthProvider, error) {
+	for _, link := range r.links {
+		if link.Provider == provider && link.ProviderUserID == providerUserID {
+			return link, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *testLinks) Update(ctx context.Context, id uuid.UUID, email *string, metadata json.RawMessage) error {
+	return nil
+}
+
+type testWorkspaces struct {
+	repository.WorkspaceRepository
+	members map[uuid.UUID]string
+}
+
+func (r *testWorkspaces) IsMember(ctx context.Context, workspaceID, userID uuid.UUID) (bool, error) {
+	_, ok := r.members[userID]
+	return ok, nil
+}
+
+func (r *testWorkspaces) AddMember(ctx context.Context, member *models.WorkspaceMember) error {
+	r.members[member.UserID] = member.Role
+	return nil
+}
+
+// signIn runs a sign-in through the mock provider like a browser would
+func signIn(t *testing.T, service *Service) (*Login, error) {
+	t.Helper()
+	authURL, state, err := service.Begin(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return service.Complete(context.Background(), callback.Query().Get("state"), state, callback.Query().Get("code"))
+}
+
+func TestSignIn(t *testing.T) {
+	idp := oidctest.NewServer("gridmate", "secret")
+	defer idp.Close()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	workspaceID := uuid.New()
+	users := &testUsers{users: map[uuid.UUID]*models.User{}}
+	links := &testLinks{}
+	workspaces := &testWorkspaces{members: map[uuid.UUID]string{}}
+	provider := oidc.NewProvider(oidc.Config{
+		Issuer:       idp.URL,
+		ClientID:     "gridmate",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost/api/v1/auth/sso/callback",
+	}, nil)
+	service := NewService(logger, provider, Config{
+		Provider:        "oidc",
+		GroupWorkspaces: map[string]GroupMapping{"analysts": {WorkspaceID: workspaceID, Role: "reviewer"}},
+		AutoProvision:   true,
+	}, users, links, workspaces)
+
+	// First sign-in provisions the user and adds them to the group's workspace
+	idp.SetUser(map[string]interface{}{
+		"sub": "idp-1", "email": "ana@example.com", "email_verified": true,
+		"given_name": "Ana", "groups": []string{"analysts", "unmapped"},
+	})
+	login, err := signIn(t, service)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !login.Created || login.User.Email != "ana@example.com" || *login.User.FirstName != "Ana" {
+		t.Errorf("login = %+v, want Ana provisioned", login)
+	}
+	if workspaces.members[login.User.ID] != "reviewer" {
+		t.Errorf("workspace role = %q, want reviewer from the analysts group", workspaces.members[login.User.ID])
+	}
+
+	// Second sign-in finds the linked account even after the email changes
+	idp.SetUser(map[string]interface{}{"sub": "idp-1", "email": "ana.new@example.com"})
+	again, err := signIn(t, service)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Created || again.User.ID != login.User.ID {
+		t.Errorf("second login = %+v, want the linked user", again)
+	}
+
+	// An existing password user is linked by verified email only
+	existing := &models.User{Email: "bo@example.com", Role: "user"}
+	users.Create(context.Background(), existing)
+	idp.SetUser(map[string]interface{}{"sub": "idp-2", "email": "bo@example.com", "email_verified": false})
+	if _, err := signIn(t, service); !errors.Is(err, ErrEmailNotVerified) {
+		t.Errorf("unverified email error = %v, want ErrEmailNotVerified", err)
+	}
+	idp.SetUser(map[string]interface{}{"sub": "idp-2", "email": "bo@example.com", "email_verified": true})
+	linked, err := signIn(t, service)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if linked.Created || linked.User.ID != existing.ID {
+		t.Errorf("login = %+v, want linked to the existing user", linked)
+	}
+
+	if _, err := service.Complete(context.Background(), "forged-state", "forged-state", "code"); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("forged state error = %v, want ErrInvalidState", err)
+	}
+
+	// A callback without the state bound to the browser is refused
+	if _, state, err := service.Begin(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if _, err := service.Complete(context.Background(), state, "", "code"); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("unbound callback error = %v, want ErrInvalidState", err)
+	}
+
+	// Sign-ins awaiting their callback are capped
+	for i := len(service.pending); i < maxPendingLogins; i++ {
+		if _, _, err := service.Begin(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, err := service.Begin(context.Background()); !errors.Is(err, ErrTooManyLogins) {
+		t.Errorf("sign-in beyond the cap error = %v, want ErrTooManyLogins", err)
+	}
+}
@@ -0,0 +1,108 @@
+package templates
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+
+	"github.com/gridmate/backend/internal/models"
+)
+
+// builtinNamespace seeds stable IDs for built-in templates
+var builtinNamespace = uuid.MustParse("6f1d3c52-8a0e-4d6b-9a57-2c4e0b1f7d93")
+
+// builtinTemplates are available to every workspace. A workspace can shadow
+// one by creating its own template with the same key.
+var builtinTemplates = []*models.ModelTemplate{
+	newBuiltin("dcf-basic", "DCF Model - Basic", "Valuation",
+		"Basic Discounted Cash Flow model with revenue projections and WACC calculation",
+		Definition{
+			SchemaVersion: DefinitionSchemaVersion,
+			Sheets:        sheetsNamed("Assumptions", "Revenue", "Costs", "FCF", "Valuation"),
+			Formulas: map[string]string{
+				"fcf":           "=EBIT*(1-TaxRate)+Depreciation-CapEx-ChangeInNWC",
+				"terminalValue": "=FCF_LastYear*(1+TerminalGrowth)/(WACC-TerminalGrowth)",
+				"npv":           "=NPV(WACC,FCF_Range)+TerminalValue/(1+WACC)^Years",
+			},
+			Assumptions: map[string]interface{}{
+				"wacc":           0.10,
+				"terminalGrowth": 0.025,
+				"taxRate":        0.21,
+			},
+		}),
+	newBuiltin("lbo-basic", "LBO Model - Basic", "Private Equity",
+		"Basic Leveraged Buyout model with debt schedule and returns analysis",
+		Definition{
+			SchemaVersion: DefinitionSchemaVersion,
+			Sheets:        sheetsNamed("Assumptions", "Sources & Uses", "OpModel", "DebtSchedule", "Returns"),
+			Formulas: map[string]string{
+				"debtPaydown": "=MIN(CashAvailable,BeginningDebt)",
+				"exitEquity":  "=ExitEV-NetDebt",
+				"moic":        "=ExitEquity/InitialEquity",
+				"irr":         "=IRR(CashFlows)",
+			},
+			Assumptions: map[string]interface{}{
+				"entryMultiple": 10.0,
+				"exitMultiple":  12.0,
+				"debtMultiple":  5.0,
+				"holdPeriod":    5,
+			},
+		}),
+	newBuiltin("comps-analysis", "Trading Comps Analysis", "Valuation",
+		"Trading comparables analysis with peer benchmarking",
+		Definition{
+			SchemaVersion: DefinitionSchemaVersion,
+			Sheets:        sheetsNamed("CompanyData", "Multiples", "Benchmarking", "Summary"),
+			Formulas: map[string]string{
+				"evToEbitda":     "=EnterpriseValue/EBITDA",
+				"peRatio":        "=Price/EPS",
+				"evToRevenue":    "=EnterpriseValue/Revenue",
+				"medianMultiple": "=MEDIAN(MultiplesRange)",
+			},
+			Assumptions: map[string]interface{}{
+				"outlierThreshold": 2.0,
+				"sectorFilter":     true,
+			},
+		}),
+}
+
+// Builtins returns the built-in template catalog
+func Builtins() []*models.ModelTemplate {
+	return builtinTemplates
+}
+
+func newBuiltin(key, name, category, description string, def Definition) *models.ModelTemplate {
+	raw, err := json.Marshal(def)
+	if err != nil {
+		panic(err)
+	}
+	return &models.ModelTemplate{
+		ID:          uuid.NewSHA1(builtinNamespace, []byte(key)),
+		TemplateKey: key,
+		Version:     1,
+		Name:        name,
+		Category:    category,
+		Description: &description,
+		Definition:  raw,
+		Status:      models.TemplateStatusActive,
+		Source:      models.TemplateSourceBuiltin,
+	}
+}
+
+func sheetsNamed(names ...string) []SheetDefinition {
+	sheets := make([]SheetDefinition, len(names))
+	for i, name := range names {
+		sheets[i] = SheetDefinition{Name: name}
+	}
+	return sheets
+}
+
+// findBuiltin looks up a built-in template by key or ID
+func findBuiltin(id string) *models.ModelTemplate {
+	for _, tmpl := range builtinTemplates {
+		if tmpl.TemplateKey == id || tmpl.ID.String() == id {
+			return tmpl
+		}
+	}
+	return nil
+}
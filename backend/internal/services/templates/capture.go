@@ -0,0 +1,372 @@
+package templates
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gridmate/backend/internal/models"
+	"github.com/gridmate/backend/internal/services/ai"
+	"github.com/gridmate/backend/internal/services/excel"
+	"github.com/gridmate/backend/internal/services/spreadsheet"
+)
+
+var (
+	cellRefPattern = regexp.MustCompile(`^\$?([A-Z]{1,3})\$?([0-9]+)$`)
+	periodPattern  = regexp.MustCompile(`(?i)^(FY|CY)?\s*'?(19|20)?\d{2}[AEPF]?$|^Q[1-4]`)
+)
+
+// Capturer derives a template definition from an existing workbook. Layout
+// comes from the pattern detector, input areas from the semantic analyzer,
+// and only defaults for input cells are kept - the workbook's computed data
+// is not part of the template.
+type Capturer struct {
+	patternDetector  *excel.PatternDetector
+	semanticAnalyzer *spreadsheet.SemanticAnalyzer
+	cellClassifier   *spreadsheet.CellClassifier
+}
+
+// NewCapturer creates a new template capturer
+func NewCapturer() *Capturer {
+	return &Capturer{
+		patternDetector:  excel.NewPatternDetector(),
+		semanticAnalyzer: spreadsheet.NewSemanticAnalyzer(),
+		cellClassifier:   spreadsheet.NewCellClassifier(),
+	}
+}
+
+// Capture builds a definition from every sheet in the workbook that has data
+func (c *Capturer) Capture(workbook *models.Workbook) (*Definition, error) {
+	if workbook == nil {
+		return nil, fmt.Errorf("workbook is required")
+	}
+
+	def := &Definition{
+		SchemaVersion: DefinitionSchemaVersion,
+		Sheets:        []SheetDefinition{},
+	}
+
+	for _, sheet := range workbook.Sheets {
+		if sheet == nil || sheet.Data == nil || len(sheet.Data.Values) == 0 {
+			continue
+		}
+		def.Sheets = append(def.Sheets, c.captureSheet(sheet))
+	}
+
+	if len(def.Sheets) == 0 {
+		return nil, fmt.Errorf("workbook has no sheet data to capture")
+	}
+
+	if err := ValidateDefinition(def); err != nil {
+		return nil, err
+	}
+
+	return def, nil
+}
+
+func (c *Capturer) captureSheet(sheet *models.Sheet) SheetDefinition {
+	values := sheet.Data.Values
+	formulas := padFormulas(sheet.Data.Formulas, values)
+	originRow, originCol := rangeOrigin(sheet.Data.Range)
+
+	name := sheet.Name
+	if name == "" {
+		name = sheet.Data.Sheet
+	}
+	def := SheetDefinition{Name: name}
+
+	rangeData := &ai.RangeData{
+		Values:   values,
+		Formulas: toInterfaceGrid(formulas),
+		Address:  sheet.Data.Range,
+		RowCount: len(values),
+	}
+	if len(values) > 0 {
+		rangeData.ColCount = len(values[0])
+	}
+
+	// Layout sections from the pattern detector
+	for _, region := range c.patternDetector.DetectRegions(rangeData) {
+		section := SectionDefinition{
+			Type:       string(region.Type),
+			Range:      regionAddress(region.StartRow, region.StartCol, region.EndRow, region.EndCol, originRow, originCol),
+			Label:      rowLabel(values, region.StartRow),
+			Confidence: region.Confidence,
+		}
+		if !sectionTypes[section.Type] {
+			continue
+		}
+		def.Sections = append(def.Sections, section)
+	}
+
+	// Input areas from the semantic analyzer
+	inputCells := make(map[[2]int]bool)
+	totalRows := make(map[int]bool)
+	for _, region := range c.semanticAnalyzer.AnalyzeRange(values, formulas) {
+		switch region.Type {
+		case "input":
+			for r := region.StartRow; r <= region.EndRow; r++ {
+				for col := region.StartCol; col <= region.EndCol; col++ {
+					inputCells[[2]int{r, col}] = true
+				}
+			}
+		case "total":
+			totalRows[region.StartRow] = true
+		}
+	}
+
+	// Period headers are taken from the first row that looks like a timeline
+	headerRow := -1
+	for r := 0; r < len(values) && r < 10 && headerRow == -1; r++ {
+		var periods []string
+		for _, v := range values[r] {
+			if s, ok := v.(string); ok && periodPattern.MatchString(strings.TrimSpace(s)) {
+				periods = append(periods, strings.TrimSpace(s))
+			}
+		}
+		if len(periods) >= 2 {
+			def.PeriodHeaders = periods
+			headerRow = r
+		}
+	}
+
+	for r, row := range values {
+		if r == headerRow {
+			continue
+		}
+		label := rowLabel(values, r)
+		if label != "" {
+			def.LineItems = append(def.LineItems, LineItem{
+				Label: label,
+				Row:   originRow + r,
+				Kind:  c.lineItemKind(row, formulas[r], totalRows[r]),
+			})
+		}
+
+		for col, value := range row {
+			if formulas[r][col] != "" {
+				continue
+			}
+			classification := c.cellClassifier.ClassifyCell(value, "", r, col, spreadsheet.CellContext{
+				IsHeaderRow:   r == headerRow,
+				IsTotalRow:    totalRows[r],
+				IsInputRegion: inputCells[[2]int{r, col}],
+			})
+			if classification.Purpose != spreadsheet.PurposeInput {
+				continue
+			}
+			def.Inputs = append(def.Inputs, InputDefinition{
+				Address:  cellAddress(originRow+r, originCol+col),
+				Label:    label,
+				DataType: inputDataType(value),
+				Default:  value,
+			})
+		}
+	}
+
+	def.Formulas = c.captureFormulas(rangeData, formulas, originRow, originCol)
+
+	return def
+}
+
+// captureFormulas keeps one entry per repeated formula pattern plus any
+// formulas that appear only once
+func (c *Capturer) captureFormulas(rangeData *ai.RangeData, formulas [][]string, originRow, originCol int) []FormulaDefinition {
+	var result []FormulaDefinition
+	covered := make(map[string]bool)
+
+	patterns := c.patternDetector.AnalyzeFormulaPatterns(rangeData)
+	sort.Slice(patterns, func(i, j int) bool { return patterns[i].Count > patterns[j].Count })
+
+	for _, pattern := range patterns {
+		var cells []string
+		first := ""
+		for _, cell := range pattern.Cells {
+			row, col, ok := parseCell(cell)
+			if !ok || covered[cell] {
+				continue
+			}
+			covered[cell] = true
+			if first == "" && row-1 < len(formulas) && col-1 < len(formulas[row-1]) {
+				first = formulas[row-1][col-1]
+			}
+			cells = append(cells, cellAddress(originRow+row-1, originCol+col-1))
+		}
+		if len(cells) == 0 || first == "" {
+			continue
+		}
+		result = append(result, FormulaDefinition{
+			Cells:       cells,
+			Formula:     first,
+			Pattern:     pattern.Pattern,
+			Description: pattern.Description,
+		})
+	}
+
+	for r, row := range formulas {
+		for col, formula := range row {
+			local := cellAddress(r+1, col+1)
+			if formula == "" || covered[local] || !strings.HasPrefix(formula, "=") {
+				continue
+			}
+			result = append(result, FormulaDefinition{
+				Cells:   []string{cellAddress(originRow+r, originCol+col)},
+				Formula: formula,
+			})
+		}
+	}
+
+	return result
+}
+
+func (c *Capturer) lineItemKind(row []interface{}, formulas []string, isTotal bool) string {
+	if isTotal {
+		return "total"
+	}
+	hasFormula, hasNumber := false, false
+	for col := 1; col < len(row); col++ {
+		if formulas[col] != "" {
+			hasFormula = true
+		} else if _, ok := toNumber(row[col]); ok {
+			hasNumber = true
+		}
+	}
+	switch {
+	case hasFormula:
+		return "calculation"
+	case hasNumber:
+		return "input"
+	default:
+		return "label"
+	}
+}
+
+// Helper functions
+
+// padFormulas returns a formula grid with the same shape as values so the
+// analyzers can index it freely
+func padFormulas(formulas [][]string, values [][]interface{}) [][]string {
+	padded := make([][]string, len(values))
+	for r := range values {
+		padded[r] = make([]string, len(values[r]))
+		if r < len(formulas) {
+			copy(padded[r], formulas[r])
+		}
+	}
+	return padded
+}
+
+func toInterfaceGrid(formulas [][]string) [][]interface{} {
+	grid := make([][]interface{}, len(formulas))
+	for r, row := range formulas {
+		grid[r] = make([]interface{}, len(row))
+		for col, f := range row {
+			if f != "" {
+				grid[r][col] = f
+			}
+		}
+	}
+	return grid
+}
+
+func rowLabel(values [][]interface{}, row int) string {
+	if row < 0 || row >= len(values) {
+		return ""
+	}
+	for _, v := range values[row] {
+		if s, ok := v.(string); ok && strings.TrimSpace(s) != "" {
+			if _, isNum := toNumber(s); !isNum {
+				return strings.TrimSpace(s)
+			}
+		}
+		if v != nil {
+			break
+		}
+	}
+	return ""
+}
+
+func inputDataType(value interface{}) string {
+	switch v := value.(type) {
+	case bool:
+		return "boolean"
+	case string:
+		if strings.HasSuffix(strings.TrimSpace(v), "%") {
+			return "percentage"
+		}
+		return "text"
+	}
+	if n, ok := toNumber(value); ok && n != 0 && n > -1 && n < 1 {
+		return "percentage"
+	}
+	return "numeric"
+}
+
+func toNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case string:
+		n, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(v), ",", ""), 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// rangeOrigin returns the 1-based row and column of the top-left cell of a
+// range such as "Sheet1!B2:F40"
+func rangeOrigin(address string) (int, int) {
+	if idx := strings.LastIndex(address, "!"); idx >= 0 {
+		address = address[idx+1:]
+	}
+	start := strings.Split(address, ":")[0]
+	if row, col, ok := parseCell(start); ok {
+		return row, col
+	}
+	return 1, 1
+}
+
+func regionAddress(startRow, startCol, endRow, endCol, originRow, originCol int) string {
+	start := cellAddress(originRow+startRow, originCol+startCol)
+	end := cellAddress(originRow+endRow, originCol+endCol)
+	if start == end {
+		return start
+	}
+	return start + ":" + end
+}
+
+// parseCell parses an A1 reference into 1-based row and column
+func parseCell(cell string) (int, int, bool) {
+	m := cellRefPattern.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(cell)))
+	if m == nil {
+		return 0, 0, false
+	}
+	col := 0
+	for _, ch := range m[1] {
+		col = col*26 + int(ch-'A'+1)
+	}
+	row, err := strconv.Atoi(m[2])
+	if err != nil {
+		return 0, 0, false
+	}
+	return row, col, true
+}
+
+// cellAddress converts 1-based row and column to an A1 reference
+func cellAddress(row, col int) string {
+	letters := ""
+	for col > 0 {
+		col--
+		letters = string(rune('A'+col%26)) + letters
+		col /= 26
+	}
+	return fmt.Sprintf("%s%d", letters, row)
+}
@@ -0,0 +1,281 @@
+package templates
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefinitionSchemaVersion is the current version of the template definition format
+const DefinitionSchemaVersion = "1.0"
+
+// DefinitionSchema is the JSON schema for a template definition. It is served
+// to clients so editors can validate templates before submitting them;
+// ValidateDefinition enforces the same rules server side.
+const DefinitionSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "https://gridmate.ai/schemas/model-template-definition.json",
+  "title": "Model template definition",
+  "type": "object",
+  "required": ["schema_version", "sheets"],
+  "additionalProperties": false,
+  "properties": {
+    "schema_version": {"type": "string", "const": "1.0"},
+    "sheets": {
+      "type": "array",
+      "minItems": 1,
+      "items": {
+        "type": "object",
+        "required": ["name"],
+        "additionalProperties": false,
+        "properties": {
+          "name": {"type": "string", "minLength": 1, "maxLength": 31},
+          "period_headers": {"type": "array", "items": {"type": "string"}},
+          "sections": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "required": ["type", "range"],
+              "additionalProperties": false,
+              "properties": {
+                "type": {"type": "string", "enum": ["header", "data", "total", "input", "calculation", "label"]},
+                "range": {"type": "string", "pattern": "^[A-Z]{1,3}[0-9]+(:[A-Z]{1,3}[0-9]+)?$"},
+                "label": {"type": "string"},
+                "confidence": {"type": "number", "minimum": 0, "maximum": 1}
+              }
+            }
+          },
+          "line_items": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "required": ["label", "row"],
+              "additionalProperties": false,
+              "properties": {
+                "label": {"type": "string", "minLength": 1},
+                "row": {"type": "integer", "minimum": 1},
+                "kind": {"type": "string", "enum": ["input", "calculation", "total", "label"]}
+              }
+            }
+          },
+          "inputs": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "required": ["address"],
+              "additionalProperties": false,
+              "properties": {
+                "address": {"type": "string", "pattern": "^[A-Z]{1,3}[0-9]+$"},
+                "label": {"type": "string"},
+                "data_type": {"type": "string", "enum": ["numeric", "percentage", "text", "date", "boolean"]},
+                "default": {}
+              }
+            }
+          },
+          "formulas": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "required": ["cells", "formula"],
+              "additionalProperties": false,
+              "properties": {
+                "cells": {"type": "array", "minItems": 1, "items": {"type": "string", "pattern": "^[A-Z]{1,3}[0-9]+$"}},
+                "formula": {"type": "string", "pattern": "^="},
+                "pattern": {"type": "string"},
+                "description": {"type": "string"}
+              }
+            }
+          }
+        }
+      }
+    },
+    "formulas": {"type": "object", "additionalProperties": {"type": "string"}},
+    "assumptions": {"type": "object"}
+  }
+}`
+
+// Definition is the structured body of a model template
+type Definition struct {
+	SchemaVersion string                 `json:"schema_version"`
+	Sheets        []SheetDefinition      `json:"sheets"`
+	Formulas      map[string]string      `json:"formulas,omitempty"`
+	Assumptions   map[string]interface{} `json:"assumptions,omitempty"`
+}
+
+// SheetDefinition describes the layout of one worksheet in a template
+type SheetDefinition struct {
+	Name          string              `json:"name"`
+	PeriodHeaders []string            `json:"period_headers,omitempty"`
+	Sections      []SectionDefinition `json:"sections,omitempty"`
+	LineItems     []LineItem          `json:"line_items,omitempty"`
+	Inputs        []InputDefinition   `json:"inputs,omitempty"`
+	Formulas      []FormulaDefinition `json:"formulas,omitempty"`
+}
+
+// SectionDefinition is a semantic region of a sheet
+type SectionDefinition struct {
+	Type       string  `json:"type"`
+	Range      string  `json:"range"`
+	Label      string  `json:"label,omitempty"`
+	Confidence float64 `json:"confidence,omitempty"`
+}
+
+// LineItem is a labelled row in a sheet
+type LineItem struct {
+	Label string `json:"label"`
+	Row   int    `json:"row"`
+	Kind  string `json:"kind,omitempty"`
+}
+
+// InputDefinition is a cell the user is expected to fill in
+type InputDefinition struct {
+	Address  string      `json:"address"`
+	Label    string      `json:"label,omitempty"`
+	DataType string      `json:"data_type,omitempty"`
+	Default  interface{} `json:"default,omitempty"`
+}
+
+// FormulaDefinition is a formula applied to one or more cells
+type FormulaDefinition struct {
+	Cells       []string `json:"cells"`
+	Formula     string   `json:"formula"`
+	Pattern     string   `json:"pattern,omitempty"`
+	Description string   `json:"description,omitempty"`
+}
+
+// FieldError describes a single schema violation
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned when a definition does not match the schema
+type ValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", fe.Field, fe.Message))
+	}
+	return "invalid template definition: " + strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) add(field, format string, args ...interface{}) {
+	e.Errors = append(e.Errors, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+var (
+	rangePattern = regexp.MustCompile(`^[A-Z]{1,3}[0-9]+(:[A-Z]{1,3}[0-9]+)?$`)
+	cellPattern  = regexp.MustCompile(`^[A-Z]{1,3}[0-9]+$`)
+
+	sectionTypes  = map[string]bool{"header": true, "data": true, "total": true, "input": true, "calculation": true, "label": true}
+	lineItemKinds = map[string]bool{"input": true, "calculation": true, "total": true, "label": true}
+	inputTypes    = map[string]bool{"numeric": true, "percentage": true, "text": true, "date": true, "boolean": true}
+)
+
+// ParseDefinition decodes and validates a raw template definition
+func ParseDefinition(raw json.RawMessage) (*Definition, error) {
+	if len(raw) == 0 {
+		return nil, &ValidationError{Errors: []FieldError{{Field: "definition", Message: "is required"}}}
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(string(raw)))
+	decoder.DisallowUnknownFields()
+
+	var def Definition
+	if err := decoder.Decode(&def); err != nil {
+		return nil, &ValidationError{Errors: []FieldError{{Field: "definition", Message: err.Error()}}}
+	}
+
+	if err := ValidateDefinition(&def); err != nil {
+		return nil, err
+	}
+
+	return &def, nil
+}
+
+// ValidateDefinition checks a definition against DefinitionSchema
+func ValidateDefinition(def *Definition) error {
+	verr := &ValidationError{}
+
+	if def.SchemaVersion != DefinitionSchemaVersion {
+		verr.add("schema_version", "must be %q", DefinitionSchemaVersion)
+	}
+	if len(def.Sheets) == 0 {
+		verr.add("sheets", "must contain at least one sheet")
+	}
+
+	seen := make(map[string]bool)
+	for i, sheet := range def.Sheets {
+		prefix := fmt.Sprintf("sheets[%d]", i)
+
+		if sheet.Name == "" {
+			verr.add(prefix+".name", "is required")
+		} else if len(sheet.Name) > 31 {
+			verr.add(prefix+".name", "must be at most 31 characters")
+		}
+		if seen[strings.ToLower(sheet.Name)] {
+			verr.add(prefix+".name", "duplicate sheet name %q", sheet.Name)
+		}
+		seen[strings.ToLower(sheet.Name)] = true
+
+		for j, section := range sheet.Sections {
+			field := fmt.Sprintf("%s.sections[%d]", prefix, j)
+			if !sectionTypes[section.Type] {
+				verr.add(field+".type", "unknown section type %q", section.Type)
+			}
+			if !rangePattern.MatchString(section.Range) {
+				verr.add(field+".range", "invalid range %q", section.Range)
+			}
+			if section.Confidence < 0 || section.Confidence > 1 {
+				verr.add(field+".confidence", "must be between 0 and 1")
+			}
+		}
+
+		for j, item := range sheet.LineItems {
+			field := fmt.Sprintf("%s.line_items[%d]", prefix, j)
+			if item.Label == "" {
+				verr.add(field+".label", "is required")
+			}
+			if item.Row < 1 {
+				verr.add(field+".row", "must be at least 1")
+			}
+			if item.Kind != "" && !lineItemKinds[item.Kind] {
+				verr.add(field+".kind", "unknown line item kind %q", item.Kind)
+			}
+		}
+
+		for j, input := range sheet.Inputs {
+			field := fmt.Sprintf("%s.inputs[%d]", prefix, j)
+			if !cellPattern.MatchString(input.Address) {
+				verr.add(field+".address", "invalid cell address %q", input.Address)
+			}
+			if input.DataType != "" && !inputTypes[input.DataType] {
+				verr.add(field+".data_type", "unknown data type %q", input.DataType)
+			}
+		}
+
+		for j, formula := range sheet.Formulas {
+			field := fmt.Sprintf("%s.formulas[%d]", prefix, j)
+			if len(formula.Cells) == 0 {
+				verr.add(field+".cells", "must contain at least one cell")
+			}
+			for _, cell := range formula.Cells {
+				if !cellPattern.MatchString(cell) {
+					verr.add(field+".cells", "invalid cell address %q", cell)
+				}
+			}
+			if !strings.HasPrefix(formula.Formula, "=") {
+				verr.add(field+".formula", "must start with '='")
+			}
+		}
+	}
+
+	if len(verr.Errors) > 0 {
+		return verr
+	}
+	return nil
+}
@@ -0,0 +1,355 @@
+package templates
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/gridmate/backend/internal/auth"
+	"github.com/gridmate/backend/internal/models"
+	"github.com/gridmate/backend/internal/repository"
+)
+
+var (
+	// ErrTemplateNotFound is returned when a template does not exist or is not visible to the caller
+	ErrTemplateNotFound = repository.ErrTemplateNotFound
+	// ErrForbidden is returned when the caller cannot modify the workspace's templates
+	ErrForbidden = errors.New("not permitted to manage templates in this workspace")
+	// ErrBuiltinReadOnly is returned when a caller tries to modify a built-in template
+	ErrBuiltinReadOnly = errors.New("built-in templates are read-only")
+)
+
+// WorkbookSource provides workbook snapshots for template capture
+type WorkbookSource interface {
+	GetWorkbookData(sessionID string) *models.Workbook
+}
+
+// Service manages workspace template libraries
+type Service struct {
+	logger         *logrus.Logger
+	templateRepo   repository.TemplateRepository
+	workspaceRepo  repository.WorkspaceRepository
+	capturer       *Capturer
+	workbookSource WorkbookSource
+}
+
+// NewService creates a new template service
+func NewService(
+	logger *logrus.Logger,
+	templateRepo repository.TemplateRepository,
+	workspaceRepo repository.WorkspaceRepository,
+) *Service {
+	return &Service{
+		logger:        logger,
+		templateRepo:  templateRepo,
+		workspaceRepo: workspaceRepo,
+		capturer:      NewCapturer(),
+	}
+}
+
+// SetWorkbookSource sets the source used to read workbooks for capture
+func (s *Service) SetWorkbookSource(source WorkbookSource) {
+	s.workbookSource = source
+}
+
+// List returns the latest version of each template visible in a workspace.
+// Built-in templates are included unless the workspace has its own template
+// with the same key.
+func (s *Service) List(ctx context.Context, userID, workspaceID uuid.UUID, category string, includeDeprecated bool) ([]*models.ModelTemplate, error) {
+	var result []*models.ModelTemplate
+	owned := make(map[string]bool)
+
+	if workspaceID != uuid.Nil {
+		if err := s.requireMember(ctx, workspaceID, userID, false); err != nil {
+			return nil, err
+		}
+
+		filter := &models.ModelTemplateFilter{
+			WorkspaceID:       workspaceID,
+			IncludeDeprecated: includeDeprecated,
+			LatestOnly:        true,
+		}
+		if category != "" {
+			filter.Category = &category
+		}
+
+		workspaceTemplates, err := s.templateRepo.List(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		for _, tmpl := range workspaceTemplates {
+			owned[tmpl.TemplateKey] = true
+		}
+		result = append(result, workspaceTemplates...)
+	}
+
+	for _, tmpl := range builtinTemplates {
+		if owned[tmpl.TemplateKey] || (category != "" && tmpl.Category != category) {
+			continue
+		}
+		result = append(result, tmpl)
+	}
+
+	return result, nil
+}
+
+// Get returns a template by ID. Built-in templates may also be requested by key.
+func (s *Service) Get(ctx context.Context, userID uuid.UUID, id string) (*models.ModelTemplate, error) {
+	if tmpl := findBuiltin(id); tmpl != nil {
+		return tmpl, nil
+	}
+
+	templateID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrTemplateNotFound
+	}
+
+	tmpl, err := s.templateRepo.GetByID(ctx, templateID)
+	if err != nil {
+		return nil, ErrTemplateNotFound
+	}
+
+	if err := s.requireMember(ctx, tmpl.WorkspaceID, userID, false); err != nil {
+		return nil, ErrTemplateNotFound
+	}
+
+	return tmpl, nil
+}
+
+// ListVersions returns every version of a template, newest first
+func (s *Service) ListVersions(ctx context.Context, userID uuid.UUID, id string) ([]*models.ModelTemplate, error) {
+	tmpl, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if tmpl.Source == models.TemplateSourceBuiltin {
+		return []*models.ModelTemplate{tmpl}, nil
+	}
+
+	return s.templateRepo.List(ctx, &models.ModelTemplateFilter{
+		WorkspaceID:       tmpl.WorkspaceID,
+		TemplateKey:       &tmpl.TemplateKey,
+		IncludeDeprecated: true,
+	})
+}
+
+// Create adds version 1 of a new template to a workspace
+func (s *Service) Create(ctx context.Context, userID uuid.UUID, req *models.CreateModelTemplateRequest) (*models.ModelTemplate, error) {
+	if err := s.requireMember(ctx, req.WorkspaceID, userID, true); err != nil {
+		return nil, err
+	}
+	if err := validateKey(req.TemplateKey); err != nil {
+		return nil, err
+	}
+
+	def, err := ParseDefinition(req.Definition)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing, err := s.templateRepo.GetLatest(ctx, req.WorkspaceID, req.TemplateKey); err == nil && existing != nil {
+		return nil, &ValidationError{Errors: []FieldError{{
+			Field:   "template_key",
+			Message: fmt.Sprintf("template %q already exists; create a new version instead", req.TemplateKey),
+		}}}
+	}
+
+	source := models.TemplateSourceManual
+	if findBuiltin(req.TemplateKey) != nil {
+		source = models.TemplateSourceCloned
+	}
+
+	return s.insert(ctx, &models.ModelTemplate{
+		WorkspaceID: req.WorkspaceID,
+		TemplateKey: req.TemplateKey,
+		Version:     1,
+		Name:        req.Name,
+		Category:    req.Category,
+		Description: req.Description,
+		Source:      source,
+		CreatedBy:   &userID,
+	}, def)
+}
+
+// Update edits a template version in place
+func (s *Service) Update(ctx context.Context, userID uuid.UUID, id string, req *models.UpdateModelTemplateRequest) (*models.ModelTemplate, error) {
+	tmpl, err := s.getOwned(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Definition != nil {
+		def, err := ParseDefinition(req.Definition)
+		if err != nil {
+			return nil, err
+		}
+		if req.Definition, err = json.Marshal(def); err != nil {
+			return nil, fmt.Errorf("failed to encode definition: %w", err)
+		}
+	}
+
+	if err := s.templateRepo.Update(ctx, tmpl.ID, req); err != nil {
+		return nil, err
+	}
+
+	return s.templateRepo.GetByID(ctx, tmpl.ID)
+}
+
+// CreateVersion adds a new version of an existing template. Unset fields are
+// carried over from the latest version.
+func (s *Service) CreateVersion(ctx context.Context, userID uuid.UUID, id string, req *models.CreateTemplateVersionRequest) (*models.ModelTemplate, error) {
+	tmpl, err := s.getOwned(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	def, err := ParseDefinition(req.Definition)
+	if err != nil {
+		return nil, err
+	}
+
+	latest, err := s.templateRepo.GetLatest(ctx, tmpl.WorkspaceID, tmpl.TemplateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	next := &models.ModelTemplate{
+		WorkspaceID: latest.WorkspaceID,
+		TemplateKey: latest.TemplateKey,
+		Version:     latest.Version + 1,
+		Name:        latest.Name,
+		Category:    latest.Category,
+		Description: latest.Description,
+		Source:      latest.Source,
+		CreatedBy:   &userID,
+	}
+	if req.Name != nil {
+		next.Name = *req.Name
+	}
+	if req.Category != nil {
+		next.Category = *req.Category
+	}
+	if req.Description != nil {
+		next.Description = req.Description
+	}
+
+	return s.insert(ctx, next, def)
+}
+
+// Deprecate marks a template version as deprecated. Deprecated versions stay
+// readable so existing models can still reference them.
+func (s *Service) Deprecate(ctx context.Context, userID uuid.UUID, id string) error {
+	tmpl, err := s.getOwned(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+
+	return s.templateRepo.Deprecate(ctx, tmpl.ID)
+}
+
+// Capture creates a template from a workbook. If the request names an
+// existing template key, the capture becomes its next version.
+func (s *Service) Capture(ctx context.Context, userID uuid.UUID, req *models.CaptureTemplateRequest, workbook *models.Workbook) (*models.ModelTemplate, error) {
+	if err := s.requireMember(ctx, req.WorkspaceID, userID, true); err != nil {
+		return nil, err
+	}
+	if err := validateKey(req.TemplateKey); err != nil {
+		return nil, err
+	}
+
+	if workbook == nil {
+		if s.workbookSource == nil || req.SessionID == "" {
+			return nil, &ValidationError{Errors: []FieldError{{Field: "session_id", Message: "a session or workbook is required"}}}
+		}
+		workbook = s.workbookSource.GetWorkbookData(req.SessionID)
+		if workbook == nil {
+			return nil, &ValidationError{Errors: []FieldError{{Field: "session_id", Message: "session not found"}}}
+		}
+	}
+
+	def, err := s.capturer.Capture(workbook)
+	if err != nil {
+		return nil, err
+	}
+
+	version := 1
+	if latest, err := s.templateRepo.GetLatest(ctx, req.WorkspaceID, req.TemplateKey); err == nil && latest != nil {
+		version = latest.Version + 1
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"workspace_id": req.WorkspaceID,
+		"template_key": req.TemplateKey,
+		"version":      version,
+		"sheets":       len(def.Sheets),
+	}).Info("Captured template from workbook")
+
+	return s.insert(ctx, &models.ModelTemplate{
+		WorkspaceID: req.WorkspaceID,
+		TemplateKey: req.TemplateKey,
+		Version:     version,
+		Name:        req.Name,
+		Category:    req.Category,
+		Description: req.Description,
+		Source:      models.TemplateSourceCaptured,
+		CreatedBy:   &userID,
+	}, def)
+}
+
+func (s *Service) insert(ctx context.Context, tmpl *models.ModelTemplate, def *Definition) (*models.ModelTemplate, error) {
+	raw, err := json.Marshal(def)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode definition: %w", err)
+	}
+	tmpl.Definition = raw
+
+	if err := s.templateRepo.Create(ctx, tmpl); err != nil {
+		return nil, err
+	}
+
+	return tmpl, nil
+}
+
+// getOwned loads a workspace template the caller is allowed to modify
+func (s *Service) getOwned(ctx context.Context, userID uuid.UUID, id string) (*models.ModelTemplate, error) {
+	if findBuiltin(id) != nil {
+		return nil, ErrBuiltinReadOnly
+	}
+
+	tmpl, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.requireMember(ctx, tmpl.WorkspaceID, userID, true); err != nil {
+		return nil, err
+	}
+
+	return tmpl, nil
+}
+
+// requireMember refuses non-members, and viewers when write is set
+func (s *Service) requireMember(ctx context.Context, workspaceID, userID uuid.UUID, write bool) error {
+	role, err := auth.WorkspaceRole(ctx, s.workspaceRepo, workspaceID, userID)
+	if err != nil || write && !auth.CanWrite(role) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func validateKey(key string) error {
+	if key == "" || len(key) > 100 || strings.TrimSpace(key) != key {
+		return &ValidationError{Errors: []FieldError{{Field: "template_key", Message: "must be 1-100 characters without surrounding whitespace"}}}
+	}
+	for _, ch := range key {
+		if !(ch >= 'a' && ch <= 'z' || ch >= '0' && ch <= '9' || ch == '-' || ch == '_') {
+			return &ValidationError{Errors: []FieldError{{Field: "template_key", Message: "may only contain lowercase letters, digits, '-' and '_'"}}}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package templates
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/gridmate/backend/internal/auth"
+	"github.com/gridmate/backend/internal/models"
+	"github.com/gridmate/backend/internal/repository"
+)
+
+type testTemplates struct {
+	templates []*models.ModelTemplate
+}
+
+func (r *testTemplates) Create(ctx context.Context, tmpl *models.ModelTemplate) error {
+	tmpl.ID = uuid.New()
+	if tmpl.Status == "" {
+		tmpl.Status = models.TemplateStatusActive
+	}
+	r.templates = append(r.templates, tmpl)
+	return nil
+}
+
+func (r *testTemplates) GetByID(ctx context.Context, id uuid.UUID) (*models.ModelTemplate, error) {
+	for _, tmpl := range r.templates {
+		if tmpl.ID == id {
+			return tmpl, nil
+		}
+	}
+	return nil, repository.ErrTemplateNotFound
+}
+
+func (r *testTemplates) GetLatest(ctx context.Context, workspaceID uuid.UUID, templateKey string) (*models.ModelTemplate, error) {
+	var latest *models.ModelTemplate
+	for _, tmpl := range r.templates {
+		if tmpl.WorkspaceID == workspaceID && tmpl.TemplateKey == templateKey && (latest == nil || tmpl.Version > latest.Version) {
+			latest = tmpl
+		}
+	}
+	if latest == nil {
+		return nil, repository.ErrTemplateNotFound
+	}
+	return latest, nil
+}
+
+func (r *testTemplates) List(ctx context.Context, filter *models.ModelTemplateFilter) ([]*models.ModelTemplate, error) {
+	return r.templates, nil
+}
+
+func (r *testTemplates) Update(ctx context.Context, id uuid.UUID, updates *models.UpdateModelTemplateRequest) error {
+	return nil
+}
+
+func (r *testTemplates) Deprecate(ctx context.Context, id uuid.UUID) error {
+	tmpl, err := r.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if tmpl.Status == models.TemplateStatusDeprecated {
+		return repository.ErrTemplateNotFound
+	}
+	tmpl.Status = models.TemplateStatusDeprecated
+	return nil
+}
+
+type testWorkspaces struct {
+	repository.WorkspaceRepository
+	roles map[uuid.UUID]string
+}
+
+func (r *testWorkspaces) GetMemberRole(ctx context.Context, workspaceID, userID uuid.UUID) (string, error) {
+	if role, ok := r.roles[userID]; ok {
+		return role, nil
+	}
+	return "", errors.New("not a member")
+}
+
+func TestCaptureVersionDeprecate(t *testing.T) {
+	ctx := context.Background()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	workspaceID := uuid.New()
+	analyst, viewer := uuid.New(), uuid.New()
+	repo := &testTemplates{}
+	service := NewService(logger, repo, &testWorkspaces{roles: map[uuid.UUID]string{analyst: "member", viewer: "viewer"}})
+
+	workbook := &models.Workbook{Name: "Model.xlsx", Sheets: []*models.Sheet{{
+		Name: "Income Statement",
+		Data: &models.RangeData{
+			Range: "A1:C4",
+			Values: [][]interface{}{
+				{"", "FY2023", "FY2024"},
+				{"Revenue", 100.0, 120.0},
+				{"Cost of Revenue", 40.0, 48.0},
+				{"Gross Profit", 60.0, 72.0},
+			},
+			Formulas: [][]string{
+				{"", "", ""},
+				{"", "", ""},
+				{"", "", ""},
+				{"", "=B2-B3", "=C2-C3"},
+			},
+		},
+	}}}
+	capture := &models.CaptureTemplateRequest{WorkspaceID: workspaceID, TemplateKey: "three-statement", Name: "Three statement", Category: "valuation"}
+
+	if _, err := service.Capture(ctx, viewer, capture, workbook); !errors.Is(err, ErrForbidden) {
+		t.Errorf("viewer capture error = %v, want ErrForbidden", err)
+	}
+	otherWorkspace := auth.WithBoundWorkspace(ctx, uuid.New())
+	if _, err := service.Capture(otherWorkspace, analyst, capture, workbook); !errors.Is(err, ErrForbidden) {
+		t.Errorf("capture bound to another workspace error = %v, want ErrForbidden", err)
+	}
+	first, err := service.Capture(ctx, analyst, capture, workbook)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Version != 1 || first.Source != models.TemplateSourceCaptured {
+		t.Errorf("first capture = version %d source %s, want version 1 captured", first.Version, first.Source)
+	}
+	if _, err := ParseDefinition(first.Definition); err != nil {
+		t.Errorf("captured definition does not validate: %v", err)
+	}
+
+	// Capturing the same key again adds the next version
+	second, err := service.Capture(ctx, analyst, capture, workbook)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Version != 2 {
+		t.Errorf("second capture version = %d, want 2", second.Version)
+	}
+
+	// A new version carries over unset fields from the latest version
+	name := "Three statement v3"
+	third, err := service.CreateVersion(ctx, analyst, first.ID.String(), &models.CreateTemplateVersionRequest{
+		Name:       &name,
+		Definition: json.RawMessage(first.Definition),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.Version != 3 || third.Name != name || third.Category != "valuation" || third.Source != models.TemplateSourceCaptured {
+		t.Errorf("new version = %+v, want version 3 renamed with the category and source carried over", third)
+	}
+	if _, err := service.CreateVersion(ctx, analyst, uuid.NewString(), &models.CreateTemplateVersionRequest{Definition: first.Definition}); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("version of an unknown template error = %v, want ErrTemplateNotFound", err)
+	}
+
+	if err := service.Deprecate(otherWorkspace, analyst, first.ID.String()); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("deprecate bound to another workspace error = %v, want ErrTemplateNotFound", err)
+	}
+	if err := service.Deprecate(ctx, viewer, first.ID.String()); !errors.Is(err, ErrForbidden) {
+		t.Errorf("viewer deprecate error = %v, want ErrForbidden", err)
+	}
+	if err := service.Deprecate(ctx, analyst, first.ID.String()); err != nil {
+		t.Fatal(err)
+	}
+	if first.Status != models.TemplateStatusDeprecated {
+		t.Errorf("status = %s, want deprecated", first.Status)
+	}
+	if err := service.Deprecate(ctx, analyst, first.ID.String()); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("second deprecate error = %v, want ErrTemplateNotFound", err)
+	}
+	if err := service.Deprecate(ctx, analyst, Builtins()[0].TemplateKey); !errors.Is(err, ErrBuiltinReadOnly) {
+		t.Errorf("built-in deprecate error = %v, want ErrBuiltinReadOnly", err)
+	}
+}
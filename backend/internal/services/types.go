@@ -1,6 +1,10 @@
 package services
 
-import "time"
+import (
+	"time"
+
+	"github.com/gridmate/backend/internal/services/ai"
+)
 
 // SelectionChanged represents a cell selection change
 type SelectionChanged struct {
@@ -25,6 +29,7 @@ type ChatResponse struct {
 	SessionID   string           `json:"session_id"`
 	IsFinal     bool             `json:"is_final"`
 	TokenUsage  *TokenUsage      `json:"token_usage,omitempty"`
+	Citations   []ai.Citation    `json:"citations,omitempty"`
 }
 
 // TokenUsage represents token usage information
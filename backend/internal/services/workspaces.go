@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/gridmate/backend/internal/repository"
+)
+
+// userWorkspaceTTL is how long a user's workspace is remembered. Tools are
+// listed and checked per session many times a request, so membership
+// changes apply after this delay.
+const userWorkspaceTTL = time.Minute
+
+// maxKnownUserWorkspaces is how many users are remembered before expired
+// entries are dropped
+const maxKnownUserWorkspaces = 1000
+
+// UserWorkspaces finds the workspace whose settings apply to a user's
+// Excel sessions
+type UserWorkspaces struct {
+	repo repository.WorkspaceRepository
+
+	mu    sync.Mutex
+	known map[string]userWorkspace
+}
+
+type userWorkspace struct {
+	workspace string
+	err       error
+	expires   time.Time
+}
+
+// NewUserWorkspaces creates a workspace source reading memberships from repo
+func NewUserWorkspaces(repo repository.WorkspaceRepository) *UserWorkspaces {
+	return &UserWorkspaces{repo: repo, known: make(map[string]userWorkspace)}
+}
+
+// UserWorkspace returns the one workspace the user belongs to. Sessions do
+// not say which workspace they act in, so users in several workspaces, and
+// tenants that are not user IDs, have none.
+func (w *UserWorkspaces) UserWorkspace(ctx context.Context, userID string) (string, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return "", fmt.Errorf("session user %q is not a user ID", userID)
+	}
+
+	w.mu.Lock()
+	known, ok := w.known[userID]
+	w.mu.Unlock()
+	if ok && time.Now().Before(known.expires) {
+		return known.workspace, known.err
+	}
+
+	workspaces, err := w.repo.GetUserWorkspaces(ctx, id)
+	if err != nil {
+		return "", fmt.Errorf("failed to get workspaces of user %s: %w", userID, err)
+	}
+	known = userWorkspace{expires: time.Now().Add(userWorkspaceTTL)}
+	switch len(workspaces) {
+	case 0:
+		known.err = fmt.Errorf("user %s belongs to no workspace", userID)
+	case 1:
+		known.workspace = workspaces[0].ID.String()
+	default:
+		known.err = fmt.Errorf("user %s belongs to %d workspaces", userID, len(workspaces))
+	}
+
+	w.mu.Lock()
+	if len(w.known) >= maxKnownUserWorkspaces {
+		for user, entry := range w.known {
+			if !time.Now().Before(entry.expires) {
+				delete(w.known, user)
+			}
+		}
+	}
+	w.known[userID] = known
+	w.mu.Unlock()
+	return known.workspace, known.err
+}
@@ -0,0 +1,11 @@
+-- Drop triggers first
+DROP TRIGGER IF EXISTS update_model_templates_updated_at ON model_templates;
+
+-- Drop indexes
+DROP INDEX IF EXISTS idx_model_templates_workspace;
+DROP INDEX IF EXISTS idx_model_templates_key;
+DROP INDEX IF EXISTS idx_model_templates_category;
+DROP INDEX IF EXISTS idx_model_templates_status;
+
+-- Drop tables
+DROP TABLE IF EXISTS model_templates;
@@ -0,0 +1,28 @@
+-- Create model_templates table for workspace-owned template libraries
+CREATE TABLE IF NOT EXISTS model_templates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    template_key VARCHAR(100) NOT NULL, -- Stable identifier shared by all versions
+    version INTEGER NOT NULL DEFAULT 1,
+    name VARCHAR(255) NOT NULL,
+    category VARCHAR(100) NOT NULL,
+    description TEXT,
+    definition JSONB NOT NULL DEFAULT '{}', -- Validated against the template definition schema
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'deprecated')),
+    source VARCHAR(20) NOT NULL DEFAULT 'manual' CHECK (source IN ('manual', 'captured', 'cloned')),
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    deprecated_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(workspace_id, template_key, version)
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_model_templates_workspace ON model_templates(workspace_id);
+CREATE INDEX IF NOT EXISTS idx_model_templates_key ON model_templates(workspace_id, template_key);
+CREATE INDEX IF NOT EXISTS idx_model_templates_category ON model_templates(category);
+CREATE INDEX IF NOT EXISTS idx_model_templates_status ON model_templates(status);
+
+-- Add updated_at trigger
+CREATE TRIGGER update_model_templates_updated_at BEFORE UPDATE ON model_templates
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,8 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_financial_facts_document;
+DROP INDEX IF EXISTS idx_financial_facts_concept;
+DROP INDEX IF EXISTS idx_financial_facts_local_name;
+DROP INDEX IF EXISTS idx_financial_facts_period;
+
+-- Drop tables
+DROP TABLE IF EXISTS financial_facts;
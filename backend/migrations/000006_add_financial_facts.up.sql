@@ -0,0 +1,24 @@
+-- Create financial_facts table for XBRL facts extracted from filings
+CREATE TABLE IF NOT EXISTS financial_facts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    document_id UUID NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    concept VARCHAR(255) NOT NULL, -- Prefixed QName, e.g. us-gaap:Revenues
+    local_name VARCHAR(255) NOT NULL, -- Concept without prefix for prefix-agnostic search
+    context_ref VARCHAR(255) NOT NULL,
+    period_type VARCHAR(20) NOT NULL CHECK (period_type IN ('instant', 'duration', 'forever')),
+    period_start DATE,
+    period_end DATE,
+    dimensions JSONB DEFAULT '{}',
+    unit VARCHAR(100),
+    decimals VARCHAR(10), -- Integer or INF
+    scale INTEGER DEFAULT 0,
+    value NUMERIC,
+    text_value TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_financial_facts_document ON financial_facts(document_id);
+CREATE INDEX IF NOT EXISTS idx_financial_facts_concept ON financial_facts(concept);
+CREATE INDEX IF NOT EXISTS idx_financial_facts_local_name ON financial_facts(local_name);
+CREATE INDEX IF NOT EXISTS idx_financial_facts_period ON financial_facts(period_end, period_start);
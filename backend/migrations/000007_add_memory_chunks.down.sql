@@ -0,0 +1,9 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_memory_chunks_user;
+DROP INDEX IF EXISTS idx_memory_chunks_session;
+DROP INDEX IF EXISTS idx_memory_chunks_document;
+DROP INDEX IF EXISTS idx_memory_chunks_embedding;
+DROP INDEX IF EXISTS idx_memory_chunks_content;
+
+-- Drop tables
+DROP TABLE IF EXISTS memory_chunks;
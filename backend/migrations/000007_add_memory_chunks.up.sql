@@ -0,0 +1,32 @@
+-- Create memory_chunks table holding document, workbook and chat chunks in one index.
+-- Requires pgvector; servers without it (e.g. Azure without the extension allow-listed)
+-- skip this migration and keep using the in-process memory stores.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_available_extensions WHERE name = 'vector') THEN
+        RAISE NOTICE 'pgvector not available, skipping memory_chunks';
+        RETURN;
+    END IF;
+
+    CREATE EXTENSION IF NOT EXISTS vector;
+
+    CREATE TABLE IF NOT EXISTS memory_chunks (
+        owner VARCHAR(300) NOT NULL, -- Writing scope: user:<uuid> or session:<id>
+        chunk_id VARCHAR(255) NOT NULL,
+        user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+        session_id VARCHAR(255),
+        document_id UUID REFERENCES documents(id) ON DELETE CASCADE,
+        source VARCHAR(20) NOT NULL CHECK (source IN ('spreadsheet', 'document', 'chat')),
+        content TEXT NOT NULL,
+        embedding vector(1536), -- OpenAI embedding dimension, as planned in 000002
+        metadata JSONB NOT NULL DEFAULT '{}', -- memory.ChunkMetadata
+        created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+        PRIMARY KEY (owner, chunk_id)
+    );
+
+    CREATE INDEX IF NOT EXISTS idx_memory_chunks_user ON memory_chunks(user_id);
+    CREATE INDEX IF NOT EXISTS idx_memory_chunks_session ON memory_chunks(session_id);
+    CREATE INDEX IF NOT EXISTS idx_memory_chunks_document ON memory_chunks(document_id);
+    CREATE INDEX IF NOT EXISTS idx_memory_chunks_embedding ON memory_chunks USING hnsw (embedding vector_cosine_ops);
+    CREATE INDEX IF NOT EXISTS idx_memory_chunks_content ON memory_chunks USING gin (to_tsvector('simple', content));
+END $$;
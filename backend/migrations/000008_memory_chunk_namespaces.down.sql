@@ -0,0 +1,14 @@
+DO $$
+BEGIN
+    IF to_regclass('memory_chunks') IS NULL THEN
+        RETURN;
+    END IF;
+
+    UPDATE memory_chunks
+    SET owner = CASE
+        WHEN split_part(owner, '/', 2) = '' THEN 'user:' || split_part(owner, '/', 1)
+        ELSE 'session:' || substring(owner FROM position('/' IN owner) + 1)
+    END;
+
+    COMMENT ON COLUMN memory_chunks.owner IS NULL;
+END $$;
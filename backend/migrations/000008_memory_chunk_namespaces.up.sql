@@ -0,0 +1,20 @@
+-- Key memory_chunks by namespace ("<tenant>/<session>", session empty for
+-- tenant-wide chunks) instead of the user:/session: owner prefixes.
+-- Skipped, like 000007, when the table was never created.
+DO $$
+BEGIN
+    IF to_regclass('memory_chunks') IS NULL THEN
+        RETURN;
+    END IF;
+
+    UPDATE memory_chunks
+    SET owner = substring(owner FROM 6) || '/'
+    WHERE owner LIKE 'user:%';
+
+    -- Session chunks without a known user move to the anonymous tenant
+    UPDATE memory_chunks
+    SET owner = COALESCE(user_id::text, 'anonymous') || '/' || substring(owner FROM 9)
+    WHERE owner LIKE 'session:%';
+
+    COMMENT ON COLUMN memory_chunks.owner IS 'Namespace key: <tenant>/<session>';
+END $$;
@@ -0,0 +1,5 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_protected_ranges_workbook;
+
+-- Drop tables
+DROP TABLE IF EXISTS protected_ranges;
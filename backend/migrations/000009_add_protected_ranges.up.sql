@@ -0,0 +1,15 @@
+-- Create protected_ranges table for cells the AI must never modify
+CREATE TABLE IF NOT EXISTS protected_ranges (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    workbook VARCHAR(255) NOT NULL, -- Workbook name reported by the add-in
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('range', 'named_range', 'sheet')),
+    target VARCHAR(255) NOT NULL, -- A1 address, range name or sheet name
+    reason TEXT,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(workspace_id, workbook, kind, target)
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_protected_ranges_workbook ON protected_ranges(workbook);
@@ -0,0 +1,16 @@
+-- Drop triggers
+DROP TRIGGER IF EXISTS reject_audit_logs_update ON audit_logs;
+
+-- Drop function
+DROP FUNCTION IF EXISTS reject_audit_log_update();
+
+-- Drop indexes
+DROP INDEX IF EXISTS idx_audit_logs_workspace;
+DROP INDEX IF EXISTS idx_audit_logs_chain;
+
+-- Drop columns
+ALTER TABLE audit_logs
+    DROP COLUMN IF EXISTS hash,
+    DROP COLUMN IF EXISTS prev_hash,
+    DROP COLUMN IF EXISTS sequence,
+    DROP COLUMN IF EXISTS workspace_id;
@@ -0,0 +1,26 @@
+-- Chain audit log entries per workspace so edits and deletions are detectable.
+-- Entries written before this migration keep a NULL sequence and stay outside
+-- the chain.
+ALTER TABLE audit_logs
+    ADD COLUMN IF NOT EXISTS workspace_id UUID, -- NULL for entries outside any workspace
+    ADD COLUMN IF NOT EXISTS sequence BIGINT,
+    ADD COLUMN IF NOT EXISTS prev_hash VARCHAR(64),
+    ADD COLUMN IF NOT EXISTS hash VARCHAR(64);
+
+-- Create indexes
+CREATE UNIQUE INDEX IF NOT EXISTS idx_audit_logs_chain
+    ON audit_logs(COALESCE(workspace_id, '00000000-0000-0000-0000-000000000000'::uuid), sequence)
+    WHERE sequence IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_audit_logs_workspace ON audit_logs(workspace_id, created_at);
+
+-- Audit entries are append-only. Retention deletes old entries, which the
+-- chain records with a checkpoint entry.
+CREATE OR REPLACE FUNCTION reject_audit_log_update()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit log entries cannot be modified';
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER reject_audit_logs_update BEFORE UPDATE ON audit_logs
+    FOR EACH ROW EXECUTE FUNCTION reject_audit_log_update();
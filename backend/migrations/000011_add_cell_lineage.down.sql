@@ -0,0 +1,6 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_cell_lineage_operation;
+DROP INDEX IF EXISTS idx_cell_lineage_cell;
+
+-- Drop tables
+DROP TABLE IF EXISTS cell_lineage;
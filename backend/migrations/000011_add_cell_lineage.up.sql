@@ -0,0 +1,28 @@
+-- Create cell_lineage table recording where each AI-written range came from
+CREATE TABLE IF NOT EXISTS cell_lineage (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    workbook VARCHAR(255) NOT NULL, -- Workbook name reported by the add-in
+    sheet VARCHAR(255) NOT NULL,
+    start_row INTEGER NOT NULL, -- Zero-based, inclusive bounds of the written range
+    start_col INTEGER NOT NULL,
+    end_row INTEGER NOT NULL,
+    end_col INTEGER NOT NULL,
+    address VARCHAR(255) NOT NULL,
+    session_id VARCHAR(255) NOT NULL,
+    message_id VARCHAR(255),
+    tool_id VARCHAR(255) NOT NULL,
+    operation_id VARCHAR(255) NOT NULL, -- Queued operation that applies the write
+    tool_name VARCHAR(100) NOT NULL,
+    model VARCHAR(100),
+    input JSONB,
+    sources JSONB, -- Documents and chunks retrieved while answering the message
+    status VARCHAR(20) NOT NULL CHECK (status IN ('pending', 'applied', 'failed', 'rejected')),
+    approval JSONB, -- Policy decision, reviewer decisions and approving user
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    applied_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_cell_lineage_cell ON cell_lineage(user_id, workbook, sheet, start_row, end_row);
+CREATE INDEX IF NOT EXISTS idx_cell_lineage_operation ON cell_lineage(operation_id);
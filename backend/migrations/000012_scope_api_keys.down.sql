@@ -0,0 +1,10 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_api_keys_workspace;
+DROP INDEX IF EXISTS idx_api_keys_hash;
+
+-- Drop columns. Key hashes are not reverted, so existing keys stop working.
+ALTER TABLE api_keys DROP COLUMN IF EXISTS replaced_by;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS usage_count;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS allowed_ips;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS workspace_id;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS scopes;
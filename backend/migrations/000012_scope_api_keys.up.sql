@@ -0,0 +1,22 @@
+-- Restrict API keys to scopes, a workspace and client addresses
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS scopes TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS workspace_id UUID REFERENCES workspaces(id) ON DELETE CASCADE;
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS allowed_ips TEXT[] NOT NULL DEFAULT '{}'; -- Addresses or CIDR ranges, empty allows any
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS usage_count BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS replaced_by UUID REFERENCES api_keys(id) ON DELETE SET NULL; -- Key issued when this one was rotated
+
+-- Existing keys keep access to everything an API key may reach
+UPDATE api_keys SET scopes = ARRAY[
+    'chat:read', 'chat:write', 'documents:read', 'documents:write',
+    'excel:read', 'excel:write', 'models:read', 'models:write',
+    'workbooks:read', 'workbooks:write', 'reviews:read', 'reviews:write',
+    'audit:read', 'audit:write', 'mcp:read', 'mcp:write'
+];
+
+-- Keys were stored as "hashed_<key>"; store their SHA-256 digest instead
+UPDATE api_keys
+SET key_hash = encode(sha256(convert_to(substring(key_hash FROM 8), 'UTF8')), 'hex')
+WHERE key_hash LIKE 'hashed\_%';
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_hash ON api_keys(key_hash);
+CREATE INDEX IF NOT EXISTS idx_api_keys_workspace ON api_keys(workspace_id) WHERE workspace_id IS NOT NULL;
@@ -0,0 +1,2 @@
+-- Drop columns
+ALTER TABLE financial_facts DROP COLUMN IF EXISTS page;
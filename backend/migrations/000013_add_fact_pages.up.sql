@@ -0,0 +1,2 @@
+-- Record the printed page of inline XBRL facts for provenance
+ALTER TABLE financial_facts ADD COLUMN IF NOT EXISTS page INTEGER;
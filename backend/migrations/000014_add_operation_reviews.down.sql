@@ -0,0 +1,6 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_operation_reviews_workspace;
+DROP INDEX IF EXISTS idx_operation_reviews_reviewers;
+
+-- Drop tables
+DROP TABLE IF EXISTS operation_reviews;
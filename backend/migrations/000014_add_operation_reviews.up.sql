@@ -0,0 +1,19 @@
+-- Create operation_reviews table for four-eyes review of queued AI changes
+CREATE TABLE IF NOT EXISTS operation_reviews (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    session_id VARCHAR(255) NOT NULL,
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    submitted_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reviewers TEXT[] NOT NULL, -- User IDs of the assigned reviewers
+    operation_ids TEXT[] NOT NULL, -- Queued operations under review
+    required_approvals INTEGER NOT NULL DEFAULT 1,
+    comment TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'completed')),
+    decisions JSONB NOT NULL DEFAULT '{}', -- Reviewer decisions keyed by operation ID
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_operation_reviews_reviewers ON operation_reviews USING GIN (reviewers) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_operation_reviews_workspace ON operation_reviews(workspace_id, created_at);